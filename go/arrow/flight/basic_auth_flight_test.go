@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync/atomic"
 	"testing"
 
 	"github.com/apache/arrow/go/v16/arrow/flight"
@@ -200,3 +201,90 @@ func TestBasicAuthHelpers(t *testing.T) {
 		t.Fatal("should have received carebears")
 	}
 }
+
+func TestServerAuthChain(t *testing.T) {
+	var skipped atomic.Int32
+	s := flight.NewServerWithMiddleware([]flight.ServerMiddleware{flight.CreateServerAuthMiddleware(
+		flight.AuthenticatorFunc(func(ctx context.Context) (interface{}, error) {
+			skipped.Add(1)
+			md, _ := metadata.FromIncomingContext(ctx)
+			if len(md.Get("x-banned")) > 0 {
+				return nil, status.Error(codes.PermissionDenied, "banned")
+			}
+			return nil, flight.ErrNoCredentials
+		}),
+		flight.NewAPIKeyAuthenticator("X-API-Key", map[string]interface{}{"key1": "alice"}),
+		flight.NewBasicTokenAuthenticator(&validator{}),
+	)})
+	s.Init("localhost:0")
+	s.RegisterFlightService(&HeaderAuthTestFlight{})
+
+	go s.Serve()
+	defer s.Shutdown()
+
+	client, err := flight.NewFlightClient(s.Addr().String(), nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	identity := func(ctx context.Context) (string, codes.Code) {
+		sc, err := client.GetSchema(ctx, &flight.FlightDescriptor{})
+		if err != nil {
+			return "", status.Code(err)
+		}
+		return string(sc.Schema), codes.OK
+	}
+	withMD := func(kv ...string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), kv...)
+	}
+
+	if id, code := identity(withMD("x-api-key", "key1")); code != codes.OK || id != "alice" {
+		t.Fatalf("api key: got %q, %s", id, code)
+	}
+
+	ctx, err := client.AuthenticateBasicToken(context.Background(), validUsername, validPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, code := identity(ctx); code != codes.OK || id != "carebears" {
+		t.Fatalf("bearer token: got %q, %s", id, code)
+	}
+	fs, err := client.ListFlights(ctx, &flight.Criteria{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if skipped.Load() == 0 {
+		t.Fatal("authenticators should have been tried in order")
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"no credentials", context.Background(), codes.Unauthenticated},
+		{"invalid api key", withMD("x-api-key", "key2"), codes.Unauthenticated},
+		// the first authenticator handling the credentials decides
+		{"invalid api key with valid token", metadata.AppendToOutgoingContext(ctx, "x-api-key", "key2"), codes.Unauthenticated},
+		{"rejected before api key", withMD("x-banned", "1", "x-api-key", "key1"), codes.PermissionDenied},
+		{"invalid token", withMD("authorization", "Bearer "+invalidBearer), codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, code := identity(tt.ctx); code != tt.code {
+				t.Fatalf("expected %s, got %s", tt.code, code)
+			}
+		})
+	}
+
+	t.Run("invalid handshake", func(t *testing.T) {
+		if _, err := client.AuthenticateBasicToken(context.Background(), invalidUsername, invalidPassword); err == nil {
+			t.Fatal("should have failed")
+		}
+	})
+}
@@ -72,6 +72,10 @@ type Client interface {
 	SetSessionOptions(ctx context.Context, request *SetSessionOptionsRequest, opts ...grpc.CallOption) (*SetSessionOptionsResult, error)
 	GetSessionOptions(ctx context.Context, request *GetSessionOptionsRequest, opts ...grpc.CallOption) (*GetSessionOptionsResult, error)
 	CloseSession(ctx context.Context, request *CloseSessionRequest, opts ...grpc.CallOption) (*CloseSessionResult, error)
+	// DoExchangeRecords starts a DoExchange call for the descriptor, returning
+	// the writer of the records and metadata sent to the server and the reader
+	// of those sent back, which can be used concurrently.
+	DoExchangeRecords(ctx context.Context, desc *FlightDescriptor, opts ...grpc.CallOption) (*Writer, *Reader, error)
 	// join the interface from the FlightServiceClient instead of re-defining all
 	// the endpoints here.
 	FlightServiceClient
@@ -260,7 +264,7 @@ func NewFlightClient(addr string, auth ClientAuthHandler, opts ...grpc.DialOptio
 		}, opts...)
 	}
 
-	conn, err := grpc.Dial(addr, opts...)
+	conn, err := grpc.Dial(dialTarget(addr), opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -271,7 +275,8 @@ func NewFlightClient(addr string, auth ClientAuthHandler, opts ...grpc.DialOptio
 // NewClientWithMiddleware takes a slice of middleware in addition to the auth and address which will be
 // used by grpc and chained, the first middleware will be the outer most with the last middleware
 // being the inner most wrapper around the actual call. It also passes along the dialoptions passed in such
-// as TLS certs and so on.
+// as TLS certs and so on. The address may also be a grpc+unix location URI, see NewLocationUnix, to
+// connect over a unix socket.
 func NewClientWithMiddleware(addr string, auth ClientAuthHandler, middleware []ClientMiddleware, opts ...grpc.DialOption) (Client, error) {
 	return NewClientWithMiddlewareCtx(context.Background(), addr, auth, middleware, opts...)
 }
@@ -294,7 +299,7 @@ func NewClientWithMiddlewareCtx(ctx context.Context, addr string, auth ClientAut
 		}
 	}
 	opts = append(opts, grpc.WithChainUnaryInterceptor(unary...), grpc.WithChainStreamInterceptor(stream...))
-	conn, err := grpc.DialContext(ctx, addr, opts...)
+	conn, err := grpc.DialContext(ctx, dialTarget(addr), opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -308,37 +313,48 @@ func NewClientFromConn(cc grpc.ClientConnInterface, auth ClientAuthHandler) Clie
 }
 
 func (c *client) AuthenticateBasicToken(ctx context.Context, username, password string, opts ...grpc.CallOption) (context.Context, error) {
+	token, err := basicAuthHandshake(ctx, c.FlightServiceClient, username, password, opts...)
+	if err != nil {
+		return ctx, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, "Authorization", token), nil
+}
+
+// basicAuthHandshake performs a Handshake authenticated with the basic
+// credentials username and password, and returns the authorization
+// header the server responded with, such as "Bearer <token>".
+func basicAuthHandshake(ctx context.Context, client FlightServiceClient, username, password string, opts ...grpc.CallOption) (string, error) {
 	authCtx := metadata.AppendToOutgoingContext(ctx, "Authorization", "Basic "+base64.RawStdEncoding.EncodeToString([]byte(strings.Join([]string{username, password}, ":"))))
 
-	stream, err := c.FlightServiceClient.Handshake(authCtx, opts...)
+	stream, err := client.Handshake(authCtx, opts...)
 	if err != nil {
-		return ctx, err
+		return "", err
 	}
 
 	err = stream.CloseSend()
 	if err != nil {
-		return ctx, err
+		return "", err
 	}
 
 	header, err := stream.Header()
 	if err != nil {
-		return ctx, err
+		return "", err
 	}
 
 	_, err = stream.Recv()
 	if err != nil && err != io.EOF {
-		return ctx, err
+		return "", err
 	}
 
 	meta := stream.Trailer()
 	md := metadata.Join(header, meta)
 	for _, token := range md.Get("authorization") {
 		if token != "" {
-			return metadata.AppendToOutgoingContext(ctx, "Authorization", token), nil
+			return token, nil
 		}
 	}
 
-	return ctx, fmt.Errorf("flight: no authorization header on the response")
+	return "", fmt.Errorf("flight: no authorization header on the response")
 }
 
 func (c *client) Authenticate(ctx context.Context, opts ...grpc.CallOption) error {
@@ -425,6 +441,32 @@ func (c *client) CloseSession(ctx context.Context, request *CloseSessionRequest,
 	return &result, err
 }
 
+// DoExchangeRecords sends desc as the first message of the exchange,
+// before anything is written, so that the server knows what to do without
+// waiting for a record. Closing the writer half-closes the exchange, the
+// results being read until the reader returns false, see NewExchangeReader
+// and NewExchangeWriter. Cancelling ctx aborts the exchange.
+func (c *client) DoExchangeRecords(ctx context.Context, desc *FlightDescriptor, opts ...grpc.CallOption) (*Writer, *Reader, error) {
+	stream, err := c.DoExchange(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := stream.Send(&FlightData{FlightDescriptor: desc}); err != nil {
+		if err == io.EOF {
+			// the status of the call is returned by Recv
+			_, err = stream.Recv()
+		}
+		return nil, nil, err
+	}
+
+	rdr, err := NewExchangeReader(stream)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewExchangeWriter(stream), rdr, nil
+}
+
 func handleAction[T, U proto.Message](ctx context.Context, client FlightServiceClient, name string, request T, response U, opts ...grpc.CallOption) error {
 	var (
 		action flight.Action
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"golang.org/x/exp/maps"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -37,12 +38,19 @@ var endOfTime = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
 // clients which properly handles Set-Cookie headers to store cookies
 // in a cookie jar, and then requests are sent with those cookies added
 // as a Cookie header.
+//
+// Cookies are kept separately for each target the middleware is used to
+// connect to, and a cookie with a Path is only sent with the calls whose
+// method, such as "/arrow.flight.protocol.FlightService/DoGet", is within
+// that path. Cookies expire according to their Expires and Max-Age
+// attributes, so a Set-Cookie with Max-Age=0 deletes a cookie.
 func NewClientCookieMiddleware() ClientMiddleware {
-	return CreateClientMiddleware(&clientCookieMiddleware{jar: make(map[string]http.Cookie)})
+	cc := &clientCookieMiddleware{jars: make(map[string]map[string]http.Cookie)}
+	return ClientMiddleware{Unary: cc.unary, Stream: cc.stream}
 }
 
 func NewCookieMiddleware() CookieMiddleware {
-	return &clientCookieMiddleware{jar: make(map[string]http.Cookie)}
+	return &clientCookieMiddleware{jars: make(map[string]map[string]http.Cookie)}
 }
 
 // CookieMiddleware is a go-routine safe middleware for flight clients
@@ -50,6 +58,9 @@ func NewCookieMiddleware() CookieMiddleware {
 // This can be passed into `CreateClientMiddleware` to create a new
 // middleware object. You can also clone it to create middleware for a
 // new client which starts with the same cookies.
+//
+// Unlike NewClientCookieMiddleware, it keeps a single set of cookies
+// whichever server it is used with, and ignores their paths.
 type CookieMiddleware interface {
 	CustomClientMiddleware
 	// Clone creates a new CookieMiddleware that starts out with the same
@@ -58,23 +69,41 @@ type CookieMiddleware interface {
 	Clone() CookieMiddleware
 }
 
+// clientCookieMiddleware holds the cookies for each target, keyed by their
+// name and path. The CookieMiddleware interface, which doesn't know the
+// target, uses the empty target.
 type clientCookieMiddleware struct {
-	jar map[string]http.Cookie
-	mx  sync.Mutex
+	jars map[string]map[string]http.Cookie
+	mx   sync.Mutex
 }
 
 func (cc *clientCookieMiddleware) Clone() CookieMiddleware {
 	cc.mx.Lock()
 	defer cc.mx.Unlock()
-	return &clientCookieMiddleware{jar: maps.Clone(cc.jar)}
+	jars := make(map[string]map[string]http.Cookie, len(cc.jars))
+	for target, jar := range cc.jars {
+		jars[target] = maps.Clone(jar)
+	}
+	return &clientCookieMiddleware{jars: jars}
 }
 
 func (cc *clientCookieMiddleware) StartCall(ctx context.Context) context.Context {
+	if cookies := cc.cookieHeader("", ""); cookies != "" {
+		return metadata.AppendToOutgoingContext(ctx, "Cookie", cookies)
+	}
+	return ctx
+}
+
+// cookieHeader returns the value of the Cookie header to send to target
+// for method, or "" if there are no cookies to send. Paths are ignored if
+// method is empty.
+func (cc *clientCookieMiddleware) cookieHeader(target, method string) string {
 	cc.mx.Lock()
 	defer cc.mx.Unlock()
 
-	if len(cc.jar) == 0 {
-		return ctx
+	jar := cc.jars[target]
+	if len(jar) == 0 {
+		return ""
 	}
 
 	now := time.Now()
@@ -85,21 +114,33 @@ func (cc *clientCookieMiddleware) StartCall(ctx context.Context) context.Context
 
 	// we will also clear any expired cookies from the jar while we determine
 	// the cookies to send.
-	cookies := make([]string, 0, len(cc.jar))
-	for id, c := range cc.jar {
+	cookies := make([]string, 0, len(jar))
+	for id, c := range jar {
 		if !c.Expires.After(now) {
-			delete(cc.jar, id)
+			delete(jar, id)
+			continue
+		}
+		if method != "" && !cookiePathMatch(c.Path, method) {
 			continue
 		}
 
 		cookies = append(cookies, (&http.Cookie{Name: c.Name, Value: c.Value}).String())
 	}
 
-	if len(cookies) == 0 {
-		return ctx
-	}
+	return strings.Join(cookies, ";")
+}
 
-	return metadata.AppendToOutgoingContext(ctx, "Cookie", strings.Join(cookies, ";"))
+// cookiePathMatch reports whether the cookie path matches the path of a
+// call, as defined by RFC 6265 section 5.1.4. A cookie without a path
+// matches every call.
+func cookiePathMatch(cookiePath, path string) bool {
+	switch {
+	case cookiePath == "" || cookiePath == path:
+		return true
+	case !strings.HasPrefix(path, cookiePath):
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || path[len(cookiePath)] == '/'
 }
 
 func processCookieExpire(c *http.Cookie, now time.Time) (remove bool) {
@@ -122,11 +163,21 @@ func processCookieExpire(c *http.Cookie, now time.Time) (remove bool) {
 }
 
 func (cc *clientCookieMiddleware) HeadersReceived(ctx context.Context, md metadata.MD) {
+	cc.store("", md)
+}
+
+// store saves the cookies set by md in the jar of target.
+func (cc *clientCookieMiddleware) store(target string, md metadata.MD) {
+	setCookies := md.Get("set-cookie")
+	if len(setCookies) == 0 {
+		return
+	}
+
 	// instead of replicating the logic for processing the Set-Cookie
 	// header, let's just make a fake response and use the built-in
 	// cookie processing. It's very non-trivial
 	cookies := (&http.Response{
-		Header: http.Header{"Set-Cookie": md.Get("set-cookie")},
+		Header: http.Header{"Set-Cookie": setCookies},
 	}).Cookies()
 
 	now := time.Now()
@@ -134,13 +185,66 @@ func (cc *clientCookieMiddleware) HeadersReceived(ctx context.Context, md metada
 	cc.mx.Lock()
 	defer cc.mx.Unlock()
 
+	jar := cc.jars[target]
+	if jar == nil {
+		jar = make(map[string]http.Cookie)
+		cc.jars[target] = jar
+	}
+
 	for _, c := range cookies {
 		id := c.Name + c.Path
 		if processCookieExpire(c, now) {
-			delete(cc.jar, id)
+			delete(jar, id)
 			continue
 		}
 
-		cc.jar[id] = *c
+		jar[id] = *c
+	}
+}
+
+func (cc *clientCookieMiddleware) unary(ctx context.Context, method string, req, reply interface{}, conn *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	target := conn.Target()
+	if cookies := cc.cookieHeader(target, method); cookies != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "Cookie", cookies)
+	}
+
+	var header, trailer metadata.MD
+	err := invoker(ctx, method, req, reply, conn, append(opts, grpc.Header(&header), grpc.Trailer(&trailer))...)
+	cc.store(target, metadata.Join(header, trailer))
+	return err
+}
+
+func (cc *clientCookieMiddleware) stream(ctx context.Context, desc *grpc.StreamDesc, conn *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	target := conn.Target()
+	if cookies := cc.cookieHeader(target, method); cookies != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "Cookie", cookies)
+	}
+
+	cs, err := streamer(ctx, desc, conn, method, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &cookieStream{ClientStream: cs, store: func(md metadata.MD) { cc.store(target, md) }}, nil
+}
+
+// cookieStream stores the cookies set by the headers of a stream once
+// they have been received, and those set by its trailers once it ends.
+type cookieStream struct {
+	grpc.ClientStream
+	store     func(metadata.MD)
+	gotHeader bool
+}
+
+func (s *cookieStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if !s.gotHeader {
+		s.gotHeader = true
+		if md, err := s.ClientStream.Header(); err == nil {
+			s.store(md)
+		}
+	}
+	if err != nil {
+		s.store(s.ClientStream.Trailer())
 	}
+	return err
 }
@@ -25,10 +25,12 @@ import (
 	"net/textproto"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
@@ -299,3 +301,142 @@ func TestCookiesClone(t *testing.T) {
 	}
 	makeReq(client2, t)
 }
+
+// cookieRecorder is a server middleware which sends the Set-Cookie headers
+// in setCookies and records the Cookie header each call arrived with.
+type cookieRecorder struct {
+	mx         sync.Mutex
+	setCookies []string
+	received   []string
+}
+
+func (c *cookieRecorder) StartCall(ctx context.Context) context.Context {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.received = append(c.received, strings.Join(metadata.ValueFromIncomingContext(ctx, "cookie"), ";"))
+	if len(c.setCookies) > 0 {
+		md := make(metadata.MD)
+		for _, sc := range c.setCookies {
+			md.Append("Set-Cookie", sc)
+		}
+		grpc.SetHeader(ctx, md)
+		c.setCookies = nil
+	}
+	return ctx
+}
+
+func (c *cookieRecorder) CallCompleted(context.Context, error) {}
+
+func (c *cookieRecorder) set(cookies ...string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.setCookies = cookies
+}
+
+func (c *cookieRecorder) last() string {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.received[len(c.received)-1]
+}
+
+func startCookieRecorder(t *testing.T) (*cookieRecorder, string) {
+	rec := &cookieRecorder{}
+	s := flight.NewServerWithMiddleware([]flight.ServerMiddleware{
+		flight.CreateServerMiddleware(rec),
+	})
+	s.Init("localhost:0")
+	s.RegisterFlightService(&flightServer{mem: memory.NewGoAllocator()})
+
+	go s.Serve()
+	t.Cleanup(s.Shutdown)
+	return rec, s.Addr().String()
+}
+
+func TestCookiePathAndTarget(t *testing.T) {
+	recA, addrA := startCookieRecorder(t)
+	recB, addrB := startCookieRecorder(t)
+
+	credsOpt := grpc.WithTransportCredentials(insecure.NewCredentials())
+	cookies := flight.NewClientCookieMiddleware()
+	clientA, err := flight.NewClientWithMiddleware(addrA, nil, []flight.ClientMiddleware{cookies}, credsOpt)
+	require.NoError(t, err)
+	defer clientA.Close()
+	clientB, err := flight.NewClientWithMiddleware(addrB, nil, []flight.ClientMiddleware{cookies}, credsOpt)
+	require.NoError(t, err)
+	defer clientB.Close()
+
+	ctx := context.Background()
+	getSchema := func(c flight.Client) {
+		_, err := c.GetSchema(ctx, &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"primitives"}})
+		require.NoError(t, err)
+	}
+	listFlights := func(c flight.Client) {
+		stream, err := c.ListFlights(ctx, &flight.Criteria{})
+		require.NoError(t, err)
+		for {
+			if _, err := stream.Recv(); err != nil {
+				require.ErrorIs(t, err, io.EOF)
+				return
+			}
+		}
+	}
+
+	recA.set("affinity=node-a",
+		"listing=1; Path=/arrow.flight.protocol.FlightService/ListFlights",
+		"other=1; Path=/arrow.flight.protocol.FlightServiceX")
+	getSchema(clientA)
+
+	// the affinity cookie is sent everywhere on A, the listing cookie only
+	// with ListFlights and the other cookie never
+	getSchema(clientA)
+	assert.Equal(t, "affinity=node-a", recA.last())
+	listFlights(clientA)
+	assert.ElementsMatch(t, []string{"affinity=node-a", "listing=1"}, strings.Split(recA.last(), ";"))
+
+	// B has its own cookies
+	recB.set("affinity=node-b")
+	listFlights(clientB)
+	assert.Equal(t, "", recB.last())
+	getSchema(clientB)
+	assert.Equal(t, "affinity=node-b", recB.last())
+	getSchema(clientA)
+	assert.Equal(t, "affinity=node-a", recA.last())
+
+	// Max-Age=0 deletes the cookie
+	recA.set("affinity=; Max-Age=0")
+	getSchema(clientA)
+	getSchema(clientA)
+	assert.Equal(t, "", recA.last())
+	getSchema(clientB)
+	assert.Equal(t, "affinity=node-b", recB.last())
+}
+
+func TestCookieConcurrentCalls(t *testing.T) {
+	rec, addr := startCookieRecorder(t)
+
+	credsOpt := grpc.WithTransportCredentials(insecure.NewCredentials())
+	client, err := flight.NewClientWithMiddleware(addr, nil,
+		[]flight.ClientMiddleware{flight.NewClientCookieMiddleware()}, credsOpt)
+	require.NoError(t, err)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				rec.set(fmt.Sprintf("c%d=%d", i, j))
+				_, err := client.GetSchema(context.Background(),
+					&flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"primitives"}})
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	_, err = client.GetSchema(context.Background(),
+		&flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"primitives"}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, rec.last())
+}
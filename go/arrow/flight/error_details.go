@@ -0,0 +1,88 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ErrorWithDetails returns a gRPC status error with the code and message
+// carrying details, such as the payload of the error or a vendor code,
+// which a handler of a flight server returns to send them to the client.
+//
+// The details are sent as a google.rpc.Status in the binary
+// grpc-status-details-bin trailer, including by the streaming RPCs
+// failing after sending data, which is the extra info of the
+// FlightStatusDetail of the C++ implementation. ErrorDetails extracts
+// them from the error of the client.
+//
+// If the details cannot be marshaled, the error has none.
+func ErrorWithDetails(code codes.Code, msg string, details ...proto.Message) error {
+	st := status.New(code, msg)
+	if len(details) == 0 {
+		return st.Err()
+	}
+
+	p := st.Proto()
+	for _, d := range details {
+		a, err := anypb.New(d)
+		if err != nil {
+			return st.Err()
+		}
+		p.Details = append(p.Details, a)
+	}
+	return status.FromProto(p).Err()
+}
+
+// ErrorDetails returns the details of the gRPC status of err, or of an
+// error it wraps, such as those of an error returned by ErrorWithDetails
+// from a handler, received by the client. It returns nil if err has no
+// status or its status has no details.
+//
+// The details whose message types are not linked into the program are
+// returned as *anypb.Any.
+func ErrorDetails(err error) []proto.Message {
+	st, ok := status.FromError(err)
+	if !ok || st == nil {
+		return nil
+	}
+
+	anys := st.Proto().GetDetails()
+	if len(anys) == 0 {
+		return nil
+	}
+
+	details := make([]proto.Message, len(anys))
+	for i, a := range anys {
+		m, err := a.UnmarshalNew()
+		if err != nil {
+			details[i] = a
+			continue
+		}
+		details[i] = m
+	}
+	return details
+}
+
+// hasErrorDetails reports whether err has a gRPC status with details.
+func hasErrorDetails(err error) bool {
+	st, ok := status.FromError(err)
+	return ok && st != nil && len(st.Proto().GetDetails()) > 0
+}
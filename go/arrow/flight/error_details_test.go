@@ -0,0 +1,302 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"golang.org/x/net/http2"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// detailsServer fails its calls with the payload and vendor code of
+// errorDetails as details, DoGet after sending a record, and once its
+// stream is cancelled for the "cancelled" ticket.
+type detailsServer struct {
+	flight.BaseFlightServer
+}
+
+func errorDetails() []proto.Message {
+	return []proto.Message{
+		wrapperspb.Bytes([]byte("error payload")),
+		wrapperspb.Int32(42),
+	}
+}
+
+func (*detailsServer) GetFlightInfo(context.Context, *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return nil, flight.ErrorWithDetails(codes.NotFound, "no such flight", errorDetails()...)
+}
+
+func (*detailsServer) DoGet(tkt *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	wr := flight.NewRecordWriter(stream, ipc.WithSchema(endpointSchema))
+	defer wr.Close()
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, endpointSchema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).Append(1)
+	rec := bldr.NewRecord()
+	defer rec.Release()
+	if err := wr.Write(rec); err != nil {
+		return err
+	}
+	if string(tkt.Ticket) == "cancelled" {
+		<-stream.Context().Done()
+		return flight.ErrorWithDetails(codes.Aborted, "query cancelled", errorDetails()...)
+	}
+	return flight.ErrorWithDetails(codes.Internal, "table broke", errorDetails()...)
+}
+
+func startDetailsServer(t *testing.T) flight.Server {
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(&detailsServer{})
+	if err := s.Init("localhost:0"); err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve()
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func checkErrorDetails(t *testing.T, details []proto.Message) {
+	t.Helper()
+	want := errorDetails()
+	if len(details) != len(want) {
+		t.Fatalf("got %d details, expected %d: %v", len(details), len(want), details)
+	}
+	for i := range want {
+		if !proto.Equal(details[i], want[i]) {
+			t.Errorf("detail %d: got %v, expected %v", i, details[i], want[i])
+		}
+	}
+}
+
+func TestErrorDetails(t *testing.T) {
+	if details := flight.ErrorDetails(nil); details != nil {
+		t.Errorf("nil error has details %v", details)
+	}
+	if details := flight.ErrorDetails(errors.New("plain")); details != nil {
+		t.Errorf("plain error has details %v", details)
+	}
+	if details := flight.ErrorDetails(status.Error(codes.Internal, "flat")); details != nil {
+		t.Errorf("flat status has details %v", details)
+	}
+
+	err := flight.ErrorWithDetails(codes.InvalidArgument, "bad", errorDetails()...)
+	if status.Code(err) != codes.InvalidArgument || status.Convert(err).Message() != "bad" {
+		t.Fatalf("unexpected status %v", err)
+	}
+	checkErrorDetails(t, flight.ErrorDetails(fmt.Errorf("wrapped: %w", err)))
+
+	// details of types unknown to the program are kept as they are
+	unknown := &anypb.Any{TypeUrl: "type.googleapis.com/vendor.Unknown", Value: []byte{1, 2, 3}}
+	err = status.FromProto(&spb.Status{Code: int32(codes.Internal), Details: []*anypb.Any{unknown}}).Err()
+	details := flight.ErrorDetails(err)
+	if len(details) != 1 || !proto.Equal(details[0], unknown) {
+		t.Fatalf("unexpected details of unknown type %v", details)
+	}
+}
+
+func TestErrorDetailsFromServer(t *testing.T) {
+	s := startDetailsServer(t)
+	client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	t.Run("unary", func(t *testing.T) {
+		_, err := client.GetFlightInfo(ctx, &flight.FlightDescriptor{})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("unexpected error %v", err)
+		}
+		checkErrorDetails(t, flight.ErrorDetails(err))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		stream, err := client.DoGet(ctx, &flight.Ticket{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rdr, err := flight.NewRecordReader(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rdr.Release()
+
+		rows, err := readRows(rdr)
+		if len(rows) != 1 {
+			t.Fatalf("got %d records before the error, expected 1", len(rows))
+		}
+		if status.Code(err) != codes.Internal || !strings.Contains(err.Error(), "table broke") {
+			t.Fatalf("unexpected error %v", err)
+		}
+		checkErrorDetails(t, flight.ErrorDetails(err))
+	})
+
+	t.Run("graceful shutdown", func(t *testing.T) {
+		stream, err := client.DoGet(ctx, &flight.Ticket{Ticket: []byte("cancelled")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rdr, err := flight.NewRecordReader(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rdr.Release()
+		if !rdr.Next() {
+			t.Fatal(rdr.Err())
+		}
+
+		// the error of the handler has details, so it is not replaced
+		// by that of the shutdown
+		go s.GracefulShutdown(context.Background())
+		if rdr.Next() {
+			t.Fatal("unexpected record")
+		}
+		if err := rdr.Err(); status.Code(err) != codes.Aborted {
+			t.Fatalf("unexpected error %v", err)
+		}
+		checkErrorDetails(t, flight.ErrorDetails(rdr.Err()))
+	})
+}
+
+// TestErrorDetailsStatusDetailsBin reads the errors of a Go server as a
+// client of the C++ implementation does, from the raw HTTP/2 response:
+// its FlightStatusDetail has the binary grpc-status-details-bin metadata
+// as extra info, which is a serialized google.rpc.Status.
+func TestErrorDetailsStatusDetailsBin(t *testing.T) {
+	s := startDetailsServer(t)
+
+	tr := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	defer tr.CloseIdleConnections()
+
+	call := func(t *testing.T, method string, req proto.Message) (data []byte, md http.Header) {
+		msg, err := proto.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a single uncompressed length-prefixed message
+		frame := make([]byte, 5, 5+len(msg))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+		frame = append(frame, msg...)
+
+		url := "http://" + s.Addr().String() + "/arrow.flight.protocol.FlightService/" + method
+		httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(frame))
+		if err != nil {
+			t.Fatal(err)
+		}
+		httpReq.Header.Set("content-type", "application/grpc")
+		httpReq.Header.Set("te", "trailers")
+
+		resp, err := tr.RoundTrip(httpReq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+
+		// a response without messages has its status in its headers
+		md = resp.Trailer
+		if md.Get("grpc-status") == "" {
+			md = resp.Header
+		}
+		return data, md
+	}
+
+	statusDetail := func(t *testing.T, md http.Header) *spb.Status {
+		t.Helper()
+		bin := md.Get("grpc-status-details-bin")
+		if bin == "" {
+			t.Fatalf("no grpc-status-details-bin in %v", md)
+		}
+		// binary metadata is base64 encoded on the wire, with or
+		// without padding
+		extraInfo, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(bin, "="))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var st spb.Status
+		if err := proto.Unmarshal(extraInfo, &st); err != nil {
+			t.Fatal(err)
+		}
+		details := make([]proto.Message, len(st.Details))
+		for i, a := range st.Details {
+			if details[i], err = a.UnmarshalNew(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkErrorDetails(t, details)
+		return &st
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		_, md := call(t, "GetFlightInfo", &flight.FlightDescriptor{})
+		if got := md.Get("grpc-status"); got != fmt.Sprint(int(codes.NotFound)) {
+			t.Fatalf("got grpc-status %q", got)
+		}
+		st := statusDetail(t, md)
+		if codes.Code(st.Code) != codes.NotFound || st.Message != "no such flight" {
+			t.Fatalf("unexpected status %v", st)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		data, md := call(t, "DoGet", &flight.Ticket{})
+		// the schema and the record were sent before the error
+		if len(data) == 0 {
+			t.Fatal("no data before the error")
+		}
+		if got := md.Get("grpc-status"); got != fmt.Sprint(int(codes.Internal)) {
+			t.Fatalf("got grpc-status %q", got)
+		}
+		st := statusDetail(t, md)
+		if codes.Code(st.Code) != codes.Internal || st.Message != "table broke" {
+			t.Fatalf("unexpected status %v", st)
+		}
+	})
+}
@@ -0,0 +1,734 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"google.golang.org/grpc"
+)
+
+// defaultEndpointQueueSize is the number of records buffered for each
+// endpoint being fetched when WithMaxBufferedRecords isn't given.
+const defaultEndpointQueueSize = 4
+
+// LocationOpener retrieves tkt from one of the locations of an endpoint
+// for the readers of NewFlightInfoReader. The returned function is called
+// once the stream has been released, such as to close the connection.
+type LocationOpener func(ctx context.Context, location *Location, tkt *Ticket) (*Reader, func(), error)
+
+// EndpointResolver returns the endpoint which the readers of
+// NewFlightInfoReader retrieve in place of the endpoint ep at index idx of
+// the FlightInfo, such as a renewed one, right before retrieving it. An
+// error fails the reader.
+type EndpointResolver func(ctx context.Context, idx int, ep *FlightEndpoint) (*FlightEndpoint, error)
+
+// EndpointVerifier verifies the records read from an endpoint by the
+// readers of NewFlightInfoReader, see WithEndpointVerifier.
+type EndpointVerifier interface {
+	// Add is called with each chunk read from the endpoint.
+	Add(chunk StreamChunk)
+	// Verify is called once the endpoint has been read entirely, an
+	// error failing the reader.
+	Verify() error
+}
+
+// flightInfoReaderConfig holds the options of NewFlightInfoReader.
+type flightInfoReaderConfig struct {
+	mem          memory.Allocator
+	concurrency  int
+	maxBuffered  int
+	unordered    bool
+	openLocation LocationOpener
+	resolve      EndpointResolver
+	newVerifier  func(idx int) EndpointVerifier
+	policy       LocationPolicy
+}
+
+// flightInfoReaderOption is a grpc.CallOption which configures the reader
+// returned by NewFlightInfoReader. gRPC itself ignores it.
+type flightInfoReaderOption struct {
+	grpc.EmptyCallOption
+	apply func(*flightInfoReaderConfig)
+}
+
+// WithEndpointConcurrency allows NewFlightInfoReader to fetch up to n
+// endpoints at once, buffering the records of the endpoints after the one
+// currently being read. Records are still returned in endpoint order
+// unless WithUnorderedEndpoints is also given. The default of 1 fetches
+// each endpoint only once the previous one has been read, which is also
+// how the endpoints of a FlightInfo marked as Ordered are fetched whatever
+// n is.
+func WithEndpointConcurrency(n int) grpc.CallOption {
+	return flightInfoReaderOption{apply: func(cfg *flightInfoReaderConfig) { cfg.concurrency = n }}
+}
+
+// WithMaxBufferedRecords limits the number of records fetched ahead of
+// the reader when endpoints are fetched concurrently. The limit is split
+// evenly between the endpoints being fetched, each of which can buffer
+// at least one record. The default is 4 records per endpoint.
+func WithMaxBufferedRecords(n int) grpc.CallOption {
+	return flightInfoReaderOption{apply: func(cfg *flightInfoReaderConfig) { cfg.maxBuffered = n }}
+}
+
+// WithUnorderedEndpoints allows records to be returned in the order they
+// arrive when endpoints are fetched concurrently, rather than in endpoint
+// order, so that the rows of different endpoints may be interleaved. It
+// has no effect if the FlightInfo is marked as Ordered.
+func WithUnorderedEndpoints() grpc.CallOption {
+	return flightInfoReaderOption{apply: func(cfg *flightInfoReaderConfig) { cfg.unordered = true }}
+}
+
+// WithReaderAllocator sets the allocator of the records read by
+// NewFlightInfoReader, memory.DefaultAllocator by default.
+func WithReaderAllocator(mem memory.Allocator) grpc.CallOption {
+	return flightInfoReaderOption{apply: func(cfg *flightInfoReaderConfig) { cfg.mem = mem }}
+}
+
+// WithLocationOpener sets how NewFlightInfoReader retrieves tickets from
+// the locations of the endpoints, such as through a pool of connections.
+// By default each location is dialed with DialLocation, the connection
+// being closed once the endpoint has been read.
+func WithLocationOpener(open LocationOpener) grpc.CallOption {
+	return flightInfoReaderOption{apply: func(cfg *flightInfoReaderConfig) { cfg.openLocation = open }}
+}
+
+// WithEndpointResolver makes NewFlightInfoReader retrieve the endpoints
+// returned by resolve in place of those of the FlightInfo.
+func WithEndpointResolver(resolve EndpointResolver) grpc.CallOption {
+	return flightInfoReaderOption{apply: func(cfg *flightInfoReaderConfig) { cfg.resolve = resolve }}
+}
+
+// WithEndpointVerifier makes NewFlightInfoReader verify the records read
+// from each endpoint with a verifier returned by newVerifier for its
+// index, such as to check them against a checksum sent by the server.
+func WithEndpointVerifier(newVerifier func(idx int) EndpointVerifier) grpc.CallOption {
+	return flightInfoReaderOption{apply: func(cfg *flightInfoReaderConfig) { cfg.newVerifier = newVerifier }}
+}
+
+// EndpointError is the error of the readers of NewFlightInfoReader when
+// reading the stream of an endpoint fails midway, or when its records
+// fail verification, so that callers know how much of the result was
+// delivered before the failure.
+type EndpointError struct {
+	// Endpoint is the index of the endpoint in the FlightInfo.
+	Endpoint int
+	// Rows is the number of rows the reader returned before the error,
+	// from any endpoint.
+	Rows int64
+	Err  error
+}
+
+func (e *EndpointError) Error() string {
+	return fmt.Sprintf("arrow/flight: reading endpoint %d failed after %d rows: %s", e.Endpoint, e.Rows, e.Err)
+}
+
+func (e *EndpointError) Unwrap() error { return e.Err }
+
+// NewFlightInfoReader returns a single reader which retrieves each
+// endpoint of info in order with client. Endpoints without a Location are
+// retrieved using client, otherwise each Location is tried in turn until
+// one succeeds, see WithLocationOpener. If none succeeds, the endpoint is
+// retrieved using client. Each stream is released as soon as it is
+// exhausted, and the streams still open when the reader is released are
+// released along with it. The opts are passed to every DoGet, and may
+// also configure the reader itself.
+//
+// By default each endpoint is only fetched once the previous one has
+// been read; see WithEndpointConcurrency, WithMaxBufferedRecords and
+// WithUnorderedEndpoints to fetch several at once. The endpoints of an
+// info marked as Ordered are always fetched one after the other.
+//
+// The first endpoint is retrieved before returning, the schema of the
+// reader being that of its stream, or that of info if it has no
+// endpoints. If a later endpoint returns a different schema, reading
+// stops with an error wrapping arrow.ErrInvalid. Endpoints without
+// records are skipped. If reading an endpoint fails midway, the reader
+// fails with an *EndpointError. Once ctx is done, the reader fails with
+// an error wrapping that of ctx. Release should be called on the reader
+// when done.
+func NewFlightInfoReader(ctx context.Context, client Client, info *FlightInfo, opts ...grpc.CallOption) (MessageReader, error) {
+	src := &endpointSource{
+		client:    client,
+		endpoints: info.Endpoint,
+		opts:      opts,
+		cfg:       flightInfoReaderConfig{mem: memory.DefaultAllocator, concurrency: 1},
+	}
+	for _, o := range opts {
+		if o, ok := o.(flightInfoReaderOption); ok {
+			o.apply(&src.cfg)
+		}
+	}
+
+	if src.cfg.concurrency > 1 && len(info.Endpoint) > 1 && !info.Ordered {
+		return newConcurrentFlightInfoReader(ctx, src)
+	}
+
+	if len(info.Endpoint) == 0 {
+		if len(info.Schema) == 0 {
+			return nil, fmt.Errorf("%w: arrow/flight: FlightInfo has neither endpoints nor a schema", arrow.ErrInvalid)
+		}
+
+		schema, err := DeserializeSchema(info.Schema, src.cfg.mem)
+		if err != nil {
+			return nil, err
+		}
+		return &flightInfoReader{refCount: 1, ctx: ctx, cancel: func() {}, src: src, schema: schema}, nil
+	}
+
+	r := &flightInfoReader{refCount: 1, src: src}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	if err := r.openNext(); err != nil {
+		r.cancel()
+		return nil, err
+	}
+	r.schema = r.cur.Schema()
+	return r, nil
+}
+
+// endpointSource opens the streams of the endpoints of a FlightInfo.
+type endpointSource struct {
+	client    Client
+	endpoints []*FlightEndpoint
+	opts      []grpc.CallOption
+	cfg       flightInfoReaderConfig
+}
+
+// open opens the stream of the endpoint at index idx, as resolved if
+// there is an EndpointResolver. The returned function must be called once
+// the stream has been released.
+func (s *endpointSource) open(ctx context.Context, idx int) (*Reader, func(), error) {
+	ep := s.endpoints[idx]
+	if s.cfg.resolve != nil {
+		var err error
+		if ep, err = s.cfg.resolve(ctx, idx, ep); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(ep.Location) == 0 {
+		rdr, err := s.doGet(ctx, s.client, ep.Ticket)
+		return rdr, func() {}, err
+	}
+
+	locs := ep.Location
+	if s.cfg.policy != nil {
+		locs = s.cfg.policy.Order(locs)
+	}
+
+	var (
+		errs         []error
+		triedDefault bool
+	)
+	for _, loc := range locs {
+		if loc.GetUri() == LocationReuseConnection {
+			triedDefault = true
+			start := time.Now()
+			rdr, err := s.doGet(ctx, s.client, ep.Ticket)
+			s.report(ctx, loc, start, err)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			return rdr, func() {}, nil
+		}
+
+		start := time.Now()
+		rdr, done, err := s.openLocation(ctx, loc, ep.Ticket)
+		s.report(ctx, loc, start, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", loc.GetUri(), err))
+			continue
+		}
+		return rdr, done, nil
+	}
+
+	if !triedDefault {
+		rdr, err := s.doGet(ctx, s.client, ep.Ticket)
+		if err == nil {
+			return rdr, func() {}, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return nil, nil, fmt.Errorf("arrow/flight: could not retrieve endpoint %d from any location: %w", idx, errors.Join(errs...))
+}
+
+// report reports the outcome of retrieving an endpoint from loc, started
+// at start, to the LocationPolicy if there is one. The attempts cut short
+// by ctx say nothing about loc, and aren't reported.
+func (s *endpointSource) report(ctx context.Context, loc *Location, start time.Time, err error) {
+	if s.cfg.policy == nil || ctx.Err() != nil {
+		return
+	}
+	s.cfg.policy.Report(loc, time.Since(start), err)
+}
+
+// openLocation retrieves tkt from loc with the LocationOpener if there is
+// one, dialing loc otherwise.
+func (s *endpointSource) openLocation(ctx context.Context, loc *Location, tkt *Ticket) (*Reader, func(), error) {
+	if s.cfg.openLocation != nil {
+		return s.cfg.openLocation(ctx, loc, tkt)
+	}
+
+	cl, err := DialLocation(ctx, loc, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	rdr, err := s.doGet(ctx, cl, tkt)
+	if err != nil {
+		cl.Close()
+		return nil, nil, err
+	}
+	return rdr, func() { cl.Close() }, nil
+}
+
+func (s *endpointSource) doGet(ctx context.Context, cl Client, tkt *Ticket) (*Reader, error) {
+	stream, err := cl.DoGet(ctx, tkt, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecordReader(stream, ipc.WithAllocator(s.cfg.mem))
+}
+
+// verifier returns the verifier of the endpoint at index idx, nil if the
+// endpoints aren't verified.
+func (s *endpointSource) verifier(idx int) EndpointVerifier {
+	if s.cfg.newVerifier == nil {
+		return nil
+	}
+	return s.cfg.newVerifier(idx)
+}
+
+// schemaMismatch returns the error of the endpoint at index idx having
+// another schema than the first.
+func schemaMismatch(idx int, expected, got *arrow.Schema) error {
+	return fmt.Errorf("%w: arrow/flight: schema of endpoint %d does not match the first endpoint: expected %s, got %s",
+		arrow.ErrInvalid, idx, expected, got)
+}
+
+// readNext implements arrio.Reader for the readers of NewFlightInfoReader.
+func readNext(r array.RecordReader) (arrow.Record, error) {
+	if r.Next() {
+		return r.Record(), nil
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// flightInfoReader chains the streams of the endpoints of a FlightInfo.
+type flightInfoReader struct {
+	refCount int64
+
+	// ctx is canceled on release, ending the stream being read
+	ctx    context.Context
+	cancel context.CancelFunc
+	src    *endpointSource
+	next   int
+	// rows is the number of rows returned so far
+	rows int64
+
+	schema   *arrow.Schema
+	cur      *Reader
+	curDone  func()
+	verifier EndpointVerifier
+	err      error
+}
+
+func (r *flightInfoReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+func (r *flightInfoReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) == 0 {
+		r.cancel()
+		r.closeCurrent()
+	}
+}
+
+func (r *flightInfoReader) Schema() *arrow.Schema { return r.schema }
+
+func (r *flightInfoReader) Err() error { return r.err }
+
+func (r *flightInfoReader) Record() arrow.Record {
+	if r.cur == nil {
+		return nil
+	}
+	return r.cur.Record()
+}
+
+// Chunk returns the current record along with the app metadata and
+// descriptor of the message it was received in.
+func (r *flightInfoReader) Chunk() StreamChunk {
+	if r.cur == nil {
+		return StreamChunk{}
+	}
+	return r.cur.Chunk()
+}
+
+func (r *flightInfoReader) LatestAppMetadata() []byte { return r.Chunk().AppMetadata }
+
+func (r *flightInfoReader) LatestFlightDescriptor() *FlightDescriptor { return r.Chunk().Desc }
+
+func (r *flightInfoReader) Read() (arrow.Record, error) { return readNext(r) }
+
+func (r *flightInfoReader) Next() bool {
+	for r.err == nil {
+		if r.cur != nil {
+			if r.cur.Next() {
+				if r.verifier != nil {
+					r.verifier.Add(r.cur.Chunk())
+				}
+				r.rows += r.cur.Record().NumRows()
+				return true
+			}
+
+			err := r.cur.Err()
+			if err != nil && r.ctx.Err() != nil {
+				err = r.ctx.Err()
+			}
+			if err == nil && r.verifier != nil {
+				err = r.verifier.Verify()
+			}
+			if err != nil {
+				r.err = &EndpointError{Endpoint: r.next - 1, Rows: r.rows, Err: err}
+			}
+			r.closeCurrent()
+			continue
+		}
+
+		if r.next >= len(r.src.endpoints) {
+			return false
+		}
+
+		if r.err = r.openNext(); r.err != nil {
+			return false
+		}
+
+		if !r.cur.Schema().Equal(r.schema) {
+			r.err = schemaMismatch(r.next-1, r.schema, r.cur.Schema())
+			r.closeCurrent()
+		}
+	}
+	return false
+}
+
+func (r *flightInfoReader) closeCurrent() {
+	if r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+	if r.curDone != nil {
+		r.curDone()
+		r.curDone = nil
+	}
+}
+
+// openNext opens the stream for the next endpoint.
+func (r *flightInfoReader) openNext() (err error) {
+	r.cur, r.curDone, err = r.src.open(r.ctx, r.next)
+	r.verifier = r.src.verifier(r.next)
+	r.next++
+	return
+}
+
+// concurrentFlightInfoReader fetches several endpoints of a FlightInfo at
+// once, each in its own goroutine.
+//
+// In ordered mode each endpoint has its own queue which is read in turn,
+// and an endpoint keeps its slot until it has been read entirely so that
+// endpoints which finished early can't buffer records without bound.
+// In unordered mode all endpoints share a single queue and release their
+// slot as soon as they are done.
+type concurrentFlightInfoReader struct {
+	refCount int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	src    *endpointSource
+	schema *arrow.Schema
+
+	slots   chan struct{}
+	ordered bool
+	queues  []chan StreamChunk
+	out     chan StreamChunk
+	cur     int
+	// done is closed once all fetching goroutines have exited
+	done chan struct{}
+
+	mu       sync.Mutex
+	fetchErr error
+
+	// rows is the number of rows returned so far
+	rows  int64
+	chunk StreamChunk
+	err   error
+}
+
+func newConcurrentFlightInfoReader(ctx context.Context, src *endpointSource) (*concurrentFlightInfoReader, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	// open the first endpoint up front so that the reader has a schema
+	// and so that failing to connect at all is reported immediately
+	first, firstDone, err := src.open(ctx, 0)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	queueSize := defaultEndpointQueueSize
+	if src.cfg.maxBuffered > 0 {
+		if queueSize = src.cfg.maxBuffered / src.cfg.concurrency; queueSize < 1 {
+			queueSize = 1
+		}
+	}
+
+	r := &concurrentFlightInfoReader{
+		refCount: 1,
+		ctx:      ctx,
+		cancel:   cancel,
+		src:      src,
+		schema:   first.Schema(),
+		slots:    make(chan struct{}, src.cfg.concurrency),
+		ordered:  !src.cfg.unordered,
+		done:     make(chan struct{}),
+	}
+
+	if r.ordered {
+		r.queues = make([]chan StreamChunk, len(src.endpoints))
+		for i := range r.queues {
+			r.queues[i] = make(chan StreamChunk, queueSize)
+		}
+	} else {
+		r.out = make(chan StreamChunk, queueSize*src.cfg.concurrency)
+	}
+
+	r.slots <- struct{}{}
+	go r.dispatch(first, firstDone)
+	return r, nil
+}
+
+// dispatch starts fetching each endpoint in order as slots become free.
+func (r *concurrentFlightInfoReader) dispatch(first *Reader, firstDone func()) {
+	var wg sync.WaitGroup
+	defer func() {
+		wg.Wait()
+		if !r.ordered {
+			close(r.out)
+		}
+		close(r.done)
+	}()
+
+	wg.Add(1)
+	go r.fetch(&wg, 0, first, firstDone)
+	for i := 1; i < len(r.src.endpoints); i++ {
+		select {
+		case r.slots <- struct{}{}:
+		case <-r.ctx.Done():
+			return
+		}
+
+		wg.Add(1)
+		go r.fetch(&wg, i, nil, nil)
+	}
+}
+
+// fetch reads the endpoint at index idx into its queue, opening it first
+// unless rdr has already been opened.
+func (r *concurrentFlightInfoReader) fetch(wg *sync.WaitGroup, idx int, rdr *Reader, done func()) {
+	defer wg.Done()
+
+	queue := r.out
+	if r.ordered {
+		queue = r.queues[idx]
+		defer close(queue)
+	} else {
+		defer func() { <-r.slots }()
+	}
+
+	if rdr == nil {
+		var err error
+		if rdr, done, err = r.src.open(r.ctx, idx); err != nil {
+			r.fail(err)
+			return
+		}
+	}
+	defer func() {
+		rdr.Release()
+		done()
+	}()
+
+	if !rdr.Schema().Equal(r.schema) {
+		r.fail(schemaMismatch(idx, r.schema, rdr.Schema()))
+		return
+	}
+
+	verifier := r.src.verifier(idx)
+	for rdr.Next() {
+		chunk := rdr.Chunk()
+		if verifier != nil {
+			verifier.Add(chunk)
+		}
+		chunk.Data.Retain()
+		select {
+		case queue <- chunk:
+		case <-r.ctx.Done():
+			chunk.Data.Release()
+			return
+		}
+	}
+
+	err := rdr.Err()
+	if err == nil && verifier != nil {
+		err = verifier.Verify()
+	}
+	if err != nil {
+		r.fail(&EndpointError{Endpoint: idx, Err: err})
+	}
+}
+
+// fail records the first error from any endpoint and stops the others.
+// Errors caused by the context being canceled are not recorded, the
+// context's error is reported instead.
+func (r *concurrentFlightInfoReader) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fetchErr == nil && r.ctx.Err() == nil {
+		r.fetchErr = err
+		r.cancel()
+	}
+}
+
+// fetchError returns the error reading failed with, with the number of
+// rows returned so far if it is an *EndpointError.
+func (r *concurrentFlightInfoReader) fetchError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fetchErr == nil {
+		return r.ctx.Err()
+	}
+	var epErr *EndpointError
+	if errors.As(r.fetchErr, &epErr) {
+		epErr.Rows = r.rows
+	}
+	return r.fetchErr
+}
+
+func (r *concurrentFlightInfoReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+func (r *concurrentFlightInfoReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) != 0 {
+		return
+	}
+
+	r.cancel()
+	<-r.done
+	if r.chunk.Data != nil {
+		r.chunk.Data.Release()
+		r.chunk = StreamChunk{}
+	}
+
+	drain := func(q chan StreamChunk) {
+		for {
+			select {
+			case chunk, ok := <-q:
+				if !ok {
+					return
+				}
+				chunk.Data.Release()
+			default:
+				return
+			}
+		}
+	}
+	if r.ordered {
+		for _, q := range r.queues[r.cur:] {
+			drain(q)
+		}
+	} else {
+		drain(r.out)
+	}
+}
+
+func (r *concurrentFlightInfoReader) Schema() *arrow.Schema { return r.schema }
+
+func (r *concurrentFlightInfoReader) Err() error { return r.err }
+
+func (r *concurrentFlightInfoReader) Record() arrow.Record { return r.chunk.Data }
+
+// Chunk returns the current record along with the app metadata and
+// descriptor of the message it was received in.
+func (r *concurrentFlightInfoReader) Chunk() StreamChunk { return r.chunk }
+
+func (r *concurrentFlightInfoReader) LatestAppMetadata() []byte { return r.chunk.AppMetadata }
+
+func (r *concurrentFlightInfoReader) LatestFlightDescriptor() *FlightDescriptor {
+	return r.chunk.Desc
+}
+
+func (r *concurrentFlightInfoReader) Read() (arrow.Record, error) { return readNext(r) }
+
+func (r *concurrentFlightInfoReader) Next() bool {
+	if r.chunk.Data != nil {
+		r.chunk.Data.Release()
+		r.chunk = StreamChunk{}
+	}
+
+	queue := r.out
+	for r.err == nil {
+		if r.ordered {
+			if r.cur >= len(r.queues) {
+				return false
+			}
+			queue = r.queues[r.cur]
+		}
+
+		select {
+		case chunk, ok := <-queue:
+			if ok {
+				r.chunk = chunk
+				r.rows += chunk.Data.NumRows()
+				return true
+			}
+			if r.ordered {
+				// the endpoint has been read entirely, let the next one start
+				<-r.slots
+				r.cur++
+			}
+			if r.err = r.fetchError(); r.err == nil && !r.ordered {
+				return false
+			}
+		case <-r.ctx.Done():
+			r.err = r.fetchError()
+		}
+	}
+	return false
+}
@@ -19,7 +19,9 @@ package flight_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 	sync "sync"
 	"testing"
 
@@ -28,8 +30,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type ServerMiddlewareAddHeader struct {
@@ -187,6 +191,84 @@ func TestServerUnaryMiddleware(t *testing.T) {
 	}
 }
 
+// readOnlyMiddleware rejects DoPut calls and records the other calls
+// along with their descriptor or ticket.
+type readOnlyMiddleware struct {
+	mx        sync.Mutex
+	calls     []string
+	completed []string
+}
+
+type methodKey struct{}
+
+func (m *readOnlyMiddleware) StartCall(ctx context.Context, method flight.FlightMethod, desc *flight.FlightDescriptor, ticket *flight.Ticket) (context.Context, error) {
+	if method == flight.FlightMethodDoPut {
+		return nil, status.Errorf(codes.PermissionDenied, "%s is read-only", strings.Join(desc.GetPath(), "/"))
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.calls = append(m.calls, fmt.Sprintf("%s %v %s", method, desc.GetPath(), ticket.GetTicket()))
+	return context.WithValue(ctx, methodKey{}, method), nil
+}
+
+func (m *readOnlyMiddleware) CallCompleted(ctx context.Context, method flight.FlightMethod, err error) {
+	if ctx.Value(methodKey{}) != method {
+		panic("missing value from context in method middleware test")
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.completed = append(m.completed, fmt.Sprintf("%s %s", method, status.Code(err)))
+}
+
+func TestServerMethodMiddleware(t *testing.T) {
+	mw := &readOnlyMiddleware{}
+	s := flight.NewServerWithMiddleware([]flight.ServerMiddleware{
+		flight.CreateServerMiddleware(ServerTraceMiddleware{}),
+		flight.CreateMethodServerMiddleware(mw),
+	})
+	s.Init("localhost:0")
+	f := &flightServer{}
+	s.RegisterFlightService(f)
+
+	go s.Serve()
+	defer s.Shutdown()
+
+	client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := context.Background()
+	_, err = client.GetSchema(ctx, &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"primitives"}})
+	require.NoError(t, err)
+
+	// the handler still receives the ticket read by the middleware
+	stream, err := client.DoGet(ctx, &flight.Ticket{Ticket: []byte("primitives")})
+	require.NoError(t, err)
+	rdr, err := flight.NewRecordReader(stream)
+	require.NoError(t, err)
+	var n int
+	for rdr.Next() {
+		n++
+	}
+	require.NoError(t, rdr.Err())
+	rdr.Release()
+	assert.Equal(t, len(arrdata.Records["primitives"]), n)
+
+	put, err := client.DoPut(ctx)
+	require.NoError(t, err)
+	require.NoError(t, put.Send(&flight.FlightData{FlightDescriptor: &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"primitives"}}}))
+	_, err = put.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.ErrorContains(t, err, "primitives is read-only")
+
+	mw.mx.Lock()
+	defer mw.mx.Unlock()
+	assert.Equal(t, []string{"GetSchema [primitives] ", "DoGet [] primitives"}, mw.calls)
+	assert.Equal(t, []string{"GetSchema OK", "DoGet OK"}, mw.completed)
+}
+
 type ClientTestSendHeaderMiddleware struct {
 	ctx context.Context
 	md  metadata.MD
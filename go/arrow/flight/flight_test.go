@@ -17,24 +17,38 @@
 package flight_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/apache/arrow/go/v16/arrow"
 	"github.com/apache/arrow/go/v16/arrow/array"
 	"github.com/apache/arrow/go/v16/arrow/flight"
 	"github.com/apache/arrow/go/v16/arrow/internal/arrdata"
 	"github.com/apache/arrow/go/v16/arrow/ipc"
 	"github.com/apache/arrow/go/v16/arrow/memory"
+	"go.uber.org/goleak"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type flightServer struct {
@@ -449,3 +463,1349 @@ func TestReaderError(t *testing.T) {
 		t.Fatal("should have errored")
 	}
 }
+
+func TestSessionOptionValueToAny(t *testing.T) {
+	for _, v := range []any{"main", true, int64(-1), 2.5, []string{"a", "b"}, nil} {
+		val, err := flight.NewSessionOptionValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := flight.SessionOptionValueToAny(&val); !reflect.DeepEqual(got, v) {
+			t.Errorf("SessionOptionValueToAny(NewSessionOptionValue(%#v)) = %#v", v, got)
+		}
+	}
+}
+
+// errReader fails with err once the records of its reader are read.
+type errReader struct {
+	array.RecordReader
+	err error
+}
+
+func (r *errReader) Err() error { return r.err }
+
+// int64Reader returns a reader of n records of one row each.
+func int64Reader(t *testing.T, mem memory.Allocator, n int) array.RecordReader {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	bldr := array.NewRecordBuilder(mem, schema)
+	defer bldr.Release()
+
+	recs := make([]arrow.Record, n)
+	for i := range recs {
+		bldr.Field(0).(*array.Int64Builder).Append(int64(i))
+		recs[i] = bldr.NewRecord()
+		defer recs[i].Release()
+	}
+	rdr, err := array.NewRecordReader(schema, recs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rdr
+}
+
+func TestStreamChunksFromReaderCtx(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	// the consumer abandons the channel after the first chunk
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan flight.StreamChunk)
+	go flight.StreamChunksFromReaderCtx(ctx, int64Reader(t, mem, 10), ch)
+
+	chunk := <-ch
+	if chunk.Err != nil {
+		t.Fatal(chunk.Err)
+	}
+	chunk.Data.Release()
+	cancel()
+
+	// no more than the record pending when cancelled is sent before the
+	// channel is closed
+	var sent int
+	for chunk := range ch {
+		if chunk.Data != nil {
+			chunk.Data.Release()
+		}
+		sent++
+	}
+	if sent > 1 {
+		t.Errorf("%d records sent after cancellation", sent)
+	}
+
+	// errors of the reader are sent as the last chunk
+	readErr := errors.New("read failed")
+	ch = make(chan flight.StreamChunk)
+	go flight.StreamChunksFromReaderCtx(context.Background(), &errReader{int64Reader(t, mem, 2), readErr}, ch)
+
+	var got []flight.StreamChunk
+	for chunk := range ch {
+		got = append(got, chunk)
+		if chunk.Data != nil {
+			chunk.Data.Release()
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d chunks, expected 3", len(got))
+	}
+	if got[0].Data == nil || got[1].Data == nil {
+		t.Errorf("expected two records before the error, got %v", got)
+	}
+	if !errors.Is(got[2].Err, readErr) {
+		t.Errorf("expected the error of the reader, got %v", got[2].Err)
+	}
+}
+
+// exchangeEchoServer sends back each record and metadata of an exchange,
+// after the path of its descriptor and before the number of records.
+type exchangeEchoServer struct {
+	flight.BaseFlightServer
+}
+
+func (f *exchangeEchoServer) DoExchange(stream flight.FlightService_DoExchangeServer) error {
+	rdr, wr, err := flight.NewExchangeReaderWriter(stream)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	if err := wr.WriteMetadata([]byte(strings.Join(rdr.LatestFlightDescriptor().GetPath(), "/"))); err != nil {
+		return err
+	}
+
+	var n int
+	for rdr.Next() {
+		if rec := rdr.Record(); rec != nil {
+			n++
+			err = wr.WriteWithAppMetadata(rec, rdr.LatestAppMetadata())
+		} else {
+			err = wr.WriteMetadata(rdr.LatestAppMetadata())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := rdr.Err(); err != nil {
+		return err
+	}
+
+	if err := wr.WriteMetadata([]byte(fmt.Sprintf("%d records", n))); err != nil {
+		return err
+	}
+	return wr.Close()
+}
+
+func TestDoExchangeRecords(t *testing.T) {
+	s := flight.NewFlightServer()
+	s.RegisterFlightService(&exchangeEchoServer{})
+	s.Init("localhost:0")
+
+	go s.Serve()
+	defer s.Shutdown()
+
+	client, err := flight.NewFlightClient(s.Addr().String(), nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"echo"}}
+	wr, rdr, err := client.DoExchangeRecords(context.Background(), desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Release()
+
+	next := func(wantRecord bool, wantMeta string) arrow.Record {
+		t.Helper()
+		if !rdr.Next() {
+			t.Fatalf("exchange ended before %q: %v", wantMeta, rdr.Err())
+		}
+		rec := rdr.Record()
+		if (rec != nil) != wantRecord {
+			t.Fatalf("got record %v with %q, expected one: %t", rec, rdr.LatestAppMetadata(), wantRecord)
+		}
+		if got := string(rdr.LatestAppMetadata()); got != wantMeta {
+			t.Fatalf("got metadata %q, expected %q", got, wantMeta)
+		}
+		return rec
+	}
+
+	// the descriptor is received before anything was written
+	next(false, "echo")
+	// metadata goes both ways before any schema was sent
+	if err := wr.WriteMetadata([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	next(false, "ping")
+
+	// the records are read back while they are written
+	const n = 10
+	recs := int64Reader(t, mem, n)
+	errs := make(chan error, 1)
+	go func() {
+		defer recs.Release()
+		var err error
+		for i := 0; err == nil && recs.Next(); i++ {
+			err = wr.WriteWithAppMetadata(recs.Record(), []byte(strconv.Itoa(i)))
+		}
+		if err == nil {
+			err = wr.WriteMetadata([]byte("end"))
+		}
+		if closeErr := wr.Close(); err == nil {
+			err = closeErr
+		}
+		errs <- err
+	}()
+
+	for i := 0; i < n; i++ {
+		rec := next(true, strconv.Itoa(i))
+		if got := rec.Column(0).(*array.Int64).Value(0); got != int64(i) {
+			t.Errorf("got record %d, expected %d", got, i)
+		}
+	}
+	next(false, "end")
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	// the results are read once done writing
+	next(false, fmt.Sprintf("%d records", n))
+	if rdr.Next() {
+		t.Fatalf("expected the end of the exchange, got %v", rdr.Record())
+	}
+	if err := rdr.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type rowCountServer struct {
+	flight.BaseFlightServer
+}
+
+func (*rowCountServer) DoPut(stream flight.FlightService_DoPutServer) error {
+	rdr, err := flight.NewRecordReader(stream)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	var rows int64
+	for rdr.Next() {
+		rows += rdr.Record().NumRows()
+	}
+	if err := rdr.Err(); err != nil {
+		return err
+	}
+	return stream.Send(&flight.PutResult{AppMetadata: []byte(strconv.FormatInt(rows, 10))})
+}
+
+func TestServerMaxRecvMsgSize(t *testing.T) {
+	// 16MB, above the 4MB limit of gRPC servers by default
+	const rows = 2 << 20
+	bldr := array.NewInt64Builder(memory.DefaultAllocator)
+	defer bldr.Release()
+	bldr.AppendValues(make([]int64, rows), nil)
+	arr := bldr.NewArray()
+	defer arr.Release()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec := array.NewRecord(schema, []arrow.Array{arr}, rows)
+	defer rec.Release()
+
+	put := func(opts ...grpc.ServerOption) (string, error) {
+		s := flight.NewServerWithMiddleware(nil, opts...)
+		s.Init("localhost:0")
+		s.RegisterFlightService(&rowCountServer{})
+		go s.Serve()
+		defer s.Shutdown()
+
+		client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+
+		stream, err := client.DoPut(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		wr := flight.NewRecordWriter(stream, ipc.WithSchema(schema))
+		wr.SetFlightDescriptor(&flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"a"}})
+		if err := wr.Write(rec); err != nil && !errors.Is(err, io.EOF) {
+			t.Fatal(err)
+		}
+		wr.Close()
+		stream.CloseSend()
+
+		res, err := stream.Recv()
+		if err != nil {
+			return "", err
+		}
+		return string(res.AppMetadata), nil
+	}
+
+	got, err := put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != strconv.Itoa(rows) {
+		t.Fatalf("expected %d rows, got %s", rows, got)
+	}
+
+	// explicit options override the defaults
+	_, err = put(grpc.MaxRecvMsgSize(1 << 20))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+}
+
+// endpointServer serves tickets "records:rows" with that many records of
+// rows rows each, "fail:records" failing after that many records of one
+// row, "endless" which sends records until the call is canceled, and
+// "other" with another schema.
+type endpointServer struct {
+	flight.BaseFlightServer
+	// active is the number of DoGet calls in progress, served that of
+	// all DoGet calls
+	active, served atomic.Int32
+	// delay is waited before serving each call, and all calls fail as
+	// unavailable while down is set
+	delay time.Duration
+	down  atomic.Bool
+}
+
+var endpointSchema = arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+func (s *endpointServer) DoGet(tkt *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	s.served.Add(1)
+	s.active.Add(1)
+	defer s.active.Add(-1)
+
+	time.Sleep(s.delay)
+	if s.down.Load() {
+		return status.Error(codes.Unavailable, "replica is down")
+	}
+
+	schema, kind, arg := endpointSchema, string(tkt.Ticket), ""
+	if k, a, ok := strings.Cut(kind, ":"); ok {
+		kind, arg = k, a
+	}
+	if kind == "other" {
+		schema = arrow.NewSchema([]arrow.Field{{Name: "b", Type: arrow.BinaryTypes.String}}, nil)
+	}
+
+	wr := flight.NewRecordWriter(stream, ipc.WithSchema(schema))
+	defer wr.Close()
+
+	write := func(rows int) error {
+		bldr := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+		defer bldr.Release()
+		for i := 0; i < rows; i++ {
+			if b, ok := bldr.Field(0).(*array.Int64Builder); ok {
+				b.Append(int64(i))
+			} else {
+				bldr.Field(0).(*array.StringBuilder).Append("x")
+			}
+		}
+		rec := bldr.NewRecord()
+		defer rec.Release()
+		return wr.Write(rec)
+	}
+
+	switch kind {
+	case "fail":
+		n, _ := strconv.Atoi(arg)
+		for i := 0; i < n; i++ {
+			if err := write(1); err != nil {
+				return err
+			}
+		}
+		return status.Error(codes.Internal, "endpoint broke")
+	case "endless":
+		for stream.Context().Err() == nil {
+			if err := write(1); err != nil {
+				return err
+			}
+		}
+		return stream.Context().Err()
+	case "other":
+		return write(1)
+	default:
+		n, _ := strconv.Atoi(kind)
+		rows, _ := strconv.Atoi(arg)
+		for i := 0; i < n; i++ {
+			if err := write(rows); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func endpointInfo(tickets ...string) *flight.FlightInfo {
+	info := &flight.FlightInfo{Schema: flight.SerializeSchema(endpointSchema, memory.DefaultAllocator)}
+	for _, tkt := range tickets {
+		info.Endpoint = append(info.Endpoint, &flight.FlightEndpoint{Ticket: &flight.Ticket{Ticket: []byte(tkt)}})
+	}
+	return info
+}
+
+func startEndpointServer(t testing.TB) (*endpointServer, flight.Client) {
+	srv := &endpointServer{}
+	s := flight.NewServerWithMiddleware(nil)
+	s.Init("localhost:0")
+	s.RegisterFlightService(srv)
+	go s.Serve()
+	t.Cleanup(s.Shutdown)
+
+	client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return srv, client
+}
+
+// readRows returns the number of rows of each record of rdr.
+func readRows(rdr flight.MessageReader) ([]int64, error) {
+	var rows []int64
+	for rdr.Next() {
+		rows = append(rows, rdr.Record().NumRows())
+	}
+	return rows, rdr.Err()
+}
+
+func TestFlightInfoReader(t *testing.T) {
+	srv, client := startEndpointServer(t)
+	ctx := context.Background()
+
+	modes := []struct {
+		name string
+		opts []grpc.CallOption
+	}{
+		{"sequential", nil},
+		{"concurrent", []grpc.CallOption{flight.WithEndpointConcurrency(2)}},
+		{"unordered", []grpc.CallOption{flight.WithEndpointConcurrency(2), flight.WithUnorderedEndpoints()}},
+	}
+	for _, mode := range modes {
+		t.Run(mode.name, func(t *testing.T) {
+			// endpoints without records are skipped
+			rdr, err := flight.NewFlightInfoReader(ctx, client, endpointInfo("2:3", "0:0", "1:5"), mode.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := readRows(rdr)
+			rdr.Release()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var total int64
+			for _, n := range rows {
+				total += n
+			}
+			if len(rows) != 3 || total != 11 {
+				t.Fatalf("unexpected records %v", rows)
+			}
+			if mode.name != "unordered" && !reflect.DeepEqual(rows, []int64{3, 3, 5}) {
+				t.Fatalf("records out of order: %v", rows)
+			}
+
+			// failing midway reports the rows delivered
+			rdr, err = flight.NewFlightInfoReader(ctx, client, endpointInfo("2:1", "fail:3"), append(mode.opts, flight.WithMaxBufferedRecords(2))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = readRows(rdr)
+			rdr.Release()
+			var epErr *flight.EndpointError
+			if !errors.As(err, &epErr) || epErr.Endpoint != 1 || status.Code(err) != codes.Internal {
+				t.Fatalf("expected an error of endpoint 1, got %v", err)
+			}
+			if epErr.Rows > 5 {
+				t.Fatalf("reported %d rows, only 5 were sent", epErr.Rows)
+			}
+
+			// schemas must match
+			rdr, err = flight.NewFlightInfoReader(ctx, client, endpointInfo("1:1", "other"), mode.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = readRows(rdr)
+			rdr.Release()
+			if !errors.Is(err, arrow.ErrInvalid) || !strings.Contains(err.Error(), "schema of endpoint 1") {
+				t.Fatalf("expected a schema mismatch, got %v", err)
+			}
+
+			// canceling the context fails the reader
+			cctx, cancel := context.WithCancel(ctx)
+			rdr, err = flight.NewFlightInfoReader(cctx, client, endpointInfo("endless", "endless"), mode.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !rdr.Next() {
+				t.Fatal(rdr.Err())
+			}
+			cancel()
+			for rdr.Next() {
+			}
+			rdr.Release()
+			if !errors.Is(rdr.Err(), context.Canceled) {
+				t.Fatalf("expected context canceled, got %v", rdr.Err())
+			}
+
+			// releasing the reader early ends the streams
+			rdr, err = flight.NewFlightInfoReader(ctx, client, endpointInfo("endless", "endless", "endless"), mode.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !rdr.Next() {
+				t.Fatal(rdr.Err())
+			}
+			rdr.Release()
+			for i := 0; srv.active.Load() != 0; i++ {
+				if i == 100 {
+					t.Fatalf("%d streams still open", srv.active.Load())
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestFlightInfoReaderLocations(t *testing.T) {
+	_, client := startEndpointServer(t)
+	other := &endpointServer{}
+	s := flight.NewServerWithMiddleware(nil)
+	s.Init("localhost:0")
+	s.RegisterFlightService(other)
+	go s.Serve()
+	defer s.Shutdown()
+
+	// the bogus location is skipped for the other server
+	info := endpointInfo("1:2", "1:3")
+	info.Endpoint[1].Location = []*flight.Location{
+		{Uri: "grpc+bogus://nowhere:1234"},
+		{Uri: "grpc://" + s.Addr().String()},
+	}
+	rdr, err := flight.NewFlightInfoReader(context.Background(), client, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Release()
+	if !rdr.Next() || rdr.Record().NumRows() != 2 {
+		t.Fatal("expected the record of endpoint 0", rdr.Err())
+	}
+	if !rdr.Next() || rdr.Record().NumRows() != 3 || other.served.Load() != 1 {
+		t.Fatal("expected the record of endpoint 1 from the other server", rdr.Err())
+	}
+	if rdr.Next() || rdr.Err() != nil {
+		t.Fatal("unexpected end of results", rdr.Err())
+	}
+
+	// without endpoints, the schema is that of the FlightInfo
+	rdr, err = flight.NewFlightInfoReader(context.Background(), client, endpointInfo())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Release()
+	if !rdr.Schema().Equal(endpointSchema) || rdr.Next() {
+		t.Fatal("expected an empty result")
+	}
+}
+
+// dataStream records the FlightData sent to it, which it then receives.
+type dataStream struct {
+	msgs []*flight.FlightData
+}
+
+func (s *dataStream) Send(fd *flight.FlightData) error {
+	s.msgs = append(s.msgs, proto.Clone(fd).(*flight.FlightData))
+	return nil
+}
+
+func (s *dataStream) Recv() (*flight.FlightData, error) {
+	if len(s.msgs) == 0 {
+		return nil, io.EOF
+	}
+	fd := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return fd, nil
+}
+
+func TestWriteMetadata(t *testing.T) {
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, endpointSchema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	rec := bldr.NewRecord()
+	defer rec.Release()
+
+	stream := &dataStream{}
+	wr := flight.NewRecordWriter(stream, ipc.WithSchema(endpointSchema))
+	for _, write := range []func() error{
+		func() error { return wr.WriteMetadata([]byte("start")) },
+		func() error { return wr.Write(rec) },
+		func() error { return wr.WriteMetadata([]byte("progress")) },
+		func() error { return wr.WriteMetadata([]byte("more progress")) },
+		func() error { return wr.Write(rec) },
+		func() error { return wr.WriteMetadata([]byte("end")) },
+		wr.Close,
+	} {
+		if err := write(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the metadata-only messages are counted along with the ipc messages
+	stats := wr.Stats()
+	if stats.Messages != 7 || stats.RecordBatches != 2 || stats.BodyBytes != 2*16 {
+		t.Fatalf("unexpected writer stats %+v", stats)
+	}
+
+	// the message only holds the app_metadata field, which other
+	// implementations read as a message without record
+	golden := []byte{0x1a, 0x05, 's', 't', 'a', 'r', 't'}
+	if b, err := proto.Marshal(stream.msgs[0]); err != nil || !bytes.Equal(b, golden) {
+		t.Fatalf("unexpected encoding %x of the metadata-only message: %v", b, err)
+	}
+
+	rdr, err := flight.NewRecordReader(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Release()
+	for _, expected := range [][]string{{"start"}, {"progress", "more progress"}} {
+		if !rdr.Next() {
+			t.Fatal("expected a record", rdr.Err())
+		}
+		if rdr.Record().NumRows() != 2 {
+			t.Fatalf("unexpected record of %d rows", rdr.Record().NumRows())
+		}
+		if got := rdr.LatestMetadataMessages(); !reflect.DeepEqual(got, toBytes(expected)) {
+			t.Fatalf("got metadata %q, expected %q", got, expected)
+		}
+	}
+	if rdr.Next() || rdr.Err() != nil {
+		t.Fatal("expected the end of the stream", rdr.Err())
+	}
+	if got := rdr.LatestMetadataMessages(); !reflect.DeepEqual(got, [][]byte{[]byte("end")}) {
+		t.Fatalf("got metadata %q at the end of the stream", got)
+	}
+}
+
+func TestReaderNextMessage(t *testing.T) {
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, endpointSchema)
+	defer bldr.Release()
+	record := func(rows int) arrow.Record {
+		bldr.Field(0).(*array.Int64Builder).AppendValues(make([]int64, rows), nil)
+		return bldr.NewRecord()
+	}
+	desc := func(path string) *flight.FlightDescriptor {
+		return &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{path}}
+	}
+
+	// the messages expected, a record of rows rows or only app metadata
+	// if rows is 0
+	type message struct {
+		rows int64
+		md   string
+		path string
+	}
+	expected := []message{
+		{md: "start", path: "a"},
+		{rows: 1, md: "first"},
+		{md: "progress"},
+		{md: "more progress", path: "b"},
+		{rows: 2, md: "second", path: "c"},
+		{rows: 3},
+		{md: "end"},
+	}
+
+	for _, exchange := range []bool{false, true} {
+		t.Run(fmt.Sprintf("exchange=%t", exchange), func(t *testing.T) {
+			stream := &dataStream{}
+			wr := flight.NewRecordWriter(stream, ipc.WithSchema(endpointSchema))
+			for i, m := range expected {
+				// the descriptor of the first record would be sent with
+				// the schema
+				if m.path != "" {
+					wr.SetFlightDescriptor(desc(m.path))
+				}
+				var err error
+				if m.rows == 0 {
+					err = wr.WriteMetadata([]byte(m.md))
+				} else {
+					rec := record(int(m.rows))
+					if m.md != "" {
+						err = wr.WriteWithAppMetadata(rec, []byte(m.md))
+					} else {
+						err = wr.Write(rec)
+					}
+					rec.Release()
+				}
+				if err != nil {
+					t.Fatal(i, err)
+				}
+			}
+			if err := wr.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			var (
+				rdr *flight.Reader
+				err error
+			)
+			if exchange {
+				rdr, err = flight.NewExchangeReader(stream)
+			} else {
+				rdr, err = flight.NewRecordReader(stream)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rdr.Release()
+
+			var got []message
+			for {
+				msg, err := rdr.NextMessage()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				var m message
+				if msg.Record != nil {
+					m.rows = msg.Record.NumRows()
+				}
+				m.md = string(msg.AppMetadata)
+				if msg.Desc != nil {
+					m.path = msg.Desc.Path[0]
+				}
+				got = append(got, m)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Fatalf("got messages %+v, expected %+v", got, expected)
+			}
+			if _, err := rdr.NextMessage(); err != io.EOF {
+				t.Fatalf("expected io.EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func toBytes(strs []string) [][]byte {
+	out := make([][]byte, len(strs))
+	for i, s := range strs {
+		out[i] = []byte(s)
+	}
+	return out
+}
+
+func TestLocations(t *testing.T) {
+	tests := []struct {
+		loc                     *flight.Location
+		scheme, authority, path string
+	}{
+		{flight.NewLocationTCP("localhost", 1234, false), flight.LocationSchemeTCP, "localhost:1234", ""},
+		{flight.NewLocationTCP("::1", 1234, false), flight.LocationSchemeTCP, "[::1]:1234", ""},
+		{flight.NewLocationTCP("example.com", 443, true), flight.LocationSchemeTLS, "example.com:443", ""},
+		{&flight.Location{Uri: "grpc://localhost:1234"}, flight.LocationSchemeGRPC, "localhost:1234", ""},
+		{&flight.Location{Uri: "grpc+unix:///tmp/flight.sock"}, flight.LocationSchemeUnix, "", "/tmp/flight.sock"},
+		{&flight.Location{Uri: "grpc+unix:///tmp/my%20flight%25.sock"}, flight.LocationSchemeUnix, "", "/tmp/my flight%.sock"},
+		{flight.NewLocationReuseConnection(), flight.LocationSchemeReuseConnection, "", ""},
+		{&flight.Location{Uri: "https://example.com/data/1"}, "https", "example.com", "/data/1"},
+	}
+	for _, tt := range tests {
+		scheme, authority, path, err := flight.ParseLocation(tt.loc.Uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scheme != tt.scheme || authority != tt.authority || path != tt.path {
+			t.Errorf("%s: got %q %q %q, expected %q %q %q", tt.loc.Uri, scheme, authority, path, tt.scheme, tt.authority, tt.path)
+		}
+	}
+
+	for _, uri := range []string{
+		"", "%", "localhost:1234", "grpc+tcp:localhost", "grpc+tcp:///path", "grpc+tcp://localhost",
+		"grpc+tcp://:1234", "grpc+tls://[::1:1234", "grpc+tcp://localhost:http", "grpc+tcp://localhost:65536",
+		"grpc+unix://", "grpc+unix://host/flight.sock", "grpc+unix:flight.sock",
+	} {
+		if _, _, _, err := flight.ParseLocation(uri); !errors.Is(err, arrow.ErrInvalid) {
+			t.Errorf("%q: expected an invalid location, got %v", uri, err)
+		}
+		if _, err := flight.NewFlightEndpoint(&flight.Ticket{}, "grpc://localhost:1234", uri); !errors.Is(err, arrow.ErrInvalid) {
+			t.Errorf("%q: expected an invalid endpoint, got %v", uri, err)
+		}
+	}
+	ep, err := flight.NewFlightEndpoint(&flight.Ticket{}, "grpc://localhost:1234", flight.LocationReuseConnection)
+	if err != nil || len(ep.Location) != 2 {
+		t.Fatalf("unexpected endpoint %v: %v", ep, err)
+	}
+	if _, err := flight.DialLocation(context.Background(), &flight.Location{Uri: "grpc+bogus://nowhere:1234"}, nil); !errors.Is(err, arrow.ErrNotImplemented) {
+		t.Errorf("expected an unsupported scheme, got %v", err)
+	}
+
+	// the path of unix locations round-trips whatever its characters
+	for _, path := range []string{"/tmp/flight.sock", "/tmp/my flight%.sock", "/tmp/ü?#.sock"} {
+		loc, err := flight.NewLocationUnix(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, got, err := flight.ParseLocation(loc.Uri); err != nil || got != path {
+			t.Errorf("%s: got path %q: %v", loc.Uri, got, err)
+		}
+	}
+
+	loc, err := flight.NewLocationUnix("/tmp/flight.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc.Uri != "grpc+unix:///tmp/flight.sock" {
+		t.Errorf("unexpected unix location %s", loc.Uri)
+	}
+	loc, err = flight.LocationForAddr(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc.Uri != "grpc+tcp://127.0.0.1:1234" {
+		t.Errorf("unexpected tcp location %s", loc.Uri)
+	}
+}
+
+func TestUnixSocketServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flight %1.sock")
+	srv := &endpointServer{}
+	s := flight.NewServerWithMiddleware(nil)
+	if err := s.InitUnix(path); err != nil {
+		t.Fatal(err)
+	}
+	s.RegisterFlightService(srv)
+	go s.Serve()
+
+	// the socket can't be taken over while the server listens on it
+	if err := flight.NewServerWithMiddleware(nil).InitUnix(path); err == nil {
+		t.Fatal("expected the socket in use to be detected")
+	}
+
+	loc, err := flight.LocationForAddr(s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := flight.NewClientWithMiddleware(loc.Uri, nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	stream, err := client.DoGet(context.Background(), &flight.Ticket{Ticket: []byte("2:3")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdr, err := flight.NewRecordReader(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := readRows(rdr)
+	rdr.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rows, []int64{3, 3}) {
+		t.Fatalf("unexpected records %v", rows)
+	}
+
+	s.Shutdown()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("expected the socket file to be removed on shutdown", err)
+	}
+
+	// the socket file of a server which didn't shut down is replaced
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis.(*net.UnixListener).SetUnlinkOnClose(false)
+	lis.Close()
+	s = flight.NewServerWithMiddleware(nil)
+	if err := s.InitUnix(path); err != nil {
+		t.Fatal(err)
+	}
+	s.Shutdown()
+}
+
+func TestFlightInfoReaderUnixLocations(t *testing.T) {
+	dflt, client := startEndpointServer(t)
+
+	tcp := &endpointServer{}
+	ts := flight.NewServerWithMiddleware(nil)
+	ts.Init("localhost:0")
+	ts.RegisterFlightService(tcp)
+	go ts.Serve()
+	defer ts.Shutdown()
+
+	unix := &endpointServer{}
+	us := flight.NewServerWithMiddleware(nil)
+	if err := us.InitUnix(filepath.Join(t.TempDir(), "flight.sock")); err != nil {
+		t.Fatal(err)
+	}
+	us.RegisterFlightService(unix)
+	go us.Serve()
+	defer us.Shutdown()
+
+	tcpLoc, err := flight.LocationForAddr(ts.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	unixLoc, err := flight.LocationForAddr(us.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadUnixLoc, err := flight.NewLocationUnix(filepath.Join(t.TempDir(), "none.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a listener closed right away gives a tcp address nothing listens on
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadTCPLoc, _ := flight.LocationForAddr(lis.Addr())
+	lis.Close()
+
+	// the location which can be dialed is used for each endpoint, whichever
+	// its network
+	info := endpointInfo("1:2", "1:3")
+	info.Endpoint[0].Location = []*flight.Location{deadTCPLoc, unixLoc}
+	info.Endpoint[1].Location = []*flight.Location{deadUnixLoc, tcpLoc}
+	rdr, err := flight.NewFlightInfoReader(context.Background(), client, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Release()
+	rows, err := readRows(rdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rows, []int64{2, 3}) || unix.served.Load() != 1 || tcp.served.Load() != 1 || dflt.served.Load() != 0 {
+		t.Fatalf("unexpected records %v served by %d over unix and %d over tcp", rows, unix.served.Load(), tcp.served.Load())
+	}
+}
+
+// startReplicas starts a server for each of delays, which replicate the
+// same endpoints, returning them with their locations.
+func startReplicas(t *testing.T, delays ...time.Duration) ([]*endpointServer, []*flight.Location) {
+	var (
+		replicas []*endpointServer
+		locs     []*flight.Location
+	)
+	for _, delay := range delays {
+		srv := &endpointServer{delay: delay}
+		s := flight.NewServerWithMiddleware(nil)
+		s.Init("localhost:0")
+		s.RegisterFlightService(srv)
+		go s.Serve()
+		t.Cleanup(s.Shutdown)
+
+		loc, err := flight.LocationForAddr(s.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		replicas = append(replicas, srv)
+		locs = append(locs, loc)
+	}
+	return replicas, locs
+}
+
+// servedBy returns the number of calls served by each of replicas.
+func servedBy(replicas []*endpointServer) []int32 {
+	served := make([]int32, len(replicas))
+	for i, r := range replicas {
+		served[i] = r.served.Load()
+	}
+	return served
+}
+
+func TestLocationPolicies(t *testing.T) {
+	dflt, client := startEndpointServer(t)
+
+	// read reads an endpoint replicated at locs n times with policy
+	read := func(t *testing.T, n int, policy flight.LocationPolicy, locs []*flight.Location) {
+		info := endpointInfo("1:1")
+		info.Endpoint[0].Location = locs
+		for i := 0; i < n; i++ {
+			rdr, err := flight.NewFlightInfoReader(context.Background(), client, info, flight.WithLocationPolicy(policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := readRows(rdr)
+			rdr.Release()
+			if err != nil || !reflect.DeepEqual(rows, []int64{1}) {
+				t.Fatalf("unexpected records %v: %v", rows, err)
+			}
+		}
+	}
+
+	t.Run("round robin", func(t *testing.T) {
+		replicas, locs := startReplicas(t, 0, 0, 0)
+		read(t, 30, flight.NewRoundRobinLocationPolicy(0), locs)
+		if served := servedBy(replicas); !reflect.DeepEqual(served, []int32{10, 10, 10}) {
+			t.Fatalf("expected an even distribution, got %v", served)
+		}
+	})
+
+	t.Run("random", func(t *testing.T) {
+		replicas, locs := startReplicas(t, 0, 0, 0)
+		read(t, 60, flight.NewRandomLocationPolicy(0), locs)
+		served := servedBy(replicas)
+		for _, n := range served {
+			if n == 0 {
+				t.Fatalf("expected each replica to serve some reads, got %v", served)
+			}
+		}
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		// each replica is measured once, the fastest then serving the
+		// others
+		replicas, locs := startReplicas(t, 50*time.Millisecond, 0, 20*time.Millisecond)
+		read(t, 10, flight.NewLatencyLocationPolicy(0), locs)
+		if served := servedBy(replicas); !reflect.DeepEqual(served, []int32{1, 8, 1}) {
+			t.Fatalf("expected the fastest replica to serve most reads, got %v", served)
+		}
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		// the reads starting at the replica which is down fail over to the
+		// next one; without cooldown, it is tried again each time
+		replicas, locs := startReplicas(t, 0, 0, 0)
+		replicas[0].down.Store(true)
+		read(t, 9, flight.NewRoundRobinLocationPolicy(0), locs)
+		if served := servedBy(replicas); !reflect.DeepEqual(served, []int32{3, 6, 3}) {
+			t.Fatalf("unexpected distribution %v", served)
+		}
+
+		// with a cooldown, it is only tried once in the meantime
+		replicas, locs = startReplicas(t, 0, 0, 0)
+		replicas[0].down.Store(true)
+		read(t, 9, flight.NewRoundRobinLocationPolicy(time.Hour), locs)
+		if served := servedBy(replicas); served[0] != 1 || served[1]+served[2] != 9 {
+			t.Fatalf("expected the replica which is down to be tried once, got %v", served)
+		}
+
+		// and again once the cooldown is over
+		policy := flight.NewRoundRobinLocationPolicy(50 * time.Millisecond)
+		replicas, locs = startReplicas(t, 0, 0, 0)
+		replicas[0].down.Store(true)
+		read(t, 4, policy, locs)
+		if served := servedBy(replicas); served[0] != 1 {
+			t.Fatalf("expected the replica which is down to be tried once, got %v", served)
+		}
+		time.Sleep(60 * time.Millisecond)
+		replicas[0].down.Store(false)
+		read(t, 3, policy, locs)
+		if served := servedBy(replicas); served[0] != 2 {
+			t.Fatalf("expected the replica to be tried again after the cooldown, got %v", served)
+		}
+	})
+
+	if dflt.served.Load() != 0 {
+		t.Fatalf("expected no read to fall back to the default server, got %d", dflt.served.Load())
+	}
+}
+
+func BenchmarkFlightInfoReaderSequential(b *testing.B) {
+	_, client := startEndpointServer(b)
+	info := endpointInfo("16:1024", "16:1024", "16:1024", "16:1024")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rdr, err := flight.NewFlightInfoReader(context.Background(), client, info)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var rows int64
+		for rdr.Next() {
+			rows += rdr.Record().NumRows()
+		}
+		if err := rdr.Err(); err != nil {
+			b.Fatal(err)
+		}
+		rdr.Release()
+		if rows != 4*16*1024 {
+			b.Fatalf("read %d rows", rows)
+		}
+	}
+}
+
+// stuckServer writes a record for DoGet and then ignores the cancellation
+// of the call until unblock is closed.
+type stuckServer struct {
+	flight.BaseFlightServer
+	unblock chan struct{}
+}
+
+func (s *stuckServer) DoGet(_ *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	wr := flight.NewRecordWriter(stream, ipc.WithSchema(endpointSchema))
+	defer wr.Close()
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, endpointSchema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).Append(1)
+	rec := bldr.NewRecord()
+	defer rec.Release()
+	if err := wr.Write(rec); err != nil {
+		return err
+	}
+	<-s.unblock
+	return nil
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	start := func(t *testing.T, svc flight.FlightServer) (flight.Server, flight.MessageReader) {
+		s := flight.NewServerWithMiddleware(nil)
+		s.Init("localhost:0")
+		s.RegisterFlightService(svc)
+		go s.Serve()
+
+		client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.Close() })
+
+		stream, err := client.DoGet(context.Background(), &flight.Ticket{Ticket: []byte("endless")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rdr, err := flight.NewRecordReader(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(rdr.Release)
+		if !rdr.Next() {
+			t.Fatal(rdr.Err())
+		}
+		return s, rdr
+	}
+
+	t.Run("drained", func(t *testing.T) {
+		srv := &endpointServer{}
+		s, rdr := start(t, srv)
+
+		// the client keeps reading so that the handler isn't blocked
+		// sending records
+		readErr := make(chan error, 1)
+		go func() {
+			for rdr.Next() {
+			}
+			readErr <- rdr.Err()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if forced := s.GracefulShutdown(ctx); forced != 0 {
+			t.Fatalf("expected no stream to be closed, got %d", forced)
+		}
+		if ctx.Err() != nil {
+			t.Fatal("the shutdown didn't finish before the deadline")
+		}
+		if srv.active.Load() != 0 {
+			t.Fatalf("expected the handler to be done, %d are active", srv.active.Load())
+		}
+		if err := <-readErr; status.Code(err) != codes.Unavailable {
+			t.Fatalf("expected Unavailable, got %v", err)
+		}
+	})
+
+	t.Run("forced", func(t *testing.T) {
+		srv := &stuckServer{unblock: make(chan struct{})}
+		defer close(srv.unblock)
+		s, rdr := start(t, srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		begin := time.Now()
+		if forced := s.GracefulShutdown(ctx); forced != 1 {
+			t.Fatalf("expected a stream to be closed, got %d", forced)
+		}
+		if elapsed := time.Since(begin); elapsed > 2*time.Second {
+			t.Fatalf("the shutdown took %s", elapsed)
+		}
+
+		for rdr.Next() {
+		}
+		if status.Code(rdr.Err()) != codes.Unavailable {
+			t.Fatalf("expected Unavailable, got %v", rdr.Err())
+		}
+	})
+
+	t.Run("unary and repeated", func(t *testing.T) {
+		srv := &stuckServer{unblock: make(chan struct{})}
+		s, _ := start(t, srv)
+		client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		first, second := make(chan int), make(chan int)
+		go func() { first <- s.GracefulShutdown(ctx) }()
+
+		// the unary RPCs of the Flight service are rejected while the
+		// stuck stream holds the shutdown
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			_, err := client.GetFlightInfo(context.Background(), &flight.FlightDescriptor{})
+			if status.Code(err) == codes.Unavailable {
+				break
+			}
+			if status.Code(err) != codes.Unimplemented || time.Now().After(deadline) {
+				t.Fatalf("expected Unavailable while draining, got %v", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// calling it again waits for the same shutdown, whose context
+		// is the one of the first call
+		expired, cancelExpired := context.WithCancel(context.Background())
+		cancelExpired()
+		go func() { second <- s.GracefulShutdown(expired) }()
+		select {
+		case forced := <-second:
+			t.Fatalf("the second call returned %d before the shutdown was done", forced)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		close(srv.unblock)
+		if forced := <-first; forced != 0 {
+			t.Fatalf("expected no stream to be closed, got %d", forced)
+		}
+		if forced := <-second; forced != 0 {
+			t.Fatalf("expected the second call to share the result, got %d", forced)
+		}
+		if forced := s.GracefulShutdown(expired); forced != 0 {
+			t.Fatalf("expected a later call to share the result, got %d", forced)
+		}
+	})
+}
+
+func TestServerHealthAndReflection(t *testing.T) {
+	hs := health.NewServer()
+	var hooked *grpc.Server
+	srv := &stuckServer{unblock: make(chan struct{})}
+	s := flight.NewServerWithMiddleware(nil, flight.WithHealthServer(hs), flight.WithReflection(),
+		flight.WithGrpcServerHook(func(gs *grpc.Server) { hooked = gs }))
+	s.Init("localhost:0")
+	s.RegisterFlightService(srv)
+	go s.Serve()
+
+	if hooked == nil {
+		t.Fatal("expected the hook to be called with the gRPC server")
+	}
+	if _, ok := hooked.GetServiceInfo()[grpc_health_v1.Health_ServiceDesc.ServiceName]; !ok {
+		t.Fatal("expected the health service to be registered")
+	}
+
+	conn, err := grpc.Dial(s.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	healthClient := grpc_health_v1.NewHealthClient(conn)
+	check := func() (grpc_health_v1.HealthCheckResponse_ServingStatus, error) {
+		resp, err := healthClient.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		return resp.GetStatus(), err
+	}
+
+	if st, err := check(); err != nil || st != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("expected SERVING, got %s: %v", st, err)
+	}
+	hs.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	if st, err := check(); err != nil || st != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("expected NOT_SERVING, got %s: %v", st, err)
+	}
+	hs.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+
+	refl, err := grpc_reflection_v1.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := refl.Send(&grpc_reflection_v1.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := refl.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var services []string
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		services = append(services, svc.GetName())
+	}
+	for _, name := range []string{"arrow.flight.protocol.FlightService", "grpc.health.v1.Health"} {
+		if !slices.Contains(services, name) {
+			t.Fatalf("expected %s in the services %v", name, services)
+		}
+	}
+	refl.CloseSend()
+
+	client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	stream, err := client.DoGet(context.Background(), &flight.Ticket{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdr, err := flight.NewRecordReader(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Release()
+
+	// the stream which ignores the cancellation holds the shutdown, during
+	// which the health checks are answered
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan int)
+	go func() { done <- s.GracefulShutdown(ctx) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		st, err := check()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st == grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected NOT_SERVING during the shutdown, got %s", st)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// flipping the status back has no effect once shutting down
+	hs.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	if st, err := check(); err != nil || st != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("expected NOT_SERVING, got %s: %v", st, err)
+	}
+	if _, err := client.GetFlightInfo(context.Background(), &flight.FlightDescriptor{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the Flight service to be unavailable, got %v", err)
+	}
+
+	close(srv.unblock)
+	if forced := <-done; forced != 0 {
+		t.Fatalf("expected no stream to be closed, got %d", forced)
+	}
+	if _, err := check(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable once shut down, got %v", err)
+	}
+}
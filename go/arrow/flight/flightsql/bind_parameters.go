@@ -0,0 +1,95 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"errors"
+	"io"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
+	"github.com/apache/arrow/go/v16/arrow/util"
+	"google.golang.org/grpc"
+)
+
+// parameterBatchOption is a grpc.CallOption which bounds the size of the
+// batches of parameters sent when executing a prepared statement. gRPC
+// itself ignores it.
+type parameterBatchOption struct {
+	grpc.EmptyCallOption
+	maxBytes int64
+}
+
+// WithMaxParameterBatchBytes makes the executions of a PreparedStatement
+// split the parameter records larger than maxBytes into slices of about
+// maxBytes each, sharing the buffers of the records, so that each batch
+// sent stays under the message size limit of the server, 4MiB by default
+// with gRPC. Slicing cannot split a single row, so a row larger than
+// maxBytes is still sent whole.
+func WithMaxParameterBatchBytes(maxBytes int64) grpc.CallOption {
+	return parameterBatchOption{maxBytes: maxBytes}
+}
+
+// maxParameterBatchBytes returns the size set by WithMaxParameterBatchBytes
+// in opts, or 0 if parameter records aren't to be split.
+func maxParameterBatchBytes(opts []grpc.CallOption) int64 {
+	var maxBytes int64
+	for _, o := range opts {
+		if o, ok := o.(parameterBatchOption); ok {
+			maxBytes = o.maxBytes
+		}
+	}
+	return maxBytes
+}
+
+// writeParameters writes the parameter record rec with wr, split into
+// slices of about maxBytes each if it is larger and maxBytes is positive.
+func writeParameters(wr *flight.Writer, rec arrow.Record, maxBytes int64) error {
+	size := util.TotalRecordSize(rec)
+	if maxBytes <= 0 || size <= maxBytes || rec.NumRows() <= 1 {
+		return wr.Write(rec)
+	}
+
+	rows := int(maxBytes * rec.NumRows() / size)
+	if rows < 1 {
+		rows = 1
+	}
+
+	var err error
+	// the slices must all be received for the channel to be drained
+	for chunk := range SliceIntoChunks(rec, rows) {
+		if err == nil {
+			err = wr.Write(chunk.Data)
+		}
+		chunk.Data.Release()
+	}
+	return err
+}
+
+// doPutError returns the error a DoPut failed with while sending err.
+// Once the server fails the call, sending fails with io.EOF and the
+// status it failed with is only returned by receiving.
+func doPutError(pstream pb.FlightService_DoPutClient, err error) error {
+	if !errors.Is(err, io.EOF) {
+		return err
+	}
+	if _, recvErr := pstream.Recv(); recvErr != nil && recvErr != io.EOF {
+		return recvErr
+	}
+	return err
+}
@@ -0,0 +1,190 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/flightsqltest"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var bindSchema = arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+// batchServer records the batches of parameters bound to its updates,
+// failing the update once it received failAfter batches, if positive.
+type batchServer struct {
+	flightsql.BaseServer
+	failAfter int
+
+	// batches holds the ids bound in each batch
+	batches [][]int64
+}
+
+func (*batchServer) CreatePreparedStatement(_ context.Context, req flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	return flightsql.ActionCreatePreparedStatementResult{Handle: []byte(req.GetQuery()), ParameterSchema: bindSchema}, nil
+}
+
+func (*batchServer) ClosePreparedStatement(context.Context, flightsql.ActionClosePreparedStatementRequest) error {
+	return nil
+}
+
+func (s *batchServer) DoPutPreparedStatementUpdate(_ context.Context, _ flightsql.PreparedStatementUpdate, rdr flight.MessageReader) (int64, error) {
+	s.batches = nil
+	var n int64
+	for rdr.Next() {
+		if rdr.Record().NumCols() == 0 {
+			// executed without parameters
+			continue
+		}
+		if s.failAfter > 0 && len(s.batches) == s.failAfter {
+			return 0, status.Error(codes.ResourceExhausted, "too many parameters")
+		}
+		ids := rdr.Record().Column(0).(*array.Int64)
+		s.batches = append(s.batches, append([]int64(nil), ids.Int64Values()...))
+		n += int64(ids.Len())
+	}
+	return n, rdr.Err()
+}
+
+// idRecord returns a record of the ids from start up to start+n.
+func idRecord(mem memory.Allocator, start, n int64) arrow.Record {
+	bldr := array.NewRecordBuilder(mem, bindSchema)
+	defer bldr.Release()
+	for id := start; id < start+n; id++ {
+		bldr.Field(0).(*array.Int64Builder).Append(id)
+	}
+	return bldr.NewRecord()
+}
+
+// idReader returns a reader of batches records of size ids each.
+func idReader(t *testing.T, mem memory.Allocator, batches, size int64) array.RecordReader {
+	recs := make([]arrow.Record, batches)
+	for i := range recs {
+		recs[i] = idRecord(mem, int64(i)*size, size)
+		defer recs[i].Release()
+	}
+	rdr, err := array.NewRecordReader(bindSchema, recs)
+	require.NoError(t, err)
+	return rdr
+}
+
+func TestPreparedStatementStreamParameters(t *testing.T) {
+	srv := &batchServer{}
+	cl := flightsqltest.StartServer(t, srv)
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	ctx := context.Background()
+	prep, err := cl.Prepare(ctx, "UPSERT")
+	require.NoError(t, err)
+	defer prep.Close(ctx)
+
+	rdr := idReader(t, mem, 200, 10)
+	prep.SetRecordReader(rdr)
+	rdr.Release()
+
+	n, err := prep.ExecuteUpdate(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2000, n)
+	require.Len(t, srv.batches, 200)
+	for i, batch := range srv.batches {
+		require.Len(t, batch, 10)
+		assert.EqualValues(t, i*10, batch[0], "batch %d", i)
+	}
+}
+
+func TestPreparedStatementSplitParameters(t *testing.T) {
+	srv := &batchServer{}
+	cl := flightsqltest.StartServer(t, srv)
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	ctx := context.Background()
+	prep, err := cl.Prepare(ctx, "UPSERT")
+	require.NoError(t, err)
+	defer prep.Close(ctx)
+
+	// 1000 ids take 8000 bytes, split into slices of at most 125 ids
+	rec := idRecord(mem, 0, 1000)
+	prep.SetParameters(rec)
+	rec.Release()
+
+	n, err := prep.ExecuteUpdate(ctx, flightsql.WithMaxParameterBatchBytes(1000))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1000, n)
+	assert.GreaterOrEqual(t, len(srv.batches), 8)
+	var next int64
+	for _, batch := range srv.batches {
+		assert.LessOrEqual(t, len(batch), 125)
+		for _, id := range batch {
+			assert.Equal(t, next, id)
+			next++
+		}
+	}
+
+	// without the option the record is sent whole
+	_, err = prep.ExecuteUpdate(ctx)
+	require.NoError(t, err)
+	assert.Len(t, srv.batches, 1)
+
+	// so is a single row larger than the limit
+	rdr := idReader(t, mem, 3, 1)
+	prep.SetRecordReader(rdr)
+	rdr.Release()
+	_, err = prep.ExecuteUpdate(ctx, flightsql.WithMaxParameterBatchBytes(1))
+	require.NoError(t, err)
+	assert.Equal(t, [][]int64{{0}, {1}, {2}}, srv.batches)
+}
+
+func TestPreparedStatementStreamParametersError(t *testing.T) {
+	srv := &batchServer{failAfter: 5}
+	cl := flightsqltest.StartServer(t, srv)
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	ctx := context.Background()
+	prep, err := cl.Prepare(ctx, "UPSERT")
+	require.NoError(t, err)
+
+	// the ids are large enough for the upload to fail while sending
+	rdr := idReader(t, mem, 200, 64*1024)
+	prep.SetRecordReader(rdr)
+	rdr.Release()
+
+	_, err = prep.ExecuteUpdate(ctx)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Len(t, srv.batches, 5)
+
+	// the reader was released by the failure
+	_, err = prep.ExecuteUpdate(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, srv.batches)
+	require.NoError(t, prep.Close(ctx))
+}
@@ -0,0 +1,56 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// WithOutgoingMetadata returns a call option sending the key/value pairs
+// kv as request headers, such as trace or tenant ids. Unlike metadata
+// attached to the context, it is sent on every RPC issued for a single
+// call of a Client method taking it, e.g. the GetFlightInfo and each
+// DoGet of ExecuteQuery, including those to other Locations of the
+// endpoints.
+//
+// Keys are lowercased as in metadata.Pairs, and the last value is used
+// if a key is given more than once. It panics if len(kv) is odd.
+func WithOutgoingMetadata(kv ...string) grpc.CallOption {
+	if len(kv)%2 == 1 {
+		panic(fmt.Sprintf("arrow/flightsql: WithOutgoingMetadata got an odd number of input pairs for metadata: %d", len(kv)))
+	}
+
+	md := make(outgoingMetadata, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		md[strings.ToLower(kv[i])] = kv[i+1]
+	}
+	return grpc.PerRPCCredentials(md)
+}
+
+// outgoingMetadata implements credentials.PerRPCCredentials, which is
+// the means for a grpc.CallOption to add request headers.
+type outgoingMetadata map[string]string
+
+func (md outgoingMetadata) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return md, nil
+}
+
+func (outgoingMetadata) RequireTransportSecurity() bool { return false }
@@ -0,0 +1,93 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// the cancellation action supported by the server, as remembered in
+// Client.cancelMode
+const (
+	cancelModeUnknown int32 = iota
+	cancelModeFlightInfo
+	cancelModeQuery
+)
+
+// Cancel requests the cancellation of the query described by info. It uses
+// the CancelFlightInfo action if the server lists it in ListActions, and
+// the CancelQuery action of servers older than 13.0.0 otherwise.
+//
+// The actions of the server are only listed by the first call, the result
+// is remembered by the Client.
+func (c *Client) Cancel(ctx context.Context, info *flight.FlightInfo, opts ...grpc.CallOption) (CancelResult, error) {
+	mode, err := c.probeCancelMode(ctx, opts...)
+	if err != nil {
+		return CancelResultUnspecified, err
+	}
+
+	if mode == cancelModeQuery {
+		return c.CancelQuery(ctx, info, opts...)
+	}
+
+	result, err := c.CancelFlightInfo(ctx, &flight.CancelFlightInfoRequest{Info: info}, opts...)
+	if err != nil {
+		return CancelResultUnspecified, err
+	}
+	return cancelStatusToCancelResult(result.GetStatus()), nil
+}
+
+func (c *Client) probeCancelMode(ctx context.Context, opts ...grpc.CallOption) (int32, error) {
+	if mode := atomic.LoadInt32(&c.cancelMode); mode != cancelModeUnknown {
+		return mode, nil
+	}
+
+	stream, err := c.Client.ListActions(ctx, &flight.Empty{}, opts...)
+	if err != nil {
+		return cancelModeUnknown, err
+	}
+
+	mode := cancelModeQuery
+	for {
+		action, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// servers which can't list their actions can only be
+			// expected to support the legacy action
+			if status.Code(err) == codes.Unimplemented {
+				break
+			}
+			return cancelModeUnknown, err
+		}
+
+		if action.GetType() == flight.CancelFlightInfoActionType {
+			mode = cancelModeFlightInfo
+		}
+	}
+
+	atomic.StoreInt32(&c.cancelMode, mode)
+	return mode, nil
+}
@@ -0,0 +1,141 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"google.golang.org/grpc"
+)
+
+// Capabilities summarizes the features a server reports through the
+// FLIGHT_SQL_SERVER_* SqlInfo values, as returned by Client.Capabilities.
+//
+// The protocol doesn't define SqlInfo values for prepared statements or
+// bulk ingestion, so those can only be discovered by trying them.
+type Capabilities struct {
+	ServerName    string
+	ServerVersion string
+	ArrowVersion  string
+	ReadOnly      bool
+	// SQL is true unless the server explicitly reports that it doesn't
+	// support SQL queries, as the flag was added after the protocol was
+	// first defined and older servers don't report it.
+	SQL                 bool
+	Substrait           bool
+	SubstraitMinVersion string
+	SubstraitMaxVersion string
+	// Transactions indicates whether the transaction and savepoint
+	// actions are supported.
+	Transactions SqlSupportedTransaction
+	// Cancel indicates whether the CancelQuery action is supported.
+	Cancel bool
+	// StatementTimeout is the timeout for prepared statement handles,
+	// zero if there is none.
+	StatementTimeout time.Duration
+	// TransactionTimeout is the timeout for transactions, zero if there
+	// is none.
+	TransactionTimeout time.Duration
+
+	reported map[SqlInfo]bool
+}
+
+// Reported returns whether the server reported a value for info, allowing
+// a missing value to be told apart from a false or empty one.
+func (c Capabilities) Reported(info SqlInfo) bool { return c.reported[info] }
+
+// Capabilities retrieves the SqlInfo values describing which features the
+// server supports and returns them as a Capabilities struct. Values which
+// the server doesn't report are left as their zero value, see
+// Capabilities.Reported.
+//
+// All of the server's SqlInfo is requested, since servers fail the request
+// if a specific id is asked for which they don't provide.
+func (c *Client) Capabilities(ctx context.Context, opts ...grpc.CallOption) (Capabilities, error) {
+	caps := Capabilities{SQL: true, reported: make(map[SqlInfo]bool)}
+
+	flightInfo, err := c.GetSqlInfo(ctx, nil, opts...)
+	if err != nil {
+		return caps, err
+	}
+
+	rdr, err := c.ReadFlightInfo(ctx, flightInfo, opts...)
+	if err != nil {
+		return caps, err
+	}
+	defer rdr.Release()
+
+	info, err := ParseSqlInfoResult(rdr)
+	if err != nil {
+		return caps, err
+	}
+
+	for id, v := range info {
+		if err := caps.set(SqlInfo(id), v); err != nil {
+			return caps, err
+		}
+	}
+	return caps, nil
+}
+
+func (c *Capabilities) set(info SqlInfo, v interface{}) error {
+	var ok bool
+	switch info {
+	case SqlInfoFlightSqlServerName:
+		c.ServerName, ok = v.(string)
+	case SqlInfoFlightSqlServerVersion:
+		c.ServerVersion, ok = v.(string)
+	case SqlInfoFlightSqlServerArrowVersion:
+		c.ArrowVersion, ok = v.(string)
+	case SqlInfoFlightSqlServerReadOnly:
+		c.ReadOnly, ok = v.(bool)
+	case SqlInfoFlightSqlServerSql:
+		c.SQL, ok = v.(bool)
+	case SqlInfoFlightSqlServerSubstrait:
+		c.Substrait, ok = v.(bool)
+	case SqlInfoFlightSqlServerSubstraitMinVersion:
+		c.SubstraitMinVersion, ok = v.(string)
+	case SqlInfoFlightSqlServerSubstraitMaxVersion:
+		c.SubstraitMaxVersion, ok = v.(string)
+	case SqlInfoFlightSqlServerTransaction:
+		var t int32
+		t, ok = v.(int32)
+		c.Transactions = SqlSupportedTransaction(t)
+	case SqlInfoFlightSqlServerCancel:
+		c.Cancel, ok = v.(bool)
+	case SqlInfoFlightSqlServerStatementTimeout:
+		var ms int32
+		ms, ok = v.(int32)
+		c.StatementTimeout = time.Duration(ms) * time.Millisecond
+	case SqlInfoFlightSqlServerTransactionTimeout:
+		var ms int32
+		ms, ok = v.(int32)
+		c.TransactionTimeout = time.Duration(ms) * time.Millisecond
+	default:
+		// servers may send more than was requested, ignore anything else
+		return nil
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: sql info %s has unexpected value type %T", arrow.ErrInvalid, info, v)
+	}
+	c.reported[info] = true
+	return nil
+}
@@ -0,0 +1,540 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/schema_ref"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"google.golang.org/grpc"
+)
+
+// DBSchemaInfo is a row of the result of GetDBSchemas, see
+// schema_ref.DBSchemas.
+//
+// The catalogs and schemas of the rows of the metadata results, such as
+// DBSchemaInfo.Catalog, are *string as their columns are nullable: a nil
+// pointer is sent as NULL, meaning that the database has no catalogs or
+// schemas, while a pointer to an empty string is sent as an empty name,
+// that of a catalog or schema named "". Clients tell them apart, JDBC
+// matching only the rows whose catalog is "" when an application asks
+// for the catalog "", so a database without catalogs must use nil rather
+// than "".
+type DBSchemaInfo struct {
+	// Catalog is the catalog of the schema, nil for NULL if there is
+	// none rather than "".
+	Catalog *string
+	Name    string
+}
+
+// TableInfo is a row of the result of GetTables, see schema_ref.Tables
+// and schema_ref.TablesWithIncludedSchema.
+type TableInfo struct {
+	// Catalog and DbSchema are the catalog and schema of the table, nil
+	// for NULL if there are none rather than "", see DBSchemaInfo.
+	Catalog, DbSchema *string
+	Name, Type        string
+	// Schema is the schema of the table if it was requested with
+	// GetTablesOpts.IncludeSchema, otherwise nil.
+	Schema *arrow.Schema
+}
+
+// PrimaryKeyInfo is a row of the result of GetPrimaryKeys, a column of
+// the primary key of a table, see schema_ref.PrimaryKeys.
+type PrimaryKeyInfo struct {
+	// Catalog and DbSchema are the catalog and schema of the table, nil
+	// for NULL if there are none rather than "", see DBSchemaInfo.
+	Catalog, DbSchema *string
+	Table, Column     string
+	// KeySequence is the position of the column in the key, starting
+	// at 1.
+	KeySequence int32
+	// KeyName is the name of the key, nil if it has none.
+	KeyName *string
+}
+
+// ForeignKeyRule is the action taken on the rows of a foreign key when
+// the row of the primary key they reference is updated or deleted.
+type ForeignKeyRule uint8
+
+const (
+	ForeignKeyCascade ForeignKeyRule = iota
+	ForeignKeyRestrict
+	ForeignKeySetNull
+	ForeignKeyNoAction
+	ForeignKeySetDefault
+)
+
+// ForeignKeyInfo is a row of the result of GetImportedKeys,
+// GetExportedKeys and GetCrossReference, a column of a foreign key along
+// with the column of the primary key it references, see
+// schema_ref.ImportedExportedKeysAndCrossReference.
+type ForeignKeyInfo struct {
+	// PkCatalog and PkDbSchema are the catalog and schema of the table of
+	// the primary key, nil for NULL if there are none rather than "", see
+	// DBSchemaInfo.
+	PkCatalog, PkDbSchema *string
+	PkTable, PkColumn     string
+	// FkCatalog and FkDbSchema are the catalog and schema of the table of
+	// the foreign key, nil for NULL if there are none rather than "".
+	FkCatalog, FkDbSchema *string
+	FkTable, FkColumn     string
+	// KeySequence is the position of the column in the foreign key,
+	// starting at 1.
+	KeySequence int32
+	// FkKeyName and PkKeyName are the names of the keys, nil if they
+	// have none.
+	FkKeyName, PkKeyName   *string
+	UpdateRule, DeleteRule ForeignKeyRule
+}
+
+// SerializeTableSchema encodes schema as the table_schema column of the
+// GetTables result, an IPC schema message as flight.SerializeSchema
+// writes it. Dictionary-encoded fields, including nested ones, keep their
+// index and value types and whether they are ordered, the dictionaries
+// themselves not being part of the schema. See DeserializeTableSchema.
+func SerializeTableSchema(schema *arrow.Schema, mem memory.Allocator) []byte {
+	return flight.SerializeSchema(schema, mem)
+}
+
+// DeserializeTableSchema decodes a value of the table_schema column of the
+// GetTables result, see SerializeTableSchema.
+func DeserializeTableSchema(b []byte, mem memory.Allocator) (*arrow.Schema, error) {
+	return flight.DeserializeSchema(b, mem)
+}
+
+// ResultIterator iterates over the rows of a result as values of type T,
+// reading the records from the server as they are needed.
+//
+//	it, err := client.GetTablesTyped(ctx, &flightsql.GetTablesOpts{})
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Release()
+//	for it.Next() {
+//		fmt.Println(it.Value().Name)
+//	}
+//	return it.Err()
+type ResultIterator[T any] struct {
+	rdr  array.RecordReader
+	bind func(arrow.Record) (func(int) (T, error), error)
+
+	row, numRows int
+	decode       func(int) (T, error)
+	cur          T
+	err          error
+}
+
+func newResultIterator[T any](rdr array.RecordReader, bind func(arrow.Record) (func(int) (T, error), error)) *ResultIterator[T] {
+	return &ResultIterator[T]{rdr: rdr, bind: bind}
+}
+
+// Next advances to the next row, returning false once there are no more
+// rows or an error occurred, see Err.
+func (it *ResultIterator[T]) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	for it.row >= it.numRows {
+		if !it.rdr.Next() {
+			it.err = it.rdr.Err()
+			return false
+		}
+
+		rec := it.rdr.Record()
+		if it.decode, it.err = it.bind(rec); it.err != nil {
+			return false
+		}
+		it.row, it.numRows = 0, int(rec.NumRows())
+	}
+
+	if it.cur, it.err = it.decode(it.row); it.err != nil {
+		return false
+	}
+	it.row++
+	return true
+}
+
+// Value returns the current row.
+func (it *ResultIterator[T]) Value() T { return it.cur }
+
+// Err returns the error which stopped the iteration, if any.
+func (it *ResultIterator[T]) Err() error { return it.err }
+
+// Release releases the reader of the result. It must be called once the
+// iterator is no longer needed, whether or not all rows were read.
+func (it *ResultIterator[T]) Release() { it.rdr.Release() }
+
+// GetCatalogsTyped requests the list of catalogs like GetCatalogs and
+// returns an iterator over their names.
+func (c *Client) GetCatalogsTyped(ctx context.Context, opts ...grpc.CallOption) (*ResultIterator[string], error) {
+	info, err := c.GetCatalogs(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := c.ReadFlightInfo(ctx, info, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newResultIterator(rdr, bindCatalogs), nil
+}
+
+// GetDBSchemasTyped requests the list of schemas like GetDBSchemas and
+// returns an iterator over them.
+func (c *Client) GetDBSchemasTyped(ctx context.Context, cmdOpts *GetDBSchemasOpts, opts ...grpc.CallOption) (*ResultIterator[DBSchemaInfo], error) {
+	info, err := c.GetDBSchemas(ctx, cmdOpts, opts...)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := c.ReadFlightInfo(ctx, info, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newResultIterator(rdr, bindDBSchemas), nil
+}
+
+// GetTablesTyped requests the list of tables like GetTables and returns an
+// iterator over them, deserializing their schemas if reqOptions asks for
+// them to be included.
+func (c *Client) GetTablesTyped(ctx context.Context, reqOptions *GetTablesOpts, opts ...grpc.CallOption) (*ResultIterator[TableInfo], error) {
+	info, err := c.GetTables(ctx, reqOptions, opts...)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := c.ReadFlightInfo(ctx, info, opts...)
+	if err != nil {
+		return nil, err
+	}
+	mem := c.Alloc
+	return newResultIterator(rdr, func(rec arrow.Record) (func(int) (TableInfo, error), error) {
+		return bindTables(mem, rec)
+	}), nil
+}
+
+// resultColumn returns the column i of rec, which must be of type A.
+func resultColumn[A arrow.Array](rec arrow.Record, i int) (A, error) {
+	var col A
+	if int(rec.NumCols()) <= i {
+		return col, fmt.Errorf("%w: arrow/flightsql: result has %d columns, expected at least %d",
+			arrow.ErrInvalid, rec.NumCols(), i+1)
+	}
+	col, ok := rec.Column(i).(A)
+	if !ok {
+		return col, fmt.Errorf("%w: arrow/flightsql: unexpected type %s for result column %q",
+			arrow.ErrInvalid, rec.Column(i).DataType(), rec.ColumnName(i))
+	}
+	return col, nil
+}
+
+func optionalString(col *array.String, i int) *string {
+	if col.IsNull(i) {
+		return nil
+	}
+	s := strings.Clone(col.Value(i))
+	return &s
+}
+
+func bindCatalogs(rec arrow.Record) (func(int) (string, error), error) {
+	names, err := resultColumn[*array.String](rec, 0)
+	if err != nil {
+		return nil, err
+	}
+	return func(i int) (string, error) {
+		return strings.Clone(names.Value(i)), nil
+	}, nil
+}
+
+func bindDBSchemas(rec arrow.Record) (func(int) (DBSchemaInfo, error), error) {
+	catalogs, err := resultColumn[*array.String](rec, 0)
+	if err != nil {
+		return nil, err
+	}
+	names, err := resultColumn[*array.String](rec, 1)
+	if err != nil {
+		return nil, err
+	}
+	return func(i int) (DBSchemaInfo, error) {
+		return DBSchemaInfo{
+			Catalog: optionalString(catalogs, i),
+			Name:    strings.Clone(names.Value(i)),
+		}, nil
+	}, nil
+}
+
+func bindTables(mem memory.Allocator, rec arrow.Record) (func(int) (TableInfo, error), error) {
+	var cols [4]*array.String
+	for i := range cols {
+		col, err := resultColumn[*array.String](rec, i)
+		if err != nil {
+			return nil, err
+		}
+		cols[i] = col
+	}
+
+	var schemas *array.Binary
+	if rec.NumCols() > 4 {
+		col, err := resultColumn[*array.Binary](rec, 4)
+		if err != nil {
+			return nil, err
+		}
+		schemas = col
+	}
+
+	return func(i int) (TableInfo, error) {
+		info := TableInfo{
+			Catalog:  optionalString(cols[0], i),
+			DbSchema: optionalString(cols[1], i),
+			Name:     strings.Clone(cols[2].Value(i)),
+			Type:     strings.Clone(cols[3].Value(i)),
+		}
+		if schemas != nil && schemas.IsValid(i) {
+			schema, err := DeserializeTableSchema(schemas.Value(i), mem)
+			if err != nil {
+				return info, fmt.Errorf("arrow/flightsql: cannot deserialize schema of table %q: %w", info.Name, err)
+			}
+			info.Schema = schema
+		}
+		return info, nil
+	}, nil
+}
+
+// CatalogsResultBuilder is a helper for constructing a record conforming
+// to schema_ref.Catalogs, the result of GetCatalogs.
+type CatalogsResultBuilder struct {
+	bldr *array.RecordBuilder
+}
+
+// NewCatalogsResultBuilder constructs a builder using the provided
+// allocator, using memory.DefaultAllocator if mem is nil.
+func NewCatalogsResultBuilder(mem memory.Allocator) *CatalogsResultBuilder {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	return &CatalogsResultBuilder{bldr: array.NewRecordBuilder(mem, schema_ref.Catalogs)}
+}
+
+// Release releases the underlying record builder.
+func (b *CatalogsResultBuilder) Release() { b.bldr.Release() }
+
+// NewRecord returns a record containing all of the rows appended so far
+// and resets the builder so it can be reused.
+func (b *CatalogsResultBuilder) NewRecord() arrow.Record { return b.bldr.NewRecord() }
+
+// Append adds the catalogs to the result being built.
+func (b *CatalogsResultBuilder) Append(names ...string) {
+	b.bldr.Field(0).(*array.StringBuilder).AppendValues(names, nil)
+}
+
+// DBSchemasResultBuilder is a helper for constructing a record conforming
+// to schema_ref.DBSchemas, the result of GetDBSchemas.
+type DBSchemasResultBuilder struct {
+	bldr *array.RecordBuilder
+}
+
+// NewDBSchemasResultBuilder constructs a builder using the provided
+// allocator, using memory.DefaultAllocator if mem is nil.
+func NewDBSchemasResultBuilder(mem memory.Allocator) *DBSchemasResultBuilder {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	return &DBSchemasResultBuilder{bldr: array.NewRecordBuilder(mem, schema_ref.DBSchemas)}
+}
+
+// Release releases the underlying record builder.
+func (b *DBSchemasResultBuilder) Release() { b.bldr.Release() }
+
+// NewRecord returns a record containing all of the rows appended so far
+// and resets the builder so it can be reused.
+func (b *DBSchemasResultBuilder) NewRecord() arrow.Record { return b.bldr.NewRecord() }
+
+// Append adds the schemas to the result being built.
+func (b *DBSchemasResultBuilder) Append(rows ...DBSchemaInfo) {
+	for _, r := range rows {
+		appendStrPtr(b.bldr.Field(0).(*array.StringBuilder), r.Catalog)
+		b.bldr.Field(1).(*array.StringBuilder).Append(r.Name)
+	}
+}
+
+// TablesResultBuilder is a helper for constructing a record conforming to
+// schema_ref.Tables, or schema_ref.TablesWithIncludedSchema if the schemas
+// of the tables are included, the result of GetTables.
+type TablesResultBuilder struct {
+	mem           memory.Allocator
+	bldr          *array.RecordBuilder
+	includeSchema bool
+}
+
+// NewTablesResultBuilder constructs a builder using the provided
+// allocator, using memory.DefaultAllocator if mem is nil. includeSchema
+// should be the IncludeSchema option of the request.
+func NewTablesResultBuilder(mem memory.Allocator, includeSchema bool) *TablesResultBuilder {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	schema := schema_ref.Tables
+	if includeSchema {
+		schema = schema_ref.TablesWithIncludedSchema
+	}
+	return &TablesResultBuilder{mem: mem, bldr: array.NewRecordBuilder(mem, schema), includeSchema: includeSchema}
+}
+
+// Release releases the underlying record builder.
+func (b *TablesResultBuilder) Release() { b.bldr.Release() }
+
+// NewRecord returns a record containing all of the rows appended so far
+// and resets the builder so it can be reused.
+func (b *TablesResultBuilder) NewRecord() arrow.Record { return b.bldr.NewRecord() }
+
+// Append adds the tables to the result being built. If the builder
+// includes schemas, a table with a nil Schema gets a null table_schema.
+func (b *TablesResultBuilder) Append(rows ...TableInfo) {
+	for _, r := range rows {
+		b.appendRow(r)
+	}
+}
+
+// AppendFiltered adds the tables whose type is one of tableTypes, the
+// table types of a GetTables request, see TableTypeFilter.
+func (b *TablesResultBuilder) AppendFiltered(tableTypes []string, rows ...TableInfo) {
+	match := TableTypeFilter(tableTypes)
+	for _, r := range rows {
+		if match(r.Type) {
+			b.appendRow(r)
+		}
+	}
+}
+
+// TableTypeFilter returns a function reporting whether the tables of a
+// type belong to the result of a GetTables request for the requested
+// table types, see GetTables.GetTableTypes: all of them if requested is
+// empty, otherwise those whose type is one of requested, compared
+// case-sensitively as per the Flight SQL specification, so that "TABLE"
+// doesn't match the "VIEW" nor the "table" type.
+func TableTypeFilter(requested []string) func(string) bool {
+	if len(requested) == 0 {
+		return func(string) bool { return true }
+	}
+
+	types := make(map[string]struct{}, len(requested))
+	for _, t := range requested {
+		types[t] = struct{}{}
+	}
+	return func(tableType string) bool {
+		_, ok := types[tableType]
+		return ok
+	}
+}
+
+func (b *TablesResultBuilder) appendRow(r TableInfo) {
+	appendStrPtr(b.bldr.Field(0).(*array.StringBuilder), r.Catalog)
+	appendStrPtr(b.bldr.Field(1).(*array.StringBuilder), r.DbSchema)
+	b.bldr.Field(2).(*array.StringBuilder).Append(r.Name)
+	b.bldr.Field(3).(*array.StringBuilder).Append(r.Type)
+	if !b.includeSchema {
+		return
+	}
+
+	schemas := b.bldr.Field(4).(*array.BinaryBuilder)
+	if r.Schema == nil {
+		schemas.AppendNull()
+	} else {
+		schemas.Append(SerializeTableSchema(r.Schema, b.mem))
+	}
+}
+
+// PrimaryKeysResultBuilder is a helper for constructing a record
+// conforming to schema_ref.PrimaryKeys, the result of GetPrimaryKeys.
+type PrimaryKeysResultBuilder struct {
+	bldr *array.RecordBuilder
+}
+
+// NewPrimaryKeysResultBuilder constructs a builder using the provided
+// allocator, using memory.DefaultAllocator if mem is nil.
+func NewPrimaryKeysResultBuilder(mem memory.Allocator) *PrimaryKeysResultBuilder {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	return &PrimaryKeysResultBuilder{bldr: array.NewRecordBuilder(mem, schema_ref.PrimaryKeys)}
+}
+
+// Release releases the underlying record builder.
+func (b *PrimaryKeysResultBuilder) Release() { b.bldr.Release() }
+
+// NewRecord returns a record containing all of the rows appended so far
+// and resets the builder so it can be reused.
+func (b *PrimaryKeysResultBuilder) NewRecord() arrow.Record { return b.bldr.NewRecord() }
+
+// Append adds the key columns to the result being built.
+func (b *PrimaryKeysResultBuilder) Append(rows ...PrimaryKeyInfo) {
+	for _, r := range rows {
+		appendStrPtr(b.bldr.Field(0).(*array.StringBuilder), r.Catalog)
+		appendStrPtr(b.bldr.Field(1).(*array.StringBuilder), r.DbSchema)
+		b.bldr.Field(2).(*array.StringBuilder).Append(r.Table)
+		b.bldr.Field(3).(*array.StringBuilder).Append(r.Column)
+		b.bldr.Field(4).(*array.Int32Builder).Append(r.KeySequence)
+		appendStrPtr(b.bldr.Field(5).(*array.StringBuilder), r.KeyName)
+	}
+}
+
+// ForeignKeysResultBuilder is a helper for constructing a record
+// conforming to schema_ref.ImportedExportedKeysAndCrossReference, the
+// result of GetImportedKeys, GetExportedKeys and GetCrossReference.
+type ForeignKeysResultBuilder struct {
+	bldr *array.RecordBuilder
+}
+
+// NewForeignKeysResultBuilder constructs a builder using the provided
+// allocator, using memory.DefaultAllocator if mem is nil.
+func NewForeignKeysResultBuilder(mem memory.Allocator) *ForeignKeysResultBuilder {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	return &ForeignKeysResultBuilder{bldr: array.NewRecordBuilder(mem, schema_ref.ImportedExportedKeysAndCrossReference)}
+}
+
+// Release releases the underlying record builder.
+func (b *ForeignKeysResultBuilder) Release() { b.bldr.Release() }
+
+// NewRecord returns a record containing all of the rows appended so far
+// and resets the builder so it can be reused.
+func (b *ForeignKeysResultBuilder) NewRecord() arrow.Record { return b.bldr.NewRecord() }
+
+// Append adds the key columns to the result being built.
+func (b *ForeignKeysResultBuilder) Append(rows ...ForeignKeyInfo) {
+	for _, r := range rows {
+		appendStrPtr(b.bldr.Field(0).(*array.StringBuilder), r.PkCatalog)
+		appendStrPtr(b.bldr.Field(1).(*array.StringBuilder), r.PkDbSchema)
+		b.bldr.Field(2).(*array.StringBuilder).Append(r.PkTable)
+		b.bldr.Field(3).(*array.StringBuilder).Append(r.PkColumn)
+		appendStrPtr(b.bldr.Field(4).(*array.StringBuilder), r.FkCatalog)
+		appendStrPtr(b.bldr.Field(5).(*array.StringBuilder), r.FkDbSchema)
+		b.bldr.Field(6).(*array.StringBuilder).Append(r.FkTable)
+		b.bldr.Field(7).(*array.StringBuilder).Append(r.FkColumn)
+		b.bldr.Field(8).(*array.Int32Builder).Append(r.KeySequence)
+		appendStrPtr(b.bldr.Field(9).(*array.StringBuilder), r.FkKeyName)
+		appendStrPtr(b.bldr.Field(10).(*array.StringBuilder), r.PkKeyName)
+		b.bldr.Field(11).(*array.Uint8Builder).Append(uint8(r.UpdateRule))
+		b.bldr.Field(12).(*array.Uint8Builder).Append(uint8(r.DeleteRule))
+	}
+}
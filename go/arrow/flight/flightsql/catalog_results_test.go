@@ -0,0 +1,360 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/flightsqltest"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/schema_ref"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(s string) *string { return &s }
+
+var (
+	testCatalogs  = []string{"main", "archive"}
+	testDBSchemas = []flightsql.DBSchemaInfo{
+		{Catalog: strPtr("main"), Name: "public"},
+		{Name: "orphan"},
+	}
+	testTables = []flightsql.TableInfo{
+		{Catalog: strPtr("main"), DbSchema: strPtr("public"), Name: "users", Type: "TABLE",
+			Schema: arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)},
+		{DbSchema: strPtr("public"), Name: "names", Type: "VIEW",
+			Schema: arrow.NewSchema([]arrow.Field{{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true}}, nil)},
+		{Name: "scratch", Type: "TEMPORARY TABLE"},
+	}
+)
+
+// catalogServer lists testCatalogs, testDBSchemas and testTables, sending
+// a record for each row.
+type catalogServer struct {
+	flightsql.BaseServer
+
+	corruptSchema bool
+}
+
+func (s *catalogServer) GetFlightInfoCatalogs(_ context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return flightsql.NewFlightInfo(desc, schema_ref.Catalogs, s.Alloc), nil
+}
+
+func (s *catalogServer) DoGetCatalogs(context.Context) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	bldr := flightsql.NewCatalogsResultBuilder(s.Alloc)
+	defer bldr.Release()
+
+	ch := make(chan flight.StreamChunk, len(testCatalogs))
+	for _, c := range testCatalogs {
+		bldr.Append(c)
+		ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+	}
+	close(ch)
+	return schema_ref.Catalogs, ch, nil
+}
+
+func (s *catalogServer) GetFlightInfoSchemas(_ context.Context, _ flightsql.GetDBSchemas, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return flightsql.NewFlightInfo(desc, schema_ref.DBSchemas, s.Alloc), nil
+}
+
+func (s *catalogServer) DoGetDBSchemas(context.Context, flightsql.GetDBSchemas) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	bldr := flightsql.NewDBSchemasResultBuilder(s.Alloc)
+	defer bldr.Release()
+
+	ch := make(chan flight.StreamChunk, len(testDBSchemas))
+	for _, row := range testDBSchemas {
+		bldr.Append(row)
+		ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+	}
+	close(ch)
+	return schema_ref.DBSchemas, ch, nil
+}
+
+func (s *catalogServer) GetFlightInfoTables(_ context.Context, cmd flightsql.GetTables, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	schema := schema_ref.Tables
+	if cmd.GetIncludeSchema() {
+		schema = schema_ref.TablesWithIncludedSchema
+	}
+	return flightsql.NewFlightInfo(desc, schema, s.Alloc), nil
+}
+
+func (s *catalogServer) DoGetTables(_ context.Context, cmd flightsql.GetTables) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	bldr := flightsql.NewTablesResultBuilder(s.Alloc, cmd.GetIncludeSchema())
+	defer bldr.Release()
+
+	match := flightsql.TableTypeFilter(cmd.GetTableTypes())
+	ch := make(chan flight.StreamChunk, len(testTables))
+	for _, row := range testTables {
+		if !match(row.Type) {
+			continue
+		}
+		bldr.Append(row)
+		rec := bldr.NewRecord()
+		if s.corruptSchema && row.Name == "names" {
+			rec.Release()
+			rec = corruptSchemaRecord(s.Alloc, row)
+		}
+		ch <- flight.StreamChunk{Data: rec}
+	}
+	close(ch)
+
+	schema := schema_ref.Tables
+	if cmd.GetIncludeSchema() {
+		schema = schema_ref.TablesWithIncludedSchema
+	}
+	return schema, ch, nil
+}
+
+// corruptSchemaRecord returns a GetTables result for row whose
+// table_schema is not a serialized schema.
+func corruptSchemaRecord(mem memory.Allocator, row flightsql.TableInfo) arrow.Record {
+	bldr := array.NewRecordBuilder(mem, schema_ref.TablesWithIncludedSchema)
+	defer bldr.Release()
+
+	bldr.Field(0).AppendNull()
+	bldr.Field(1).(*array.StringBuilder).Append(*row.DbSchema)
+	bldr.Field(2).(*array.StringBuilder).Append(row.Name)
+	bldr.Field(3).(*array.StringBuilder).Append(row.Type)
+	bldr.Field(4).(*array.BinaryBuilder).Append([]byte("not a schema"))
+	return bldr.NewRecord()
+}
+
+func startCatalogServer(t *testing.T, srv *catalogServer) *flightsql.Client {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	t.Cleanup(func() { mem.AssertSize(t, 0) })
+
+	cl := flightsqltest.StartServer(t, srv)
+	cl.Alloc = mem
+	return cl
+}
+
+func TestCatalogResultIterators(t *testing.T) {
+	cl := startCatalogServer(t, &catalogServer{})
+	ctx := context.Background()
+
+	catalogs, err := cl.GetCatalogsTyped(ctx)
+	require.NoError(t, err)
+	var gotCatalogs []string
+	for catalogs.Next() {
+		gotCatalogs = append(gotCatalogs, catalogs.Value())
+	}
+	require.NoError(t, catalogs.Err())
+	catalogs.Release()
+	assert.Equal(t, testCatalogs, gotCatalogs)
+
+	schemas, err := cl.GetDBSchemasTyped(ctx, &flightsql.GetDBSchemasOpts{})
+	require.NoError(t, err)
+	var gotSchemas []flightsql.DBSchemaInfo
+	for schemas.Next() {
+		gotSchemas = append(gotSchemas, schemas.Value())
+	}
+	require.NoError(t, schemas.Err())
+	schemas.Release()
+	assert.Equal(t, testDBSchemas, gotSchemas)
+
+	for _, includeSchema := range []bool{false, true} {
+		tables, err := cl.GetTablesTyped(ctx, &flightsql.GetTablesOpts{IncludeSchema: includeSchema})
+		require.NoError(t, err)
+		var got []flightsql.TableInfo
+		for tables.Next() {
+			got = append(got, tables.Value())
+		}
+		require.NoError(t, tables.Err())
+		tables.Release()
+
+		require.Len(t, got, len(testTables))
+		for i, want := range testTables {
+			assert.Equal(t, want.Catalog, got[i].Catalog)
+			assert.Equal(t, want.DbSchema, got[i].DbSchema)
+			assert.Equal(t, want.Name, got[i].Name)
+			assert.Equal(t, want.Type, got[i].Type)
+			if includeSchema && want.Schema != nil {
+				assert.Truef(t, want.Schema.Equal(got[i].Schema), "schema of %s: %s", want.Name, got[i].Schema)
+			} else {
+				assert.Nil(t, got[i].Schema)
+			}
+		}
+	}
+
+	// stopping early releases the remaining records
+	tables, err := cl.GetTablesTyped(ctx, &flightsql.GetTablesOpts{})
+	require.NoError(t, err)
+	require.True(t, tables.Next())
+	assert.Equal(t, "users", tables.Value().Name)
+	tables.Release()
+}
+
+func TestTableTypeFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested []string
+		expected  []string
+	}{
+		{"nil", nil, []string{"users", "names", "scratch"}},
+		{"empty", []string{}, []string{"users", "names", "scratch"}},
+		{"single type", []string{"TABLE"}, []string{"users"}},
+		{"multiple types", []string{"VIEW", "TEMPORARY TABLE"}, []string{"names", "scratch"}},
+		{"case sensitive", []string{"table", "View"}, nil},
+	}
+
+	cl := startCatalogServer(t, &catalogServer{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filtered []string
+			match := flightsql.TableTypeFilter(tt.requested)
+			for _, row := range testTables {
+				if match(row.Type) {
+					filtered = append(filtered, row.Name)
+				}
+			}
+			assert.Equal(t, tt.expected, filtered)
+
+			bldr := flightsql.NewTablesResultBuilder(memory.DefaultAllocator, false)
+			defer bldr.Release()
+			bldr.AppendFiltered(tt.requested, testTables...)
+			rec := bldr.NewRecord()
+			defer rec.Release()
+			assert.EqualValues(t, len(tt.expected), rec.NumRows())
+
+			tables, err := cl.GetTablesTyped(context.Background(), &flightsql.GetTablesOpts{TableTypes: tt.requested})
+			require.NoError(t, err)
+			defer tables.Release()
+			var got []string
+			for tables.Next() {
+				got = append(got, tables.Value().Name)
+			}
+			require.NoError(t, tables.Err())
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestCatalogResultIteratorSchemaError(t *testing.T) {
+	cl := startCatalogServer(t, &catalogServer{corruptSchema: true})
+
+	tables, err := cl.GetTablesTyped(context.Background(), &flightsql.GetTablesOpts{IncludeSchema: true})
+	require.NoError(t, err)
+	defer tables.Release()
+
+	require.True(t, tables.Next())
+	assert.Equal(t, "users", tables.Value().Name)
+	assert.False(t, tables.Next())
+	assert.ErrorContains(t, tables.Err(), `table "names"`)
+	assert.False(t, tables.Next())
+}
+
+func TestTableSchemaDictionaryFields(t *testing.T) {
+	dict := func(index arrow.DataType, ordered bool) *arrow.DictionaryType {
+		return &arrow.DictionaryType{IndexType: index, ValueType: arrow.BinaryTypes.String, Ordered: ordered}
+	}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "status", Type: dict(arrow.PrimitiveTypes.Int8, false), Nullable: true},
+		{Name: "tags", Type: arrow.ListOf(dict(arrow.PrimitiveTypes.Int32, true))},
+		{Name: "attrs", Type: arrow.StructOf(arrow.Field{Name: "kind", Type: dict(arrow.PrimitiveTypes.Uint16, false), Nullable: true})},
+		{Name: "labels", Type: arrow.MapOf(arrow.BinaryTypes.String, dict(arrow.PrimitiveTypes.Int64, false))},
+	}, nil)
+
+	got, err := flightsql.DeserializeTableSchema(flightsql.SerializeTableSchema(schema, memory.DefaultAllocator), memory.DefaultAllocator)
+	require.NoError(t, err)
+	assert.Truef(t, schema.Equal(got), "got %s", got)
+
+	// as through the table_schema column of the GetTables result
+	bldr := flightsql.NewTablesResultBuilder(memory.DefaultAllocator, true)
+	defer bldr.Release()
+	bldr.Append(flightsql.TableInfo{Name: "events", Type: "TABLE", Schema: schema})
+	rec := bldr.NewRecord()
+	defer rec.Release()
+	got, err = flightsql.DeserializeTableSchema(rec.Column(4).(*array.Binary).Value(0), memory.DefaultAllocator)
+	require.NoError(t, err)
+	assert.Truef(t, schema.Equal(got), "got %s", got)
+}
+
+func TestCatalogResultNullCatalogs(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	// assertNulls checks that the column i of rec holds a null for the
+	// first row, the nil pointer, and "" for the second, the empty one
+	assertNulls := func(t *testing.T, rec arrow.Record, cols ...int) {
+		require.EqualValues(t, 2, rec.NumRows())
+		for _, i := range cols {
+			col := rec.Column(i).(*array.String)
+			assert.Truef(t, col.IsNull(0), "column %q", rec.ColumnName(i))
+			assert.Truef(t, col.IsValid(1), "column %q", rec.ColumnName(i))
+			assert.Equal(t, "", col.Value(1))
+		}
+	}
+
+	t.Run("schemas", func(t *testing.T) {
+		bldr := flightsql.NewDBSchemasResultBuilder(mem)
+		defer bldr.Release()
+		bldr.Append(
+			flightsql.DBSchemaInfo{Catalog: nil, Name: "public"},
+			flightsql.DBSchemaInfo{Catalog: strPtr(""), Name: "public"})
+		rec := bldr.NewRecord()
+		defer rec.Release()
+		assertNulls(t, rec, 0)
+	})
+
+	t.Run("tables", func(t *testing.T) {
+		bldr := flightsql.NewTablesResultBuilder(mem, false)
+		defer bldr.Release()
+		bldr.Append(
+			flightsql.TableInfo{Name: "users", Type: "TABLE"},
+			flightsql.TableInfo{Catalog: strPtr(""), DbSchema: strPtr(""), Name: "users", Type: "TABLE"})
+		rec := bldr.NewRecord()
+		defer rec.Release()
+		assertNulls(t, rec, 0, 1)
+	})
+
+	t.Run("primary keys", func(t *testing.T) {
+		bldr := flightsql.NewPrimaryKeysResultBuilder(mem)
+		defer bldr.Release()
+		bldr.Append(
+			flightsql.PrimaryKeyInfo{Table: "users", Column: "id", KeySequence: 1},
+			flightsql.PrimaryKeyInfo{Catalog: strPtr(""), DbSchema: strPtr(""), Table: "users", Column: "id",
+				KeySequence: 1, KeyName: strPtr("")})
+		rec := bldr.NewRecord()
+		defer rec.Release()
+		require.True(t, rec.Schema().Equal(schema_ref.PrimaryKeys))
+		assertNulls(t, rec, 0, 1, 5)
+	})
+
+	t.Run("foreign keys", func(t *testing.T) {
+		bldr := flightsql.NewForeignKeysResultBuilder(mem)
+		defer bldr.Release()
+		bldr.Append(
+			flightsql.ForeignKeyInfo{PkTable: "users", PkColumn: "id", FkTable: "orders", FkColumn: "user_id",
+				KeySequence: 1, UpdateRule: flightsql.ForeignKeyCascade, DeleteRule: flightsql.ForeignKeyRestrict},
+			flightsql.ForeignKeyInfo{PkCatalog: strPtr(""), PkDbSchema: strPtr(""), PkTable: "users", PkColumn: "id",
+				FkCatalog: strPtr(""), FkDbSchema: strPtr(""), FkTable: "orders", FkColumn: "user_id",
+				KeySequence: 1, FkKeyName: strPtr(""), PkKeyName: strPtr(""),
+				UpdateRule: flightsql.ForeignKeyNoAction, DeleteRule: flightsql.ForeignKeySetDefault})
+		rec := bldr.NewRecord()
+		defer rec.Release()
+		require.True(t, rec.Schema().Equal(schema_ref.ImportedExportedKeysAndCrossReference))
+		assertNulls(t, rec, 0, 1, 4, 5, 9, 10)
+		assert.Equal(t, []uint8{0, 3}, rec.Column(11).(*array.Uint8).Uint8Values())
+		assert.Equal(t, []uint8{1, 4}, rec.Column(12).(*array.Uint8).Uint8Values())
+	})
+}
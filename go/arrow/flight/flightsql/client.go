@@ -18,9 +18,9 @@ package flightsql
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/apache/arrow/go/v16/arrow"
 	"github.com/apache/arrow/go/v16/arrow/array"
@@ -29,6 +29,8 @@ import (
 	"github.com/apache/arrow/go/v16/arrow/ipc"
 	"github.com/apache/arrow/go/v16/arrow/memory"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 )
@@ -38,16 +40,40 @@ import (
 // than having to manually construct both yourself. It just delegates
 // its arguments to flight.NewClientWithMiddleware to create the
 // underlying Flight Client.
+//
+// Transient failures can be retried by passing the middleware returned by
+// flight.NewRetryMiddleware. Actions such as CreatePreparedStatement are
+// only retried if their types are listed in the policy's
+// IdempotentActions.
 func NewClient(addr string, auth flight.ClientAuthHandler, middleware []flight.ClientMiddleware, opts ...grpc.DialOption) (*Client, error) {
 	return NewClientCtx(context.Background(), addr, auth, middleware, opts...)
 }
 
+// WithTokenAuth returns middleware for NewClient which authenticates
+// every call with a bearer token acquired through a Handshake with the
+// basic credentials username and password, and acquires a new one when
+// the server rejects it. See flight.NewBearerTokenMiddleware.
+func WithTokenAuth(username, password string) flight.ClientMiddleware {
+	return flight.NewBearerTokenMiddleware(username, password)
+}
+
+// WithStaticToken returns middleware for NewClient which authenticates
+// every call with the bearer token token.
+func WithStaticToken(token string) flight.ClientMiddleware {
+	return flight.NewStaticBearerTokenMiddleware(token)
+}
+
 func NewClientCtx(ctx context.Context, addr string, auth flight.ClientAuthHandler, middleware []flight.ClientMiddleware, opts ...grpc.DialOption) (*Client, error) {
 	cl, err := flight.NewClientWithMiddlewareCtx(ctx, addr, auth, middleware, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return &Client{cl, memory.DefaultAllocator}, nil
+	return &Client{
+		Client:     cl,
+		Alloc:      memory.DefaultAllocator,
+		Locations:  NewLocationPool(LocationPoolOptions{}),
+		statements: newPreparedStatements(),
+	}, nil
 }
 
 // Client wraps a regular Flight RPC Client to provide the FlightSQL
@@ -55,7 +81,25 @@ func NewClientCtx(ctx context.Context, addr string, auth flight.ClientAuthHandle
 type Client struct {
 	Client flight.Client
 
+	// Alloc is the allocator of the records read by the client, unless
+	// the call has a WithClientAllocator option.
 	Alloc memory.Allocator
+	// LocationDialer is used by ReadFlightInfo to connect to the Locations
+	// of an endpoint, closing each connection once the endpoint has been
+	// read. If nil, connections are taken from Locations instead.
+	LocationDialer LocationDialer
+	// Locations caches the connections ReadFlightInfo makes to the
+	// Locations of endpoints. NewClient creates one with the default
+	// options, which can be replaced before the client is used. If both
+	// it and LocationDialer are nil, DialLocation is used.
+	Locations *LocationPool
+
+	// cancelMode is the cancellation action used by Cancel, accessed
+	// atomically
+	cancelMode int32
+	// statements are the prepared statements created by the client which
+	// haven't been closed yet, closed by Close
+	statements *preparedStatements
 }
 
 func descForCommand(cmd proto.Message) (*flight.FlightDescriptor, error) {
@@ -149,32 +193,51 @@ func (c *Client) GetExecuteSchema(ctx context.Context, query string, opts ...grp
 	return schemaForCommand(ctx, c, &cmd, opts...)
 }
 
+// ExecuteSubstrait executes the serialized Substrait plan on the server
+// and returns a FlightInfo object describing where to retrieve the
+// results. To execute it within a transaction use Txn.ExecuteSubstrait.
 func (c *Client) ExecuteSubstrait(ctx context.Context, plan SubstraitPlan, opts ...grpc.CallOption) (*flight.FlightInfo, error) {
 	cmd := pb.CommandStatementSubstraitPlan{
 		Plan: &pb.SubstraitPlan{Plan: plan.Plan, Version: plan.Version}}
 	return flightInfoForCommand(ctx, c, &cmd, opts...)
 }
 
+// ExecuteSubstraitPoll idempotently starts execution of a Substrait plan or
+// checks for its completion, see ExecutePoll.
 func (c *Client) ExecuteSubstraitPoll(ctx context.Context, plan SubstraitPlan, retryDescriptor *flight.FlightDescriptor, opts ...grpc.CallOption) (*flight.PollInfo, error) {
 	cmd := pb.CommandStatementSubstraitPlan{
 		Plan: &pb.SubstraitPlan{Plan: plan.Plan, Version: plan.Version}}
 	return pollInfoForCommand(ctx, c, &cmd, retryDescriptor, opts...)
 }
 
+// GetExecuteSubstraitSchema gets the schema of the result set of a
+// Substrait plan without executing it.
 func (c *Client) GetExecuteSubstraitSchema(ctx context.Context, plan SubstraitPlan, opts ...grpc.CallOption) (*flight.SchemaResult, error) {
 	cmd := pb.CommandStatementSubstraitPlan{
 		Plan: &pb.SubstraitPlan{Plan: plan.Plan, Version: plan.Version}}
 	return schemaForCommand(ctx, c, &cmd, opts...)
 }
 
+// AffectedRows interprets a row count returned by one of the update
+// methods, such as ExecuteUpdate. It returns false if the server reported
+// UpdateResultUnknown because it could not determine how many rows were
+// affected, which must not be confused with a count of zero.
+func AffectedRows(n int64) (rows int64, known bool) {
+	if n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
 // ExecuteUpdate is for executing an update query and only returns the number of affected rows.
+// If the server could not determine the number of affected rows it returns
+// UpdateResultUnknown, see AffectedRows.
 func (c *Client) ExecuteUpdate(ctx context.Context, query string, opts ...grpc.CallOption) (n int64, err error) {
 	var (
-		cmd          pb.CommandStatementUpdate
-		desc         *flight.FlightDescriptor
-		stream       pb.FlightService_DoPutClient
-		res          *pb.PutResult
-		updateResult pb.DoPutUpdateResult
+		cmd    pb.CommandStatementUpdate
+		desc   *flight.FlightDescriptor
+		stream pb.FlightService_DoPutClient
+		res    *pb.PutResult
 	)
 
 	cmd.Query = query
@@ -198,19 +261,17 @@ func (c *Client) ExecuteUpdate(ctx context.Context, query string, opts ...grpc.C
 		return
 	}
 
-	if err = proto.Unmarshal(res.GetAppMetadata(), &updateResult); err != nil {
-		return
-	}
-
-	return updateResult.GetRecordCount(), nil
+	return UnmarshalDoPutUpdateResult(res.GetAppMetadata())
 }
 
+// ExecuteSubstraitUpdate executes the serialized Substrait plan of an
+// update on the server and returns the number of affected rows, which may
+// be UpdateResultUnknown.
 func (c *Client) ExecuteSubstraitUpdate(ctx context.Context, plan SubstraitPlan, opts ...grpc.CallOption) (n int64, err error) {
 	var (
-		desc         *flight.FlightDescriptor
-		stream       pb.FlightService_DoPutClient
-		res          *pb.PutResult
-		updateResult pb.DoPutUpdateResult
+		desc   *flight.FlightDescriptor
+		stream pb.FlightService_DoPutClient
+		res    *pb.PutResult
 	)
 
 	cmd := pb.CommandStatementSubstraitPlan{
@@ -236,11 +297,7 @@ func (c *Client) ExecuteSubstraitUpdate(ctx context.Context, plan SubstraitPlan,
 		return
 	}
 
-	if err = proto.Unmarshal(res.GetAppMetadata(), &updateResult); err != nil {
-		return
-	}
-
-	return updateResult.GetRecordCount(), nil
+	return UnmarshalDoPutUpdateResult(res.GetAppMetadata())
 }
 
 // GetCatalogs requests the list of catalogs from the server and
@@ -266,7 +323,8 @@ func (c *Client) GetDBSchemasSchema(ctx context.Context, opts ...grpc.CallOption
 }
 
 // DoGet uses the provided flight ticket to request the stream of data.
-// It returns a recordbatch reader to stream the results. Release
+// It returns a recordbatch reader to stream the results, decoded with
+// the client's Alloc unless WithClientAllocator is passed. Release
 // should be called on the reader when done.
 func (c *Client) DoGet(ctx context.Context, in *flight.Ticket, opts ...grpc.CallOption) (*flight.Reader, error) {
 	stream, err := c.Client.DoGet(ctx, in, opts...)
@@ -274,7 +332,7 @@ func (c *Client) DoGet(ctx context.Context, in *flight.Ticket, opts ...grpc.Call
 		return nil, err
 	}
 
-	return flight.NewRecordReader(stream, ipc.WithAllocator(c.Alloc))
+	return flight.NewRecordReader(stream, ipc.WithAllocator(c.allocator(opts)))
 }
 
 // GetTables requests a list of tables from the server, with the provided
@@ -424,9 +482,12 @@ func (c *Client) Prepare(ctx context.Context, query string, opts ...grpc.CallOpt
 	if stream, err = c.Client.DoAction(ctx, &action, opts...); err != nil {
 		return
 	}
-	return parsePreparedStatementResponse(c, c.Alloc, stream)
+	return parsePreparedStatementResponse(c, c.Alloc, stream, opts...)
 }
 
+// PrepareSubstrait creates a prepared statement for the serialized
+// Substrait plan on the server. Close should be called on the returned
+// statement when it is no longer needed.
 func (c *Client) PrepareSubstrait(ctx context.Context, plan SubstraitPlan, opts ...grpc.CallOption) (stmt *PreparedStatement, err error) {
 	const actionType = CreatePreparedSubstraitPlanActionType
 
@@ -447,7 +508,98 @@ func (c *Client) PrepareSubstrait(ctx context.Context, plan SubstraitPlan, opts
 	if stream, err = c.Client.DoAction(ctx, &action, opts...); err != nil {
 		return
 	}
-	return parsePreparedStatementResponse(c, c.Alloc, stream)
+	return parsePreparedStatementResponse(c, c.Alloc, stream, opts...)
+}
+
+// PrepareAndExecuteUpdate is a convenience for executing a parameterized
+// update query. It creates a prepared statement for the query, binds params
+// to it (if not nil), executes it and returns the number of affected rows.
+//
+// The prepared statement is always closed before returning, even if the
+// update fails or ctx is done.
+func (c *Client) PrepareAndExecuteUpdate(ctx context.Context, query string, params arrow.Record, opts ...grpc.CallOption) (int64, error) {
+	var records []arrow.Record
+	if params != nil {
+		records = []arrow.Record{params}
+	}
+
+	counts, err := c.PrepareUpdate(ctx, query, records, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return counts[0], nil
+}
+
+// closeDetached closes prep, even once ctx is done, as the update helpers
+// created it and must not leave it open on the server. The values of ctx
+// are kept, but not its deadline or cancellation.
+func closeDetached(ctx context.Context, prep *PreparedStatement, opts ...grpc.CallOption) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), preparedStatementCloseTimeout)
+	defer cancel()
+	return prep.Close(ctx, opts...)
+}
+
+// PrepareAndExecuteUpdateStream is the batch form of
+// PrepareAndExecuteUpdate: it creates a prepared statement for the query,
+// executes it once with all the records of rdr streamed as the parameters,
+// and returns the total number of affected rows reported by the server.
+//
+// The prepared statement is always closed before returning, even if the
+// update fails or ctx is done. rdr is retained for the duration of the call only.
+func (c *Client) PrepareAndExecuteUpdateStream(ctx context.Context, query string, rdr array.RecordReader, opts ...grpc.CallOption) (n int64, err error) {
+	prep, err := c.Prepare(ctx, query, opts...)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := closeDetached(ctx, prep, opts...); err == nil {
+			err = closeErr
+		}
+	}()
+
+	prep.SetRecordReader(rdr)
+	return prep.ExecuteUpdate(ctx, opts...)
+}
+
+// PrepareUpdate creates a single prepared statement for the update query
+// and executes it once for each record in params, binding the record as
+// the parameters. It returns the number of rows affected by each
+// execution. If params is empty, the statement is executed once without
+// any parameters bound and a single count is returned.
+//
+// Execution stops at the first error, in which case the counts of the
+// executions which succeeded are returned along with the error. The
+// prepared statement is always closed before returning, even if ctx is
+// done.
+func (c *Client) PrepareUpdate(ctx context.Context, query string, params []arrow.Record, opts ...grpc.CallOption) (counts []int64, err error) {
+	prep, err := c.Prepare(ctx, query, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := closeDetached(ctx, prep, opts...); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if len(params) == 0 {
+		n, err := prep.ExecuteUpdate(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return []int64{n}, nil
+	}
+
+	counts = make([]int64, 0, len(params))
+	for _, rec := range params {
+		prep.SetParameters(rec)
+		n, err := prep.ExecuteUpdate(ctx, opts...)
+		if err != nil {
+			return counts, err
+		}
+		counts = append(counts, n)
+	}
+	return counts, nil
 }
 
 func (c *Client) LoadPreparedStatementFromResult(result *CreatePreparedStatementResult) (*PreparedStatement, error) {
@@ -467,15 +619,10 @@ func (c *Client) LoadPreparedStatementFromResult(result *CreatePreparedStatement
 			return nil, err
 		}
 	}
-	return &PreparedStatement{
-		client:        c,
-		handle:        result.PreparedStatementHandle,
-		datasetSchema: dsSchema,
-		paramSchema:   paramSchema,
-	}, nil
+	return newPreparedStatement(c, result.PreparedStatementHandle, dsSchema, paramSchema, nil), nil
 }
 
-func parsePreparedStatementResponse(c *Client, mem memory.Allocator, results pb.FlightService_DoActionClient) (*PreparedStatement, error) {
+func parsePreparedStatementResponse(c *Client, mem memory.Allocator, results pb.FlightService_DoActionClient, opts ...grpc.CallOption) (*PreparedStatement, error) {
 	if err := results.CloseSend(); err != nil {
 		return nil, err
 	}
@@ -517,12 +664,7 @@ func parsePreparedStatementResponse(c *Client, mem memory.Allocator, results pb.
 		return nil, err
 	}
 
-	return &PreparedStatement{
-		client:        c,
-		handle:        message.PreparedStatementHandle,
-		datasetSchema: dsSchema,
-		paramSchema:   paramSchema,
-	}, nil
+	return newPreparedStatement(c, message.PreparedStatementHandle, dsSchema, paramSchema, opts), nil
 }
 
 func (c *Client) getFlightInfo(ctx context.Context, desc *flight.FlightDescriptor, opts ...grpc.CallOption) (*flight.FlightInfo, error) {
@@ -534,11 +676,24 @@ func (c *Client) getSchema(ctx context.Context, desc *flight.FlightDescriptor, o
 }
 
 // Close will close the underlying flight Client in use by this flightsql.Client
-func (c *Client) Close() error { return c.Client.Close() }
+// along with its LocationPool. The prepared statements created by the
+// client which are still open are closed on the server first, on a best
+// effort basis.
+func (c *Client) Close() error {
+	c.statements.closeAll()
+	if c.Locations != nil {
+		if err := c.Locations.Close(); err != nil {
+			c.Client.Close()
+			return err
+		}
+	}
+	return c.Client.Close()
+}
 
 // Deprecated: In 13.0.0. Use CancelFlightInfo instead if you can
 // assume that server requires 13.0.0 or later. Otherwise, you may
-// need to use CancelQuery and/or CancelFlightInfo.
+// need to use CancelQuery and/or CancelFlightInfo, or Cancel which
+// picks between them.
 func (c *Client) CancelQuery(ctx context.Context, info *flight.FlightInfo, opts ...grpc.CallOption) (cancelResult CancelResult, err error) {
 	const actionType = CancelQueryActionType
 
@@ -584,6 +739,30 @@ func (c *Client) CancelQuery(ctx context.Context, info *flight.FlightInfo, opts
 	return
 }
 
+// HealthCheck invokes the HealthCheck action, returning the status and
+// uptime reported by the server. Servers which don't support the action
+// fail with an error.
+func (c *Client) HealthCheck(ctx context.Context, opts ...grpc.CallOption) (HealthCheckResult, error) {
+	stream, err := c.Client.DoAction(ctx, &flight.Action{Type: HealthCheckActionType}, opts...)
+	if err != nil {
+		return HealthCheckResult{}, err
+	}
+	defer stream.CloseSend()
+
+	res, err := stream.Recv()
+	if err != nil {
+		return HealthCheckResult{}, err
+	}
+
+	if err = flight.ReadUntilEOF(stream); err != nil {
+		return HealthCheckResult{}, err
+	}
+
+	return unmarshalHealthCheckResult(res.Body)
+}
+
+// CancelFlightInfo invokes the CancelFlightInfo action, which servers
+// older than 13.0.0 don't support. See Cancel.
 func (c *Client) CancelFlightInfo(ctx context.Context, request *flight.CancelFlightInfoRequest, opts ...grpc.CallOption) (*flight.CancelFlightInfoResult, error) {
 	return c.Client.CancelFlightInfo(ctx, request, opts...)
 }
@@ -592,18 +771,27 @@ func (c *Client) RenewFlightEndpoint(ctx context.Context, request *flight.RenewF
 	return c.Client.RenewFlightEndpoint(ctx, request, opts...)
 }
 
+// SetSessionOptions invokes the SetSessionOptions action, see
+// SetSessionOptionValues.
 func (c *Client) SetSessionOptions(ctx context.Context, request *flight.SetSessionOptionsRequest, opts ...grpc.CallOption) (*flight.SetSessionOptionsResult, error) {
 	return c.Client.SetSessionOptions(ctx, request, opts...)
 }
 
+// GetSessionOptions invokes the GetSessionOptions action, see
+// GetSessionOptionValues.
 func (c *Client) GetSessionOptions(ctx context.Context, request *flight.GetSessionOptionsRequest, opts ...grpc.CallOption) (*flight.GetSessionOptionsResult, error) {
 	return c.Client.GetSessionOptions(ctx, request, opts...)
 }
 
+// CloseSession invokes the CloseSession action, ending the session of
+// the client. The request has no fields.
 func (c *Client) CloseSession(ctx context.Context, request *flight.CloseSessionRequest, opts ...grpc.CallOption) (*flight.CloseSessionResult, error) {
 	return c.Client.CloseSession(ctx, request, opts...)
 }
 
+// BeginTransaction starts a transaction on the server and returns a Txn
+// through which statements are executed within it. The transaction ends
+// when Commit or Rollback is called.
 func (c *Client) BeginTransaction(ctx context.Context, opts ...grpc.CallOption) (*Txn, error) {
 	request := &pb.ActionBeginTransactionRequest{}
 	action, err := packAction(BeginTransactionActionType, request)
@@ -648,29 +836,56 @@ func (tx Transaction) IsValid() bool { return len(tx) != 0 }
 
 var (
 	ErrInvalidTxn         = fmt.Errorf("%w: missing a valid transaction", arrow.ErrInvalid)
+	ErrTxnFinished        = fmt.Errorf("%w: transaction has already been committed or rolled back", ErrInvalidTxn)
 	ErrInvalidSavepoint   = fmt.Errorf("%w: missing a valid savepoint", arrow.ErrInvalid)
 	ErrBadServerTxn       = fmt.Errorf("%w: server returned an empty transaction ID", arrow.ErrInvalid)
 	ErrBadServerSavepoint = fmt.Errorf("%w: server returned an empty savepoint ID", arrow.ErrInvalid)
 )
 
+// Txn is a transaction started with Client.BeginTransaction. The
+// statements executed or prepared through it set its ID as their
+// transaction_id so that the server runs them within the transaction.
+//
+// Once Commit or Rollback has been called, every method returns an
+// error wrapping ErrTxnFinished. A Txn is not safe for concurrent use
+// and must only be used from one goroutine at a time; the prepared
+// statements it creates may be used independently.
 type Txn struct {
-	c   *Client
-	txn Transaction
+	c    *Client
+	txn  Transaction
+	done bool
 }
 
-func (tx *Txn) ID() Transaction { return tx.txn }
+// ID returns the handle of the transaction on the server, or an invalid
+// Transaction once it has been committed or rolled back.
+func (tx *Txn) ID() Transaction {
+	if tx.done {
+		return nil
+	}
+	return tx.txn
+}
 
-func (tx *Txn) Execute(ctx context.Context, query string, opts ...grpc.CallOption) (*flight.FlightInfo, error) {
+func (tx *Txn) valid() error {
+	if tx.done {
+		return ErrTxnFinished
+	}
 	if !tx.txn.IsValid() {
-		return nil, ErrInvalidTxn
+		return ErrInvalidTxn
+	}
+	return nil
+}
+
+func (tx *Txn) Execute(ctx context.Context, query string, opts ...grpc.CallOption) (*flight.FlightInfo, error) {
+	if err := tx.valid(); err != nil {
+		return nil, err
 	}
 	cmd := &pb.CommandStatementQuery{Query: query, TransactionId: tx.txn}
 	return flightInfoForCommand(ctx, tx.c, cmd, opts...)
 }
 
 func (tx *Txn) ExecutePoll(ctx context.Context, query string, retryDescriptor *flight.FlightDescriptor, opts ...grpc.CallOption) (*flight.PollInfo, error) {
-	if !tx.txn.IsValid() {
-		return nil, ErrInvalidTxn
+	if err := tx.valid(); err != nil {
+		return nil, err
 	}
 	// The server should encode the transaction into the retry descriptor
 	cmd := &pb.CommandStatementQuery{Query: query, TransactionId: tx.txn}
@@ -678,8 +893,8 @@ func (tx *Txn) ExecutePoll(ctx context.Context, query string, retryDescriptor *f
 }
 
 func (tx *Txn) ExecuteSubstrait(ctx context.Context, plan SubstraitPlan, opts ...grpc.CallOption) (*flight.FlightInfo, error) {
-	if !tx.txn.IsValid() {
-		return nil, ErrInvalidTxn
+	if err := tx.valid(); err != nil {
+		return nil, err
 	}
 	cmd := &pb.CommandStatementSubstraitPlan{
 		Plan:          &pb.SubstraitPlan{Plan: plan.Plan, Version: plan.Version},
@@ -688,8 +903,8 @@ func (tx *Txn) ExecuteSubstrait(ctx context.Context, plan SubstraitPlan, opts ..
 }
 
 func (tx *Txn) ExecuteSubstraitPoll(ctx context.Context, plan SubstraitPlan, retryDescriptor *flight.FlightDescriptor, opts ...grpc.CallOption) (*flight.PollInfo, error) {
-	if !tx.txn.IsValid() {
-		return nil, ErrInvalidTxn
+	if err := tx.valid(); err != nil {
+		return nil, err
 	}
 	// The server should encode the transaction into the retry descriptor
 	cmd := &pb.CommandStatementSubstraitPlan{
@@ -700,16 +915,16 @@ func (tx *Txn) ExecuteSubstraitPoll(ctx context.Context, plan SubstraitPlan, ret
 }
 
 func (tx *Txn) GetExecuteSchema(ctx context.Context, query string, opts ...grpc.CallOption) (*flight.SchemaResult, error) {
-	if !tx.txn.IsValid() {
-		return nil, ErrInvalidTxn
+	if err := tx.valid(); err != nil {
+		return nil, err
 	}
 	cmd := &pb.CommandStatementQuery{Query: query, TransactionId: tx.txn}
 	return schemaForCommand(ctx, tx.c, cmd, opts...)
 }
 
 func (tx *Txn) GetExecuteSubstraitSchema(ctx context.Context, plan SubstraitPlan, opts ...grpc.CallOption) (*flight.SchemaResult, error) {
-	if !tx.txn.IsValid() {
-		return nil, ErrInvalidTxn
+	if err := tx.valid(); err != nil {
+		return nil, err
 	}
 	cmd := &pb.CommandStatementSubstraitPlan{
 		Plan:          &pb.SubstraitPlan{Plan: plan.Plan, Version: plan.Version},
@@ -718,8 +933,8 @@ func (tx *Txn) GetExecuteSubstraitSchema(ctx context.Context, plan SubstraitPlan
 }
 
 func (tx *Txn) ExecuteUpdate(ctx context.Context, query string, opts ...grpc.CallOption) (n int64, err error) {
-	if !tx.txn.IsValid() {
-		return 0, ErrInvalidTxn
+	if err := tx.valid(); err != nil {
+		return 0, err
 	}
 
 	var (
@@ -727,10 +942,9 @@ func (tx *Txn) ExecuteUpdate(ctx context.Context, query string, opts ...grpc.Cal
 			Query:         query,
 			TransactionId: tx.txn,
 		}
-		desc         *flight.FlightDescriptor
-		stream       pb.FlightService_DoPutClient
-		res          *pb.PutResult
-		updateResult pb.DoPutUpdateResult
+		desc   *flight.FlightDescriptor
+		stream pb.FlightService_DoPutClient
+		res    *pb.PutResult
 	)
 	if desc, err = descForCommand(cmd); err != nil {
 		return
@@ -752,23 +966,18 @@ func (tx *Txn) ExecuteUpdate(ctx context.Context, query string, opts ...grpc.Cal
 		return
 	}
 
-	if err = proto.Unmarshal(res.GetAppMetadata(), &updateResult); err != nil {
-		return
-	}
-
-	return updateResult.GetRecordCount(), nil
+	return UnmarshalDoPutUpdateResult(res.GetAppMetadata())
 }
 
 func (tx *Txn) ExecuteSubstraitUpdate(ctx context.Context, plan SubstraitPlan, opts ...grpc.CallOption) (n int64, err error) {
-	if !tx.txn.IsValid() {
-		return 0, ErrInvalidTxn
+	if err := tx.valid(); err != nil {
+		return 0, err
 	}
 
 	var (
-		desc         *flight.FlightDescriptor
-		stream       pb.FlightService_DoPutClient
-		res          *pb.PutResult
-		updateResult pb.DoPutUpdateResult
+		desc   *flight.FlightDescriptor
+		stream pb.FlightService_DoPutClient
+		res    *pb.PutResult
 	)
 
 	cmd := pb.CommandStatementSubstraitPlan{
@@ -796,16 +1005,12 @@ func (tx *Txn) ExecuteSubstraitUpdate(ctx context.Context, plan SubstraitPlan, o
 		return
 	}
 
-	if err = proto.Unmarshal(res.GetAppMetadata(), &updateResult); err != nil {
-		return
-	}
-
-	return updateResult.GetRecordCount(), nil
+	return UnmarshalDoPutUpdateResult(res.GetAppMetadata())
 }
 
 func (tx *Txn) Prepare(ctx context.Context, query string, opts ...grpc.CallOption) (prep *PreparedStatement, err error) {
-	if !tx.txn.IsValid() {
-		return nil, ErrInvalidTxn
+	if err := tx.valid(); err != nil {
+		return nil, err
 	}
 
 	const actionType = CreatePreparedStatementActionType
@@ -826,12 +1031,12 @@ func (tx *Txn) Prepare(ctx context.Context, query string, opts ...grpc.CallOptio
 	if stream, err = tx.c.Client.DoAction(ctx, &action, opts...); err != nil {
 		return
 	}
-	return parsePreparedStatementResponse(tx.c, tx.c.Alloc, stream)
+	return parsePreparedStatementResponse(tx.c, tx.c.Alloc, stream, opts...)
 }
 
 func (tx *Txn) PrepareSubstrait(ctx context.Context, plan SubstraitPlan, opts ...grpc.CallOption) (stmt *PreparedStatement, err error) {
-	if !tx.txn.IsValid() {
-		return nil, ErrInvalidTxn
+	if err := tx.valid(); err != nil {
+		return nil, err
 	}
 
 	const actionType = CreatePreparedSubstraitPlanActionType
@@ -855,68 +1060,71 @@ func (tx *Txn) PrepareSubstrait(ctx context.Context, plan SubstraitPlan, opts ..
 	if stream, err = tx.c.Client.DoAction(ctx, &action, opts...); err != nil {
 		return
 	}
-	return parsePreparedStatementResponse(tx.c, tx.c.Alloc, stream)
+	return parsePreparedStatementResponse(tx.c, tx.c.Alloc, stream, opts...)
 }
 
+// Commit commits the transaction. The Txn can no longer be used afterwards,
+// even if the server fails to commit.
 func (tx *Txn) Commit(ctx context.Context, opts ...grpc.CallOption) error {
-	if !tx.txn.IsValid() {
-		return ErrInvalidTxn
-	}
-
-	request := &pb.ActionEndTransactionRequest{
-		TransactionId: tx.txn,
-		Action:        EndTransactionCommit,
-	}
-
-	action, err := packAction(EndTransactionActionType, request)
-	if err != nil {
-		return err
-	}
-
-	stream, err := tx.c.Client.DoAction(ctx, &action, opts...)
-	if err != nil {
-		return err
-	}
-
-	if err := stream.CloseSend(); err != nil {
-		return err
-	}
+	_, err := tx.end(ctx, EndTransactionCommit, opts...)
+	return err
+}
 
-	tx.txn = nil
-	return flight.ReadUntilEOF(stream)
+// CommitWithResult commits the transaction like Commit, returning the
+// result reported by the server, such as commit metadata or the id of a
+// follow-on transaction. The result is empty if the server doesn't
+// report one.
+func (tx *Txn) CommitWithResult(ctx context.Context, opts ...grpc.CallOption) ([]byte, error) {
+	return tx.end(ctx, EndTransactionCommit, opts...)
 }
 
+// Rollback rolls back the transaction. The Txn can no longer be used
+// afterwards, even if the server fails to roll back.
 func (tx *Txn) Rollback(ctx context.Context, opts ...grpc.CallOption) error {
-	if !tx.txn.IsValid() {
-		return ErrInvalidTxn
+	_, err := tx.end(ctx, EndTransactionRollback, opts...)
+	return err
+}
+
+func (tx *Txn) end(ctx context.Context, endAction EndTransactionRequestType, opts ...grpc.CallOption) ([]byte, error) {
+	if err := tx.valid(); err != nil {
+		return nil, err
 	}
+	// the transaction is finished even if the request fails, the server
+	// may have ended it
+	tx.done = true
 
 	request := &pb.ActionEndTransactionRequest{
 		TransactionId: tx.txn,
-		Action:        EndTransactionRollback,
+		Action:        endAction,
 	}
 
 	action, err := packAction(EndTransactionActionType, request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	stream, err := tx.c.Client.DoAction(ctx, &action, opts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := stream.CloseSend(); err != nil {
-		return err
+		return nil, err
 	}
 
-	tx.txn = nil
-	return flight.ReadUntilEOF(stream)
+	res, err := stream.Recv()
+	switch {
+	case err == io.EOF:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return res.GetBody(), flight.ReadUntilEOF(stream)
 }
 
 func (tx *Txn) BeginSavepoint(ctx context.Context, name string, opts ...grpc.CallOption) (Savepoint, error) {
-	if !tx.txn.IsValid() {
-		return nil, ErrInvalidTxn
+	if err := tx.valid(); err != nil {
+		return nil, err
 	}
 
 	request := &pb.ActionBeginSavepointRequest{
@@ -955,6 +1163,9 @@ func (tx *Txn) BeginSavepoint(ctx context.Context, name string, opts ...grpc.Cal
 }
 
 func (tx *Txn) ReleaseSavepoint(ctx context.Context, sp Savepoint, opts ...grpc.CallOption) error {
+	if err := tx.valid(); err != nil {
+		return err
+	}
 	if !sp.IsValid() {
 		return ErrInvalidSavepoint
 	}
@@ -981,6 +1192,9 @@ func (tx *Txn) ReleaseSavepoint(ctx context.Context, sp Savepoint, opts ...grpc.
 }
 
 func (tx *Txn) RollbackSavepoint(ctx context.Context, sp Savepoint, opts ...grpc.CallOption) error {
+	if err := tx.valid(); err != nil {
+		return err
+	}
 	if !sp.IsValid() {
 		return ErrInvalidSavepoint
 	}
@@ -1013,14 +1227,19 @@ func (tx *Txn) RollbackSavepoint(ctx context.Context, sp Savepoint, opts ...grpc
 // If the server returned the Dataset Schema or Parameter Binding schemas
 // at creation, they will also be accessible from this object. Close
 // should be called when no longer needed.
+//
+// Calls executing the statement, which bind its parameters and may get
+// an updated handle in return, are serialized so that each executes with
+// the handle returned for the parameters it bound.
 type PreparedStatement struct {
-	client        *Client
-	handle        []byte
+	client *Client
+	life   *preparedStatementLife
+	// exec serializes the calls binding the parameters of the statement
+	exec          sync.Mutex
 	datasetSchema *arrow.Schema
 	paramSchema   *arrow.Schema
 	paramBinding  arrow.Record
 	streamBinding array.RecordReader
-	closed        bool
 }
 
 // Execute executes the prepared statement on the server and returns a FlightInfo
@@ -1029,36 +1248,22 @@ type PreparedStatement struct {
 //
 // Will error if already closed.
 func (p *PreparedStatement) Execute(ctx context.Context, opts ...grpc.CallOption) (*flight.FlightInfo, error) {
-	if p.closed {
-		return nil, errors.New("arrow/flightsql: prepared statement already closed")
-	}
-
-	cmd := &pb.CommandPreparedStatementQuery{PreparedStatementHandle: p.handle}
-
-	desc, err := descForCommand(cmd)
-	if err != nil {
+	if err := p.life.begin(); err != nil {
 		return nil, err
 	}
+	defer p.life.end()
+	p.exec.Lock()
+	defer p.exec.Unlock()
 
 	if p.hasBindParameters() {
-		pstream, err := p.client.Client.DoPut(ctx, opts...)
-		if err != nil {
-			return nil, err
-		}
-
-		wr, err := p.writeBindParameters(pstream, desc)
-		if err != nil {
-			return nil, err
-		}
-		if err = wr.Close(); err != nil {
+		if err := p.bindParameters(ctx, opts...); err != nil {
 			return nil, err
 		}
-		pstream.CloseSend()
+	}
 
-		// wait for the server to ack the result
-		if _, err = pstream.Recv(); err != nil && err != io.EOF {
-			return nil, err
-		}
+	desc, err := descForCommand(&pb.CommandPreparedStatementQuery{PreparedStatementHandle: p.life.currentHandle()})
+	if err != nil {
+		return nil, err
 	}
 
 	return p.client.getFlightInfo(ctx, desc, opts...)
@@ -1069,38 +1274,61 @@ func (p *PreparedStatement) Execute(ctx context.Context, opts ...grpc.CallOption
 //
 // Will error if already closed.
 func (p *PreparedStatement) ExecutePut(ctx context.Context, opts ...grpc.CallOption) error {
-	if p.closed {
-		return errors.New("arrow/flightsql: prepared statement already closed")
+	if err := p.life.begin(); err != nil {
+		return err
+	}
+	defer p.life.end()
+	p.exec.Lock()
+	defer p.exec.Unlock()
+
+	if p.hasBindParameters() {
+		return p.bindParameters(ctx, opts...)
 	}
 
-	cmd := &pb.CommandPreparedStatementQuery{PreparedStatementHandle: p.handle}
+	return nil
+}
 
-	desc, err := descForCommand(cmd)
+// bindParameters sends the parameter bindings to the server with DoPut.
+// If the server responds with an updated handle for the statement, it
+// replaces the current handle.
+func (p *PreparedStatement) bindParameters(ctx context.Context, opts ...grpc.CallOption) error {
+	if err := p.checkBindParameters(); err != nil {
+		return err
+	}
+
+	desc, err := descForCommand(&pb.CommandPreparedStatementQuery{PreparedStatementHandle: p.life.currentHandle()})
 	if err != nil {
 		return err
 	}
 
-	if p.hasBindParameters() {
-		pstream, err := p.client.Client.DoPut(ctx, opts...)
-		if err != nil {
-			return err
-		}
+	pstream, err := p.client.Client.DoPut(ctx, opts...)
+	if err != nil {
+		return err
+	}
 
-		wr, err := p.writeBindParameters(pstream, desc)
-		if err != nil {
-			return err
-		}
-		if err = wr.Close(); err != nil {
-			return err
-		}
-		pstream.CloseSend()
+	wr, err := p.writeBindParameters(pstream, desc, opts)
+	if err != nil {
+		return err
+	}
+	if err = wr.Close(); err != nil {
+		return err
+	}
+	pstream.CloseSend()
 
-		// wait for the server to ack the result
-		if _, err = pstream.Recv(); err != nil && err != io.EOF {
-			return err
-		}
+	// wait for the server to ack the result, servers may optionally
+	// respond with a DoPutPreparedStatementResult with an updated handle
+	res, err := pstream.Recv()
+	if err != nil && err != io.EOF {
+		return err
 	}
 
+	handle, ok, err := unmarshalPreparedStatementResult(res.GetAppMetadata())
+	if err != nil {
+		return err
+	}
+	if ok {
+		p.life.setHandle(handle)
+	}
 	return nil
 }
 
@@ -1109,44 +1337,28 @@ func (p *PreparedStatement) ExecutePut(ctx context.Context, opts ...grpc.CallOpt
 //
 // Will error if already closed.
 func (p *PreparedStatement) ExecutePoll(ctx context.Context, retryDescriptor *flight.FlightDescriptor, opts ...grpc.CallOption) (*flight.PollInfo, error) {
-	if p.closed {
-		return nil, errors.New("arrow/flightsql: prepared statement already closed")
+	if err := p.life.begin(); err != nil {
+		return nil, err
 	}
-
-	cmd := &pb.CommandPreparedStatementQuery{PreparedStatementHandle: p.handle}
+	defer p.life.end()
+	p.exec.Lock()
+	defer p.exec.Unlock()
 
 	desc := retryDescriptor
-	var err error
-
 	if desc == nil {
-		desc, err = descForCommand(cmd)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	if retryDescriptor == nil {
 		if p.hasBindParameters() {
-			pstream, err := p.client.Client.DoPut(ctx, opts...)
-			if err != nil {
+			if err := p.bindParameters(ctx, opts...); err != nil {
 				return nil, err
 			}
+		}
 
-			wr, err := p.writeBindParameters(pstream, desc)
-			if err != nil {
-				return nil, err
-			}
-			if err = wr.Close(); err != nil {
-				return nil, err
-			}
-			pstream.CloseSend()
-
-			// wait for the server to ack the result
-			if _, err = pstream.Recv(); err != nil && err != io.EOF {
-				return nil, err
-			}
+		var err error
+		desc, err = descForCommand(&pb.CommandPreparedStatementQuery{PreparedStatementHandle: p.life.currentHandle()})
+		if err != nil {
+			return nil, err
 		}
 	}
+
 	return p.client.Client.PollFlightInfo(ctx, desc, opts...)
 }
 
@@ -1154,19 +1366,24 @@ func (p *PreparedStatement) ExecutePoll(ctx context.Context, retryDescriptor *fl
 // and returns the number of rows affected. If SetParameters was called,
 // the parameter bindings will be sent with the request to execute.
 func (p *PreparedStatement) ExecuteUpdate(ctx context.Context, opts ...grpc.CallOption) (nrecords int64, err error) {
-	if p.closed {
-		return 0, errors.New("arrow/flightsql: prepared statement already closed")
+	if err := p.life.begin(); err != nil {
+		return 0, err
 	}
+	defer p.life.end()
+	p.exec.Lock()
+	defer p.exec.Unlock()
 
 	var (
-		execCmd      = &pb.CommandPreparedStatementUpdate{PreparedStatementHandle: p.handle}
-		desc         *flight.FlightDescriptor
-		pstream      pb.FlightService_DoPutClient
-		wr           *flight.Writer
-		res          *pb.PutResult
-		updateResult pb.DoPutUpdateResult
+		execCmd = &pb.CommandPreparedStatementUpdate{PreparedStatementHandle: p.life.currentHandle()}
+		desc    *flight.FlightDescriptor
+		pstream pb.FlightService_DoPutClient
+		wr      *flight.Writer
 	)
 
+	if err = p.checkBindParameters(); err != nil {
+		return
+	}
+
 	desc, err = descForCommand(execCmd)
 	if err != nil {
 		return
@@ -1176,7 +1393,7 @@ func (p *PreparedStatement) ExecuteUpdate(ctx context.Context, opts ...grpc.Call
 		return
 	}
 	if p.hasBindParameters() {
-		wr, err = p.writeBindParameters(pstream, desc)
+		wr, err = p.writeBindParameters(pstream, desc, opts)
 		if err != nil {
 			return
 		}
@@ -1190,50 +1407,180 @@ func (p *PreparedStatement) ExecuteUpdate(ctx context.Context, opts ...grpc.Call
 		}
 	}
 
-	if err = wr.Close(); err != nil {
-		return
+	return finishUpdate(pstream, wr)
+}
+
+// ExecuteUpdateStream executes the prepared statement update query on the
+// server once for every row of the records received from recs, sent as
+// the parameters of a single DoPut, and returns the total number of rows
+// affected. This allows binding more parameters than fit in a record,
+// such as for batch updates, without materializing them.
+//
+// The records must all have the same schema, which is sent once, and are
+// released once written. The call ends once recs is closed. If an error
+// occurs before then, the remaining records are drained from recs and
+// released in the background.
+func (p *PreparedStatement) ExecuteUpdateStream(ctx context.Context, recs <-chan arrow.Record, opts ...grpc.CallOption) (n int64, err error) {
+	defer func() {
+		if err != nil {
+			go func() {
+				for rec := range recs {
+					rec.Release()
+				}
+			}()
+		}
+	}()
+
+	if err := p.life.begin(); err != nil {
+		return 0, err
 	}
-	if err = pstream.CloseSend(); err != nil {
-		return
+	defer p.life.end()
+	p.exec.Lock()
+	defer p.exec.Unlock()
+
+	desc, err := descForCommand(&pb.CommandPreparedStatementUpdate{PreparedStatementHandle: p.life.currentHandle()})
+	if err != nil {
+		return 0, err
 	}
-	if res, err = pstream.Recv(); err != nil {
-		return
+
+	rec, ok, err := recvRecord(ctx, recs)
+	if err != nil {
+		return 0, err
 	}
 
-	if err = proto.Unmarshal(res.GetAppMetadata(), &updateResult); err != nil {
-		return
+	schema := p.paramSchema
+	if ok {
+		schema = rec.Schema()
+		if p.paramSchema != nil {
+			err = p.checkParameterSchema(schema)
+		}
+	} else if schema == nil {
+		schema = arrow.NewSchema([]arrow.Field{}, nil)
+	}
+
+	var pstream pb.FlightService_DoPutClient
+	if err == nil {
+		pstream, err = p.client.Client.DoPut(ctx, opts...)
+	}
+	if err != nil {
+		if ok {
+			rec.Release()
+		}
+		return 0, err
+	}
+
+	wr := flight.NewRecordWriter(pstream, ipc.WithSchema(schema))
+	wr.SetFlightDescriptor(desc)
+	for ok {
+		err = wr.Write(rec)
+		rec.Release()
+		if err != nil {
+			return 0, err
+		}
+		if rec, ok, err = recvRecord(ctx, recs); err != nil {
+			return 0, err
+		}
+	}
+
+	return finishUpdate(pstream, wr)
+}
+
+// recvRecord receives the next record from recs, returning false once recs
+// is closed.
+func recvRecord(ctx context.Context, recs <-chan arrow.Record) (arrow.Record, bool, error) {
+	select {
+	case rec, ok := <-recs:
+		return rec, ok, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
 	}
+}
 
-	return updateResult.GetRecordCount(), nil
+// finishUpdate ends the DoPut of an update and returns the number of
+// affected rows reported by the server.
+func finishUpdate(pstream pb.FlightService_DoPutClient, wr *flight.Writer) (int64, error) {
+	if err := wr.Close(); err != nil {
+		return 0, err
+	}
+	if err := pstream.CloseSend(); err != nil {
+		return 0, err
+	}
+	res, err := pstream.Recv()
+	if err != nil {
+		return 0, err
+	}
+
+	return UnmarshalDoPutUpdateResult(res.GetAppMetadata())
 }
 
 func (p *PreparedStatement) hasBindParameters() bool {
 	return (p.paramBinding != nil && p.paramBinding.NumRows() > 0) || (p.streamBinding != nil)
 }
 
-func (p *PreparedStatement) writeBindParameters(pstream pb.FlightService_DoPutClient, desc *pb.FlightDescriptor) (*flight.Writer, error) {
+// checkBindParameters verifies that the bound parameters can be used with
+// the parameter schema returned by the server, if any. Differences which
+// servers may coerce, such as the width of integers or the nullability of
+// fields, are left for the server to accept or reject.
+func (p *PreparedStatement) checkBindParameters() error {
+	if p.paramSchema == nil || !p.hasBindParameters() {
+		return nil
+	}
+
+	var schema *arrow.Schema
+	if p.paramBinding != nil {
+		schema = p.paramBinding.Schema()
+	} else {
+		schema = p.streamBinding.Schema()
+	}
+
+	return p.checkParameterSchema(schema)
+}
+
+// checkParameterSchema verifies that parameters with the given schema can
+// be used with the parameter schema returned by the server.
+func (p *PreparedStatement) checkParameterSchema(schema *arrow.Schema) error {
+	if err := checkParameterCoercion(schema, p.paramSchema); err != nil {
+		return status.Errorf(codes.InvalidArgument,
+			"arrow/flightsql: parameters do not match the parameter schema of the prepared statement: %s\nexpected: %s\ngot: %s",
+			status.Convert(err).Message(), p.paramSchema, schema)
+	}
+	return nil
+}
+
+func (p *PreparedStatement) writeBindParameters(pstream pb.FlightService_DoPutClient, desc *pb.FlightDescriptor, opts []grpc.CallOption) (*flight.Writer, error) {
+	maxBytes := maxParameterBatchBytes(opts)
 	if p.paramBinding != nil {
 		wr := flight.NewRecordWriter(pstream, ipc.WithSchema(p.paramBinding.Schema()))
 		wr.SetFlightDescriptor(desc)
-		if err := wr.Write(p.paramBinding); err != nil {
-			return nil, err
+		if err := writeParameters(wr, p.paramBinding, maxBytes); err != nil {
+			return nil, doPutError(pstream, err)
 		}
 		return wr, nil
 	} else {
 		wr := flight.NewRecordWriter(pstream, ipc.WithSchema(p.streamBinding.Schema()))
 		wr.SetFlightDescriptor(desc)
 		for p.streamBinding.Next() {
-			if err := wr.Write(p.streamBinding.Record()); err != nil {
-				return nil, err
+			if err := writeParameters(wr, p.streamBinding.Record(), maxBytes); err != nil {
+				p.abortStreamBinding()
+				return nil, doPutError(pstream, err)
 			}
 		}
 		if err := p.streamBinding.Err(); err != nil {
+			p.abortStreamBinding()
 			return nil, err
 		}
 		return wr, nil
 	}
 }
 
+// abortStreamBinding releases the parameter reader which failed to be
+// sent, along with the record it holds, rather than leaving it partially
+// consumed.
+func (p *PreparedStatement) abortStreamBinding() {
+	p.streamBinding.Release()
+	p.streamBinding = nil
+}
+
 // DatasetSchema may be nil if the server did not return it when creating the
 // Prepared Statement.
 func (p *PreparedStatement) DatasetSchema() *arrow.Schema { return p.datasetSchema }
@@ -1242,19 +1589,23 @@ func (p *PreparedStatement) DatasetSchema() *arrow.Schema { return p.datasetSche
 // the prepared statement.
 func (p *PreparedStatement) ParameterSchema() *arrow.Schema { return p.paramSchema }
 
-// The handle associated with this PreparedStatement
-func (p *PreparedStatement) Handle() []byte { return p.handle }
+// The handle associated with this PreparedStatement. Servers may return
+// an updated handle when parameters are bound, so this can change after
+// calling Execute, ExecutePut or ExecutePoll. The latest handle is the one
+// used by subsequent calls, including Close.
+func (p *PreparedStatement) Handle() []byte { return p.life.currentHandle() }
 
 // GetSchema re-requests the schema of the result set of the prepared
 // statement from the server. It should otherwise be identical to DatasetSchema.
 //
 // Will error if already closed.
 func (p *PreparedStatement) GetSchema(ctx context.Context, opts ...grpc.CallOption) (*flight.SchemaResult, error) {
-	if p.closed {
-		return nil, errors.New("arrow/flightsql: prepared statement already closed")
+	if err := p.life.begin(); err != nil {
+		return nil, err
 	}
+	defer p.life.end()
 
-	cmd := &pb.CommandPreparedStatementQuery{PreparedStatementHandle: p.handle}
+	cmd := &pb.CommandPreparedStatementQuery{PreparedStatementHandle: p.life.currentHandle()}
 
 	desc, err := descForCommand(cmd)
 	if err != nil {
@@ -1264,6 +1615,68 @@ func (p *PreparedStatement) GetSchema(ctx context.Context, opts ...grpc.CallOpti
 	return p.client.getSchema(ctx, desc, opts...)
 }
 
+// RefreshDatasetSchema requests the schema of the result set from the
+// server using the current handle and updates the value returned by
+// DatasetSchema. This is useful when binding parameters caused the server
+// to return a new handle, as the dataset schema may have changed with it.
+// The DoPutPreparedStatementResult of the binding only carries the new
+// handle, not the schema, hence the GetSchema request.
+//
+// If the server doesn't implement GetSchema, WithFlightInfoSchemaFallback
+// allows the schema to be read from the FlightInfo of the statement.
+//
+// Will error if already closed.
+func (p *PreparedStatement) RefreshDatasetSchema(ctx context.Context, opts ...grpc.CallOption) (*arrow.Schema, error) {
+	if err := p.life.begin(); err != nil {
+		return nil, err
+	}
+	defer p.life.end()
+
+	cmd := &pb.CommandPreparedStatementQuery{PreparedStatementHandle: p.life.currentHandle()}
+	schema, err := p.client.resultSchema(ctx, cmd, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p.datasetSchema = schema
+	return schema, nil
+}
+
+// Explain returns the query plan of the prepared statement, as text or
+// JSON depending on the server, using the ExplainPreparedStatement
+// action. Servers which don't support the action fail with an error.
+//
+// Will error if already closed.
+func (p *PreparedStatement) Explain(ctx context.Context, opts ...grpc.CallOption) (string, error) {
+	if err := p.life.begin(); err != nil {
+		return "", err
+	}
+	defer p.life.end()
+
+	request := &pb.CommandPreparedStatementQuery{PreparedStatementHandle: p.life.currentHandle()}
+	action, err := packAction(ExplainPreparedStatementActionType, request)
+	if err != nil {
+		return "", err
+	}
+
+	stream, err := p.client.Client.DoAction(ctx, &action, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer stream.CloseSend()
+
+	res, err := stream.Recv()
+	if err != nil {
+		return "", err
+	}
+
+	if err = flight.ReadUntilEOF(stream); err != nil {
+		return "", err
+	}
+
+	return string(res.Body), nil
+}
+
 func (p *PreparedStatement) clearParameters() {
 	if p.paramBinding != nil {
 		p.paramBinding.Release()
@@ -1276,7 +1689,9 @@ func (p *PreparedStatement) clearParameters() {
 }
 
 // SetParameters takes a record batch to send as the parameter bindings when
-// executing. It should match the schema from ParameterSchema.
+// executing. Each row of the record is a set of parameters, so several rows
+// can be bound to execute the statement as a batch. It should match the
+// schema from ParameterSchema, which is checked when executing.
 //
 // This will call Retain on the record to ensure it doesn't get released out
 // from under the statement. Release will be called on a previous binding
@@ -1291,7 +1706,15 @@ func (p *PreparedStatement) SetParameters(binding arrow.Record) {
 }
 
 // SetRecordReader takes a RecordReader to send as the parameter bindings when
-// executing. It should match the schema from ParameterSchema.
+// executing. It should match the schema from ParameterSchema, which is checked
+// when executing. The reader is consumed by the next execution, so it must be
+// set again to execute the statement again with parameters.
+//
+// Each record is sent as it is read, without buffering the whole set of
+// parameters, so this allows binding more parameters than fit in memory.
+// If sending fails, for instance because the server failed the call, the
+// reader is released without being read any further. See also
+// WithMaxParameterBatchBytes.
 //
 // This will call Retain on the reader to ensure it doesn't get released out
 // from under the statement. Release will be called on a previous binding
@@ -1299,47 +1722,26 @@ func (p *PreparedStatement) SetParameters(binding arrow.Record) {
 // PreparedStatement.
 func (p *PreparedStatement) SetRecordReader(binding array.RecordReader) {
 	p.clearParameters()
-	binding.Retain()
 	p.streamBinding = binding
-	p.streamBinding.Retain()
+	if p.streamBinding != nil {
+		p.streamBinding.Retain()
+	}
 }
 
 // Close calls release on any parameter binding record and sends
 // a ClosePreparedStatement action to the server. After calling
 // Close, the PreparedStatement should not be used again.
+//
+// Close may be called concurrently with the other methods, in which
+// case it waits for the calls in progress to complete before closing the
+// statement. Calling Close again, or after the statement was closed
+// because of ClosePreparedStatementOnDone, does nothing.
 func (p *PreparedStatement) Close(ctx context.Context, opts ...grpc.CallOption) error {
-	if p.closed {
-		return errors.New("arrow/flightsql: already closed")
+	if !p.life.markClosed() {
+		return nil
 	}
 
+	p.life.inflight.Wait()
 	p.clearParameters()
-
-	const actionType = ClosePreparedStatementActionType
-	var (
-		cmd     anypb.Any
-		request pb.ActionClosePreparedStatementRequest
-	)
-
-	request.PreparedStatementHandle = p.handle
-	if err := cmd.MarshalFrom(&request); err != nil {
-		return err
-	}
-
-	body, err := proto.Marshal(&cmd)
-	if err != nil {
-		return err
-	}
-
-	action := &flight.Action{Type: actionType, Body: body}
-	stream, err := p.client.Client.DoAction(ctx, action, opts...)
-	if err != nil {
-		return err
-	}
-
-	if err = stream.CloseSend(); err != nil {
-		return err
-	}
-
-	p.closed = true
-	return flight.ReadUntilEOF(stream)
+	return p.life.closeOnServer(ctx, opts...)
 }
@@ -0,0 +1,310 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultClientPoolMaxConns            = 8
+	defaultClientPoolMaxIdle             = 4
+	defaultClientPoolIdleTimeout         = 5 * time.Minute
+	defaultClientPoolHealthCheckInterval = 30 * time.Second
+)
+
+// ErrClientPoolClosed is returned when acquiring a client from a
+// ClientPool which has been closed.
+var ErrClientPoolClosed = errors.New("arrow/flightsql: client pool is closed")
+
+// PoolOptions configures the clients of a ClientPool.
+type PoolOptions struct {
+	// Auth, Middleware and DialOptions are passed to NewClient to create
+	// each client of the pool.
+	Auth        flight.ClientAuthHandler
+	Middleware  []flight.ClientMiddleware
+	DialOptions []grpc.DialOption
+	// MaxConns is the number of clients open at once, whether acquired
+	// or idle, beyond which Acquire waits for a client to be released.
+	// Defaults to 8 if 0; a negative value means no limit.
+	MaxConns int
+	// MaxIdle is the number of clients kept open while released. Defaults
+	// to 4 if 0; a negative value closes clients as soon as they are
+	// released.
+	MaxIdle int
+	// IdleTimeout is how long a released client is kept open. Defaults to
+	// 5 minutes if 0; a negative value keeps them until the pool is
+	// closed or MaxIdle is exceeded.
+	IdleTimeout time.Duration
+	// HealthCheck checks that a client which was idle is still usable
+	// before Acquire returns it, the client being closed and replaced if
+	// it fails. Defaults to listing the actions of the server.
+	HealthCheck func(context.Context, *Client) error
+	// HealthCheckInterval is how long a client must have been idle to be
+	// checked. Defaults to 30 seconds if 0; a negative value checks
+	// clients every time they are acquired.
+	HealthCheckInterval time.Duration
+}
+
+// ClientPool reuses the clients connected to a Flight SQL server, so
+// that concurrent users such as the requests of a web backend each use
+// a client of their own without dialing the server every time. Clients
+// are created on demand, up to PoolOptions.MaxConns.
+type ClientPool struct {
+	addr string
+	opts PoolOptions
+	// slots holds a value for each acquired client, nil if unlimited
+	slots chan struct{}
+
+	mu sync.Mutex
+	// idle holds the released clients, the most recently released last
+	idle     []*idleClient
+	acquired map[*Client]struct{}
+	closed   bool
+}
+
+// idleClient is a released client of a ClientPool.
+type idleClient struct {
+	cl        *Client
+	idleSince time.Time
+	timer     *time.Timer
+}
+
+// NewClientPool returns an empty pool of clients connecting to addr.
+func NewClientPool(addr string, opts PoolOptions) *ClientPool {
+	if opts.MaxConns == 0 {
+		opts.MaxConns = defaultClientPoolMaxConns
+	}
+	if opts.MaxIdle == 0 {
+		opts.MaxIdle = defaultClientPoolMaxIdle
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = defaultClientPoolIdleTimeout
+	}
+	if opts.HealthCheck == nil {
+		opts.HealthCheck = listActions
+	}
+	if opts.HealthCheckInterval == 0 {
+		opts.HealthCheckInterval = defaultClientPoolHealthCheckInterval
+	}
+
+	p := &ClientPool{addr: addr, opts: opts, acquired: make(map[*Client]struct{})}
+	if opts.MaxConns > 0 {
+		p.slots = make(chan struct{}, opts.MaxConns)
+	}
+	return p
+}
+
+// listActions is the default health check of a ClientPool.
+func listActions(ctx context.Context, cl *Client) error {
+	stream, err := cl.Client.ListActions(ctx, &flight.Empty{})
+	if err != nil {
+		return err
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Acquire returns a client of the pool, reusing an idle one if possible,
+// to be returned to the pool with Release once done. If MaxConns clients
+// are already acquired, it waits for one to be released or for ctx to
+// be done.
+func (p *ClientPool) Acquire(ctx context.Context) (*Client, error) {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	cl, err := p.get(ctx)
+	if err != nil {
+		p.releaseSlot()
+		return nil, err
+	}
+	return cl, nil
+}
+
+// get returns an idle client which is still healthy, or a new one.
+func (p *ClientPool) get(ctx context.Context) (*Client, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrClientPoolClosed
+		}
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			break
+		}
+		ic := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if ic.timer != nil {
+			ic.timer.Stop()
+		}
+		p.mu.Unlock()
+
+		if time.Since(ic.idleSince) >= p.opts.HealthCheckInterval {
+			if err := p.opts.HealthCheck(ctx, ic.cl); err != nil {
+				ic.cl.Close()
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+		}
+		if err := p.markAcquired(ic.cl); err != nil {
+			return nil, err
+		}
+		return ic.cl, nil
+	}
+
+	cl, err := NewClientCtx(ctx, p.addr, p.opts.Auth, p.opts.Middleware, p.opts.DialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.markAcquired(cl); err != nil {
+		return nil, err
+	}
+	return cl, nil
+}
+
+// markAcquired records cl as acquired, closing it if the pool was closed
+// in the meantime.
+func (p *ClientPool) markAcquired(cl *Client) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		cl.Close()
+		return ErrClientPoolClosed
+	}
+	p.acquired[cl] = struct{}{}
+	return nil
+}
+
+func (p *ClientPool) releaseSlot() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// Release returns cl, acquired with Acquire, to the pool. It must not be
+// used afterwards. Clients which weren't acquired from the pool, or were
+// already released, are ignored.
+func (p *ClientPool) Release(cl *Client) {
+	p.mu.Lock()
+	if _, ok := p.acquired[cl]; !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.acquired, cl)
+
+	if p.closed {
+		p.mu.Unlock()
+		cl.Close()
+		p.releaseSlot()
+		return
+	}
+
+	ic := &idleClient{cl: cl, idleSince: time.Now()}
+	if p.opts.IdleTimeout > 0 {
+		ic.timer = time.AfterFunc(p.opts.IdleTimeout, func() { p.expire(ic) })
+	}
+	p.idle = append(p.idle, ic)
+	p.trimIdle()
+	p.mu.Unlock()
+	p.releaseSlot()
+}
+
+// expire closes ic if it is still idle.
+func (p *ClientPool) expire(ic *idleClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, c := range p.idle {
+		if c == ic {
+			p.idle = append(p.idle[:i], p.idle[i+1:]...)
+			ic.cl.Close()
+			return
+		}
+	}
+}
+
+// trimIdle closes the longest idle clients beyond MaxIdle. p.mu must be
+// held.
+func (p *ClientPool) trimIdle() {
+	maxIdle := p.opts.MaxIdle
+	if maxIdle < 0 {
+		maxIdle = 0
+	}
+	if len(p.idle) <= maxIdle {
+		return
+	}
+
+	n := len(p.idle) - maxIdle
+	for _, ic := range p.idle[:n] {
+		if ic.timer != nil {
+			ic.timer.Stop()
+		}
+		ic.cl.Close()
+	}
+	p.idle = append(p.idle[:0], p.idle[n:]...)
+}
+
+// Len returns the number of open clients of the pool, whether idle or
+// acquired.
+func (p *ClientPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle) + len(p.acquired)
+}
+
+// Close closes the idle clients of the pool. Clients still acquired are
+// closed once released, and Acquire fails with ErrClientPoolClosed.
+func (p *ClientPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	var errs []error
+	for _, ic := range p.idle {
+		if ic.timer != nil {
+			ic.timer.Stop()
+		}
+		if err := ic.cl.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	p.idle = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,167 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/flightsqltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientPoolAcquireRelease(t *testing.T) {
+	pool := flightsql.NewClientPool(flightsqltest.Serve(t, &flightsql.BaseServer{}), flightsql.PoolOptions{
+		DialOptions: dialOpts, HealthCheckInterval: -1})
+	defer pool.Close()
+
+	ctx := context.Background()
+	cl, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	_, err = cl.HealthCheck(ctx)
+	require.NoError(t, err)
+	pool.Release(cl)
+	// releasing again is ignored
+	pool.Release(cl)
+	assert.Equal(t, 1, pool.Len())
+
+	// the released client passes the health check and is reused
+	again, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	assert.Same(t, cl, again)
+
+	other, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, cl, other)
+	assert.Equal(t, 2, pool.Len())
+	pool.Release(other)
+	pool.Release(again)
+
+	require.NoError(t, pool.Close())
+	assert.Zero(t, pool.Len())
+	_, err = pool.Acquire(ctx)
+	assert.ErrorIs(t, err, flightsql.ErrClientPoolClosed)
+}
+
+func TestClientPoolMaxConns(t *testing.T) {
+	pool := flightsql.NewClientPool(flightsqltest.Serve(t, &flightsql.BaseServer{}), flightsql.PoolOptions{
+		DialOptions: dialOpts, MaxConns: 2})
+	defer pool.Close()
+
+	ctx := context.Background()
+	first, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	second, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+
+	// a third client waits for one to be released
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = pool.Acquire(short)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var (
+		wg    sync.WaitGroup
+		third *flightsql.Client
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		third, err = pool.Acquire(ctx)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	pool.Release(first)
+	wg.Wait()
+	require.NoError(t, err)
+	assert.Same(t, first, third)
+	assert.Equal(t, 2, pool.Len())
+
+	pool.Release(second)
+	pool.Release(third)
+}
+
+func TestClientPoolEvictUnhealthy(t *testing.T) {
+	var (
+		mx     sync.Mutex
+		broken = map[*flightsql.Client]bool{}
+	)
+	pool := flightsql.NewClientPool(flightsqltest.Serve(t, &flightsql.BaseServer{}), flightsql.PoolOptions{
+		DialOptions: dialOpts,
+		HealthCheck: func(_ context.Context, cl *flightsql.Client) error {
+			mx.Lock()
+			defer mx.Unlock()
+			if broken[cl] {
+				return errors.New("connection lost")
+			}
+			return nil
+		},
+		HealthCheckInterval: -1,
+	})
+	defer pool.Close()
+
+	ctx := context.Background()
+	first, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	second, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	pool.Release(first)
+	pool.Release(second)
+	assert.Equal(t, 2, pool.Len())
+
+	mx.Lock()
+	broken[second] = true
+	mx.Unlock()
+
+	// the broken client is closed and the healthy one returned instead
+	cl, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	assert.Same(t, first, cl)
+	assert.Equal(t, 1, pool.Len())
+
+	// once no idle client is healthy a new one is created
+	mx.Lock()
+	broken[first] = true
+	mx.Unlock()
+	pool.Release(cl)
+	cl, err = pool.Acquire(ctx)
+	require.NoError(t, err)
+	assert.NotSame(t, first, cl)
+	assert.NotSame(t, second, cl)
+	pool.Release(cl)
+}
+
+func TestClientPoolIdle(t *testing.T) {
+	pool := flightsql.NewClientPool(flightsqltest.Serve(t, &flightsql.BaseServer{}), flightsql.PoolOptions{
+		DialOptions: dialOpts, MaxIdle: 1, IdleTimeout: 20 * time.Millisecond})
+	defer pool.Close()
+
+	ctx := context.Background()
+	first, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	second, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	pool.Release(first)
+	pool.Release(second)
+	// only the most recently released client is kept, until it times out
+	assert.Equal(t, 1, pool.Len())
+	assert.Eventually(t, func() bool { return pool.Len() == 0 }, time.Second, 5*time.Millisecond)
+}
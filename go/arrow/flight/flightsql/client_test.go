@@ -17,8 +17,11 @@
 package flightsql_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"io"
+	"math"
 	"strings"
 	"testing"
 
@@ -28,10 +31,13 @@ import (
 	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
 	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
 	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 )
@@ -124,6 +130,10 @@ func (m *FlightServiceClientMock) DoExchange(ctx context.Context, opts ...grpc.C
 	panic("not implemented") // TODO: Implement
 }
 
+func (m *FlightServiceClientMock) DoExchangeRecords(ctx context.Context, desc *flight.FlightDescriptor, opts ...grpc.CallOption) (*flight.Writer, *flight.Reader, error) {
+	panic("not implemented") // TODO: Implement
+}
+
 func (m *FlightServiceClientMock) DoAction(ctx context.Context, in *flight.Action, opts ...grpc.CallOption) (flight.FlightService_DoActionClient, error) {
 	args := m.Called(in.Type, in.Body, opts)
 	return args.Get(0).(flight.FlightService_DoActionClient), args.Error(1)
@@ -623,6 +633,122 @@ func (s *FlightSqlClientSuite) TestExecuteUpdate() {
 	s.EqualValues(100, num)
 }
 
+func (s *FlightSqlClientSuite) TestExecuteUpdateMalformedResult() {
+	mockedPut := &mockDoPutClient{}
+	mockedPut.On("Send", mock.Anything).Return(nil)
+	mockedPut.On("CloseSend").Return(nil)
+	mockedPut.On("Recv").Return(&pb.PutResult{AppMetadata: []byte{0xff}}, nil)
+	s.mockClient.On("DoPut", s.callOpts).Return(mockedPut, nil)
+
+	_, err := s.sqlClient.ExecuteUpdate(context.TODO(), "query", s.callOpts...)
+	s.ErrorIs(err, arrow.ErrInvalid)
+	s.ErrorContains(err, "DoPutUpdateResult")
+}
+
+// The serialized commands sent by the C++ and Java clients for a Substrait
+// plan with the bytes 0a02 0801 and version "0.42.1", as an Any message
+// wrapping the command. The commands only differ from each other by the
+// type URL and the optional transaction id.
+const (
+	substraitPlanHex = "0a0e0a040a0208011206302e34322e31"
+
+	substraitCmdHex = "0a4b" + "747970652e676f6f676c65617069732e636f6d2f6172726f772e666c696768742e70726f746f636f6c2e73716c2e" +
+		"436f6d6d616e6453746174656d656e74537562737472616974506c616e"
+	substraitPrepareHex = "0a56" + "747970652e676f6f676c65617069732e636f6d2f6172726f772e666c696768742e70726f746f636f6c2e73716c2e" +
+		"416374696f6e4372656174655072657061726564537562737472616974506c616e52657175657374"
+)
+
+var testSubstraitPlan = flightsql.SubstraitPlan{Plan: []byte{0x0a, 0x02, 0x08, 0x01}, Version: "0.42.1"}
+
+func mustDecodeHex(s string) []byte {
+	out, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func (s *FlightSqlClientSuite) TestExecuteSubstrait() {
+	cmd := mustDecodeHex(substraitCmdHex + "1210" + substraitPlanHex)
+
+	s.mockClient.On("GetFlightInfo", flight.DescriptorCMD, cmd, s.callOpts).Return(&emptyFlightInfo, nil)
+	info, err := s.sqlClient.ExecuteSubstrait(context.Background(), testSubstraitPlan, s.callOpts...)
+	s.NoError(err)
+	s.Equal(&emptyFlightInfo, info)
+}
+
+func (s *FlightSqlClientSuite) TestExecuteSubstraitUpdate() {
+	cmd := mustDecodeHex(substraitCmdHex + "1210" + substraitPlanHex)
+	resdata, _ := proto.Marshal(&pb.DoPutUpdateResult{RecordCount: 7})
+
+	mockedPut := &mockDoPutClient{}
+	defer mockedPut.AssertExpectations(s.T())
+	mockedPut.On("Send", mock.MatchedBy(func(fd *flight.FlightData) bool {
+		return bytes.Equal(cmd, fd.FlightDescriptor.GetCmd())
+	})).Return(nil)
+	mockedPut.On("CloseSend").Return(nil)
+	mockedPut.On("Recv").Return(&pb.PutResult{AppMetadata: resdata}, nil)
+	s.mockClient.On("DoPut", s.callOpts).Return(mockedPut, nil)
+
+	n, err := s.sqlClient.ExecuteSubstraitUpdate(context.Background(), testSubstraitPlan, s.callOpts...)
+	s.NoError(err)
+	s.EqualValues(7, n)
+}
+
+func (s *FlightSqlClientSuite) TestPrepareSubstrait() {
+	body := mustDecodeHex(substraitPrepareHex + "1210" + substraitPlanHex)
+	closeAct := getAction(&pb.ActionClosePreparedStatementRequest{PreparedStatementHandle: []byte("plan")})
+
+	var out anypb.Any
+	out.MarshalFrom(&pb.ActionCreatePreparedStatementResult{PreparedStatementHandle: []byte("plan")})
+	data, _ := proto.Marshal(&out)
+
+	createRsp := &mockDoActionClient{}
+	defer createRsp.AssertExpectations(s.T())
+	createRsp.On("Recv").Return(&pb.Result{Body: data}, nil).Once()
+	createRsp.On("Recv").Return(&pb.Result{}, io.EOF)
+	createRsp.On("CloseSend").Return(nil)
+
+	closeRsp := &mockDoActionClient{}
+	defer closeRsp.AssertExpectations(s.T())
+	closeRsp.On("Recv").Return(&pb.Result{}, io.EOF)
+	closeRsp.On("CloseSend").Return(nil)
+
+	s.mockClient.On("DoAction", flightsql.CreatePreparedSubstraitPlanActionType, body, s.callOpts).
+		Return(createRsp, nil)
+	s.mockClient.On("DoAction", flightsql.ClosePreparedStatementActionType, closeAct.Body, s.callOpts).
+		Return(closeRsp, nil)
+
+	prepared, err := s.sqlClient.PrepareSubstrait(context.Background(), testSubstraitPlan, s.callOpts...)
+	s.Require().NoError(err)
+	s.Equal("plan", string(prepared.Handle()))
+	s.NoError(prepared.Close(context.Background(), s.callOpts...))
+}
+
+func (s *FlightSqlClientSuite) TestTxnExecuteSubstrait() {
+	var out anypb.Any
+	out.MarshalFrom(&pb.ActionBeginTransactionResult{TransactionId: []byte("txn")})
+	data, _ := proto.Marshal(&out)
+
+	beginRsp := &mockDoActionClient{}
+	defer beginRsp.AssertExpectations(s.T())
+	beginRsp.On("Recv").Return(&pb.Result{Body: data}, nil).Once()
+	beginRsp.On("Recv").Return(&pb.Result{}, io.EOF)
+	beginRsp.On("CloseSend").Return(nil)
+	s.mockClient.On("DoAction", flightsql.BeginTransactionActionType, getAction(&pb.ActionBeginTransactionRequest{}).Body, s.callOpts).
+		Return(beginRsp, nil)
+
+	// the transaction id "txn" is appended as field 2 of the command
+	cmd := mustDecodeHex(substraitCmdHex + "1215" + substraitPlanHex + "120374786e")
+	s.mockClient.On("GetFlightInfo", flight.DescriptorCMD, cmd, s.callOpts).Return(&emptyFlightInfo, nil)
+
+	tx, err := s.sqlClient.BeginTransaction(context.Background(), s.callOpts...)
+	s.Require().NoError(err)
+	info, err := tx.ExecuteSubstrait(context.Background(), testSubstraitPlan, s.callOpts...)
+	s.NoError(err)
+	s.Equal(&emptyFlightInfo, info)
+}
+
 func (s *FlightSqlClientSuite) TestGetSqlInfo() {
 	sqlInfo := []flightsql.SqlInfo{
 		flightsql.SqlInfoFlightSqlServerName,
@@ -713,3 +839,53 @@ func (s *FlightSqlClientSuite) TestPreparedStatementLoadFromResult() {
 func TestFlightSqlClient(t *testing.T) {
 	suite.Run(t, new(FlightSqlClientSuite))
 }
+
+func TestUnmarshalPutResults(t *testing.T) {
+	data, err := proto.Marshal(&pb.DoPutUpdateResult{RecordCount: 42})
+	require.NoError(t, err)
+	n, err := flightsql.UnmarshalDoPutUpdateResult(data)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, n)
+
+	data, err = proto.Marshal(&pb.DoPutUpdateResult{RecordCount: flightsql.UpdateResultUnknown})
+	require.NoError(t, err)
+	n, err = flightsql.UnmarshalDoPutUpdateResult(data)
+	require.NoError(t, err)
+	assert.Equal(t, flightsql.UpdateResultUnknown, n)
+
+	data, err = proto.Marshal(&pb.DoPutUpdateResult{RecordCount: 42})
+	require.NoError(t, err)
+	result, err := flightsql.UnmarshalDoPutResult(data)
+	require.NoError(t, err)
+	rows, known := result.RowsAffected()
+	assert.True(t, known)
+	assert.EqualValues(t, 42, rows)
+
+	// any negative count is unknown
+	for _, count := range []int64{flightsql.UpdateResultUnknown, -2, math.MinInt64} {
+		data, err = proto.Marshal(&pb.DoPutUpdateResult{RecordCount: count})
+		require.NoError(t, err)
+		result, err = flightsql.UnmarshalDoPutResult(data)
+		require.NoError(t, err)
+		assert.Equal(t, flightsql.UpdateResultUnknown, result.RecordCount)
+		_, known = result.RowsAffected()
+		assert.False(t, known)
+	}
+
+	_, err = flightsql.UnmarshalDoPutUpdateResult([]byte{0x08})
+	assert.ErrorIs(t, err, arrow.ErrInvalid)
+
+	// DoPutPreparedStatementResult{prepared_statement_handle: "new handle"}
+	data = protowire.AppendTag(nil, 1, protowire.BytesType)
+	data = protowire.AppendBytes(data, []byte("new handle"))
+	handle, err := flightsql.UnmarshalPreparedStatementHandle(data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new handle"), handle)
+
+	// a handle field longer than the message is malformed
+	truncated := append(protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.BytesType), 10), "short"...)
+	for _, md := range [][]byte{nil, {0xff}, protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 1), truncated} {
+		_, err = flightsql.UnmarshalPreparedStatementHandle(md)
+		assert.ErrorIs(t, err, arrow.ErrInvalid)
+	}
+}
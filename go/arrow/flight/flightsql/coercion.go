@@ -0,0 +1,326 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/compute"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ParameterSchemaServer is an optional interface which a Server can
+// implement to opt in to parameter coercion in DoPut.
+//
+// When implemented, the parameters a client binds to a prepared statement
+// are reconciled with the schema returned by PreparedStatementParameterSchema
+// before DoPutPreparedStatementQuery or DoPutPreparedStatementUpdate see
+// them. Differences in field nullability are accepted as long as no nulls
+// are sent for a non-nullable parameter, and integer columns of a different
+// width are converted with a safe cast. Any other mismatch, or a cast which
+// would lose data, is reported as an InvalidArgument error.
+//
+// Nullability differences of the child fields of list and struct
+// parameters are also accepted, and the values of the struct fields,
+// list elements and map items are checked in the same way: a null where
+// the parameter schema declares a non-nullable child field, and none of
+// its parents is null, is reported as an InvalidArgument error. Map and
+// union parameters must otherwise match exactly.
+//
+// Returning a nil schema disables coercion for that statement.
+type ParameterSchemaServer interface {
+	PreparedStatementParameterSchema(ctx context.Context, handle []byte) (*arrow.Schema, error)
+}
+
+func checkParameterCoercion(from, to *arrow.Schema) error {
+	if from.NumFields() != to.NumFields() {
+		return status.Errorf(codes.InvalidArgument,
+			"parameter count mismatch: got %d, expected %d", from.NumFields(), to.NumFields())
+	}
+
+	for i, dst := range to.Fields() {
+		src := from.Field(i)
+		switch {
+		case arrow.TypeEqual(relaxNullability(src.Type), relaxNullability(dst.Type)):
+		case arrow.IsInteger(src.Type.ID()) && arrow.IsInteger(dst.Type.ID()):
+		default:
+			return status.Errorf(codes.InvalidArgument,
+				"cannot coerce parameter %d (%s) from %s to %s", i, dst.Name, src.Type, dst.Type)
+		}
+	}
+	return nil
+}
+
+func coerceParameters(ctx context.Context, rec arrow.Record, to *arrow.Schema) (arrow.Record, error) {
+	cols := make([]arrow.Array, 0, rec.NumCols())
+	defer func() {
+		for _, c := range cols {
+			c.Release()
+		}
+	}()
+
+	for i, dst := range to.Fields() {
+		col := rec.Column(i)
+		if !dst.Nullable && col.NullN() > 0 {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"parameter %d (%s) is not nullable but %d null values were provided", i, dst.Name, col.NullN())
+		}
+		if err := checkChildNulls(col, dst.Type, nil, dst.Name); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "parameter %d (%s): %s", i, dst.Name, err.Error())
+		}
+
+		if arrow.TypeEqual(col.DataType(), dst.Type) {
+			col.Retain()
+			cols = append(cols, col)
+			continue
+		}
+
+		if arrow.TypeEqual(relaxNullability(col.DataType()), relaxNullability(dst.Type)) {
+			data := retypeData(col.Data(), dst.Type)
+			cols = append(cols, array.MakeFromData(data))
+			data.Release()
+			continue
+		}
+
+		out, err := compute.CastArray(ctx, col, compute.SafeCastOptions(dst.Type))
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"cannot coerce parameter %d (%s) from %s to %s: %s", i, dst.Name, col.DataType(), dst.Type, err.Error())
+		}
+		cols = append(cols, out)
+	}
+
+	return array.NewRecord(to, cols, rec.NumRows()), nil
+}
+
+// checkChildNulls returns an error if a child of arr has a null value
+// while its field in dt is not nullable, at a position where neither arr
+// nor its parents are null. mask holds the positions of arr whose parents
+// are valid, all of them if nil, and path names arr in the error.
+func checkChildNulls(arr arrow.Array, dt arrow.DataType, mask []bool, path string) error {
+	valid := func(i int) bool { return arr.IsValid(i) && (mask == nil || mask[i]) }
+
+	switch arr := arr.(type) {
+	case *array.Struct:
+		st, ok := dt.(*arrow.StructType)
+		if !ok {
+			return nil
+		}
+		childMask := make([]bool, arr.Len())
+		for i := range childMask {
+			childMask[i] = valid(i)
+		}
+		for j, f := range st.Fields() {
+			if err := checkChildField(arr.Field(j), f, childMask, path); err != nil {
+				return err
+			}
+		}
+	case array.ListLike:
+		nested, ok := dt.(arrow.NestedType)
+		if !ok {
+			return nil
+		}
+		values := arr.ListValues()
+		childMask := make([]bool, values.Len())
+		for i := 0; i < arr.Len(); i++ {
+			if !valid(i) {
+				continue
+			}
+			start, end := arr.ValueOffsets(i)
+			for k := start; k < end; k++ {
+				childMask[k] = true
+			}
+		}
+		return checkChildField(values, nested.Fields()[0], childMask, path)
+	}
+	return nil
+}
+
+// checkChildField checks the values of the child field f of a parameter
+// at the positions of mask, then its own children.
+func checkChildField(child arrow.Array, f arrow.Field, mask []bool, path string) error {
+	path += "." + f.Name
+	if !f.Nullable && child.NullN() > 0 {
+		nulls := 0
+		for k := range mask {
+			if mask[k] && child.IsNull(k) {
+				nulls++
+			}
+		}
+		if nulls > 0 {
+			return fmt.Errorf("field %s is not nullable but %d null values were provided", path, nulls)
+		}
+	}
+	return checkChildNulls(child, f.Type, mask, path)
+}
+
+// relaxNullability returns dt with every nested field marked as nullable
+// so that types which only differ by the nullability of their children
+// compare as equal. Only list and struct types are handled, any other
+// type is returned unchanged.
+func relaxNullability(dt arrow.DataType) arrow.DataType {
+	switch dt := dt.(type) {
+	case *arrow.LargeListType:
+		return arrow.LargeListOfField(relaxField(dt.ElemField()))
+	case *arrow.ListType:
+		return arrow.ListOfField(relaxField(dt.ElemField()))
+	case *arrow.FixedSizeListType:
+		return arrow.FixedSizeListOfField(dt.Len(), relaxField(dt.ElemField()))
+	case *arrow.StructType:
+		fields := dt.Fields()
+		for i := range fields {
+			fields[i] = relaxField(fields[i])
+		}
+		return arrow.StructOf(fields...)
+	}
+	return dt
+}
+
+func relaxField(f arrow.Field) arrow.Field {
+	f.Nullable = true
+	f.Type = relaxNullability(f.Type)
+	return f
+}
+
+// retypeData returns a view of data using dt as its type, applying the
+// child field types of dt to the children. dt must only differ from the
+// type of data by the nullability of nested fields.
+func retypeData(data arrow.ArrayData, dt arrow.DataType) arrow.ArrayData {
+	var children []arrow.ArrayData
+	if nested, ok := dt.(arrow.NestedType); ok {
+		fields := nested.Fields()
+		children = make([]arrow.ArrayData, len(data.Children()))
+		for i, c := range data.Children() {
+			children[i] = retypeData(c, fields[i].Type)
+			defer children[i].Release()
+		}
+	}
+	return array.NewData(dt, data.Len(), data.Buffers(), children, data.NullN(), data.Offset())
+}
+
+// coercingReader wraps the parameter stream of a DoPut call and coerces
+// each record to the prepared statement's parameter schema as it is read.
+type coercingReader struct {
+	flight.MessageReader
+
+	refCount int64
+	ctx      context.Context
+	schema   *arrow.Schema
+	rec      arrow.Record
+	err      error
+}
+
+func newCoercingReader(ctx context.Context, mem memory.Allocator, rdr flight.MessageReader, schema *arrow.Schema) *coercingReader {
+	rdr.Retain()
+	return &coercingReader{
+		MessageReader: rdr,
+		refCount:      1,
+		ctx:           compute.WithAllocator(ctx, mem),
+		schema:        schema,
+	}
+}
+
+func (r *coercingReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+func (r *coercingReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) == 0 {
+		if r.rec != nil {
+			r.rec.Release()
+			r.rec = nil
+		}
+		r.MessageReader.Release()
+	}
+}
+
+func (r *coercingReader) Schema() *arrow.Schema { return r.schema }
+
+func (r *coercingReader) Next() bool {
+	if r.rec != nil {
+		r.rec.Release()
+		r.rec = nil
+	}
+
+	if r.err != nil || !r.MessageReader.Next() {
+		return false
+	}
+
+	r.rec, r.err = coerceParameters(r.ctx, r.MessageReader.Record(), r.schema)
+	return r.err == nil
+}
+
+func (r *coercingReader) Record() arrow.Record { return r.rec }
+
+func (r *coercingReader) Read() (arrow.Record, error) {
+	if !r.Next() {
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return r.rec, nil
+}
+
+func (r *coercingReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.MessageReader.Err()
+}
+
+func (r *coercingReader) Chunk() flight.StreamChunk {
+	chunk := r.MessageReader.Chunk()
+	chunk.Data = r.rec
+	return chunk
+}
+
+// parameterReader returns the reader that should be handed to the
+// prepared statement handlers in DoPut, applying coercion if the server
+// has opted in to it. The returned reader must be released by the caller.
+func (f *flightSqlServer) parameterReader(ctx context.Context, handle []byte, rdr flight.MessageReader) (flight.MessageReader, error) {
+	srv, ok := f.srv.(ParameterSchemaServer)
+	if !ok {
+		rdr.Retain()
+		return rdr, nil
+	}
+
+	target, err := srv.PreparedStatementParameterSchema(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	incoming := rdr.Schema()
+	if target == nil || incoming == nil || incoming.Equal(target) {
+		rdr.Retain()
+		return rdr, nil
+	}
+
+	if err := checkParameterCoercion(incoming, target); err != nil {
+		return nil, err
+	}
+
+	return newCoercingReader(ctx, f.mem, rdr, target), nil
+}
@@ -0,0 +1,177 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/apache/arrow/go/v16/arrow/scalar"
+)
+
+// ColumnStats holds statistics of a column of the result described by a
+// FlightInfo, which clients may use for planning. See SetColumnStats and
+// GetColumnStats.
+type ColumnStats struct {
+	// Column is the name of the field of the result schema.
+	Column string
+	// NullCount is the number of nulls in the column, or TotalUnknown.
+	NullCount int64
+	// Min and Max are the smallest and largest values of the column, of
+	// the type of the field. They are nil if unknown.
+	Min, Max scalar.Scalar
+}
+
+// columnStatsJSON is the encoding of the statistics in the AppMetadata
+// of a FlightInfo, an object such as:
+//
+//	{"column_stats":[{"column":"id","null_count":0,"min":1,"max":42}]}
+//
+// Unknown values are omitted. The min and max values use the same JSON
+// representation as a value of the column's type in array.FromJSON.
+type columnStatsJSON struct {
+	ColumnStats []columnStatJSON `json:"column_stats"`
+}
+
+type columnStatJSON struct {
+	Column    string          `json:"column"`
+	NullCount *int64          `json:"null_count,omitempty"`
+	Min       json.RawMessage `json:"min,omitempty"`
+	Max       json.RawMessage `json:"max,omitempty"`
+}
+
+// SetColumnStats encodes stats into the AppMetadata of info, replacing
+// its current value. Servers can call it from handlers such as
+// GetFlightInfoStatement when the statistics are cheaply available.
+//
+// The AppMetadata holds a JSON object with the statistics in its
+// "column_stats" member, see GetColumnStats for decoding it.
+func SetColumnStats(info *flight.FlightInfo, stats ...ColumnStats) error {
+	out := columnStatsJSON{ColumnStats: make([]columnStatJSON, len(stats))}
+	for i, s := range stats {
+		enc := columnStatJSON{Column: s.Column}
+		if s.NullCount != TotalUnknown {
+			n := s.NullCount
+			enc.NullCount = &n
+		}
+
+		var err error
+		if enc.Min, err = scalarToJSON(s.Min); err != nil {
+			return fmt.Errorf("arrow/flightsql: invalid min of column %q: %w", s.Column, err)
+		}
+		if enc.Max, err = scalarToJSON(s.Max); err != nil {
+			return fmt.Errorf("arrow/flightsql: invalid max of column %q: %w", s.Column, err)
+		}
+		out.ColumnStats[i] = enc
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	info.AppMetadata = data
+	return nil
+}
+
+// GetColumnStats decodes the column statistics set by SetColumnStats from
+// the AppMetadata of info, using the schema of info for the types of the
+// min and max values. It returns nil if info has no statistics.
+func GetColumnStats(info *flight.FlightInfo, mem memory.Allocator) ([]ColumnStats, error) {
+	if len(info.AppMetadata) == 0 {
+		return nil, nil
+	}
+
+	var enc columnStatsJSON
+	if err := json.Unmarshal(info.AppMetadata, &enc); err != nil {
+		return nil, fmt.Errorf("%w: arrow/flightsql: invalid column stats: %s", arrow.ErrInvalid, err.Error())
+	}
+	if len(enc.ColumnStats) == 0 {
+		return nil, nil
+	}
+
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	schema, err := flight.DeserializeSchema(info.Schema, mem)
+	if err != nil {
+		return nil, fmt.Errorf("arrow/flightsql: cannot decode column stats without a schema: %w", err)
+	}
+
+	stats := make([]ColumnStats, len(enc.ColumnStats))
+	for i, s := range enc.ColumnStats {
+		idx := schema.FieldIndices(s.Column)
+		if len(idx) == 0 {
+			return nil, fmt.Errorf("%w: arrow/flightsql: column stats for unknown column %q", arrow.ErrInvalid, s.Column)
+		}
+		dt := schema.Field(idx[0]).Type
+
+		stats[i] = ColumnStats{Column: s.Column, NullCount: TotalUnknown}
+		if s.NullCount != nil {
+			stats[i].NullCount = *s.NullCount
+		}
+		if stats[i].Min, err = scalarFromJSON(mem, dt, s.Min); err != nil {
+			return nil, fmt.Errorf("%w: arrow/flightsql: invalid min of column %q: %s", arrow.ErrInvalid, s.Column, err.Error())
+		}
+		if stats[i].Max, err = scalarFromJSON(mem, dt, s.Max); err != nil {
+			return nil, fmt.Errorf("%w: arrow/flightsql: invalid max of column %q: %s", arrow.ErrInvalid, s.Column, err.Error())
+		}
+	}
+	return stats, nil
+}
+
+// scalarToJSON encodes sc as the JSON value array.FromJSON expects for
+// its type, or returns nil if sc is nil or null.
+func scalarToJSON(sc scalar.Scalar) (json.RawMessage, error) {
+	if sc == nil || !sc.IsValid() {
+		return nil, nil
+	}
+
+	arr, err := scalar.MakeArrayFromScalar(sc, 1, memory.DefaultAllocator)
+	if err != nil {
+		return nil, err
+	}
+	defer arr.Release()
+
+	data, err := json.Marshal(arr)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+func scalarFromJSON(mem memory.Allocator, dt arrow.DataType, data json.RawMessage) (scalar.Scalar, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	arr, _, err := array.FromJSON(mem, dt, strings.NewReader("["+string(data)+"]"))
+	if err != nil {
+		return nil, err
+	}
+	defer arr.Release()
+	return scalar.GetScalar(arr, 0)
+}
@@ -0,0 +1,107 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// UnsupportedCommandError is the error of a call whose command the
+// server doesn't handle, either because the server doesn't know it, such
+// as a command newer than the server, or because it isn't a command of
+// the RPC it was sent with. It has the InvalidArgument status code.
+type UnsupportedCommandError struct {
+	// TypeURL is the type URL of the Any the command was packed in.
+	TypeURL string
+}
+
+func (e *UnsupportedCommandError) Error() string {
+	name := e.TypeURL[strings.LastIndexByte(e.TypeURL, '/')+1:]
+	return "requested command is invalid: unsupported command type " + name
+}
+
+func (e *UnsupportedCommandError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, e.Error())
+}
+
+// unsupportedCommand returns the error of a command which was parsed but
+// isn't handled.
+func unsupportedCommand(cmd proto.Message) error {
+	return &UnsupportedCommandError{TypeURL: "type.googleapis.com/" + string(proto.MessageName(cmd))}
+}
+
+// ParseCommand decodes a serialized Flight SQL command, as found in the
+// Cmd of a FlightDescriptor or in a Ticket, into the message it wraps.
+// The returned message can be inspected with a type switch on the
+// interfaces of this package, such as StatementQuery or GetTables.
+//
+// Errors are returned as gRPC status errors with the InvalidArgument code,
+// a command of an unknown type as an *UnsupportedCommandError.
+func ParseCommand(cmd []byte) (proto.Message, error) {
+	var anycmd anypb.Any
+	if err := proto.Unmarshal(cmd, &anycmd); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to parse command: %s", err.Error())
+	}
+
+	msg, err := anycmd.UnmarshalNew()
+	if errors.Is(err, protoregistry.NotFound) {
+		return nil, &UnsupportedCommandError{TypeURL: anycmd.GetTypeUrl()}
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "could not unmarshal Any to a command type: %s", err.Error())
+	}
+	return msg, nil
+}
+
+// DescribeCommand returns a human readable JSON description of a
+// serialized Flight SQL command for use when debugging or logging. The
+// result is an object with the full protobuf name of the command in
+// "type" and its fields in "command", for example:
+//
+//	{"type":"arrow.flight.protocol.sql.CommandStatementQuery","command":{"query":"SELECT 1"}}
+//
+// Fields use their protobuf names, unset fields are omitted and binary
+// fields such as statement handles are base64 encoded.
+func DescribeCommand(cmd []byte) (string, error) {
+	msg, err := ParseCommand(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	fields, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to marshal command: %s", err.Error())
+	}
+
+	out, err := json.Marshal(struct {
+		Type    string          `json:"type"`
+		Command json.RawMessage `json:"command"`
+	}{string(proto.MessageName(msg)), fields})
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to marshal command: %s", err.Error())
+	}
+	return string(out), nil
+}
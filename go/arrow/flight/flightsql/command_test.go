@@ -0,0 +1,171 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func packCommand(t *testing.T, msg proto.Message) []byte {
+	var cmd anypb.Any
+	require.NoError(t, cmd.MarshalFrom(msg))
+	out, err := proto.Marshal(&cmd)
+	require.NoError(t, err)
+	return out
+}
+
+type describedCommand struct {
+	Type    string                 `json:"type"`
+	Command map[string]interface{} `json:"command"`
+}
+
+func TestDescribeCommandStatementQuery(t *testing.T) {
+	cmd := packCommand(t, &pb.CommandStatementQuery{
+		Query:         "SELECT * FROM t",
+		TransactionId: []byte("txn-1"),
+	})
+
+	desc, err := flightsql.DescribeCommand(cmd)
+	require.NoError(t, err)
+
+	var out describedCommand
+	require.NoError(t, json.Unmarshal([]byte(desc), &out))
+	assert.Equal(t, "arrow.flight.protocol.sql.CommandStatementQuery", out.Type)
+	assert.Equal(t, "SELECT * FROM t", out.Command["query"])
+	assert.Equal(t, "dHhuLTE=", out.Command["transaction_id"])
+}
+
+func TestDescribeCommandGetTables(t *testing.T) {
+	catalog, pattern := "main", "int%"
+	cmd := packCommand(t, &pb.CommandGetTables{
+		Catalog:                &catalog,
+		TableNameFilterPattern: &pattern,
+		TableTypes:             []string{"TABLE", "VIEW"},
+		IncludeSchema:          true,
+	})
+
+	desc, err := flightsql.DescribeCommand(cmd)
+	require.NoError(t, err)
+
+	var out describedCommand
+	require.NoError(t, json.Unmarshal([]byte(desc), &out))
+	assert.Equal(t, "arrow.flight.protocol.sql.CommandGetTables", out.Type)
+	assert.Equal(t, map[string]interface{}{
+		"catalog":                   "main",
+		"table_name_filter_pattern": "int%",
+		"table_types":               []interface{}{"TABLE", "VIEW"},
+		"include_schema":            true,
+	}, out.Command)
+}
+
+func TestDescribeCommandInvalid(t *testing.T) {
+	_, err := flightsql.DescribeCommand([]byte("not a command"))
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestUnsupportedCommand(t *testing.T) {
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&flightsql.BaseServer{}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	assertUnsupported := func(t *testing.T, err error, name string) {
+		st, ok := status.FromError(err)
+		require.True(t, ok, err)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		assert.Equal(t, "requested command is invalid: unsupported command type "+name, st.Message())
+	}
+
+	// valid commands sent to the wrong RPC
+	_, err = cl.Client.GetFlightInfo(ctx, &flight.FlightDescriptor{
+		Type: flight.DescriptorCMD, Cmd: packCommand(t, &pb.TicketStatementQuery{})})
+	assertUnsupported(t, err, "arrow.flight.protocol.sql.TicketStatementQuery")
+
+	_, err = cl.Client.GetSchema(ctx, &flight.FlightDescriptor{
+		Type: flight.DescriptorCMD, Cmd: packCommand(t, &pb.CommandStatementUpdate{})})
+	assertUnsupported(t, err, "arrow.flight.protocol.sql.CommandStatementUpdate")
+
+	stream, err := cl.Client.DoGet(ctx, &flight.Ticket{Ticket: packCommand(t, &pb.CommandStatementUpdate{})})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assertUnsupported(t, err, "arrow.flight.protocol.sql.CommandStatementUpdate")
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(`[{"a": 1}]`))
+	require.NoError(t, err)
+	defer rec.Release()
+	put := func(cmd []byte) error {
+		stream, err := cl.Client.DoPut(ctx)
+		require.NoError(t, err)
+		wr := flight.NewRecordWriter(stream, ipc.WithSchema(schema))
+		wr.SetFlightDescriptor(&flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: cmd})
+		if err := wr.Write(rec); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		wr.Close()
+		require.NoError(t, stream.CloseSend())
+		_, err = stream.Recv()
+		return err
+	}
+	assertUnsupported(t, put(packCommand(t, &pb.CommandGetXdbcTypeInfo{})), "arrow.flight.protocol.sql.CommandGetXdbcTypeInfo")
+
+	// a command unknown to the server, such as one added by a newer
+	// version of Flight SQL
+	future, err := proto.Marshal(&anypb.Any{TypeUrl: "type.googleapis.com/arrow.flight.protocol.sql.CommandFromTheFuture"})
+	require.NoError(t, err)
+
+	_, err = cl.Client.GetFlightInfo(ctx, &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: future})
+	assertUnsupported(t, err, "arrow.flight.protocol.sql.CommandFromTheFuture")
+
+	stream, err = cl.Client.DoGet(ctx, &flight.Ticket{Ticket: future})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assertUnsupported(t, err, "arrow.flight.protocol.sql.CommandFromTheFuture")
+
+	assertUnsupported(t, put(future), "arrow.flight.protocol.sql.CommandFromTheFuture")
+
+	_, err = flightsql.ParseCommand(future)
+	var unsupported *flightsql.UnsupportedCommandError
+	require.ErrorAs(t, err, &unsupported)
+	assert.Equal(t, "type.googleapis.com/arrow.flight.protocol.sql.CommandFromTheFuture", unsupported.TypeURL)
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -93,6 +94,41 @@ func (r *Rows) Columns() []string {
 	return cols
 }
 
+// ColumnTypeDatabaseTypeName returns the type name the server reported
+// in the column's Flight SQL metadata, or the name of its Arrow type such
+// as "INT64", "UTF8" or "DECIMAL".
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	field := r.schema.Field(index)
+	md := flightsql.ColumnMetadata{Data: &field.Metadata}
+	if name, ok := md.TypeName(); ok {
+		return name
+	}
+	return strings.ToUpper(field.Type.Name())
+}
+
+// ColumnTypeNullable returns whether the column may contain nulls,
+// according to its Arrow field.
+func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return r.schema.Field(index).Nullable, true
+}
+
+// ColumnTypePrecisionScale returns the precision and scale of decimal
+// columns, or of the columns whose Flight SQL metadata reports them.
+func (r *Rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	field := r.schema.Field(index)
+	if dt, isDecimal := field.Type.(arrow.DecimalType); isDecimal {
+		return int64(dt.GetPrecision()), int64(dt.GetScale()), true
+	}
+
+	md := flightsql.ColumnMetadata{Data: &field.Metadata}
+	prec, hasPrec := md.Precision()
+	sc, hasScale := md.Scale()
+	if !hasPrec && !hasScale {
+		return 0, 0, false
+	}
+	return int64(prec), int64(sc), true
+}
+
 func (r *Rows) releaseRecord() {
 	if r.currentRecord != nil {
 		r.currentRecord.Release()
@@ -367,7 +403,14 @@ func (d *Driver) Open(name string) (driver.Conn, error) {
 		return nil, err
 	}
 
-	return c.Connect(context.Background())
+	// without a sql.DB to close the connector, the connection is
+	// responsible for the client
+	conn, err := c.Connect(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	conn.(*Connection).ownsClient = true
+	return conn, nil
 }
 
 // OpenConnector must parse the name in the same format that Driver.Open
@@ -386,10 +429,16 @@ func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
 	return c, nil
 }
 
+// Connector creates the connections of a sql.DB, which all share a
+// single Flight SQL client, and so a single gRPC connection, that is
+// closed along with the sql.DB.
 type Connector struct {
 	addr    string
 	timeout time.Duration
 	options []grpc.DialOption
+
+	mu     sync.Mutex
+	client *flightsql.Client
 }
 
 // Configure the driver with the corresponding config
@@ -429,17 +478,39 @@ func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
 		defer cancel()
 	}
 
-	client, err := flightsql.NewClientCtx(ctx, c.addr, nil, nil, c.options...)
-	if err != nil {
-		return nil, err
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == nil {
+		client, err := flightsql.NewClientCtx(ctx, c.addr, nil, nil, c.options...)
+		if err != nil {
+			return nil, err
+		}
+		c.client = client
 	}
 
 	return &Connection{
-		client:  client,
+		client:  c.client,
 		timeout: c.timeout,
 	}, nil
 }
 
+// Close closes the client shared by the connections, it's called by
+// sql.DB.Close.
+func (c *Connector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == nil {
+		return nil
+	}
+
+	err := c.client.Close()
+	c.client = nil
+
+	return err
+}
+
 // Driver returns the underlying Driver of the Connector,
 // mainly to maintain compatibility with the Driver method
 // on sql.DB.
@@ -450,6 +521,8 @@ func (c *Connector) Driver() driver.Driver {
 type Connection struct {
 	client *flightsql.Client
 	txn    *flightsql.Txn
+	// ownsClient is set if the client isn't shared through a Connector
+	ownsClient bool
 
 	timeout time.Duration
 }
@@ -490,6 +563,39 @@ func (c *Connection) PrepareContext(ctx context.Context, query string) (driver.S
 	return s, nil
 }
 
+// ExecContext executes a query that doesn't return rows, such as an
+// INSERT or UPDATE, through CommandStatementUpdate. Queries with arguments
+// are run as prepared statements instead.
+func (c *Connection) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if len(args) > 0 {
+		// We cannot pass arguments to the client so we skip a direct query.
+		// This will force the sql-framework to prepare and execute queries.
+		return nil, driver.ErrSkip
+	}
+
+	if _, set := ctx.Deadline(); !set && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var (
+		n   int64
+		err error
+	)
+	if c.txn != nil && c.txn.ID().IsValid() {
+		n, err = c.txn.ExecuteUpdate(ctx, query)
+	} else {
+		n, err = c.client.ExecuteUpdate(ctx, query)
+		c.txn = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{affected: n, lastinsert: -1}, nil
+}
+
 func (c *Connection) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
 	if len(args) > 0 {
 		// We cannot pass arguments to the client so we skip a direct query.
@@ -503,7 +609,16 @@ func (c *Connection) QueryContext(ctx context.Context, query string, args []driv
 		defer cancel()
 	}
 
-	info, err := c.client.Execute(ctx, query)
+	var (
+		info *flight.FlightInfo
+		err  error
+	)
+	if c.txn != nil && c.txn.ID().IsValid() {
+		info, err = c.txn.Execute(ctx, query)
+	} else {
+		info, err = c.client.Execute(ctx, query)
+		c.txn = nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -586,7 +701,10 @@ func (c *Connection) Close() error {
 		return nil
 	}
 
-	err := c.client.Close()
+	var err error
+	if c.ownsClient {
+		err = c.client.Close()
+	}
 	c.client = nil
 
 	return err
@@ -28,11 +28,14 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
 
 	"github.com/apache/arrow/go/v16/arrow"
 	"github.com/apache/arrow/go/v16/arrow/array"
@@ -1746,6 +1749,145 @@ func TestNoPreparedStatementImplemented(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestColumnTypes(t *testing.T) {
+	typeName := flightsql.NewColumnMetadataBuilder().TypeName("VARCHAR").Precision(100).Metadata()
+	backend := &MockServer{
+		DataSchema: arrow.NewSchema([]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Uint32, Nullable: false},
+			{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true, Metadata: typeName},
+			{Name: "price", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}, Nullable: true},
+			{Name: "day", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+			{Name: "data", Type: arrow.BinaryTypes.LargeBinary, Nullable: true},
+		}, nil),
+		Data: `[{"id": 1, "name": "one", "price": "12.34", "day": 19000, "data": "YWJj"},
+		        {"id": 2, "name": null, "price": null, "day": null, "data": null}]`,
+	}
+
+	server := flight.NewServerWithMiddleware(nil)
+	server.RegisterFlightService(flightsql.NewFlightServer(backend))
+	require.NoError(t, server.Init("localhost:0"))
+	go server.Serve()
+	defer server.Shutdown()
+
+	cfg := driver.DriverConfig{
+		Timeout: 5 * time.Second,
+		Address: server.Addr().String(),
+	}
+	db, err := sql.Open("flightsql", cfg.DSN())
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT * FROM foo")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	require.Len(t, types, 5)
+
+	expectedNames := []string{"UINT32", "VARCHAR", "DECIMAL", "DATE32", "LARGE_BINARY"}
+	expectedNullable := []bool{false, true, true, true, true}
+	for i, ct := range types {
+		require.Equal(t, expectedNames[i], ct.DatabaseTypeName(), ct.Name())
+		nullable, ok := ct.Nullable()
+		require.True(t, ok)
+		require.Equal(t, expectedNullable[i], nullable, ct.Name())
+	}
+
+	precision, scale, ok := types[2].DecimalSize()
+	require.True(t, ok)
+	require.EqualValues(t, 10, precision)
+	require.EqualValues(t, 2, scale)
+	precision, _, ok = types[1].DecimalSize()
+	require.True(t, ok)
+	require.EqualValues(t, 100, precision)
+	_, _, ok = types[0].DecimalSize()
+	require.False(t, ok)
+
+	var (
+		id    uint32
+		name  sql.NullString
+		price sql.NullFloat64
+		day   sql.NullTime
+		data  []byte
+	)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&id, &name, &price, &day, &data))
+	require.EqualValues(t, 1, id)
+	require.Equal(t, sql.NullString{String: "one", Valid: true}, name)
+	require.Equal(t, sql.NullFloat64{Float64: 12.34, Valid: true}, price)
+	require.Equal(t, time.Date(2022, 1, 8, 0, 0, 0, 0, time.UTC), day.Time.UTC())
+	require.Equal(t, []byte("abc"), data)
+
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&id, &name, &price, &day, &data))
+	require.EqualValues(t, 2, id)
+	require.False(t, name.Valid)
+	require.False(t, price.Valid)
+	require.False(t, day.Valid)
+	require.Nil(t, data)
+
+	require.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+}
+
+// connCounter counts the gRPC connections accepted by a server.
+type connCounter struct {
+	conns atomic.Int32
+}
+
+func (c *connCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context { return ctx }
+func (c *connCounter) HandleRPC(context.Context, stats.RPCStats)                       {}
+func (c *connCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (c *connCounter) HandleConn(_ context.Context, s stats.ConnStats) {
+	if _, ok := s.(*stats.ConnBegin); ok {
+		c.conns.Add(1)
+	}
+}
+
+func TestConnectionsShareClient(t *testing.T) {
+	backend := &MockServer{
+		DataSchema: arrow.NewSchema([]arrow.Field{{Name: "value", Type: arrow.PrimitiveTypes.Int64}}, nil),
+		Data:       `[{"value": 1}]`,
+	}
+
+	counter := &connCounter{}
+	server := flight.NewServerWithMiddleware(nil, grpc.StatsHandler(counter))
+	server.RegisterFlightService(flightsql.NewFlightServer(backend))
+	require.NoError(t, server.Init("localhost:0"))
+	go server.Serve()
+	defer server.Shutdown()
+
+	cfg := driver.DriverConfig{
+		Timeout: 5 * time.Second,
+		Address: server.Addr().String(),
+	}
+	db, err := sql.Open("flightsql", cfg.DSN())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err)
+		conns = append(conns, conn)
+
+		var value int64
+		require.NoError(t, conn.QueryRowContext(ctx, "SELECT value FROM foo").Scan(&value))
+		require.EqualValues(t, 1, value)
+	}
+	require.EqualValues(t, 3, db.Stats().OpenConnections)
+	require.EqualValues(t, 1, counter.conns.Load())
+	for _, conn := range conns {
+		require.NoError(t, conn.Close())
+	}
+
+	require.NoError(t, db.Close())
+}
+
 // Mockup database server
 type MockServer struct {
 	flightsql.BaseServer
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -136,3 +137,37 @@ func Test_fromArrowType(t *testing.T) {
 	tf(t, 19, time.Duration(1000))                           // "f20-duration_us"
 	tf(t, 20, time.Duration(1))                              // "f21-duration_ns"
 }
+
+func Test_fromArrowTypeUnsignedAndLarge(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	tests := []struct {
+		dt   arrow.DataType
+		json string
+		want any
+	}{
+		{arrow.PrimitiveTypes.Uint8, `[8, null]`, uint8(8)},
+		{arrow.PrimitiveTypes.Uint16, `[16, null]`, uint16(16)},
+		{arrow.PrimitiveTypes.Uint64, `[64, null]`, uint64(64)},
+		{arrow.BinaryTypes.LargeString, `["large", null]`, "large"},
+		{arrow.BinaryTypes.StringView, `["view", null]`, "view"},
+		{&arrow.FixedSizeBinaryType{ByteWidth: 2}, `["YWI=", null]`, []byte("ab")},
+		{arrow.FixedWidthTypes.Date32, `[1, null]`, time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dt.String(), func(t *testing.T) {
+			arr, _, err := array.FromJSON(mem, tt.dt, strings.NewReader(tt.json))
+			require.NoError(t, err)
+			defer arr.Release()
+
+			v, err := fromArrowType(arr, 0)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, v)
+
+			v, err = fromArrowType(arr, 1)
+			require.NoError(t, err)
+			require.Nil(t, v)
+		})
+	}
+}
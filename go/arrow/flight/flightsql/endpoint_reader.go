@@ -0,0 +1,247 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"google.golang.org/grpc"
+)
+
+// LocationDialer connects to the location of a FlightEndpoint so that its
+// ticket can be retrieved from there. The returned client is closed once
+// the endpoint has been read.
+type LocationDialer func(ctx context.Context, location *flight.Location) (flight.Client, error)
+
+// DialLocation connects to a location without caching the connection.
+// It supports grpc, grpc+tcp and grpc+unix locations, which are dialed
+// without transport security, and grpc+tls locations, which use the
+// system's root certificates.
+func DialLocation(ctx context.Context, location *flight.Location) (flight.Client, error) {
+	return dialLocation(ctx, location, nil, nil)
+}
+
+// ExecuteQuery executes the query and returns a reader over the results
+// of every endpoint of the resulting FlightInfo, see ReadFlightInfo.
+func (c *Client) ExecuteQuery(ctx context.Context, query string, opts ...grpc.CallOption) (flight.MessageReader, error) {
+	info, err := c.Execute(ctx, query, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.ReadFlightInfo(ctx, info, opts...)
+}
+
+// endpointReaderConfig holds the options of ReadFlightInfo which don't
+// configure the reader of the flight package it is built on.
+type endpointReaderConfig struct {
+	renewWindow time.Duration
+	// verifyChecksums verifies the checksum of each endpoint
+	verifyChecksums bool
+	// mem is the allocator of the records read, if not that of the client
+	mem memory.Allocator
+}
+
+// WithClientAllocator makes the readers of DoGet, ReadFlightInfo and
+// ExecuteQuery decode the records with mem rather than the Alloc of the
+// client, such as to track them against the memory budget of the caller.
+// The records are released to mem once the reader and the records
+// retained from it are released.
+func WithClientAllocator(mem memory.Allocator) grpc.CallOption {
+	return endpointReaderOption{apply: func(cfg *endpointReaderConfig) { cfg.mem = mem }}
+}
+
+// allocator returns the allocator of the records read by a call with
+// opts, see WithClientAllocator.
+func (c *Client) allocator(opts []grpc.CallOption) memory.Allocator {
+	var cfg endpointReaderConfig
+	for _, o := range opts {
+		if o, ok := o.(endpointReaderOption); ok {
+			o.apply(&cfg)
+		}
+	}
+	switch {
+	case cfg.mem != nil:
+		return cfg.mem
+	case c.Alloc != nil:
+		return c.Alloc
+	}
+	return memory.DefaultAllocator
+}
+
+// endpointReaderOption is a grpc.CallOption which configures the reader
+// returned by ReadFlightInfo and ExecuteQuery. gRPC itself ignores it.
+type endpointReaderOption struct {
+	grpc.EmptyCallOption
+	apply func(*endpointReaderConfig)
+}
+
+// WithEndpointConcurrency allows ReadFlightInfo and ExecuteQuery to fetch
+// up to n endpoints at once, see flight.WithEndpointConcurrency.
+func WithEndpointConcurrency(n int) grpc.CallOption {
+	return flight.WithEndpointConcurrency(n)
+}
+
+// WithMaxBufferedRecords limits the number of records fetched ahead of
+// the reader when endpoints are fetched concurrently, see
+// flight.WithMaxBufferedRecords.
+func WithMaxBufferedRecords(n int) grpc.CallOption {
+	return flight.WithMaxBufferedRecords(n)
+}
+
+// WithUnorderedEndpoints allows records to be returned in the order they
+// arrive when endpoints are fetched concurrently, see
+// flight.WithUnorderedEndpoints.
+func WithUnorderedEndpoints() grpc.CallOption {
+	return flight.WithUnorderedEndpoints()
+}
+
+// WithLocationPolicy makes ReadFlightInfo try the Locations of each
+// endpoint in the order chosen by policy, in place of the Policy of the
+// client's LocationPool, see flight.WithLocationPolicy.
+func WithLocationPolicy(policy flight.LocationPolicy) grpc.CallOption {
+	return flight.WithLocationPolicy(policy)
+}
+
+// ReadFlightInfo returns a single reader which retrieves each endpoint of
+// info in order, as flight.NewFlightInfoReader does. Endpoints without a
+// Location are retrieved using this client, otherwise each Location is
+// tried in turn until one succeeds, in the order chosen by the Policy of
+// the client's LocationPool if it has one, connecting through the
+// LocationPool, or the LocationDialer if there is one. If none succeeds,
+// the endpoint is retrieved using this client. Each stream is released
+// as soon as it is exhausted.
+//
+// By default each endpoint is only fetched once the previous one has
+// been read; see WithEndpointConcurrency, WithMaxBufferedRecords and
+// WithUnorderedEndpoints to fetch several at once. The endpoints of an
+// info marked as Ordered are always fetched one after the other.
+//
+// The schema of the reader is that of the first endpoint; if a later
+// endpoint returns a different schema, reading stops with an error
+// wrapping arrow.ErrInvalid. Release should be called on the reader
+// when done.
+//
+// The app metadata a server attaches to each record, such as with the
+// Chunk function, is available alongside it from the reader's Chunk and
+// LatestAppMetadata methods.
+func (c *Client) ReadFlightInfo(ctx context.Context, info *flight.FlightInfo, opts ...grpc.CallOption) (flight.MessageReader, error) {
+	var cfg endpointReaderConfig
+	for _, o := range opts {
+		if o, ok := o.(endpointReaderOption); ok {
+			o.apply(&cfg)
+		}
+	}
+
+	// the pool's policy comes first, so that the caller's replaces it
+	readerOpts := make([]grpc.CallOption, 0, len(opts)+4)
+	if c.Locations != nil && c.Locations.opts.Policy != nil {
+		readerOpts = append(readerOpts, flight.WithLocationPolicy(c.Locations.opts.Policy))
+	}
+	readerOpts = append(append(readerOpts, opts...),
+		flight.WithReaderAllocator(c.allocator(opts)),
+		flight.WithLocationOpener(func(ctx context.Context, loc *flight.Location, tkt *flight.Ticket) (*flight.Reader, func(), error) {
+			return c.openLocation(ctx, loc, tkt, opts)
+		}))
+	if cfg.verifyChecksums {
+		readerOpts = append(readerOpts, flight.WithEndpointVerifier(func(int) flight.EndpointVerifier {
+			return &checksumVerifier{}
+		}))
+	}
+
+	var renewer *endpointRenewer
+	if cfg.renewWindow > 0 && len(info.Endpoint) > 1 {
+		renewer = newEndpointRenewer(ctx, c, info.Endpoint, cfg.renewWindow, opts)
+		readerOpts = append(readerOpts, flight.WithEndpointResolver(renewer.resolve))
+	}
+
+	rdr, err := flight.NewFlightInfoReader(ctx, c.Client, info, readerOpts...)
+	if renewer == nil {
+		return rdr, err
+	}
+	if err != nil {
+		renewer.stop()
+		return nil, err
+	}
+	return &renewingReader{MessageReader: rdr, refCount: 1, renewer: renewer}, nil
+}
+
+// renewingReader stops renewing the endpoints of a FlightInfo once its
+// reader is released.
+type renewingReader struct {
+	flight.MessageReader
+	refCount int64
+	renewer  *endpointRenewer
+}
+
+func (r *renewingReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+func (r *renewingReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) == 0 {
+		r.MessageReader.Release()
+		r.renewer.stop()
+	}
+}
+
+// openLocation retrieves tkt from loc, using the LocationDialer if there
+// is one and the client's LocationPool otherwise.
+func (c *Client) openLocation(ctx context.Context, loc *flight.Location, tkt *flight.Ticket, opts []grpc.CallOption) (*flight.Reader, func(), error) {
+	if c.LocationDialer != nil || c.Locations == nil {
+		dial := c.LocationDialer
+		if dial == nil {
+			dial = DialLocation
+		}
+
+		cl, err := dial(ctx, loc)
+		if err != nil {
+			return nil, nil, err
+		}
+		rdr, err := doGetFrom(ctx, cl, c.allocator(opts), tkt, opts...)
+		if err != nil {
+			cl.Close()
+			return nil, nil, err
+		}
+		return rdr, func() { cl.Close() }, nil
+	}
+
+	cl, release, err := c.Locations.acquire(ctx, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+	rdr, err := doGetFrom(ctx, cl, c.allocator(opts), tkt, opts...)
+	if err != nil {
+		// a server error doesn't mean the connection is broken, but
+		// it's no use keeping one we can't read from
+		release(false)
+		return nil, nil, err
+	}
+	return rdr, func() { release(true) }, nil
+}
+
+func doGetFrom(ctx context.Context, cl flight.Client, mem memory.Allocator, tkt *flight.Ticket, opts ...grpc.CallOption) (*flight.Reader, error) {
+	stream, err := cl.DoGet(ctx, tkt, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return flight.NewRecordReader(stream, ipc.WithAllocator(mem))
+}
@@ -0,0 +1,160 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"google.golang.org/grpc"
+)
+
+// WithEndpointRenewal makes ReadFlightInfo and ExecuteQuery renew each
+// endpoint which hasn't been fetched yet with RenewFlightEndpoint once it
+// is within window of its expiration time, so that results read slowly
+// don't expire before they are fetched. The renewed endpoint is fetched
+// in place of the original one. A failure to renew an endpoint is only
+// reported once the reader gets to fetching it.
+func WithEndpointRenewal(window time.Duration) grpc.CallOption {
+	return endpointReaderOption{apply: func(cfg *endpointReaderConfig) { cfg.renewWindow = window }}
+}
+
+// endpointRenewer renews the endpoints of a FlightInfo in the background
+// until they are taken to be fetched.
+type endpointRenewer struct {
+	c      *Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	opts   []grpc.CallOption
+	window time.Duration
+	// done is closed once the renewing goroutine has exited
+	done chan struct{}
+
+	mu        sync.Mutex
+	endpoints []*flight.FlightEndpoint
+	// taken marks the endpoints being fetched, which are no longer
+	// renewed, final those which can't be renewed any further
+	taken, final []bool
+	errs         []error
+}
+
+func newEndpointRenewer(ctx context.Context, c *Client, endpoints []*flight.FlightEndpoint, window time.Duration, opts []grpc.CallOption) *endpointRenewer {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &endpointRenewer{
+		c:         c,
+		ctx:       ctx,
+		cancel:    cancel,
+		opts:      opts,
+		window:    window,
+		done:      make(chan struct{}),
+		endpoints: append([]*flight.FlightEndpoint(nil), endpoints...),
+		taken:     make([]bool, len(endpoints)),
+		final:     make([]bool, len(endpoints)),
+		errs:      make([]error, len(endpoints)),
+	}
+	go r.run()
+	return r
+}
+
+// take returns the endpoint at index idx to be fetched, as last renewed,
+// or the error renewing it failed with.
+func (r *endpointRenewer) take(idx int) (*flight.FlightEndpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.taken[idx] = true
+	return r.endpoints[idx], r.errs[idx]
+}
+
+// stop stops renewing endpoints and waits for the renewal in progress,
+// if any.
+func (r *endpointRenewer) stop() {
+	r.cancel()
+	<-r.done
+}
+
+// resolve returns the endpoint at index idx to be fetched, as last
+// renewed, implementing flight.EndpointResolver.
+func (r *endpointRenewer) resolve(_ context.Context, idx int, _ *flight.FlightEndpoint) (*flight.FlightEndpoint, error) {
+	return r.take(idx)
+}
+
+// next returns the index of the endpoint to renew first and when to
+// renew it, or -1 if none is left to renew.
+func (r *endpointRenewer) next() (int, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx, at := -1, time.Time{}
+	for i, ep := range r.endpoints {
+		if r.taken[i] || r.final[i] || r.errs[i] != nil || ep.GetExpirationTime() == nil {
+			continue
+		}
+		if renewAt := ep.GetExpirationTime().AsTime().Add(-r.window); idx < 0 || renewAt.Before(at) {
+			idx, at = i, renewAt
+		}
+	}
+	return idx, at
+}
+
+func (r *endpointRenewer) run() {
+	defer close(r.done)
+
+	for {
+		idx, at := r.next()
+		if idx < 0 {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-timer.C:
+		case <-r.ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		r.mu.Lock()
+		ep, taken := r.endpoints[idx], r.taken[idx]
+		r.mu.Unlock()
+		if taken {
+			continue
+		}
+
+		renewed, err := r.c.RenewFlightEndpoint(r.ctx, &flight.RenewFlightEndpointRequest{Endpoint: ep}, r.opts...)
+		if r.ctx.Err() != nil {
+			return
+		}
+
+		r.mu.Lock()
+		switch {
+		case r.taken[idx]:
+			// the endpoint is already being fetched
+		case err != nil:
+			r.errs[idx] = fmt.Errorf("arrow/flightsql: failed to renew endpoint %d: %w", idx, err)
+		default:
+			r.endpoints[idx] = renewed
+			// an endpoint which wasn't extended beyond the window would
+			// otherwise be renewed again right away
+			exp := renewed.GetExpirationTime()
+			r.final[idx] = exp == nil || !exp.AsTime().Add(-r.window).After(time.Now())
+		}
+		r.mu.Unlock()
+	}
+}
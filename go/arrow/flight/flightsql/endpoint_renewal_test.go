@@ -0,0 +1,244 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/flightsqltest"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// expiringServer serves results whose endpoints expire ttl after they
+// were issued or last renewed. Renewing the endpoint at index
+// failRenewal fails.
+type expiringServer struct {
+	flightsql.BaseServer
+	endpoints   int
+	ttl         time.Duration
+	failRenewal int
+
+	renewals int64
+}
+
+// endpoint returns the endpoint at index idx, valid for ttl.
+func (s *expiringServer) endpoint(idx int) (*flight.FlightEndpoint, error) {
+	exp := time.Now().Add(s.ttl)
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte(fmt.Sprintf("%d/%d", idx, exp.UnixNano())))
+	if err != nil {
+		return nil, err
+	}
+	return &flight.FlightEndpoint{Ticket: &flight.Ticket{Ticket: tkt}, ExpirationTime: timestamppb.New(exp)}, nil
+}
+
+func (s *expiringServer) GetFlightInfoStatement(_ context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	endpoints := make([]*flight.FlightEndpoint, s.endpoints)
+	for i := range endpoints {
+		var err error
+		if endpoints[i], err = s.endpoint(i); err != nil {
+			return nil, err
+		}
+	}
+	return flightsql.NewFlightInfo(desc, latencySchema, s.Alloc, flightsql.WithEndpoints(endpoints...)), nil
+}
+
+// parseExpiringHandle returns the index and expiration time of the
+// endpoint whose statement handle is handle.
+func parseExpiringHandle(handle []byte) (int, time.Time, error) {
+	idx, exp, _ := strings.Cut(string(handle), "/")
+	i, err := strconv.Atoi(idx)
+	if err != nil {
+		return 0, time.Time{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	nanos, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return i, time.Unix(0, nanos), nil
+}
+
+func (s *expiringServer) RenewFlightEndpoint(_ context.Context, req *flight.RenewFlightEndpointRequest) (*flight.FlightEndpoint, error) {
+	cmd, err := flightsql.ParseCommand(req.GetEndpoint().GetTicket().GetTicket())
+	if err != nil {
+		return nil, err
+	}
+	tkt, ok := cmd.(flightsql.StatementQueryTicket)
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "not a statement ticket")
+	}
+	idx, exp, err := parseExpiringHandle(tkt.GetStatementHandle())
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(exp) {
+		return nil, status.Errorf(codes.NotFound, "endpoint %d expired", idx)
+	}
+	if idx == s.failRenewal {
+		return nil, status.Errorf(codes.Unavailable, "cannot renew endpoint %d", idx)
+	}
+	atomic.AddInt64(&s.renewals, 1)
+	return s.endpoint(idx)
+}
+
+func (s *expiringServer) DoGetStatement(_ context.Context, tkt flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	idx, exp, err := parseExpiringHandle(tkt.GetStatementHandle())
+	if err != nil {
+		return nil, nil, err
+	}
+	if time.Now().After(exp) {
+		return nil, nil, status.Errorf(codes.NotFound, "endpoint %d expired", idx)
+	}
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).Append(int64(idx))
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+	close(ch)
+	return latencySchema, ch, nil
+}
+
+// readSlowly reads each record of the query, waiting for pause after
+// each of them.
+func readSlowly(cl *flightsql.Client, pause time.Duration, opts ...grpc.CallOption) ([]int64, error) {
+	rdr, err := cl.ExecuteQuery(context.Background(), "SELECT 1", opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Release()
+
+	var got []int64
+	for rdr.Next() {
+		got = append(got, rdr.Record().Column(0).(*array.Int64).Value(0))
+		time.Sleep(pause)
+	}
+	return got, rdr.Err()
+}
+
+func TestEndpointRenewal(t *testing.T) {
+	const (
+		ttl    = 150 * time.Millisecond
+		window = 100 * time.Millisecond
+		pause  = 200 * time.Millisecond
+	)
+
+	srv := &expiringServer{endpoints: 3, ttl: ttl, failRenewal: -1}
+	cl := flightsqltest.StartServer(t, srv)
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	cl.Alloc = mem
+
+	// reading slower than the endpoints expire fails without renewal
+	got, err := readSlowly(cl, pause)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, []int64{0}, got)
+
+	for _, opts := range [][]grpc.CallOption{
+		{flightsql.WithEndpointRenewal(window)},
+		{flightsql.WithEndpointRenewal(window), flightsql.WithEndpointConcurrency(2), flightsql.WithMaxBufferedRecords(2)},
+	} {
+		atomic.StoreInt64(&srv.renewals, 0)
+		got, err := readSlowly(cl, pause, opts...)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0, 1, 2}, got)
+		assert.Positive(t, atomic.LoadInt64(&srv.renewals))
+	}
+
+	// a failure to renew is reported once the endpoint is fetched
+	srv.failRenewal = 2
+	got, err = readSlowly(cl, pause, flightsql.WithEndpointRenewal(window))
+	assert.Equal(t, []int64{0, 1}, got)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.ErrorContains(t, err, "failed to renew endpoint 2")
+}
+
+func TestEndpointRenewalRelease(t *testing.T) {
+	srv := &expiringServer{endpoints: 3, ttl: time.Hour, failRenewal: -1}
+	cl := flightsqltest.StartServer(t, srv)
+
+	// releasing the reader stops renewals scheduled far ahead
+	rdr, err := cl.ExecuteQuery(context.Background(), "SELECT 1", flightsql.WithEndpointRenewal(time.Minute))
+	require.NoError(t, err)
+	require.True(t, rdr.Next())
+	done := make(chan struct{})
+	go func() {
+		rdr.Release()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("releasing the reader did not stop the renewals")
+	}
+	assert.Zero(t, atomic.LoadInt64(&srv.renewals))
+}
+
+func TestRenewFlightEndpoint(t *testing.T) {
+	srv := &expiringServer{endpoints: 1, ttl: time.Minute, failRenewal: -1}
+	cl := flightsqltest.StartServer(t, srv)
+
+	ctx := context.Background()
+	actions, err := cl.Client.ListActions(ctx, &flight.Empty{})
+	require.NoError(t, err)
+	var found bool
+	for {
+		a, err := actions.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		found = found || a.Type == flight.RenewFlightEndpointActionType
+	}
+	assert.True(t, found, "RenewFlightEndpoint missing from ListActions")
+
+	info, err := cl.Execute(ctx, "SELECT 1")
+	require.NoError(t, err)
+	require.Len(t, info.GetEndpoint(), 1)
+	ep := info.GetEndpoint()[0]
+
+	time.Sleep(10 * time.Millisecond)
+	renewed, err := cl.RenewFlightEndpoint(ctx, &flight.RenewFlightEndpointRequest{Endpoint: ep})
+	require.NoError(t, err)
+	assert.True(t, renewed.GetExpirationTime().AsTime().After(ep.GetExpirationTime().AsTime()),
+		"renewed endpoint expires at %s, not after %s", renewed.GetExpirationTime().AsTime(), ep.GetExpirationTime().AsTime())
+	assert.EqualValues(t, 1, atomic.LoadInt64(&srv.renewals))
+
+	// the renewed endpoint can still be fetched
+	rdr, err := cl.DoGet(ctx, renewed.GetTicket())
+	require.NoError(t, err)
+	defer rdr.Release()
+	require.True(t, rdr.Next())
+	assert.EqualValues(t, 0, rdr.Record().Column(0).(*array.Int64).Value(0))
+}
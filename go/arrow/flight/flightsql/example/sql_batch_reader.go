@@ -28,11 +28,11 @@ import (
 
 	"github.com/apache/arrow/go/v16/arrow"
 	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
 	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
 	"github.com/apache/arrow/go/v16/arrow/internal/debug"
 	"github.com/apache/arrow/go/v16/arrow/memory"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
@@ -261,12 +261,8 @@ func (r *SqlBatchReader) Next() bool {
 	for rows < maxBatchSize && r.rows.Next() {
 		if err := r.rows.Scan(r.rowdest...); err != nil {
 			// Not really useful except for testing Flight SQL clients
-			detail := wrapperspb.StringValue{Value: r.schema.String()}
-			if st, sterr := status.New(codes.Unknown, err.Error()).WithDetails(&detail); sterr != nil {
-				r.err = err
-			} else {
-				r.err = st.Err()
-			}
+			r.err = flight.ErrorWithDetails(codes.Unknown, err.Error(),
+				&wrapperspb.StringValue{Value: r.schema.String()})
 			return false
 		}
 
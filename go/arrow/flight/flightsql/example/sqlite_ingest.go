@@ -0,0 +1,201 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package example
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// DoPutCommandStatementIngest inserts the records of rdr into a table,
+// creating it first if needed. Unless a transaction is given, the records
+// are inserted in a transaction of their own so that the ingestion is
+// all or nothing.
+func (s *SQLiteFlightSQLServer) DoPutCommandStatementIngest(ctx context.Context, opts flightsql.IngestOptions, rdr flight.MessageReader) (n int64, err error) {
+	if opts.Catalog != nil {
+		return 0, status.Error(codes.InvalidArgument, "sqlite does not support catalogs")
+	}
+
+	var tx *sql.Tx
+	if len(opts.TransactionID) > 0 {
+		val, ok := s.openTransactions.Load(string(opts.TransactionID))
+		if !ok {
+			return 0, status.Error(codes.InvalidArgument, "invalid transaction handle provided")
+		}
+		tx = val.(*sql.Tx)
+	} else {
+		if tx, err = s.db.BeginTx(ctx, nil); err != nil {
+			return 0, status.Errorf(codes.Internal, "failed to begin transaction: %s", err.Error())
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			if err = tx.Commit(); err != nil {
+				n, err = 0, status.Errorf(codes.Internal, "failed to commit ingestion: %s", err.Error())
+			}
+		}()
+	}
+
+	schema := rdr.Schema()
+	if schema == nil {
+		return 0, status.Error(codes.InvalidArgument, "missing schema of the records to ingest")
+	}
+
+	prefix := ""
+	switch {
+	case opts.DBSchema != nil:
+		prefix = quoteIdent(*opts.DBSchema) + "."
+	case opts.Temporary:
+		prefix = "temp."
+	}
+	table := prefix + quoteIdent(opts.Table)
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT count(*) > 0 FROM "+prefix+"sqlite_master WHERE type = 'table' AND name = ?", opts.Table).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+
+	action, err := opts.TableAction(exists)
+	if err != nil {
+		return 0, err
+	}
+	if action == flightsql.IngestTableReplace {
+		if _, err = tx.ExecContext(ctx, "DROP TABLE "+table); err != nil {
+			return 0, err
+		}
+	}
+
+	if action != flightsql.IngestTableAppend {
+		cols := make([]string, schema.NumFields())
+		for i, f := range schema.Fields() {
+			typ, err := sqliteColumnType(f.Type)
+			if err != nil {
+				return 0, err
+			}
+			cols[i] = quoteIdent(f.Name) + " " + typ
+		}
+
+		stmt := "CREATE TABLE "
+		if opts.Temporary {
+			stmt = "CREATE TEMP TABLE "
+		}
+		if _, err = tx.ExecContext(ctx, stmt+table+" ("+strings.Join(cols, ", ")+")"); err != nil {
+			return 0, err
+		}
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", schema.NumFields()), ", ")
+	insert, err := tx.PrepareContext(ctx, "INSERT INTO "+table+" VALUES ("+placeholders+")")
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+
+	args := make([]interface{}, schema.NumFields())
+	for rdr.Next() {
+		rec := rdr.Record()
+		for i := 0; i < int(rec.NumRows()); i++ {
+			for c, col := range rec.Columns() {
+				if args[c], err = sqliteValue(col, i); err != nil {
+					return 0, err
+				}
+			}
+			if _, err = insert.ExecContext(ctx, args...); err != nil {
+				return 0, err
+			}
+		}
+		n += rec.NumRows()
+	}
+	return n, rdr.Err()
+}
+
+// sqliteColumnType returns the type of the column of a table created to
+// ingest records of type dt.
+func sqliteColumnType(dt arrow.DataType) (string, error) {
+	switch {
+	case arrow.IsInteger(dt.ID()), dt.ID() == arrow.BOOL:
+		return "INTEGER", nil
+	case arrow.IsFloating(dt.ID()):
+		return "REAL", nil
+	case dt.ID() == arrow.STRING, dt.ID() == arrow.LARGE_STRING:
+		return "TEXT", nil
+	case arrow.IsBaseBinary(dt.ID()), dt.ID() == arrow.FIXED_SIZE_BINARY:
+		return "BLOB", nil
+	}
+	return "", status.Errorf(codes.Unimplemented, "cannot ingest columns of type %s", dt)
+}
+
+// sqliteValue returns the value at index i of arr as an argument of a
+// statement.
+func sqliteValue(arr arrow.Array, i int) (interface{}, error) {
+	if arr.IsNull(i) {
+		return nil, nil
+	}
+
+	switch arr := arr.(type) {
+	case *array.Boolean:
+		return arr.Value(i), nil
+	case *array.Int8:
+		return int64(arr.Value(i)), nil
+	case *array.Int16:
+		return int64(arr.Value(i)), nil
+	case *array.Int32:
+		return int64(arr.Value(i)), nil
+	case *array.Int64:
+		return arr.Value(i), nil
+	case *array.Uint8:
+		return int64(arr.Value(i)), nil
+	case *array.Uint16:
+		return int64(arr.Value(i)), nil
+	case *array.Uint32:
+		return int64(arr.Value(i)), nil
+	case *array.Uint64:
+		return int64(arr.Value(i)), nil
+	case *array.Float32:
+		return float64(arr.Value(i)), nil
+	case *array.Float64:
+		return arr.Value(i), nil
+	case array.StringLike:
+		return arr.Value(i), nil
+	case *array.Binary:
+		return arr.Value(i), nil
+	case *array.LargeBinary:
+		return arr.Value(i), nil
+	case *array.FixedSizeBinary:
+		return arr.Value(i), nil
+	}
+	return nil, fmt.Errorf("%w: cannot ingest values of type %s", arrow.ErrNotImplemented, arr.DataType())
+}
@@ -210,8 +210,10 @@ type SQLiteFlightSQLServer struct {
 }
 
 func NewSQLiteFlightSQLServer(db *sql.DB) (*SQLiteFlightSQLServer, error) {
-	ret := &SQLiteFlightSQLServer{db: db}
-	ret.Alloc = memory.DefaultAllocator
+	ret := &SQLiteFlightSQLServer{
+		BaseServer: flightsql.NewBaseServer(flightsql.WithBaseServerAllocator(memory.DefaultAllocator)),
+		db:         db,
+	}
 	for k, v := range SqlInfoResultMap() {
 		ret.RegisterSqlInfo(flightsql.SqlInfo(k), v)
 	}
@@ -0,0 +1,81 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"google.golang.org/grpc"
+)
+
+// ExecuteResult reads the results of a query along with the FlightInfo
+// describing them, see ExecuteWithInfo.
+type ExecuteResult struct {
+	flight.MessageReader
+
+	// Info is the FlightInfo returned for the query.
+	Info *flight.FlightInfo
+}
+
+// EndpointInfo is what a server attached to one endpoint of a result.
+type EndpointInfo struct {
+	// AppMetadata is the app metadata of the endpoint, if any.
+	AppMetadata []byte
+	// ExpirationTime is when the endpoint stops being retrievable, or
+	// the zero time if it doesn't expire.
+	ExpirationTime time.Time
+}
+
+// ExecuteWithInfo executes the query and returns a reader over the
+// results of every endpoint of the resulting FlightInfo, as ExecuteQuery
+// does, keeping the FlightInfo so that the metadata the server attached
+// to it remains available. Release should be called on the result when
+// done.
+func (c *Client) ExecuteWithInfo(ctx context.Context, query string, opts ...grpc.CallOption) (*ExecuteResult, error) {
+	info, err := c.Execute(ctx, query, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rdr, err := c.ReadFlightInfo(ctx, info, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ExecuteResult{MessageReader: rdr, Info: info}, nil
+}
+
+// Ordered reports whether the server marked the result as ordered, in
+// which case its endpoints were fetched one after the other.
+func (r *ExecuteResult) Ordered() bool { return r.Info.GetOrdered() }
+
+// AppMetadata returns the app metadata of the FlightInfo, if any.
+func (r *ExecuteResult) AppMetadata() []byte { return r.Info.GetAppMetadata() }
+
+// Endpoints returns the app metadata and expiration time of each endpoint
+// of the result, in endpoint order.
+func (r *ExecuteResult) Endpoints() []EndpointInfo {
+	endpoints := make([]EndpointInfo, len(r.Info.GetEndpoint()))
+	for i, ep := range r.Info.GetEndpoint() {
+		endpoints[i].AppMetadata = ep.GetAppMetadata()
+		if ts := ep.GetExpirationTime(); ts != nil {
+			endpoints[i].ExpirationTime = ts.AsTime()
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,135 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/apache/arrow/go/v16/arrow/util"
+)
+
+// TotalUnknown is the value of FlightInfo.TotalRecords and
+// FlightInfo.TotalBytes when the size of the result is not known.
+const TotalUnknown int64 = -1
+
+// FlightInfoOption customizes a FlightInfo created by NewFlightInfo.
+type FlightInfoOption func(*flight.FlightInfo)
+
+// WithTotalRecords sets the number of records the result is expected to
+// contain. Clients may use it to preallocate, so it should only be set
+// when the backend can estimate it with reasonable accuracy.
+func WithTotalRecords(n int64) FlightInfoOption {
+	return func(info *flight.FlightInfo) { info.TotalRecords = n }
+}
+
+// WithTotalBytes sets the expected size of the result in bytes.
+func WithTotalBytes(n int64) FlightInfoOption {
+	return func(info *flight.FlightInfo) { info.TotalBytes = n }
+}
+
+// WithTotalsFromRecords sets the number of records and bytes from a
+// result which has already been materialized, such as a cached result
+// set. The byte count is the size of the buffers of the records.
+func WithTotalsFromRecords(recs ...arrow.Record) FlightInfoOption {
+	return func(info *flight.FlightInfo) {
+		info.TotalRecords, info.TotalBytes = 0, 0
+		for _, r := range recs {
+			info.TotalRecords += r.NumRows()
+			info.TotalBytes += util.TotalRecordSize(r)
+		}
+	}
+}
+
+// WithEndpoints replaces the default endpoint of the FlightInfo.
+func WithEndpoints(endpoints ...*flight.FlightEndpoint) FlightInfoOption {
+	return func(info *flight.FlightInfo) { info.Endpoint = endpoints }
+}
+
+// WithOrdered marks the result as ordered: its rows are only in order if
+// the endpoints are read one after the other, as the results of a query
+// with an ORDER BY split across partitions. Client.ReadFlightInfo never
+// fetches the endpoints of such a result concurrently.
+func WithOrdered() FlightInfoOption {
+	return func(info *flight.FlightInfo) { info.Ordered = true }
+}
+
+// NewFlightInfo is a helper for the GetFlightInfo handlers of a Server.
+// It returns a FlightInfo for desc with the serialized schema (if not nil)
+// and a single endpoint whose ticket is the command of desc, to be served
+// by the matching DoGet handler. TotalRecords and TotalBytes are set to
+// TotalUnknown unless provided with one of the options:
+//
+//	return flightsql.NewFlightInfo(desc, schema, s.Alloc,
+//		flightsql.WithTotalRecords(rows), flightsql.WithTotalBytes(size)), nil
+func NewFlightInfo(desc *flight.FlightDescriptor, schema *arrow.Schema, mem memory.Allocator, opts ...FlightInfoOption) *flight.FlightInfo {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+
+	info := &flight.FlightInfo{
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: desc.Cmd}}},
+		FlightDescriptor: desc,
+		TotalRecords:     TotalUnknown,
+		TotalBytes:       TotalUnknown,
+	}
+	if schema != nil {
+		info.Schema = flight.SerializeSchema(schema, mem)
+	}
+
+	for _, o := range opts {
+		o(info)
+	}
+	return info
+}
+
+// NewStatementFlightInfo is like NewFlightInfo, but the ticket of the
+// endpoint is a TicketStatementQuery containing handle, see
+// TicketStatementQuery. It lets stateless servers put in the handle all
+// that DoGetStatement needs, such as the query itself, rather than an
+// identifier of state kept by the server between the two calls:
+//
+//	func (s *server) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+//		return flightsql.NewStatementFlightInfo(desc, resultSchema, s.Alloc, []byte(cmd.GetQuery()))
+//	}
+//
+//	func (s *server) DoGetStatement(ctx context.Context, tkt flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+//		return s.runQuery(ctx, string(tkt.GetStatementHandle()))
+//	}
+//
+// The handle is sent to the client and back as is, so it shouldn't
+// contain anything the client mustn't see or alter, see NewSignedHandle.
+// The locations of the endpoints given with WithEndpoints are checked,
+// failing with an error wrapping arrow.ErrInvalid if one of them is
+// malformed, see flight.ParseLocation.
+func NewStatementFlightInfo(desc *flight.FlightDescriptor, schema *arrow.Schema, mem memory.Allocator, handle []byte, opts ...FlightInfoOption) (*flight.FlightInfo, error) {
+	tkt, err := TicketStatementQuery(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]FlightInfoOption{WithEndpoints(&flight.FlightEndpoint{Ticket: tkt})}, opts...)
+	info := NewFlightInfo(desc, schema, mem, opts...)
+	for _, ep := range info.Endpoint {
+		for _, loc := range ep.Location {
+			if _, _, _, err := flight.ParseLocation(loc.GetUri()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return info, nil
+}
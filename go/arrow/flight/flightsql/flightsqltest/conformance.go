@@ -0,0 +1,219 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsqltest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/schema_ref"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Capabilities describes which parts of the protocol a server supports
+// so that RunConformance can skip the tests for anything else.
+type Capabilities struct {
+	// StatementQuery is a query which the server can execute, returning
+	// at least one row. If empty, the statement and prepared statement
+	// tests are skipped.
+	StatementQuery string
+	// PreparedStatements indicates that StatementQuery can also be
+	// executed as a prepared statement.
+	PreparedStatements bool
+	// InvalidQuery is a query which the server must reject with an
+	// error, either when planning or when retrieving its results.
+	InvalidQuery string
+
+	Catalogs     bool
+	DBSchemas    bool
+	Tables       bool
+	TableTypes   bool
+	SqlInfo      bool
+	XdbcTypeInfo bool
+}
+
+// RunConformance starts srv and runs a set of subtests against it
+// covering statement execution, prepared statements, catalog metadata,
+// SqlInfo and error propagation. Tests for anything not enabled in caps
+// are skipped.
+func RunConformance(t *testing.T, srv flightsql.Server, caps Capabilities) {
+	cl := StartServer(t, srv)
+	c := &conformance{cl: cl, caps: caps}
+
+	t.Run("StatementQuery", c.statementQuery)
+	t.Run("PreparedStatement", c.preparedStatement)
+	t.Run("InvalidQuery", c.invalidQuery)
+	t.Run("Catalogs", c.metadata(caps.Catalogs, schema_ref.Catalogs,
+		func(ctx context.Context) (*flight.FlightInfo, error) { return cl.GetCatalogs(ctx) }))
+	t.Run("DBSchemas", c.metadata(caps.DBSchemas, schema_ref.DBSchemas,
+		func(ctx context.Context) (*flight.FlightInfo, error) {
+			return cl.GetDBSchemas(ctx, &flightsql.GetDBSchemasOpts{})
+		}))
+	t.Run("Tables", c.metadata(caps.Tables, schema_ref.Tables,
+		func(ctx context.Context) (*flight.FlightInfo, error) {
+			return cl.GetTables(ctx, &flightsql.GetTablesOpts{})
+		}))
+	t.Run("TableTypes", c.metadata(caps.TableTypes, schema_ref.TableTypes,
+		func(ctx context.Context) (*flight.FlightInfo, error) { return cl.GetTableTypes(ctx) }))
+	t.Run("SqlInfo", c.metadata(caps.SqlInfo, schema_ref.SqlInfo,
+		func(ctx context.Context) (*flight.FlightInfo, error) { return cl.GetSqlInfo(ctx, nil) }))
+	t.Run("XdbcTypeInfo", c.metadata(caps.XdbcTypeInfo, schema_ref.XdbcTypeInfo,
+		func(ctx context.Context) (*flight.FlightInfo, error) { return cl.GetXdbcTypeInfo(ctx, nil) }))
+}
+
+type conformance struct {
+	cl   *flightsql.Client
+	caps Capabilities
+}
+
+func releaseAll(recs []arrow.Record) {
+	for _, r := range recs {
+		r.Release()
+	}
+}
+
+// checkInfoSchema verifies that the schema advertised in info, if any,
+// matches the schema of the stream which was actually returned.
+func checkInfoSchema(t *testing.T, info *flight.FlightInfo, actual *arrow.Schema) {
+	t.Helper()
+	if len(info.Schema) == 0 {
+		return
+	}
+
+	advertised, err := flight.DeserializeSchema(info.Schema, memory.DefaultAllocator)
+	require.NoError(t, err)
+	assert.Truef(t, advertised.Equal(actual),
+		"FlightInfo schema does not match stream schema\nadvertised: %s\nactual:     %s", advertised, actual)
+}
+
+// checkFields verifies that actual has the names and types of the fields
+// of expected, ignoring field metadata.
+func checkFields(t *testing.T, expected, actual *arrow.Schema) {
+	t.Helper()
+	require.NotNil(t, actual)
+	require.Equalf(t, expected.NumFields(), actual.NumFields(), "schema: %s", actual)
+	for i, f := range expected.Fields() {
+		got := actual.Field(i)
+		assert.Equal(t, f.Name, got.Name)
+		assert.Truef(t, arrow.TypeEqual(f.Type, got.Type),
+			"field %s: expected type %s, got %s", f.Name, f.Type, got.Type)
+	}
+}
+
+func (c *conformance) statementQuery(t *testing.T) {
+	if c.caps.StatementQuery == "" {
+		t.Skip("statement queries not supported")
+	}
+
+	ctx := context.Background()
+	info, err := c.cl.Execute(ctx, c.caps.StatementQuery)
+	require.NoError(t, err)
+	require.NotEmpty(t, info.Endpoint, "FlightInfo must have at least one endpoint")
+
+	schema, recs, err := ReadAll(ctx, c.cl, info)
+	require.NoError(t, err)
+	defer releaseAll(recs)
+
+	checkInfoSchema(t, info, schema)
+
+	var rows int64
+	for _, r := range recs {
+		assert.True(t, r.Schema().Equal(schema), "record schema does not match stream schema")
+		rows += r.NumRows()
+	}
+	assert.NotZero(t, rows, "query returned no rows")
+}
+
+func (c *conformance) preparedStatement(t *testing.T) {
+	if c.caps.StatementQuery == "" || !c.caps.PreparedStatements {
+		t.Skip("prepared statements not supported")
+	}
+
+	ctx := context.Background()
+	direct, err := c.cl.Execute(ctx, c.caps.StatementQuery)
+	require.NoError(t, err)
+	schema, expected, err := ReadAll(ctx, c.cl, direct)
+	require.NoError(t, err)
+	defer releaseAll(expected)
+
+	prep, err := c.cl.Prepare(ctx, c.caps.StatementQuery)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, prep.Close(ctx))
+	}()
+
+	info, err := prep.Execute(ctx)
+	require.NoError(t, err)
+
+	prepSchema, actual, err := ReadAll(ctx, c.cl, info)
+	require.NoError(t, err)
+	defer releaseAll(actual)
+
+	checkInfoSchema(t, info, prepSchema)
+	if ds := prep.DatasetSchema(); ds != nil {
+		checkFields(t, ds, prepSchema)
+	}
+
+	require.Truef(t, schema.Equal(prepSchema),
+		"prepared statement schema does not match direct execution\nexpected: %s\nactual:   %s", schema, prepSchema)
+	AssertRecordSetsEqual(t, schema, expected, actual)
+}
+
+func (c *conformance) invalidQuery(t *testing.T) {
+	if c.caps.InvalidQuery == "" {
+		t.Skip("no invalid query provided")
+	}
+
+	ctx := context.Background()
+	info, err := c.cl.Execute(ctx, c.caps.InvalidQuery)
+	if err == nil {
+		var recs []arrow.Record
+		_, recs, err = ReadAll(ctx, c.cl, info)
+		releaseAll(recs)
+	}
+
+	require.Error(t, err, "invalid query was not rejected")
+	st, ok := status.FromError(err)
+	require.True(t, ok, "error is not a gRPC status: %s", err)
+	assert.NotEqual(t, codes.OK, st.Code())
+}
+
+func (c *conformance) metadata(enabled bool, expected *arrow.Schema, get func(context.Context) (*flight.FlightInfo, error)) func(*testing.T) {
+	return func(t *testing.T) {
+		if !enabled {
+			t.Skip("not supported")
+		}
+
+		ctx := context.Background()
+		info, err := get(ctx)
+		require.NoError(t, err)
+
+		schema, recs, err := ReadAll(ctx, c.cl, info)
+		require.NoError(t, err)
+		defer releaseAll(recs)
+
+		checkFields(t, expected, schema)
+		checkInfoSchema(t, info, schema)
+	}
+}
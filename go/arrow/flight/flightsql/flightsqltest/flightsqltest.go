@@ -0,0 +1,201 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flightsqltest provides utilities for testing Flight SQL server
+// implementations: starting a server with a connected client, reading and
+// comparing result sets, and a conformance suite exercising the common
+// parts of the protocol.
+package flightsqltest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// StartServer registers srv with a new Flight server listening on a
+// random local port and returns a client connected to it. Any provided
+// dial options are used in addition to insecure transport credentials.
+// The client is closed and the server shut down when the test finishes.
+func StartServer(t testing.TB, srv flightsql.Server, opts ...grpc.DialOption) *flightsql.Client {
+	t.Helper()
+
+	addr := Serve(t, srv)
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	cl, err := flightsql.NewClient(addr, nil, nil, opts...)
+	if err != nil {
+		t.Fatalf("flightsqltest: failed to connect client: %s", err)
+	}
+	t.Cleanup(func() { cl.Close() })
+	return cl
+}
+
+// Serve registers srv with a new Flight server listening on a random
+// local port and returns its address, for tests which manage their own
+// connections. The server is shut down when the test finishes.
+func Serve(t testing.TB, srv flightsql.Server) string {
+	t.Helper()
+
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	if err := s.Init("localhost:0"); err != nil {
+		t.Fatalf("flightsqltest: failed to start server: %s", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Serve()
+	}()
+	t.Cleanup(func() {
+		s.Shutdown()
+		<-done
+	})
+	return s.Addr().String()
+}
+
+// ReadAll retrieves every endpoint of info using cl and returns the
+// records that were read along with the schema of the stream. The caller
+// is responsible for releasing the returned records.
+func ReadAll(ctx context.Context, cl *flightsql.Client, info *flight.FlightInfo) (*arrow.Schema, []arrow.Record, error) {
+	var (
+		schema *arrow.Schema
+		out    []arrow.Record
+	)
+
+	release := func() {
+		for _, r := range out {
+			r.Release()
+		}
+	}
+
+	for _, ep := range info.Endpoint {
+		rdr, err := cl.DoGet(ctx, ep.Ticket)
+		if err != nil {
+			release()
+			return nil, nil, err
+		}
+
+		schema = rdr.Schema()
+		for rdr.Next() {
+			rec := rdr.Record()
+			rec.Retain()
+			out = append(out, rec)
+		}
+		err = rdr.Err()
+		rdr.Release()
+		if err != nil {
+			release()
+			return nil, nil, err
+		}
+	}
+	return schema, out, nil
+}
+
+// AssertRecordsEqual reports a test error if expected and actual are not
+// equal, including a unified diff of each mismatching column in the
+// failure message. It returns whether the records were equal.
+func AssertRecordsEqual(t testing.TB, expected, actual arrow.Record) bool {
+	t.Helper()
+
+	if array.RecordEqual(expected, actual) {
+		return true
+	}
+
+	if !expected.Schema().Equal(actual.Schema()) {
+		t.Errorf("records not equal: schema mismatch\nexpected: %s\nactual:   %s",
+			expected.Schema(), actual.Schema())
+		return false
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "records not equal (expected %d rows, got %d)", expected.NumRows(), actual.NumRows())
+	for i, f := range expected.Schema().Fields() {
+		exp, act := expected.Column(i), actual.Column(i)
+		if array.Equal(exp, act) {
+			continue
+		}
+
+		fmt.Fprintf(&msg, "\ncolumn %d (%s):\n", i, f.Name)
+		edits, err := array.Diff(exp, act)
+		if err != nil {
+			fmt.Fprintf(&msg, "expected: %s\nactual:   %s\n", exp, act)
+			continue
+		}
+		msg.WriteString(edits.UnifiedDiff(exp, act))
+	}
+	t.Error(msg.String())
+	return false
+}
+
+// AssertRecordSetsEqual compares two sets of records as if each set were
+// concatenated into a single record, so that the results are not
+// sensitive to how the rows were split into batches. Both sets must
+// share the same schema. It returns whether the sets were equal.
+func AssertRecordSetsEqual(t testing.TB, schema *arrow.Schema, expected, actual []arrow.Record) bool {
+	t.Helper()
+
+	exp, err := concatRecords(schema, expected)
+	if err != nil {
+		t.Errorf("failed to concatenate expected records: %s", err)
+		return false
+	}
+	defer exp.Release()
+
+	act, err := concatRecords(schema, actual)
+	if err != nil {
+		t.Errorf("failed to concatenate actual records: %s", err)
+		return false
+	}
+	defer act.Release()
+
+	return AssertRecordsEqual(t, exp, act)
+}
+
+func concatRecords(schema *arrow.Schema, recs []arrow.Record) (arrow.Record, error) {
+	tbl := array.NewTableFromRecords(schema, recs)
+	defer tbl.Release()
+
+	cols := make([]arrow.Array, 0, tbl.NumCols())
+	defer func() {
+		for _, c := range cols {
+			c.Release()
+		}
+	}()
+
+	for i := 0; i < int(tbl.NumCols()); i++ {
+		chunks := tbl.Column(i).Data().Chunks()
+		if len(chunks) == 0 {
+			cols = append(cols, array.MakeArrayOfNull(memory.DefaultAllocator, schema.Field(i).Type, 0))
+			continue
+		}
+		col, err := array.Concatenate(chunks, memory.DefaultAllocator)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return array.NewRecord(schema, cols, tbl.NumRows()), nil
+}
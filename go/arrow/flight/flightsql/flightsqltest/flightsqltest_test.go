@@ -0,0 +1,188 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsqltest_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/flightsqltest"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const memQuery = "SELECT id FROM t"
+
+var memSchema = arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+// memServer serves a single fixed query, split across two batches, both
+// as a statement and as a prepared statement.
+type memServer struct {
+	flightsql.BaseServer
+}
+
+func (s *memServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte(cmd.GetQuery()))
+	if err != nil {
+		return nil, err
+	}
+	return &flight.FlightInfo{
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: tkt}}},
+		FlightDescriptor: desc,
+		Schema:           flight.SerializeSchema(memSchema, memory.DefaultAllocator),
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+func (s *memServer) DoGetStatement(_ context.Context, cmd flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	return s.doGet(string(cmd.GetStatementHandle()))
+}
+
+func (s *memServer) CreatePreparedStatement(_ context.Context, req flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	return flightsql.ActionCreatePreparedStatementResult{
+		Handle:        []byte(req.GetQuery()),
+		DatasetSchema: memSchema,
+	}, nil
+}
+
+func (s *memServer) ClosePreparedStatement(context.Context, flightsql.ActionClosePreparedStatementRequest) error {
+	return nil
+}
+
+func (s *memServer) GetFlightInfoPreparedStatement(_ context.Context, _ flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return &flight.FlightInfo{
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: desc.Cmd}}},
+		FlightDescriptor: desc,
+		Schema:           flight.SerializeSchema(memSchema, memory.DefaultAllocator),
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+func (s *memServer) DoGetPreparedStatement(_ context.Context, cmd flightsql.PreparedStatementQuery) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	return s.doGet(string(cmd.GetPreparedStatementHandle()))
+}
+
+func (s *memServer) doGet(query string) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	if query != memQuery {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "unknown query: %s", query)
+	}
+
+	ch := make(chan flight.StreamChunk, 2)
+	for _, rows := range []string{`[{"id": 1}, {"id": 2}]`, `[{"id": 3}]`} {
+		rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, memSchema, strings.NewReader(rows))
+		if err != nil {
+			return nil, nil, err
+		}
+		ch <- flight.StreamChunk{Data: rec}
+	}
+	close(ch)
+	return memSchema, ch, nil
+}
+
+func TestRunConformance(t *testing.T) {
+	srv := &memServer{}
+	require.NoError(t, srv.RegisterSqlInfo(flightsql.SqlInfoFlightSqlServerName, "memory"))
+	srv.RegisterXdbcTypeInfo(flightsql.XdbcTypeInfoRow{
+		TypeName:    "BIGINT",
+		DataType:    flightsql.XdbcBigInt,
+		Nullable:    flightsql.NullabilityNullable,
+		Searchable:  flightsql.SearchableFull,
+		SqlDataType: flightsql.XdbcBigInt,
+	})
+	defer srv.Close()
+
+	flightsqltest.RunConformance(t, srv, flightsqltest.Capabilities{
+		StatementQuery:     memQuery,
+		PreparedStatements: true,
+		InvalidQuery:       "SELECT nothing",
+		SqlInfo:            true,
+		XdbcTypeInfo:       true,
+	})
+}
+
+func TestReadAll(t *testing.T) {
+	cl := flightsqltest.StartServer(t, &memServer{})
+
+	ctx := context.Background()
+	info, err := cl.Execute(ctx, memQuery)
+	require.NoError(t, err)
+
+	schema, recs, err := flightsqltest.ReadAll(ctx, cl, info)
+	require.NoError(t, err)
+	defer func() {
+		for _, r := range recs {
+			r.Release()
+		}
+	}()
+
+	assert.True(t, memSchema.Equal(schema))
+	require.Len(t, recs, 2)
+
+	expected, _, err := array.RecordFromJSON(memory.DefaultAllocator, memSchema,
+		strings.NewReader(`[{"id": 1}, {"id": 2}, {"id": 3}]`))
+	require.NoError(t, err)
+	defer expected.Release()
+
+	assert.True(t, flightsqltest.AssertRecordSetsEqual(t, memSchema, []arrow.Record{expected}, recs))
+}
+
+// recorder captures the errors reported by the assertion helpers.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Error(args ...interface{}) { r.errors = append(r.errors, fmt.Sprint(args...)) }
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertRecordsEqualDiff(t *testing.T) {
+	expected, _, err := array.RecordFromJSON(memory.DefaultAllocator, memSchema,
+		strings.NewReader(`[{"id": 1}, {"id": 2}, {"id": 3}]`))
+	require.NoError(t, err)
+	defer expected.Release()
+
+	actual, _, err := array.RecordFromJSON(memory.DefaultAllocator, memSchema,
+		strings.NewReader(`[{"id": 1}, {"id": 5}, {"id": 3}]`))
+	require.NoError(t, err)
+	defer actual.Release()
+
+	rec := &recorder{TB: t}
+	assert.False(t, flightsqltest.AssertRecordsEqual(rec, expected, actual))
+	require.Len(t, rec.errors, 1)
+	assert.Contains(t, rec.errors[0], "column 0 (id)")
+	assert.Contains(t, rec.errors[0], "@@ -1, +1 @@\n-2\n+5\n")
+
+	rec = &recorder{TB: t}
+	assert.True(t, flightsqltest.AssertRecordsEqual(rec, expected, expected))
+	assert.Empty(t, rec.errors)
+}
@@ -0,0 +1,49 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package flightsqltest_test
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/example"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/flightsqltest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteServerConformance(t *testing.T) {
+	db, err := example.CreateDB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	srv, err := example.NewSQLiteFlightSQLServer(db)
+	require.NoError(t, err)
+
+	flightsqltest.RunConformance(t, srv, flightsqltest.Capabilities{
+		StatementQuery:     "SELECT * FROM intTable",
+		PreparedStatements: true,
+		InvalidQuery:       "SELECT * FROM missingTable",
+		Catalogs:           true,
+		DBSchemas:          true,
+		Tables:             true,
+		TableTypes:         true,
+		SqlInfo:            true,
+		XdbcTypeInfo:       true,
+	})
+}
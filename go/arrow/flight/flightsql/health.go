@@ -0,0 +1,82 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+)
+
+// HealthStatusServing is the status reported by the HealthCheck action
+// when the server doesn't implement HealthCheckServer.
+const HealthStatusServing = "SERVING"
+
+// HealthCheckResult is the response to a HealthCheck action.
+type HealthCheckResult struct {
+	// Status is a short description of the state of the server,
+	// HealthStatusServing unless overridden by a HealthCheckServer.
+	Status string
+	// Uptime is how long ago the flight server was constructed, with
+	// millisecond precision.
+	Uptime time.Duration
+}
+
+// healthCheckJSON is the body of the result of a HealthCheck action.
+// There is no protobuf message for it in the Flight SQL protocol, so
+// it is sent as JSON to be easy to consume from probes.
+type healthCheckJSON struct {
+	Status   string `json:"status"`
+	UptimeMs int64  `json:"uptime_ms"`
+}
+
+func (r HealthCheckResult) marshal() ([]byte, error) {
+	return json.Marshal(healthCheckJSON{Status: r.Status, UptimeMs: r.Uptime.Milliseconds()})
+}
+
+func unmarshalHealthCheckResult(b []byte) (HealthCheckResult, error) {
+	var out healthCheckJSON
+	if err := json.Unmarshal(b, &out); err != nil {
+		return HealthCheckResult{}, fmt.Errorf("%w: invalid HealthCheck result: %s", arrow.ErrInvalid, err.Error())
+	}
+	return HealthCheckResult{Status: out.Status, Uptime: time.Duration(out.UptimeMs) * time.Millisecond}, nil
+}
+
+// HealthCheckServer is an optional interface which a Server can implement
+// to customize the response to the HealthCheck action, for instance to
+// verify that its backing database is reachable.
+//
+// The returned string is reported as the status of the server. Returning
+// an error fails the action with that error, so that probes treat the
+// server as unhealthy.
+type HealthCheckServer interface {
+	HealthCheck(context.Context) (string, error)
+}
+
+func (f *flightSqlServer) healthCheck(ctx context.Context) (HealthCheckResult, error) {
+	result := HealthCheckResult{Status: HealthStatusServing, Uptime: time.Since(f.started)}
+	if hc, ok := f.srv.(HealthCheckServer); ok {
+		var err error
+		if result.Status, err = hc.HealthCheck(ctx); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
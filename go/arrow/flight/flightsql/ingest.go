@@ -0,0 +1,332 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// The generated code predates CommandStatementIngest, so it is encoded
+// and decoded by hand following FlightSql.proto.
+const statementIngestTypeURL = "type.googleapis.com/arrow.flight.protocol.sql.CommandStatementIngest"
+
+// IngestTableNotExistOption is what the server does when the table to
+// ingest into doesn't exist.
+type IngestTableNotExistOption int32
+
+const (
+	// IngestTableNotExistUnspecified leaves it to the server, which
+	// should fail.
+	IngestTableNotExistUnspecified IngestTableNotExistOption = iota
+	// IngestTableNotExistCreate creates the table with the schema of the
+	// ingested records.
+	IngestTableNotExistCreate
+	// IngestTableNotExistFail fails the ingestion.
+	IngestTableNotExistFail
+)
+
+// IngestTableExistsOption is what the server does when the table to
+// ingest into already exists.
+type IngestTableExistsOption int32
+
+const (
+	// IngestTableExistsUnspecified leaves it to the server, which should
+	// fail.
+	IngestTableExistsUnspecified IngestTableExistsOption = iota
+	// IngestTableExistsFail fails the ingestion.
+	IngestTableExistsFail
+	// IngestTableExistsAppend appends the records to the table.
+	IngestTableExistsAppend
+	// IngestTableExistsReplace drops the table and creates it again with
+	// the schema of the ingested records.
+	IngestTableExistsReplace
+)
+
+// IngestTableAction is what a server does with the table to ingest into
+// before appending the records to it, see IngestOptions.TableAction.
+type IngestTableAction int8
+
+const (
+	// IngestTableCreate creates the table, which doesn't exist, with the
+	// schema of the ingested records.
+	IngestTableCreate IngestTableAction = iota
+	// IngestTableAppend appends the records to the existing table.
+	IngestTableAppend
+	// IngestTableReplace drops the existing table and creates it again
+	// with the schema of the ingested records.
+	IngestTableReplace
+)
+
+// IngestOptions describes the table records are ingested into with
+// Client.ExecuteIngest, as sent in a CommandStatementIngest.
+type IngestOptions struct {
+	// Table is the name of the table, which is required.
+	Table string
+	// Catalog and DBSchema qualify the name of the table, if not nil.
+	Catalog, DBSchema *string
+	// Temporary makes the server ingest into a temporary table.
+	Temporary bool
+	// IfNotExist and IfExists are what the server does depending on
+	// whether the table exists.
+	IfNotExist IngestTableNotExistOption
+	IfExists   IngestTableExistsOption
+	// TransactionID is the transaction to ingest in, if not empty.
+	TransactionID []byte
+	// Options are backend-specific options.
+	Options map[string]string
+}
+
+// TableAction returns what to do with the table to ingest into depending
+// on whether it exists, for the DoPutCommandStatementIngest handlers of a
+// Server. It fails with ALREADY_EXISTS or NOT_FOUND if the options don't
+// allow ingesting into an existing or missing table, as when they are
+// unspecified.
+func (o *IngestOptions) TableAction(exists bool) (IngestTableAction, error) {
+	switch {
+	case exists && o.IfExists == IngestTableExistsAppend:
+		return IngestTableAppend, nil
+	case exists && o.IfExists == IngestTableExistsReplace:
+		return IngestTableReplace, nil
+	case exists:
+		return 0, status.Errorf(codes.AlreadyExists, "table %s already exists", o.Table)
+	case o.IfNotExist == IngestTableNotExistCreate:
+		return IngestTableCreate, nil
+	default:
+		return 0, status.Errorf(codes.NotFound, "table %s does not exist", o.Table)
+	}
+}
+
+func (o *IngestOptions) marshal() []byte {
+	var tableDef []byte
+	if o.IfNotExist != IngestTableNotExistUnspecified {
+		tableDef = protowire.AppendTag(tableDef, 1, protowire.VarintType)
+		tableDef = protowire.AppendVarint(tableDef, uint64(o.IfNotExist))
+	}
+	if o.IfExists != IngestTableExistsUnspecified {
+		tableDef = protowire.AppendTag(tableDef, 2, protowire.VarintType)
+		tableDef = protowire.AppendVarint(tableDef, uint64(o.IfExists))
+	}
+
+	var b []byte
+	appendBytes := func(num protowire.Number, v []byte) {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, v)
+	}
+	appendBytes(1, tableDef)
+	if o.Table != "" {
+		appendBytes(2, []byte(o.Table))
+	}
+	if o.DBSchema != nil {
+		appendBytes(3, []byte(*o.DBSchema))
+	}
+	if o.Catalog != nil {
+		appendBytes(4, []byte(*o.Catalog))
+	}
+	if o.Temporary {
+		b = protowire.AppendTag(b, 5, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	if o.TransactionID != nil {
+		appendBytes(6, o.TransactionID)
+	}
+
+	keys := make([]string, 0, len(o.Options))
+	for k := range o.Options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		entry := protowire.AppendTag(nil, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, o.Options[k])
+		appendBytes(1000, entry)
+	}
+	return b
+}
+
+func unmarshalIngestOptions(b []byte) (opts IngestOptions, err error) {
+	varint := func(v []byte) uint64 {
+		n, _ := protowire.ConsumeVarint(v)
+		return n
+	}
+
+	err = rangeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return rangeFields(v, func(num protowire.Number, typ protowire.Type, v []byte) error {
+				switch {
+				case num == 1 && typ == protowire.VarintType:
+					opts.IfNotExist = IngestTableNotExistOption(varint(v))
+				case num == 2 && typ == protowire.VarintType:
+					opts.IfExists = IngestTableExistsOption(varint(v))
+				}
+				return nil
+			})
+		case num == 2 && typ == protowire.BytesType:
+			opts.Table = string(v)
+		case num == 3 && typ == protowire.BytesType:
+			s := string(v)
+			opts.DBSchema = &s
+		case num == 4 && typ == protowire.BytesType:
+			s := string(v)
+			opts.Catalog = &s
+		case num == 5 && typ == protowire.VarintType:
+			opts.Temporary = varint(v) != 0
+		case num == 6 && typ == protowire.BytesType:
+			opts.TransactionID = append([]byte{}, v...)
+		case num == 1000 && typ == protowire.BytesType:
+			var key, value string
+			err := rangeFields(v, func(num protowire.Number, typ protowire.Type, v []byte) error {
+				switch {
+				case num == 1 && typ == protowire.BytesType:
+					key = string(v)
+				case num == 2 && typ == protowire.BytesType:
+					value = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if opts.Options == nil {
+				opts.Options = make(map[string]string)
+			}
+			opts.Options[key] = value
+		}
+		return nil
+	})
+	if err == nil && opts.Table == "" {
+		err = errors.New("missing table name")
+	}
+	return
+}
+
+// StatementIngestServer is an optional interface which a Server can
+// implement to support the bulk ingestion of Client.ExecuteIngest.
+//
+// DoPutCommandStatementIngest ingests the records read from the reader
+// into the table described by the options and returns the number of rows
+// ingested. The schema of the records is read before it is called, so
+// that the table can be created or checked with the schema of the reader
+// before reading the records, see IngestOptions.TableAction.
+type StatementIngestServer interface {
+	DoPutCommandStatementIngest(context.Context, IngestOptions, flight.MessageReader) (int64, error)
+}
+
+// doPutStatementIngest handles a CommandStatementIngest, whose serialized
+// content is cmd.
+func (f *flightSqlServer) doPutStatementIngest(stream flight.FlightService_DoPutServer, cmd []byte, rdr flight.MessageReader) error {
+	srv, ok := f.srv.(StatementIngestServer)
+	if !ok {
+		return status.Error(codes.Unimplemented, "DoPutCommandStatementIngest not implemented")
+	}
+
+	opts, err := unmarshalIngestOptions(cmd)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid CommandStatementIngest: %s", err.Error())
+	}
+
+	// the schema is read before calling the handler, so that it can create
+	// or check the table before reading any record
+	if rdr.Schema() == nil {
+		return status.Errorf(codes.InvalidArgument, "missing schema of the records to ingest: %s", rdr.Err())
+	}
+
+	recordCount, err := srv.DoPutCommandStatementIngest(stream.Context(), opts, rdr)
+	if err != nil {
+		return err
+	}
+
+	return sendUpdateResult(stream, recordCount)
+}
+
+// ExecuteIngest ingests the records of rdr into the table described by
+// opts, returning the number of rows the server reports as ingested. The
+// records are streamed to the server as they are read from rdr, as fast
+// as the server accepts them.
+//
+// All the records are ingested with the schema of rdr, which the server
+// receives before them. A record with another schema fails the ingestion
+// with an error wrapping arrow.ErrInvalid, the records of another schema
+// being ingested with another call, such as with IngestTableExistsAppend
+// into a table the server evolves.
+//
+// If writing a record fails the error reports its index in rdr, starting
+// at 0. If ctx is canceled during the ingestion, the stream is canceled
+// and ctx.Err() is returned; whether the records sent so far were
+// ingested depends on the server.
+func (c *Client) ExecuteIngest(ctx context.Context, rdr array.RecordReader, opts IngestOptions, callOpts ...grpc.CallOption) (int64, error) {
+	cmd, err := proto.Marshal(&anypb.Any{TypeUrl: statementIngestTypeURL, Value: opts.marshal()})
+	if err != nil {
+		return 0, err
+	}
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: cmd}
+
+	// canceling the context of the stream on return ends it if the
+	// ingestion fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pstream, err := c.Client.DoPut(ctx, callOpts...)
+	if err != nil {
+		return 0, err
+	}
+
+	wr := flight.NewRecordWriter(pstream, ipc.WithSchema(rdr.Schema()))
+	wr.SetFlightDescriptor(desc)
+	for i := 0; rdr.Next(); i++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		rec := rdr.Record()
+		if !rec.Schema().Equal(rdr.Schema()) {
+			return 0, fmt.Errorf("%w: arrow/flightsql: schema of record %d for ingestion does not match the schema of the reader: expected %s, got %s",
+				arrow.ErrInvalid, i, rdr.Schema(), rec.Schema())
+		}
+		if err := wr.Write(rec); err != nil {
+			// Send only reports io.EOF if the server ended the stream,
+			// the reason is read by Recv
+			if errors.Is(err, io.EOF) {
+				if _, recvErr := pstream.Recv(); recvErr != nil && recvErr != io.EOF {
+					err = recvErr
+				}
+			}
+			return 0, fmt.Errorf("arrow/flightsql: failed to write record %d for ingestion: %w", i, err)
+		}
+	}
+	if err := rdr.Err(); err != nil {
+		return 0, err
+	}
+
+	return finishUpdate(pstream, wr)
+}
@@ -0,0 +1,191 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
+	"github.com/apache/arrow/go/v16/arrow/internal/flatbuf"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// IPCStreamServer is an optional interface which a Server can implement
+// to serve the results of statements from already serialized Arrow IPC
+// streams, such as those of a result cache, without decoding them into
+// records only to encode them again.
+//
+// The returned reader must contain an IPC stream whose schema message
+// matches the returned schema; its messages are copied to the client
+// as is. If it implements io.Closer it is closed once the stream has
+// been sent. Returning a nil reader and a nil error falls back to the
+// corresponding DoGet handler of the Server, for instance on a cache
+// miss.
+type IPCStreamServer interface {
+	DoGetStatementIPC(context.Context, StatementQueryTicket) (*arrow.Schema, io.Reader, error)
+	DoGetPreparedStatementIPC(context.Context, PreparedStatementQuery) (*arrow.Schema, io.Reader, error)
+}
+
+// doGetIPC serves cmd from an IPCStreamServer if the server implements
+// it, reporting whether the request was handled.
+func (f *flightSqlServer) doGetIPC(cmd proto.Message, stream flight.FlightService_DoGetServer) (bool, error) {
+	srv, ok := f.srv.(IPCStreamServer)
+	if !ok {
+		return false, nil
+	}
+
+	var (
+		sc  *arrow.Schema
+		rdr io.Reader
+		err error
+	)
+	switch cmd := cmd.(type) {
+	case *pb.TicketStatementQuery:
+		sc, rdr, err = srv.DoGetStatementIPC(stream.Context(), cmd)
+	case *pb.CommandPreparedStatementQuery:
+		sc, rdr, err = srv.DoGetPreparedStatementIPC(stream.Context(), cmd)
+	default:
+		return false, nil
+	}
+
+	if err != nil {
+		return true, err
+	}
+	if rdr == nil {
+		return false, nil
+	}
+	if c, ok := rdr.(io.Closer); ok {
+		defer c.Close()
+	}
+	return true, writeIPCStream(stream, sc, rdr)
+}
+
+// writeIPCStream sends the messages of the IPC stream read from r without
+// decoding them, after checking that its schema matches schema.
+func writeIPCStream(stream flight.FlightService_DoGetServer, schema *arrow.Schema, r io.Reader) error {
+	meta, body, err := readIPCMessage(r)
+	if err != nil {
+		if isStatusError(err) {
+			return err
+		}
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return status.Errorf(codes.Internal, "failed to read schema of IPC stream: %s", err.Error())
+	}
+
+	msg := ipc.NewMessage(memory.NewBufferBytes(meta), memory.NewBufferBytes(body))
+	defer msg.Release()
+	if msg.Type() != ipc.MessageSchema {
+		return status.Errorf(codes.Internal, "IPC stream must start with a schema message, got %s", msg.Type())
+	}
+	if _, err := ipc.NewReaderFromMessageReader(&singleMessageReader{msg: msg}, ipc.WithSchema(schema)); err != nil {
+		return status.Errorf(codes.Internal, "invalid schema for IPC stream: %s", err.Error())
+	}
+
+	for {
+		if err := stream.Send(&flight.FlightData{DataHeader: meta, DataBody: body}); err != nil {
+			return err
+		}
+
+		meta, body, err = readIPCMessage(r)
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case isStatusError(err):
+			return err
+		case err != nil:
+			return status.Errorf(codes.Internal, "failed to read IPC stream: %s", err.Error())
+		}
+	}
+}
+
+// isStatusError reports whether err has a gRPC status, such as the
+// errors of flight.ErrorWithDetails returned by the reader of a handler,
+// which are sent to the client as they are, with their details.
+func isStatusError(err error) bool {
+	_, ok := status.FromError(err)
+	return err != nil && ok
+}
+
+// ipcContinuation is the marker which precedes the length of each message
+// of an IPC stream since format version 0.15.
+const ipcContinuation = 0xFFFFFFFF
+
+// readIPCMessage reads the flatbuffer metadata and body of the next message
+// of an IPC stream. It returns io.EOF at the end of the stream.
+func readIPCMessage(r io.Reader) (meta, body []byte, err error) {
+	var buf [4]byte
+	if _, err = io.ReadFull(r, buf[:]); err != nil {
+		return nil, nil, err
+	}
+
+	length := binary.LittleEndian.Uint32(buf[:])
+	if length == ipcContinuation {
+		if _, err = io.ReadFull(r, buf[:]); err != nil {
+			return nil, nil, unexpectedEOF(err)
+		}
+		length = binary.LittleEndian.Uint32(buf[:])
+	}
+	if length == 0 {
+		return nil, nil, io.EOF
+	}
+
+	meta = make([]byte, length)
+	if _, err = io.ReadFull(r, meta); err != nil {
+		return nil, nil, unexpectedEOF(err)
+	}
+
+	body = make([]byte, flatbuf.GetRootAsMessage(meta, 0).BodyLength())
+	if _, err = io.ReadFull(r, body); err != nil {
+		return nil, nil, unexpectedEOF(err)
+	}
+	return meta, body, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// singleMessageReader is an ipc.MessageReader over a single message.
+type singleMessageReader struct {
+	msg *ipc.Message
+}
+
+func (r *singleMessageReader) Message() (*ipc.Message, error) {
+	if r.msg == nil {
+		return nil, io.EOF
+	}
+	msg := r.msg
+	r.msg = nil
+	return msg, nil
+}
+
+func (r *singleMessageReader) Retain()  {}
+func (r *singleMessageReader) Release() {}
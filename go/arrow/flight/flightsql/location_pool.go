@@ -0,0 +1,279 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultLocationPoolMaxIdle     = 4
+	defaultLocationPoolIdleTimeout = 5 * time.Minute
+)
+
+// ErrLocationPoolClosed is returned when connecting to a location through
+// a LocationPool which has been closed.
+var ErrLocationPoolClosed = errors.New("arrow/flightsql: location pool is closed")
+
+// LocationPoolOptions configures the connections made by a LocationPool.
+type LocationPoolOptions struct {
+	// DialOptions holds extra options for dialing locations, keyed by
+	// their scheme such as "grpc+tls". They are applied after the
+	// transport credentials chosen for the scheme, so they can replace
+	// them.
+	DialOptions map[string][]grpc.DialOption
+	// TLSConfig is used for grpc+tls locations. If nil, the system's
+	// root certificates are used.
+	TLSConfig *tls.Config
+	// MaxIdle is the number of connections kept open while no endpoint
+	// is being read from them. Defaults to 4 if 0; a negative value
+	// closes connections as soon as they are idle.
+	MaxIdle int
+	// IdleTimeout is how long an idle connection is kept open. Defaults
+	// to 5 minutes if 0; a negative value keeps them until the pool is
+	// closed or MaxIdle is exceeded.
+	IdleTimeout time.Duration
+	// Policy chooses the order in which ReadFlightInfo tries the
+	// Locations of each endpoint, such as flight.NewRoundRobinLocationPolicy
+	// to spread the load between replicas, unless the call is given its
+	// own with WithLocationPolicy. If nil, they are tried in the order
+	// they are listed.
+	Policy flight.LocationPolicy
+}
+
+// LocationPool caches the connections to the Locations of endpoints, so
+// that reading several endpoints from the same node, or several results
+// from the same cluster, dials each node only once. Connections are keyed
+// by the URI of the location and shared between concurrent readers.
+//
+// NewClient gives each Client a LocationPool with the default options,
+// which is closed by Client.Close.
+type LocationPool struct {
+	opts LocationPoolOptions
+
+	mu     sync.Mutex
+	conns  map[string]*pooledLocation
+	closed bool
+}
+
+// pooledLocation is a connection in a LocationPool, with the number of
+// streams reading from it.
+type pooledLocation struct {
+	uri       string
+	cl        flight.Client
+	refs      int
+	idleSince time.Time
+	timer     *time.Timer
+}
+
+// NewLocationPool returns an empty pool which dials locations with the
+// given options.
+func NewLocationPool(opts LocationPoolOptions) *LocationPool {
+	if opts.MaxIdle == 0 {
+		opts.MaxIdle = defaultLocationPoolMaxIdle
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = defaultLocationPoolIdleTimeout
+	}
+	return &LocationPool{opts: opts, conns: make(map[string]*pooledLocation)}
+}
+
+// Dial connects to location like DialLocation, using the pool's TLS
+// configuration and dial options. The connection is not cached.
+func (p *LocationPool) Dial(ctx context.Context, location *flight.Location) (flight.Client, error) {
+	return dialLocation(ctx, location, p.opts.TLSConfig, p.opts.DialOptions)
+}
+
+// NewLocationDialer returns a LocationDialer which connects to locations
+// like DialLocation without caching the connections, using tlsConfig for
+// grpc+tls locations and adding the dial options given for the scheme of
+// each location, as for a LocationPool. A nil tlsConfig uses the system's
+// root certificates.
+func NewLocationDialer(tlsConfig *tls.Config, opts map[string][]grpc.DialOption) LocationDialer {
+	return func(ctx context.Context, location *flight.Location) (flight.Client, error) {
+		return dialLocation(ctx, location, tlsConfig, opts)
+	}
+}
+
+// acquire returns a connection to location, dialing it if the pool has
+// none. release must be called once the connection is no longer used,
+// with healthy set to false if it failed so that it is not reused.
+func (p *LocationPool) acquire(ctx context.Context, location *flight.Location) (cl flight.Client, release func(healthy bool), err error) {
+	uri := location.GetUri()
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, nil, ErrLocationPoolClosed
+	}
+	if pc, ok := p.conns[uri]; ok {
+		p.use(pc)
+		p.mu.Unlock()
+		return pc.cl, p.releaser(pc), nil
+	}
+	p.mu.Unlock()
+
+	cl, err = p.Dial(ctx, location)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		cl.Close()
+		return nil, nil, ErrLocationPoolClosed
+	}
+	// another reader may have connected in the meantime
+	if pc, ok := p.conns[uri]; ok {
+		cl.Close()
+		p.use(pc)
+		return pc.cl, p.releaser(pc), nil
+	}
+
+	pc := &pooledLocation{uri: uri, cl: cl, refs: 1}
+	p.conns[uri] = pc
+	return cl, p.releaser(pc), nil
+}
+
+// use marks pc as in use by one more stream. p.mu must be held.
+func (p *LocationPool) use(pc *pooledLocation) {
+	pc.refs++
+	if pc.timer != nil {
+		pc.timer.Stop()
+		pc.timer = nil
+	}
+}
+
+func (p *LocationPool) releaser(pc *pooledLocation) func(bool) {
+	var once sync.Once
+	return func(healthy bool) {
+		once.Do(func() { p.release(pc, healthy) })
+	}
+}
+
+func (p *LocationPool) release(pc *pooledLocation, healthy bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pc.refs--
+	if !healthy && p.conns[pc.uri] == pc {
+		delete(p.conns, pc.uri)
+	}
+	if pc.refs > 0 {
+		return
+	}
+	if p.closed || p.conns[pc.uri] != pc {
+		pc.cl.Close()
+		return
+	}
+
+	pc.idleSince = time.Now()
+	if p.opts.IdleTimeout > 0 {
+		pc.timer = time.AfterFunc(p.opts.IdleTimeout, func() { p.expire(pc) })
+	}
+	p.trimIdle()
+}
+
+// expire closes pc if it is still idle.
+func (p *LocationPool) expire(pc *pooledLocation) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pc.refs == 0 && p.conns[pc.uri] == pc {
+		delete(p.conns, pc.uri)
+		pc.cl.Close()
+	}
+}
+
+// trimIdle closes the longest idle connections beyond MaxIdle. p.mu must
+// be held.
+func (p *LocationPool) trimIdle() {
+	var idle []*pooledLocation
+	for _, pc := range p.conns {
+		if pc.refs == 0 {
+			idle = append(idle, pc)
+		}
+	}
+
+	maxIdle := p.opts.MaxIdle
+	if maxIdle < 0 {
+		maxIdle = 0
+	}
+	if len(idle) <= maxIdle {
+		return
+	}
+
+	sort.Slice(idle, func(i, j int) bool { return idle[i].idleSince.Before(idle[j].idleSince) })
+	for _, pc := range idle[:len(idle)-maxIdle] {
+		if pc.timer != nil {
+			pc.timer.Stop()
+		}
+		delete(p.conns, pc.uri)
+		pc.cl.Close()
+	}
+}
+
+// Len returns the number of open connections in the pool, whether idle
+// or in use.
+func (p *LocationPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conns)
+}
+
+// Close closes the idle connections of the pool. Connections still in
+// use are closed once their streams are released, and no new
+// connections are made.
+func (p *LocationPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	var errs []error
+	for uri, pc := range p.conns {
+		delete(p.conns, uri)
+		if pc.refs > 0 {
+			continue
+		}
+		if pc.timer != nil {
+			pc.timer.Stop()
+		}
+		if err := pc.cl.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dialLocation connects to location with the transport credentials for
+// its scheme, see flight.DialLocation, followed by the options given for
+// it in opts.
+func dialLocation(ctx context.Context, location *flight.Location, tlsConfig *tls.Config, opts map[string][]grpc.DialOption) (flight.Client, error) {
+	scheme, _, _, _ := flight.ParseLocation(location.GetUri())
+	return flight.DialLocation(ctx, location, tlsConfig, opts[scheme]...)
+}
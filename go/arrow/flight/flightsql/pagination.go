@@ -0,0 +1,187 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Results too large for a server to hold can be served in pages. The last
+// record of each page but the last is sent with the app metadata returned
+// by ContinuationTokenMetadata, and the client asks for the next page by
+// sending the token back with the same query, as field 1000 of the
+// CommandStatementQuery. Servers which don't page their results never
+// send a token, so never receive one.
+const (
+	continuationTokenTypeURL = "type.googleapis.com/arrow.flight.protocol.sql.ContinuationToken"
+	continuationTokenField   = protowire.Number(1000)
+)
+
+// ContinuationTokenMetadata returns the app metadata to send with the last
+// record of a page of results from DoGetStatement, so that the client
+// requests the page after it by calling GetFlightInfo with token. Pages
+// without any rows must still send a record, with no rows, to carry it:
+//
+//	ch <- flightsql.Chunk(rec, flightsql.ContinuationTokenMetadata(token))
+func ContinuationTokenMetadata(token []byte) []byte {
+	data, err := proto.Marshal(&anypb.Any{TypeUrl: continuationTokenTypeURL, Value: token})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// ParseContinuationToken returns the continuation token in the app
+// metadata of a record, and false if it doesn't hold one.
+func ParseContinuationToken(appMetadata []byte) ([]byte, bool) {
+	if len(appMetadata) == 0 {
+		return nil, false
+	}
+	var container anypb.Any
+	if proto.Unmarshal(appMetadata, &container) != nil || container.GetTypeUrl() != continuationTokenTypeURL {
+		return nil, false
+	}
+	if container.Value == nil {
+		return []byte{}, true
+	}
+	return container.Value, true
+}
+
+// StatementQueryContinuationToken returns the continuation token sent by
+// a Cursor with a query, for GetFlightInfoStatement to return the page of
+// results following it. It returns nil for the first page.
+func StatementQueryContinuationToken(cmd StatementQuery) []byte {
+	msg, ok := cmd.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	var token []byte
+	_ = rangeFields(msg.ProtoReflect().GetUnknown(), func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num == continuationTokenField && typ == protowire.BytesType {
+			token = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return token
+}
+
+// Cursor reads the results of a query served in pages, requesting each
+// page from the server once the previous one has been read, see
+// ContinuationTokenMetadata. The results of servers which don't page
+// them are read as a single page.
+type Cursor struct {
+	c     *Client
+	ctx   context.Context
+	query string
+	opts  []grpc.CallOption
+
+	rdr   flight.MessageReader
+	token []byte
+	pages int
+	err   error
+}
+
+// ExecuteCursor executes the query and returns a cursor over its results,
+// having requested their first page. The pages are read with
+// ReadFlightInfo, which opts are also passed to.
+func (c *Client) ExecuteCursor(ctx context.Context, query string, opts ...grpc.CallOption) (*Cursor, error) {
+	cur := &Cursor{c: c, ctx: ctx, query: query, opts: opts}
+	if err := cur.openPage(nil); err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// openPage requests the page of results following token.
+func (cur *Cursor) openPage(token []byte) error {
+	cmd := &pb.CommandStatementQuery{Query: cur.query}
+	if token != nil {
+		unknown := protowire.AppendTag(nil, continuationTokenField, protowire.BytesType)
+		cmd.ProtoReflect().SetUnknown(protowire.AppendBytes(unknown, token))
+	}
+
+	info, err := flightInfoForCommand(cur.ctx, cur.c, cmd, cur.opts...)
+	if err != nil {
+		return err
+	}
+	rdr, err := cur.c.ReadFlightInfo(cur.ctx, info, cur.opts...)
+	if err != nil {
+		return err
+	}
+
+	cur.rdr, cur.token = rdr, nil
+	cur.pages++
+	return nil
+}
+
+// Next advances to the next record, requesting the next page once the
+// records of the current one have been read. It returns false once the
+// last page has been read or an error occurred, see Err.
+func (cur *Cursor) Next() bool {
+	for cur.rdr != nil {
+		if cur.rdr.Next() {
+			if token, ok := ParseContinuationToken(cur.rdr.LatestAppMetadata()); ok {
+				cur.token = token
+			}
+			return true
+		}
+
+		cur.err = cur.rdr.Err()
+		cur.rdr.Release()
+		cur.rdr = nil
+		if cur.err != nil || cur.token == nil {
+			return false
+		}
+		if cur.err = cur.openPage(cur.token); cur.err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// Record returns the current record, which is only valid until the next
+// call to Next.
+func (cur *Cursor) Record() arrow.Record {
+	if cur.rdr == nil {
+		return nil
+	}
+	return cur.rdr.Record()
+}
+
+// Pages returns the number of pages requested so far.
+func (cur *Cursor) Pages() int { return cur.pages }
+
+// Err returns the error which stopped Next, if any.
+func (cur *Cursor) Err() error { return cur.err }
+
+// Release releases the page being read, if any. It must be called if the
+// records of the cursor are not all read.
+func (cur *Cursor) Release() {
+	if cur.rdr != nil {
+		cur.rdr.Release()
+		cur.rdr = nil
+	}
+}
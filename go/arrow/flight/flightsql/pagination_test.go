@@ -0,0 +1,157 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const pagedResultPages = 3
+
+// pagedServer serves the ids 0 to 5 of the query "paged" in three pages
+// of two records each, the token of each page being the number of the
+// next one.
+type pagedServer struct {
+	flightsql.BaseServer
+
+	mx     sync.Mutex
+	tokens [][]byte
+}
+
+func (s *pagedServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	token := flightsql.StatementQueryContinuationToken(cmd)
+	s.mx.Lock()
+	s.tokens = append(s.tokens, token)
+	s.mx.Unlock()
+
+	page := 0
+	if token != nil {
+		page, _ = strconv.Atoi(string(token))
+	}
+	if cmd.GetQuery() != "paged" || page >= pagedResultPages {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown page %q of %q", token, cmd.GetQuery())
+	}
+
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte(strconv.Itoa(page)))
+	if err != nil {
+		return nil, err
+	}
+	return &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: tkt}}},
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+func (s *pagedServer) DoGetStatement(_ context.Context, tkt flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	page, err := strconv.Atoi(string(tkt.GetStatementHandle()))
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	ch := make(chan flight.StreamChunk, 2)
+	for i := 0; i < 2; i++ {
+		bldr := array.NewInt64Builder(memory.DefaultAllocator)
+		bldr.Append(int64(page*2 + i))
+		arr := bldr.NewArray()
+		rec := array.NewRecord(schema, []arrow.Array{arr}, 1)
+		arr.Release()
+		bldr.Release()
+
+		var md []byte
+		if i == 1 && page+1 < pagedResultPages {
+			md = flightsql.ContinuationTokenMetadata([]byte(strconv.Itoa(page + 1)))
+		}
+		ch <- flightsql.Chunk(rec, md)
+	}
+	close(ch)
+	return schema, ch, nil
+}
+
+func TestCursorPages(t *testing.T) {
+	srv := &pagedServer{}
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	cl.Alloc = mem
+
+	cur, err := cl.ExecuteCursor(context.Background(), "paged")
+	require.NoError(t, err)
+	defer cur.Release()
+	assert.Equal(t, 1, cur.Pages())
+
+	var ids []int64
+	for cur.Next() {
+		ids = append(ids, cur.Record().Column(0).(*array.Int64).Int64Values()...)
+	}
+	require.NoError(t, cur.Err())
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5}, ids)
+	assert.Equal(t, pagedResultPages, cur.Pages())
+	assert.False(t, cur.Next())
+	assert.Equal(t, [][]byte{nil, []byte("1"), []byte("2")}, srv.tokens)
+
+	// a failure to request the next page stops the cursor
+	cur, err = cl.ExecuteCursor(context.Background(), "paged")
+	require.NoError(t, err)
+	defer cur.Release()
+	require.True(t, cur.Next())
+	s.Shutdown()
+	for cur.Next() {
+	}
+	assert.Error(t, cur.Err())
+}
+
+func TestParseContinuationToken(t *testing.T) {
+	token, ok := flightsql.ParseContinuationToken(flightsql.ContinuationTokenMetadata([]byte("next")))
+	assert.True(t, ok)
+	assert.Equal(t, []byte("next"), token)
+
+	token, ok = flightsql.ParseContinuationToken(flightsql.ContinuationTokenMetadata(nil))
+	assert.True(t, ok)
+	assert.NotNil(t, token)
+
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte("handle"))
+	require.NoError(t, err)
+	for _, md := range [][]byte{nil, []byte("app metadata"), tkt} {
+		_, ok := flightsql.ParseContinuationToken(md)
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,157 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"google.golang.org/grpc"
+)
+
+const (
+	// defaultPollInterval is the time waited between two polls of a
+	// query unless WithPollInterval is given
+	defaultPollInterval = 100 * time.Millisecond
+	// pollCancelTimeout bounds the time spent canceling a query whose
+	// polling was interrupted
+	pollCancelTimeout = 5 * time.Second
+)
+
+// pollConfig holds the options of ExecutePollUntilComplete.
+type pollConfig struct {
+	interval  time.Duration
+	progress  func(float64)
+	endpoints chan<- *flight.FlightEndpoint
+}
+
+// pollOption is a grpc.CallOption which configures
+// ExecutePollUntilComplete. gRPC itself ignores it.
+type pollOption struct {
+	grpc.EmptyCallOption
+	apply func(*pollConfig)
+}
+
+// WithPollInterval sets the time ExecutePollUntilComplete waits between
+// two polls of a query, 100ms by default.
+func WithPollInterval(d time.Duration) grpc.CallOption {
+	return pollOption{apply: func(cfg *pollConfig) { cfg.interval = d }}
+}
+
+// WithPollProgress makes ExecutePollUntilComplete call fn with the
+// fraction of the query completed, between 0 and 1, after each poll for
+// which the server reported its progress, and with 1 once it completed.
+func WithPollProgress(fn func(progress float64)) grpc.CallOption {
+	return pollOption{apply: func(cfg *pollConfig) { cfg.progress = fn }}
+}
+
+// WithPollEndpoints makes ExecutePollUntilComplete send each endpoint of
+// the result on ch as soon as a poll returns it, so that it can be read
+// before the query completes. ch is closed when ExecutePollUntilComplete
+// returns; as polling waits for each endpoint to be received, ch should
+// be read concurrently.
+func WithPollEndpoints(ch chan<- *flight.FlightEndpoint) grpc.CallOption {
+	return pollOption{apply: func(cfg *pollConfig) { cfg.endpoints = ch }}
+}
+
+// ExecutePollUntilComplete executes the query with ExecutePoll, polling
+// it again with the returned retry descriptor until the server reports
+// it complete, and returns the FlightInfo of the completed query. Polls
+// are spaced by the interval of WithPollInterval, but made early enough
+// not to miss the expiration time of the retry descriptor.
+//
+// If ctx is canceled before the query completes, the query is canceled
+// with CancelFlightInfo on a best-effort basis and ctx.Err() is
+// returned.
+func (c *Client) ExecutePollUntilComplete(ctx context.Context, query string, opts ...grpc.CallOption) (*flight.FlightInfo, error) {
+	cfg := pollConfig{interval: defaultPollInterval}
+	for _, o := range opts {
+		if o, ok := o.(pollOption); ok {
+			o.apply(&cfg)
+		}
+	}
+	if cfg.endpoints != nil {
+		defer close(cfg.endpoints)
+	}
+
+	var (
+		retry *flight.FlightDescriptor
+		last  *flight.FlightInfo
+		sent  int
+	)
+	for {
+		poll, err := c.ExecutePoll(ctx, query, retry, opts...)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.cancelPoll(last, opts)
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if poll.GetInfo() != nil {
+			last = poll.GetInfo()
+		}
+
+		for cfg.endpoints != nil && sent < len(last.GetEndpoint()) {
+			select {
+			case cfg.endpoints <- last.Endpoint[sent]:
+				sent++
+			case <-ctx.Done():
+				c.cancelPoll(last, opts)
+				return nil, ctx.Err()
+			}
+		}
+
+		if poll.GetFlightDescriptor() == nil {
+			if cfg.progress != nil {
+				cfg.progress(1)
+			}
+			return last, nil
+		}
+		if cfg.progress != nil && poll.Progress != nil {
+			cfg.progress(poll.GetProgress())
+		}
+		retry = poll.GetFlightDescriptor()
+
+		wait := cfg.interval
+		if exp := poll.GetExpirationTime(); exp != nil {
+			if untilExp := time.Until(exp.AsTime()); untilExp < wait {
+				wait = untilExp
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			c.cancelPoll(last, opts)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// cancelPoll cancels the query described by info, if the server returned
+// one, ignoring failures.
+func (c *Client) cancelPoll(info *flight.FlightInfo, opts []grpc.CallOption) {
+	if info == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pollCancelTimeout)
+	defer cancel()
+	_, _ = c.CancelFlightInfo(ctx, &flight.CancelFlightInfoRequest{Info: info}, opts...)
+}
@@ -0,0 +1,143 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/flightsqltest"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const pollsToComplete = 3
+
+// pollServer completes the query "done" on its third poll, adding an
+// endpoint on each poll. The query "never" never completes.
+type pollServer struct {
+	flightsql.BaseServer
+
+	mx       sync.Mutex
+	polls    int
+	canceled []*flight.FlightInfo
+}
+
+func (s *pollServer) PollFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.PollInfo, error) {
+	s.mx.Lock()
+	s.polls++
+	polls := s.polls
+	s.mx.Unlock()
+
+	info := &flight.FlightInfo{FlightDescriptor: desc, TotalRecords: -1, TotalBytes: -1}
+	for i := 0; i < polls; i++ {
+		info.Endpoint = append(info.Endpoint, &flight.FlightEndpoint{
+			Ticket: &flight.Ticket{Ticket: []byte(strconv.Itoa(i))}})
+	}
+
+	if cmd.GetQuery() == "done" && polls == pollsToComplete {
+		return &flight.PollInfo{Info: info, Progress: proto.Float64(1)}, nil
+	}
+	return &flight.PollInfo{
+		Info:             info,
+		FlightDescriptor: desc,
+		Progress:         proto.Float64(float64(polls) / pollsToComplete),
+		ExpirationTime:   timestamppb.New(time.Now().Add(time.Minute)),
+	}, nil
+}
+
+func (s *pollServer) CancelFlightInfo(_ context.Context, req *flight.CancelFlightInfoRequest) (flight.CancelFlightInfoResult, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.canceled = append(s.canceled, req.GetInfo())
+	return flight.CancelFlightInfoResult{Status: flight.CancelStatusCancelled}, nil
+}
+
+type FlightSqlPollSuite struct {
+	suite.Suite
+
+	srv *pollServer
+	cl  *flightsql.Client
+}
+
+func (s *FlightSqlPollSuite) SetupTest() {
+	s.srv = &pollServer{}
+	s.cl = flightsqltest.StartServer(s.T(), s.srv)
+}
+
+func (s *FlightSqlPollSuite) TestExecutePollUntilComplete() {
+	var progress []float64
+	ch := make(chan *flight.FlightEndpoint)
+	var (
+		wg      sync.WaitGroup
+		tickets []string
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ep := range ch {
+			tickets = append(tickets, string(ep.GetTicket().GetTicket()))
+		}
+	}()
+
+	info, err := s.cl.ExecutePollUntilComplete(context.Background(), "done",
+		flightsql.WithPollInterval(time.Millisecond),
+		flightsql.WithPollProgress(func(p float64) { progress = append(progress, p) }),
+		flightsql.WithPollEndpoints(ch))
+	s.Require().NoError(err)
+	wg.Wait()
+
+	s.Equal(pollsToComplete, s.srv.polls)
+	s.Equal([]float64{1.0 / 3, 2.0 / 3, 1}, progress)
+	s.Equal([]string{"0", "1", "2"}, tickets)
+	s.Require().Len(info.GetEndpoint(), pollsToComplete)
+	for i, ep := range info.GetEndpoint() {
+		s.Equal(strconv.Itoa(i), string(ep.GetTicket().GetTicket()))
+	}
+	s.Empty(s.srv.canceled)
+}
+
+func (s *FlightSqlPollSuite) TestExecutePollUntilCompleteCancel() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int
+	_, err := s.cl.ExecutePollUntilComplete(ctx, "never",
+		flightsql.WithPollInterval(time.Millisecond),
+		flightsql.WithPollProgress(func(float64) {
+			if calls++; calls == 2 {
+				cancel()
+			}
+		}))
+	s.ErrorIs(err, context.Canceled)
+	s.Equal(2, calls)
+
+	s.srv.mx.Lock()
+	defer s.srv.mx.Unlock()
+	s.Require().Len(s.srv.canceled, 1)
+	s.Len(s.srv.canceled[0].GetEndpoint(), 2)
+}
+
+func TestPoll(t *testing.T) {
+	suite.Run(t, new(FlightSqlPollSuite))
+}
@@ -0,0 +1,196 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// preparedStatementHandleLen is the length of the handles generated by
+// PreparedStatementCache.NewHandle.
+const preparedStatementHandleLen = 16
+
+// PreparedStatementCache maps the handles of prepared statements to the
+// state a server keeps for them, removing the entries which haven't been
+// used for longer than their time to live. It is safe for concurrent use.
+//
+// A BaseServer given a cache with WithPreparedStatementCache removes
+// entries in ClosePreparedStatement; see BaseServer.PreparedStatements.
+type PreparedStatementCache struct {
+	onRemove func(handle []byte, state interface{})
+
+	mu      sync.Mutex
+	entries map[string]*preparedStatementEntry
+	closed  bool
+}
+
+type preparedStatementEntry struct {
+	state    interface{}
+	ttl      time.Duration
+	deadline time.Time
+	timer    *time.Timer
+}
+
+// NewPreparedStatementCache returns an empty cache. If onRemove is not
+// nil, it is called with each entry removed from the cache, whether it
+// expired, was deleted or the cache was closed, so that the resources of
+// the statement can be released.
+func NewPreparedStatementCache(onRemove func(handle []byte, state interface{})) *PreparedStatementCache {
+	return &PreparedStatementCache{
+		onRemove: onRemove,
+		entries:  make(map[string]*preparedStatementEntry),
+	}
+}
+
+// Put stores state under handle, replacing any previous entry, to be
+// removed once it hasn't been retrieved with Get for ttl. A ttl of 0 or
+// less keeps the entry until it is deleted.
+func (c *PreparedStatementCache) Put(handle []byte, state interface{}, ttl time.Duration) {
+	key := string(handle)
+	entry := &preparedStatementEntry{state: state, ttl: ttl}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		c.removed(key, entry)
+		return
+	}
+	old := c.entries[key]
+	if old != nil && old.timer != nil {
+		old.timer.Stop()
+	}
+	c.entries[key] = entry
+	if ttl > 0 {
+		entry.deadline = time.Now().Add(ttl)
+		entry.timer = time.AfterFunc(ttl, func() { c.expire(key, entry) })
+	}
+	c.mu.Unlock()
+
+	if old != nil {
+		c.removed(key, old)
+	}
+}
+
+// NewHandle stores state under a new random handle, which is returned,
+// as with Put.
+func (c *PreparedStatementCache) NewHandle(state interface{}, ttl time.Duration) ([]byte, error) {
+	handle := make([]byte, preparedStatementHandleLen)
+	if _, err := rand.Read(handle); err != nil {
+		return nil, err
+	}
+	c.Put(handle, state, ttl)
+	return handle, nil
+}
+
+// Get returns the state stored under handle, restarting its time to
+// live.
+func (c *PreparedStatementCache) Get(handle []byte) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[string(handle)]
+	if !ok {
+		return nil, false
+	}
+	if entry.timer != nil {
+		entry.deadline = time.Now().Add(entry.ttl)
+		entry.timer.Reset(entry.ttl)
+	}
+	return entry.state, true
+}
+
+// Lookup is like Get, but returns a NotFound error for handles which are
+// unknown or have expired, for returning from handlers directly.
+func (c *PreparedStatementCache) Lookup(handle []byte) (interface{}, error) {
+	state, ok := c.Get(handle)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "prepared statement %x not found or expired", handle)
+	}
+	return state, nil
+}
+
+// Delete removes the entry stored under handle, reporting whether there
+// was one.
+func (c *PreparedStatementCache) Delete(handle []byte) bool {
+	key := string(handle)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		delete(c.entries, key)
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+	}
+	c.mu.Unlock()
+
+	if ok {
+		c.removed(key, entry)
+	}
+	return ok
+}
+
+// Len returns the number of entries in the cache.
+func (c *PreparedStatementCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Close removes every entry from the cache. Entries put afterwards are
+// removed immediately.
+func (c *PreparedStatementCache) Close() {
+	c.mu.Lock()
+	entries := c.entries
+	c.entries = make(map[string]*preparedStatementEntry)
+	c.closed = true
+	for _, entry := range entries {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+	}
+	c.mu.Unlock()
+
+	for key, entry := range entries {
+		c.removed(key, entry)
+	}
+}
+
+// expire removes entry if it is still the one stored under key. A Get
+// racing with the timer may have restarted it, in which case the entry
+// is kept until the timer fires again.
+func (c *PreparedStatementCache) expire(key string, entry *preparedStatementEntry) {
+	c.mu.Lock()
+	if c.entries[key] != entry || time.Now().Before(entry.deadline) {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.entries, key)
+	c.mu.Unlock()
+
+	c.removed(key, entry)
+}
+
+func (c *PreparedStatementCache) removed(key string, entry *preparedStatementEntry) {
+	if c.onRemove != nil {
+		c.onRemove([]byte(key), entry.state)
+	}
+}
@@ -0,0 +1,178 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// removals records the entries removed from a PreparedStatementCache.
+type removals struct {
+	mx      sync.Mutex
+	handles []string
+}
+
+func (r *removals) record(handle []byte, _ interface{}) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.handles = append(r.handles, string(handle))
+}
+
+func (r *removals) get() []string {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return append([]string(nil), r.handles...)
+}
+
+func TestPreparedStatementCache(t *testing.T) {
+	var removed removals
+	cache := flightsql.NewPreparedStatementCache(removed.record)
+
+	cache.Put([]byte("a"), "state a", 0)
+	state, ok := cache.Get([]byte("a"))
+	assert.True(t, ok)
+	assert.Equal(t, "state a", state)
+
+	_, ok = cache.Get([]byte("missing"))
+	assert.False(t, ok)
+	_, err := cache.Lookup([]byte("missing"))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	handle, err := cache.NewHandle("state b", 0)
+	require.NoError(t, err)
+	assert.Len(t, handle, 16)
+	state, err = cache.Lookup(handle)
+	require.NoError(t, err)
+	assert.Equal(t, "state b", state)
+
+	cache.Put([]byte("a"), "state a2", 0)
+	state, _ = cache.Get([]byte("a"))
+	assert.Equal(t, "state a2", state)
+	assert.Equal(t, []string{"a"}, removed.get(), "replaced entries are removed")
+
+	assert.True(t, cache.Delete([]byte("a")))
+	assert.False(t, cache.Delete([]byte("a")))
+	assert.Equal(t, 1, cache.Len())
+
+	cache.Close()
+	assert.Zero(t, cache.Len())
+	assert.Equal(t, []string{"a", "a", string(handle)}, removed.get())
+}
+
+func TestPreparedStatementCacheExpiry(t *testing.T) {
+	var removed removals
+	cache := flightsql.NewPreparedStatementCache(removed.record)
+	defer cache.Close()
+
+	cache.Put([]byte("short"), 1, 20*time.Millisecond)
+	cache.Put([]byte("long"), 2, time.Hour)
+
+	// using an entry keeps it alive
+	for i := 0; i < 5; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, ok := cache.Get([]byte("short"))
+		require.True(t, ok)
+	}
+
+	assert.Eventually(t, func() bool { return cache.Len() == 1 }, time.Second, 5*time.Millisecond)
+	_, ok := cache.Get([]byte("short"))
+	assert.False(t, ok)
+	_, ok = cache.Get([]byte("long"))
+	assert.True(t, ok)
+	assert.Equal(t, []string{"short"}, removed.get())
+}
+
+func TestPreparedStatementCacheConcurrent(t *testing.T) {
+	cache := flightsql.NewPreparedStatementCache(nil)
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				handle := []byte(fmt.Sprintf("%d-%d", i, j%10))
+				cache.Put(handle, j, time.Millisecond)
+				cache.Get(handle)
+				if j%3 == 0 {
+					cache.Delete(handle)
+				}
+				cache.Len()
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Eventually(t, func() bool { return cache.Len() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+// cachingServer keeps its prepared statements in the BaseServer's cache.
+type cachingServer struct {
+	flightsql.BaseServer
+}
+
+func (s *cachingServer) CreatePreparedStatement(_ context.Context, req flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	handle, err := s.PreparedStatements().NewHandle(req.GetQuery(), time.Minute)
+	return flightsql.ActionCreatePreparedStatementResult{Handle: handle}, err
+}
+
+func (s *cachingServer) GetFlightInfoPreparedStatement(_ context.Context, cmd flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	if _, err := s.PreparedStatements().Lookup(cmd.GetPreparedStatementHandle()); err != nil {
+		return nil, err
+	}
+	return &flight.FlightInfo{FlightDescriptor: desc, TotalRecords: -1, TotalBytes: -1}, nil
+}
+
+func TestBaseServerPreparedStatementCache(t *testing.T) {
+	srv := &cachingServer{BaseServer: flightsql.NewBaseServer(
+		flightsql.WithPreparedStatementCache(flightsql.NewPreparedStatementCache(nil)))}
+	defer srv.Close()
+
+	server := flight.NewServerWithMiddleware(nil)
+	server.RegisterFlightService(flightsql.NewFlightServer(srv))
+	require.NoError(t, server.Init("localhost:0"))
+	go server.Serve()
+	defer server.Shutdown()
+
+	cl, err := flightsql.NewClient(server.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	stmt, err := cl.Prepare(ctx, "SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, srv.PreparedStatements().Len())
+
+	_, err = stmt.Execute(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, stmt.Close(ctx))
+	assert.Zero(t, srv.PreparedStatements().Len())
+	_, err = srv.PreparedStatements().Lookup(stmt.Handle())
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
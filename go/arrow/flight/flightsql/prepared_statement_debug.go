@@ -0,0 +1,40 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build debug
+
+package flightsql
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/apache/arrow/go/v16/arrow/internal/debug"
+)
+
+// trackPreparedStatement logs the prepared statements which are garbage
+// collected without being closed, along with where they were created.
+func trackPreparedStatement(p *PreparedStatement) {
+	buf := make([]byte, 4096)
+	stack := buf[:runtime.Stack(buf, false)]
+
+	runtime.SetFinalizer(p, func(p *PreparedStatement) {
+		if !p.life.isClosed() {
+			debug.Log(fmt.Sprintf("arrow/flightsql: prepared statement %q was not closed, created at:\n%s",
+				p.life.currentHandle(), stack))
+		}
+	})
+}
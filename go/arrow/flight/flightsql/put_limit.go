@@ -0,0 +1,150 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"io"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WithMaxPutBytes limits the records uploaded by a single DoPut call,
+// such as for ingestion or as the parameters of a prepared statement, to
+// n bytes in total, counting the buffers of the records as decoded. Once
+// a record takes the upload past n, the reader given to the handler
+// stops with an error and the call fails with RESOURCE_EXHAUSTED,
+// whatever the handler returns, so that a client can't make the server
+// buffer unbounded data. A value of 0 or less doesn't limit uploads,
+// which is the default.
+//
+// The size of each message remains limited by the gRPC server, see
+// grpc.MaxRecvMsgSize.
+func WithMaxPutBytes(n int64) FlightServerOption {
+	return func(f *flightSqlServer) { f.maxPutBytes = n }
+}
+
+// limitedReader wraps the record stream of a DoPut call, stopping with
+// an error once the records read from it exceed a number of bytes.
+type limitedReader struct {
+	flight.MessageReader
+
+	refCount int64
+	max      int64
+	total    int64
+	err      error
+}
+
+func newLimitedReader(rdr flight.MessageReader, max int64) *limitedReader {
+	rdr.Retain()
+	return &limitedReader{MessageReader: rdr, refCount: 1, max: max}
+}
+
+func (r *limitedReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+func (r *limitedReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) == 0 {
+		r.MessageReader.Release()
+	}
+}
+
+func (r *limitedReader) Next() bool {
+	if r.err != nil || !r.MessageReader.Next() {
+		return false
+	}
+
+	r.total += util.TotalRecordSize(r.MessageReader.Record())
+	if r.total > r.max {
+		r.err = status.Errorf(codes.ResourceExhausted, "uploaded records exceed the limit of %d bytes", r.max)
+		return false
+	}
+	return true
+}
+
+func (r *limitedReader) Record() arrow.Record {
+	if r.err != nil {
+		return nil
+	}
+	return r.MessageReader.Record()
+}
+
+func (r *limitedReader) Read() (arrow.Record, error) {
+	if !r.Next() {
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return r.Record(), nil
+}
+
+// NextMessage returns the next message of the stream, see
+// flight.Reader.NextMessage, counting its record against the limit. The
+// reader wraps the flight.Reader of the DoPut stream, which is a
+// flight.MessageSource.
+func (r *limitedReader) NextMessage() (flight.Message, error) {
+	if r.err != nil {
+		return flight.Message{}, r.err
+	}
+	msg, err := r.MessageReader.(flight.MessageSource).NextMessage()
+	if err != nil || msg.Record == nil {
+		return msg, err
+	}
+
+	r.total += util.TotalRecordSize(msg.Record)
+	if r.total > r.max {
+		r.err = status.Errorf(codes.ResourceExhausted, "uploaded records exceed the limit of %d bytes", r.max)
+		return flight.Message{}, r.err
+	}
+	return msg, nil
+}
+
+func (r *limitedReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.MessageReader.Err()
+}
+
+func (r *limitedReader) Chunk() flight.StreamChunk {
+	chunk := r.MessageReader.Chunk()
+	if r.err != nil {
+		chunk.Data, chunk.Err = nil, r.err
+	}
+	return chunk
+}
+
+// limitedPutStream refuses to send the results of a DoPut call once its
+// limitedReader exceeded its limit, so that the client doesn't take the
+// results of a handler which ignored the error for a success.
+type limitedPutStream struct {
+	flight.FlightService_DoPutServer
+	rdr *limitedReader
+}
+
+func (s *limitedPutStream) Send(res *flight.PutResult) error {
+	if s.rdr.err != nil {
+		return s.rdr.err
+	}
+	return s.FlightService_DoPutServer.Send(res)
+}
@@ -0,0 +1,157 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// UpdatedHandleServer is an optional interface which a Server can
+// implement to return a new handle for a prepared statement once its
+// parameters are bound, for instance when a stateless server encodes the
+// bound parameters into the handle or when the bound values change the
+// dataset schema.
+//
+// When implemented, DoPutPreparedStatementQueryWithHandle is called in
+// place of DoPutPreparedStatementQuery. A non-nil handle is sent to the
+// client as a DoPutPreparedStatementResult and the client uses it in place
+// of the previous handle for all subsequent requests. Returning nil keeps
+// the existing handle.
+//
+// DoPutPreparedStatementResult only carries the handle, so an updated
+// dataset schema is not sent with it: clients get the schema of the new
+// handle with GetSchema, see PreparedStatement.RefreshDatasetSchema.
+type UpdatedHandleServer interface {
+	DoPutPreparedStatementQueryWithHandle(context.Context, PreparedStatementQuery, flight.MessageReader, flight.MetadataWriter) ([]byte, error)
+}
+
+// The generated protobuf code predates DoPutPreparedStatementResult, so
+// it is encoded by hand here. Its only field is:
+//
+//	optional bytes prepared_statement_handle = 1;
+const preparedStatementResultHandleField protowire.Number = 1
+
+// marshalPreparedStatementResult encodes a DoPutPreparedStatementResult
+// message containing the provided handle.
+func marshalPreparedStatementResult(handle []byte) []byte {
+	b := protowire.AppendTag(nil, preparedStatementResultHandleField, protowire.BytesType)
+	return protowire.AppendBytes(b, handle)
+}
+
+// unmarshalPreparedStatementResult decodes a DoPutPreparedStatementResult
+// message, returning the updated handle it contains. ok is false if the
+// bytes are not a DoPutPreparedStatementResult with a handle set, such as
+// app metadata written by the server for other purposes. An error is
+// returned if the handle is present but malformed.
+func unmarshalPreparedStatementResult(b []byte) (handle []byte, ok bool, err error) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || num != preparedStatementResultHandleField || typ != protowire.BytesType {
+			return nil, false, nil
+		}
+		b = b[n:]
+
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, false, fmt.Errorf("%w: arrow/flightsql: malformed handle in DoPutPreparedStatementResult: %s",
+				arrow.ErrInvalid, protowire.ParseError(n))
+		}
+		handle, ok = append([]byte(nil), v...), true
+		b = b[n:]
+	}
+	return handle, ok, nil
+}
+
+// DoPutResult is the result of an update as sent by a server in a
+// DoPutUpdateResult.
+type DoPutResult struct {
+	// RecordCount is the number of affected rows, or UpdateResultUnknown
+	// if the server could not determine it. It is never any other negative
+	// value.
+	RecordCount int64
+}
+
+// RowsAffected returns the number of affected rows, and false if the
+// server could not determine it, see AffectedRows.
+func (r DoPutResult) RowsAffected() (int64, bool) {
+	return AffectedRows(r.RecordCount)
+}
+
+// UnmarshalDoPutResult decodes the app metadata of the PutResult a server
+// sends in response to an update, such as a CommandStatementUpdate or
+// CommandPreparedStatementUpdate. Negative counts are all read as
+// UpdateResultUnknown.
+func UnmarshalDoPutResult(appMetadata []byte) (DoPutResult, error) {
+	var result pb.DoPutUpdateResult
+	if err := proto.Unmarshal(appMetadata, &result); err != nil {
+		return DoPutResult{}, fmt.Errorf("%w: arrow/flightsql: app metadata is not a valid DoPutUpdateResult", arrow.ErrInvalid)
+	}
+	if result.GetRecordCount() < 0 {
+		return DoPutResult{RecordCount: UpdateResultUnknown}, nil
+	}
+	return DoPutResult{RecordCount: result.GetRecordCount()}, nil
+}
+
+// UnmarshalDoPutUpdateResult decodes the app metadata of the PutResult a
+// server sends in response to an update, such as a CommandStatementUpdate
+// or CommandPreparedStatementUpdate, returning the number of affected rows.
+// The count is UpdateResultUnknown if the server could not determine it,
+// see AffectedRows.
+func UnmarshalDoPutUpdateResult(appMetadata []byte) (int64, error) {
+	result, err := UnmarshalDoPutResult(appMetadata)
+	return result.RecordCount, err
+}
+
+// sendUpdateResult sends the number of rows affected by an update as
+// returned by a handler, any negative count being sent as
+// UpdateResultUnknown.
+func sendUpdateResult(stream flight.FlightService_DoPutServer, recordCount int64) error {
+	if recordCount < 0 {
+		recordCount = UpdateResultUnknown
+	}
+
+	out := &flight.PutResult{}
+	var err error
+	if out.AppMetadata, err = proto.Marshal(&pb.DoPutUpdateResult{RecordCount: recordCount}); err != nil {
+		return status.Errorf(codes.Internal, "failed to marshal PutResult: %s", err.Error())
+	}
+	return stream.Send(out)
+}
+
+// UnmarshalPreparedStatementHandle decodes the app metadata of the
+// PutResult a server may send after parameters are bound to a prepared
+// statement, returning the updated handle of the statement to use from
+// then on.
+func UnmarshalPreparedStatementHandle(appMetadata []byte) ([]byte, error) {
+	handle, ok, err := unmarshalPreparedStatementResult(appMetadata)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: arrow/flightsql: app metadata is not a DoPutPreparedStatementResult with a handle", arrow.ErrInvalid)
+	}
+	return handle, nil
+}
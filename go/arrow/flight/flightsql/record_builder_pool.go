@@ -0,0 +1,69 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"sync"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+)
+
+// recordBuilderPool reuses the record builders of the results built by
+// the BaseServer, which for nested schemas such as that of SqlInfo are
+// costly to create on every request. Builders are pooled by schema, the
+// schemas of the results of a given kind being the same pointer. A
+// builder holds no memory once NewRecord has been called, so those
+// dropped by the pool need no releasing.
+type recordBuilderPool struct {
+	mem   memory.Allocator
+	pools sync.Map // *arrow.Schema -> *sync.Pool
+}
+
+func newRecordBuilderPool(mem memory.Allocator) *recordBuilderPool {
+	return &recordBuilderPool{mem: mem}
+}
+
+// get returns an empty builder of records of the schema, which is only
+// used by the caller until it is given back with put. A nil pool returns
+// a new builder using mem.
+func (p *recordBuilderPool) get(mem memory.Allocator, schema *arrow.Schema) *array.RecordBuilder {
+	if p == nil || p.mem != mem {
+		return array.NewRecordBuilder(mem, schema)
+	}
+	pool, _ := p.pools.LoadOrStore(schema, &sync.Pool{})
+	if bldr, ok := pool.(*sync.Pool).Get().(*array.RecordBuilder); ok {
+		return bldr
+	}
+	return array.NewRecordBuilder(p.mem, schema)
+}
+
+// put gives back a builder returned by get, discarding the values it
+// holds, if any. The builder must not be used afterwards.
+func (p *recordBuilderPool) put(mem memory.Allocator, schema *arrow.Schema, bldr *array.RecordBuilder) {
+	// builders which failed midway hold the values appended so far
+	rec := bldr.NewRecord()
+	rec.Release()
+
+	if p == nil || p.mem != mem {
+		bldr.Release()
+		return
+	}
+	pool, _ := p.pools.LoadOrStore(schema, &sync.Pool{})
+	pool.(*sync.Pool).Put(bldr)
+}
@@ -0,0 +1,216 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"google.golang.org/grpc"
+)
+
+// ErrChecksumMismatch is wrapped by the errors of the readers verifying
+// result checksums, see WithChecksumVerification, when the records read
+// from an endpoint don't match its checksum, as when the stream was
+// truncated.
+var ErrChecksumMismatch = errors.New("arrow/flightsql: result checksum mismatch")
+
+// resultChecksumMagic starts the app metadata holding a ResultChecksum.
+var resultChecksumMagic = []byte("ARROWSUM")
+
+const resultChecksumLen = 8 + 8 + 8 + 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ResultChecksum summarizes the records of a stream: their number, their
+// total number of rows, and a CRC-32C of the number of rows of each of
+// them in order, so that dropped, duplicated or reordered records are
+// detected even if the total number of rows matches.
+type ResultChecksum struct {
+	Records int64
+	Rows    int64
+	CRC     uint32
+}
+
+// Add adds rec to the records summarized by the checksum.
+func (c *ResultChecksum) Add(rec arrow.Record) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(rec.NumRows()))
+	c.CRC = crc32.Update(c.CRC, castagnoli, buf[:])
+	c.Records++
+	c.Rows += rec.NumRows()
+}
+
+// AppMetadata returns the checksum encoded as the app metadata of a
+// record, see ParseResultChecksum.
+func (c ResultChecksum) AppMetadata() []byte {
+	buf := make([]byte, resultChecksumLen)
+	copy(buf, resultChecksumMagic)
+	binary.LittleEndian.PutUint64(buf[8:], uint64(c.Records))
+	binary.LittleEndian.PutUint64(buf[16:], uint64(c.Rows))
+	binary.LittleEndian.PutUint32(buf[24:], c.CRC)
+	return buf
+}
+
+// ParseResultChecksum decodes the checksum encoded in the app metadata
+// of a record by ResultChecksum.AppMetadata, returning false if appMeta
+// doesn't hold one.
+func ParseResultChecksum(appMeta []byte) (ResultChecksum, bool) {
+	if len(appMeta) != resultChecksumLen || !bytes.HasPrefix(appMeta, resultChecksumMagic) {
+		return ResultChecksum{}, false
+	}
+	return ResultChecksum{
+		Records: int64(binary.LittleEndian.Uint64(appMeta[8:])),
+		Rows:    int64(binary.LittleEndian.Uint64(appMeta[16:])),
+		CRC:     binary.LittleEndian.Uint32(appMeta[24:]),
+	}, true
+}
+
+// ChecksumChunks returns a channel forwarding the chunks of ch, the last
+// record carrying the ResultChecksum of all of them in its app metadata,
+// for the DoGet handlers of a Server whose clients verify the results
+// with WithChecksumVerification:
+//
+//	return schema, flightsql.ChecksumChunks(ctx, schema, ch), nil
+//
+// If the last record already has app metadata, if there is no record, or
+// if the last chunk only holds app metadata, the checksum is sent with an
+// additional record without rows of the given schema. Error chunks are
+// forwarded without a checksum. Once ctx is done, the chunks of ch are
+// released rather than forwarded.
+func ChecksumChunks(ctx context.Context, schema *arrow.Schema, ch <-chan flight.StreamChunk) <-chan flight.StreamChunk {
+	out := make(chan flight.StreamChunk)
+	go func() {
+		defer close(out)
+
+		var (
+			sum     ResultChecksum
+			pending flight.StreamChunk
+		)
+		send := func(chunk flight.StreamChunk) bool {
+			select {
+			case out <- chunk:
+				return true
+			case <-ctx.Done():
+				if chunk.Data != nil {
+					chunk.Data.Release()
+				}
+				for chunk := range ch {
+					if chunk.Data != nil {
+						chunk.Data.Release()
+					}
+				}
+				return false
+			}
+		}
+
+		// each record is held back until the next one arrives, so that the
+		// checksum can be attached to the last
+		for chunk := range ch {
+			if pending.Data != nil && !send(pending) {
+				if chunk.Data != nil {
+					chunk.Data.Release()
+				}
+				return
+			}
+			if chunk.Err != nil {
+				send(chunk)
+				return
+			}
+			if chunk.Data == nil {
+				// the checksum is then sent with a record after it
+				pending = flight.StreamChunk{}
+				if !send(chunk) {
+					return
+				}
+				continue
+			}
+			sum.Add(chunk.Data)
+			pending = chunk
+		}
+
+		if pending.Data != nil && pending.AppMetadata == nil {
+			pending.AppMetadata = sum.AppMetadata()
+			send(pending)
+			return
+		}
+		if pending.Data != nil && !send(pending) {
+			return
+		}
+
+		empty := emptyRecord(schema)
+		sum.Add(empty)
+		send(flight.StreamChunk{Data: empty, AppMetadata: sum.AppMetadata()})
+	}()
+	return out
+}
+
+// emptyRecord returns a record of schema without rows.
+func emptyRecord(schema *arrow.Schema) arrow.Record {
+	cols := make([]arrow.Array, schema.NumFields())
+	for i, f := range schema.Fields() {
+		cols[i] = array.MakeArrayOfNull(memory.DefaultAllocator, f.Type, 0)
+		defer cols[i].Release()
+	}
+	return array.NewRecord(schema, cols, 0)
+}
+
+// WithChecksumVerification makes the readers of ReadFlightInfo and
+// ExecuteQuery verify that the records of each endpoint match the
+// ResultChecksum sent in the app metadata of its last record, such as by
+// a server using ChecksumChunks. The reader fails with an error wrapping
+// ErrChecksumMismatch if they don't, or if the stream ended without a
+// checksum, as a truncated stream would.
+func WithChecksumVerification() grpc.CallOption {
+	return endpointReaderOption{apply: func(cfg *endpointReaderConfig) { cfg.verifyChecksums = true }}
+}
+
+// checksumVerifier verifies the records of an endpoint against the last
+// checksum received with them, implementing flight.EndpointVerifier.
+type checksumVerifier struct {
+	sum      ResultChecksum
+	expected *ResultChecksum
+}
+
+func (v *checksumVerifier) Add(chunk flight.StreamChunk) {
+	v.sum.Add(chunk.Data)
+	if sum, ok := ParseResultChecksum(chunk.AppMetadata); ok {
+		v.expected = &sum
+	}
+}
+
+// Verify returns an error if the records of the endpoint, all of which
+// were added, don't match the checksum.
+func (v *checksumVerifier) Verify() error {
+	switch {
+	case v.expected == nil:
+		return fmt.Errorf("%w: stream ended without a checksum after %d rows in %d records",
+			ErrChecksumMismatch, v.sum.Rows, v.sum.Records)
+	case *v.expected != v.sum:
+		return fmt.Errorf("%w: expected %d rows in %d records, got %d rows in %d records",
+			ErrChecksumMismatch, v.expected.Rows, v.expected.Records, v.sum.Rows, v.sum.Records)
+	}
+	return nil
+}
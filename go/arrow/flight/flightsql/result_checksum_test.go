@@ -0,0 +1,186 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// checksumServer returns two endpoints of three records each, whose
+// checksum is sent with ChecksumChunks. The last record of endpoint 1 is
+// dropped after the checksum was computed if truncate is set.
+type checksumServer struct {
+	flightsql.BaseServer
+	truncate bool
+}
+
+const checksumEndpointRows = 1 + 2 + 3
+
+func (s *checksumServer) GetFlightInfoStatement(_ context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	endpoints := make([]*flight.FlightEndpoint, 2)
+	for i := range endpoints {
+		tkt, err := flightsql.TicketStatementQuery([]byte(strconv.Itoa(i)))
+		if err != nil {
+			return nil, err
+		}
+		endpoints[i] = &flight.FlightEndpoint{Ticket: tkt}
+	}
+	return flightsql.NewFlightInfo(desc, latencySchema, s.Alloc, flightsql.WithEndpoints(endpoints...)), nil
+}
+
+func (s *checksumServer) DoGetStatement(ctx context.Context, tkt flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	idx, err := strconv.Atoi(string(tkt.GetStatementHandle()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+	defer bldr.Release()
+	ch := make(chan flight.StreamChunk, 3)
+	for n := 1; n <= 3; n++ {
+		for i := 0; i < n; i++ {
+			bldr.Field(0).(*array.Int64Builder).Append(int64(idx))
+		}
+		ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+	}
+	close(ch)
+
+	out := flightsql.ChecksumChunks(ctx, latencySchema, ch)
+	if s.truncate && idx == 1 {
+		out = dropLastChunk(out)
+	}
+	return latencySchema, out, nil
+}
+
+// dropLastChunk forwards the chunks of ch but the last one.
+func dropLastChunk(ch <-chan flight.StreamChunk) <-chan flight.StreamChunk {
+	out := make(chan flight.StreamChunk)
+	go func() {
+		defer close(out)
+		prev, ok := <-ch
+		if !ok {
+			return
+		}
+		for chunk := range ch {
+			out <- prev
+			prev = chunk
+		}
+		prev.Data.Release()
+	}()
+	return out
+}
+
+func TestResultChecksum(t *testing.T) {
+	srv := &checksumServer{}
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	readRows := func(opts ...grpc.CallOption) (int64, error) {
+		rdr, err := cl.ExecuteQuery(context.Background(), "SELECT 1", opts...)
+		if err != nil {
+			return 0, err
+		}
+		defer rdr.Release()
+
+		var rows int64
+		for rdr.Next() {
+			rows += rdr.Record().NumRows()
+		}
+		return rows, rdr.Err()
+	}
+
+	for _, opts := range [][]grpc.CallOption{
+		{flightsql.WithChecksumVerification()},
+		{flightsql.WithChecksumVerification(), flightsql.WithEndpointConcurrency(2)},
+	} {
+		srv.truncate = false
+		rows, err := readRows(opts...)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2*checksumEndpointRows, rows)
+
+		srv.truncate = true
+		_, err = readRows(opts...)
+		assert.ErrorIs(t, err, flightsql.ErrChecksumMismatch)
+		assert.ErrorContains(t, err, "endpoint 1")
+	}
+
+	// the truncated stream goes unnoticed without verification
+	rows, err := readRows()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2*checksumEndpointRows-3, rows)
+}
+
+func TestChecksumChunks(t *testing.T) {
+	ctx := context.Background()
+	collect := func(ch <-chan flight.StreamChunk) []flight.StreamChunk {
+		var chunks []flight.StreamChunk
+		for chunk := range ch {
+			chunks = append(chunks, chunk)
+			if chunk.Data != nil {
+				defer chunk.Data.Release()
+			}
+		}
+		return chunks
+	}
+
+	// the checksum of an empty result is sent with a record without rows
+	in := make(chan flight.StreamChunk)
+	close(in)
+	chunks := collect(flightsql.ChecksumChunks(ctx, latencySchema, in))
+	require.Len(t, chunks, 1)
+	assert.Zero(t, chunks[0].Data.NumRows())
+	sum, ok := flightsql.ParseResultChecksum(chunks[0].AppMetadata)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), sum.Records)
+	assert.Zero(t, sum.Rows)
+
+	// as is that of a result whose last record has app metadata of its own
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	in = make(chan flight.StreamChunk, 1)
+	in <- flight.StreamChunk{Data: bldr.NewRecord(), AppMetadata: []byte("mine")}
+	close(in)
+	chunks = collect(flightsql.ChecksumChunks(ctx, latencySchema, in))
+	require.Len(t, chunks, 2)
+	assert.Equal(t, []byte("mine"), chunks[0].AppMetadata)
+	sum, ok = flightsql.ParseResultChecksum(chunks[1].AppMetadata)
+	require.True(t, ok)
+	assert.Equal(t, int64(2), sum.Records)
+	assert.Equal(t, int64(2), sum.Rows)
+
+	_, ok = flightsql.ParseResultChecksum([]byte("mine"))
+	assert.False(t, ok)
+}
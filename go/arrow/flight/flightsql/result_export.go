@@ -0,0 +1,118 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/csv"
+	"google.golang.org/grpc"
+)
+
+// ExecuteToJSON executes the query and writes its results to w as
+// newline-delimited JSON, one object per row keyed by column name, as
+// array.RecordToJSON writes them: nulls as null, lists as arrays and
+// structs as nested objects. Each record is written as soon as it is
+// read, so that the result is never held in memory as a whole. The rows
+// written before an error are left in w.
+func (c *Client) ExecuteToJSON(ctx context.Context, query string, w io.Writer, opts ...grpc.CallOption) error {
+	rdr, err := c.ExecuteQuery(ctx, query, opts...)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	for rdr.Next() {
+		if err := array.RecordToJSON(rdr.Record(), w); err != nil {
+			return err
+		}
+	}
+	return rdr.Err()
+}
+
+// CSVOptions configures the output of Client.ExecuteToCSV.
+type CSVOptions struct {
+	// Header writes the names of the columns as the first line, even if
+	// the result has no rows.
+	Header bool
+	// Delimiter separates the fields of a line, ',' if zero.
+	Delimiter rune
+	// Null is written for null values, an empty field by default, which
+	// can't be told apart from an empty string.
+	Null string
+}
+
+// ExecuteToCSV executes the query and writes its results to w as CSV
+// with the writer of the csv package, one line per row. Fields holding
+// the delimiter, quotes or line breaks are quoted, timestamps are written
+// in UTC as "2006-01-02 15:04:05.999999999", dates as "2006-01-02" and
+// binary values in base64. As with ExecuteToJSON, each record is written
+// as soon as it is read. It fails before writing anything if a column
+// has a type the csv package can't write, such as a struct.
+func (c *Client) ExecuteToCSV(ctx context.Context, query string, w io.Writer, opts CSVOptions, callOpts ...grpc.CallOption) error {
+	rdr, err := c.ExecuteQuery(ctx, query, callOpts...)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	csvOpts := []csv.Option{csv.WithHeader(opts.Header), csv.WithNullWriter(opts.Null)}
+	if opts.Delimiter != 0 {
+		csvOpts = append(csvOpts, csv.WithComma(opts.Delimiter))
+	}
+	wr, err := newCSVWriter(w, rdr.Schema(), csvOpts...)
+	if err != nil {
+		return err
+	}
+
+	written := false
+	for rdr.Next() {
+		if err := wr.Write(rdr.Record()); err != nil {
+			return err
+		}
+		written = true
+	}
+	if err := rdr.Err(); err != nil {
+		wr.Flush()
+		return err
+	}
+
+	// the header is only written along with the first record
+	if !written && opts.Header {
+		empty := emptyRecord(rdr.Schema())
+		defer empty.Release()
+		if err := wr.Write(empty); err != nil {
+			return err
+		}
+	}
+	return wr.Flush()
+}
+
+// newCSVWriter returns the error csv.NewWriter panics with if schema has
+// a field it can't write.
+func newCSVWriter(w io.Writer, schema *arrow.Schema, opts ...csv.Option) (wr *csv.Writer, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: arrow/flightsql: cannot write the result as CSV: %v", arrow.ErrNotImplemented, r)
+		}
+	}()
+	return csv.NewWriter(w, schema, opts...), nil
+}
@@ -0,0 +1,162 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/flightsqltest"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var exportSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "score", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	{Name: "active", Type: arrow.FixedWidthTypes.Boolean},
+	{Name: "tags", Type: arrow.ListOf(arrow.PrimitiveTypes.Int32), Nullable: true},
+	{Name: "point", Type: arrow.StructOf(
+		arrow.Field{Name: "x", Type: arrow.PrimitiveTypes.Int32},
+		arrow.Field{Name: "label", Type: arrow.BinaryTypes.String, Nullable: true},
+	), Nullable: true},
+}, nil)
+
+var csvSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "name", Type: arrow.BinaryTypes.String},
+	{Name: "note", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "at", Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}, Nullable: true},
+	{Name: "n", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+}, nil)
+
+// exportResults are the results of the queries of exportServer, as the
+// JSON of each of their records.
+var exportResults = map[string]struct {
+	schema  *arrow.Schema
+	batches []string
+}{
+	"SELECT * FROM export": {exportSchema, []string{
+		`[{"id": 1, "name": "alice", "score": 1.5, "active": true, "tags": [1, 2], "point": {"x": 3, "label": "a"}},
+		  {"id": 2, "name": null, "score": null, "active": false, "tags": [], "point": null}]`,
+		`[{"id": 3, "name": "", "score": -2, "active": true, "tags": null, "point": {"x": 0, "label": null}}]`,
+	}},
+	"SELECT * FROM csv": {csvSchema, []string{
+		`[{"name": "Smith, John", "note": "said \"hi\"", "at": "2024-03-01T12:30:45.123Z", "n": 1},
+		  {"name": "plain", "note": null, "at": null, "n": null}]`,
+		`[{"name": "multi\nline", "note": "", "at": "1999-12-31T23:59:59Z", "n": -7}]`,
+	}},
+	"SELECT * FROM empty": {csvSchema, nil},
+}
+
+// exportServer answers the queries of exportResults.
+type exportServer struct {
+	flightsql.BaseServer
+}
+
+func (s *exportServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	result, ok := exportResults[cmd.GetQuery()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown query %q", cmd.GetQuery())
+	}
+	tkt, err := flightsql.TicketStatementQuery([]byte(cmd.GetQuery()))
+	if err != nil {
+		return nil, err
+	}
+	return flightsql.NewFlightInfo(desc, result.schema, s.Alloc,
+		flightsql.WithEndpoints(&flight.FlightEndpoint{Ticket: tkt})), nil
+}
+
+func (s *exportServer) DoGetStatement(_ context.Context, tkt flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	result := exportResults[string(tkt.GetStatementHandle())]
+	ch := make(chan flight.StreamChunk, len(result.batches))
+	defer close(ch)
+	for _, batch := range result.batches {
+		rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, result.schema, strings.NewReader(batch))
+		if err != nil {
+			return nil, nil, err
+		}
+		ch <- flight.StreamChunk{Data: rec}
+	}
+	return result.schema, ch, nil
+}
+
+func TestExecuteToJSON(t *testing.T) {
+	cl := flightsqltest.StartServer(t, &exportServer{})
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	var buf bytes.Buffer
+	require.NoError(t, cl.ExecuteToJSON(context.Background(), "SELECT * FROM export", &buf, flightsql.WithClientAllocator(mem)))
+	assert.Equal(t, `{"active":true,"id":1,"name":"alice","point":{"label":"a","x":3},"score":1.5,"tags":[1,2]}
+{"active":false,"id":2,"name":null,"point":null,"score":null,"tags":[]}
+{"active":true,"id":3,"name":"","point":{"label":null,"x":0},"score":-2,"tags":null}
+`, buf.String())
+}
+
+func TestExecuteToCSV(t *testing.T) {
+	cl := flightsqltest.StartServer(t, &exportServer{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		opts     flightsql.CSVOptions
+		expected string
+	}{
+		{"default", "SELECT * FROM csv", flightsql.CSVOptions{},
+			`"Smith, John","said ""hi""",2024-03-01 12:30:45.123,1
+plain,,,
+"multi
+line",,1999-12-31 23:59:59,-7
+`},
+		{"options", "SELECT * FROM csv", flightsql.CSVOptions{Header: true, Delimiter: ';', Null: "NULL"},
+			`name;note;at;n
+Smith, John;"said ""hi""";2024-03-01 12:30:45.123;1
+plain;NULL;NULL;NULL
+"multi
+line";;1999-12-31 23:59:59;-7
+`},
+		{"empty with header", "SELECT * FROM empty", flightsql.CSVOptions{Header: true}, "name,note,at,n\n"},
+		{"empty", "SELECT * FROM empty", flightsql.CSVOptions{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, cl.ExecuteToCSV(ctx, tt.query, &buf, tt.opts))
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+
+	// lists of structs can't be written as CSV
+	var buf bytes.Buffer
+	err := cl.ExecuteToCSV(ctx, "SELECT * FROM export", &buf, flightsql.CSVOptions{})
+	assert.ErrorIs(t, err, arrow.ErrNotImplemented)
+	assert.ErrorContains(t, err, "point")
+	assert.Zero(t, buf.Len())
+}
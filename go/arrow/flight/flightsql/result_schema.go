@@ -0,0 +1,90 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// schemaFallbackOption is a grpc.CallOption which allows the result
+// schema of a statement to be read from its FlightInfo. gRPC itself
+// ignores it.
+type schemaFallbackOption struct {
+	grpc.EmptyCallOption
+}
+
+// WithFlightInfoSchemaFallback allows GetExecuteResultSchema and
+// RefreshDatasetSchema to fall back to GetFlightInfo when the server
+// doesn't implement GetSchema, reading the schema from the FlightInfo.
+// Servers may execute the statement to answer GetFlightInfo, so this is
+// only done if asked for.
+func WithFlightInfoSchemaFallback() grpc.CallOption {
+	return schemaFallbackOption{}
+}
+
+// GetExecuteResultSchema is like GetExecuteSchema, but deserializes the
+// schema of the result set, metadata included. The prepared statement
+// equivalent is RefreshDatasetSchema. See WithFlightInfoSchemaFallback for
+// servers which don't implement GetSchema.
+func (c *Client) GetExecuteResultSchema(ctx context.Context, query string, opts ...grpc.CallOption) (*arrow.Schema, error) {
+	return c.resultSchema(ctx, &pb.CommandStatementQuery{Query: query}, opts)
+}
+
+// resultSchema requests the schema of the result set of cmd with
+// GetSchema, or with GetFlightInfo if the server doesn't implement it and
+// WithFlightInfoSchemaFallback is given.
+func (c *Client) resultSchema(ctx context.Context, cmd proto.Message, opts []grpc.CallOption) (*arrow.Schema, error) {
+	desc, err := descForCommand(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.getSchema(ctx, desc, opts...)
+	if err == nil {
+		return flight.DeserializeSchema(res.GetSchema(), c.Alloc)
+	}
+	if status.Code(err) != codes.Unimplemented || !hasSchemaFallback(opts) {
+		return nil, err
+	}
+
+	info, err := c.getFlightInfo(ctx, desc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.GetSchema()) == 0 {
+		return nil, fmt.Errorf("%w: arrow/flightsql: server returned no schema in the FlightInfo", arrow.ErrInvalid)
+	}
+	return flight.DeserializeSchema(info.GetSchema(), c.Alloc)
+}
+
+func hasSchemaFallback(opts []grpc.CallOption) bool {
+	for _, o := range opts {
+		if _, ok := o.(schemaFallbackOption); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var resultSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64,
+		Metadata: arrow.NewMetadata([]string{flightsql.TypeNameKey}, []string{"BIGINT"})},
+	{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true,
+		Metadata: arrow.NewMetadata([]string{flightsql.TableNameKey}, []string{"people"})},
+}, nil)
+
+// schemaServer returns resultSchema in the FlightInfo of every statement,
+// and also with GetSchema unless noGetSchema is set.
+type schemaServer struct {
+	flightsql.BaseServer
+
+	noGetSchema bool
+	flightInfos atomic.Int32
+}
+
+func (s *schemaServer) getSchema(desc *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	if s.noGetSchema {
+		return nil, status.Error(codes.Unimplemented, "GetSchema not implemented")
+	}
+	return &flight.SchemaResult{Schema: flight.SerializeSchema(resultSchema, memory.DefaultAllocator)}, nil
+}
+
+func (s *schemaServer) flightInfo(desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	s.flightInfos.Add(1)
+	return &flight.FlightInfo{
+		Schema:           flight.SerializeSchema(resultSchema, memory.DefaultAllocator),
+		FlightDescriptor: desc,
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+func (s *schemaServer) GetSchemaStatement(_ context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	return s.getSchema(desc)
+}
+
+func (s *schemaServer) GetSchemaPreparedStatement(_ context.Context, _ flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	return s.getSchema(desc)
+}
+
+func (s *schemaServer) GetFlightInfoStatement(_ context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return s.flightInfo(desc)
+}
+
+func (s *schemaServer) GetFlightInfoPreparedStatement(_ context.Context, _ flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return s.flightInfo(desc)
+}
+
+func (s *schemaServer) CreatePreparedStatement(context.Context, flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	return flightsql.ActionCreatePreparedStatementResult{Handle: []byte("handle")}, nil
+}
+
+func (s *schemaServer) ClosePreparedStatement(context.Context, flightsql.ActionClosePreparedStatementRequest) error {
+	return nil
+}
+
+func TestGetExecuteResultSchema(t *testing.T) {
+	for _, noGetSchema := range []bool{false, true} {
+		srv := &schemaServer{noGetSchema: noGetSchema}
+		s := flight.NewServerWithMiddleware(nil)
+		s.RegisterFlightService(flightsql.NewFlightServer(srv))
+		require.NoError(t, s.Init("localhost:0"))
+		go s.Serve()
+		defer s.Shutdown()
+
+		cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+		require.NoError(t, err)
+		defer cl.Close()
+
+		ctx := context.Background()
+		prep, err := cl.Prepare(ctx, "SELECT * FROM people")
+		require.NoError(t, err)
+		defer prep.Close(ctx)
+
+		if noGetSchema {
+			_, err = cl.GetExecuteResultSchema(ctx, "SELECT * FROM people")
+			assert.Equal(t, codes.Unimplemented, status.Code(err))
+			_, err = prep.RefreshDatasetSchema(ctx)
+			assert.Equal(t, codes.Unimplemented, status.Code(err))
+		}
+
+		schema, err := cl.GetExecuteResultSchema(ctx, "SELECT * FROM people", flightsql.WithFlightInfoSchemaFallback())
+		require.NoError(t, err)
+		assert.Truef(t, resultSchema.Equal(schema), "expected: %s\ngot: %s", resultSchema, schema)
+		assert.Equal(t, resultSchema.Field(0).Metadata, schema.Field(0).Metadata)
+		assert.Equal(t, resultSchema.Field(1).Metadata, schema.Field(1).Metadata)
+
+		schema, err = prep.RefreshDatasetSchema(ctx, flightsql.WithFlightInfoSchemaFallback())
+		require.NoError(t, err)
+		assert.Truef(t, resultSchema.Equal(schema), "expected: %s\ngot: %s", resultSchema, schema)
+		assert.Equal(t, resultSchema.Field(1).Metadata, prep.DatasetSchema().Field(1).Metadata)
+
+		// GetFlightInfo is only called if GetSchema is not implemented
+		if noGetSchema {
+			assert.EqualValues(t, 2, srv.flightInfos.Load())
+		} else {
+			assert.Zero(t, srv.flightInfos.Load())
+		}
+	}
+}
+
+func TestExecuteExpecting(t *testing.T) {
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&schemaServer{}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	// metadata isn't compared
+	expected := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	info, err := cl.ExecuteExpecting(ctx, "SELECT * FROM people", expected)
+	require.NoError(t, err)
+	assert.NotNil(t, info)
+
+	changed := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "age", Type: arrow.PrimitiveTypes.Int8},
+	}, nil)
+	_, err = cl.ExecuteExpecting(ctx, "SELECT * FROM people", changed)
+	assert.ErrorIs(t, err, arrow.ErrInvalid)
+	var mismatch *flightsql.SchemaMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, []string{
+		`column "id" has type int64, expected int32`,
+		`column "name" has nullable=true, expected nullable=false`,
+		`column "age" (int8) is missing`,
+	}, mismatch.Differences)
+	assert.ErrorContains(t, err, `column "id" has type int64, expected int32`)
+
+	reordered := arrow.NewSchema([]arrow.Field{
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	_, err = cl.ExecuteExpecting(ctx, "SELECT * FROM people", reordered)
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, []string{
+		`column "name" is at position 1, expected 0`,
+		`unexpected column "id" (int64)`,
+	}, mismatch.Differences)
+}
@@ -0,0 +1,99 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"google.golang.org/grpc"
+)
+
+// SchemaMismatchError is returned by ExecuteExpecting when the schema of
+// the results differs from the expected one. It matches arrow.ErrInvalid
+// with errors.Is.
+type SchemaMismatchError struct {
+	Expected, Actual *arrow.Schema
+	// Differences describe each column which differs, such as
+	// `column "price" has type float64, expected decimal(10, 2)`.
+	Differences []string
+}
+
+func (e *SchemaMismatchError) Error() string {
+	return "arrow/flightsql: result schema differs from the expected schema:\n\t" +
+		strings.Join(e.Differences, "\n\t")
+}
+
+func (e *SchemaMismatchError) Unwrap() error { return arrow.ErrInvalid }
+
+// ExecuteExpecting executes the query as Execute does, and checks that
+// the schema of its results, as returned in the FlightInfo, is expected.
+// Columns are compared by name, position, type and nullability; their
+// metadata is not compared. If they differ, the error is a
+// *SchemaMismatchError describing each difference, so that changes of
+// the backend are noticed before reading the results.
+func (c *Client) ExecuteExpecting(ctx context.Context, query string, expected *arrow.Schema, opts ...grpc.CallOption) (*flight.FlightInfo, error) {
+	info, err := c.Execute(ctx, query, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.GetSchema()) == 0 {
+		return nil, fmt.Errorf("%w: arrow/flightsql: server returned no schema for the query", arrow.ErrInvalid)
+	}
+
+	actual, err := flight.DeserializeSchema(info.GetSchema(), c.Alloc)
+	if err != nil {
+		return nil, err
+	}
+	if diffs := diffSchemas(expected, actual); len(diffs) > 0 {
+		return nil, &SchemaMismatchError{Expected: expected, Actual: actual, Differences: diffs}
+	}
+	return info, nil
+}
+
+// diffSchemas describes how the fields of actual differ from those of
+// expected, ignoring metadata.
+func diffSchemas(expected, actual *arrow.Schema) (diffs []string) {
+	for i, want := range expected.Fields() {
+		indices := actual.FieldIndices(want.Name)
+		if len(indices) == 0 {
+			diffs = append(diffs, fmt.Sprintf("column %q (%s) is missing", want.Name, want.Type))
+			continue
+		}
+
+		got := actual.Field(indices[0])
+		if indices[0] != i {
+			diffs = append(diffs, fmt.Sprintf("column %q is at position %d, expected %d", want.Name, indices[0], i))
+		}
+		if !arrow.TypeEqual(got.Type, want.Type) {
+			diffs = append(diffs, fmt.Sprintf("column %q has type %s, expected %s", want.Name, got.Type, want.Type))
+		}
+		if got.Nullable != want.Nullable {
+			diffs = append(diffs, fmt.Sprintf("column %q has nullable=%t, expected nullable=%t", want.Name, got.Nullable, want.Nullable))
+		}
+	}
+
+	for _, got := range actual.Fields() {
+		if !expected.HasField(got.Name) {
+			diffs = append(diffs, fmt.Sprintf("unexpected column %q (%s)", got.Name, got.Type))
+		}
+	}
+	return diffs
+}
@@ -18,7 +18,12 @@ package flightsql
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"runtime"
+	"sync"
+	"time"
 
 	"github.com/apache/arrow/go/v16/arrow"
 	"github.com/apache/arrow/go/v16/arrow/array"
@@ -28,9 +33,12 @@ import (
 	"github.com/apache/arrow/go/v16/arrow/internal/debug"
 	"github.com/apache/arrow/go/v16/arrow/ipc"
 	"github.com/apache/arrow/go/v16/arrow/memory"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
@@ -120,12 +128,21 @@ type ActionClosePreparedStatementRequest interface {
 // ActionCreatePreparedStatementRequest represents a request to construct
 // a new prepared statement
 type ActionCreatePreparedStatementRequest interface {
+	// GetQuery returns the SQL query to prepare
 	GetQuery() string
+	// GetTransactionId returns the transaction the statement is created
+	// in, which all its executions must then happen in, or nil to execute
+	// it outside of any transaction
 	GetTransactionId() []byte
 }
 
+// ActionCreatePreparedSubstraitPlanRequest represents a request to
+// construct a new prepared statement from a Substrait plan
 type ActionCreatePreparedSubstraitPlanRequest interface {
+	// GetPlan returns the Substrait plan to prepare
 	GetPlan() SubstraitPlan
+	// GetTransactionId returns the transaction the statement is created
+	// in, or nil, as for ActionCreatePreparedStatementRequest
 	GetTransactionId() []byte
 }
 
@@ -191,6 +208,10 @@ type ActionEndTransactionRequest interface {
 	GetAction() EndTransactionRequestType
 }
 
+type endTransactionResultServer interface {
+	EndTransactionWithResult(context.Context, ActionEndTransactionRequest) ([]byte, error)
+}
+
 type ActionEndSavepointRequest interface {
 	GetSavepointId() []byte
 	GetAction() EndSavepointRequestType
@@ -206,6 +227,9 @@ func (c *getXdbcTypeInfo) GetDataType() *int32 { return c.DataType }
 type GetXdbcTypeInfo interface {
 	// GetDataType returns either nil (get for all types)
 	// or a specific SQL type ID to fetch information about.
+	// A type the server doesn't support is answered with
+	// an empty result rather than an error, see
+	// XdbcTypeInfoResultBuilder.AppendFiltered.
 	GetDataType() *int32
 }
 
@@ -241,6 +265,8 @@ type GetTables interface {
 	GetCatalog() *string
 	GetDBSchemaFilterPattern() *string
 	GetTableNameFilterPattern() *string
+	// GetTableTypes returns the types of the tables requested, all of
+	// them if empty, see TableTypeFilter.
 	GetTableTypes() []string
 	GetIncludeSchema() bool
 }
@@ -262,6 +288,19 @@ func packActionResult(msg proto.Message) (*pb.Result, error) {
 	return ret, nil
 }
 
+// Chunk returns a chunk of a result for the channels returned by the
+// DoGet handlers, attaching app metadata, such as a partition id or a
+// checkpoint token, to the record.
+//
+// The metadata is sent in the same FlightData message as the record, and
+// is available on the client alongside it from the Chunk and
+// LatestAppMetadata methods of the readers returned by DoGet,
+// ReadFlightInfo and ExecuteQuery. The record must not be nil, and is
+// released once it has been written.
+func Chunk(rec arrow.Record, appMetadata []byte) flight.StreamChunk {
+	return flight.StreamChunk{Data: rec, AppMetadata: appMetadata}
+}
+
 // BaseServer must be embedded into any FlightSQL Server implementation
 // and provides default implementations of all methods returning an
 // unimplemented error if called. This allows consumers to gradually
@@ -269,36 +308,270 @@ func packActionResult(msg proto.Message) (*pb.Result, error) {
 // boilerplate the same "unimplemented" methods.
 //
 // The base implementation also contains handling for registering sql info
-// and serving it up in response to GetSqlInfo requests.
+// and xdbc type info and serving them up in response to GetSqlInfo and
+// GetXdbcTypeInfo requests. Anything registered is held until Close is
+// called, which should be done after the server has been shut down.
+//
+// A BaseServer should be constructed with NewBaseServer. Relying on the
+// zero value is deprecated, though it continues to work as long as the
+// server is wrapped with NewFlightServer before any requests are served.
 type BaseServer struct {
-	sqlInfoToResult SqlInfoResultMap
+	// registered holds what is registered with the server, which may be
+	// replaced while requests are served, see baseRegistry
+	registered         *baseRegistry
+	preparedStatements *PreparedStatementCache
+	// builders are the record builders reused across requests, see
+	// recordBuilderPool
+	builders *recordBuilderPool
 	// Alloc allows specifying a particular allocator to use for any
 	// allocations done by the base implementation.
-	// Will use memory.DefaultAllocator if nil
+	// Will use memory.DefaultAllocator if nil. It must not be modified
+	// once the server is handling requests.
 	Alloc memory.Allocator
 }
 
+// baseRegistry holds the sql info and type info registered with a
+// BaseServer, shared by its copies. Both are replaced under mu: the sql
+// info map is copied on write, so a map read from it is never modified,
+// and the record is retained by the requests which read it until they
+// are done with it.
+type baseRegistry struct {
+	mu           sync.RWMutex
+	sqlInfo      SqlInfoResultMap
+	xdbcTypeInfo arrow.Record
+}
+
+// sqlInfoMap returns the registered sql info, which must not be modified.
+func (r *baseRegistry) sqlInfoMap() SqlInfoResultMap {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.sqlInfo
+}
+
+// setSqlInfo registers result for id on a copy of the sql info.
+func (r *baseRegistry) setSqlInfo(id uint32, result interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := make(SqlInfoResultMap, len(r.sqlInfo)+1)
+	for k, v := range r.sqlInfo {
+		info[k] = v
+	}
+	info[id] = result
+	r.sqlInfo = info
+}
+
+// setSqlInfoMap replaces the sql info with info, which must not be
+// modified afterwards.
+func (r *baseRegistry) setSqlInfoMap(info SqlInfoResultMap) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sqlInfo = info
+}
+
+// retainXdbcTypeInfo returns the registered type info, retained, or nil.
+func (r *baseRegistry) retainXdbcTypeInfo() arrow.Record {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.xdbcTypeInfo != nil {
+		r.xdbcTypeInfo.Retain()
+	}
+	return r.xdbcTypeInfo
+}
+
+// hasXdbcTypeInfo reports whether type info is registered.
+func (r *baseRegistry) hasXdbcTypeInfo() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.xdbcTypeInfo != nil
+}
+
+// setXdbcTypeInfo replaces the registered type info with rec, which is
+// retained, releasing the previous record. A nil rec removes it.
+func (r *baseRegistry) setXdbcTypeInfo(rec arrow.Record) {
+	if rec != nil {
+		rec.Retain()
+	}
+	r.mu.Lock()
+	prev := r.xdbcTypeInfo
+	r.xdbcTypeInfo = rec
+	r.mu.Unlock()
+	if prev != nil {
+		prev.Release()
+	}
+}
+
+// BaseServerOption configures a BaseServer created by NewBaseServer.
+type BaseServerOption func(*BaseServer)
+
+// WithBaseServerAllocator sets the allocator used for any allocations done
+// by the base implementation. A nil allocator means memory.DefaultAllocator.
+func WithBaseServerAllocator(mem memory.Allocator) BaseServerOption {
+	return func(b *BaseServer) { b.Alloc = mem }
+}
+
+// WithPreparedStatementCache gives the BaseServer a cache in which the
+// Server can keep the state of its prepared statements, see
+// BaseServer.PreparedStatements. The cache is closed by BaseServer.Close.
+func WithPreparedStatementCache(cache *PreparedStatementCache) BaseServerOption {
+	return func(b *BaseServer) { b.preparedStatements = cache }
+}
+
+// NewBaseServer returns a BaseServer for embedding in a Server
+// implementation, with its allocator and sql info registry initialized
+// up front so that nothing is lazily assigned while serving requests.
+func NewBaseServer(opts ...BaseServerOption) BaseServer {
+	var b BaseServer
+	for _, o := range opts {
+		o(&b)
+	}
+	b.initBaseServer()
+	return b
+}
+
+// initBaseServer fills in the defaults of a zero value BaseServer. It is
+// called by NewBaseServer and once by NewFlightServer for servers which
+// embed a BaseServer, before any request is handled.
+func (b *BaseServer) initBaseServer() {
+	if b.Alloc == nil {
+		b.Alloc = memory.DefaultAllocator
+	}
+	if b.builders == nil {
+		b.builders = newRecordBuilderPool(b.Alloc)
+	}
+	if b.registered == nil {
+		b.registered = &baseRegistry{}
+	}
+}
+
 func (BaseServer) mustEmbedBaseServer() {}
 
+// PreparedStatements returns the cache given with
+// WithPreparedStatementCache, or nil. A Server using it generates the
+// handles of its statements in CreatePreparedStatement with
+// PreparedStatementCache.NewHandle and retrieves their state with
+// PreparedStatementCache.Lookup; the default ClosePreparedStatement
+// removes them.
+func (b *BaseServer) PreparedStatements() *PreparedStatementCache {
+	return b.preparedStatements
+}
+
 // RegisterSqlInfo registers a specific result to return for a given sqlinfo
 // id. The result must be one of the following types: string, bool, int64,
 // int32, []string, or map[int32][]int32.
 //
 // Once registered, this value will be returned for any SqlInfo requests.
+// It may be called while requests are served.
 func (b *BaseServer) RegisterSqlInfo(id SqlInfo, result interface{}) error {
-	if b.sqlInfoToResult == nil {
-		b.sqlInfoToResult = make(SqlInfoResultMap)
-	}
-
 	switch result.(type) {
 	case string, bool, int64, int32, []string, map[int32][]int32:
-		b.sqlInfoToResult[uint32(id)] = result
+		if b.registered == nil {
+			b.registered = &baseRegistry{}
+		}
+		b.registered.setSqlInfo(uint32(id), result)
 	default:
 		return fmt.Errorf("invalid sql info type '%T' registered for id: %d", result, id)
 	}
 	return nil
 }
 
+// RegisterSqlInfoMap replaces all of the registered sql info with the
+// contents of info, such as a map loaded with SqlInfoResultMapFromJSON.
+// Every value must be one of the types accepted by RegisterSqlInfo;
+// if any is not, an error is returned and the registered info is left
+// unchanged.
+func (b *BaseServer) RegisterSqlInfoMap(info SqlInfoResultMap) error {
+	result := make(SqlInfoResultMap, len(info))
+	for id, v := range info {
+		switch v.(type) {
+		case string, bool, int64, int32, []string, map[int32][]int32:
+			result[id] = v
+		default:
+			return fmt.Errorf("invalid sql info type '%T' registered for id: %d", v, id)
+		}
+	}
+
+	if b.registered == nil {
+		b.registered = &baseRegistry{}
+	}
+	b.registered.setSqlInfoMap(result)
+	return nil
+}
+
+// RegisterXdbcTypeInfo registers the list of data types to return in
+// response to GetXdbcTypeInfo requests, replacing any previously
+// registered rows. Requests for a specific data type are answered with
+// only the matching rows. It may be called while requests are served,
+// those in progress keep the rows they started with.
+//
+// The rows are held in memory allocated from Alloc until Close is called,
+// which the caller must do once the flight server has been shut down.
+func (b *BaseServer) RegisterXdbcTypeInfo(rows ...XdbcTypeInfoRow) {
+	bldr := NewXdbcTypeInfoResultBuilder(b.allocator())
+	defer bldr.Release()
+
+	bldr.Append(rows...)
+	rec := bldr.NewRecord()
+	defer rec.Release()
+
+	b.setXdbcTypeInfo(rec)
+}
+
+// RegisterXdbcTypeInfoRecord is like RegisterXdbcTypeInfo but accepts an
+// already built record, such as the output of an XdbcTypeInfoResultBuilder.
+// The record must have the schema schema_ref.XdbcTypeInfo. Retain is called
+// on the record and it is released when Close is called or when another
+// set of type info is registered.
+func (b *BaseServer) RegisterXdbcTypeInfoRecord(rec arrow.Record) error {
+	if !rec.Schema().Equal(schema_ref.XdbcTypeInfo) {
+		return fmt.Errorf("%w: type info record does not match the XdbcTypeInfo schema: %s",
+			arrow.ErrInvalid, rec.Schema())
+	}
+
+	b.setXdbcTypeInfo(rec)
+	return nil
+}
+
+func (b *BaseServer) setXdbcTypeInfo(rec arrow.Record) {
+	if b.registered == nil {
+		b.registered = &baseRegistry{}
+	}
+	b.registered.setXdbcTypeInfo(rec)
+}
+
+// Close releases any resources held by the base implementation, such as
+// the rows registered with RegisterXdbcTypeInfo and the entries of the
+// prepared statement cache.
+//
+// Neither the flight server nor its Shutdown ever call Close: the caller
+// must call it once the flight server has been shut down, or those
+// resources are leaked. Implementations which define their own Close
+// method must call this one from it.
+func (b *BaseServer) Close() error {
+	if b.registered != nil {
+		b.registered.setXdbcTypeInfo(nil)
+	}
+	if b.preparedStatements != nil {
+		b.preparedStatements.Close()
+	}
+	return nil
+}
+
+func (b *BaseServer) allocator() memory.Allocator {
+	if b.Alloc == nil {
+		return memory.DefaultAllocator
+	}
+	return b.Alloc
+}
+
 func (BaseServer) GetFlightInfoStatement(context.Context, StatementQuery, *flight.FlightDescriptor) (*flight.FlightInfo, error) {
 	return nil, status.Errorf(codes.Unimplemented, "GetFlightInfoStatement not implemented")
 }
@@ -339,12 +612,48 @@ func (BaseServer) DoGetCatalogs(context.Context) (*arrow.Schema, <-chan flight.S
 	return nil, nil, status.Errorf(codes.Unimplemented, "DoGetCatalogs not implemented")
 }
 
-func (BaseServer) GetFlightInfoXdbcTypeInfo(context.Context, GetXdbcTypeInfo, *flight.FlightDescriptor) (*flight.FlightInfo, error) {
-	return nil, status.Errorf(codes.Unimplemented, "GetFlightInfoXdbcTypeInfo not implemented")
+// GetFlightInfoXdbcTypeInfo is a base implementation of GetXdbcTypeInfo
+// using the rows registered by calling RegisterXdbcTypeInfo. Will return
+// an unimplemented error if no type info has been registered.
+func (b *BaseServer) GetFlightInfoXdbcTypeInfo(_ context.Context, _ GetXdbcTypeInfo, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	if !b.registered.hasXdbcTypeInfo() {
+		return nil, status.Errorf(codes.Unimplemented, "GetFlightInfoXdbcTypeInfo not implemented")
+	}
+
+	return NewFlightInfo(desc, schema_ref.XdbcTypeInfo, b.allocator()), nil
 }
 
-func (BaseServer) DoGetXdbcTypeInfo(context.Context, GetXdbcTypeInfo) (*arrow.Schema, <-chan flight.StreamChunk, error) {
-	return nil, nil, status.Errorf(codes.Unimplemented, "DoGetXdbcTypeInfo not implemented")
+// DoGetXdbcTypeInfo returns a flight stream containing the registered
+// type info rows, filtered by the requested data type if there is one.
+// The stream has no rows if no registered row has that type.
+func (b *BaseServer) DoGetXdbcTypeInfo(_ context.Context, cmd GetXdbcTypeInfo) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	// the rows are held until the stream is done, as they may be
+	// replaced meanwhile
+	info := b.registered.retainXdbcTypeInfo()
+	if info == nil {
+		return nil, nil, status.Errorf(codes.Unimplemented, "DoGetXdbcTypeInfo not implemented")
+	}
+
+	batch, err := filterXdbcTypeInfo(b.allocator(), info, cmd.GetDataType())
+	if err != nil {
+		info.Release()
+		return nil, nil, status.Errorf(codes.Internal, "error filtering type info: %s", err.Error())
+	}
+	defer batch.Release()
+
+	ch := make(chan flight.StreamChunk)
+	rdr, err := array.NewRecordReader(schema_ref.XdbcTypeInfo, []arrow.Record{batch})
+	if err != nil {
+		info.Release()
+		return nil, nil, status.Errorf(codes.Internal, "error producing record response: %s", err.Error())
+	}
+
+	// StreamChunksFromReader will call release on the reader when done
+	go func() {
+		defer info.Release()
+		flight.StreamChunksFromReader(rdr, ch)
+	}()
+	return schema_ref.XdbcTypeInfo, ch, nil
 }
 
 // GetFlightInfoSqlInfo is a base implementation of GetSqlInfo by using any
@@ -352,31 +661,18 @@ func (BaseServer) DoGetXdbcTypeInfo(context.Context, GetXdbcTypeInfo) (*arrow.Sc
 // if there is no sql info registered, otherwise a FlightInfo for retrieving
 // the Sql info.
 func (b *BaseServer) GetFlightInfoSqlInfo(_ context.Context, _ GetSqlInfo, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
-	if len(b.sqlInfoToResult) == 0 {
+	if len(b.registered.sqlInfoMap()) == 0 {
 		return nil, status.Error(codes.NotFound, "no sql information available")
 	}
 
-	if b.Alloc == nil {
-		b.Alloc = memory.DefaultAllocator
-	}
-
-	return &flight.FlightInfo{
-		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: desc.Cmd}}},
-		FlightDescriptor: desc,
-		TotalRecords:     -1,
-		TotalBytes:       -1,
-		Schema:           flight.SerializeSchema(schema_ref.SqlInfo, b.Alloc),
-	}, nil
+	return NewFlightInfo(desc, schema_ref.SqlInfo, b.allocator()), nil
 }
 
 // DoGetSqlInfo returns a flight stream containing the list of sqlinfo results
 func (b *BaseServer) DoGetSqlInfo(_ context.Context, cmd GetSqlInfo) (*arrow.Schema, <-chan flight.StreamChunk, error) {
-	if b.Alloc == nil {
-		b.Alloc = memory.DefaultAllocator
-	}
-
-	bldr := array.NewRecordBuilder(b.Alloc, schema_ref.SqlInfo)
-	defer bldr.Release()
+	mem := b.allocator()
+	bldr := b.builders.get(mem, schema_ref.SqlInfo)
+	defer b.builders.put(mem, schema_ref.SqlInfo, bldr)
 
 	nameFieldBldr := bldr.Field(0).(*array.Uint32Builder)
 	valFieldBldr := bldr.Field(1).(*array.DenseUnionBuilder)
@@ -386,6 +682,7 @@ func (b *BaseServer) DoGetSqlInfo(_ context.Context, cmd GetSqlInfo) (*arrow.Sch
 	sqlInfoResultBldr := newSqlInfoResultBuilder(valFieldBldr)
 
 	keys := cmd.GetInfo()
+	registered := b.registered.sqlInfoMap()
 
 	// populate both the nameFieldBldr and the values for each
 	// element on command.info.
@@ -394,7 +691,7 @@ func (b *BaseServer) DoGetSqlInfo(_ context.Context, cmd GetSqlInfo) (*arrow.Sch
 	// data type is handled by the sqlInfoResultBuilder.
 	if len(keys) > 0 {
 		for _, info := range keys {
-			val, ok := b.sqlInfoToResult[info]
+			val, ok := registered[info]
 			if !ok {
 				return nil, nil, status.Errorf(codes.NotFound, "no information for sql info number %d", info)
 			}
@@ -402,7 +699,7 @@ func (b *BaseServer) DoGetSqlInfo(_ context.Context, cmd GetSqlInfo) (*arrow.Sch
 			sqlInfoResultBldr.Append(val)
 		}
 	} else {
-		for k, v := range b.sqlInfoToResult {
+		for k, v := range registered {
 			nameFieldBldr.Append(k)
 			sqlInfoResultBldr.Append(v)
 		}
@@ -487,8 +784,17 @@ func (BaseServer) CreatePreparedSubstraitPlan(context.Context, ActionCreatePrepa
 	return res, status.Error(codes.Unimplemented, "CreatePreparedSubstraitPlan not implemented")
 }
 
-func (BaseServer) ClosePreparedStatement(context.Context, ActionClosePreparedStatementRequest) error {
-	return status.Error(codes.Unimplemented, "ClosePreparedStatement not implemented")
+// ClosePreparedStatement removes the statement from the cache given with
+// WithPreparedStatementCache, returning a NotFound error if it isn't in
+// it. Without a cache it is unimplemented.
+func (b *BaseServer) ClosePreparedStatement(_ context.Context, req ActionClosePreparedStatementRequest) error {
+	if b.preparedStatements == nil {
+		return status.Error(codes.Unimplemented, "ClosePreparedStatement not implemented")
+	}
+	if !b.preparedStatements.Delete(req.GetPreparedStatementHandle()) {
+		return status.Errorf(codes.NotFound, "prepared statement %x not found or expired", req.GetPreparedStatementHandle())
+	}
+	return nil
 }
 
 func (BaseServer) DoPutCommandStatementUpdate(context.Context, StatementUpdate) (int64, error) {
@@ -540,6 +846,10 @@ func (BaseServer) PollFlightInfoPreparedStatement(context.Context, PreparedState
 	return nil, status.Error(codes.Unimplemented, "PollFlightInfoPreparedStatement not implemented")
 }
 
+func (BaseServer) ExplainPreparedStatement(context.Context, PreparedStatementQuery) (string, error) {
+	return "", status.Error(codes.Unimplemented, "ExplainPreparedStatement not implemented")
+}
+
 func (BaseServer) EndTransaction(context.Context, ActionEndTransactionRequest) error {
 	return status.Error(codes.Unimplemented, "EndTransaction not implemented")
 }
@@ -574,7 +884,9 @@ func (BaseServer) CloseSession(context.Context, *flight.CloseSessionRequest) (*f
 // from the method, it should be populated within a goroutine to ensure
 // there are no deadlocks.
 type Server interface {
-	// GetFlightInfoStatement returns a FlightInfo for executing the requested sql query
+	// GetFlightInfoStatement returns a FlightInfo for executing the requested sql query.
+	// If the size of the result can be estimated, TotalRecords and TotalBytes
+	// should be set rather than TotalUnknown, see NewFlightInfo.
 	GetFlightInfoStatement(context.Context, StatementQuery, *flight.FlightDescriptor) (*flight.FlightInfo, error)
 	// GetFlightInfoSubstraitPlan returns a FlightInfo for executing the requested substrait plan
 	GetFlightInfoSubstraitPlan(context.Context, StatementSubstraitPlan, *flight.FlightDescriptor) (*flight.FlightInfo, error)
@@ -583,7 +895,8 @@ type Server interface {
 	// GetSchemaSubstraitPlan returns the schema of the result set for the requested substrait plan
 	GetSchemaSubstraitPlan(context.Context, StatementSubstraitPlan, *flight.FlightDescriptor) (*flight.SchemaResult, error)
 	// DoGetStatement returns a stream containing the query results for the
-	// requested statement handle that was populated by GetFlightInfoStatement
+	// requested statement handle that was populated by GetFlightInfoStatement.
+	// Large results should be produced lazily, see StreamFromSource.
 	DoGetStatement(context.Context, StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error)
 	// GetFlightInfoPreparedStatement returns a FlightInfo for executing an already
 	// prepared statement with the provided statement handle.
@@ -609,11 +922,14 @@ type Server interface {
 	DoGetSqlInfo(context.Context, GetSqlInfo) (*arrow.Schema, <-chan flight.StreamChunk, error)
 	// GetFlightInfoSchemas returns a FlightInfo for requesting a list of schemas
 	GetFlightInfoSchemas(context.Context, GetDBSchemas, *flight.FlightDescriptor) (*flight.FlightInfo, error)
-	// DoGetDBSchemas returns a stream containing the list of schemas
+	// DoGetDBSchemas returns a stream containing the list of schemas, see
+	// DBSchemasResultBuilder. A NULL catalog, as when the database has no
+	// catalogs, differs from an empty one, see DBSchemaInfo.
 	DoGetDBSchemas(context.Context, GetDBSchemas) (*arrow.Schema, <-chan flight.StreamChunk, error)
 	// GetFlightInfoTables returns a FlightInfo for listing the tables available
 	GetFlightInfoTables(context.Context, GetTables, *flight.FlightDescriptor) (*flight.FlightInfo, error)
-	// DoGetTables returns a stream containing the list of tables
+	// DoGetTables returns a stream containing the list of tables, see
+	// TablesResultBuilder and the NULL catalogs and schemas of DBSchemaInfo.
 	DoGetTables(context.Context, GetTables) (*arrow.Schema, <-chan flight.StreamChunk, error)
 	// GetFlightInfoTableTypes returns a FlightInfo for retrieving a list
 	// of table types supported
@@ -622,26 +938,33 @@ type Server interface {
 	DoGetTableTypes(context.Context) (*arrow.Schema, <-chan flight.StreamChunk, error)
 	// GetFlightInfoPrimaryKeys returns a FlightInfo for extracting information about primary keys
 	GetFlightInfoPrimaryKeys(context.Context, TableRef, *flight.FlightDescriptor) (*flight.FlightInfo, error)
-	// DoGetPrimaryKeys returns a stream containing the data related to primary keys
+	// DoGetPrimaryKeys returns a stream containing the data related to primary keys,
+	// see PrimaryKeysResultBuilder
 	DoGetPrimaryKeys(context.Context, TableRef) (*arrow.Schema, <-chan flight.StreamChunk, error)
 	// GetFlightInfoExportedKeys returns a FlightInfo for extracting information about foreign keys
 	GetFlightInfoExportedKeys(context.Context, TableRef, *flight.FlightDescriptor) (*flight.FlightInfo, error)
-	// DoGetExportedKeys returns a stream containing the data related to foreign keys
+	// DoGetExportedKeys returns a stream containing the data related to foreign keys,
+	// see ForeignKeysResultBuilder
 	DoGetExportedKeys(context.Context, TableRef) (*arrow.Schema, <-chan flight.StreamChunk, error)
 	// GetFlightInfoImportedKeys returns a FlightInfo for extracting information about imported keys
 	GetFlightInfoImportedKeys(context.Context, TableRef, *flight.FlightDescriptor) (*flight.FlightInfo, error)
-	// DoGetImportedKeys returns a stream containing the data related to imported keys
+	// DoGetImportedKeys returns a stream containing the data related to imported keys,
+	// see ForeignKeysResultBuilder
 	DoGetImportedKeys(context.Context, TableRef) (*arrow.Schema, <-chan flight.StreamChunk, error)
 	// GetFlightInfoCrossReference returns a FlightInfo for extracting data related
 	// to primary and foreign keys
 	GetFlightInfoCrossReference(context.Context, CrossTableRef, *flight.FlightDescriptor) (*flight.FlightInfo, error)
-	// DoGetCrossReference returns a stream of data related to foreign and primary keys
+	// DoGetCrossReference returns a stream of data related to foreign and primary keys,
+	// see ForeignKeysResultBuilder
 	DoGetCrossReference(context.Context, CrossTableRef) (*arrow.Schema, <-chan flight.StreamChunk, error)
 	// DoPutCommandStatementUpdate executes a sql update statement and returns
-	// the number of affected rows
+	// the number of affected rows, or UpdateResultUnknown if the number of
+	// affected rows can't be determined. The count is sent to the client as is.
 	DoPutCommandStatementUpdate(context.Context, StatementUpdate) (int64, error)
 	// DoPutCommandSubstraitPlan executes a substrait plan and returns the number
-	// of affected rows.
+	// of affected rows, or UpdateResultUnknown if it can't be determined.
+	// Servers supporting the bulk ingestion of Client.ExecuteIngest also
+	// implement StatementIngestServer.
 	DoPutCommandSubstraitPlan(context.Context, StatementSubstraitPlan) (int64, error)
 	// CreatePreparedStatement constructs a prepared statement from a sql query
 	// and returns an opaque statement handle for use.
@@ -662,11 +985,20 @@ type Server interface {
 	// Currently anything written to the writer will be ignored. It is in the
 	// interface for potential future enhancements to avoid having to change
 	// the interface in the future.
+	//
+	// A server which returns a new handle for the statement once its
+	// parameters are bound implements UpdatedHandleServer instead.
+	//
+	// Unless the parameters are coerced, see ParameterSchemaServer, the
+	// reader is a flight.MessageSource returning each message along with
+	// its app metadata and descriptor, including those carrying only app
+	// metadata, so that the metadata sent with each batch can be
+	// correlated with it.
 	DoPutPreparedStatementQuery(context.Context, PreparedStatementQuery, flight.MessageReader, flight.MetadataWriter) error
 	// DoPutPreparedStatementUpdate executes an update SQL Prepared statement
 	// for the specified statement handle. The reader allows providing a sequence
 	// of uploaded record batches to bind the parameters to. Returns the number
-	// of affected records.
+	// of affected records, or UpdateResultUnknown if it can't be determined.
 	DoPutPreparedStatementUpdate(context.Context, PreparedStatementUpdate, flight.MessageReader) (int64, error)
 	// BeginTransaction starts a new transaction and returns the id
 	BeginTransaction(context.Context, ActionBeginTransactionRequest) (id []byte, err error)
@@ -674,7 +1006,14 @@ type Server interface {
 	BeginSavepoint(context.Context, ActionBeginSavepointRequest) (id []byte, err error)
 	// EndSavepoint releases or rolls back a savepoint
 	EndSavepoint(context.Context, ActionEndSavepointRequest) error
-	// EndTransaction commits or rolls back a transaction
+	// EndTransaction commits or rolls back a transaction. A server which
+	// reports a result when a transaction ends, such as the id of a
+	// follow-on transaction or a commit LSN, can instead implement
+	//
+	//	EndTransactionWithResult(context.Context, ActionEndTransactionRequest) ([]byte, error)
+	//
+	// whose result is sent to the client as the body of the action's
+	// Result, see Txn.CommitWithResult.
 	EndTransaction(context.Context, ActionEndTransactionRequest) error
 	// CancelFlightInfo attempts to explicitly cancel a FlightInfo
 	CancelFlightInfo(context.Context, *flight.CancelFlightInfoRequest) (flight.CancelFlightInfoResult, error)
@@ -688,6 +1027,10 @@ type Server interface {
 	PollFlightInfoSubstraitPlan(context.Context, StatementSubstraitPlan, *flight.FlightDescriptor) (*flight.PollInfo, error)
 	// PollFlightInfoPreparedStatement handles polling for query execution.
 	PollFlightInfoPreparedStatement(context.Context, PreparedStatementQuery, *flight.FlightDescriptor) (*flight.PollInfo, error)
+	// ExplainPreparedStatement returns the query plan of a prepared
+	// statement for the ExplainPreparedStatement action, either as text
+	// or as JSON, which is returned to the client as is.
+	ExplainPreparedStatement(context.Context, PreparedStatementQuery) (string, error)
 	// SetSessionOptions sets option(s) for the current server session.
 	SetSessionOptions(context.Context, *flight.SetSessionOptionsRequest) (*flight.SetSessionOptionsResult, error)
 	// GetSessionOptions gets option(s) for the current server session.
@@ -699,9 +1042,13 @@ type Server interface {
 }
 
 // NewFlightServer constructs a FlightRPC server from the provided
-// FlightSQL Server so that it can be passed to RegisterFlightService.
-func NewFlightServer(srv Server) flight.FlightServer {
-	return &flightSqlServer{srv: srv, mem: memory.DefaultAllocator}
+// FlightSQL Server so that it can be passed to RegisterFlightService. The
+// options are those of NewFlightServerWithAllocator.
+//
+// Shutting down the flight server does not release the resources held by
+// srv: if it embeds BaseServer, the caller must call srv.Close afterwards.
+func NewFlightServer(srv Server, opts ...FlightServerOption) flight.FlightServer {
+	return NewFlightServerWithAllocator(srv, nil, opts...)
 }
 
 // NewFlightServerWithAllocator constructs a FlightRPC server from
@@ -709,34 +1056,144 @@ func NewFlightServer(srv Server) flight.FlightServer {
 // RegisterFlightService, setting the provided allocator into the server
 // for use with any allocations necessary by the routing.
 //
-// Will default to memory.DefaultAllocator if mem is nil
-func NewFlightServerWithAllocator(srv Server, mem memory.Allocator) flight.FlightServer {
+// Will default to memory.DefaultAllocator if mem is nil. The options
+// configure the routing, such as WithMaxConcurrentStreams or
+// WithServerTracing.
+func NewFlightServerWithAllocator(srv Server, mem memory.Allocator, opts ...FlightServerOption) flight.FlightServer {
 	if mem == nil {
 		mem = memory.DefaultAllocator
 	}
-	return &flightSqlServer{srv: srv, mem: mem}
+	if b, ok := srv.(interface{ initBaseServer() }); ok {
+		b.initBaseServer()
+	}
+	f := &flightSqlServer{srv: srv, mem: mem, started: time.Now()}
+	for _, o := range opts {
+		o(f)
+	}
+	if len(f.middleware) > 0 {
+		return &middlewareServer{FlightServer: f, middleware: f.middleware}
+	}
+	return f
 }
 
 // flightSqlServer is a wrapper around a FlightSQL server interface to
 // perform routing from FlightRPC to FlightSQL.
 type flightSqlServer struct {
 	flight.BaseFlightServer
-	mem memory.Allocator
-	srv Server
+	mem     memory.Allocator
+	srv     Server
+	started time.Time
+
+	// streams is the semaphore bounding concurrent DoGet and DoPut
+	// calls, nil if unlimited
+	streams    chan struct{}
+	streamWait time.Duration
+
+	// middleware is called around each call, see withServerMiddleware
+	middleware []flight.ServerMiddleware
+	// dictDeltas enables dictionary deltas in DoGet results
+	dictDeltas bool
+	// gzip compresses DoGet results with gRPC gzip compression
+	gzip bool
+	// grpcOpts are the options of the gRPC server the service is
+	// registered on, see WithGrpcServerOptions
+	grpcOpts []grpc.ServerOption
+	// statsHandler is given the stats of each DoGet result, if not nil
+	statsHandler StatsHandler
+	// maxPutBytes limits the records uploaded by each DoPut call if
+	// positive, see WithMaxPutBytes
+	maxPutBytes int64
+}
+
+// StatsHandler is called with the stats of the writer of each DoGet
+// result once it has been written, or once writing it failed, along with
+// the context of the call, see WithStatsHandler.
+type StatsHandler func(ctx context.Context, stats flight.WriterStats)
+
+// WithStatsHandler makes DoGet call h with the final stats of the writer
+// of each result, such as to report the number of bytes sent or the
+// compression ratio of the results as metrics. The results sent as
+// pre-serialized IPC streams aren't written by a writer, h not being
+// called for them.
+func WithStatsHandler(h StatsHandler) FlightServerOption {
+	return func(f *flightSqlServer) { f.statsHandler = h }
+}
+
+// WithGrpcServerOptions sets options of the gRPC server which the Flight
+// SQL service is registered on, such as grpc.MaxRecvMsgSize for the
+// clients ingesting large record batches with DoPut, or keepalive
+// enforcement. They are applied by the RegisterFlightService method of the
+// servers of flight.NewServerWithMiddleware, which must be called before
+// the server is otherwise used, overriding the options passed to the
+// constructor. Servers created otherwise can get them from the
+// flight.ServerOptionsProvider implemented by the service.
+func WithGrpcServerOptions(opts ...grpc.ServerOption) FlightServerOption {
+	return func(f *flightSqlServer) { f.grpcOpts = append(f.grpcOpts, opts...) }
+}
+
+// GrpcServerOptions implements flight.ServerOptionsProvider.
+func (f *flightSqlServer) GrpcServerOptions() []grpc.ServerOption { return f.grpcOpts }
+
+// WithDictionaryDeltas makes DoGet send only the values appended to the
+// dictionaries of dictionary-encoded columns since the previous record
+// of the result, as delta dictionary batches, rather than the whole
+// dictionaries again. Dictionaries which aren't extensions of those
+// already sent, as when they shrink or are rewritten, are still sent
+// whole as replacements.
+func WithDictionaryDeltas() FlightServerOption {
+	return func(f *flightSqlServer) { f.dictDeltas = true }
+}
+
+// WithGzipCompression makes DoGet compress its results with gRPC gzip
+// compression when the client advertises support for it, for clients
+// which don't support the compression of Arrow IPC buffers. Unlike the
+// latter, the whole messages are compressed by the gRPC transport, the
+// client decompressing them transparently. Other clients receive the
+// results uncompressed.
+func WithGzipCompression() FlightServerOption {
+	return func(f *flightSqlServer) { f.gzip = true }
+}
+
+// useGzip makes the messages sent on the stream of ctx compressed with
+// gzip if the client supports it.
+func useGzip(ctx context.Context) error {
+	names, err := grpc.ClientSupportedCompressors(ctx)
+	if err != nil {
+		// not a gRPC stream, such as in tests
+		return nil
+	}
+	for _, name := range names {
+		if name == gzip.Name {
+			return grpc.SetSendCompressor(ctx, gzip.Name)
+		}
+	}
+	return nil
 }
 
-func (f *flightSqlServer) GetFlightInfo(ctx context.Context, request *flight.FlightDescriptor) (*flight.FlightInfo, error) {
-	var (
-		anycmd anypb.Any
-		cmd    proto.Message
-		err    error
-	)
-	if err = proto.Unmarshal(request.Cmd, &anycmd); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "unable to parse command: %s", err.Error())
+// recoverHandler, deferred by the methods dispatching calls to the
+// handlers of a Server, turns a panic of a handler into an INTERNAL error
+// failing the call, so that a bug in a handler doesn't crash the whole
+// server. The panic value and stack are logged rather than sent to the
+// client, as they may reveal details of the server. Panics in goroutines
+// started by the handlers, such as those sending the chunks of a result,
+// can't be recovered.
+func recoverHandler(method string, err *error) {
+	r := recover()
+	if r == nil {
+		return
 	}
+	stack := make([]byte, 64<<10)
+	stack = stack[:runtime.Stack(stack, false)]
+	log.Printf("arrow/flightsql: panic in %s handler: %v\n%s", method, r, stack)
+	*err = status.Errorf(codes.Internal, "internal error handling %s", method)
+}
 
-	if cmd, err = anycmd.UnmarshalNew(); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "could not unmarshal Any to a command type: %s", err.Error())
+func (f *flightSqlServer) GetFlightInfo(ctx context.Context, request *flight.FlightDescriptor) (_ *flight.FlightInfo, err error) {
+	defer recoverHandler("GetFlightInfo", &err)
+
+	cmd, err := ParseCommand(request.Cmd)
+	if err != nil {
+		return nil, err
 	}
 
 	switch cmd := cmd.(type) {
@@ -768,7 +1225,7 @@ func (f *flightSqlServer) GetFlightInfo(ctx context.Context, request *flight.Fli
 		return f.srv.GetFlightInfoCrossReference(ctx, toCrossTableRef(cmd), request)
 	}
 
-	return nil, status.Error(codes.InvalidArgument, "requested command is invalid")
+	return nil, unsupportedCommand(cmd)
 }
 
 func (f *flightSqlServer) PollFlightInfo(ctx context.Context, request *flight.FlightDescriptor) (*flight.PollInfo, error) {
@@ -802,17 +1259,9 @@ func (f *flightSqlServer) PollFlightInfo(ctx context.Context, request *flight.Fl
 }
 
 func (f *flightSqlServer) GetSchema(ctx context.Context, request *flight.FlightDescriptor) (*flight.SchemaResult, error) {
-	var (
-		anycmd anypb.Any
-		cmd    proto.Message
-		err    error
-	)
-	if err = proto.Unmarshal(request.Cmd, &anycmd); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "unable to parse command: %s", err.Error())
-	}
-
-	if cmd, err = anycmd.UnmarshalNew(); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "could not unmarshal Any to a command type: %s", err.Error())
+	cmd, err := ParseCommand(request.Cmd)
+	if err != nil {
+		return nil, err
 	}
 
 	switch cmd := cmd.(type) {
@@ -847,24 +1296,44 @@ func (f *flightSqlServer) GetSchema(ctx context.Context, request *flight.FlightD
 		return &flight.SchemaResult{Schema: flight.SerializeSchema(schema_ref.CrossReference, f.mem)}, nil
 	}
 
-	return nil, status.Errorf(codes.InvalidArgument, "requested command is invalid: %s", anycmd.GetTypeUrl())
+	return nil, unsupportedCommand(cmd)
 }
 
 func (f *flightSqlServer) DoGet(request *flight.Ticket, stream flight.FlightService_DoGetServer) (err error) {
+	defer recoverHandler("DoGet", &err)
+
 	var (
 		anycmd anypb.Any
 		cmd    proto.Message
 		cc     <-chan flight.StreamChunk
 		sc     *arrow.Schema
 	)
+	release, err := f.acquireStream(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if f.gzip {
+		if err := useGzip(stream.Context()); err != nil {
+			return status.Errorf(codes.Internal, "unable to compress results: %s", err.Error())
+		}
+	}
+
 	if err = proto.Unmarshal(request.Ticket, &anycmd); err != nil {
 		return status.Errorf(codes.InvalidArgument, "unable to parse ticket: %s", err.Error())
 	}
 
-	if cmd, err = anycmd.UnmarshalNew(); err != nil {
+	if cmd, err = anycmd.UnmarshalNew(); errors.Is(err, protoregistry.NotFound) {
+		return &UnsupportedCommandError{TypeURL: anycmd.GetTypeUrl()}
+	} else if err != nil {
 		return status.Errorf(codes.InvalidArgument, "unable to unmarshal proto.Any: %s", err.Error())
 	}
 
+	if handled, err := f.doGetIPC(cmd, stream); handled || err != nil {
+		return err
+	}
+
 	switch cmd := cmd.(type) {
 	case *pb.TicketStatementQuery:
 		sc, cc, err = f.srv.DoGetStatement(stream.Context(), cmd)
@@ -891,29 +1360,57 @@ func (f *flightSqlServer) DoGet(request *flight.Ticket, stream flight.FlightServ
 	case *pb.CommandGetCrossReference:
 		sc, cc, err = f.srv.DoGetCrossReference(stream.Context(), toCrossTableRef(cmd))
 	default:
-		return status.Error(codes.InvalidArgument, "requested command is invalid")
+		return unsupportedCommand(cmd)
 	}
 
 	if err != nil {
 		return err
 	}
 
-	wr := flight.NewRecordWriter(stream, ipc.WithSchema(sc))
+	wr := flight.NewRecordWriter(stream, ipc.WithSchema(sc), ipc.WithDictionaryDeltas(f.dictDeltas))
+	if f.statsHandler != nil {
+		defer func() { f.statsHandler(stream.Context(), wr.Stats()) }()
+	}
 	defer wr.Close()
 
 	for chunk := range cc {
-		if chunk.Err != nil {
-			return chunk.Err
-		}
-
-		wr.SetFlightDescriptor(chunk.Desc)
-		if err = wr.WriteWithAppMetadata(chunk.Data, chunk.AppMetadata); err != nil {
+		if err = writeChunk(wr, chunk); err != nil {
+			// the producer stops once the stream context is cancelled, as
+			// on flight.Server.GracefulShutdown, but the chunks it is
+			// already sending must still be received to be released
+			go releaseChunks(cc)
 			return err
 		}
-		chunk.Data.Release()
 	}
 
-	return err
+	// closing the writer sends the schema if no record was written, so
+	// that an empty result still has one
+	return wr.Close()
+}
+
+// writeChunk writes chunk to wr, releasing its record, or returns the
+// error of the chunk.
+func writeChunk(wr *flight.Writer, chunk flight.StreamChunk) error {
+	if chunk.Err != nil {
+		return chunk.Err
+	}
+
+	wr.SetFlightDescriptor(chunk.Desc)
+	if chunk.Data == nil {
+		return wr.WriteMetadata(chunk.AppMetadata)
+	}
+	defer chunk.Data.Release()
+	return wr.WriteWithAppMetadata(chunk.Data, chunk.AppMetadata)
+}
+
+// releaseChunks receives the remaining chunks of ch, releasing their
+// records.
+func releaseChunks(ch <-chan flight.StreamChunk) {
+	for chunk := range ch {
+		if chunk.Data != nil {
+			chunk.Data.Release()
+		}
+	}
 }
 
 type putMetadataWriter struct {
@@ -924,12 +1421,33 @@ func (p *putMetadataWriter) WriteMetadata(appMetadata []byte) error {
 	return p.stream.Send(&flight.PutResult{AppMetadata: appMetadata})
 }
 
-func (f *flightSqlServer) DoPut(stream flight.FlightService_DoPutServer) error {
-	rdr, err := flight.NewRecordReader(stream, ipc.WithAllocator(f.mem), ipc.WithDelayReadSchema(true))
+func (f *flightSqlServer) DoPut(stream flight.FlightService_DoPutServer) (err error) {
+	defer recoverHandler("DoPut", &err)
+
+	release, err := f.acquireStream(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	in, err := flight.NewRecordReader(stream, ipc.WithAllocator(f.mem), ipc.WithDelayReadSchema(true))
 	if err != nil {
 		return status.Errorf(codes.InvalidArgument, "failed to read input stream: %s", err.Error())
 	}
-	defer rdr.Release()
+	defer in.Release()
+
+	var rdr flight.MessageReader = in
+	if f.maxPutBytes > 0 {
+		limited := newLimitedReader(in, f.maxPutBytes)
+		defer limited.Release()
+		// the handler may not fail when its records are cut short
+		defer func() {
+			if limited.err != nil {
+				err = limited.err
+			}
+		}()
+		rdr, stream = limited, &limitedPutStream{FlightService_DoPutServer: stream, rdr: limited}
+	}
 
 	// flight descriptor should have come with the schema message
 	request := rdr.LatestFlightDescriptor()
@@ -942,7 +1460,13 @@ func (f *flightSqlServer) DoPut(stream flight.FlightService_DoPutServer) error {
 		return status.Errorf(codes.InvalidArgument, "unable to parse command: %s", err.Error())
 	}
 
-	if cmd, err = anycmd.UnmarshalNew(); err != nil {
+	if anycmd.GetTypeUrl() == statementIngestTypeURL {
+		return f.doPutStatementIngest(stream, anycmd.GetValue(), rdr)
+	}
+
+	if cmd, err = anycmd.UnmarshalNew(); errors.Is(err, protoregistry.NotFound) {
+		return &UnsupportedCommandError{TypeURL: anycmd.GetTypeUrl()}
+	} else if err != nil {
 		return status.Errorf(codes.InvalidArgument, "could not unmarshal google.protobuf.Any: %s", err.Error())
 	}
 
@@ -953,40 +1477,47 @@ func (f *flightSqlServer) DoPut(stream flight.FlightService_DoPutServer) error {
 			return err
 		}
 
-		result := pb.DoPutUpdateResult{RecordCount: recordCount}
-		out := &flight.PutResult{}
-		if out.AppMetadata, err = proto.Marshal(&result); err != nil {
-			return status.Errorf(codes.Internal, "failed to marshal PutResult: %s", err.Error())
-		}
-		return stream.Send(out)
+		return sendUpdateResult(stream, recordCount)
 	case *pb.CommandStatementSubstraitPlan:
 		recordCount, err := f.srv.DoPutCommandSubstraitPlan(stream.Context(), &statementSubstraitPlan{cmd})
 		if err != nil {
 			return err
 		}
 
-		result := pb.DoPutUpdateResult{RecordCount: recordCount}
-		out := &flight.PutResult{}
-		if out.AppMetadata, err = proto.Marshal(&result); err != nil {
-			return status.Errorf(codes.Internal, "failed to marshal PutResult: %s", err.Error())
-		}
-		return stream.Send(out)
+		return sendUpdateResult(stream, recordCount)
 	case *pb.CommandPreparedStatementQuery:
-		return f.srv.DoPutPreparedStatementQuery(stream.Context(), cmd, rdr, &putMetadataWriter{stream})
+		params, err := f.parameterReader(stream.Context(), cmd.GetPreparedStatementHandle(), rdr)
+		if err != nil {
+			return err
+		}
+		defer params.Release()
+
+		srv, ok := f.srv.(UpdatedHandleServer)
+		if !ok {
+			return f.srv.DoPutPreparedStatementQuery(stream.Context(), cmd, params, &putMetadataWriter{stream})
+		}
+
+		handle, err := srv.DoPutPreparedStatementQueryWithHandle(stream.Context(), cmd, params, &putMetadataWriter{stream})
+		if err != nil || handle == nil {
+			return err
+		}
+
+		return stream.Send(&flight.PutResult{AppMetadata: marshalPreparedStatementResult(handle)})
 	case *pb.CommandPreparedStatementUpdate:
-		recordCount, err := f.srv.DoPutPreparedStatementUpdate(stream.Context(), cmd, rdr)
+		params, err := f.parameterReader(stream.Context(), cmd.GetPreparedStatementHandle(), rdr)
 		if err != nil {
 			return err
 		}
+		defer params.Release()
 
-		result := pb.DoPutUpdateResult{RecordCount: recordCount}
-		out := &flight.PutResult{}
-		if out.AppMetadata, err = proto.Marshal(&result); err != nil {
-			return status.Errorf(codes.Internal, "failed to marshal PutResult: %s", err.Error())
+		recordCount, err := f.srv.DoPutPreparedStatementUpdate(stream.Context(), cmd, params)
+		if err != nil {
+			return err
 		}
-		return stream.Send(out)
+
+		return sendUpdateResult(stream, recordCount)
 	default:
-		return status.Error(codes.InvalidArgument, "the defined request is invalid")
+		return unsupportedCommand(cmd)
 	}
 }
 
@@ -1002,6 +1533,8 @@ func (f *flightSqlServer) ListActions(_ *flight.Empty, stream flight.FlightServi
 		CreatePreparedSubstraitPlanActionType,
 		EndSavepointActionType,
 		EndTransactionActionType,
+		HealthCheckActionType,
+		ExplainPreparedStatementActionType,
 	}
 
 	for _, a := range actions {
@@ -1027,10 +1560,23 @@ func cancelStatusToCancelResult(status flight.CancelStatus) CancelResult {
 	}
 }
 
-func (f *flightSqlServer) DoAction(cmd *flight.Action, stream flight.FlightService_DoActionServer) error {
+func (f *flightSqlServer) DoAction(cmd *flight.Action, stream flight.FlightService_DoActionServer) (err error) {
+	defer recoverHandler("DoAction", &err)
+
 	var anycmd anypb.Any
 
 	switch cmd.Type {
+	case HealthCheckActionType:
+		result, err := f.healthCheck(stream.Context())
+		if err != nil {
+			return err
+		}
+
+		out := &pb.Result{}
+		if out.Body, err = result.marshal(); err != nil {
+			return status.Errorf(codes.Internal, "failed to marshal HealthCheck result: %s", err.Error())
+		}
+		return stream.Send(out)
 	case flight.CancelFlightInfoActionType:
 		var (
 			request flight.CancelFlightInfoRequest
@@ -1235,6 +1781,22 @@ func (f *flightSqlServer) DoAction(cmd *flight.Action, stream flight.FlightServi
 			return status.Errorf(codes.Internal, "unable to marshal result: %s", err.Error())
 		}
 		return stream.Send(&ret)
+	case ExplainPreparedStatementActionType:
+		if err := proto.Unmarshal(cmd.Body, &anycmd); err != nil {
+			return status.Errorf(codes.InvalidArgument, "unable to parse command: %s", err.Error())
+		}
+
+		var request pb.CommandPreparedStatementQuery
+		if err := anycmd.UnmarshalTo(&request); err != nil {
+			return status.Errorf(codes.InvalidArgument, "unable to unmarshal google.protobuf.Any: %s", err.Error())
+		}
+
+		plan, err := f.srv.ExplainPreparedStatement(stream.Context(), &request)
+		if err != nil {
+			return err
+		}
+
+		return stream.Send(&pb.Result{Body: []byte(plan)})
 	case ClosePreparedStatementActionType:
 		if err := proto.Unmarshal(cmd.Body, &anycmd); err != nil {
 			return status.Errorf(codes.InvalidArgument, "unable to parse command: %s", err.Error())
@@ -1260,6 +1822,14 @@ func (f *flightSqlServer) DoAction(cmd *flight.Action, stream flight.FlightServi
 			return status.Errorf(codes.InvalidArgument, "unable to unmarshal google.protobuf.Any: %s", err.Error())
 		}
 
+		if end, ok := f.srv.(endTransactionResultServer); ok {
+			body, err := end.EndTransactionWithResult(stream.Context(), &request)
+			if err != nil {
+				return err
+			}
+			return stream.Send(&pb.Result{Body: body})
+		}
+
 		if err := f.srv.EndTransaction(stream.Context(), &request); err != nil {
 			return err
 		}
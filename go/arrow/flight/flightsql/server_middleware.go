@@ -0,0 +1,162 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// withServerMiddleware applies mw to the calls handled by the FlightRPC
+// server, as if it was given to flight.NewServerWithMiddleware. Middleware
+// given first is called first.
+func withServerMiddleware(mw flight.ServerMiddleware) FlightServerOption {
+	return func(f *flightSqlServer) { f.middleware = append(f.middleware, mw) }
+}
+
+// middlewareServer calls the middleware of a flightSqlServer around each
+// of its methods, emulating the gRPC server so that the middleware sees
+// the same calls it would as gRPC interceptors.
+type middlewareServer struct {
+	flight.FlightServer
+	middleware []flight.ServerMiddleware
+}
+
+// GrpcServerOptions implements flight.ServerOptionsProvider for the
+// wrapped server.
+func (m *middlewareServer) GrpcServerOptions() []grpc.ServerOption {
+	if p, ok := m.FlightServer.(flight.ServerOptionsProvider); ok {
+		return p.GrpcServerOptions()
+	}
+	return nil
+}
+
+func fullMethod(name string) string {
+	return "/" + pb.FlightService_ServiceDesc.ServiceName + "/" + name
+}
+
+func (m *middlewareServer) unary(ctx context.Context, name string, req interface{}, call func(context.Context) (interface{}, error)) (interface{}, error) {
+	info := &grpc.UnaryServerInfo{Server: m.FlightServer, FullMethod: fullMethod(name)}
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) { return call(ctx) }
+	for i := len(m.middleware) - 1; i >= 0; i-- {
+		if mw, next := m.middleware[i].Unary, handler; mw != nil {
+			handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return mw(ctx, req, info, next)
+			}
+		}
+	}
+	return handler(ctx, req)
+}
+
+// stream handles a streaming call with the gRPC handler of the method,
+// which receives req as the first message of the stream if not nil.
+func (m *middlewareServer) stream(name string, req proto.Message, stream grpc.ServerStream) error {
+	var (
+		handler grpc.StreamHandler
+		info    = &grpc.StreamServerInfo{FullMethod: fullMethod(name)}
+	)
+	for _, desc := range pb.FlightService_ServiceDesc.Streams {
+		if desc.StreamName == name {
+			handler = desc.Handler
+			info.IsClientStream, info.IsServerStream = desc.ClientStreams, desc.ServerStreams
+		}
+	}
+	if req != nil {
+		stream = &receivedStream{ServerStream: stream, req: req}
+	}
+
+	for i := len(m.middleware) - 1; i >= 0; i-- {
+		if mw, next := m.middleware[i].Stream, handler; mw != nil {
+			handler = func(srv interface{}, stream grpc.ServerStream) error {
+				return mw(srv, stream, info, next)
+			}
+		}
+	}
+	return handler(m.FlightServer, stream)
+}
+
+// receivedStream is the stream of a call whose request was already
+// received by gRPC, which it receives again for the gRPC handler.
+type receivedStream struct {
+	grpc.ServerStream
+	req proto.Message
+}
+
+func (s *receivedStream) RecvMsg(m interface{}) error {
+	if s.req == nil {
+		return s.ServerStream.RecvMsg(m)
+	}
+	proto.Merge(m.(proto.Message), s.req)
+	s.req = nil
+	return nil
+}
+
+func (m *middlewareServer) Handshake(stream flight.FlightService_HandshakeServer) error {
+	return m.stream("Handshake", nil, stream)
+}
+
+func (m *middlewareServer) ListFlights(req *flight.Criteria, stream flight.FlightService_ListFlightsServer) error {
+	return m.stream("ListFlights", req, stream)
+}
+
+func (m *middlewareServer) GetFlightInfo(ctx context.Context, req *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	resp, err := m.unary(ctx, "GetFlightInfo", req, func(ctx context.Context) (interface{}, error) {
+		return m.FlightServer.GetFlightInfo(ctx, req)
+	})
+	info, _ := resp.(*flight.FlightInfo)
+	return info, err
+}
+
+func (m *middlewareServer) PollFlightInfo(ctx context.Context, req *flight.FlightDescriptor) (*flight.PollInfo, error) {
+	resp, err := m.unary(ctx, "PollFlightInfo", req, func(ctx context.Context) (interface{}, error) {
+		return m.FlightServer.PollFlightInfo(ctx, req)
+	})
+	info, _ := resp.(*flight.PollInfo)
+	return info, err
+}
+
+func (m *middlewareServer) GetSchema(ctx context.Context, req *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	resp, err := m.unary(ctx, "GetSchema", req, func(ctx context.Context) (interface{}, error) {
+		return m.FlightServer.GetSchema(ctx, req)
+	})
+	result, _ := resp.(*flight.SchemaResult)
+	return result, err
+}
+
+func (m *middlewareServer) DoGet(req *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	return m.stream("DoGet", req, stream)
+}
+
+func (m *middlewareServer) DoPut(stream flight.FlightService_DoPutServer) error {
+	return m.stream("DoPut", nil, stream)
+}
+
+func (m *middlewareServer) DoExchange(stream flight.FlightService_DoExchangeServer) error {
+	return m.stream("DoExchange", nil, stream)
+}
+
+func (m *middlewareServer) DoAction(req *flight.Action, stream flight.FlightService_DoActionServer) error {
+	return m.stream("DoAction", req, stream)
+}
+
+func (m *middlewareServer) ListActions(req *flight.Empty, stream flight.FlightService_ListActionsServer) error {
+	return m.stream("ListActions", req, stream)
+}
@@ -17,27 +17,56 @@
 package flightsql_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/apache/arrow/go/v16/arrow"
 	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/decimal256"
 	"github.com/apache/arrow/go/v16/arrow/flight"
 	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/flightsqltest"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/schema_ref"
 	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
 	"github.com/apache/arrow/go/v16/arrow/flight/session"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
 	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/apache/arrow/go/v16/arrow/scalar"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 var dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
@@ -889,3 +918,4970 @@ func TestStatelessServerSessionCookies(t *testing.T) {
 	require.Len(t, trailer.Get("set-cookie"), 1)
 	require.Equal(t, "arrow_flight_session=; Max-Age=0", trailer.Get("set-cookie")[0])
 }
+
+// affinityMiddleware pins each client to the node which first served it
+// with a cookie, recording the affinity cookie each call arrived with.
+type affinityMiddleware struct {
+	node string
+
+	mx   sync.Mutex
+	seen []string
+}
+
+func (m *affinityMiddleware) StartCall(ctx context.Context) context.Context {
+	c, err := session.GetIncomingCookieByName(ctx, "node")
+	m.mx.Lock()
+	m.seen = append(m.seen, c.Value)
+	m.mx.Unlock()
+	if err == http.ErrNoCookie {
+		if err := session.SetOutgoingCookie(ctx, http.Cookie{Name: "node", Value: m.node}); err != nil {
+			panic(err)
+		}
+	}
+	return ctx
+}
+
+func (m *affinityMiddleware) CallCompleted(context.Context, error) {}
+
+func TestSessionAffinityCookies(t *testing.T) {
+	startNode := func(name string) (*affinityMiddleware, string) {
+		affinity := &affinityMiddleware{node: name}
+		srv := flight.NewServerWithMiddleware([]flight.ServerMiddleware{
+			flight.CreateServerMiddleware(affinity),
+			flight.CreateServerMiddleware(session.NewServerSessionMiddleware(nil)),
+		})
+		srv.RegisterFlightService(flightsql.NewFlightServer(&testServer{}))
+		srv.Init("localhost:0")
+
+		go srv.Serve()
+		t.Cleanup(srv.Shutdown)
+		return affinity, srv.Addr().String()
+	}
+	nodeA, addrA := startNode("a")
+	nodeB, addrB := startNode("b")
+
+	// one cookie jar shared by the connections to both nodes
+	cookies := flight.NewClientCookieMiddleware()
+	clientA, err := flightsql.NewClient(addrA, nil, []flight.ClientMiddleware{cookies}, dialOpts...)
+	require.NoError(t, err)
+	defer clientA.Close()
+	clientB, err := flightsql.NewClient(addrB, nil, []flight.ClientMiddleware{cookies}, dialOpts...)
+	require.NoError(t, err)
+	defer clientB.Close()
+
+	ctx := context.Background()
+	optionVals, err := flight.NewSessionOptionValues(map[string]any{"hello": "world"})
+	require.NoError(t, err)
+
+	_, err = clientA.SetSessionOptions(ctx, &flight.SetSessionOptionsRequest{SessionOptions: optionVals})
+	require.NoError(t, err)
+
+	// the second call carries the cookies set by the first
+	res, err := clientA.GetSessionOptions(ctx, &flight.GetSessionOptionsRequest{})
+	require.NoError(t, err)
+	require.Contains(t, res.GetSessionOptions(), "hello")
+	assert.Equal(t, []string{"", "a"}, nodeA.seen)
+
+	// but not those of the other node
+	res, err = clientB.GetSessionOptions(ctx, &flight.GetSessionOptionsRequest{})
+	require.NoError(t, err)
+	require.Empty(t, res.GetSessionOptions())
+	_, err = clientB.GetSessionOptions(ctx, &flight.GetSessionOptionsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "b"}, nodeB.seen)
+
+	// concurrent calls share the session
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := clientA.GetSessionOptions(ctx, &flight.GetSessionOptionsRequest{})
+			if assert.NoError(t, err) {
+				assert.Contains(t, res.GetSessionOptions(), "hello")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+const coercionInsertQuery = "INSERT INTO t (id, name) VALUES (?, ?)"
+
+// the parameter schemas of the statements understood by the coercion
+// test server, keyed by query
+var coercionParamSchemas = map[string]*arrow.Schema{
+	coercionInsertQuery: arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+	}, nil),
+	"INSERT INTO t (tags) VALUES (?)": arrow.NewSchema([]arrow.Field{
+		{Name: "tags", Type: arrow.ListOfField(arrow.Field{Name: "item", Type: arrow.PrimitiveTypes.Int32}), Nullable: true},
+	}, nil),
+	"INSERT INTO t (point) VALUES (?)": arrow.NewSchema([]arrow.Field{
+		{Name: "point", Type: arrow.StructOf(
+			arrow.Field{Name: "x", Type: arrow.PrimitiveTypes.Int32},
+			arrow.Field{Name: "y", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		), Nullable: true},
+	}, nil),
+	"INSERT INTO t (id, attrs) VALUES (?, ?)": arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "attrs", Type: nonNullableItemsMap},
+	}, nil),
+}
+
+var nonNullableItemsMap = func() *arrow.MapType {
+	mt := arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int32)
+	mt.SetItemNullable(false)
+	return mt
+}()
+
+type coercionTestServer struct {
+	flightsql.BaseServer
+}
+
+func (*coercionTestServer) CreatePreparedStatement(ctx context.Context, req flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	return flightsql.ActionCreatePreparedStatementResult{
+		Handle:          []byte(req.GetQuery()),
+		ParameterSchema: coercionParamSchemas[req.GetQuery()],
+	}, nil
+}
+
+func (*coercionTestServer) ClosePreparedStatement(context.Context, flightsql.ActionClosePreparedStatementRequest) error {
+	return nil
+}
+
+func (*coercionTestServer) PreparedStatementParameterSchema(_ context.Context, handle []byte) (*arrow.Schema, error) {
+	return coercionParamSchemas[string(handle)], nil
+}
+
+func (*coercionTestServer) DoPutPreparedStatementUpdate(ctx context.Context, cmd flightsql.PreparedStatementUpdate, rdr flight.MessageReader) (int64, error) {
+	expected := coercionParamSchemas[string(cmd.GetPreparedStatementHandle())]
+	if !rdr.Schema().Equal(expected) {
+		return 0, status.Errorf(codes.Internal, "unexpected parameter schema: %s", rdr.Schema())
+	}
+
+	var n int64
+	for rdr.Next() {
+		rec := rdr.Record()
+		if !rec.Schema().Equal(expected) {
+			return 0, status.Errorf(codes.Internal, "unexpected record schema: %s", rec.Schema())
+		}
+		n += rec.NumRows()
+	}
+	return n, rdr.Err()
+}
+
+type FlightSqlParameterCoercionSuite struct {
+	suite.Suite
+
+	s  flight.Server
+	cl *flightsql.Client
+}
+
+func (s *FlightSqlParameterCoercionSuite) SetupSuite() {
+	s.s = flight.NewServerWithMiddleware(nil)
+	s.s.RegisterFlightService(flightsql.NewFlightServer(&coercionTestServer{}))
+	s.Require().NoError(s.s.Init("localhost:0"))
+
+	go s.s.Serve()
+}
+
+func (s *FlightSqlParameterCoercionSuite) TearDownSuite() {
+	s.s.Shutdown()
+}
+
+func (s *FlightSqlParameterCoercionSuite) SetupTest() {
+	cl, err := flightsql.NewClient(s.s.Addr().String(), nil, nil, dialOpts...)
+	s.Require().NoError(err)
+	s.cl = cl
+}
+
+func (s *FlightSqlParameterCoercionSuite) TearDownTest() {
+	s.Require().NoError(s.cl.Close())
+	s.cl = nil
+}
+
+func (s *FlightSqlParameterCoercionSuite) executeWithParams(schema *arrow.Schema, data string) (int64, error) {
+	return s.execute(coercionInsertQuery, schema, data)
+}
+
+func (s *FlightSqlParameterCoercionSuite) execute(query string, schema *arrow.Schema, data string) (int64, error) {
+	ctx := context.Background()
+	prep, err := s.cl.Prepare(ctx, query)
+	s.Require().NoError(err)
+	defer prep.Close(ctx)
+
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(data))
+	s.Require().NoError(err)
+	defer rec.Release()
+
+	prep.SetParameters(rec)
+	return prep.ExecuteUpdate(ctx)
+}
+
+func (s *FlightSqlParameterCoercionSuite) TestNullabilityMismatch() {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	n, err := s.executeWithParams(schema, `[{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]`)
+	s.Require().NoError(err)
+	s.EqualValues(2, n)
+}
+
+func (s *FlightSqlParameterCoercionSuite) TestIntegerWidening() {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	n, err := s.executeWithParams(schema, `[{"id": 1, "name": "a"}]`)
+	s.Require().NoError(err)
+	s.EqualValues(1, n)
+}
+
+func (s *FlightSqlParameterCoercionSuite) TestLossyDowncast() {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	_, err := s.executeWithParams(schema, `[{"id": 1, "name": "a"}, {"id": 8589934592, "name": "b"}]`)
+	s.Require().Error(err)
+	s.Equal(codes.InvalidArgument, status.Code(err))
+	s.Contains(err.Error(), "cannot coerce parameter 0 (id) from int64 to int32")
+}
+
+func (s *FlightSqlParameterCoercionSuite) TestNullInNonNullableParameter() {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	_, err := s.executeWithParams(schema, `[{"id": null, "name": "a"}]`)
+	s.Require().Error(err)
+	s.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func (s *FlightSqlParameterCoercionSuite) TestIncompatibleType() {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	_, err := s.executeWithParams(schema, `[{"id": "1", "name": "a"}]`)
+	s.Require().Error(err)
+	s.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func (s *FlightSqlParameterCoercionSuite) TestNestedNullabilityMismatch() {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "tags", Type: arrow.ListOfField(arrow.Field{Name: "item", Type: arrow.PrimitiveTypes.Int32, Nullable: true}), Nullable: true},
+	}, nil)
+
+	n, err := s.execute("INSERT INTO t (tags) VALUES (?)", schema, `[{"tags": [1, 2]}, {"tags": []}]`)
+	s.Require().NoError(err)
+	s.EqualValues(2, n)
+}
+
+func (s *FlightSqlParameterCoercionSuite) TestNullInNonNullableListElement() {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "tags", Type: arrow.ListOfField(arrow.Field{Name: "item", Type: arrow.PrimitiveTypes.Int32, Nullable: true}), Nullable: true},
+	}, nil)
+
+	// a null list has no elements to check
+	n, err := s.execute("INSERT INTO t (tags) VALUES (?)", schema, `[{"tags": [1]}, {"tags": null}]`)
+	s.Require().NoError(err)
+	s.EqualValues(2, n)
+
+	_, err = s.execute("INSERT INTO t (tags) VALUES (?)", schema, `[{"tags": [1]}, {"tags": [2, null]}]`)
+	s.Equal(codes.InvalidArgument, status.Code(err))
+	s.ErrorContains(err, "field tags.item is not nullable but 1 null values were provided")
+}
+
+func (s *FlightSqlParameterCoercionSuite) TestNullInNonNullableStructField() {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "point", Type: arrow.StructOf(
+			arrow.Field{Name: "x", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+			arrow.Field{Name: "y", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		), Nullable: true},
+	}, nil)
+
+	// the fields of a null struct are not checked
+	n, err := s.execute("INSERT INTO t (point) VALUES (?)", schema, `[{"point": {"x": 1, "y": null}}, {"point": null}]`)
+	s.Require().NoError(err)
+	s.EqualValues(2, n)
+
+	_, err = s.execute("INSERT INTO t (point) VALUES (?)", schema, `[{"point": {"x": null, "y": 1}}]`)
+	s.Equal(codes.InvalidArgument, status.Code(err))
+	s.ErrorContains(err, "field point.x is not nullable")
+}
+
+func (s *FlightSqlParameterCoercionSuite) TestNullInNonNullableMapValue() {
+	// the builders do not append nulls to non-nullable items, unlike
+	// other implementations, so the values are built as nullable
+	mb := array.NewMapBuilder(memory.DefaultAllocator, arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int32, false)
+	defer mb.Release()
+	mb.Append(true)
+	mb.KeyBuilder().(*array.StringBuilder).AppendValues([]string{"a", "b"}, nil)
+	mb.ItemBuilder().(*array.Int32Builder).AppendValues([]int32{1, 0}, []bool{true, false})
+	built := mb.NewArray()
+	defer built.Release()
+
+	data, entries := built.Data(), built.Data().Children()[0]
+	entries = array.NewData(nonNullableItemsMap.Elem(), entries.Len(), entries.Buffers(), entries.Children(), entries.NullN(), entries.Offset())
+	defer entries.Release()
+	data = array.NewData(nonNullableItemsMap, data.Len(), data.Buffers(), []arrow.ArrayData{entries}, data.NullN(), data.Offset())
+	defer data.Release()
+	col := array.MakeFromData(data)
+	defer col.Release()
+
+	// the id is coerced for its nullability, which has the map checked
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "attrs", Type: nonNullableItemsMap},
+	}, nil)
+	ids := array.NewInt32Builder(memory.DefaultAllocator)
+	defer ids.Release()
+	ids.Append(1)
+	id := ids.NewArray()
+	defer id.Release()
+	rec := array.NewRecord(schema, []arrow.Array{id, col}, 1)
+	defer rec.Release()
+
+	ctx := context.Background()
+	prep, err := s.cl.Prepare(ctx, "INSERT INTO t (id, attrs) VALUES (?, ?)")
+	s.Require().NoError(err)
+	defer prep.Close(ctx)
+
+	prep.SetParameters(rec)
+	_, err = prep.ExecuteUpdate(ctx)
+	s.Equal(codes.InvalidArgument, status.Code(err))
+	s.ErrorContains(err, "field attrs.entries.value is not nullable")
+}
+
+func TestParameterCoercion(t *testing.T) {
+	suite.Run(t, new(FlightSqlParameterCoercionSuite))
+}
+
+type FlightSqlServerXdbcTypeInfoSuite struct {
+	suite.Suite
+
+	mem *memory.CheckedAllocator
+	srv *testServer
+	s   flight.Server
+	cl  *flightsql.Client
+}
+
+func (s *FlightSqlServerXdbcTypeInfoSuite) SetupSuite() {
+	s.mem = memory.NewCheckedAllocator(memory.DefaultAllocator)
+	s.srv = &testServer{}
+	s.srv.Alloc = s.mem
+
+	length := []string{"length"}
+	s.srv.RegisterXdbcTypeInfo(
+		flightsql.XdbcTypeInfoRow{TypeName: "bit", DataType: flightsql.XdbcBit, SqlDataType: flightsql.XdbcBit,
+			Nullable: flightsql.NullabilityNullable, Searchable: flightsql.SearchableFull},
+		flightsql.XdbcTypeInfoRow{TypeName: "integer", DataType: flightsql.XdbcInteger, SqlDataType: flightsql.XdbcInteger,
+			Nullable: flightsql.NullabilityNullable, Searchable: flightsql.SearchableFull},
+		flightsql.XdbcTypeInfoRow{TypeName: "bigint", DataType: flightsql.XdbcBigInt, SqlDataType: flightsql.XdbcBigInt,
+			Nullable: flightsql.NullabilityNullable, Searchable: flightsql.SearchableFull},
+		flightsql.XdbcTypeInfoRow{TypeName: "varchar", DataType: flightsql.XdbcVarchar, SqlDataType: flightsql.XdbcVarchar,
+			CreateParams: length, Nullable: flightsql.NullabilityNullable, CaseSensitive: true,
+			Searchable: flightsql.SearchableFull},
+		flightsql.XdbcTypeInfoRow{TypeName: "date", DataType: flightsql.XdbcDate, SqlDataType: flightsql.XdbcDate,
+			Nullable: flightsql.NullabilityNullable, Searchable: flightsql.SearchableFull},
+	)
+
+	s.s = flight.NewServerWithMiddleware(nil)
+	s.s.RegisterFlightService(flightsql.NewFlightServerWithAllocator(s.srv, s.mem))
+	s.Require().NoError(s.s.Init("localhost:0"))
+
+	go s.s.Serve()
+}
+
+func (s *FlightSqlServerXdbcTypeInfoSuite) TearDownSuite() {
+	s.s.Shutdown()
+	s.Require().NoError(s.srv.Close())
+	s.mem.AssertSize(s.T(), 0)
+}
+
+func (s *FlightSqlServerXdbcTypeInfoSuite) SetupTest() {
+	cl, err := flightsql.NewClient(s.s.Addr().String(), nil, nil, dialOpts...)
+	s.Require().NoError(err)
+	s.cl = cl
+}
+
+func (s *FlightSqlServerXdbcTypeInfoSuite) TearDownTest() {
+	s.Require().NoError(s.cl.Close())
+	s.cl = nil
+}
+
+func (s *FlightSqlServerXdbcTypeInfoSuite) getTypeInfo(dataType *int32) []string {
+	ctx := context.Background()
+	info, err := s.cl.GetXdbcTypeInfo(ctx, dataType)
+	s.Require().NoError(err)
+	s.Require().Len(info.GetEndpoint(), 1)
+
+	rdr, err := s.cl.DoGet(ctx, info.GetEndpoint()[0].GetTicket())
+	s.Require().NoError(err)
+	defer rdr.Release()
+
+	s.True(rdr.Schema().Equal(schema_ref.XdbcTypeInfo))
+	var names []string
+	for rdr.Next() {
+		col := rdr.Record().Column(0).(*array.String)
+		for i := 0; i < col.Len(); i++ {
+			names = append(names, col.Value(i))
+		}
+	}
+	s.Require().NoError(rdr.Err())
+	return names
+}
+
+func (s *FlightSqlServerXdbcTypeInfoSuite) TestGetAllTypes() {
+	s.Equal([]string{"bit", "integer", "bigint", "varchar", "date"}, s.getTypeInfo(nil))
+}
+
+func (s *FlightSqlServerXdbcTypeInfoSuite) TestGetFilteredType() {
+	varchar := int32(flightsql.XdbcVarchar)
+	s.Equal([]string{"varchar"}, s.getTypeInfo(&varchar))
+}
+
+func (s *FlightSqlServerXdbcTypeInfoSuite) TestGetUnknownType() {
+	longVarbinary := int32(flightsql.XdbcLongVarbinary)
+	s.Empty(s.getTypeInfo(&longVarbinary))
+}
+
+func TestXdbcTypeInfo(t *testing.T) {
+	suite.Run(t, new(FlightSqlServerXdbcTypeInfoSuite))
+}
+
+func TestXdbcTypeInfoAppendFiltered(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	rows := []flightsql.XdbcTypeInfoRow{
+		{TypeName: "integer", DataType: flightsql.XdbcInteger, SqlDataType: flightsql.XdbcInteger},
+		{TypeName: "int4", DataType: flightsql.XdbcInteger, SqlDataType: flightsql.XdbcInteger},
+		{TypeName: "varchar", DataType: flightsql.XdbcVarchar, SqlDataType: flightsql.XdbcVarchar},
+	}
+	integer, longVarbinary := int32(flightsql.XdbcInteger), int32(flightsql.XdbcLongVarbinary)
+
+	tests := []struct {
+		name     string
+		dataType *int32
+		expected []string
+	}{
+		{"all types", nil, []string{"integer", "int4", "varchar"}},
+		{"known type", &integer, []string{"integer", "int4"}},
+		{"unknown type", &longVarbinary, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bldr := flightsql.NewXdbcTypeInfoResultBuilder(mem)
+			defer bldr.Release()
+			bldr.AppendFiltered(tt.dataType, rows...)
+			rec := bldr.NewRecord()
+			defer rec.Release()
+
+			assert.True(t, rec.Schema().Equal(schema_ref.XdbcTypeInfo))
+			var names []string
+			col := rec.Column(0).(*array.String)
+			for i := 0; i < col.Len(); i++ {
+				names = append(names, col.Value(i))
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestXdbcTypeInfoInterval(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	integer, err := flightsql.NewXdbcTypeInfoRow("integer", arrow.PrimitiveTypes.Int32)
+	require.NoError(t, err)
+	interval, err := flightsql.NewXdbcTypeInfoRow("interval day to second", arrow.FixedWidthTypes.DayTimeInterval)
+	require.NoError(t, err)
+
+	srv := &testServer{}
+	srv.Alloc = mem
+	srv.RegisterXdbcTypeInfo(integer, interval)
+	defer func() { require.NoError(t, srv.Close()) }()
+
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServerWithAllocator(srv, mem))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	dataType := int32(flightsql.XdbcInterval)
+	info, err := cl.GetXdbcTypeInfo(ctx, &dataType)
+	require.NoError(t, err)
+	rdr, err := cl.DoGet(ctx, info.GetEndpoint()[0].GetTicket())
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.True(t, rdr.Next())
+	rec := rdr.Record()
+	require.EqualValues(t, 1, rec.NumRows())
+
+	column := func(name string) arrow.Array {
+		idx := rec.Schema().FieldIndices(name)
+		require.Len(t, idx, 1)
+		return rec.Column(idx[0])
+	}
+	assert.Equal(t, "interval day to second", column("type_name").(*array.String).Value(0))
+	assert.EqualValues(t, flightsql.XdbcInterval, column("data_type").(*array.Int32).Value(0))
+	assert.EqualValues(t, flightsql.XdbcInterval, column("sql_data_type").(*array.Int32).Value(0))
+	assert.EqualValues(t, flightsql.XdbcSubcodeIntervalDayToSecond, column("datetime_subcode").(*array.Int32).Value(0))
+	assert.True(t, column("interval_precision").IsNull(0))
+	assert.False(t, rdr.Next())
+	require.NoError(t, rdr.Err())
+}
+
+func TestNewXdbcTypeInfoRow(t *testing.T) {
+	tests := []struct {
+		dt       arrow.DataType
+		dataType flightsql.XdbcDataType
+		sqlType  flightsql.XdbcDataType
+		subcode  *int32
+	}{
+		{arrow.PrimitiveTypes.Int64, flightsql.XdbcBigInt, flightsql.XdbcBigInt, nil},
+		{arrow.BinaryTypes.String, flightsql.XdbcVarchar, flightsql.XdbcVarchar, nil},
+		{arrow.FixedWidthTypes.Date32, flightsql.XdbcDate, flightsql.XdbcDatetime, proto.Int32(int32(flightsql.XdbcSubcodeDate))},
+		{arrow.FixedWidthTypes.Timestamp_us, flightsql.XdbcTimestamp, flightsql.XdbcDatetime, proto.Int32(int32(flightsql.XdbcSubcodeTimestampWithTimezone))},
+		{arrow.FixedWidthTypes.MonthInterval, flightsql.XdbcInterval, flightsql.XdbcInterval, proto.Int32(int32(flightsql.XdbcSubcodeIntervalYearToMonth))},
+		{arrow.FixedWidthTypes.DayTimeInterval, flightsql.XdbcInterval, flightsql.XdbcInterval, proto.Int32(int32(flightsql.XdbcSubcodeIntervalDayToSecond))},
+		{arrow.FixedWidthTypes.MonthDayNanoInterval, flightsql.XdbcInterval, flightsql.XdbcInterval, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dt.String(), func(t *testing.T) {
+			row, err := flightsql.NewXdbcTypeInfoRow("t", tt.dt)
+			require.NoError(t, err)
+			assert.Equal(t, tt.dataType, row.DataType)
+			assert.Equal(t, tt.sqlType, row.SqlDataType)
+			assert.Equal(t, tt.subcode, row.DatetimeSubcode)
+		})
+	}
+
+	_, err := flightsql.NewXdbcTypeInfoRow("list", arrow.ListOf(arrow.PrimitiveTypes.Int32))
+	assert.ErrorIs(t, err, arrow.ErrNotImplemented)
+}
+
+func TestArrowToXdbcDataType(t *testing.T) {
+	tests := []struct {
+		dt       arrow.DataType
+		xdbcType flightsql.XdbcDataType
+		name     string
+	}{
+		{arrow.FixedWidthTypes.Boolean, flightsql.XdbcBit, "BOOLEAN"},
+		{arrow.PrimitiveTypes.Int8, flightsql.XdbcTinyInt, "TINYINT"},
+		{arrow.PrimitiveTypes.Uint8, flightsql.XdbcTinyInt, "TINYINT"},
+		{arrow.PrimitiveTypes.Int16, flightsql.XdbcSmallInt, "SMALLINT"},
+		{arrow.PrimitiveTypes.Uint16, flightsql.XdbcSmallInt, "SMALLINT"},
+		{arrow.PrimitiveTypes.Int32, flightsql.XdbcInteger, "INTEGER"},
+		{arrow.PrimitiveTypes.Uint32, flightsql.XdbcInteger, "INTEGER"},
+		{arrow.PrimitiveTypes.Int64, flightsql.XdbcBigInt, "BIGINT"},
+		{arrow.PrimitiveTypes.Uint64, flightsql.XdbcBigInt, "BIGINT"},
+		{arrow.FixedWidthTypes.Float16, flightsql.XdbcReal, "REAL"},
+		{arrow.PrimitiveTypes.Float32, flightsql.XdbcReal, "REAL"},
+		{arrow.PrimitiveTypes.Float64, flightsql.XdbcDouble, "DOUBLE"},
+		{&arrow.Decimal128Type{Precision: 10, Scale: 2}, flightsql.XdbcDecimal, "DECIMAL"},
+		{&arrow.Decimal256Type{Precision: 50, Scale: 2}, flightsql.XdbcDecimal, "DECIMAL"},
+		{arrow.BinaryTypes.String, flightsql.XdbcVarchar, "VARCHAR"},
+		{arrow.BinaryTypes.LargeString, flightsql.XdbcVarchar, "VARCHAR"},
+		{arrow.BinaryTypes.StringView, flightsql.XdbcVarchar, "VARCHAR"},
+		{arrow.BinaryTypes.Binary, flightsql.XdbcVarbinary, "VARBINARY"},
+		{arrow.BinaryTypes.LargeBinary, flightsql.XdbcVarbinary, "VARBINARY"},
+		{arrow.BinaryTypes.BinaryView, flightsql.XdbcVarbinary, "VARBINARY"},
+		{&arrow.FixedSizeBinaryType{ByteWidth: 16}, flightsql.XdbcBinary, "BINARY"},
+		{arrow.FixedWidthTypes.Date32, flightsql.XdbcDate, "DATE"},
+		{arrow.FixedWidthTypes.Date64, flightsql.XdbcDate, "DATE"},
+		{arrow.FixedWidthTypes.Time32ms, flightsql.XdbcTime, "TIME"},
+		{arrow.FixedWidthTypes.Time64us, flightsql.XdbcTime, "TIME"},
+		{&arrow.TimestampType{Unit: arrow.Microsecond}, flightsql.XdbcTimestamp, "TIMESTAMP"},
+		{arrow.FixedWidthTypes.Timestamp_us, flightsql.XdbcTimestamp, "TIMESTAMP WITH TIME ZONE"},
+		{arrow.FixedWidthTypes.MonthInterval, flightsql.XdbcInterval, "INTERVAL"},
+		{arrow.FixedWidthTypes.DayTimeInterval, flightsql.XdbcInterval, "INTERVAL"},
+		{arrow.FixedWidthTypes.MonthDayNanoInterval, flightsql.XdbcInterval, "INTERVAL"},
+		{arrow.FixedWidthTypes.Duration_ms, flightsql.XdbcInterval, "INTERVAL"},
+		{&arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}, flightsql.XdbcVarchar, "VARCHAR"},
+		{arrow.RunEndEncodedOf(arrow.PrimitiveTypes.Int32, arrow.PrimitiveTypes.Float64), flightsql.XdbcDouble, "DOUBLE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dt.String(), func(t *testing.T) {
+			xdbcType, name, ok := flightsql.ArrowToXdbcDataType(tt.dt)
+			require.True(t, ok)
+			assert.Equal(t, int32(tt.xdbcType), xdbcType)
+			assert.Equal(t, tt.name, name)
+
+			row, err := flightsql.NewXdbcTypeInfoRow(name, tt.dt)
+			require.NoError(t, err)
+			assert.Equal(t, tt.xdbcType, row.DataType)
+		})
+	}
+
+	for _, dt := range []arrow.DataType{
+		arrow.Null,
+		arrow.ListOf(arrow.PrimitiveTypes.Int32),
+		arrow.StructOf(arrow.Field{Name: "a", Type: arrow.PrimitiveTypes.Int32}),
+		arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int32),
+	} {
+		_, _, ok := flightsql.ArrowToXdbcDataType(dt)
+		assert.False(t, ok, dt.String())
+	}
+}
+
+func TestGetXdbcTypeInfoTyped(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	var rows []flightsql.XdbcTypeInfoRow
+	for _, tt := range []struct {
+		name string
+		dt   arrow.DataType
+	}{
+		{"boolean", arrow.FixedWidthTypes.Boolean},
+		{"integer", arrow.PrimitiveTypes.Int32},
+		{"integer unsigned", arrow.PrimitiveTypes.Uint32},
+		{"double", arrow.PrimitiveTypes.Float64},
+		{"decimal(38, 10)", &arrow.Decimal128Type{Precision: 38, Scale: 10}},
+		{"varchar", arrow.BinaryTypes.String},
+		{"varbinary", arrow.BinaryTypes.Binary},
+		{"date", arrow.FixedWidthTypes.Date32},
+		{"timestamp", arrow.FixedWidthTypes.Timestamp_us},
+		{"interval", arrow.FixedWidthTypes.MonthInterval},
+	} {
+		row, err := flightsql.NewXdbcTypeInfoRow(tt.name, tt.dt)
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+	rows[5].CreateParams = []string{"length"}
+	rows[5].CaseSensitive = true
+	rows[6].CreateParams = []string{}
+
+	srv := &testServer{}
+	srv.Alloc = mem
+	srv.RegisterXdbcTypeInfo(rows...)
+	defer func() { require.NoError(t, srv.Close()) }()
+
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServerWithAllocator(srv, mem))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	got, err := cl.GetXdbcTypeInfoTyped(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, rows, got)
+
+	varchar := int32(flightsql.XdbcVarchar)
+	got, err = cl.GetXdbcTypeInfoTyped(ctx, &varchar)
+	require.NoError(t, err)
+	assert.Equal(t, rows[5:6], got)
+	assert.Equal(t, flightsql.NullabilityNullable, got[0].Nullable)
+	assert.Equal(t, flightsql.SearchableFull, got[0].Searchable)
+}
+
+// rawTypeInfoServer returns rec as the GetXdbcTypeInfo result.
+type rawTypeInfoServer struct {
+	flightsql.BaseServer
+
+	rec arrow.Record
+}
+
+func (s *rawTypeInfoServer) GetFlightInfoXdbcTypeInfo(_ context.Context, _ flightsql.GetXdbcTypeInfo, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return flightsql.NewFlightInfo(desc, s.rec.Schema(), s.Alloc), nil
+}
+
+func (s *rawTypeInfoServer) DoGetXdbcTypeInfo(context.Context, flightsql.GetXdbcTypeInfo) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	ch := make(chan flight.StreamChunk, 1)
+	s.rec.Retain()
+	ch <- flight.StreamChunk{Data: s.rec}
+	close(ch)
+	return s.rec.Schema(), ch, nil
+}
+
+func TestGetXdbcTypeInfoTypedSchemas(t *testing.T) {
+	getTypeInfo := func(t *testing.T, fields []arrow.Field, md *arrow.Metadata, row string) ([]flightsql.XdbcTypeInfoRow, error) {
+		rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, arrow.NewSchema(fields, md), strings.NewReader(row))
+		require.NoError(t, err)
+		defer rec.Release()
+
+		s := flight.NewServerWithMiddleware(nil)
+		s.RegisterFlightService(flightsql.NewFlightServer(&rawTypeInfoServer{rec: rec}))
+		require.NoError(t, s.Init("localhost:0"))
+		go s.Serve()
+		defer s.Shutdown()
+
+		cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+		require.NoError(t, err)
+		defer cl.Close()
+
+		return cl.GetXdbcTypeInfoTyped(context.Background(), nil)
+	}
+
+	required := []arrow.Field{
+		{Name: "type_name", Type: arrow.BinaryTypes.String},
+		{Name: "data_type", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "nullable", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "case_sensitive", Type: arrow.FixedWidthTypes.Boolean},
+		{Name: "searchable", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "fixed_prec_scale", Type: arrow.FixedWidthTypes.Boolean},
+		{Name: "sql_data_type", Type: arrow.PrimitiveTypes.Int32},
+	}
+	const row = `[{"type_name": "integer", "data_type": 4, "nullable": 1, "case_sensitive": false,
+		"searchable": 3, "fixed_prec_scale": false, "sql_data_type": 4, "num_prec_radix": 10, "vendor_flags": 7}]`
+
+	t.Run("extra columns and metadata", func(t *testing.T) {
+		md := arrow.NewMetadata([]string{"vendor"}, []string{"example"})
+		fields := append(append([]arrow.Field{}, schema_ref.XdbcTypeInfo.Fields()...),
+			arrow.Field{Name: "vendor_flags", Type: arrow.PrimitiveTypes.Int64, Nullable: true})
+		fields[0].Metadata = arrow.NewMetadata([]string{"ARROW:FLIGHT:SQL:REMARKS"}, []string{"name"})
+
+		got, err := getTypeInfo(t, fields, &md, row)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, "integer", got[0].TypeName)
+		assert.Equal(t, flightsql.XdbcInteger, got[0].DataType)
+		assert.Equal(t, flightsql.SearchableFull, got[0].Searchable)
+		require.NotNil(t, got[0].NumPrecRadix)
+		assert.EqualValues(t, 10, *got[0].NumPrecRadix)
+		assert.Nil(t, got[0].ColumnSize)
+		assert.Nil(t, got[0].CreateParams)
+	})
+
+	t.Run("nullable columns missing", func(t *testing.T) {
+		got, err := getTypeInfo(t, required, nil, row)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, "integer", got[0].TypeName)
+		assert.Nil(t, got[0].NumPrecRadix)
+	})
+
+	t.Run("required column missing", func(t *testing.T) {
+		_, err := getTypeInfo(t, required[:len(required)-1], nil, row)
+		assert.ErrorIs(t, err, arrow.ErrInvalid)
+		assert.ErrorContains(t, err, `"sql_data_type"`)
+	})
+
+	t.Run("wrong column type", func(t *testing.T) {
+		fields := append([]arrow.Field{}, required...)
+		fields[1].Type = arrow.PrimitiveTypes.Int64
+		_, err := getTypeInfo(t, fields, nil, row)
+		assert.ErrorIs(t, err, arrow.ErrInvalid)
+		assert.ErrorContains(t, err, `"data_type"`)
+	})
+}
+
+func TestRegisterXdbcTypeInfoRecord(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	var srv testServer
+	bldr := flightsql.NewXdbcTypeInfoResultBuilder(mem)
+	defer bldr.Release()
+
+	bldr.Append(flightsql.XdbcTypeInfoRow{TypeName: "integer", DataType: flightsql.XdbcInteger, SqlDataType: flightsql.XdbcInteger})
+	rec := bldr.NewRecord()
+	require.NoError(t, srv.RegisterXdbcTypeInfoRecord(rec))
+	rec.Release()
+
+	bad, _, err := array.RecordFromJSON(mem, arrow.NewSchema([]arrow.Field{
+		{Name: "data_type", Type: arrow.BinaryTypes.String},
+	}, nil), strings.NewReader(`[{"data_type": "integer"}]`))
+	require.NoError(t, err)
+	defer bad.Release()
+	require.ErrorIs(t, srv.RegisterXdbcTypeInfoRecord(bad), arrow.ErrInvalid)
+
+	require.NoError(t, srv.Close())
+}
+
+// allXdbcTypes requests the type info of every data type.
+type allXdbcTypes struct{}
+
+func (allXdbcTypes) GetDataType() *int32 { return nil }
+
+func TestReregisterXdbcTypeInfo(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	srv := &testServer{}
+	srv.Alloc = mem
+	row := func(name string) flightsql.XdbcTypeInfoRow {
+		return flightsql.XdbcTypeInfoRow{TypeName: name, DataType: flightsql.XdbcInteger, SqlDataType: flightsql.XdbcInteger}
+	}
+	srv.RegisterXdbcTypeInfo(row("integer"))
+
+	// a stream keeps the rows it started with
+	ctx := context.Background()
+	_, ch, err := srv.DoGetXdbcTypeInfo(ctx, allXdbcTypes{})
+	require.NoError(t, err)
+	srv.RegisterXdbcTypeInfo(row("int4"))
+	chunk, ok := <-ch
+	require.True(t, ok)
+	require.NoError(t, chunk.Err)
+	assert.Equal(t, "integer", chunk.Data.Column(0).(*array.String).Value(0))
+	chunk.Data.Release()
+	for range ch {
+	}
+
+	const n = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ch, err := srv.DoGetXdbcTypeInfo(ctx, allXdbcTypes{})
+			if err != nil {
+				errs <- err
+				return
+			}
+			for chunk := range ch {
+				if chunk.Err != nil {
+					errs <- chunk.Err
+					continue
+				}
+				if rows := chunk.Data.NumRows(); rows != 1 {
+					errs <- fmt.Errorf("got %d rows, expected 1", rows)
+				}
+				chunk.Data.Release()
+			}
+		}()
+		srv.RegisterXdbcTypeInfo(row(fmt.Sprintf("int%d", i)))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	require.NoError(t, srv.Close())
+}
+
+// updateTestServer understands a single "UPDATE t ... WHERE id = ?"
+// statement, counting the bound ids which exist in the table.
+type updateTestServer struct {
+	flightsql.BaseServer
+
+	mx          sync.Mutex
+	next        int
+	open        map[string]bool
+	lastBatches int
+}
+
+const updateTestQuery = "UPDATE t SET name = 'x' WHERE id = ?"
+
+var updateTestIDs = map[int64]bool{1: true, 2: true, 3: true, 4: true, 5: true}
+
+func (s *updateTestServer) CreatePreparedStatement(ctx context.Context, req flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.next++
+	handle := fmt.Sprintf("%d:%s", s.next, req.GetQuery())
+	s.open[handle] = true
+	return flightsql.ActionCreatePreparedStatementResult{
+		Handle: []byte(handle),
+		ParameterSchema: arrow.NewSchema([]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		}, nil),
+	}, nil
+}
+
+func (s *updateTestServer) ClosePreparedStatement(_ context.Context, req flightsql.ActionClosePreparedStatementRequest) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.open, string(req.GetPreparedStatementHandle()))
+	return nil
+}
+
+func (s *updateTestServer) numOpen() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return len(s.open)
+}
+
+func (s *updateTestServer) DoPutPreparedStatementUpdate(_ context.Context, cmd flightsql.PreparedStatementUpdate, rdr flight.MessageReader) (int64, error) {
+	if !strings.HasSuffix(string(cmd.GetPreparedStatementHandle()), updateTestQuery) {
+		return 0, status.Error(codes.InvalidArgument, "unknown table")
+	}
+
+	var n int64
+	batches := 0
+	for rdr.Next() {
+		ids, ok := rdr.Record().Column(0).(*array.Int64)
+		if !ok {
+			return 0, status.Error(codes.InvalidArgument, "expected int64 id parameter")
+		}
+		for _, id := range ids.Int64Values() {
+			if updateTestIDs[id] {
+				n++
+			}
+		}
+		batches++
+	}
+
+	s.mx.Lock()
+	s.lastBatches = batches
+	s.mx.Unlock()
+	return n, rdr.Err()
+}
+
+type FlightSqlPreparedUpdateSuite struct {
+	suite.Suite
+
+	srv *updateTestServer
+	s   flight.Server
+	cl  *flightsql.Client
+}
+
+func (s *FlightSqlPreparedUpdateSuite) SetupSuite() {
+	s.srv = &updateTestServer{open: make(map[string]bool)}
+	s.s = flight.NewServerWithMiddleware(nil)
+	s.s.RegisterFlightService(flightsql.NewFlightServer(s.srv))
+	s.Require().NoError(s.s.Init("localhost:0"))
+
+	go s.s.Serve()
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TearDownSuite() {
+	s.s.Shutdown()
+}
+
+func (s *FlightSqlPreparedUpdateSuite) SetupTest() {
+	cl, err := flightsql.NewClient(s.s.Addr().String(), nil, nil, dialOpts...)
+	s.Require().NoError(err)
+	s.cl = cl
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TearDownTest() {
+	s.Require().NoError(s.cl.Close())
+	s.cl = nil
+}
+
+func (s *FlightSqlPreparedUpdateSuite) idParams(ids string) arrow.Record {
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	}, nil), strings.NewReader(ids))
+	s.Require().NoError(err)
+	return rec
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestPrepareAndExecuteUpdate() {
+	params := s.idParams(`[{"id": 1}, {"id": 3}, {"id": 7}]`)
+	defer params.Release()
+
+	n, err := s.cl.PrepareAndExecuteUpdate(context.Background(), updateTestQuery, params)
+	s.Require().NoError(err)
+	s.EqualValues(2, n)
+	s.Zero(s.srv.numOpen())
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestPrepareUpdate() {
+	first, second := s.idParams(`[{"id": 1}]`), s.idParams(`[{"id": 2}, {"id": 4}, {"id": 5}]`)
+	defer first.Release()
+	defer second.Release()
+
+	counts, err := s.cl.PrepareUpdate(context.Background(), updateTestQuery, []arrow.Record{first, second})
+	s.Require().NoError(err)
+	s.Equal([]int64{1, 3}, counts)
+	s.Zero(s.srv.numOpen())
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestPrepareAndExecuteUpdateStream() {
+	var recs []arrow.Record
+	for _, ids := range []string{`[{"id": 1}, {"id": 8}]`, `[{"id": 2}, {"id": 4}]`} {
+		rec := s.idParams(ids)
+		defer rec.Release()
+		recs = append(recs, rec)
+	}
+	rdr, err := array.NewRecordReader(recs[0].Schema(), recs)
+	s.Require().NoError(err)
+	defer rdr.Release()
+
+	n, err := s.cl.PrepareAndExecuteUpdateStream(context.Background(), updateTestQuery, rdr)
+	s.Require().NoError(err)
+	s.EqualValues(3, n)
+	s.srv.mx.Lock()
+	s.Equal(2, s.srv.lastBatches)
+	s.srv.mx.Unlock()
+	s.Zero(s.srv.numOpen())
+
+	rdr, err = array.NewRecordReader(recs[0].Schema(), recs)
+	s.Require().NoError(err)
+	defer rdr.Release()
+	_, err = s.cl.PrepareAndExecuteUpdateStream(context.Background(), "UPDATE missing SET name = 'x' WHERE id = ?", rdr)
+	s.Equal(codes.InvalidArgument, status.Code(err))
+	s.Zero(s.srv.numOpen())
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestExecuteUpdateStream() {
+	ctx := context.Background()
+	prep, err := s.cl.Prepare(ctx, updateTestQuery)
+	s.Require().NoError(err)
+	defer prep.Close(ctx)
+
+	recs := make(chan arrow.Record)
+	go func() {
+		defer close(recs)
+		for _, ids := range []string{`[{"id": 1}, {"id": 2}]`, `[{"id": 3}, {"id": 8}]`, `[{"id": 4}, {"id": 5}, {"id": 9}]`} {
+			recs <- s.idParams(ids)
+		}
+	}()
+
+	n, err := prep.ExecuteUpdateStream(ctx, recs)
+	s.Require().NoError(err)
+	s.EqualValues(5, n)
+	s.srv.mx.Lock()
+	s.Equal(3, s.srv.lastBatches)
+	s.srv.mx.Unlock()
+
+	// the remaining records are drained if the parameters are rejected
+	recs = make(chan arrow.Record)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(recs)
+		for i := 0; i < 3; i++ {
+			rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{
+				{Name: "id", Type: arrow.BinaryTypes.String},
+			}, nil), strings.NewReader(`[{"id": "1"}]`))
+			s.Require().NoError(err)
+			recs <- rec
+		}
+	}()
+
+	_, err = prep.ExecuteUpdateStream(ctx, recs)
+	s.Equal(codes.InvalidArgument, status.Code(err), err)
+	<-done
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestCloseTwice() {
+	ctx := context.Background()
+	prep, err := s.cl.Prepare(ctx, updateTestQuery)
+	s.Require().NoError(err)
+	s.Equal(1, s.srv.numOpen())
+
+	s.NoError(prep.Close(ctx))
+	s.Zero(s.srv.numOpen())
+	s.NoError(prep.Close(ctx))
+
+	_, err = prep.ExecuteUpdate(ctx)
+	s.ErrorIs(err, flightsql.ErrPreparedStatementClosed)
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestCloseOnContextDone() {
+	stmtCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctx := context.Background()
+	prep, err := s.cl.Prepare(ctx, updateTestQuery, flightsql.ClosePreparedStatementOnDone(stmtCtx))
+	s.Require().NoError(err)
+	params := s.idParams(`[{"id": 1}]`)
+	defer params.Release()
+	prep.SetParameters(params)
+	n, err := prep.ExecuteUpdate(ctx)
+	s.Require().NoError(err)
+	s.EqualValues(1, n)
+	s.Equal(1, s.srv.numOpen())
+
+	cancel()
+	s.Eventually(func() bool { return s.srv.numOpen() == 0 }, time.Second, 10*time.Millisecond)
+	_, err = prep.ExecuteUpdate(ctx)
+	s.ErrorIs(err, flightsql.ErrPreparedStatementClosed)
+	s.NoError(prep.Close(ctx))
+
+	// closing the statement stops watching the context
+	stmtCtx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	prep, err = s.cl.Prepare(ctx, updateTestQuery, flightsql.ClosePreparedStatementOnDone(stmtCtx))
+	s.Require().NoError(err)
+	s.NoError(prep.Close(ctx))
+	cancel()
+	s.Zero(s.srv.numOpen())
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestCloseDuringExecute() {
+	ctx := context.Background()
+	prep, err := s.cl.Prepare(ctx, updateTestQuery)
+	s.Require().NoError(err)
+
+	recs := make(chan arrow.Record)
+	executed := make(chan int64)
+	go func() {
+		n, err := prep.ExecuteUpdateStream(ctx, recs)
+		s.NoError(err)
+		executed <- n
+	}()
+	recs <- s.idParams(`[{"id": 1}, {"id": 2}]`)
+
+	closed := make(chan error)
+	go func() { closed <- prep.Close(ctx) }()
+
+	// the statement is closed once the execution completes
+	s.Never(func() bool { return s.srv.numOpen() == 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	close(recs)
+	s.EqualValues(2, <-executed)
+	s.NoError(<-closed)
+	s.Zero(s.srv.numOpen())
+
+	_, err = prep.ExecuteUpdateStream(ctx, nil)
+	s.ErrorIs(err, flightsql.ErrPreparedStatementClosed)
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestClientCloseDuringExecute() {
+	cl, err := flightsql.NewClient(s.s.Addr().String(), nil, nil, dialOpts...)
+	s.Require().NoError(err)
+
+	ctx := context.Background()
+	prep, err := cl.Prepare(ctx, updateTestQuery)
+	s.Require().NoError(err)
+
+	recs := make(chan arrow.Record)
+	defer close(recs)
+	go prep.ExecuteUpdateStream(ctx, recs)
+	// the stream is open once the second record is received
+	recs <- s.idParams(`[{"id": 1}]`)
+	recs <- s.idParams(`[{"id": 2}]`)
+
+	// closing the client gives up on the statement stuck in a call once
+	// the close times out, instead of waiting for the call to complete
+	closed := make(chan error)
+	go func() { closed <- cl.Close() }()
+	select {
+	case err := <-closed:
+		s.NoError(err)
+	case <-time.After(30 * time.Second):
+		s.FailNow("client close waited for the call in progress")
+	}
+
+	s.srv.mx.Lock()
+	defer s.srv.mx.Unlock()
+	s.Len(s.srv.open, 1)
+	clear(s.srv.open)
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestClientCloseClosesStatements() {
+	cl, err := flightsql.NewClient(s.s.Addr().String(), nil, nil, dialOpts...)
+	s.Require().NoError(err)
+
+	ctx := context.Background()
+	first, err := cl.Prepare(ctx, updateTestQuery)
+	s.Require().NoError(err)
+	_, err = cl.Prepare(ctx, updateTestQuery)
+	s.Require().NoError(err)
+	s.Require().NoError(first.Close(ctx))
+	_, err = cl.Prepare(ctx, updateTestQuery)
+	s.Require().NoError(err)
+	s.Equal(2, s.srv.numOpen())
+
+	s.NoError(cl.Close())
+	s.Zero(s.srv.numOpen())
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestClosedOnError() {
+	params := s.idParams(`[{"id": 1}]`)
+	defer params.Release()
+
+	_, err := s.cl.PrepareAndExecuteUpdate(context.Background(), "UPDATE missing SET name = 'x' WHERE id = ?", params)
+	s.Equal(codes.InvalidArgument, status.Code(err))
+	s.Zero(s.srv.numOpen())
+}
+
+// cancellingReader cancels the context of the call reading it once its
+// records are exhausted.
+type cancellingReader struct {
+	array.RecordReader
+	cancel context.CancelFunc
+}
+
+func (r *cancellingReader) Next() bool {
+	if r.RecordReader.Next() {
+		return true
+	}
+	r.cancel()
+	return false
+}
+
+func (s *FlightSqlPreparedUpdateSuite) TestClosedOnCancel() {
+	params := s.idParams(`[{"id": 1}]`)
+	defer params.Release()
+
+	rdr, err := array.NewRecordReader(params.Schema(), []arrow.Record{params})
+	s.Require().NoError(err)
+	defer rdr.Release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err = s.cl.PrepareAndExecuteUpdateStream(ctx, updateTestQuery, &cancellingReader{RecordReader: rdr, cancel: cancel})
+	s.Equal(codes.Canceled, status.Code(err))
+	s.Zero(s.srv.numOpen())
+}
+
+func TestPreparedUpdate(t *testing.T) {
+	suite.Run(t, new(FlightSqlPreparedUpdateSuite))
+}
+
+// multiEndpointServer splits each result across three endpoints. The
+// second must be fetched from the server at the remote address.
+type multiEndpointServer struct {
+	flightsql.BaseServer
+	remote   string
+	isRemote bool
+}
+
+func (s *multiEndpointServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	last := "c"
+	if cmd.GetQuery() == "mismatch" {
+		last = "bad"
+	}
+
+	ticket := func(handle string) *flight.Ticket {
+		tkt, err := flightsql.CreateStatementQueryTicket([]byte(handle))
+		if err != nil {
+			panic(err)
+		}
+		return &flight.Ticket{Ticket: tkt}
+	}
+
+	return &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint: []*flight.FlightEndpoint{
+			{Ticket: ticket("a")},
+			{Ticket: ticket("b"), Location: []*flight.Location{
+				{Uri: "grpc+bogus://nowhere"},
+				{Uri: "grpc+tcp://" + s.remote},
+			}},
+			{Ticket: ticket(last), Location: []*flight.Location{{Uri: flight.LocationReuseConnection}}},
+		},
+		TotalRecords: -1,
+		TotalBytes:   -1,
+	}, nil
+}
+
+func (s *multiEndpointServer) DoGetStatement(_ context.Context, cmd flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	var rows string
+	switch handle := string(cmd.GetStatementHandle()); {
+	case handle == "b" && !s.isRemote:
+		return nil, nil, status.Error(codes.FailedPrecondition, "endpoint b must be read from the remote server")
+	case handle == "bad":
+		schema = arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.BinaryTypes.String}}, nil)
+		rows = `[{"id": "x"}]`
+	case handle == "a":
+		rows = `[{"id": 1}, {"id": 2}]`
+	case handle == "b":
+		rows = `[{"id": 3}]`
+	case handle == "c":
+		rows = `[{"id": 4}, {"id": 5}, {"id": 6}]`
+	}
+
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(rows))
+	if err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: rec}
+	close(ch)
+	return schema, ch, nil
+}
+
+func TestExecuteQueryMultipleEndpoints(t *testing.T) {
+	remote := flight.NewServerWithMiddleware(nil)
+	remote.RegisterFlightService(flightsql.NewFlightServer(&multiEndpointServer{isRemote: true}))
+	require.NoError(t, remote.Init("localhost:0"))
+	go remote.Serve()
+	defer remote.Shutdown()
+
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&multiEndpointServer{remote: remote.Addr().String()}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	cl.Alloc = mem
+
+	ctx := context.Background()
+	rdr, err := cl.ExecuteQuery(ctx, "SELECT id FROM t")
+	require.NoError(t, err)
+
+	var ids []int64
+	for rdr.Next() {
+		ids = append(ids, rdr.Record().Column(0).(*array.Int64).Int64Values()...)
+	}
+	require.NoError(t, rdr.Err())
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6}, ids)
+	assert.Equal(t, "id", rdr.Schema().Field(0).Name)
+	rdr.Release()
+
+	rdr, err = cl.ExecuteQuery(ctx, "mismatch")
+	require.NoError(t, err)
+	for rdr.Next() {
+	}
+	assert.ErrorIs(t, rdr.Err(), arrow.ErrInvalid)
+	assert.ErrorContains(t, rdr.Err(), "schema of endpoint 2 does not match")
+	rdr.Release()
+}
+
+func TestExecuteQueryEndpointConcurrency(t *testing.T) {
+	remote := flight.NewServerWithMiddleware(nil)
+	remote.RegisterFlightService(flightsql.NewFlightServer(&multiEndpointServer{isRemote: true}))
+	require.NoError(t, remote.Init("localhost:0"))
+	go remote.Serve()
+	defer remote.Shutdown()
+
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&multiEndpointServer{remote: remote.Addr().String()}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	cl.Alloc = mem
+
+	ctx := context.Background()
+	rdr, err := cl.ExecuteQuery(ctx, "SELECT id FROM t", flightsql.WithEndpointConcurrency(3), flightsql.WithMaxBufferedRecords(1))
+	require.NoError(t, err)
+
+	var ids []int64
+	for rdr.Next() {
+		ids = append(ids, rdr.Record().Column(0).(*array.Int64).Int64Values()...)
+	}
+	require.NoError(t, rdr.Err())
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6}, ids)
+	rdr.Release()
+
+	rdr, err = cl.ExecuteQuery(ctx, "SELECT id FROM t", flightsql.WithEndpointConcurrency(2), flightsql.WithUnorderedEndpoints())
+	require.NoError(t, err)
+	ids = ids[:0]
+	for rdr.Next() {
+		ids = append(ids, rdr.Record().Column(0).(*array.Int64).Int64Values()...)
+	}
+	require.NoError(t, rdr.Err())
+	assert.ElementsMatch(t, []int64{1, 2, 3, 4, 5, 6}, ids)
+	rdr.Release()
+
+	rdr, err = cl.ExecuteQuery(ctx, "mismatch", flightsql.WithEndpointConcurrency(3))
+	require.NoError(t, err)
+	for rdr.Next() {
+	}
+	assert.ErrorIs(t, rdr.Err(), arrow.ErrInvalid)
+	assert.ErrorContains(t, rdr.Err(), "schema of endpoint 2 does not match")
+	rdr.Release()
+}
+
+// latencyServer splits each result across endpoints whose batches each
+// take delay to produce. The endpoint at index slow never finishes and
+// the one at index fail returns an error, if set. If ordered is set the
+// result is marked as Ordered and carries app metadata.
+type latencyServer struct {
+	flightsql.BaseServer
+	endpoints, batches int
+	delay              time.Duration
+	slow, fail         int
+	ordered            bool
+
+	// active counts the endpoints being streamed, maxActive is the
+	// largest number streamed at once
+	active, maxActive int64
+}
+
+var latencyExpiration = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var latencySchema = arrow.NewSchema([]arrow.Field{{Name: "endpoint", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+func (s *latencyServer) GetFlightInfoStatement(_ context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	endpoints := make([]*flight.FlightEndpoint, s.endpoints)
+	for i := range endpoints {
+		tkt, err := flightsql.CreateStatementQueryTicket([]byte(strconv.Itoa(i)))
+		if err != nil {
+			return nil, err
+		}
+		endpoints[i] = &flight.FlightEndpoint{Ticket: &flight.Ticket{Ticket: tkt}}
+		if s.ordered {
+			endpoints[i].AppMetadata = []byte("endpoint " + strconv.Itoa(i))
+			endpoints[i].ExpirationTime = timestamppb.New(latencyExpiration.Add(time.Duration(i) * time.Hour))
+		}
+	}
+
+	opts := []flightsql.FlightInfoOption{flightsql.WithEndpoints(endpoints...)}
+	if s.ordered {
+		opts = append(opts, flightsql.WithOrdered())
+	}
+	info := flightsql.NewFlightInfo(desc, latencySchema, s.Alloc, opts...)
+	if s.ordered {
+		info.AppMetadata = []byte("result")
+	}
+	return info, nil
+}
+
+func (s *latencyServer) DoGetStatement(ctx context.Context, cmd flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	idx, err := strconv.Atoi(string(cmd.GetStatementHandle()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	active := atomic.AddInt64(&s.active, 1)
+	for {
+		max := atomic.LoadInt64(&s.maxActive)
+		if active <= max || atomic.CompareAndSwapInt64(&s.maxActive, max, active) {
+			break
+		}
+	}
+
+	ch := make(chan flight.StreamChunk)
+	go func() {
+		defer close(ch)
+		defer atomic.AddInt64(&s.active, -1)
+		if idx == s.fail {
+			ch <- flight.StreamChunk{Err: status.Error(codes.Internal, "endpoint failed")}
+			return
+		}
+
+		bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+		defer bldr.Release()
+		for i := 0; i < s.batches || idx == s.slow; i++ {
+			select {
+			case <-time.After(s.delay):
+			case <-ctx.Done():
+				return
+			}
+
+			bldr.Field(0).(*array.Int64Builder).Append(int64(idx))
+			select {
+			case ch <- flight.StreamChunk{Data: bldr.NewRecord()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return latencySchema, ch, nil
+}
+
+func TestEndpointConcurrencyErrors(t *testing.T) {
+	cl := flightsqltest.StartServer(t, &latencyServer{endpoints: 4, batches: 2, delay: time.Millisecond, slow: 0, fail: 2})
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	cl.Alloc = mem
+
+	// the first endpoint never finishes, the failure of the third must
+	// still be reported
+	rdr, err := cl.ExecuteQuery(context.Background(), "SELECT 1", flightsql.WithEndpointConcurrency(4))
+	require.NoError(t, err)
+	for rdr.Next() {
+	}
+	assert.Equal(t, codes.Internal, status.Code(rdr.Err()))
+	rdr.Release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rdr, err = cl.ExecuteQuery(ctx, "SELECT 1", flightsql.WithEndpointConcurrency(2), flightsql.WithUnorderedEndpoints())
+	require.NoError(t, err)
+	require.True(t, rdr.Next())
+	cancel()
+	for rdr.Next() {
+	}
+	assert.ErrorIs(t, rdr.Err(), context.Canceled)
+	rdr.Release()
+}
+
+func TestEndpointConcurrencyOrdered(t *testing.T) {
+	srv := &latencyServer{endpoints: 4, batches: 2, delay: time.Millisecond, slow: -1, fail: -1, ordered: true}
+	cl := flightsqltest.StartServer(t, srv)
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	cl.Alloc = mem
+
+	// an ordered result is read one endpoint after the other whatever
+	// the options
+	res, err := cl.ExecuteWithInfo(context.Background(), "SELECT 1",
+		flightsql.WithEndpointConcurrency(4), flightsql.WithUnorderedEndpoints())
+	require.NoError(t, err)
+	defer res.Release()
+
+	var got []int64
+	for res.Next() {
+		got = append(got, res.Record().Column(0).(*array.Int64).Int64Values()...)
+	}
+	require.NoError(t, res.Err())
+	assert.Equal(t, []int64{0, 0, 1, 1, 2, 2, 3, 3}, got)
+	assert.EqualValues(t, 1, atomic.LoadInt64(&srv.maxActive))
+
+	assert.True(t, res.Ordered())
+	assert.Equal(t, []byte("result"), res.AppMetadata())
+	endpoints := res.Endpoints()
+	require.Len(t, endpoints, 4)
+	for i, ep := range endpoints {
+		assert.Equal(t, []byte("endpoint "+strconv.Itoa(i)), ep.AppMetadata)
+		assert.True(t, latencyExpiration.Add(time.Duration(i)*time.Hour).Equal(ep.ExpirationTime))
+	}
+
+	// without expirations the times are zero
+	srv.ordered = false
+	res, err = cl.ExecuteWithInfo(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+	defer res.Release()
+	assert.False(t, res.Ordered())
+	assert.Nil(t, res.AppMetadata())
+	for _, ep := range res.Endpoints() {
+		assert.True(t, ep.ExpirationTime.IsZero())
+	}
+}
+
+func TestEndpointConcurrencyReleaseEarly(t *testing.T) {
+	cl := flightsqltest.StartServer(t, &latencyServer{endpoints: 8, batches: 4, slow: -1, fail: -1})
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	cl.Alloc = mem
+
+	rdr, err := cl.ExecuteQuery(context.Background(), "SELECT 1", flightsql.WithEndpointConcurrency(4))
+	require.NoError(t, err)
+	require.True(t, rdr.Next())
+	assert.EqualValues(t, 0, rdr.Record().Column(0).(*array.Int64).Value(0))
+	rdr.Release()
+}
+
+func BenchmarkEndpointConcurrency(b *testing.B) {
+	cl := flightsqltest.StartServer(b, &latencyServer{endpoints: 8, batches: 4, delay: 5 * time.Millisecond, slow: -1, fail: -1})
+
+	for _, n := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rdr, err := cl.ExecuteQuery(context.Background(), "SELECT 1", flightsql.WithEndpointConcurrency(n))
+				if err != nil {
+					b.Fatal(err)
+				}
+				for rdr.Next() {
+				}
+				if err := rdr.Err(); err != nil {
+					b.Fatal(err)
+				}
+				rdr.Release()
+			}
+		})
+	}
+}
+
+// ipcCacheServer serves statements from pre-serialized IPC streams and
+// falls back to DoGetStatement for anything not cached.
+type ipcCacheServer struct {
+	flightsql.BaseServer
+	schema *arrow.Schema
+	cache  map[string][]byte
+}
+
+func (s *ipcCacheServer) DoGetStatementIPC(_ context.Context, cmd flightsql.StatementQueryTicket) (*arrow.Schema, io.Reader, error) {
+	handle := string(cmd.GetStatementHandle())
+	if handle == "wrong schema" {
+		return arrow.NewSchema([]arrow.Field{{Name: "other", Type: arrow.BinaryTypes.String}}, nil), bytes.NewReader(s.cache["cached"]), nil
+	}
+
+	data, ok := s.cache[handle]
+	if !ok {
+		return nil, nil, nil
+	}
+	return s.schema, bytes.NewReader(data), nil
+}
+
+func (s *ipcCacheServer) DoGetPreparedStatementIPC(context.Context, flightsql.PreparedStatementQuery) (*arrow.Schema, io.Reader, error) {
+	return nil, nil, nil
+}
+
+func (s *ipcCacheServer) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, s.schema, strings.NewReader(`[{"id": 42, "name": "uncached"}]`))
+	if err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: rec}
+	close(ch)
+	return s.schema, ch, nil
+}
+
+func TestDoGetPreSerializedIPC(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String}, Nullable: true},
+	}, nil)
+
+	var expected []arrow.Record
+	for _, rows := range []string{
+		`[{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]`,
+		`[{"id": 3, "name": null}, {"id": 4, "name": "a"}]`,
+	} {
+		rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(rows))
+		require.NoError(t, err)
+		defer rec.Release()
+		expected = append(expected, rec)
+	}
+
+	var buf bytes.Buffer
+	w := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	for _, rec := range expected {
+		require.NoError(t, w.Write(rec))
+	}
+	require.NoError(t, w.Close())
+
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&ipcCacheServer{
+		schema: schema,
+		cache:  map[string][]byte{"cached": buf.Bytes()},
+	}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	doGet := func(handle string) (*flight.Reader, error) {
+		tkt, err := flightsql.CreateStatementQueryTicket([]byte(handle))
+		require.NoError(t, err)
+		return cl.DoGet(context.Background(), &flight.Ticket{Ticket: tkt})
+	}
+
+	rdr, err := doGet("cached")
+	require.NoError(t, err)
+	assert.True(t, schema.Equal(rdr.Schema()))
+	var i int
+	for ; rdr.Next(); i++ {
+		require.Less(t, i, len(expected))
+		assert.Truef(t, array.RecordEqual(expected[i], rdr.Record()), "expected: %s\ngot: %s", expected[i], rdr.Record())
+	}
+	require.NoError(t, rdr.Err())
+	assert.Equal(t, len(expected), i)
+	rdr.Release()
+
+	rdr, err = doGet("miss")
+	require.NoError(t, err)
+	require.True(t, rdr.Next())
+	assert.EqualValues(t, 42, rdr.Record().Column(0).(*array.Int64).Value(0))
+	rdr.Release()
+
+	_, err = doGet("wrong schema")
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.ErrorContains(t, err, "invalid schema for IPC stream")
+}
+
+// statsTestServer attaches column statistics to every FlightInfo.
+type statsTestServer struct {
+	flightsql.BaseServer
+}
+
+func (s *statsTestServer) GetFlightInfoStatement(_ context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "price", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	info := flightsql.NewFlightInfo(desc, schema, s.Alloc)
+	err := flightsql.SetColumnStats(info,
+		flightsql.ColumnStats{Column: "id", NullCount: 0, Min: scalar.NewInt64Scalar(1), Max: scalar.NewInt64Scalar(42)},
+		flightsql.ColumnStats{Column: "price", NullCount: 3, Min: scalar.NewFloat64Scalar(-0.5)},
+		flightsql.ColumnStats{Column: "name", NullCount: flightsql.TotalUnknown, Max: scalar.NewStringScalar("zebra")},
+	)
+	return info, err
+}
+
+func TestColumnStats(t *testing.T) {
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&statsTestServer{}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	info, err := cl.Execute(context.Background(), "SELECT * FROM t")
+	require.NoError(t, err)
+
+	stats, err := flightsql.GetColumnStats(info, nil)
+	require.NoError(t, err)
+	require.Len(t, stats, 3)
+
+	assert.Equal(t, "id", stats[0].Column)
+	assert.EqualValues(t, 0, stats[0].NullCount)
+	assert.True(t, scalar.Equals(scalar.NewInt64Scalar(1), stats[0].Min), stats[0].Min)
+	assert.True(t, scalar.Equals(scalar.NewInt64Scalar(42), stats[0].Max), stats[0].Max)
+
+	assert.EqualValues(t, 3, stats[1].NullCount)
+	assert.True(t, scalar.Equals(scalar.NewFloat64Scalar(-0.5), stats[1].Min), stats[1].Min)
+	assert.Nil(t, stats[1].Max)
+
+	assert.Equal(t, flightsql.TotalUnknown, stats[2].NullCount)
+	assert.Nil(t, stats[2].Min)
+	assert.True(t, scalar.Equals(scalar.NewStringScalar("zebra"), stats[2].Max), stats[2].Max)
+
+	stats, err = flightsql.GetColumnStats(&flight.FlightInfo{}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, stats)
+
+	info.AppMetadata = []byte(`{"column_stats":[{"column":"missing","min":1}]}`)
+	_, err = flightsql.GetColumnStats(info, nil)
+	assert.ErrorIs(t, err, arrow.ErrInvalid)
+}
+
+// txnRecordingServer records the transaction id of every command it sees.
+type txnRecordingServer struct {
+	flightsql.BaseServer
+
+	mu   sync.Mutex
+	next int
+	seen []string
+}
+
+func (s *txnRecordingServer) record(kind string, txn []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen = append(s.seen, kind+":"+string(txn))
+}
+
+func (s *txnRecordingServer) BeginTransaction(context.Context, flightsql.ActionBeginTransactionRequest) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	return []byte(fmt.Sprintf("txn-%d", s.next)), nil
+}
+
+func (s *txnRecordingServer) EndTransaction(_ context.Context, req flightsql.ActionEndTransactionRequest) error {
+	s.record(req.GetAction().String(), req.GetTransactionId())
+	return nil
+}
+
+func (s *txnRecordingServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	s.record("query", cmd.GetTransactionId())
+	return flightsql.NewFlightInfo(desc, nil, s.Alloc), nil
+}
+
+func (s *txnRecordingServer) DoPutCommandStatementUpdate(_ context.Context, cmd flightsql.StatementUpdate) (int64, error) {
+	s.record("update", cmd.GetTransactionId())
+	return 1, nil
+}
+
+func (s *txnRecordingServer) CreatePreparedStatement(_ context.Context, req flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	s.record("prepare", req.GetTransactionId())
+	return flightsql.ActionCreatePreparedStatementResult{Handle: []byte(req.GetQuery())}, nil
+}
+
+func (s *txnRecordingServer) ClosePreparedStatement(context.Context, flightsql.ActionClosePreparedStatementRequest) error {
+	return nil
+}
+
+func TestClientTransactions(t *testing.T) {
+	txnSrv := &txnRecordingServer{}
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(txnSrv))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	tx, err := cl.BeginTransaction(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, flightsql.Transaction("txn-1"), tx.ID())
+
+	_, err = tx.Execute(ctx, "SELECT 1")
+	require.NoError(t, err)
+	n, err := tx.ExecuteUpdate(ctx, "DELETE FROM t")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	prep, err := tx.Prepare(ctx, "SELECT ?")
+	require.NoError(t, err)
+	require.NoError(t, prep.Close(ctx))
+	require.NoError(t, tx.Commit(ctx))
+
+	// outside of a transaction
+	_, err = cl.Execute(ctx, "SELECT 2")
+	require.NoError(t, err)
+
+	tx2, err := cl.BeginTransaction(ctx)
+	require.NoError(t, err)
+	_, err = tx2.ExecuteUpdate(ctx, "DELETE FROM t")
+	require.NoError(t, err)
+	require.NoError(t, tx2.Rollback(ctx))
+
+	assert.Equal(t, []string{
+		"query:txn-1",
+		"update:txn-1",
+		"prepare:txn-1",
+		"END_TRANSACTION_COMMIT:txn-1",
+		"query:",
+		"update:txn-2",
+		"END_TRANSACTION_ROLLBACK:txn-2",
+	}, txnSrv.seen)
+
+	for _, tx := range []*flightsql.Txn{tx, tx2} {
+		// as before ErrTxnFinished, callers check the ID to tell whether
+		// the transaction is still in progress
+		assert.False(t, tx.ID().IsValid())
+		_, err = tx.Execute(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, flightsql.ErrTxnFinished)
+		_, err = tx.ExecuteUpdate(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, flightsql.ErrTxnFinished)
+		_, err = tx.Prepare(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, flightsql.ErrTxnFinished)
+		assert.ErrorIs(t, tx.Commit(ctx), flightsql.ErrTxnFinished)
+		assert.ErrorIs(t, tx.Rollback(ctx), flightsql.ErrInvalidTxn)
+	}
+	assert.Len(t, txnSrv.seen, 7)
+}
+
+// commitTokenServer reports a commit token when a transaction commits.
+type commitTokenServer struct {
+	txnRecordingServer
+}
+
+func (s *commitTokenServer) EndTransactionWithResult(_ context.Context, req flightsql.ActionEndTransactionRequest) ([]byte, error) {
+	s.record(req.GetAction().String(), req.GetTransactionId())
+	if req.GetAction() == flightsql.EndTransactionCommit {
+		return []byte("lsn:" + string(req.GetTransactionId())), nil
+	}
+	return nil, nil
+}
+
+func TestClientCommitWithResult(t *testing.T) {
+	txnSrv := &commitTokenServer{}
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(txnSrv))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	tx, err := cl.BeginTransaction(ctx)
+	require.NoError(t, err)
+	token, err := tx.CommitWithResult(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("lsn:txn-1"), token)
+	_, err = tx.CommitWithResult(ctx)
+	assert.ErrorIs(t, err, flightsql.ErrTxnFinished)
+
+	// the result is dropped by Commit and Rollback
+	tx, err = cl.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+	tx, err = cl.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback(ctx))
+
+	assert.Equal(t, []string{
+		"END_TRANSACTION_COMMIT:txn-1",
+		"END_TRANSACTION_COMMIT:txn-2",
+		"END_TRANSACTION_ROLLBACK:txn-3",
+	}, txnSrv.seen)
+
+	// servers only implementing EndTransaction report no result
+	plainSrv := flight.NewServerWithMiddleware(nil)
+	plainSrv.RegisterFlightService(flightsql.NewFlightServer(&txnRecordingServer{}))
+	require.NoError(t, plainSrv.Init("localhost:0"))
+	go plainSrv.Serve()
+	defer plainSrv.Shutdown()
+
+	plain, err := flightsql.NewClient(plainSrv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer plain.Close()
+
+	tx, err = plain.BeginTransaction(ctx)
+	require.NoError(t, err)
+	token, err = tx.CommitWithResult(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, token)
+}
+
+func TestClientEndTransactionFailure(t *testing.T) {
+	txnSrv := &txnRecordingServer{}
+	cl := flightsqltest.StartServer(t, txnSrv)
+
+	ctx := context.Background()
+	tx, err := cl.BeginTransaction(ctx)
+	require.NoError(t, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Equal(t, codes.Canceled, status.Code(tx.Commit(canceled)))
+
+	// the request may have reached the server before failing, so the
+	// transaction is not ended again
+	assert.False(t, tx.ID().IsValid())
+	assert.ErrorIs(t, tx.Commit(ctx), flightsql.ErrTxnFinished)
+	_, err = tx.Execute(ctx, "SELECT 1")
+	assert.ErrorIs(t, err, flightsql.ErrTxnFinished)
+	assert.Empty(t, txnSrv.seen)
+}
+
+func TestParseSqlInfoResult(t *testing.T) {
+	info := flightsql.SqlInfoResultMap{
+		uint32(flightsql.SqlInfoFlightSqlServerName):     "parser",
+		uint32(flightsql.SqlInfoFlightSqlServerReadOnly): true,
+		uint32(flightsql.SqlInfoMaxBinaryLiteralLen):     int64(1024),
+		uint32(flightsql.SqlInfoSupportedGroupBy):        int32(3),
+		uint32(flightsql.SqlInfoKeywords):                []string{"ABSOLUTE", "ACTION"},
+		uint32(flightsql.SqlInfoSupportsConvert): map[int32][]int32{
+			int32(flightsql.SqlConvertBigInt):  {int32(flightsql.SqlConvertInteger), int32(flightsql.SqlConvertVarchar)},
+			int32(flightsql.SqlConvertVarchar): {int32(flightsql.SqlConvertBigInt)},
+			int32(flightsql.SqlConvertBit):     {},
+		},
+	}
+
+	base := flightsql.NewBaseServer()
+	require.NoError(t, base.RegisterSqlInfoMap(info))
+
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&base))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	flightInfo, err := cl.GetSqlInfo(ctx, nil)
+	require.NoError(t, err)
+	rdr, err := cl.ReadFlightInfo(ctx, flightInfo)
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	got, err := flightsql.ParseSqlInfoResult(rdr)
+	require.NoError(t, err)
+	assert.Equal(t, info, got)
+}
+
+func TestParseSqlInfoResultChildOrder(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	build := func(dt arrow.DataType, data string) arrow.Array {
+		arr, _, err := array.FromJSON(mem, dt, strings.NewReader(data))
+		require.NoError(t, err)
+		return arr
+	}
+
+	names := build(arrow.PrimitiveTypes.Uint32, `[0, 3]`)
+	defer names.Release()
+	typeIDs := build(arrow.PrimitiveTypes.Int8, `[7, 2]`)
+	defer typeIDs.Release()
+	offsets := build(arrow.PrimitiveTypes.Int32, `[0, 0]`)
+	defer offsets.Release()
+	bools := build(arrow.FixedWidthTypes.Boolean, `[true]`)
+	defer bools.Release()
+	strs := build(arrow.BinaryTypes.String, `["reordered"]`)
+	defer strs.Release()
+
+	// the children are in a different order and use different type codes
+	// than in the schema of the Flight SQL specification
+	values, err := array.NewDenseUnionFromArraysWithFieldCodes(typeIDs, offsets,
+		[]arrow.Array{bools, strs}, []string{"bool_value", "string_value"}, []arrow.UnionTypeCode{2, 7})
+	require.NoError(t, err)
+	defer values.Release()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "info_name", Type: arrow.PrimitiveTypes.Uint32},
+		{Name: "value", Type: values.DataType()},
+	}, nil)
+	rec := array.NewRecord(schema, []arrow.Array{names, values}, 2)
+	defer rec.Release()
+
+	rdr, err := array.NewRecordReader(schema, []arrow.Record{rec})
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	got, err := flightsql.ParseSqlInfoResult(rdr)
+	require.NoError(t, err)
+	assert.Equal(t, flightsql.SqlInfoResultMap{0: "reordered", 3: true}, got)
+}
+
+// cancelActionServer is a bare Flight server which only supports either
+// the legacy CancelQuery action or the CancelFlightInfo action.
+type cancelActionServer struct {
+	flight.BaseFlightServer
+	legacy    bool
+	listed    atomic.Int32
+	cancelled atomic.Int32
+}
+
+func (s *cancelActionServer) ListActions(_ *flight.Empty, stream flight.FlightService_ListActionsServer) error {
+	s.listed.Add(1)
+	action := flight.CancelFlightInfoActionType
+	if s.legacy {
+		action = flightsql.CancelQueryActionType
+	}
+	return stream.Send(&flight.ActionType{Type: action})
+}
+
+func (s *cancelActionServer) DoAction(action *flight.Action, stream flight.FlightService_DoActionServer) error {
+	switch {
+	case s.legacy && action.Type == flightsql.CancelQueryActionType:
+		var (
+			anycmd  anypb.Any
+			request pb.ActionCancelQueryRequest
+		)
+		if err := proto.Unmarshal(action.Body, &anycmd); err != nil {
+			return err
+		}
+		if err := anycmd.UnmarshalTo(&request); err != nil {
+			return err
+		}
+		if len(request.GetInfo()) == 0 {
+			return status.Error(codes.InvalidArgument, "missing FlightInfo")
+		}
+
+		s.cancelled.Add(1)
+		result, err := anypb.New(&pb.ActionCancelQueryResult{Result: flightsql.CancelResultCancelled})
+		if err != nil {
+			return err
+		}
+		body, err := proto.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return stream.Send(&pb.Result{Body: body})
+	case !s.legacy && action.Type == flight.CancelFlightInfoActionType:
+		var request flight.CancelFlightInfoRequest
+		if err := proto.Unmarshal(action.Body, &request); err != nil {
+			return err
+		}
+		if request.GetInfo() == nil {
+			return status.Error(codes.InvalidArgument, "missing FlightInfo")
+		}
+
+		s.cancelled.Add(1)
+		body, err := proto.Marshal(&flight.CancelFlightInfoResult{Status: flight.CancelStatusCancelling})
+		if err != nil {
+			return err
+		}
+		return stream.Send(&pb.Result{Body: body})
+	}
+	return status.Errorf(codes.Unimplemented, "action %s not supported", action.Type)
+}
+
+func TestClientCancel(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		legacy   bool
+		expected flightsql.CancelResult
+	}{
+		{"CancelQuery", true, flightsql.CancelResultCancelled},
+		{"CancelFlightInfo", false, flightsql.CancelResultCancelling},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cancelSrv := &cancelActionServer{legacy: tc.legacy}
+			srv := flight.NewServerWithMiddleware(nil)
+			srv.RegisterFlightService(cancelSrv)
+			require.NoError(t, srv.Init("localhost:0"))
+			go srv.Serve()
+			defer srv.Shutdown()
+
+			cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+			require.NoError(t, err)
+			defer cl.Close()
+
+			info := &flight.FlightInfo{FlightDescriptor: &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("query")}}
+			for i := 0; i < 2; i++ {
+				result, err := cl.Cancel(context.Background(), info)
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, result)
+			}
+
+			assert.EqualValues(t, 2, cancelSrv.cancelled.Load())
+			// the supported action is only probed once
+			assert.EqualValues(t, 1, cancelSrv.listed.Load())
+		})
+	}
+}
+
+// blockingStreamServer holds each DoGet stream until unblock is closed,
+// signalling on started as the handler begins.
+type blockingStreamServer struct {
+	flightsql.BaseServer
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (s *blockingStreamServer) DoGetStatement(ctx context.Context, _ flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	s.started <- struct{}{}
+	select {
+	case <-s.unblock:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).Append(1)
+
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+	close(ch)
+	return latencySchema, ch, nil
+}
+
+func TestMaxConcurrentStreams(t *testing.T) {
+	const limit = 2
+
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte("query"))
+	require.NoError(t, err)
+
+	start := func(t *testing.T, opts ...flightsql.FlightServerOption) (*blockingStreamServer, *flightsql.Client) {
+		blocking := &blockingStreamServer{
+			started: make(chan struct{}, limit+1),
+			unblock: make(chan struct{}),
+		}
+		srv := flight.NewServerWithMiddleware(nil)
+		srv.RegisterFlightService(flightsql.NewFlightServerWithAllocator(blocking, nil, opts...))
+		require.NoError(t, srv.Init("localhost:0"))
+		go srv.Serve()
+		t.Cleanup(srv.Shutdown)
+
+		cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+		require.NoError(t, err)
+		t.Cleanup(func() { cl.Close() })
+		return blocking, cl
+	}
+
+	doGet := func(cl *flightsql.Client, errs chan<- error) {
+		rdr, err := cl.DoGet(context.Background(), &flight.Ticket{Ticket: tkt})
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer rdr.Release()
+		for rdr.Next() {
+		}
+		errs <- rdr.Err()
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		blocking, cl := start(t, flightsql.WithMaxConcurrentStreams(limit))
+
+		errs := make(chan error, limit)
+		for i := 0; i < limit; i++ {
+			go doGet(cl, errs)
+		}
+		for i := 0; i < limit; i++ {
+			<-blocking.started
+		}
+
+		_, err := cl.DoGet(context.Background(), &flight.Ticket{Ticket: tkt})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		close(blocking.unblock)
+		for i := 0; i < limit; i++ {
+			assert.NoError(t, <-errs)
+		}
+
+		// the finished streams are available again
+		rdr, err := cl.DoGet(context.Background(), &flight.Ticket{Ticket: tkt})
+		require.NoError(t, err)
+		rdr.Release()
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		blocking, cl := start(t, flightsql.WithMaxConcurrentStreams(limit),
+			flightsql.WithStreamWaitTimeout(time.Minute))
+
+		errs := make(chan error, limit+1)
+		for i := 0; i < limit+1; i++ {
+			go doGet(cl, errs)
+		}
+		for i := 0; i < limit; i++ {
+			<-blocking.started
+		}
+
+		// the extra stream is queued until another one finishes
+		select {
+		case <-blocking.started:
+			t.Fatal("stream started beyond the limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(blocking.unblock)
+		<-blocking.started
+		for i := 0; i < limit+1; i++ {
+			assert.NoError(t, <-errs)
+		}
+	})
+
+	t.Run("wait timeout", func(t *testing.T) {
+		blocking, cl := start(t, flightsql.WithMaxConcurrentStreams(limit),
+			flightsql.WithStreamWaitTimeout(10*time.Millisecond))
+		defer close(blocking.unblock)
+
+		errs := make(chan error, limit)
+		for i := 0; i < limit; i++ {
+			go doGet(cl, errs)
+		}
+		for i := 0; i < limit; i++ {
+			<-blocking.started
+		}
+
+		_, err := cl.DoGet(context.Background(), &flight.Ticket{Ticket: tkt})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+}
+
+func TestClientOutgoingMetadata(t *testing.T) {
+	var (
+		mx    sync.Mutex
+		calls = map[string][]string{}
+	)
+	record := func(ctx context.Context, method string) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		mx.Lock()
+		defer mx.Unlock()
+		calls[method] = append(calls[method], md.Get("x-trace-id")...)
+	}
+
+	srv := flight.NewServerWithMiddleware([]flight.ServerMiddleware{{
+		Unary: func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			record(ctx, info.FullMethod)
+			return handler(ctx, req)
+		},
+		Stream: func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			record(stream.Context(), info.FullMethod)
+			return handler(srv, stream)
+		},
+	}})
+	srv.RegisterFlightService(flightsql.NewFlightServer(&latencyServer{endpoints: 3, batches: 1, slow: -1, fail: -1}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	rdr, err := cl.ExecuteQuery(context.Background(), "SELECT 1",
+		flightsql.WithOutgoingMetadata("X-Trace-Id", "trace-1"),
+		grpc.MaxCallRecvMsgSize(1<<20), flightsql.WithEndpointConcurrency(2))
+	require.NoError(t, err)
+	rows := 0
+	for rdr.Next() {
+		rows += int(rdr.Record().NumRows())
+	}
+	require.NoError(t, rdr.Err())
+	rdr.Release()
+	assert.Equal(t, 3, rows)
+
+	mx.Lock()
+	defer mx.Unlock()
+	assert.Equal(t, map[string][]string{
+		"/arrow.flight.protocol.FlightService/GetFlightInfo": {"trace-1"},
+		"/arrow.flight.protocol.FlightService/DoGet":         {"trace-1", "trace-1", "trace-1"},
+	}, calls)
+}
+
+// decimal256Server returns a single record of decimal256 values for any
+// statement.
+type decimal256Server struct {
+	flightsql.BaseServer
+	rec arrow.Record
+}
+
+func (s *decimal256Server) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	ch := make(chan flight.StreamChunk, 1)
+	s.rec.Retain()
+	ch <- flight.StreamChunk{Data: s.rec}
+	close(ch)
+	return s.rec.Schema(), ch, nil
+}
+
+func TestDoGetDecimal256(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	dt := &arrow.Decimal256Type{Precision: 60, Scale: 10}
+	fraction, err := decimal256.FromString("-12345678901234567890.0123456789", dt.Precision, dt.Scale)
+	require.NoError(t, err)
+	values := []decimal256.Num{
+		decimal256.GetMaxValue(dt.Precision),
+		decimal256.GetMaxValue(dt.Precision).Negate(),
+		decimal256.FromI64(0),
+		{},
+		fraction,
+		decimal256.FromI64(-1),
+	}
+	valid := []bool{true, true, true, false, true, true}
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "amount", Type: dt, Nullable: true}}, nil)
+	bldr := array.NewRecordBuilder(mem, schema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Decimal256Builder).AppendValues(values, valid)
+	rec := bldr.NewRecord()
+	defer rec.Release()
+
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&decimal256Server{rec: rec}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+	cl.Alloc = mem
+
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte("SELECT amount"))
+	require.NoError(t, err)
+	rdr, err := cl.DoGet(context.Background(), &flight.Ticket{Ticket: tkt})
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	assert.Truef(t, schema.Equal(rdr.Schema()), "expected: %s\ngot: %s", schema, rdr.Schema())
+	require.True(t, rdr.Next())
+	got := rdr.Record().Column(0).(*array.Decimal256)
+	require.Equal(t, len(values), got.Len())
+	for i, v := range values {
+		assert.Equal(t, !valid[i], got.IsNull(i), "null at %d", i)
+		if valid[i] {
+			assert.Equal(t, v.Array(), got.Value(i).Array(), "value at %d", i)
+		}
+	}
+	assert.False(t, rdr.Next())
+	require.NoError(t, rdr.Err())
+}
+
+// emptyResultServer returns no records for any statement.
+type emptyResultServer struct {
+	flightsql.BaseServer
+}
+
+var emptyResultSchema = arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+func (*emptyResultServer) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	ch := make(chan flight.StreamChunk)
+	close(ch)
+	return emptyResultSchema, ch, nil
+}
+
+func TestDoGetEmptyResult(t *testing.T) {
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&emptyResultServer{}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte("SELECT id WHERE false"))
+	require.NoError(t, err)
+	rdr, err := cl.DoGet(context.Background(), &flight.Ticket{Ticket: tkt})
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	// the schema is sent even though no record is
+	assert.Truef(t, emptyResultSchema.Equal(rdr.Schema()), "expected: %s\ngot: %s", emptyResultSchema, rdr.Schema())
+	assert.False(t, rdr.Next())
+	assert.NoError(t, rdr.Err())
+}
+
+// dictionaryServer returns records of a dictionary-encoded column whose
+// dictionary grows from one record to the next, then is replaced.
+type dictionaryServer struct {
+	flightsql.BaseServer
+}
+
+var dictionaryResultType = &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+
+var dictionaryResultSchema = arrow.NewSchema([]arrow.Field{{Name: "v", Type: dictionaryResultType}}, nil)
+
+// dictionaryResults are the values of each record returned by
+// dictionaryServer, each record using the whole of its dictionary.
+var dictionaryResults = [][]string{
+	{strings.Repeat("a", 1024), strings.Repeat("b", 1024)},
+	{strings.Repeat("a", 1024), strings.Repeat("b", 1024), strings.Repeat("c", 1024)},
+	{strings.Repeat("a", 1024), strings.Repeat("b", 1024), strings.Repeat("c", 1024), strings.Repeat("d", 1024)},
+	{"x"},
+}
+
+func (*dictionaryServer) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	ch := make(chan flight.StreamChunk, len(dictionaryResults))
+	for _, values := range dictionaryResults {
+		dict := array.NewStringBuilder(memory.DefaultAllocator)
+		dict.AppendValues(values, nil)
+		dictArr := dict.NewArray()
+		dict.Release()
+
+		indices := array.NewInt32Builder(memory.DefaultAllocator)
+		for i := range values {
+			indices.Append(int32(i))
+		}
+		indicesArr := indices.NewArray()
+		indices.Release()
+
+		col := array.NewDictionaryArray(dictionaryResultType, indicesArr, dictArr)
+		ch <- flight.StreamChunk{Data: array.NewRecord(dictionaryResultSchema, []arrow.Array{col}, int64(col.Len()))}
+		col.Release()
+		indicesArr.Release()
+		dictArr.Release()
+	}
+	close(ch)
+	return dictionaryResultSchema, ch, nil
+}
+
+func TestDoGetDictionaryDeltas(t *testing.T) {
+	// readDictionaries returns the values of each record of the result
+	// and the total size of its dictionary batches.
+	readDictionaries := func(opts ...flightsql.FlightServerOption) ([][]string, int64) {
+		s := flight.NewServerWithMiddleware(nil)
+		s.RegisterFlightService(flightsql.NewFlightServer(&dictionaryServer{}, opts...))
+		require.NoError(t, s.Init("localhost:0"))
+		go s.Serve()
+		defer s.Shutdown()
+
+		cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+		require.NoError(t, err)
+		defer cl.Close()
+
+		ctx := context.Background()
+		tkt, err := flightsql.CreateStatementQueryTicket([]byte("SELECT v"))
+		require.NoError(t, err)
+
+		rdr, err := cl.DoGet(ctx, &flight.Ticket{Ticket: tkt})
+		require.NoError(t, err)
+		defer rdr.Release()
+		var got [][]string
+		for rdr.Next() {
+			col := rdr.Record().Column(0).(*array.Dictionary)
+			dict := col.Dictionary().(*array.String)
+			values := make([]string, col.Len())
+			for i := range values {
+				values[i] = dict.Value(col.GetValueIndex(i))
+			}
+			got = append(got, values)
+		}
+		require.NoError(t, rdr.Err())
+
+		stream, err := cl.Client.DoGet(ctx, &flight.Ticket{Ticket: tkt})
+		require.NoError(t, err)
+		var size int64
+		for {
+			data, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			msg := ipc.NewMessage(memory.NewBufferBytes(data.DataHeader), memory.NewBufferBytes(data.DataBody))
+			if msg.Type() == ipc.MessageDictionaryBatch {
+				size += msg.BodyLen()
+			}
+			msg.Release()
+		}
+		return got, size
+	}
+
+	full, fullSize := readDictionaries()
+	deltas, deltaSize := readDictionaries(flightsql.WithDictionaryDeltas())
+	assert.Equal(t, dictionaryResults, full)
+	assert.Equal(t, dictionaryResults, deltas)
+	// the values appended are sent once rather than with every record
+	assert.Less(t, deltaSize, fullSize-3*1024)
+}
+
+// repetitiveServer returns a record of the same value repeated, which
+// compresses well.
+type repetitiveServer struct {
+	flightsql.BaseServer
+}
+
+const repetitiveRows = 10000
+
+func (*repetitiveServer) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+	defer bldr.Release()
+	for i := 0; i < repetitiveRows; i++ {
+		bldr.Field(0).(*array.Int64Builder).Append(42)
+	}
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+	close(ch)
+	return latencySchema, ch, nil
+}
+
+// wireCounter counts the bytes of the messages received by a client as
+// sent on the wire, before decompression.
+type wireCounter struct {
+	received int64
+}
+
+func (c *wireCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context   { return ctx }
+func (c *wireCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+func (c *wireCounter) HandleConn(context.Context, stats.ConnStats)                       {}
+
+func (c *wireCounter) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if in, ok := s.(*stats.InPayload); ok {
+		atomic.AddInt64(&c.received, int64(in.CompressedLength))
+	}
+}
+
+func TestDoGetGzipCompression(t *testing.T) {
+	// readResult returns the values of the result and the number of bytes
+	// received for them. The client advertises gzip, which is registered
+	// by the flightsql package, without compressing its own messages.
+	readResult := func(opts ...flightsql.FlightServerOption) ([]int64, int64) {
+		s := flight.NewServerWithMiddleware(nil)
+		s.RegisterFlightService(flightsql.NewFlightServer(&repetitiveServer{}, opts...))
+		require.NoError(t, s.Init("localhost:0"))
+		go s.Serve()
+		defer s.Shutdown()
+
+		var counter wireCounter
+		cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, append(dialOpts, grpc.WithStatsHandler(&counter))...)
+		require.NoError(t, err)
+		defer cl.Close()
+
+		tkt, err := flightsql.CreateStatementQueryTicket([]byte("SELECT 42"))
+		require.NoError(t, err)
+		rdr, err := cl.DoGet(context.Background(), &flight.Ticket{Ticket: tkt})
+		require.NoError(t, err)
+		defer rdr.Release()
+
+		var got []int64
+		for rdr.Next() {
+			got = append(got, rdr.Record().Column(0).(*array.Int64).Int64Values()...)
+		}
+		require.NoError(t, rdr.Err())
+		return got, atomic.LoadInt64(&counter.received)
+	}
+
+	want := make([]int64, repetitiveRows)
+	for i := range want {
+		want[i] = 42
+	}
+
+	plain, plainSize := readResult()
+	compressed, compressedSize := readResult(flightsql.WithGzipCompression())
+	assert.Equal(t, want, plain)
+	assert.Equal(t, want, compressed)
+	assert.Greater(t, plainSize, int64(8*repetitiveRows))
+	assert.Less(t, compressedSize, plainSize/10)
+}
+
+// statelessServer keeps no state between GetFlightInfoStatement and
+// DoGetStatement, the query "SELECT <n>" being carried by the ticket.
+type statelessServer struct {
+	flightsql.BaseServer
+}
+
+func (s *statelessServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return flightsql.NewStatementFlightInfo(desc, latencySchema, s.Alloc, []byte(cmd.GetQuery()))
+}
+
+func (s *statelessServer) DoGetStatement(_ context.Context, tkt flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	n, err := strconv.ParseInt(strings.TrimPrefix(string(tkt.GetStatementHandle()), "SELECT "), 10, 64)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).Append(n)
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+	close(ch)
+	return latencySchema, ch, nil
+}
+
+func TestStatelessStatementTicket(t *testing.T) {
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&statelessServer{}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	for _, n := range []int64{1, -7, 42} {
+		query := fmt.Sprintf("SELECT %d", n)
+		info, err := cl.Execute(ctx, query)
+		require.NoError(t, err)
+		require.Len(t, info.GetEndpoint(), 1)
+
+		tkt, err := flightsql.GetStatementQueryTicket(info.GetEndpoint()[0].GetTicket())
+		require.NoError(t, err)
+		assert.Equal(t, query, string(tkt.GetStatementHandle()))
+
+		rdr, err := cl.DoGet(ctx, info.GetEndpoint()[0].GetTicket())
+		require.NoError(t, err)
+		require.True(t, rdr.Next())
+		assert.Equal(t, n, rdr.Record().Column(0).(*array.Int64).Value(0))
+		assert.False(t, rdr.Next())
+		assert.NoError(t, rdr.Err())
+		rdr.Release()
+	}
+
+	// handles which aren't valid UTF-8 survive the packing into the ticket
+	handle := []byte{0, 0xff, 0xfe, 'q', 0x80}
+	tkt, err := flightsql.TicketStatementQuery(handle)
+	require.NoError(t, err)
+	got, err := flightsql.GetStatementQueryTicket(tkt)
+	require.NoError(t, err)
+	assert.Equal(t, handle, got.GetStatementHandle())
+
+	// the locations of the endpoints are checked
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD}
+	ep, err := flight.NewFlightEndpoint(tkt, "grpc+tcp://[::1]:1234", flight.LocationReuseConnection)
+	require.NoError(t, err)
+	info, err := flightsql.NewStatementFlightInfo(desc, latencySchema, nil, handle, flightsql.WithEndpoints(ep))
+	require.NoError(t, err)
+	assert.Len(t, info.Endpoint[0].Location, 2)
+	_, err = flightsql.NewStatementFlightInfo(desc, latencySchema, nil, handle,
+		flightsql.WithEndpoints(&flight.FlightEndpoint{Ticket: tkt, Location: []*flight.Location{{Uri: "grpc+tcp://nowhere"}}}))
+	assert.ErrorIs(t, err, arrow.ErrInvalid)
+}
+
+// healthTestServer reports its database as unreachable once down is set.
+type healthTestServer struct {
+	flightsql.BaseServer
+	down atomic.Bool
+}
+
+func (s *healthTestServer) HealthCheck(context.Context) (string, error) {
+	if s.down.Load() {
+		return "", status.Error(codes.Unavailable, "database unreachable")
+	}
+	return "DB_OK", nil
+}
+
+func TestHealthCheck(t *testing.T) {
+	healthSrv := &healthTestServer{}
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&flightsql.BaseServer{}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	custom := flight.NewServerWithMiddleware(nil)
+	custom.RegisterFlightService(flightsql.NewFlightServer(healthSrv))
+	require.NoError(t, custom.Init("localhost:0"))
+	go custom.Serve()
+	defer custom.Shutdown()
+
+	ctx := context.Background()
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	actions, err := cl.Client.ListActions(ctx, &flight.Empty{})
+	require.NoError(t, err)
+	var found bool
+	for {
+		a, err := actions.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		found = found || a.Type == flightsql.HealthCheckActionType
+	}
+	assert.True(t, found, "HealthCheck missing from ListActions")
+
+	stream, err := cl.Client.DoAction(ctx, &flight.Action{Type: flightsql.HealthCheckActionType})
+	require.NoError(t, err)
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	require.NoError(t, flight.ReadUntilEOF(stream))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(res.Body, &body))
+	assert.Equal(t, flightsql.HealthStatusServing, body["status"])
+	assert.GreaterOrEqual(t, body["uptime_ms"], float64(0))
+
+	time.Sleep(5 * time.Millisecond)
+	result, err := cl.HealthCheck(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, flightsql.HealthStatusServing, result.Status)
+	assert.GreaterOrEqual(t, result.Uptime, 5*time.Millisecond)
+
+	customCl, err := flightsql.NewClient(custom.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer customCl.Close()
+
+	result, err = customCl.HealthCheck(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "DB_OK", result.Status)
+
+	healthSrv.down.Store(true)
+	_, err = customCl.HealthCheck(ctx)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+// explainTestServer prepares statements whose handle is their query and
+// explains them with a stub plan.
+type explainTestServer struct {
+	flightsql.BaseServer
+}
+
+func (*explainTestServer) CreatePreparedStatement(_ context.Context, req flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	return flightsql.ActionCreatePreparedStatementResult{Handle: []byte(req.GetQuery())}, nil
+}
+
+func (*explainTestServer) ClosePreparedStatement(context.Context, flightsql.ActionClosePreparedStatementRequest) error {
+	return nil
+}
+
+func (*explainTestServer) ExplainPreparedStatement(_ context.Context, cmd flightsql.PreparedStatementQuery) (string, error) {
+	return "Scan: " + string(cmd.GetPreparedStatementHandle()), nil
+}
+
+func TestExplainPreparedStatement(t *testing.T) {
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&explainTestServer{}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	unsupported := flight.NewServerWithMiddleware(nil)
+	unsupported.RegisterFlightService(flightsql.NewFlightServer(&rotatingTestServer{}))
+	require.NoError(t, unsupported.Init("localhost:0"))
+	go unsupported.Serve()
+	defer unsupported.Shutdown()
+
+	ctx := context.Background()
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	actions, err := cl.Client.ListActions(ctx, &flight.Empty{})
+	require.NoError(t, err)
+	var found bool
+	for {
+		a, err := actions.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		found = found || a.Type == flightsql.ExplainPreparedStatementActionType
+	}
+	assert.True(t, found, "ExplainPreparedStatement missing from ListActions")
+
+	prep, err := cl.Prepare(ctx, "SELECT * FROM t")
+	require.NoError(t, err)
+	plan, err := prep.Explain(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Scan: SELECT * FROM t", plan)
+
+	require.NoError(t, prep.Close(ctx))
+	_, err = prep.Explain(ctx)
+	assert.ErrorIs(t, err, flightsql.ErrPreparedStatementClosed)
+
+	unsupportedCl, err := flightsql.NewClient(unsupported.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer unsupportedCl.Close()
+
+	prep, err = unsupportedCl.Prepare(ctx, "SELECT ?")
+	require.NoError(t, err)
+	defer prep.Close(ctx)
+	_, err = prep.Explain(ctx)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+// estimateTestServer knows the size of its results up front.
+type estimateTestServer struct {
+	flightsql.BaseServer
+}
+
+func (s *estimateTestServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	switch cmd.GetQuery() {
+	case "SELECT unknown":
+		return flightsql.NewFlightInfo(desc, schema, s.Alloc), nil
+	case "SELECT id FROM t ORDER BY id":
+		return flightsql.NewFlightInfo(desc, schema, s.Alloc,
+			flightsql.WithTotalRecords(42), flightsql.WithOrdered()), nil
+	}
+	return flightsql.NewFlightInfo(desc, schema, s.Alloc,
+		flightsql.WithTotalRecords(42), flightsql.WithTotalBytes(42*8)), nil
+}
+
+func TestFlightInfoEstimates(t *testing.T) {
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&estimateTestServer{BaseServer: flightsql.NewBaseServer()}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	info, err := cl.Execute(context.Background(), "SELECT id FROM t")
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, info.TotalRecords)
+	assert.EqualValues(t, 42*8, info.TotalBytes)
+	assert.False(t, info.Ordered)
+
+	info, err = cl.Execute(context.Background(), "SELECT id FROM t ORDER BY id")
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, info.TotalRecords)
+	assert.True(t, info.Ordered)
+
+	info, err = cl.Execute(context.Background(), "SELECT unknown")
+	require.NoError(t, err)
+	assert.Equal(t, flightsql.TotalUnknown, info.TotalRecords)
+	assert.Equal(t, flightsql.TotalUnknown, info.TotalBytes)
+}
+
+func TestWithTotalsFromRecords(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	first, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(`[{"id": 1}, {"id": 2}]`))
+	require.NoError(t, err)
+	defer first.Release()
+	second, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(`[{"id": 3}]`))
+	require.NoError(t, err)
+	defer second.Release()
+
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("cmd")}
+	info := flightsql.NewFlightInfo(desc, schema, nil, flightsql.WithTotalsFromRecords(first, second))
+	assert.EqualValues(t, 3, info.TotalRecords)
+	assert.Positive(t, info.TotalBytes)
+	assert.Equal(t, []byte("cmd"), info.Endpoint[0].Ticket.Ticket)
+}
+
+type sqlInfoTestServer struct {
+	flightsql.BaseServer
+}
+
+func testConcurrentSqlInfo(t *testing.T, srv *sqlInfoTestServer) {
+	require.NoError(t, srv.RegisterSqlInfo(flightsql.SqlInfoFlightSqlServerName, "concurrent"))
+
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	const n = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.Background()
+			info, err := cl.GetSqlInfo(ctx, []flightsql.SqlInfo{flightsql.SqlInfoFlightSqlServerName})
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			rdr, err := cl.DoGet(ctx, info.Endpoint[0].Ticket)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer rdr.Release()
+
+			var rows int64
+			for rdr.Next() {
+				rows += rdr.Record().NumRows()
+			}
+			if err := rdr.Err(); err != nil {
+				errs <- err
+			} else if rows != 1 {
+				errs <- fmt.Errorf("expected 1 row, got %d", rows)
+			}
+		}()
+
+		// the info may be registered while requests are served
+		version := fmt.Sprintf("v%d", i)
+		if i%2 == 0 {
+			assert.NoError(t, srv.RegisterSqlInfo(flightsql.SqlInfoFlightSqlServerVersion, version))
+		} else {
+			assert.NoError(t, srv.RegisterSqlInfoMap(flightsql.SqlInfoResultMap{
+				uint32(flightsql.SqlInfoFlightSqlServerName):    "concurrent",
+				uint32(flightsql.SqlInfoFlightSqlServerVersion): version,
+			}))
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestConcurrentSqlInfo(t *testing.T) {
+	t.Run("zero value", func(t *testing.T) {
+		testConcurrentSqlInfo(t, &sqlInfoTestServer{})
+	})
+
+	t.Run("NewBaseServer", func(t *testing.T) {
+		mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+		defer mem.AssertSize(t, 0)
+
+		srv := &sqlInfoTestServer{BaseServer: flightsql.NewBaseServer(flightsql.WithBaseServerAllocator(mem))}
+		assert.Same(t, mem, srv.Alloc)
+		testConcurrentSqlInfo(t, srv)
+	})
+}
+
+// ddlTestServer can't report the number of rows affected by DDL.
+type ddlTestServer struct {
+	updateTestServer
+}
+
+func (*ddlTestServer) DoPutCommandStatementUpdate(_ context.Context, cmd flightsql.StatementUpdate) (int64, error) {
+	switch {
+	case strings.HasPrefix(cmd.GetQuery(), "CREATE"):
+		return flightsql.UpdateResultUnknown, nil
+	case strings.HasPrefix(cmd.GetQuery(), "DROP"):
+		// some backends report other negative counts
+		return -2, nil
+	}
+	return 0, nil
+}
+
+func (*ddlTestServer) DoPutPreparedStatementUpdate(context.Context, flightsql.PreparedStatementUpdate, flight.MessageReader) (int64, error) {
+	return flightsql.UpdateResultUnknown, nil
+}
+
+func TestUpdateResultUnknown(t *testing.T) {
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&ddlTestServer{
+		updateTestServer: updateTestServer{open: make(map[string]bool)},
+	}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	n, err := cl.ExecuteUpdate(ctx, "CREATE TABLE t (id INTEGER)")
+	require.NoError(t, err)
+	assert.Equal(t, flightsql.UpdateResultUnknown, n)
+	_, known := flightsql.AffectedRows(n)
+	assert.False(t, known)
+
+	n, err = cl.ExecuteUpdate(ctx, "DELETE FROM t")
+	require.NoError(t, err)
+	rows, known := flightsql.AffectedRows(n)
+	assert.True(t, known)
+	assert.Zero(t, rows)
+
+	n, err = cl.ExecuteUpdate(ctx, "DROP TABLE t")
+	require.NoError(t, err)
+	assert.Equal(t, flightsql.UpdateResultUnknown, n)
+
+	prep, err := cl.Prepare(ctx, "CREATE INDEX idx ON t (id)")
+	require.NoError(t, err)
+	defer prep.Close(ctx)
+
+	n, err = prep.ExecuteUpdate(ctx)
+	require.NoError(t, err)
+	_, known = flightsql.AffectedRows(n)
+	assert.False(t, known)
+}
+
+// rotatingTestServer is a stateless server which encodes the bound
+// parameter into the prepared statement handle.
+type rotatingTestServer struct {
+	flightsql.BaseServer
+}
+
+func (*rotatingTestServer) CreatePreparedStatement(_ context.Context, req flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	return flightsql.ActionCreatePreparedStatementResult{
+		Handle: []byte(req.GetQuery()),
+		ParameterSchema: arrow.NewSchema([]arrow.Field{
+			{Name: "v", Type: arrow.BinaryTypes.String},
+		}, nil),
+	}, nil
+}
+
+func (*rotatingTestServer) ClosePreparedStatement(context.Context, flightsql.ActionClosePreparedStatementRequest) error {
+	return nil
+}
+
+func (*rotatingTestServer) DoPutPreparedStatementQueryWithHandle(_ context.Context, cmd flightsql.PreparedStatementQuery, rdr flight.MessageReader, _ flight.MetadataWriter) ([]byte, error) {
+	handle := string(cmd.GetPreparedStatementHandle())
+	for rdr.Next() {
+		col := rdr.Record().Column(0).(*array.String)
+		for i := 0; i < col.Len(); i++ {
+			handle += ":" + col.Value(i)
+		}
+	}
+	return []byte(handle), rdr.Err()
+}
+
+// DoPutPreparedStatementUpdate reports the total length of the bound
+// values as the number of affected rows.
+func (*rotatingTestServer) DoPutPreparedStatementUpdate(_ context.Context, _ flightsql.PreparedStatementUpdate, rdr flight.MessageReader) (int64, error) {
+	var n int64
+	for rdr.Next() {
+		col := rdr.Record().Column(0).(*array.String)
+		for i := 0; i < col.Len(); i++ {
+			n += int64(len(col.Value(i)))
+		}
+	}
+	return n, rdr.Err()
+}
+
+func (*rotatingTestServer) rotatedSchema(handle []byte) *arrow.Schema {
+	return arrow.NewSchema([]arrow.Field{
+		{Name: strings.ReplaceAll(string(handle), ":", "_"), Type: arrow.BinaryTypes.String},
+	}, nil)
+}
+
+func (s *rotatingTestServer) GetSchemaPreparedStatement(_ context.Context, cmd flightsql.PreparedStatementQuery, _ *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	return &flight.SchemaResult{
+		Schema: flight.SerializeSchema(s.rotatedSchema(cmd.GetPreparedStatementHandle()), memory.DefaultAllocator),
+	}, nil
+}
+
+func (*rotatingTestServer) GetFlightInfoPreparedStatement(_ context.Context, cmd flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint: []*flight.FlightEndpoint{{
+			Ticket: getTicket(&pb.CommandPreparedStatementQuery{PreparedStatementHandle: cmd.GetPreparedStatementHandle()}),
+		}},
+	}, nil
+}
+
+func (s *rotatingTestServer) DoGetPreparedStatement(_ context.Context, cmd flightsql.PreparedStatementQuery) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	handle := cmd.GetPreparedStatementHandle()
+	schema := s.rotatedSchema(handle)
+	bldr := array.NewStringBuilder(memory.DefaultAllocator)
+	defer bldr.Release()
+	bldr.Append(string(handle))
+	arr := bldr.NewArray()
+	defer arr.Release()
+
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: array.NewRecord(schema, []arrow.Array{arr}, 1)}
+	close(ch)
+	return schema, ch, nil
+}
+
+func TestPreparedStatementHandleRotation(t *testing.T) {
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&rotatingTestServer{}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	prep, err := cl.Prepare(ctx, "SELECT ?")
+	require.NoError(t, err)
+	defer prep.Close(ctx)
+
+	execute := func(param string) string {
+		rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, prep.ParameterSchema(),
+			strings.NewReader(`[{"v": "`+param+`"}]`))
+		require.NoError(t, err)
+		defer rec.Release()
+		prep.SetParameters(rec)
+
+		info, err := prep.Execute(ctx)
+		require.NoError(t, err)
+		require.Len(t, info.GetEndpoint(), 1)
+
+		rdr, err := cl.DoGet(ctx, info.GetEndpoint()[0].GetTicket())
+		require.NoError(t, err)
+		defer rdr.Release()
+		require.True(t, rdr.Next())
+		return rdr.Record().Column(0).(*array.String).Value(0)
+	}
+
+	assert.Equal(t, "SELECT ?:a", execute("a"))
+	assert.Equal(t, []byte("SELECT ?:a"), prep.Handle())
+
+	// binding again uses the rotated handle
+	assert.Equal(t, "SELECT ?:a:b", execute("b"))
+	assert.Equal(t, []byte("SELECT ?:a:b"), prep.Handle())
+
+	schema, err := prep.RefreshDatasetSchema(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT ?_a_b", schema.Field(0).Name)
+	assert.Same(t, schema, prep.DatasetSchema())
+}
+
+// staleHandleTestServer is a stateless server which issues a new handle
+// each time parameters are bound, and rejects any handle but the latest.
+type staleHandleTestServer struct {
+	flightsql.BaseServer
+
+	mx       sync.Mutex
+	issued   int
+	executed []string
+	closed   string
+}
+
+// checkHandle returns an error if handle isn't the latest handle issued.
+func (s *staleHandleTestServer) checkHandle(handle []byte) error {
+	if latest := strconv.Itoa(s.issued); string(handle) != latest {
+		return status.Errorf(codes.InvalidArgument, "stale handle %q, latest is %q", handle, latest)
+	}
+	return nil
+}
+
+func (s *staleHandleTestServer) CreatePreparedStatement(context.Context, flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return flightsql.ActionCreatePreparedStatementResult{Handle: []byte(strconv.Itoa(s.issued))}, nil
+}
+
+func (s *staleHandleTestServer) DoPutPreparedStatementQueryWithHandle(_ context.Context, cmd flightsql.PreparedStatementQuery, rdr flight.MessageReader, _ flight.MetadataWriter) ([]byte, error) {
+	for rdr.Next() {
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if err := s.checkHandle(cmd.GetPreparedStatementHandle()); err != nil {
+		return nil, err
+	}
+	s.issued++
+	return []byte(strconv.Itoa(s.issued)), rdr.Err()
+}
+
+func (s *staleHandleTestServer) GetFlightInfoPreparedStatement(_ context.Context, cmd flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if err := s.checkHandle(cmd.GetPreparedStatementHandle()); err != nil {
+		return nil, err
+	}
+	s.executed = append(s.executed, string(cmd.GetPreparedStatementHandle()))
+	return &flight.FlightInfo{FlightDescriptor: desc}, nil
+}
+
+func (s *staleHandleTestServer) ClosePreparedStatement(_ context.Context, req flightsql.ActionClosePreparedStatementRequest) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if err := s.checkHandle(req.GetPreparedStatementHandle()); err != nil {
+		return err
+	}
+	s.closed = string(req.GetPreparedStatementHandle())
+	return nil
+}
+
+func TestPreparedStatementStaleHandle(t *testing.T) {
+	srv := &staleHandleTestServer{}
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	prep, err := cl.Prepare(ctx, "SELECT ?")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("0"), prep.Handle())
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(`[{"v": 1}]`))
+	require.NoError(t, err)
+	defer rec.Release()
+	prep.SetParameters(rec)
+
+	_, err = prep.Execute(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), prep.Handle())
+	_, err = prep.Execute(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), prep.Handle())
+
+	// concurrent calls each execute with the handle bound for them
+	const concurrent = 8
+	var wg sync.WaitGroup
+	errs := make([]error, concurrent)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = prep.Execute(ctx)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []byte(strconv.Itoa(2+concurrent)), prep.Handle())
+	assert.Len(t, srv.executed, 2+concurrent)
+
+	// closing uses the latest handle
+	require.NoError(t, prep.Close(ctx))
+	assert.Equal(t, strconv.Itoa(2+concurrent), srv.closed)
+}
+
+// putMetadataTestServer writes metadata as the app metadata of the
+// result of binding parameters to its prepared statements.
+type putMetadataTestServer struct {
+	flightsql.BaseServer
+	metadata []byte
+}
+
+func (*putMetadataTestServer) CreatePreparedStatement(context.Context, flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	return flightsql.ActionCreatePreparedStatementResult{Handle: []byte("handle")}, nil
+}
+
+func (*putMetadataTestServer) ClosePreparedStatement(context.Context, flightsql.ActionClosePreparedStatementRequest) error {
+	return nil
+}
+
+func (s *putMetadataTestServer) DoPutPreparedStatementQuery(_ context.Context, _ flightsql.PreparedStatementQuery, rdr flight.MessageReader, w flight.MetadataWriter) error {
+	for rdr.Next() {
+	}
+	if err := rdr.Err(); err != nil {
+		return err
+	}
+	return w.WriteMetadata(s.metadata)
+}
+
+func (*putMetadataTestServer) GetFlightInfoPreparedStatement(_ context.Context, _ flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return &flight.FlightInfo{FlightDescriptor: desc}, nil
+}
+
+func TestPreparedStatementResultMetadata(t *testing.T) {
+	handle := protowire.AppendTag(nil, 1, protowire.BytesType)
+	tests := []struct {
+		name     string
+		metadata []byte
+		handle   string
+		err      bool
+	}{
+		{"empty", nil, "handle", false},
+		{"other message", []byte("not a handle"), "handle", false},
+		{"new handle", protowire.AppendBytes(handle, []byte("new handle")), "new handle", false},
+		{"truncated handle", append(protowire.AppendVarint(handle, 10), "short"...), "handle", true},
+	}
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(`[{"v": 1}]`))
+	require.NoError(t, err)
+	defer rec.Release()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := flightsqltest.StartServer(t, &putMetadataTestServer{metadata: tt.metadata})
+
+			ctx := context.Background()
+			prep, err := cl.Prepare(ctx, "SELECT ?")
+			require.NoError(t, err)
+			defer prep.Close(ctx)
+			prep.SetParameters(rec)
+
+			_, err = prep.Execute(ctx)
+			if tt.err {
+				assert.ErrorIs(t, err, arrow.ErrInvalid)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, []byte(tt.handle), prep.Handle())
+		})
+	}
+}
+
+func TestPreparedStatementBindParameters(t *testing.T) {
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&rotatingTestServer{}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	ctx := context.Background()
+	prep, err := cl.Prepare(ctx, "UPDATE")
+	require.NoError(t, err)
+
+	bind := func(rows string) {
+		rec, _, err := array.RecordFromJSON(mem, prep.ParameterSchema(), strings.NewReader(rows))
+		require.NoError(t, err)
+		prep.SetParameters(rec)
+		// the statement keeps its own reference
+		rec.Release()
+	}
+
+	bind(`[{"v": "a"}, {"v": "bb"}]`)
+	n, err := prep.ExecuteUpdate(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+
+	bind(`[{"v": "cccc"}]`)
+	n, err = prep.ExecuteUpdate(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, n)
+
+	// the same binding can be executed again
+	n, err = prep.ExecuteUpdate(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, n)
+
+	bind(`[{"v": "d"}]`)
+	_, err = prep.Execute(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATE:d", string(prep.Handle()))
+
+	recs := make([]arrow.Record, 2)
+	for i, rows := range []string{`[{"v": "e"}]`, `[{"v": "f"}]`} {
+		recs[i], _, err = array.RecordFromJSON(mem, prep.ParameterSchema(), strings.NewReader(rows))
+		require.NoError(t, err)
+		defer recs[i].Release()
+	}
+	rdr, err := array.NewRecordReader(prep.ParameterSchema(), recs)
+	require.NoError(t, err)
+	prep.SetRecordReader(rdr)
+	rdr.Release()
+
+	_, err = prep.Execute(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATE:d:e:f", string(prep.Handle()))
+
+	wrong := arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec, _, err := array.RecordFromJSON(mem, wrong, strings.NewReader(`[{"v": 1}]`))
+	require.NoError(t, err)
+	prep.SetParameters(rec)
+	rec.Release()
+
+	_, err = prep.Execute(ctx)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.ErrorContains(t, err, "cannot coerce parameter 0 (v) from int64 to utf8")
+	_, err = prep.ExecuteUpdate(ctx)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	require.NoError(t, prep.Close(ctx))
+}
+
+func TestGetSqlInfoValues(t *testing.T) {
+	registered := map[flightsql.SqlInfo]interface{}{
+		flightsql.SqlInfoFlightSqlServerName:        "values",
+		flightsql.SqlInfoFlightSqlServerReadOnly:    true,
+		flightsql.SqlInfoMaxBinaryLiteralLen:        int64(1024),
+		flightsql.SqlInfoFlightSqlServerTransaction: int32(flightsql.SqlTransactionTransaction),
+		flightsql.SqlInfoKeywords:                   []string{"LIMIT", "OFFSET"},
+		flightsql.SqlInfoSupportsConvert: map[int32][]int32{
+			int32(flightsql.SqlConvertBigInt): {int32(flightsql.SqlConvertInteger), int32(flightsql.SqlConvertVarchar)},
+		},
+	}
+
+	infoSrv := flightsql.NewBaseServer()
+	for id, v := range registered {
+		require.NoError(t, infoSrv.RegisterSqlInfo(id, v))
+	}
+
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&infoSrv))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	values, err := cl.GetSqlInfoValues(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, flightsql.SqlInfoValues(registered), values)
+
+	name, ok := values.ServerName()
+	assert.True(t, ok)
+	assert.Equal(t, "values", name)
+	readOnly, ok := values.ReadOnly()
+	assert.True(t, ok)
+	assert.True(t, readOnly)
+	txns, ok := values.SupportsTransactions()
+	assert.True(t, ok)
+	assert.True(t, txns)
+	_, ok = values.ServerVersion()
+	assert.False(t, ok)
+	// the wrong type is not reported
+	_, ok = values.Int32(flightsql.SqlInfoMaxBinaryLiteralLen)
+	assert.False(t, ok)
+
+	values, err = cl.GetSqlInfoValues(ctx, []flightsql.SqlInfo{flightsql.SqlInfoFlightSqlServerName, flightsql.SqlInfoKeywords})
+	require.NoError(t, err)
+	assert.Equal(t, flightsql.SqlInfoValues{
+		flightsql.SqlInfoFlightSqlServerName: "values",
+		flightsql.SqlInfoKeywords:            []string{"LIMIT", "OFFSET"},
+	}, values)
+
+	// the values which are reported are returned along with the error
+	values, err = cl.GetSqlInfoValues(ctx, []flightsql.SqlInfo{flightsql.SqlInfoFlightSqlServerName, flightsql.SqlInfoFlightSqlServerVersion})
+	assert.ErrorIs(t, err, flightsql.ErrSqlInfoNotReported)
+	assert.Equal(t, flightsql.SqlInfoValues{flightsql.SqlInfoFlightSqlServerName: "values"}, values)
+}
+
+func TestClientCapabilities(t *testing.T) {
+	capSrv := flightsql.NewBaseServer()
+	require.NoError(t, capSrv.RegisterSqlInfo(flightsql.SqlInfoFlightSqlServerName, "capabilities"))
+	require.NoError(t, capSrv.RegisterSqlInfo(flightsql.SqlInfoFlightSqlServerSubstrait, false))
+	require.NoError(t, capSrv.RegisterSqlInfo(flightsql.SqlInfoFlightSqlServerTransaction, int32(flightsql.SqlTransactionSavepoint)))
+	require.NoError(t, capSrv.RegisterSqlInfo(flightsql.SqlInfoFlightSqlServerCancel, true))
+	require.NoError(t, capSrv.RegisterSqlInfo(flightsql.SqlInfoFlightSqlServerStatementTimeout, int32(30000)))
+	// not a capability, should be ignored
+	require.NoError(t, capSrv.RegisterSqlInfo(flightsql.SqlInfoDDLCatalog, true))
+
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&capSrv))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	caps, err := cl.Capabilities(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "capabilities", caps.ServerName)
+	assert.False(t, caps.Substrait)
+	assert.True(t, caps.Reported(flightsql.SqlInfoFlightSqlServerSubstrait))
+	assert.Equal(t, flightsql.SqlTransactionSavepoint, caps.Transactions)
+	assert.True(t, caps.Cancel)
+	assert.Equal(t, 30*time.Second, caps.StatementTimeout)
+
+	// unreported values keep their defaults
+	assert.True(t, caps.SQL)
+	assert.False(t, caps.Reported(flightsql.SqlInfoFlightSqlServerSql))
+	assert.False(t, caps.ReadOnly)
+	assert.False(t, caps.Reported(flightsql.SqlInfoFlightSqlServerReadOnly))
+	assert.Zero(t, caps.TransactionTimeout)
+	assert.False(t, caps.Reported(flightsql.SqlInfoFlightSqlServerTransactionTimeout))
+}
+
+// clusterNodeServer is a node of a two node cluster where only node B
+// holds data: node A plans the queries, pointing their endpoints at B,
+// and only serves the tickets which are local to it.
+type clusterNodeServer struct {
+	flightsql.BaseServer
+	dataNode   string
+	dataScheme string
+	hasData    bool
+}
+
+func (s *clusterNodeServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	ticket := func(handle string) *flight.Ticket {
+		tkt, err := flightsql.CreateStatementQueryTicket([]byte(handle))
+		if err != nil {
+			panic(err)
+		}
+		return &flight.Ticket{Ticket: tkt}
+	}
+	scheme := s.dataScheme
+	if scheme == "" {
+		scheme = "grpc+tcp"
+	}
+	nodeB := &flight.Location{Uri: scheme + "://" + s.dataNode}
+
+	var endpoints []*flight.FlightEndpoint
+	switch cmd.GetQuery() {
+	case "split":
+		endpoints = []*flight.FlightEndpoint{
+			{Ticket: ticket("1"), Location: []*flight.Location{nodeB}},
+			{Ticket: ticket("2"), Location: []*flight.Location{{Uri: "grpc+bogus://nowhere"}, nodeB}},
+			{Ticket: ticket("3"), Location: []*flight.Location{nodeB}},
+		}
+	case "fallback":
+		endpoints = []*flight.FlightEndpoint{
+			{Ticket: ticket("local"), Location: []*flight.Location{{Uri: "grpc+bogus://nowhere"}, nodeB}},
+		}
+	}
+
+	return &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint:         endpoints,
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+func (s *clusterNodeServer) DoGetStatement(_ context.Context, cmd flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	handle := string(cmd.GetStatementHandle())
+	switch {
+	case handle == "local" && s.hasData:
+		return nil, nil, status.Error(codes.NotFound, "ticket is local to the planning node")
+	case handle != "local" && !s.hasData:
+		return nil, nil, status.Error(codes.FailedPrecondition, "data lives on node B")
+	}
+
+	id := int64(0)
+	if handle != "local" {
+		id, _ = strconv.ParseInt(handle, 10, 64)
+	}
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).Append(id)
+
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+	close(ch)
+	return schema, ch, nil
+}
+
+// connCounter counts the gRPC connections accepted by a server.
+type connCounter struct {
+	conns atomic.Int32
+}
+
+func (c *connCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context   { return ctx }
+func (c *connCounter) HandleRPC(context.Context, stats.RPCStats)                         {}
+func (c *connCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+
+func (c *connCounter) HandleConn(_ context.Context, s stats.ConnStats) {
+	if _, ok := s.(*stats.ConnBegin); ok {
+		c.conns.Add(1)
+	}
+}
+
+func TestReadFlightInfoLocationPool(t *testing.T) {
+	counter := &connCounter{}
+	nodeB := flight.NewServerWithMiddleware(nil, grpc.StatsHandler(counter))
+	nodeB.RegisterFlightService(flightsql.NewFlightServer(&clusterNodeServer{hasData: true}))
+	require.NoError(t, nodeB.Init("localhost:0"))
+	go nodeB.Serve()
+	defer nodeB.Shutdown()
+
+	nodeA := flight.NewServerWithMiddleware(nil)
+	nodeA.RegisterFlightService(flightsql.NewFlightServer(&clusterNodeServer{dataNode: nodeB.Addr().String()}))
+	require.NoError(t, nodeA.Init("localhost:0"))
+	go nodeA.Serve()
+	defer nodeA.Shutdown()
+
+	cl, err := flightsql.NewClient(nodeA.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+	cl.Alloc = mem
+
+	readIDs := func(query string, opts ...grpc.CallOption) []int64 {
+		rdr, err := cl.ExecuteQuery(context.Background(), query, opts...)
+		require.NoError(t, err)
+		defer rdr.Release()
+
+		var ids []int64
+		for rdr.Next() {
+			ids = append(ids, rdr.Record().Column(0).(*array.Int64).Int64Values()...)
+		}
+		require.NoError(t, rdr.Err())
+		return ids
+	}
+
+	t.Run("endpoints on another node", func(t *testing.T) {
+		assert.Equal(t, []int64{1, 2, 3}, readIDs("split"))
+		assert.ElementsMatch(t, []int64{1, 2, 3}, readIDs("split", flightsql.WithEndpointConcurrency(3)))
+		assert.EqualValues(t, 1, counter.conns.Load())
+		assert.Equal(t, 1, cl.Locations.Len())
+	})
+
+	t.Run("fall back to the original connection", func(t *testing.T) {
+		assert.Equal(t, []int64{0}, readIDs("fallback"))
+		// a connection whose stream failed isn't kept
+		assert.Equal(t, 0, cl.Locations.Len())
+	})
+
+	t.Run("closed with the client", func(t *testing.T) {
+		readIDs("split")
+		assert.Equal(t, 1, cl.Locations.Len())
+		require.NoError(t, cl.Close())
+		assert.Equal(t, 0, cl.Locations.Len())
+	})
+}
+
+func TestLocationPoolIdleLimits(t *testing.T) {
+	var nodes []string
+	for i := 0; i < 3; i++ {
+		srv := flight.NewServerWithMiddleware(nil)
+		srv.RegisterFlightService(flightsql.NewFlightServer(&clusterNodeServer{hasData: true}))
+		require.NoError(t, srv.Init("localhost:0"))
+		go srv.Serve()
+		defer srv.Shutdown()
+		nodes = append(nodes, srv.Addr().String())
+	}
+
+	planner := flight.NewServerWithMiddleware(nil)
+	planner.RegisterFlightService(flightsql.NewFlightServer(&clusterNodeServer{}))
+	require.NoError(t, planner.Init("localhost:0"))
+	go planner.Serve()
+	defer planner.Shutdown()
+
+	cl, err := flightsql.NewClient(planner.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte("1"))
+	require.NoError(t, err)
+	info := &flight.FlightInfo{}
+	for _, node := range nodes {
+		info.Endpoint = append(info.Endpoint, &flight.FlightEndpoint{
+			Ticket:   &flight.Ticket{Ticket: tkt},
+			Location: []*flight.Location{{Uri: "grpc+tcp://" + node}},
+		})
+	}
+
+	read := func() {
+		rdr, err := cl.ReadFlightInfo(context.Background(), info)
+		require.NoError(t, err)
+		defer rdr.Release()
+		for rdr.Next() {
+		}
+		require.NoError(t, rdr.Err())
+	}
+
+	cl.Locations = flightsql.NewLocationPool(flightsql.LocationPoolOptions{MaxIdle: 2})
+	read()
+	assert.Equal(t, 2, cl.Locations.Len())
+	require.NoError(t, cl.Locations.Close())
+
+	cl.Locations = flightsql.NewLocationPool(flightsql.LocationPoolOptions{IdleTimeout: 10 * time.Millisecond})
+	read()
+	assert.Eventually(t, func() bool { return cl.Locations.Len() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestLocationPoolPolicy(t *testing.T) {
+	var (
+		counters []*connCounter
+		locs     []*flight.Location
+	)
+	for i := 0; i < 3; i++ {
+		counter := &connCounter{}
+		srv := flight.NewServerWithMiddleware(nil, grpc.StatsHandler(counter))
+		srv.RegisterFlightService(flightsql.NewFlightServer(&clusterNodeServer{hasData: true}))
+		require.NoError(t, srv.Init("localhost:0"))
+		go srv.Serve()
+		defer srv.Shutdown()
+		counters = append(counters, counter)
+		locs = append(locs, &flight.Location{Uri: "grpc+tcp://" + srv.Addr().String()})
+	}
+
+	planner := flight.NewServerWithMiddleware(nil)
+	planner.RegisterFlightService(flightsql.NewFlightServer(&clusterNodeServer{}))
+	require.NoError(t, planner.Init("localhost:0"))
+	go planner.Serve()
+	defer planner.Shutdown()
+
+	cl, err := flightsql.NewClient(planner.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	// a single endpoint replicated on the three nodes
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte("1"))
+	require.NoError(t, err)
+	info := &flight.FlightInfo{Endpoint: []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: tkt}, Location: locs}}}
+	read := func(opts ...grpc.CallOption) {
+		rdr, err := cl.ReadFlightInfo(context.Background(), info, opts...)
+		require.NoError(t, err)
+		defer rdr.Release()
+		for rdr.Next() {
+		}
+		require.NoError(t, rdr.Err())
+	}
+
+	// each read goes to the next node, through a connection of the pool
+	cl.Locations = flightsql.NewLocationPool(flightsql.LocationPoolOptions{Policy: flight.NewRoundRobinLocationPolicy(0)})
+	for i := 0; i < 3; i++ {
+		read()
+	}
+	assert.Equal(t, 3, cl.Locations.Len())
+
+	// the policy of a call replaces that of the pool
+	policy := &firstLocationPolicy{}
+	read(flightsql.WithLocationPolicy(policy))
+	assert.EqualValues(t, 1, policy.reports.Load())
+	for _, c := range counters {
+		assert.EqualValues(t, 1, c.conns.Load())
+	}
+}
+
+// firstLocationPolicy tries the locations in the order they are listed,
+// counting the reported attempts.
+type firstLocationPolicy struct {
+	reports atomic.Int32
+}
+
+func (p *firstLocationPolicy) Order(locations []*flight.Location) []*flight.Location {
+	return locations
+}
+
+func (p *firstLocationPolicy) Report(*flight.Location, time.Duration, error) {
+	p.reports.Add(1)
+}
+
+// selfSignedTLS returns the TLS configuration of a server with a
+// self-signed certificate for name, and of a client trusting it.
+func selfSignedTLS(t *testing.T, name string) (server, client *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client = &tls.Config{RootCAs: roots, ServerName: name}
+	return server, client
+}
+
+func TestReadFlightInfoTLSLocation(t *testing.T) {
+	serverTLS, clientTLS := selfSignedTLS(t, "data.flight.test")
+
+	nodeB := flight.NewServerWithMiddleware(nil, grpc.Creds(credentials.NewTLS(serverTLS)))
+	nodeB.RegisterFlightService(flightsql.NewFlightServer(&clusterNodeServer{hasData: true}))
+	require.NoError(t, nodeB.Init("localhost:0"))
+	go nodeB.Serve()
+	defer nodeB.Shutdown()
+
+	nodeA := flight.NewServerWithMiddleware(nil)
+	nodeA.RegisterFlightService(flightsql.NewFlightServer(&clusterNodeServer{
+		dataNode: nodeB.Addr().String(), dataScheme: "grpc+tls"}))
+	require.NoError(t, nodeA.Init("localhost:0"))
+	go nodeA.Serve()
+	defer nodeA.Shutdown()
+
+	cl, err := flightsql.NewClient(nodeA.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	readIDs := func() ([]int64, error) {
+		rdr, err := cl.ExecuteQuery(context.Background(), "split")
+		if err != nil {
+			return nil, err
+		}
+		defer rdr.Release()
+
+		var ids []int64
+		for rdr.Next() {
+			ids = append(ids, rdr.Record().Column(0).(*array.Int64).Int64Values()...)
+		}
+		return ids, rdr.Err()
+	}
+
+	t.Run("pool", func(t *testing.T) {
+		cl.Locations = flightsql.NewLocationPool(flightsql.LocationPoolOptions{TLSConfig: clientTLS})
+		defer cl.Locations.Close()
+
+		ids, err := readIDs()
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, ids)
+		assert.Equal(t, 1, cl.Locations.Len())
+	})
+
+	t.Run("dialer", func(t *testing.T) {
+		cl.LocationDialer = flightsql.NewLocationDialer(clientTLS, nil)
+		defer func() { cl.LocationDialer = nil }()
+
+		ids, err := readIDs()
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, ids)
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		// the system roots don't trust the server, so the endpoints are
+		// retrieved from node A, which doesn't have them
+		cl.LocationDialer = flightsql.NewLocationDialer(nil, nil)
+		defer func() { cl.LocationDialer = nil }()
+
+		_, err := readIDs()
+		assert.ErrorContains(t, err, "grpc+tls://"+nodeB.Addr().String())
+		assert.ErrorContains(t, err, "certificate")
+		assert.ErrorContains(t, err, "data lives on node B")
+	})
+
+	t.Run("unknown scheme", func(t *testing.T) {
+		_, err := flightsql.DialLocation(context.Background(), &flight.Location{Uri: "grpc+bogus://nowhere:1234/path"})
+		assert.ErrorIs(t, err, arrow.ErrNotImplemented)
+		assert.ErrorContains(t, err, `"grpc+bogus://nowhere:1234/path"`)
+	})
+}
+
+// flakySQLServer fails the first two attempts of planning a query and of
+// creating a prepared statement with UNAVAILABLE.
+type flakySQLServer struct {
+	flightsql.BaseServer
+	infoAttempts, prepareAttempts atomic.Int32
+}
+
+func (s *flakySQLServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	if s.infoAttempts.Add(1) <= 2 {
+		return nil, status.Error(codes.Unavailable, "try again")
+	}
+	return &flight.FlightInfo{FlightDescriptor: desc, TotalRecords: -1, TotalBytes: -1}, nil
+}
+
+func (s *flakySQLServer) CreatePreparedStatement(context.Context, flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	if s.prepareAttempts.Add(1) <= 2 {
+		return flightsql.ActionCreatePreparedStatementResult{}, status.Error(codes.Unavailable, "try again")
+	}
+	return flightsql.ActionCreatePreparedStatementResult{Handle: []byte("stmt")}, nil
+}
+
+func (s *flakySQLServer) ClosePreparedStatement(context.Context, flightsql.ActionClosePreparedStatementRequest) error {
+	return nil
+}
+
+func TestClientRetryMiddleware(t *testing.T) {
+	srv := &flakySQLServer{}
+	server := flight.NewServerWithMiddleware(nil)
+	server.RegisterFlightService(flightsql.NewFlightServer(srv))
+	require.NoError(t, server.Init("localhost:0"))
+	go server.Serve()
+	defer server.Shutdown()
+
+	retry := flight.NewRetryMiddleware(flight.RetryPolicy{
+		InitialBackoff:    time.Millisecond,
+		IdempotentActions: []string{flightsql.CreatePreparedStatementActionType},
+	})
+	cl, err := flightsql.NewClient(server.Addr().String(), nil, []flight.ClientMiddleware{retry}, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	_, err = cl.Execute(ctx, "SELECT 1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, srv.infoAttempts.Load())
+
+	stmt, err := cl.Prepare(ctx, "SELECT ?")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("stmt"), stmt.Handle())
+	assert.EqualValues(t, 3, srv.prepareAttempts.Load())
+	require.NoError(t, stmt.Close(ctx))
+}
+
+// appMetadataServer returns results split across two endpoints, each
+// returning three records tagged with their endpoint and partition.
+type appMetadataServer struct {
+	flightsql.BaseServer
+}
+
+func (s *appMetadataServer) GetFlightInfoStatement(_ context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	info := &flight.FlightInfo{FlightDescriptor: desc, TotalRecords: -1, TotalBytes: -1}
+	for _, handle := range []string{"a", "b"} {
+		tkt, err := flightsql.CreateStatementQueryTicket([]byte(handle))
+		if err != nil {
+			return nil, err
+		}
+		info.Endpoint = append(info.Endpoint, &flight.FlightEndpoint{Ticket: &flight.Ticket{Ticket: tkt}})
+	}
+	return info, nil
+}
+
+func (s *appMetadataServer) DoGetStatement(_ context.Context, cmd flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	ch := make(chan flight.StreamChunk, 3)
+	for i := 0; i < 3; i++ {
+		rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(fmt.Sprintf(`[{"id": %d}]`, i)))
+		if err != nil {
+			return nil, nil, err
+		}
+		ch <- flightsql.Chunk(rec, []byte(fmt.Sprintf("%s/partition-%d", cmd.GetStatementHandle(), i)))
+	}
+	close(ch)
+	return schema, ch, nil
+}
+
+func TestChunkAppMetadata(t *testing.T) {
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&appMetadataServer{}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	info, err := cl.Execute(ctx, "SELECT id FROM partitioned")
+	require.NoError(t, err)
+
+	t.Run("DoGet", func(t *testing.T) {
+		rdr, err := cl.DoGet(ctx, info.Endpoint[0].Ticket)
+		require.NoError(t, err)
+		defer rdr.Release()
+
+		var metadata []string
+		for rdr.Next() {
+			chunk := rdr.Chunk()
+			assert.EqualValues(t, len(metadata), chunk.Data.Column(0).(*array.Int64).Value(0))
+			metadata = append(metadata, string(chunk.AppMetadata))
+		}
+		require.NoError(t, rdr.Err())
+		assert.Equal(t, []string{"a/partition-0", "a/partition-1", "a/partition-2"}, metadata)
+	})
+
+	expected := []string{
+		"a/partition-0", "a/partition-1", "a/partition-2",
+		"b/partition-0", "b/partition-1", "b/partition-2",
+	}
+	for name, opts := range map[string][]grpc.CallOption{
+		"sequential": nil,
+		"concurrent": {flightsql.WithEndpointConcurrency(2)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rdr, err := cl.ReadFlightInfo(ctx, info, opts...)
+			require.NoError(t, err)
+			defer rdr.Release()
+
+			var metadata []string
+			for rdr.Next() {
+				assert.Equal(t, rdr.LatestAppMetadata(), rdr.Chunk().AppMetadata)
+				metadata = append(metadata, string(rdr.LatestAppMetadata()))
+			}
+			require.NoError(t, rdr.Err())
+			assert.Equal(t, expected, metadata)
+		})
+	}
+}
+
+func TestClientTokenAuth(t *testing.T) {
+	var issued atomic.Int32
+	issue := func(_ context.Context, username, password string) (string, error) {
+		if username != "user" || password != "pass" {
+			return "", fmt.Errorf("unknown user %q", username)
+		}
+		return fmt.Sprintf("token-%d", issued.Add(1)), nil
+	}
+	// only the latest token is valid
+	validate := func(_ context.Context, token string) (interface{}, error) {
+		if token != fmt.Sprintf("token-%d", issued.Load()) {
+			return nil, fmt.Errorf("stale token %q", token)
+		}
+		return "user", nil
+	}
+
+	server := flight.NewServerWithMiddleware([]flight.ServerMiddleware{
+		flight.CreateServerBearerTokenMiddleware(issue, validate),
+	})
+	server.RegisterFlightService(flightsql.NewFlightServer(&appMetadataServer{}))
+	require.NoError(t, server.Init("localhost:0"))
+	go server.Serve()
+	defer server.Shutdown()
+
+	newClient := func(mw flight.ClientMiddleware) *flightsql.Client {
+		cl, err := flightsql.NewClient(server.Addr().String(), nil, []flight.ClientMiddleware{mw}, dialOpts...)
+		require.NoError(t, err)
+		return cl
+	}
+
+	ctx := context.Background()
+	cl := newClient(flightsql.WithTokenAuth("user", "pass"))
+	defer cl.Close()
+	_, err := cl.Execute(ctx, "SELECT 1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, issued.Load())
+
+	// another client authenticating invalidates the first client's token
+	other := newClient(flightsql.WithTokenAuth("user", "pass"))
+	defer other.Close()
+	_, err = other.Execute(ctx, "SELECT 1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, issued.Load())
+
+	rdr, err := cl.ExecuteQuery(ctx, "SELECT 1")
+	require.NoError(t, err)
+	for rdr.Next() {
+	}
+	require.NoError(t, rdr.Err())
+	rdr.Release()
+	assert.EqualValues(t, 3, issued.Load())
+
+	static := newClient(flightsql.WithStaticToken("token-1"))
+	defer static.Close()
+	_, err = static.Execute(ctx, "SELECT 1")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+// sqlInfoResult reads the result of DoGetSqlInfo for the given ids.
+func sqlInfoResult(srv *flightsql.BaseServer, ids ...uint32) (arrow.Record, error) {
+	_, ch, err := srv.DoGetSqlInfo(context.Background(), &pb.CommandGetSqlInfo{Info: ids})
+	if err != nil {
+		return nil, err
+	}
+	var rec arrow.Record
+	for chunk := range ch {
+		if chunk.Err != nil {
+			return nil, chunk.Err
+		}
+		rec = chunk.Data
+	}
+	return rec, nil
+}
+
+func registerTestSqlInfo(t testing.TB, srv *flightsql.BaseServer) {
+	require.NoError(t, srv.RegisterSqlInfoMap(flightsql.SqlInfoResultMap{
+		uint32(flightsql.SqlInfoFlightSqlServerName):     "pooled",
+		uint32(flightsql.SqlInfoFlightSqlServerVersion):  "1.0",
+		uint32(flightsql.SqlInfoFlightSqlServerReadOnly): true,
+		uint32(flightsql.SqlInfoKeywords):                []string{"SELECT", "FROM"},
+	}))
+}
+
+func TestSqlInfoBuilderReuse(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	pooled := flightsql.NewBaseServer(flightsql.WithBaseServerAllocator(mem))
+	registerTestSqlInfo(t, &pooled)
+	// the zero value doesn't reuse builders
+	var reference flightsql.BaseServer
+	registerTestSqlInfo(t, &reference)
+
+	requests := [][]uint32{
+		{uint32(flightsql.SqlInfoFlightSqlServerName)},
+		{uint32(flightsql.SqlInfoFlightSqlServerReadOnly), uint32(flightsql.SqlInfoKeywords)},
+		{uint32(flightsql.SqlInfoKeywords), uint32(flightsql.SqlInfoFlightSqlServerVersion), uint32(flightsql.SqlInfoFlightSqlServerName)},
+	}
+	expected := make([]arrow.Record, len(requests))
+	for i, ids := range requests {
+		rec, err := sqlInfoResult(&reference, ids...)
+		require.NoError(t, err)
+		defer rec.Release()
+		expected[i] = rec
+	}
+
+	const goroutines, iterations = 8, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				// failing midway leaves values in the builder, which
+				// must not show up in the next result
+				if _, err := sqlInfoResult(&pooled, uint32(flightsql.SqlInfoFlightSqlServerName), 99999); status.Code(err) != codes.NotFound {
+					errs <- fmt.Errorf("expected NotFound, got %v", err)
+					return
+				}
+
+				n := (g + i) % len(requests)
+				rec, err := sqlInfoResult(&pooled, requests[n]...)
+				if err != nil {
+					errs <- err
+					return
+				}
+				equal := array.RecordEqual(expected[n], rec)
+				rec.Release()
+				if !equal {
+					errs <- fmt.Errorf("unexpected result for %v", requests[n])
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func BenchmarkDoGetSqlInfo(b *testing.B) {
+	ids := []uint32{uint32(flightsql.SqlInfoFlightSqlServerName), uint32(flightsql.SqlInfoKeywords)}
+	bench := func(b *testing.B, srv *flightsql.BaseServer) {
+		registerTestSqlInfo(b, srv)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rec, err := sqlInfoResult(srv, ids...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			rec.Release()
+		}
+	}
+
+	b.Run("pooled", func(b *testing.B) {
+		srv := flightsql.NewBaseServer()
+		bench(b, &srv)
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		var srv flightsql.BaseServer
+		bench(b, &srv)
+	})
+}
+
+// identityServer fails the calls of users other than the one authenticated
+// with the handle of the statement.
+type identityServer struct {
+	flightsql.BaseServer
+}
+
+func (s *identityServer) GetFlightInfoStatement(ctx context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	user, _ := flight.AuthFromContext(ctx).(string)
+	if user == "" {
+		return nil, status.Error(codes.Internal, "no identity")
+	}
+	return flightsql.NewStatementFlightInfo(desc, latencySchema, s.Alloc, []byte(user))
+}
+
+func (s *identityServer) DoGetStatement(ctx context.Context, tkt flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	if user, _ := flight.AuthFromContext(ctx).(string); user != string(tkt.GetStatementHandle()) {
+		return nil, nil, status.Errorf(codes.PermissionDenied, "statement of another user")
+	}
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).Append(1)
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+	close(ch)
+	return latencySchema, ch, nil
+}
+
+func TestAuthenticatorIdentity(t *testing.T) {
+	s := flight.NewServerWithMiddleware([]flight.ServerMiddleware{flight.CreateServerAuthMiddleware(
+		flight.NewAPIKeyAuthenticator("x-api-key", map[string]interface{}{"k1": "alice", "k2": "bob"}),
+	)})
+	s.RegisterFlightService(flightsql.NewFlightServer(&identityServer{}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	alice := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "k1")
+	bob := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "k2")
+
+	_, err = cl.Execute(context.Background(), "SELECT 1")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	info, err := cl.Execute(alice, "SELECT 1")
+	require.NoError(t, err)
+
+	rdr, err := cl.DoGet(alice, info.Endpoint[0].Ticket)
+	require.NoError(t, err)
+	require.True(t, rdr.Next())
+	assert.EqualValues(t, 1, rdr.Record().NumRows())
+	rdr.Release()
+
+	_, err = cl.DoGet(bob, info.Endpoint[0].Ticket)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// panicServer panics in its handlers, as a bug dereferencing nil would.
+type panicServer struct {
+	flightsql.BaseServer
+}
+
+func (*panicServer) GetFlightInfoStatement(context.Context, flightsql.StatementQuery, *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	var info *flight.FlightInfo
+	info.TotalRecords = -1
+	return info, nil
+}
+
+func (*panicServer) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	panic("secret connection string")
+}
+
+func (*panicServer) DoPutCommandStatementUpdate(context.Context, flightsql.StatementUpdate) (int64, error) {
+	panic("secret connection string")
+}
+
+func (*panicServer) BeginTransaction(context.Context, flightsql.ActionBeginTransactionRequest) ([]byte, error) {
+	panic("secret connection string")
+}
+
+func TestHandlerPanicRecovery(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&panicServer{}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	tkt, err := flightsql.TicketStatementQuery([]byte("handle"))
+	require.NoError(t, err)
+
+	calls := map[string]func() error{
+		"GetFlightInfo": func() error {
+			_, err := cl.Execute(ctx, "SELECT 1")
+			return err
+		},
+		"DoGet": func() error {
+			_, err := cl.DoGet(ctx, tkt)
+			return err
+		},
+		"DoPut": func() error {
+			_, err := cl.ExecuteUpdate(ctx, "UPDATE t SET x = 1")
+			return err
+		},
+		"DoAction": func() error {
+			_, err := cl.BeginTransaction(ctx)
+			return err
+		},
+	}
+	for method, call := range calls {
+		t.Run(method, func(t *testing.T) {
+			// the server keeps serving after a panic
+			for i := 0; i < 2; i++ {
+				err := call()
+				assert.Equal(t, codes.Internal, status.Code(err))
+				assert.Contains(t, status.Convert(err).Message(), method)
+				assert.NotContains(t, err.Error(), "secret")
+			}
+		})
+	}
+
+	_, err = cl.GetTables(ctx, &flightsql.GetTablesOpts{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	assert.Contains(t, logged.String(), "secret connection string")
+	assert.Contains(t, logged.String(), "panicServer")
+}
+
+// bindingServer counts the rows of the parameters bound to its prepared
+// statements.
+type bindingServer struct {
+	flightsql.BaseServer
+}
+
+func (*bindingServer) CreatePreparedStatement(context.Context, flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	return flightsql.ActionCreatePreparedStatementResult{Handle: []byte("stmt"), ParameterSchema: latencySchema}, nil
+}
+
+func (*bindingServer) DoPutPreparedStatementUpdate(_ context.Context, _ flightsql.PreparedStatementUpdate, rdr flight.MessageReader) (n int64, err error) {
+	for rdr.Next() {
+		n += rdr.Record().NumRows()
+	}
+	return n, rdr.Err()
+}
+
+func TestGrpcServerOptions(t *testing.T) {
+	// 16MB of parameters, above the 4MB limit of gRPC servers by default
+	const rows = 2 << 20
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).AppendValues(make([]int64, rows), nil)
+	rec := bldr.NewRecord()
+	defer rec.Release()
+
+	update := func(opts ...flightsql.FlightServerOption) (int64, error) {
+		s := flight.NewServerWithMiddleware(nil, grpc.MaxRecvMsgSize(1<<20))
+		s.RegisterFlightService(flightsql.NewFlightServer(&bindingServer{}, opts...))
+		require.NoError(t, s.Init("localhost:0"))
+		go s.Serve()
+		defer s.Shutdown()
+
+		cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+		require.NoError(t, err)
+		defer cl.Close()
+
+		prep, err := cl.Prepare(context.Background(), "UPDATE t SET x = ?")
+		require.NoError(t, err)
+		defer prep.Close(context.Background())
+		prep.SetParameters(rec)
+		return prep.ExecuteUpdate(context.Background())
+	}
+
+	// the options of the service override those of the constructor
+	n, err := update(flightsql.WithGrpcServerOptions(grpc.MaxRecvMsgSize(32 << 20)))
+	require.NoError(t, err)
+	assert.EqualValues(t, rows, n)
+
+	_, err = update()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// and apply with middleware as well
+	n, err = update(flightsql.WithGrpcServerOptions(grpc.MaxRecvMsgSize(32<<20)),
+		flightsql.WithServerTracing())
+	require.NoError(t, err)
+	assert.EqualValues(t, rows, n)
+}
+
+// ingestServer keeps the schema and number of rows of the tables records
+// are ingested into.
+type ingestServer struct {
+	flightsql.BaseServer
+	mu      sync.Mutex
+	schemas map[string]*arrow.Schema
+	rows    map[string]int64
+}
+
+func (s *ingestServer) DoPutCommandStatementIngest(_ context.Context, opts flightsql.IngestOptions, rdr flight.MessageReader) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the table is handled before reading any record
+	schema, exists := s.schemas[opts.Table]
+	action, err := opts.TableAction(exists)
+	if err != nil {
+		return 0, err
+	}
+	switch action {
+	case flightsql.IngestTableAppend:
+		if !schema.Equal(rdr.Schema()) {
+			return 0, status.Errorf(codes.InvalidArgument, "schema of table %s does not match: %s", opts.Table, rdr.Schema())
+		}
+	default:
+		s.schemas[opts.Table], s.rows[opts.Table] = rdr.Schema(), 0
+	}
+
+	var n int64
+	for rdr.Next() {
+		n += rdr.Record().NumRows()
+	}
+	s.rows[opts.Table] += n
+	return n, rdr.Err()
+}
+
+// recordsReader reads the records given, whatever their schema, its
+// schema being that of the first.
+type recordsReader struct {
+	schema *arrow.Schema
+	recs   []arrow.Record
+	cur    arrow.Record
+}
+
+func (r *recordsReader) Retain()               {}
+func (r *recordsReader) Release()              {}
+func (r *recordsReader) Schema() *arrow.Schema { return r.schema }
+func (r *recordsReader) Record() arrow.Record  { return r.cur }
+func (r *recordsReader) Err() error            { return nil }
+func (r *recordsReader) Next() bool {
+	if len(r.recs) == 0 {
+		return false
+	}
+	r.cur, r.recs = r.recs[0], r.recs[1:]
+	return true
+}
+
+func TestExecuteIngestTableActions(t *testing.T) {
+	srv := &ingestServer{schemas: make(map[string]*arrow.Schema), rows: make(map[string]int64)}
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	newRecord := func(schema *arrow.Schema, rows int) arrow.Record {
+		bldr := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+		defer bldr.Release()
+		for _, f := range bldr.Fields() {
+			for i := 0; i < rows; i++ {
+				switch b := f.(type) {
+				case *array.Int64Builder:
+					b.Append(int64(i))
+				case *array.StringBuilder:
+					b.Append(strconv.Itoa(i))
+				}
+			}
+		}
+		return bldr.NewRecord()
+	}
+	other := arrow.NewSchema([]arrow.Field{{Name: "name", Type: arrow.BinaryTypes.String}}, nil)
+	rec, otherRec := newRecord(latencySchema, 3), newRecord(other, 2)
+	defer rec.Release()
+	defer otherRec.Release()
+
+	ctx := context.Background()
+	ingest := func(opts flightsql.IngestOptions, recs ...arrow.Record) (int64, error) {
+		return cl.ExecuteIngest(ctx, &recordsReader{schema: recs[0].Schema(), recs: recs}, opts)
+	}
+
+	// a new table is created with the schema of the records
+	n, err := ingest(flightsql.IngestOptions{Table: "t", IfNotExist: flightsql.IngestTableNotExistCreate}, rec, rec)
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, n)
+	assert.True(t, latencySchema.Equal(srv.schemas["t"]))
+
+	// which records are then appended to
+	n, err = ingest(flightsql.IngestOptions{Table: "t", IfExists: flightsql.IngestTableExistsAppend}, rec)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+	assert.EqualValues(t, 9, srv.rows["t"])
+
+	_, err = ingest(flightsql.IngestOptions{Table: "t", IfNotExist: flightsql.IngestTableNotExistCreate}, rec)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = ingest(flightsql.IngestOptions{Table: "u", IfExists: flightsql.IngestTableExistsAppend}, rec)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = ingest(flightsql.IngestOptions{Table: "t", IfExists: flightsql.IngestTableExistsAppend}, otherRec)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// records of another schema than the first fail the ingestion
+	_, err = ingest(flightsql.IngestOptions{Table: "t", IfExists: flightsql.IngestTableExistsAppend}, rec, otherRec)
+	assert.ErrorIs(t, err, arrow.ErrInvalid)
+	assert.ErrorContains(t, err, "schema of record 1")
+
+	// replacing the table evolves its schema
+	n, err = ingest(flightsql.IngestOptions{Table: "t", IfExists: flightsql.IngestTableExistsReplace}, otherRec)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	assert.True(t, other.Equal(srv.schemas["t"]))
+	assert.EqualValues(t, 2, srv.rows["t"])
+}
+
+// progressServer sends progress updates between the records of a query,
+// with a checksum if verify is set.
+type progressServer struct {
+	flightsql.BaseServer
+	verify bool
+}
+
+func (s *progressServer) GetFlightInfoStatement(_ context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	tkt, err := flightsql.TicketStatementQuery([]byte("progress"))
+	if err != nil {
+		return nil, err
+	}
+	return flightsql.NewFlightInfo(desc, latencySchema, s.Alloc,
+		flightsql.WithEndpoints(&flight.FlightEndpoint{Ticket: tkt})), nil
+}
+
+func (s *progressServer) DoGetStatement(ctx context.Context, _ flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+	defer bldr.Release()
+	ch := make(chan flight.StreamChunk, 4)
+	for i := 0; i < 2; i++ {
+		bldr.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+		ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+		ch <- flight.StreamChunk{AppMetadata: []byte(strconv.Itoa(50 * (i + 1)))}
+	}
+	close(ch)
+	if s.verify {
+		return latencySchema, flightsql.ChecksumChunks(ctx, latencySchema, ch), nil
+	}
+	return latencySchema, ch, nil
+}
+
+func TestDoGetMetadataOnlyChunks(t *testing.T) {
+	srv := &progressServer{}
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	info, err := cl.Execute(ctx, "SELECT progress")
+	require.NoError(t, err)
+
+	// the chunks without data are sent as metadata-only messages
+	rdr, err := cl.DoGet(ctx, info.Endpoint[0].Ticket)
+	require.NoError(t, err)
+	defer rdr.Release()
+	var progress []string
+	for rdr.Next() {
+		assert.EqualValues(t, 3, rdr.Record().NumRows())
+		for _, md := range rdr.LatestMetadataMessages() {
+			progress = append(progress, string(md))
+		}
+	}
+	require.NoError(t, rdr.Err())
+	for _, md := range rdr.LatestMetadataMessages() {
+		progress = append(progress, string(md))
+	}
+	assert.Equal(t, []string{"50", "100"}, progress)
+
+	// which the readers of results skip, checksums included
+	for _, verify := range []bool{false, true} {
+		srv.verify = verify
+		var opts []grpc.CallOption
+		if verify {
+			opts = append(opts, flightsql.WithChecksumVerification())
+		}
+		rdr, err := cl.ExecuteQuery(ctx, "SELECT progress", opts...)
+		require.NoError(t, err)
+		var rows int64
+		for rdr.Next() {
+			rows += rdr.Record().NumRows()
+		}
+		require.NoError(t, rdr.Err())
+		rdr.Release()
+		assert.EqualValues(t, 6, rows)
+	}
+}
+
+func TestClientAllocator(t *testing.T) {
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&progressServer{}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+	clientMem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer clientMem.AssertSize(t, 0)
+	cl.Alloc = clientMem
+
+	ctx := context.Background()
+	info, err := cl.Execute(ctx, "SELECT progress")
+	require.NoError(t, err)
+
+	// read checks that the records retained from rdr are allocated with
+	// mem and only it
+	read := func(rdr flight.MessageReader, mem, other *memory.CheckedAllocator) {
+		var recs []arrow.Record
+		for rdr.Next() {
+			rdr.Record().Retain()
+			recs = append(recs, rdr.Record())
+		}
+		require.NoError(t, rdr.Err())
+		rdr.Release()
+		require.Len(t, recs, 2)
+		assert.NotZero(t, mem.CurrentAlloc())
+		assert.Zero(t, other.CurrentAlloc())
+		for _, rec := range recs {
+			rec.Release()
+		}
+		mem.AssertSize(t, 0)
+	}
+
+	for _, call := range []func(opts ...grpc.CallOption) (flight.MessageReader, error){
+		func(opts ...grpc.CallOption) (flight.MessageReader, error) {
+			return cl.DoGet(ctx, info.Endpoint[0].Ticket, opts...)
+		},
+		func(opts ...grpc.CallOption) (flight.MessageReader, error) {
+			return cl.ExecuteQuery(ctx, "SELECT progress", opts...)
+		},
+	} {
+		mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+		rdr, err := call(flightsql.WithClientAllocator(mem))
+		require.NoError(t, err)
+		read(rdr, mem, clientMem)
+
+		// the records are decoded with the allocator of the client otherwise
+		rdr, err = call()
+		require.NoError(t, err)
+		read(rdr, clientMem, mem)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	stats := make(chan flight.WriterStats, 1)
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&progressServer{},
+		flightsql.WithStatsHandler(func(_ context.Context, s flight.WriterStats) { stats <- s })))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	rdr, err := cl.ExecuteQuery(context.Background(), "SELECT progress")
+	require.NoError(t, err)
+	for rdr.Next() {
+	}
+	require.NoError(t, rdr.Err())
+	rdr.Release()
+
+	// the schema, two records and the two progress updates
+	final := <-stats
+	assert.EqualValues(t, 5, final.Messages)
+	assert.EqualValues(t, 2, final.RecordBatches)
+	assert.Zero(t, final.DictionaryBatches)
+	assert.EqualValues(t, 2*3*8, final.BodyBytes)
+	assert.EqualValues(t, 1, final.CompressionRatio())
+}
+
+// lenientIngestServer counts the rows ingested, ignoring the errors of
+// the reader.
+type lenientIngestServer struct {
+	flightsql.BaseServer
+}
+
+func (s *lenientIngestServer) DoPutCommandStatementIngest(_ context.Context, _ flightsql.IngestOptions, rdr flight.MessageReader) (int64, error) {
+	var n int64
+	for rdr.Next() {
+		n += rdr.Record().NumRows()
+	}
+	return n, nil
+}
+
+func TestMaxPutBytes(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	// each record holds 100 int64 values, that is 800 bytes
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServerWithAllocator(&lenientIngestServer{}, mem, flightsql.WithMaxPutBytes(2000)))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+	defer bldr.Release()
+	ingest := func(n int) (int64, error) {
+		recs := make([]arrow.Record, n)
+		for i := range recs {
+			for j := 0; j < 100; j++ {
+				bldr.Field(0).(*array.Int64Builder).Append(int64(j))
+			}
+			recs[i] = bldr.NewRecord()
+			defer recs[i].Release()
+		}
+		return cl.ExecuteIngest(context.Background(), &recordsReader{schema: latencySchema, recs: recs}, flightsql.IngestOptions{Table: "t"})
+	}
+
+	rows, err := ingest(2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 200, rows)
+
+	// the call fails even though the handler ignores the error
+	_, err = ingest(3)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), err)
+}
+
+// endlessServer produces records of latencySchema until the context of
+// the call is done, allocating them with mem.
+type endlessServer struct {
+	flightsql.BaseServer
+	mem memory.Allocator
+}
+
+func (s *endlessServer) GetFlightInfoStatement(_ context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	tkt, err := flightsql.TicketStatementQuery([]byte("endless"))
+	if err != nil {
+		return nil, err
+	}
+	return flightsql.NewFlightInfo(desc, latencySchema, s.Alloc,
+		flightsql.WithEndpoints(&flight.FlightEndpoint{Ticket: tkt})), nil
+}
+
+func (s *endlessServer) DoGetStatement(ctx context.Context, _ flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	ch := make(chan flight.StreamChunk, 4)
+	go func() {
+		defer close(ch)
+		bldr := array.NewRecordBuilder(s.mem, latencySchema)
+		defer bldr.Release()
+		for {
+			bldr.Field(0).(*array.Int64Builder).AppendValues(make([]int64, 32*1024), nil)
+			rec := bldr.NewRecord()
+			select {
+			case ch <- flight.StreamChunk{Data: rec}:
+			case <-ctx.Done():
+				rec.Release()
+				return
+			}
+		}
+	}()
+	return latencySchema, ch, nil
+}
+
+func TestGracefulShutdownReleasesRecords(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&endlessServer{mem: mem}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	info, err := cl.Execute(ctx, "SELECT endless")
+	require.NoError(t, err)
+	rdr, err := cl.DoGet(ctx, info.Endpoint[0].Ticket)
+	require.NoError(t, err)
+	defer rdr.Release()
+	require.True(t, rdr.Next())
+
+	// the client stops reading, so that the handler is blocked sending and
+	// its stream has to be closed
+	shutdownCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Equal(t, 1, s.GracefulShutdown(shutdownCtx))
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	for rdr.Next() {
+	}
+	assert.Equal(t, codes.Unavailable, status.Code(rdr.Err()))
+
+	// the records produced but not sent are released once the handler
+	// notices the stream was closed
+	assert.Eventually(t, func() bool { return mem.CurrentAlloc() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+// batchMetadataServer records the messages of the parameters bound to
+// prepared statements, as "rows:metadata" for those with a record.
+type batchMetadataServer struct {
+	flightsql.BaseServer
+	messages []string
+}
+
+func (s *batchMetadataServer) DoPutPreparedStatementQuery(_ context.Context, _ flightsql.PreparedStatementQuery, rdr flight.MessageReader, _ flight.MetadataWriter) error {
+	src, ok := rdr.(flight.MessageSource)
+	if !ok {
+		return status.Error(codes.Internal, "the reader isn't a flight.MessageSource")
+	}
+
+	s.messages = nil
+	for {
+		msg, err := src.NextMessage()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Record != nil {
+			s.messages = append(s.messages, fmt.Sprintf("%d:%s", msg.Record.NumRows(), msg.AppMetadata))
+		} else {
+			s.messages = append(s.messages, string(msg.AppMetadata))
+		}
+	}
+}
+
+func TestDoPutPreparedStatementQueryMessages(t *testing.T) {
+	for _, limit := range []int64{0, 1 << 20} {
+		t.Run(fmt.Sprintf("limit=%d", limit), func(t *testing.T) {
+			srv := &batchMetadataServer{}
+			s := flight.NewServerWithMiddleware(nil)
+			s.RegisterFlightService(flightsql.NewFlightServer(srv, flightsql.WithMaxPutBytes(limit)))
+			require.NoError(t, s.Init("localhost:0"))
+			go s.Serve()
+			defer s.Shutdown()
+
+			cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+			require.NoError(t, err)
+			defer cl.Close()
+
+			var cmd anypb.Any
+			require.NoError(t, cmd.MarshalFrom(&pb.CommandPreparedStatementQuery{PreparedStatementHandle: []byte("stmt")}))
+			desc, err := proto.Marshal(&cmd)
+			require.NoError(t, err)
+
+			stream, err := cl.Client.DoPut(context.Background())
+			require.NoError(t, err)
+			wr := flight.NewRecordWriter(stream, ipc.WithSchema(latencySchema))
+			wr.SetFlightDescriptor(&flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: desc})
+
+			bldr := array.NewRecordBuilder(memory.DefaultAllocator, latencySchema)
+			defer bldr.Release()
+			write := func(rows int, md string) {
+				bldr.Field(0).(*array.Int64Builder).AppendValues(make([]int64, rows), nil)
+				rec := bldr.NewRecord()
+				defer rec.Release()
+				require.NoError(t, wr.WriteWithAppMetadata(rec, []byte(md)))
+			}
+			require.NoError(t, wr.WriteMetadata([]byte("begin")))
+			write(1, "batch 1")
+			require.NoError(t, wr.WriteMetadata([]byte("between")))
+			write(2, "batch 2")
+			write(3, "batch 3")
+			require.NoError(t, wr.WriteMetadata([]byte("done")))
+			require.NoError(t, wr.Close())
+			require.NoError(t, stream.CloseSend())
+			for {
+				_, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, []string{"begin", "1:batch 1", "between", "2:batch 2", "3:batch 3", "done"}, srv.messages)
+		})
+	}
+}
+
+// detailsServer fails its calls with errors carrying details, its
+// DoGet streams after sending a record.
+type detailsServer struct {
+	flightsql.BaseServer
+}
+
+var detailsSchema = arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+func detailsError(method string) error {
+	return flight.ErrorWithDetails(codes.FailedPrecondition, method+" failed",
+		wrapperspb.Bytes([]byte(method)), wrapperspb.Int32(42))
+}
+
+func detailsRecord() arrow.Record {
+	rec, _, _ := array.RecordFromJSON(memory.DefaultAllocator, detailsSchema, strings.NewReader(`[{"a": 1}]`))
+	return rec
+}
+
+func (*detailsServer) GetFlightInfoStatement(context.Context, flightsql.StatementQuery, *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return nil, detailsError("GetFlightInfo")
+}
+
+func (s *detailsServer) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	ch := make(chan flight.StreamChunk, 2)
+	ch <- flight.StreamChunk{Data: detailsRecord()}
+	ch <- flight.StreamChunk{Err: detailsError("DoGet")}
+	close(ch)
+	return detailsSchema, ch, nil
+}
+
+func (s *detailsServer) DoGetStatementIPC(_ context.Context, cmd flightsql.StatementQueryTicket) (*arrow.Schema, io.Reader, error) {
+	if string(cmd.GetStatementHandle()) != "ipc" {
+		return nil, nil, nil
+	}
+	rec := detailsRecord()
+	defer rec.Release()
+
+	// the stream of the handler breaks after its first record
+	var buf bytes.Buffer
+	if err := ipc.NewWriter(&buf, ipc.WithSchema(detailsSchema)).Write(rec); err != nil {
+		return nil, nil, err
+	}
+	return detailsSchema, io.MultiReader(&buf, iotest.ErrReader(detailsError("DoGetIPC"))), nil
+}
+
+func (s *detailsServer) DoGetPreparedStatementIPC(context.Context, flightsql.PreparedStatementQuery) (*arrow.Schema, io.Reader, error) {
+	return nil, nil, nil
+}
+
+func (*detailsServer) DoPutCommandStatementUpdate(context.Context, flightsql.StatementUpdate) (int64, error) {
+	return 0, detailsError("DoPut")
+}
+
+func (*detailsServer) BeginTransaction(context.Context, flightsql.ActionBeginTransactionRequest) ([]byte, error) {
+	return nil, detailsError("DoAction")
+}
+
+func TestHandlerErrorDetails(t *testing.T) {
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&detailsServer{}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	doGet := func(t *testing.T, handle string) error {
+		tkt, err := flightsql.CreateStatementQueryTicket([]byte(handle))
+		require.NoError(t, err)
+		rdr, err := cl.DoGet(ctx, &flight.Ticket{Ticket: tkt})
+		if err != nil {
+			return err
+		}
+		defer rdr.Release()
+
+		// the record sent before the error is received
+		require.True(t, rdr.Next())
+		assert.EqualValues(t, 1, rdr.Record().Column(0).(*array.Int64).Value(0))
+		require.False(t, rdr.Next())
+		return rdr.Err()
+	}
+
+	calls := map[string]func(*testing.T) error{
+		"GetFlightInfo": func(*testing.T) error {
+			_, err := cl.Execute(ctx, "SELECT 1")
+			return err
+		},
+		"DoGet":    func(t *testing.T) error { return doGet(t, "chunks") },
+		"DoGetIPC": func(t *testing.T) error { return doGet(t, "ipc") },
+		"DoPut": func(*testing.T) error {
+			_, err := cl.ExecuteUpdate(ctx, "UPDATE t SET x = 1")
+			return err
+		},
+		"DoAction": func(*testing.T) error {
+			_, err := cl.BeginTransaction(ctx)
+			return err
+		},
+	}
+	for method, call := range calls {
+		t.Run(method, func(t *testing.T) {
+			err := call(t)
+			assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+			assert.ErrorContains(t, err, method+" failed")
+
+			details := flight.ErrorDetails(err)
+			require.Len(t, details, 2)
+			assert.Equal(t, []byte(method), details[0].(*wrapperspb.BytesValue).GetValue())
+			assert.EqualValues(t, 42, details[1].(*wrapperspb.Int32Value).GetValue())
+		})
+	}
+}
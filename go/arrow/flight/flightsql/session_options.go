@@ -0,0 +1,96 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"google.golang.org/grpc"
+)
+
+// SessionOptionsError is returned by SetSessionOptionValues when the
+// server rejected some of the options, the others having been set. It
+// matches arrow.ErrInvalid with errors.Is.
+type SessionOptionsError struct {
+	// Errors is the reason each rejected option was rejected for.
+	Errors map[string]flight.SetSessionOptionsResultErrorValue
+}
+
+func (e *SessionOptionsError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rejected := make([]string, len(names))
+	for i, name := range names {
+		rejected[i] = fmt.Sprintf("%s (%s)", name, e.Errors[name])
+	}
+	return "arrow/flightsql: session options rejected: " + strings.Join(rejected, ", ")
+}
+
+func (e *SessionOptionsError) Unwrap() error { return arrow.ErrInvalid }
+
+// SetSessionOptionValues sets options of the session of the client, each
+// value being a string, bool, int64, float64 or []string, or nil to unset
+// the option. If the server rejects some of the options the others are
+// still set and a *SessionOptionsError is returned.
+//
+// The session is identified by a cookie set by the server, so the client
+// must have been created with the middleware of
+// flight.NewClientCookieMiddleware for the session to span calls.
+func (c *Client) SetSessionOptionValues(ctx context.Context, options map[string]any, opts ...grpc.CallOption) error {
+	values, err := flight.NewSessionOptionValues(options)
+	if err != nil {
+		return fmt.Errorf("%w: arrow/flightsql: %s", arrow.ErrInvalid, err)
+	}
+
+	result, err := c.SetSessionOptions(ctx, &flight.SetSessionOptionsRequest{SessionOptions: values}, opts...)
+	if err != nil {
+		return err
+	}
+	if len(result.GetErrors()) == 0 {
+		return nil
+	}
+
+	errs := make(map[string]flight.SetSessionOptionsResultErrorValue, len(result.GetErrors()))
+	for name, e := range result.GetErrors() {
+		errs[name] = e.GetValue()
+	}
+	return &SessionOptionsError{Errors: errs}
+}
+
+// GetSessionOptionValues returns the options of the session of the
+// client, with values of the types given to SetSessionOptionValues.
+func (c *Client) GetSessionOptionValues(ctx context.Context, opts ...grpc.CallOption) (map[string]any, error) {
+	result, err := c.GetSessionOptions(ctx, &flight.GetSessionOptionsRequest{}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	options := make(map[string]any, len(result.GetSessionOptions()))
+	for name, value := range result.GetSessionOptions() {
+		options[name] = flight.SessionOptionValueToAny(value)
+	}
+	return options, nil
+}
@@ -0,0 +1,125 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/schema_ref"
+	"github.com/apache/arrow/go/v16/arrow/flight/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// catalogSessionServer lists the tables of the catalog set as the
+// "catalog" option of the session, rejecting any other option.
+type catalogSessionServer struct {
+	flightsql.BaseServer
+
+	catalogs []string
+}
+
+func (s *catalogSessionServer) SetSessionOptions(ctx context.Context, req *flight.SetSessionOptionsRequest) (*flight.SetSessionOptionsResult, error) {
+	sess, err := session.GetSessionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make(map[string]*flight.SetSessionOptionsResultError)
+	for name, val := range req.GetSessionOptions() {
+		switch {
+		case name == "catalog" && val.GetOptionValue() == nil:
+			sess.EraseSessionOption(name)
+		case name == "catalog" && val.GetStringValue() != "":
+			sess.SetSessionOption(name, val)
+		case name == "catalog":
+			errs[name] = &flight.SetSessionOptionsResultError{Value: flight.SetSessionOptionsResultErrorInvalidValue}
+		default:
+			errs[name] = &flight.SetSessionOptionsResultError{Value: flight.SetSessionOptionsResultErrorInvalidName}
+		}
+	}
+	return &flight.SetSessionOptionsResult{Errors: errs}, nil
+}
+
+func (s *catalogSessionServer) GetSessionOptions(ctx context.Context, _ *flight.GetSessionOptionsRequest) (*flight.GetSessionOptionsResult, error) {
+	sess, err := session.GetSessionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &flight.GetSessionOptionsResult{SessionOptions: sess.GetSessionOptions()}, nil
+}
+
+func (s *catalogSessionServer) GetFlightInfoTables(ctx context.Context, _ flightsql.GetTables, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	sess, err := session.GetSessionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.catalogs = append(s.catalogs, sess.GetSessionOption("catalog").GetStringValue())
+	return flightsql.NewFlightInfo(desc, schema_ref.Tables, s.Alloc), nil
+}
+
+func TestSessionOptionValues(t *testing.T) {
+	srv := &catalogSessionServer{}
+	s := flight.NewServerWithMiddleware([]flight.ServerMiddleware{
+		flight.CreateServerMiddleware(session.NewServerSessionMiddleware(session.NewStatefulServerSessionManager())),
+	})
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil,
+		[]flight.ClientMiddleware{flight.NewClientCookieMiddleware()}, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	require.NoError(t, cl.SetSessionOptionValues(ctx, map[string]any{"catalog": "main"}))
+
+	options, err := cl.GetSessionOptionValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"catalog": "main"}, options)
+
+	_, err = cl.GetTables(ctx, &flightsql.GetTablesOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main"}, srv.catalogs)
+
+	// rejected options are reported by name
+	err = cl.SetSessionOptionValues(ctx, map[string]any{"catalog": "", "schema": "public", "timeout": int64(10)})
+	var optErr *flightsql.SessionOptionsError
+	require.ErrorAs(t, err, &optErr)
+	assert.ErrorIs(t, err, arrow.ErrInvalid)
+	assert.Equal(t, map[string]flight.SetSessionOptionsResultErrorValue{
+		"catalog": flight.SetSessionOptionsResultErrorInvalidValue,
+		"schema":  flight.SetSessionOptionsResultErrorInvalidName,
+		"timeout": flight.SetSessionOptionsResultErrorInvalidName,
+	}, optErr.Errors)
+	assert.EqualError(t, err, "arrow/flightsql: session options rejected: catalog (INVALID_VALUE), schema (INVALID_NAME), timeout (INVALID_NAME)")
+
+	// values the client can't send fail before reaching the server
+	err = cl.SetSessionOptionValues(ctx, map[string]any{"catalog": 1})
+	assert.ErrorIs(t, err, arrow.ErrInvalid)
+
+	require.NoError(t, cl.SetSessionOptionValues(ctx, map[string]any{"catalog": nil}))
+	options, err = cl.GetSessionOptionValues(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, options)
+}
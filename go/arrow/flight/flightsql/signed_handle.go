@@ -0,0 +1,78 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// NewSignedHandle returns an opaque handle packing payload with an
+// HMAC-SHA256 tag computed with key, for servers to return as the handle
+// of a prepared statement or transaction. VerifySignedHandle recovers the
+// payload of a handle, rejecting any that was not created with the same
+// key, so a client can't forge the handle of another client's statement.
+//
+// The payload is not encrypted, so it shouldn't contain secrets.
+func NewSignedHandle(key []byte, payload proto.Message) []byte {
+	packed, err := anypb.New(payload)
+	if err != nil {
+		panic(fmt.Errorf("arrow/flightsql: cannot pack handle payload: %w", err))
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(packed)
+	if err != nil {
+		panic(fmt.Errorf("arrow/flightsql: cannot marshal handle payload: %w", err))
+	}
+
+	return append(data, handleTag(key, data)...)
+}
+
+// VerifySignedHandle checks the tag of a handle returned by
+// NewSignedHandle with the same key and returns its payload, or an
+// error wrapping arrow.ErrInvalid if the handle was modified or signed
+// with another key.
+func VerifySignedHandle(key, handle []byte) (proto.Message, error) {
+	if len(handle) < sha256.Size {
+		return nil, fmt.Errorf("%w: arrow/flightsql: handle is too short", arrow.ErrInvalid)
+	}
+
+	data, tag := handle[:len(handle)-sha256.Size], handle[len(handle)-sha256.Size:]
+	if !hmac.Equal(tag, handleTag(key, data)) {
+		return nil, fmt.Errorf("%w: arrow/flightsql: invalid handle signature", arrow.ErrInvalid)
+	}
+
+	var packed anypb.Any
+	if err := proto.Unmarshal(data, &packed); err != nil {
+		return nil, fmt.Errorf("%w: arrow/flightsql: malformed handle: %s", arrow.ErrInvalid, err)
+	}
+	payload, err := packed.UnmarshalNew()
+	if err != nil {
+		return nil, fmt.Errorf("%w: arrow/flightsql: malformed handle: %s", arrow.ErrInvalid, err)
+	}
+	return payload, nil
+}
+
+func handleTag(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
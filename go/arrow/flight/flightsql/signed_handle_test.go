@@ -0,0 +1,66 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSignedHandle(t *testing.T) {
+	key := []byte("tenant key")
+	payload := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("statement 1")}
+
+	handle := flightsql.NewSignedHandle(key, payload)
+
+	t.Run("round trip", func(t *testing.T) {
+		got, err := flightsql.VerifySignedHandle(key, handle)
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(payload, got), "got %v", got)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		for i := range handle {
+			tampered := append([]byte(nil), handle...)
+			tampered[i] ^= 1
+			_, err := flightsql.VerifySignedHandle(key, tampered)
+			assert.ErrorIs(t, err, arrow.ErrInvalid, "byte %d", i)
+		}
+
+		_, err := flightsql.VerifySignedHandle(key, handle[:len(handle)-1])
+		assert.ErrorIs(t, err, arrow.ErrInvalid)
+		_, err = flightsql.VerifySignedHandle(key, []byte("short"))
+		assert.ErrorIs(t, err, arrow.ErrInvalid)
+		_, err = flightsql.VerifySignedHandle(key, nil)
+		assert.ErrorIs(t, err, arrow.ErrInvalid)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, err := flightsql.VerifySignedHandle([]byte("other key"), handle)
+		assert.ErrorIs(t, err, arrow.ErrInvalid)
+
+		forged := flightsql.NewSignedHandle([]byte("other key"), payload)
+		_, err = flightsql.VerifySignedHandle(key, forged)
+		assert.ErrorIs(t, err, arrow.ErrInvalid)
+	})
+}
@@ -17,6 +17,8 @@
 package flightsql
 
 import (
+	"fmt"
+
 	"github.com/apache/arrow/go/v16/arrow"
 	"github.com/apache/arrow/go/v16/arrow/array"
 )
@@ -91,3 +93,137 @@ func (s *sqlInfoResultBldr) Append(v interface{}) {
 		}
 	}
 }
+
+// ParseSqlInfoResult reads the result of a GetSqlInfo request into a
+// SqlInfoResultMap. Each value has the Go type accepted by
+// BaseServer.RegisterSqlInfo for it: string, bool, int64, int32, []string
+// or map[int32][]int32.
+//
+// The children of the value union are identified by their names rather
+// than their position or type code, so results from servers which order
+// them differently are decoded correctly. Null values are skipped.
+func ParseSqlInfoResult(rdr array.RecordReader) (SqlInfoResultMap, error) {
+	out := make(SqlInfoResultMap)
+	for rdr.Next() {
+		if err := parseSqlInfoRecord(rdr.Record(), out); err != nil {
+			return nil, err
+		}
+	}
+	return out, rdr.Err()
+}
+
+func parseSqlInfoRecord(rec arrow.Record, out SqlInfoResultMap) error {
+	nameIdx, valueIdx := rec.Schema().FieldIndices("info_name"), rec.Schema().FieldIndices("value")
+	if len(nameIdx) != 1 || len(valueIdx) != 1 {
+		return fmt.Errorf("%w: unexpected sql info result schema: %s", arrow.ErrInvalid, rec.Schema())
+	}
+
+	names, ok := rec.Column(nameIdx[0]).(*array.Uint32)
+	if !ok {
+		return fmt.Errorf("%w: sql info names must be uint32, got %s", arrow.ErrInvalid, rec.Column(nameIdx[0]).DataType())
+	}
+	values, ok := rec.Column(valueIdx[0]).(*array.DenseUnion)
+	if !ok {
+		return fmt.Errorf("%w: sql info values must be a dense union, got %s", arrow.ErrInvalid, rec.Column(valueIdx[0]).DataType())
+	}
+
+	fields := values.UnionType().Fields()
+	for i := 0; i < names.Len(); i++ {
+		childID := values.ChildID(i)
+		v, err := sqlInfoValue(fields[childID].Name, values.Field(childID), int(values.ValueOffset(i)))
+		if err != nil {
+			return fmt.Errorf("sql info %d: %w", names.Value(i), err)
+		}
+		if v != nil {
+			out[names.Value(i)] = v
+		}
+	}
+	return nil
+}
+
+// sqlInfoValue returns the value at index i of the union child called
+// name, or nil if it is null.
+func sqlInfoValue(name string, child arrow.Array, i int) (interface{}, error) {
+	if child.IsNull(i) {
+		return nil, nil
+	}
+
+	var (
+		v  interface{}
+		ok bool
+	)
+	switch name {
+	case "string_value":
+		var arr *array.String
+		if arr, ok = child.(*array.String); ok {
+			v = arr.Value(i)
+		}
+	case "bool_value":
+		var arr *array.Boolean
+		if arr, ok = child.(*array.Boolean); ok {
+			v = arr.Value(i)
+		}
+	case "bigint_value":
+		var arr *array.Int64
+		if arr, ok = child.(*array.Int64); ok {
+			v = arr.Value(i)
+		}
+	case "int32_bitmask":
+		var arr *array.Int32
+		if arr, ok = child.(*array.Int32); ok {
+			v = arr.Value(i)
+		}
+	case "string_list":
+		var arr *array.List
+		if arr, ok = child.(*array.List); ok {
+			var elems *array.String
+			if elems, ok = arr.ListValues().(*array.String); ok {
+				start, end := arr.ValueOffsets(i)
+				list := make([]string, 0, end-start)
+				for j := start; j < end; j++ {
+					list = append(list, elems.Value(int(j)))
+				}
+				v = list
+			}
+		}
+	case "int32_to_int32_list_map":
+		var arr *array.Map
+		if arr, ok = child.(*array.Map); ok {
+			v, ok = int32ToInt32ListValue(arr, i)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown sql info value type %q", arrow.ErrInvalid, name)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected type %s for sql info value %q", arrow.ErrInvalid, child.DataType(), name)
+	}
+	return v, nil
+}
+
+func int32ToInt32ListValue(arr *array.Map, i int) (map[int32][]int32, bool) {
+	keys, ok := arr.Keys().(*array.Int32)
+	if !ok {
+		return nil, false
+	}
+	items, ok := arr.Items().(*array.List)
+	if !ok {
+		return nil, false
+	}
+	elems, ok := items.ListValues().(*array.Int32)
+	if !ok {
+		return nil, false
+	}
+
+	start, end := arr.ValueOffsets(i)
+	out := make(map[int32][]int32, end-start)
+	for j := int(start); j < int(end); j++ {
+		lstart, lend := items.ValueOffsets(j)
+		list := make([]int32, 0, lend-lstart)
+		for k := lstart; k < lend; k++ {
+			list = append(list, elems.Value(int(k)))
+		}
+		out[keys.Value(j)] = list
+	}
+	return out, true
+}
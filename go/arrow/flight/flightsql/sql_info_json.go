@@ -0,0 +1,182 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
+)
+
+// sqlInfoJSONValue is the JSON representation of a single SqlInfo value.
+// Exactly one of the members must be set, identifying which member of the
+// SqlInfo dense union the value belongs to.
+type sqlInfoJSONValue struct {
+	String              *string            `json:"string,omitempty"`
+	Bool                *bool              `json:"bool,omitempty"`
+	Int64               *int64             `json:"int64,omitempty"`
+	Int32Bitmask        *int32             `json:"int32_bitmask,omitempty"`
+	StringList          *[]string          `json:"string_list,omitempty"`
+	Int32ToInt32ListMap *map[int32][]int32 `json:"int32_to_int32_list_map,omitempty"`
+}
+
+func (v *sqlInfoJSONValue) value() (interface{}, error) {
+	var (
+		out interface{}
+		n   int
+	)
+	if v.String != nil {
+		out, n = *v.String, n+1
+	}
+	if v.Bool != nil {
+		out, n = *v.Bool, n+1
+	}
+	if v.Int64 != nil {
+		out, n = *v.Int64, n+1
+	}
+	if v.Int32Bitmask != nil {
+		out, n = *v.Int32Bitmask, n+1
+	}
+	if v.StringList != nil {
+		out, n = *v.StringList, n+1
+	}
+	if v.Int32ToInt32ListMap != nil {
+		out, n = *v.Int32ToInt32ListMap, n+1
+	}
+
+	if n != 1 {
+		return nil, fmt.Errorf("must have exactly one value member, found %d", n)
+	}
+	return out, nil
+}
+
+func sqlInfoToJSONValue(v interface{}) (sqlInfoJSONValue, error) {
+	var out sqlInfoJSONValue
+	switch v := v.(type) {
+	case string:
+		out.String = &v
+	case bool:
+		out.Bool = &v
+	case int64:
+		out.Int64 = &v
+	case int32:
+		out.Int32Bitmask = &v
+	case []string:
+		out.StringList = &v
+	case map[int32][]int32:
+		out.Int32ToInt32ListMap = &v
+	default:
+		return out, fmt.Errorf("invalid sql info type '%T'", v)
+	}
+	return out, nil
+}
+
+func parseSqlInfoKey(key string) (uint32, error) {
+	if id, ok := pb.SqlInfo_value[key]; ok {
+		return uint32(id), nil
+	}
+
+	id, err := strconv.ParseUint(key, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: unknown sql info '%s'", arrow.ErrInvalid, key)
+	}
+	return uint32(id), nil
+}
+
+// SqlInfoResultMapFromJSON reads a SqlInfoResultMap from its JSON
+// representation. The input must be a single JSON object where each key
+// is either the name of a SqlInfo (e.g. "FLIGHT_SQL_SERVER_NAME") or its
+// numeric id (e.g. "0"), and each value is an object with exactly one of
+// the following members, selecting the type of the value:
+//
+//	"string":                  a string
+//	"bool":                    a boolean
+//	"int64":                   a 64-bit integer
+//	"int32_bitmask":           a 32-bit integer, used for bitmasks
+//	"string_list":             a list of strings
+//	"int32_to_int32_list_map": an object of 32-bit integer keys to lists of
+//	                           32-bit integers
+//
+// For example:
+//
+//	{
+//	  "FLIGHT_SQL_SERVER_NAME": {"string": "my server"},
+//	  "FLIGHT_SQL_SERVER_READ_ONLY": {"bool": false},
+//	  "SQL_KEYWORDS": {"string_list": ["ABS", "ALL"]},
+//	  "SQL_SUPPORTS_CONVERT": {"int32_to_int32_list_map": {"0": [1, 2]}}
+//	}
+//
+// Unknown SqlInfo names, unknown members and values which don't set exactly
+// one member are reported as errors identifying the offending key.
+func SqlInfoResultMapFromJSON(r io.Reader) (SqlInfoResultMap, error) {
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: invalid sql info json: %s", arrow.ErrInvalid, err.Error())
+	}
+
+	out := make(SqlInfoResultMap, len(raw))
+	for key, msg := range raw {
+		id, err := parseSqlInfoKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, dup := out[id]; dup {
+			return nil, fmt.Errorf("%w: sql info '%s' specified more than once", arrow.ErrInvalid, key)
+		}
+
+		var v sqlInfoJSONValue
+		dec := json.NewDecoder(bytes.NewReader(msg))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("%w: invalid value for sql info '%s': %s", arrow.ErrInvalid, key, err.Error())
+		}
+
+		if out[id], err = v.value(); err != nil {
+			return nil, fmt.Errorf("%w: invalid value for sql info '%s': %s", arrow.ErrInvalid, key, err.Error())
+		}
+	}
+	return out, nil
+}
+
+// WriteJSON writes the map to w using the JSON representation documented
+// on SqlInfoResultMapFromJSON. Well known ids are written using their
+// SqlInfo name, any others by their numeric id.
+func (s SqlInfoResultMap) WriteJSON(w io.Writer) error {
+	out := make(map[string]sqlInfoJSONValue, len(s))
+	for id, v := range s {
+		key, ok := pb.SqlInfo_name[int32(id)]
+		if !ok {
+			key = strconv.FormatUint(uint64(id), 10)
+		}
+
+		val, err := sqlInfoToJSONValue(v)
+		if err != nil {
+			return fmt.Errorf("%w: sql info %d: %s", arrow.ErrInvalid, id, err.Error())
+		}
+		out[key] = val
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
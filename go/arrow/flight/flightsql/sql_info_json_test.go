@@ -0,0 +1,116 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const sqlInfoJSON = `{
+	"FLIGHT_SQL_SERVER_NAME": {"string": "test server"},
+	"FLIGHT_SQL_SERVER_READ_ONLY": {"bool": true},
+	"SQL_MAX_COLUMNS_IN_TABLE": {"int64": 1024},
+	"SQL_SUPPORTED_TRANSACTIONS_ISOLATION_LEVELS": {"int32_bitmask": 5},
+	"SQL_KEYWORDS": {"string_list": ["ABS", "ALL"]},
+	"SQL_SUPPORTS_CONVERT": {"int32_to_int32_list_map": {"0": [1, 2], "3": []}},
+	"10000": {"string": "custom"},
+	"1": {"string": "1.0"}
+}`
+
+func TestSqlInfoResultMapFromJSON(t *testing.T) {
+	info, err := flightsql.SqlInfoResultMapFromJSON(strings.NewReader(sqlInfoJSON))
+	require.NoError(t, err)
+
+	assert.Equal(t, flightsql.SqlInfoResultMap{
+		uint32(flightsql.SqlInfoFlightSqlServerName):                  "test server",
+		uint32(flightsql.SqlInfoFlightSqlServerVersion):               "1.0",
+		uint32(flightsql.SqlInfoFlightSqlServerReadOnly):              true,
+		uint32(flightsql.SqlInfoMaxColumnsInTable):                    int64(1024),
+		uint32(flightsql.SqlInfoSupportedTransactionsIsolationlevels): int32(5),
+		uint32(flightsql.SqlInfoKeywords):                             []string{"ABS", "ALL"},
+		uint32(flightsql.SqlInfoSupportsConvert):                      map[int32][]int32{0: {1, 2}, 3: {}},
+		10000:                                                         "custom",
+	}, info)
+}
+
+func TestSqlInfoResultMapJSONRoundTrip(t *testing.T) {
+	info, err := flightsql.SqlInfoResultMapFromJSON(strings.NewReader(sqlInfoJSON))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, info.WriteJSON(&buf))
+	assert.Contains(t, buf.String(), `"FLIGHT_SQL_SERVER_NAME"`)
+	assert.Contains(t, buf.String(), `"10000"`)
+
+	roundTrip, err := flightsql.SqlInfoResultMapFromJSON(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, info, roundTrip)
+}
+
+func TestSqlInfoResultMapFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		name, json, errContains string
+	}{
+		{"unknown name", `{"NOT_A_SQL_INFO": {"string": "x"}}`, "NOT_A_SQL_INFO"},
+		{"unknown member", `{"SQL_KEYWORDS": {"strings": ["x"]}}`, "SQL_KEYWORDS"},
+		{"no member", `{"SQL_KEYWORDS": {}}`, "SQL_KEYWORDS"},
+		{"two members", `{"SQL_KEYWORDS": {"string": "x", "bool": true}}`, "SQL_KEYWORDS"},
+		{"wrong type", `{"SQL_MAX_COLUMNS_IN_TABLE": {"int64": "x"}}`, "SQL_MAX_COLUMNS_IN_TABLE"},
+		{"duplicate", `{"FLIGHT_SQL_SERVER_NAME": {"string": "x"}, "0": {"string": "y"}}`, "more than once"},
+		{"not an object", `[]`, "invalid sql info json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := flightsql.SqlInfoResultMapFromJSON(strings.NewReader(tt.json))
+			assert.ErrorIs(t, err, arrow.ErrInvalid)
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
+}
+
+func TestRegisterSqlInfoMap(t *testing.T) {
+	info, err := flightsql.SqlInfoResultMapFromJSON(strings.NewReader(sqlInfoJSON))
+	require.NoError(t, err)
+
+	var srv testServer
+	require.NoError(t, srv.RegisterSqlInfoMap(info))
+
+	desc := &flight.FlightDescriptor{Cmd: []byte{}}
+	_, err = srv.GetFlightInfoSqlInfo(context.Background(), nil, desc)
+	require.NoError(t, err)
+
+	// an invalid map is rejected without replacing what was registered
+	assert.Error(t, srv.RegisterSqlInfoMap(flightsql.SqlInfoResultMap{0: "name", 1: 1.5}))
+	_, err = srv.GetFlightInfoSqlInfo(context.Background(), nil, desc)
+	require.NoError(t, err)
+
+	require.NoError(t, srv.RegisterSqlInfoMap(flightsql.SqlInfoResultMap{}))
+	_, err = srv.GetFlightInfoSqlInfo(context.Background(), nil, desc)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,154 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+// ErrSqlInfoNotReported is returned by Client.GetSqlInfoValues, along
+// with the values which were reported, when the server doesn't report
+// some of the requested SqlInfo.
+var ErrSqlInfoNotReported = errors.New("arrow/flightsql: sql info not reported by the server")
+
+// SqlInfoValues holds decoded SqlInfo values, as returned by
+// Client.GetSqlInfoValues. Each value has the Go type accepted by
+// BaseServer.RegisterSqlInfo for it: string, bool, int64, int32, []string
+// or map[int32][]int32.
+type SqlInfoValues map[SqlInfo]interface{}
+
+// GetSqlInfoValues retrieves the requested SqlInfo from the server and
+// decodes it, or all of the server's SqlInfo if ids is empty.
+//
+// All of the server's SqlInfo is requested either way, since servers fail
+// the request if a specific id is asked for which they don't provide. If
+// some of ids aren't reported, the values which are get returned together
+// with an error wrapping ErrSqlInfoNotReported.
+func (c *Client) GetSqlInfoValues(ctx context.Context, ids []SqlInfo, opts ...grpc.CallOption) (SqlInfoValues, error) {
+	flightInfo, err := c.GetSqlInfo(ctx, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rdr, err := c.ReadFlightInfo(ctx, flightInfo, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Release()
+
+	info, err := ParseSqlInfoResult(rdr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		values := make(SqlInfoValues, len(info))
+		for id, v := range info {
+			values[SqlInfo(id)] = v
+		}
+		return values, nil
+	}
+
+	var (
+		values  = make(SqlInfoValues, len(ids))
+		missing []SqlInfo
+	)
+	for _, id := range ids {
+		if v, ok := info[uint32(id)]; ok {
+			values[id] = v
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return values, fmt.Errorf("%w: %v", ErrSqlInfoNotReported, missing)
+	}
+	return values, nil
+}
+
+// String returns the value of id if it was reported as a string.
+func (v SqlInfoValues) String(id SqlInfo) (string, bool) {
+	s, ok := v[id].(string)
+	return s, ok
+}
+
+// Bool returns the value of id if it was reported as a bool.
+func (v SqlInfoValues) Bool(id SqlInfo) (bool, bool) {
+	b, ok := v[id].(bool)
+	return b, ok
+}
+
+// Int64 returns the value of id if it was reported as an int64.
+func (v SqlInfoValues) Int64(id SqlInfo) (int64, bool) {
+	n, ok := v[id].(int64)
+	return n, ok
+}
+
+// Int32 returns the value of id if it was reported as an int32, which is
+// used for enums and bitmasks.
+func (v SqlInfoValues) Int32(id SqlInfo) (int32, bool) {
+	n, ok := v[id].(int32)
+	return n, ok
+}
+
+// StringList returns the value of id if it was reported as a list of
+// strings.
+func (v SqlInfoValues) StringList(id SqlInfo) ([]string, bool) {
+	l, ok := v[id].([]string)
+	return l, ok
+}
+
+// Int32ToInt32ListMap returns the value of id if it was reported as a map
+// of int32 to lists of int32, such as SqlInfoSupportsConvert.
+func (v SqlInfoValues) Int32ToInt32ListMap(id SqlInfo) (map[int32][]int32, bool) {
+	m, ok := v[id].(map[int32][]int32)
+	return m, ok
+}
+
+// ServerName returns the reported SqlInfoFlightSqlServerName.
+func (v SqlInfoValues) ServerName() (string, bool) {
+	return v.String(SqlInfoFlightSqlServerName)
+}
+
+// ServerVersion returns the reported SqlInfoFlightSqlServerVersion.
+func (v SqlInfoValues) ServerVersion() (string, bool) {
+	return v.String(SqlInfoFlightSqlServerVersion)
+}
+
+// ArrowVersion returns the reported SqlInfoFlightSqlServerArrowVersion.
+func (v SqlInfoValues) ArrowVersion() (string, bool) {
+	return v.String(SqlInfoFlightSqlServerArrowVersion)
+}
+
+// ReadOnly returns the reported SqlInfoFlightSqlServerReadOnly.
+func (v SqlInfoValues) ReadOnly() (bool, bool) {
+	return v.Bool(SqlInfoFlightSqlServerReadOnly)
+}
+
+// SupportsTransactions returns whether the server supports transactions,
+// according to SqlInfoFlightSqlServerTransaction or, if that isn't
+// reported, SqlInfoTransactionsSupported.
+func (v SqlInfoValues) SupportsTransactions() (bool, bool) {
+	if t, ok := v.Int32(SqlInfoFlightSqlServerTransaction); ok {
+		return SqlSupportedTransaction(t) != SqlTransactionNone, true
+	}
+	return v.Bool(SqlInfoTransactionsSupported)
+}
@@ -0,0 +1,353 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/decimal128"
+	"github.com/apache/arrow/go/v16/arrow/decimal256"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+)
+
+// defaultSQLRowsBatchSize is the number of rows per record used by
+// RecordsFromSQLRows if no batch size is given.
+const defaultSQLRowsBatchSize = 1024
+
+var (
+	scanTypeTime        = reflect.TypeOf(time.Time{})
+	scanTypeBytes       = reflect.TypeOf([]byte(nil))
+	scanTypeRawBytes    = reflect.TypeOf(sql.RawBytes(nil))
+	scanTypeNullBool    = reflect.TypeOf(sql.NullBool{})
+	scanTypeNullByte    = reflect.TypeOf(sql.NullByte{})
+	scanTypeNullInt16   = reflect.TypeOf(sql.NullInt16{})
+	scanTypeNullInt32   = reflect.TypeOf(sql.NullInt32{})
+	scanTypeNullInt64   = reflect.TypeOf(sql.NullInt64{})
+	scanTypeNullFloat64 = reflect.TypeOf(sql.NullFloat64{})
+	scanTypeNullString  = reflect.TypeOf(sql.NullString{})
+	scanTypeNullTime    = reflect.TypeOf(sql.NullTime{})
+)
+
+// RecordsFromSQLRows converts the result of a database/sql query into
+// records of up to batchSize rows, for returning from handlers such as
+// DoGetStatement. A batchSize of 0 or less uses a default of 1024 rows.
+//
+// The schema is derived from rows.ColumnTypes: the column's ScanType is
+// used if the driver reports a specific one, otherwise its
+// DatabaseTypeName. Integers map to the Arrow integer of the same width,
+// times to microsecond timestamps in UTC, DECIMAL and NUMERIC columns
+// with a known size to decimal128 or decimal256, and anything unknown to
+// strings. Columns are nullable unless the driver reports otherwise.
+//
+// The rows are read and closed by a goroutine which sends the records on
+// the returned channel, which must be drained. Errors are sent as the
+// last chunk.
+func RecordsFromSQLRows(mem memory.Allocator, rows *sql.Rows, batchSize int) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	if batchSize <= 0 {
+		batchSize = defaultSQLRowsBatchSize
+	}
+
+	cols, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		return nil, nil, err
+	}
+
+	fields := make([]arrow.Field, len(cols))
+	for i, c := range cols {
+		nullable, ok := c.Nullable()
+		fields[i] = arrow.Field{
+			Name:     c.Name(),
+			Type:     sqlColumnArrowType(c),
+			Nullable: nullable || !ok,
+		}
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	ch := make(chan flight.StreamChunk, 1)
+	go func() {
+		defer close(ch)
+		defer rows.Close()
+
+		if err := streamSQLRows(mem, schema, rows, batchSize, ch); err != nil {
+			ch <- flight.StreamChunk{Err: err}
+		}
+	}()
+	return schema, ch, nil
+}
+
+func streamSQLRows(mem memory.Allocator, schema *arrow.Schema, rows *sql.Rows, batchSize int, ch chan<- flight.StreamChunk) error {
+	bldr := array.NewRecordBuilder(mem, schema)
+	defer bldr.Release()
+
+	dests := make([]interface{}, schema.NumFields())
+	appends := make([]func() error, schema.NumFields())
+	for i, f := range schema.Fields() {
+		dests[i], appends[i] = sqlColumnScanner(f.Type, bldr.Field(i))
+	}
+
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dests...); err != nil {
+			return err
+		}
+		for i, appendValue := range appends {
+			if err := appendValue(); err != nil {
+				return fmt.Errorf("column %q: %w", schema.Field(i).Name, err)
+			}
+		}
+
+		if n++; n == batchSize {
+			ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+			n = 0
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if n > 0 {
+		ch <- flight.StreamChunk{Data: bldr.NewRecord()}
+	}
+	return nil
+}
+
+// sqlColumnArrowType returns the Arrow type for the column c of a
+// database/sql result.
+func sqlColumnArrowType(c *sql.ColumnType) arrow.DataType {
+	name := strings.ToUpper(c.DatabaseTypeName())
+	if precision, scale, ok := c.DecimalSize(); ok && precision > 0 &&
+		(strings.HasPrefix(name, "DECIMAL") || strings.HasPrefix(name, "NUMERIC")) {
+		if precision <= 38 {
+			return &arrow.Decimal128Type{Precision: int32(precision), Scale: int32(scale)}
+		}
+		return &arrow.Decimal256Type{Precision: int32(precision), Scale: int32(scale)}
+	}
+
+	// drivers such as SQLite's report a string scan type for their times,
+	// while returning a time.Time
+	isTime := strings.HasPrefix(name, "DATE") || strings.HasPrefix(name, "TIMESTAMP")
+
+	if st := c.ScanType(); st != nil && !(isTime && (st.Kind() == reflect.String || st.Kind() == reflect.Interface)) {
+		switch st {
+		case scanTypeTime, scanTypeNullTime:
+			return arrow.FixedWidthTypes.Timestamp_us
+		case scanTypeBytes, scanTypeRawBytes:
+			return arrow.BinaryTypes.Binary
+		case scanTypeNullBool:
+			return arrow.FixedWidthTypes.Boolean
+		case scanTypeNullByte:
+			return arrow.PrimitiveTypes.Uint8
+		case scanTypeNullInt16:
+			return arrow.PrimitiveTypes.Int16
+		case scanTypeNullInt32:
+			return arrow.PrimitiveTypes.Int32
+		case scanTypeNullInt64:
+			return arrow.PrimitiveTypes.Int64
+		case scanTypeNullFloat64:
+			return arrow.PrimitiveTypes.Float64
+		case scanTypeNullString:
+			return arrow.BinaryTypes.String
+		}
+
+		switch st.Kind() {
+		case reflect.Bool:
+			return arrow.FixedWidthTypes.Boolean
+		case reflect.Int8:
+			return arrow.PrimitiveTypes.Int8
+		case reflect.Int16:
+			return arrow.PrimitiveTypes.Int16
+		case reflect.Int32:
+			return arrow.PrimitiveTypes.Int32
+		case reflect.Int, reflect.Int64:
+			return arrow.PrimitiveTypes.Int64
+		case reflect.Uint8:
+			return arrow.PrimitiveTypes.Uint8
+		case reflect.Uint16:
+			return arrow.PrimitiveTypes.Uint16
+		case reflect.Uint32:
+			return arrow.PrimitiveTypes.Uint32
+		case reflect.Uint, reflect.Uint64:
+			return arrow.PrimitiveTypes.Uint64
+		case reflect.Float32:
+			return arrow.PrimitiveTypes.Float32
+		case reflect.Float64:
+			return arrow.PrimitiveTypes.Float64
+		case reflect.String:
+			return arrow.BinaryTypes.String
+		}
+	}
+
+	switch {
+	case strings.Contains(name, "BOOL"):
+		return arrow.FixedWidthTypes.Boolean
+	case name == "TINYINT":
+		return arrow.PrimitiveTypes.Int8
+	case name == "SMALLINT":
+		return arrow.PrimitiveTypes.Int16
+	case strings.Contains(name, "INT"):
+		return arrow.PrimitiveTypes.Int64
+	case strings.Contains(name, "REAL"), strings.Contains(name, "FLOA"), strings.Contains(name, "DOUB"):
+		return arrow.PrimitiveTypes.Float64
+	case strings.Contains(name, "BLOB"), strings.Contains(name, "BINARY"), name == "BYTEA":
+		return arrow.BinaryTypes.Binary
+	case isTime:
+		return arrow.FixedWidthTypes.Timestamp_us
+	}
+	return arrow.BinaryTypes.String
+}
+
+// sqlColumnScanner returns the destination to pass to sql.Rows.Scan for a
+// column of type dt, and the function appending the scanned value to b.
+func sqlColumnScanner(dt arrow.DataType, b array.Builder) (interface{}, func() error) {
+	switch b := b.(type) {
+	case *array.BooleanBuilder:
+		var v sql.NullBool
+		return &v, func() error {
+			b.AppendValues([]bool{v.Bool}, []bool{v.Valid})
+			return nil
+		}
+	case *array.Float32Builder:
+		var v sql.NullFloat64
+		return &v, func() error {
+			b.AppendValues([]float32{float32(v.Float64)}, []bool{v.Valid})
+			return nil
+		}
+	case *array.Float64Builder:
+		var v sql.NullFloat64
+		return &v, func() error {
+			b.AppendValues([]float64{v.Float64}, []bool{v.Valid})
+			return nil
+		}
+	case *array.StringBuilder:
+		var v sql.NullString
+		return &v, func() error {
+			b.AppendValues([]string{v.String}, []bool{v.Valid})
+			return nil
+		}
+	case *array.BinaryBuilder:
+		var v []byte
+		return &v, func() error {
+			if v == nil {
+				b.AppendNull()
+			} else {
+				b.Append(v)
+			}
+			return nil
+		}
+	case *array.TimestampBuilder:
+		unit := dt.(*arrow.TimestampType).Unit
+		var v interface{}
+		return &v, func() error {
+			var t time.Time
+			switch v := v.(type) {
+			case nil:
+				b.AppendNull()
+				return nil
+			case time.Time:
+				t = v
+			case string:
+				ts, err := arrow.TimestampFromString(v, unit)
+				if err != nil {
+					return err
+				}
+				b.Append(ts)
+				return nil
+			default:
+				return fmt.Errorf("%w: cannot convert %T to a timestamp", arrow.ErrInvalid, v)
+			}
+
+			ts, err := arrow.TimestampFromTime(t, unit)
+			if err != nil {
+				return err
+			}
+			b.Append(ts)
+			return nil
+		}
+	case *array.Decimal128Builder:
+		decType := dt.(*arrow.Decimal128Type)
+		var v sql.NullString
+		return &v, func() error {
+			if !v.Valid {
+				b.AppendNull()
+				return nil
+			}
+			n, err := decimal128.FromString(v.String, decType.Precision, decType.Scale)
+			if err != nil {
+				return err
+			}
+			b.Append(n)
+			return nil
+		}
+	case *array.Decimal256Builder:
+		decType := dt.(*arrow.Decimal256Type)
+		var v sql.NullString
+		return &v, func() error {
+			if !v.Valid {
+				b.AppendNull()
+				return nil
+			}
+			n, err := decimal256.FromString(v.String, decType.Precision, decType.Scale)
+			if err != nil {
+				return err
+			}
+			b.Append(n)
+			return nil
+		}
+	}
+
+	// the integer types, unsigned values beyond the range of int64 aren't
+	// supported by database/sql
+	var v sql.NullInt64
+	return &v, func() error {
+		if !v.Valid {
+			b.AppendNull()
+			return nil
+		}
+		switch b := b.(type) {
+		case *array.Int8Builder:
+			b.Append(int8(v.Int64))
+		case *array.Int16Builder:
+			b.Append(int16(v.Int64))
+		case *array.Int32Builder:
+			b.Append(int32(v.Int64))
+		case *array.Int64Builder:
+			b.Append(v.Int64)
+		case *array.Uint8Builder:
+			b.Append(uint8(v.Int64))
+		case *array.Uint16Builder:
+			b.Append(uint16(v.Int64))
+		case *array.Uint32Builder:
+			b.Append(uint32(v.Int64))
+		case *array.Uint64Builder:
+			b.Append(uint64(v.Int64))
+		default:
+			return fmt.Errorf("%w: unsupported column type %s", arrow.ErrNotImplemented, dt)
+		}
+		return nil
+	}
+}
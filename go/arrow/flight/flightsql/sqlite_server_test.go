@@ -35,6 +35,7 @@ import (
 	"github.com/apache/arrow/go/v16/arrow/memory"
 	"github.com/apache/arrow/go/v16/arrow/scalar"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -905,6 +906,177 @@ func (s *FlightSqliteServerSuite) TestTransactions() {
 	s.EqualValues(rowCount, tbl.NumRows())
 }
 
+func (s *FlightSqliteServerSuite) TestPreparedStatementInTransaction() {
+	ctx := context.Background()
+	rowCount := s.execCountQuery("SELECT COUNT(*) FROM intTable")
+
+	tx, err := s.cl.BeginTransaction(ctx)
+	s.Require().NoError(err)
+	_, err = tx.ExecuteUpdate(ctx, "INSERT INTO intTable (keyName, value) VALUES ('in txn', 1)")
+	s.Require().NoError(err)
+
+	// the statement sees the rows inserted by the transaction
+	prep, err := tx.Prepare(ctx, "SELECT COUNT(*) FROM intTable")
+	s.Require().NoError(err)
+	defer prep.Close(ctx)
+
+	info, err := prep.Execute(ctx)
+	s.Require().NoError(err)
+	rdr, err := s.cl.DoGet(ctx, info.Endpoint[0].Ticket)
+	s.Require().NoError(err)
+	defer rdr.Release()
+	rec, err := rdr.Read()
+	s.Require().NoError(err)
+	s.EqualValues(rowCount+1, rec.Column(0).(*array.Int64).Value(0))
+
+	s.Require().NoError(tx.Rollback(ctx))
+	s.EqualValues(rowCount, s.execCountQuery("SELECT COUNT(*) FROM intTable"))
+}
+
 func TestSqliteServer(t *testing.T) {
 	suite.Run(t, new(FlightSqliteServerSuite))
 }
+
+func TestRecordsFromSQLRows(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE mixed (
+		id INTEGER NOT NULL, price REAL, name TEXT, data BLOB, flag BOOLEAN, created DATETIME);
+	INSERT INTO mixed VALUES (1, 1.5, 'one', x'0102', true, '2024-01-02 03:04:05');
+	INSERT INTO mixed VALUES (2, NULL, NULL, NULL, NULL, NULL);
+	INSERT INTO mixed VALUES (3, -2.25, 'three', x'03', false, '2024-05-06 07:08:09');`)
+	require.NoError(t, err)
+
+	rows, err := db.Query("SELECT * FROM mixed ORDER BY id")
+	require.NoError(t, err)
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	schema, ch, err := flightsql.RecordsFromSQLRows(mem, rows, 2)
+	require.NoError(t, err)
+
+	expectedSchema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "price", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "data", Type: arrow.BinaryTypes.Binary, Nullable: true},
+		{Name: "flag", Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+		{Name: "created", Type: arrow.FixedWidthTypes.Timestamp_us, Nullable: true},
+	}, nil)
+	assert.Truef(t, expectedSchema.Equal(schema), "expected: %s\ngot: %s", expectedSchema, schema)
+
+	var recs []arrow.Record
+	for chunk := range ch {
+		require.NoError(t, chunk.Err)
+		recs = append(recs, chunk.Data)
+	}
+	defer func() {
+		for _, r := range recs {
+			r.Release()
+		}
+	}()
+
+	// batches of two rows
+	require.Len(t, recs, 2)
+	assert.EqualValues(t, 2, recs[0].NumRows())
+	assert.EqualValues(t, 1, recs[1].NumRows())
+
+	expected := []string{
+		`[{"id": 1, "price": 1.5, "name": "one", "data": "AQI=", "flag": true, "created": "2024-01-02T03:04:05"},
+		  {"id": 2, "price": null, "name": null, "data": null, "flag": null, "created": null}]`,
+		`[{"id": 3, "price": -2.25, "name": "three", "data": "Aw==", "flag": false, "created": "2024-05-06T07:08:09"}]`,
+	}
+	for i, rec := range recs {
+		exp, _, err := array.RecordFromJSON(mem, schema, strings.NewReader(expected[i]))
+		require.NoError(t, err)
+		assert.Truef(t, array.RecordEqual(exp, rec), "expected: %s\ngot: %s", exp, rec)
+		exp.Release()
+	}
+}
+
+// cancelingReader cancels the ingestion it is read by after a number of
+// records.
+type cancelingReader struct {
+	array.RecordReader
+
+	after  int
+	cancel context.CancelFunc
+}
+
+func (r *cancelingReader) Next() bool {
+	if r.after == 0 {
+		r.cancel()
+	}
+	r.after--
+	return r.RecordReader.Next()
+}
+
+func (s *FlightSqliteServerSuite) TestExecuteIngest() {
+	const batches, batchSize = 1000, 1000
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	bldr := array.NewInt64Builder(s.mem)
+	defer bldr.Release()
+	recs := make([]arrow.Record, batches)
+	for i := range recs {
+		for j := 0; j < batchSize; j++ {
+			bldr.Append(int64(i*batchSize + j))
+		}
+		arr := bldr.NewArray()
+		recs[i] = array.NewRecord(schema, []arrow.Array{arr}, batchSize)
+		arr.Release()
+		defer recs[i].Release()
+	}
+	newReader := func(recs []arrow.Record) array.RecordReader {
+		rdr, err := array.NewRecordReader(schema, recs)
+		s.Require().NoError(err)
+		return rdr
+	}
+
+	ctx := context.Background()
+	rdr := newReader(recs)
+	defer rdr.Release()
+	n, err := s.cl.ExecuteIngest(ctx, rdr, flightsql.IngestOptions{
+		Table:      "ingested",
+		IfNotExist: flightsql.IngestTableNotExistCreate,
+	})
+	s.Require().NoError(err)
+	s.EqualValues(batches*batchSize, n)
+	s.EqualValues(batches*batchSize, s.execCountQuery("SELECT COUNT(*) FROM ingested"))
+
+	// the table now exists
+	rdr = newReader(recs[:2])
+	defer rdr.Release()
+	_, err = s.cl.ExecuteIngest(ctx, rdr, flightsql.IngestOptions{Table: "ingested"})
+	s.Equal(codes.AlreadyExists, status.Code(err))
+
+	rdr = newReader(recs[:2])
+	defer rdr.Release()
+	n, err = s.cl.ExecuteIngest(ctx, rdr, flightsql.IngestOptions{
+		Table:    "ingested",
+		IfExists: flightsql.IngestTableExistsReplace,
+	})
+	s.Require().NoError(err)
+	s.EqualValues(2*batchSize, n)
+	s.EqualValues(2*batchSize, s.execCountQuery("SELECT COUNT(*) FROM ingested"))
+
+	rdr = newReader(recs[:1])
+	defer rdr.Release()
+	_, err = s.cl.ExecuteIngest(ctx, rdr, flightsql.IngestOptions{Table: "missing"})
+	s.Equal(codes.NotFound, status.Code(err))
+
+	// canceling the context stops the ingestion, which is rolled back
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	crdr := &cancelingReader{RecordReader: newReader(recs[:10]), after: 5, cancel: cancel}
+	defer crdr.Release()
+	_, err = s.cl.ExecuteIngest(cctx, crdr, flightsql.IngestOptions{
+		Table:    "ingested",
+		IfExists: flightsql.IngestTableExistsAppend,
+	})
+	s.ErrorIs(err, context.Canceled)
+	s.EqualValues(2*batchSize, s.execCountQuery("SELECT COUNT(*) FROM ingested"))
+}
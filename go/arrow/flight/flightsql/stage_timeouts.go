@@ -0,0 +1,180 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TimeoutStage is a stage of a call bounded by ClientTimeouts.
+type TimeoutStage int8
+
+const (
+	// TimeoutStageMetadata is a metadata call, such as GetFlightInfo.
+	TimeoutStageMetadata TimeoutStage = iota
+	// TimeoutStageStreamStart is the wait for the first message of a
+	// DoGet stream.
+	TimeoutStageStreamStart
+	// TimeoutStageProgress is the wait for each following message of a
+	// DoGet stream.
+	TimeoutStageProgress
+)
+
+func (s TimeoutStage) String() string {
+	switch s {
+	case TimeoutStageMetadata:
+		return "metadata call"
+	case TimeoutStageStreamStart:
+		return "stream start"
+	case TimeoutStageProgress:
+		return "stream progress"
+	}
+	return fmt.Sprintf("TimeoutStage(%d)", int8(s))
+}
+
+// StageTimeoutError is the error of a call which exceeded one of its
+// ClientTimeouts. It matches context.DeadlineExceeded with errors.Is and
+// has the codes.DeadlineExceeded status code.
+type StageTimeoutError struct {
+	Stage   TimeoutStage
+	Timeout time.Duration
+}
+
+func (e *StageTimeoutError) Error() string {
+	return fmt.Sprintf("arrow/flightsql: %s timeout of %s exceeded", e.Stage, e.Timeout)
+}
+
+func (e *StageTimeoutError) Unwrap() error { return context.DeadlineExceeded }
+
+func (e *StageTimeoutError) GRPCStatus() *status.Status {
+	return status.New(codes.DeadlineExceeded, e.Error())
+}
+
+// ClientTimeouts bounds the stages of the calls of a client separately,
+// unlike the deadline of a context which bounds a call as a whole. A zero
+// timeout doesn't bound its stage.
+type ClientTimeouts struct {
+	// Metadata bounds each GetFlightInfo, PollFlightInfo and GetSchema
+	// call.
+	Metadata time.Duration
+	// StreamStart bounds the time from the start of a DoGet call until
+	// its first message is received.
+	StreamStart time.Duration
+	// Progress bounds the time each following message of a DoGet stream
+	// is waited for, which starts once the previous one was received,
+	// whether it holds a record or only app metadata. It allows long
+	// streams while failing those which stall.
+	Progress time.Duration
+}
+
+// WithClientTimeouts returns middleware for NewClient which bounds the
+// stages of its calls as configured by timeouts. A call exceeding one
+// of the timeouts is canceled and fails with a *StageTimeoutError.
+func WithClientTimeouts(timeouts ClientTimeouts) flight.ClientMiddleware {
+	return flight.ClientMiddleware{
+		Unary: func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if timeouts.Metadata <= 0 {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+			callCtx, cancel := context.WithTimeout(ctx, timeouts.Metadata)
+			defer cancel()
+
+			err := invoker(callCtx, method, req, reply, cc, opts...)
+			if err != nil && ctx.Err() == nil && callCtx.Err() == context.DeadlineExceeded {
+				return &StageTimeoutError{Stage: TimeoutStageMetadata, Timeout: timeouts.Metadata}
+			}
+			return err
+		},
+		Stream: func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			if !strings.HasSuffix(method, "/DoGet") || (timeouts.StreamStart <= 0 && timeouts.Progress <= 0) {
+				return streamer(ctx, desc, cc, method, opts...)
+			}
+
+			ctx, cancel := context.WithCancel(ctx)
+			s := &timedStream{timeouts: timeouts, cancel: cancel}
+			s.arm(TimeoutStageStreamStart, timeouts.StreamStart)
+			stream, err := streamer(ctx, desc, cc, method, opts...)
+			if err != nil {
+				s.finish()
+				return nil, s.timeoutError(err)
+			}
+			s.ClientStream = stream
+			return s, nil
+		},
+	}
+}
+
+// timedStream cancels a DoGet stream when the wait for its next message
+// exceeds its timeout.
+type timedStream struct {
+	grpc.ClientStream
+	timeouts ClientTimeouts
+	cancel   context.CancelFunc
+
+	mu    sync.Mutex
+	timer *time.Timer
+	// expired is the stage whose timeout expired, if any
+	expired atomic.Pointer[StageTimeoutError]
+}
+
+// arm starts bounding the wait for the next message to d, if positive.
+func (s *timedStream) arm(stage TimeoutStage, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if d <= 0 {
+		return
+	}
+	s.timer = time.AfterFunc(d, func() {
+		s.expired.CompareAndSwap(nil, &StageTimeoutError{Stage: stage, Timeout: d})
+		s.cancel()
+	})
+}
+
+func (s *timedStream) finish() {
+	s.arm(TimeoutStageProgress, 0)
+	s.cancel()
+}
+
+func (s *timedStream) timeoutError(err error) error {
+	if expired := s.expired.Load(); expired != nil {
+		return expired
+	}
+	return err
+}
+
+func (s *timedStream) RecvMsg(m interface{}) error {
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		s.finish()
+		return s.timeoutError(err)
+	}
+	s.arm(TimeoutStageProgress, s.timeouts.Progress)
+	return nil
+}
@@ -0,0 +1,193 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stallingServer serves streams which stall in different ways, depending
+// on the ticket.
+type stallingServer struct {
+	flight.BaseFlightServer
+}
+
+func (*stallingServer) GetFlightInfo(ctx context.Context, _ *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (*stallingServer) DoGet(tkt *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	switch string(tkt.GetTicket()) {
+	case "slow start":
+		<-stream.Context().Done()
+	case "stall":
+		// a message, then nothing
+		if err := stream.Send(&flight.FlightData{AppMetadata: []byte("first")}); err != nil {
+			return err
+		}
+		<-stream.Context().Done()
+	case "heartbeat":
+		// metadata-only messages more often than the progress timeout,
+		// for much longer than it
+		for i := 0; i < 10; i++ {
+			time.Sleep(50 * time.Millisecond)
+			if err := stream.Send(&flight.FlightData{AppMetadata: []byte("heartbeat")}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestClientTimeouts(t *testing.T) {
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(&stallingServer{})
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	timeouts := flightsql.ClientTimeouts{
+		Metadata:    100 * time.Millisecond,
+		StreamStart: 100 * time.Millisecond,
+		Progress:    150 * time.Millisecond,
+	}
+	cl, err := flightsql.NewClient(s.Addr().String(), nil,
+		[]flight.ClientMiddleware{flightsql.WithClientTimeouts(timeouts)}, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	stageOf := func(t *testing.T, err error) flightsql.TimeoutStage {
+		var timeout *flightsql.StageTimeoutError
+		require.ErrorAs(t, err, &timeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+		return timeout.Stage
+	}
+	recvAll := func(tkt string) (msgs int, err error) {
+		stream, err := cl.Client.DoGet(ctx, &flight.Ticket{Ticket: []byte(tkt)})
+		if err != nil {
+			return 0, err
+		}
+		for {
+			if _, err := stream.Recv(); err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				return msgs, err
+			}
+			msgs++
+		}
+	}
+
+	t.Run("metadata", func(t *testing.T) {
+		_, err := cl.Execute(ctx, "SELECT 1")
+		assert.Equal(t, flightsql.TimeoutStageMetadata, stageOf(t, err))
+	})
+
+	t.Run("stream start", func(t *testing.T) {
+		msgs, err := recvAll("slow start")
+		assert.Zero(t, msgs)
+		assert.Equal(t, flightsql.TimeoutStageStreamStart, stageOf(t, err))
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		start := time.Now()
+		msgs, err := recvAll("stall")
+		assert.Equal(t, 1, msgs)
+		assert.Equal(t, flightsql.TimeoutStageProgress, stageOf(t, err))
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("metadata-only messages are progress", func(t *testing.T) {
+		msgs, err := recvAll("heartbeat")
+		require.NoError(t, err)
+		assert.Equal(t, 10, msgs)
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		// the deadline of the context is not a stage timeout
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		_, err := cl.Execute(ctx, "SELECT 1")
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+		var timeout *flightsql.StageTimeoutError
+		assert.False(t, errors.As(err, &timeout))
+	})
+}
+
+// stallingStatementServer stalls after the first record of its results.
+type stallingStatementServer struct {
+	flightsql.BaseServer
+}
+
+func (*stallingStatementServer) DoGetStatement(ctx context.Context, _ flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(`[{"id": 1}]`))
+	if err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan flight.StreamChunk)
+	go func() {
+		defer close(ch)
+		ch <- flight.StreamChunk{Data: rec}
+		<-ctx.Done()
+	}()
+	return schema, ch, nil
+}
+
+func TestClientProgressTimeoutReader(t *testing.T) {
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&stallingStatementServer{}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil,
+		[]flight.ClientMiddleware{flightsql.WithClientTimeouts(flightsql.ClientTimeouts{Progress: 100 * time.Millisecond})}, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte("stall"))
+	require.NoError(t, err)
+	rdr, err := cl.DoGet(context.Background(), &flight.Ticket{Ticket: tkt})
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.True(t, rdr.Next())
+	assert.False(t, rdr.Next())
+	var timeout *flightsql.StageTimeoutError
+	require.ErrorAs(t, rdr.Err(), &timeout)
+	assert.Equal(t, flightsql.TimeoutStageProgress, timeout.Stage)
+	assert.Equal(t, 100*time.Millisecond, timeout.Timeout)
+}
@@ -0,0 +1,245 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
+	"google.golang.org/grpc"
+)
+
+// ErrPreparedStatementClosed is returned when using a PreparedStatement
+// which was closed.
+var ErrPreparedStatementClosed = errors.New("arrow/flightsql: prepared statement already closed")
+
+// preparedStatementCloseTimeout bounds the time spent closing a prepared
+// statement which wasn't closed explicitly, when its context is done or
+// the client is closed, or which the update helpers of the client
+// created once their context is done.
+const preparedStatementCloseTimeout = 5 * time.Second
+
+// preparedStatementOption is a grpc.CallOption which configures the
+// PreparedStatement returned by Prepare. gRPC itself ignores it.
+type preparedStatementOption struct {
+	grpc.EmptyCallOption
+	ctx context.Context
+}
+
+// ClosePreparedStatementOnDone ties the lifetime of the statement created
+// by Prepare, PrepareSubstrait or the equivalent methods of Txn to ctx:
+// once ctx is done, the statement is closed on the server as if Close was
+// called, waiting for the calls using it to complete. This is done on a
+// best effort basis, giving up after a few seconds, and the parameter
+// bindings of the statement are only released by Close.
+func ClosePreparedStatementOnDone(ctx context.Context) grpc.CallOption {
+	return preparedStatementOption{ctx: ctx}
+}
+
+func newPreparedStatement(c *Client, handle []byte, datasetSchema, paramSchema *arrow.Schema, opts []grpc.CallOption) *PreparedStatement {
+	life := &preparedStatementLife{client: c, handle: handle}
+	c.statements.add(life)
+
+	for _, o := range opts {
+		if o, ok := o.(preparedStatementOption); ok {
+			life.mu.Lock()
+			life.stop = context.AfterFunc(o.ctx, life.closeInBackground)
+			life.mu.Unlock()
+		}
+	}
+
+	p := &PreparedStatement{
+		client:        c,
+		life:          life,
+		datasetSchema: datasetSchema,
+		paramSchema:   paramSchema,
+	}
+	trackPreparedStatement(p)
+	return p
+}
+
+// preparedStatementLife tracks whether a prepared statement was closed
+// and the calls in progress using it. It is kept apart from the
+// PreparedStatement so that the client can close the statements it
+// created without keeping them from being garbage collected.
+type preparedStatementLife struct {
+	client *Client
+
+	mu sync.Mutex
+	// handle is the current handle of the statement, which servers may
+	// replace when parameters are bound
+	handle   []byte
+	closed   bool
+	inflight sync.WaitGroup
+	// stop stops the context.AfterFunc closing the statement, if any
+	stop func() bool
+}
+
+// begin registers a call using the statement, to be followed by end,
+// unless the statement was closed.
+func (l *preparedStatementLife) begin() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrPreparedStatementClosed
+	}
+	l.inflight.Add(1)
+	return nil
+}
+
+func (l *preparedStatementLife) end() { l.inflight.Done() }
+
+func (l *preparedStatementLife) currentHandle() []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.handle
+}
+
+// setHandle replaces the handle of the statement with the updated handle
+// returned by the server.
+func (l *preparedStatementLife) setHandle(handle []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handle = handle
+}
+
+func (l *preparedStatementLife) isClosed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closed
+}
+
+// markClosed marks the statement as closed, so that no new calls can
+// begin, returning false if it already was.
+func (l *preparedStatementLife) markClosed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.closed = true
+	if l.stop != nil {
+		l.stop()
+	}
+	return true
+}
+
+// closeInBackground closes a statement which wasn't closed with Close.
+func (l *preparedStatementLife) closeInBackground() {
+	if !l.markClosed() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), preparedStatementCloseTimeout)
+	defer cancel()
+	_ = l.closeOnServer(ctx)
+}
+
+// closeOnServer waits for the calls in progress to complete and sends
+// the ClosePreparedStatement action. If ctx is done first, it gives up
+// and returns the error of ctx, leaving the statement open on the server.
+// It must only be called once markClosed returned true.
+func (l *preparedStatementLife) closeOnServer(ctx context.Context, opts ...grpc.CallOption) error {
+	l.client.statements.remove(l)
+
+	done := make(chan struct{})
+	go func() {
+		l.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	request := &pb.ActionClosePreparedStatementRequest{PreparedStatementHandle: l.currentHandle()}
+	action, err := packAction(ClosePreparedStatementActionType, request)
+	if err != nil {
+		return err
+	}
+
+	stream, err := l.client.Client.DoAction(ctx, &action, opts...)
+	if err != nil {
+		return err
+	}
+
+	if err = stream.CloseSend(); err != nil {
+		return err
+	}
+
+	return flight.ReadUntilEOF(stream)
+}
+
+// preparedStatements is the set of the open prepared statements of a
+// client. A nil set, as for a Client which wasn't created by NewClient,
+// doesn't track the statements.
+type preparedStatements struct {
+	mu   sync.Mutex
+	open map[*preparedStatementLife]struct{}
+}
+
+func newPreparedStatements() *preparedStatements {
+	return &preparedStatements{open: make(map[*preparedStatementLife]struct{})}
+}
+
+func (s *preparedStatements) add(l *preparedStatementLife) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open[l] = struct{}{}
+}
+
+func (s *preparedStatements) remove(l *preparedStatementLife) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.open, l)
+}
+
+// closeAll closes the open statements in the background, waiting for
+// them to be closed.
+func (s *preparedStatements) closeAll() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	open := make([]*preparedStatementLife, 0, len(s.open))
+	for l := range s.open {
+		open = append(open, l)
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, l := range open {
+		wg.Add(1)
+		go func(l *preparedStatementLife) {
+			defer wg.Done()
+			l.closeInBackground()
+		}(l)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,83 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FlightServerOption configures the FlightRPC server created by
+// NewFlightServer or NewFlightServerWithAllocator.
+type FlightServerOption func(*flightSqlServer)
+
+// WithMaxConcurrentStreams bounds the number of DoGet and DoPut calls
+// handled at the same time to n, so that a burst of calls can't exhaust
+// the memory or backend connections of the server. Calls beyond the limit
+// are rejected with codes.ResourceExhausted, or wait for a stream to
+// finish if WithStreamWaitTimeout is also given. A limit of 0 or less
+// means no limit, which is the default.
+func WithMaxConcurrentStreams(n int) FlightServerOption {
+	return func(f *flightSqlServer) {
+		if n <= 0 {
+			f.streams = nil
+			return
+		}
+		f.streams = make(chan struct{}, n)
+	}
+}
+
+// WithStreamWaitTimeout makes the DoGet and DoPut calls exceeding the
+// limit of WithMaxConcurrentStreams wait up to d for another stream to
+// finish, rather than being rejected straight away. Calls are still
+// rejected earlier if their context is done.
+func WithStreamWaitTimeout(d time.Duration) FlightServerOption {
+	return func(f *flightSqlServer) { f.streamWait = d }
+}
+
+// acquireStream reserves one of the concurrent streams of the server,
+// returning the function releasing it once the handler is done.
+func (f *flightSqlServer) acquireStream(ctx context.Context) (release func(), err error) {
+	if f.streams == nil {
+		return func() {}, nil
+	}
+
+	release = func() { <-f.streams }
+	select {
+	case f.streams <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if f.streamWait <= 0 {
+		return nil, status.Errorf(codes.ResourceExhausted, "too many concurrent streams (limit %d)", cap(f.streams))
+	}
+
+	timer := time.NewTimer(f.streamWait)
+	defer timer.Stop()
+	select {
+	case f.streams <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, status.Errorf(codes.ResourceExhausted, "too many concurrent streams (limit %d), none available after %s", cap(f.streams), f.streamWait)
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
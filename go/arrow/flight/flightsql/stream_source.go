@@ -0,0 +1,112 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+)
+
+// StreamingRecordSource produces the records of a result on demand, for
+// results too large to build up front such as the rows of a query.
+//
+// Next returns the next record, which the caller takes ownership of, or
+// io.EOF once the source is exhausted. Any other error ends the stream.
+// If the source also implements io.Closer, Close is called once the
+// stream ends, whether or not it was exhausted.
+type StreamingRecordSource interface {
+	Next() (arrow.Record, error)
+}
+
+// RecordSourceFunc adapts a function to a StreamingRecordSource.
+type RecordSourceFunc func() (arrow.Record, error)
+
+// Next calls f.
+func (f RecordSourceFunc) Next() (arrow.Record, error) { return f() }
+
+// StreamFromSource returns a channel of the records of src, for returning
+// from handlers such as DoGetStatement. Unlike building the records into
+// a buffered channel or a RecordReader, records are only pulled from src
+// as the previous ones are consumed, so at most one record produced by
+// src is waiting to be sent at any time.
+//
+// As with flight.StreamChunksFromReader the channel must be drained.
+// Errors, including a panic of src, are sent as the last chunk.
+func StreamFromSource(src StreamingRecordSource) <-chan flight.StreamChunk {
+	ch := make(chan flight.StreamChunk)
+	go func() {
+		defer close(ch)
+		if c, ok := src.(io.Closer); ok {
+			defer c.Close()
+		}
+		defer func() {
+			if err := recover(); err != nil {
+				ch <- flight.StreamChunk{Err: fmt.Errorf("panic while reading: %s", err)}
+			}
+		}()
+
+		for {
+			rec, err := src.Next()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					ch <- flight.StreamChunk{Err: err}
+				}
+				return
+			}
+			ch <- flight.StreamChunk{Data: rec}
+		}
+	}()
+	return ch
+}
+
+// SliceIntoChunks returns a channel of the record rec split into slices
+// of up to rows rows each, for returning from handlers such as
+// DoGetStatement. The slices share the buffers of rec rather than
+// copying them, and each one is released once it has been written.
+//
+// The record is retained until all the slices have been sent, so the
+// caller can release its own reference right away. If rows isn't
+// positive, or rec has no more than rows rows, rec is sent whole. As
+// with StreamFromSource the channel must be drained.
+func SliceIntoChunks(rec arrow.Record, rows int) <-chan flight.StreamChunk {
+	rec.Retain()
+	ch := make(chan flight.StreamChunk)
+	go func() {
+		defer close(ch)
+		defer rec.Release()
+
+		n := rec.NumRows()
+		if rows <= 0 || n <= int64(rows) {
+			rec.Retain()
+			ch <- flight.StreamChunk{Data: rec}
+			return
+		}
+
+		for i := int64(0); i < n; i += int64(rows) {
+			j := i + int64(rows)
+			if j > n {
+				j = n
+			}
+			ch <- flight.StreamChunk{Data: rec.NewSlice(i, j)}
+		}
+	}()
+	return ch
+}
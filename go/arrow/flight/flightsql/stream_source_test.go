@@ -0,0 +1,278 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sourceBatchRows = 32 * 1024
+	sourceBatches   = 256
+)
+
+var sourceSchema = arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+// countingSource produces sourceBatches batches of sequential ids, 64MiB
+// in total, recording the most memory held by its records at any time.
+type countingSource struct {
+	mem    *memory.CheckedAllocator
+	next   int64
+	peak   int
+	closed bool
+}
+
+func (s *countingSource) Next() (arrow.Record, error) {
+	if s.next == sourceBatches*sourceBatchRows {
+		return nil, io.EOF
+	}
+
+	bldr := array.NewInt64Builder(s.mem)
+	defer bldr.Release()
+	bldr.Reserve(sourceBatchRows)
+	for i := 0; i < sourceBatchRows; i++ {
+		bldr.UnsafeAppend(s.next)
+		s.next++
+	}
+	arr := bldr.NewArray()
+	defer arr.Release()
+
+	if n := s.mem.CurrentAlloc(); n > s.peak {
+		s.peak = n
+	}
+	return array.NewRecord(sourceSchema, []arrow.Array{arr}, sourceBatchRows), nil
+}
+
+func (s *countingSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+type sourceServer struct {
+	flightsql.BaseServer
+	src *countingSource
+}
+
+func (s *sourceServer) GetFlightInfoStatement(_ context.Context, _ flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte("source"))
+	if err != nil {
+		return nil, err
+	}
+	return &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: tkt}}},
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+func (s *sourceServer) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	return sourceSchema, flightsql.StreamFromSource(s.src), nil
+}
+
+func TestStreamFromSourceBoundedMemory(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	src := &countingSource{mem: mem}
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&sourceServer{src: src}))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx := context.Background()
+	info, err := cl.Execute(ctx, "SELECT id FROM huge")
+	require.NoError(t, err)
+	rdr, err := cl.DoGet(ctx, info.Endpoint[0].Ticket)
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	var rows, want int64
+	for rdr.Next() {
+		ids := rdr.Record().Column(0).(*array.Int64)
+		for _, id := range ids.Int64Values() {
+			if id != want {
+				t.Fatalf("got id %d, want %d", id, want)
+			}
+			want++
+		}
+		rows += rdr.Record().NumRows()
+	}
+	require.NoError(t, rdr.Err())
+	assert.EqualValues(t, sourceBatches*sourceBatchRows, rows)
+
+	// the record being written, the one waiting on the channel and the one
+	// being built, plus their validity bitmaps, whereas buffering the result
+	// would hold all of them
+	const batchSize = sourceBatchRows * 8
+	assert.Less(t, src.peak, 4*batchSize)
+	assert.Eventually(t, func() bool { return mem.CurrentAlloc() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestStreamFromSourceErrors(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	src := &countingSource{mem: mem}
+	errFailed := errors.New("backend failed")
+	calls := 0
+	ch := flightsql.StreamFromSource(flightsql.RecordSourceFunc(func() (arrow.Record, error) {
+		if calls++; calls > 2 {
+			return nil, errFailed
+		}
+		return src.Next()
+	}))
+
+	var chunks []flight.StreamChunk
+	for chunk := range ch {
+		chunks = append(chunks, chunk)
+	}
+	require.Len(t, chunks, 3)
+	for _, chunk := range chunks[:2] {
+		assert.NoError(t, chunk.Err)
+		chunk.Data.Release()
+	}
+	assert.ErrorIs(t, chunks[2].Err, errFailed)
+
+	ch = flightsql.StreamFromSource(flightsql.RecordSourceFunc(func() (arrow.Record, error) {
+		panic("boom")
+	}))
+	chunk := <-ch
+	assert.ErrorContains(t, chunk.Err, "panic while reading: boom")
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	src = &countingSource{mem: mem, next: sourceBatches * sourceBatchRows}
+	chunks = chunks[:0]
+	for chunk := range flightsql.StreamFromSource(src) {
+		chunks = append(chunks, chunk)
+	}
+	assert.Empty(t, chunks)
+	assert.True(t, src.closed)
+}
+
+func TestSliceIntoChunks(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	rec, _, err := array.RecordFromJSON(mem, schema, strings.NewReader(`[
+		{"id": 0, "name": "a"}, {"id": 1, "name": null}, {"id": 2, "name": "ccc"},
+		{"id": 3, "name": "d"}, {"id": 4, "name": "ee"}, {"id": 5, "name": null},
+		{"id": 6, "name": "g"}
+	]`))
+	require.NoError(t, err)
+
+	defer rec.Release()
+
+	ch := flightsql.SliceIntoChunks(rec, 3)
+	allocated := mem.CurrentAlloc()
+
+	var slices []arrow.Record
+	for chunk := range ch {
+		require.NoError(t, chunk.Err)
+		slices = append(slices, chunk.Data)
+	}
+	require.Len(t, slices, 3)
+	assert.EqualValues(t, []int64{3, 3, 1}, []int64{slices[0].NumRows(), slices[1].NumRows(), slices[2].NumRows()})
+
+	// the slices share the buffers of the record
+	assert.Equal(t, allocated, mem.CurrentAlloc())
+	for _, slice := range slices {
+		for c, col := range slice.Columns() {
+			assert.Same(t, rec.Column(c).Data().Buffers()[1], col.Data().Buffers()[1])
+		}
+	}
+
+	joined, err := array.Concatenate(slicesColumn(slices, 1), mem)
+	require.NoError(t, err)
+	defer joined.Release()
+	assert.True(t, array.Equal(rec.Column(1), joined))
+
+	for _, slice := range slices {
+		slice.Release()
+	}
+
+	// a record no larger than the slices is sent whole
+	chunks := flightsql.SliceIntoChunks(rec, 7)
+	chunk := <-chunks
+	assert.Same(t, rec, chunk.Data)
+	chunk.Data.Release()
+	_, ok := <-chunks
+	assert.False(t, ok)
+
+	// the slices are written with the rows they cover only
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(flightsql.NewFlightServer(&sliceServer{rec: rec}))
+	require.NoError(t, srv.Init("localhost:0"))
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	cl, err := flightsql.NewClient(srv.Addr().String(), nil, nil, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	rdr, err := cl.ExecuteQuery(context.Background(), "SELECT * FROM t")
+	require.NoError(t, err)
+	defer rdr.Release()
+	var ids []int64
+	for rdr.Next() {
+		assert.LessOrEqual(t, rdr.Record().NumRows(), int64(2))
+		ids = append(ids, rdr.Record().Column(0).(*array.Int64).Int64Values()...)
+	}
+	require.NoError(t, rdr.Err())
+	assert.Equal(t, rec.Column(0).(*array.Int64).Int64Values(), ids)
+}
+
+// sliceServer serves rec in slices of two rows.
+type sliceServer struct {
+	sourceServer
+	rec arrow.Record
+}
+
+func (s *sliceServer) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	return s.rec.Schema(), flightsql.SliceIntoChunks(s.rec, 2), nil
+}
+
+func slicesColumn(recs []arrow.Record, col int) []arrow.Array {
+	arrs := make([]arrow.Array, len(recs))
+	for i, rec := range recs {
+		arrs[i] = rec.Column(col)
+	}
+	return arrs
+}
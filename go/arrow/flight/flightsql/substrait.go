@@ -0,0 +1,220 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// SubstraitVersion is the version of Substrait a plan was produced for.
+type SubstraitVersion struct {
+	Major, Minor, Patch uint32
+	GitHash             string
+	// Producer is the name of the system which produced the plan, if set.
+	Producer string
+}
+
+// String returns the version as "major.minor.patch", as used by
+// SubstraitPlan.Version.
+func (v SubstraitVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// SubstraitPlanInfo describes the envelope of a serialized Substrait plan.
+type SubstraitPlanInfo struct {
+	// Version is the Substrait version of the plan, or nil if the plan
+	// doesn't specify it.
+	Version *SubstraitVersion
+	// NumRelations is the number of relation trees in the plan.
+	NumRelations int
+	// ExtensionURIs are the URIs of the extensions used by the plan.
+	ExtensionURIs []string
+	// ExpectedTypeURLs are the protobuf types of the advanced extensions
+	// the plan uses.
+	ExpectedTypeURLs []string
+}
+
+// The fields of the Substrait messages decoded by ParseSubstraitPlan. The
+// Substrait protobuf definitions aren't a dependency of this package, so
+// the envelope is decoded by hand; see substrait/plan.proto.
+const (
+	substraitPlanExtensionURIsField    protowire.Number = 1
+	substraitPlanRelationsField        protowire.Number = 3
+	substraitPlanExpectedTypeURLsField protowire.Number = 5
+	substraitPlanVersionField          protowire.Number = 6
+
+	substraitExtensionURIField protowire.Number = 2
+
+	substraitVersionMajorField    protowire.Number = 1
+	substraitVersionMinorField    protowire.Number = 2
+	substraitVersionPatchField    protowire.Number = 3
+	substraitVersionGitHashField  protowire.Number = 4
+	substraitVersionProducerField protowire.Number = 5
+)
+
+// ParseSubstraitPlan decodes the envelope of the serialized Substrait
+// plan, such as SubstraitPlan.Plan, so that handlers can reject malformed
+// plans before executing them. It checks that the plan is a well-formed
+// Plan message with at least one relation, but doesn't validate the
+// relations themselves, which is left to the backend.
+//
+// The error returned for an invalid plan is a gRPC status with the code
+// InvalidArgument, so handlers can return it as is.
+func ParseSubstraitPlan(plan []byte) (*SubstraitPlanInfo, error) {
+	if len(plan) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid substrait plan: plan is empty")
+	}
+
+	var info SubstraitPlanInfo
+	err := rangeFields(plan, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch num {
+		case substraitPlanRelationsField:
+			if typ != protowire.BytesType {
+				return errWireType("relations")
+			}
+			info.NumRelations++
+		case substraitPlanExtensionURIsField:
+			if typ != protowire.BytesType {
+				return errWireType("extension_uris")
+			}
+			uri, err := parseSubstraitExtensionURI(v)
+			if err != nil {
+				return err
+			}
+			info.ExtensionURIs = append(info.ExtensionURIs, uri)
+		case substraitPlanExpectedTypeURLsField:
+			if typ != protowire.BytesType {
+				return errWireType("expected_type_urls")
+			}
+			info.ExpectedTypeURLs = append(info.ExpectedTypeURLs, string(v))
+		case substraitPlanVersionField:
+			if typ != protowire.BytesType {
+				return errWireType("version")
+			}
+			version, err := parseSubstraitVersion(v)
+			if err != nil {
+				return err
+			}
+			info.Version = version
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid substrait plan: %s", err)
+	}
+
+	if info.NumRelations == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid substrait plan: plan has no relations")
+	}
+	return &info, nil
+}
+
+func parseSubstraitExtensionURI(b []byte) (uri string, err error) {
+	err = rangeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num == substraitExtensionURIField {
+			if typ != protowire.BytesType {
+				return errWireType("extension_uris.uri")
+			}
+			uri = string(v)
+		}
+		return nil
+	})
+	return
+}
+
+func parseSubstraitVersion(b []byte) (*SubstraitVersion, error) {
+	var version SubstraitVersion
+	err := rangeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		var (
+			field string
+			dst   *uint32
+		)
+		switch num {
+		case substraitVersionMajorField:
+			field, dst = "version.major_number", &version.Major
+		case substraitVersionMinorField:
+			field, dst = "version.minor_number", &version.Minor
+		case substraitVersionPatchField:
+			field, dst = "version.patch_number", &version.Patch
+		case substraitVersionGitHashField:
+			if typ != protowire.BytesType {
+				return errWireType("version.git_hash")
+			}
+			version.GitHash = string(v)
+			return nil
+		case substraitVersionProducerField:
+			if typ != protowire.BytesType {
+				return errWireType("version.producer")
+			}
+			version.Producer = string(v)
+			return nil
+		default:
+			return nil
+		}
+
+		if typ != protowire.VarintType {
+			return errWireType(field)
+		}
+		n, _ := protowire.ConsumeVarint(v)
+		*dst = uint32(n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func errWireType(field string) error {
+	return fmt.Errorf("unexpected wire type for field %s", field)
+}
+
+// rangeFields calls fn with each field of the serialized protobuf message
+// b. For fields of type BytesType v is the content of the field, for
+// others it is its raw encoding.
+func rangeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var v []byte
+		if typ == protowire.BytesType {
+			v, n = protowire.ConsumeBytes(b)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n >= 0 {
+				v = b[:n]
+			}
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// serializedSubstraitPlan returns a serialized Substrait plan with an extension
+// URI, a relation and a version.
+func serializedSubstraitPlan() []byte {
+	var version []byte
+	version = appendVarint(version, 2, 42)
+	version = appendVarint(version, 3, 1)
+	version = appendMessage(version, 5, []byte("arrow-go"))
+
+	// PlanRel{root: RelRoot{input: Rel{}, names: ["a"]}}
+	root := appendMessage(nil, 1, nil)
+	root = appendMessage(root, 2, []byte("a"))
+	rel := appendMessage(nil, 2, root)
+
+	// SimpleExtensionURI{extension_uri_anchor: 1, uri: ...}
+	uri := appendVarint(nil, 1, 1)
+	uri = appendMessage(uri, 2, []byte("https://example.com/functions.yaml"))
+
+	var plan []byte
+	plan = appendMessage(plan, 1, uri)
+	plan = appendMessage(plan, 3, rel)
+	plan = appendMessage(plan, 5, []byte("type.googleapis.com/example.Hint"))
+	plan = appendMessage(plan, 6, version)
+	// an unknown field, as added by newer versions of Substrait
+	plan = appendVarint(plan, 100, 7)
+	return plan
+}
+
+func TestParseSubstraitPlan(t *testing.T) {
+	info, err := flightsql.ParseSubstraitPlan(serializedSubstraitPlan())
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.NumRelations)
+	assert.Equal(t, []string{"https://example.com/functions.yaml"}, info.ExtensionURIs)
+	assert.Equal(t, []string{"type.googleapis.com/example.Hint"}, info.ExpectedTypeURLs)
+	require.NotNil(t, info.Version)
+	assert.Equal(t, "0.42.1", info.Version.String())
+	assert.Equal(t, "arrow-go", info.Version.Producer)
+
+	// the version is optional
+	info, err = flightsql.ParseSubstraitPlan(appendMessage(nil, 3, nil))
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.NumRelations)
+	assert.Nil(t, info.Version)
+}
+
+func TestParseSubstraitPlanInvalid(t *testing.T) {
+	valid := serializedSubstraitPlan()
+
+	tests := []struct {
+		name string
+		plan []byte
+	}{
+		{"empty", nil},
+		{"garbage", []byte("SELECT * FROM t")},
+		{"truncated", valid[:len(valid)-5]},
+		{"no relations", appendMessage(nil, 6, appendVarint(nil, 2, 42))},
+		{"relation wire type", appendVarint(nil, 3, 1)},
+		{"version wire type", appendMessage(appendMessage(nil, 3, nil), 6, appendMessage(nil, 1, []byte("x")))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := flightsql.ParseSubstraitPlan(tt.plan)
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), err.Error())
+		})
+	}
+}
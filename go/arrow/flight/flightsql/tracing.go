@@ -0,0 +1,59 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/otelflight"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewServerTracingMiddleware returns the middleware starting an
+// OpenTelemetry span around each call handled by the server, which is the
+// child of the span of the client if the call has a W3C traceparent
+// header, as sent by NewClientTracingMiddleware. If tp is nil the global
+// TracerProvider is used.
+//
+// Spans are named after the RPC and the Flight SQL command it carries,
+// such as "GetFlightInfo CommandStatementQuery" or "DoAction
+// CreatePreparedStatement". The span is in the context passed to the
+// handlers, so they can add attributes or start child spans of their own
+// with trace.SpanFromContext. See the otelflight package for the
+// attributes of the spans.
+func NewServerTracingMiddleware(tp trace.TracerProvider) flight.ServerMiddleware {
+	return otelflight.NewServerMiddleware(otelflight.WithTracerProvider(tp))
+}
+
+// NewClientTracingMiddleware returns the middleware sending the span in
+// the context of each call as a W3C traceparent header, for the server to
+// link its spans to. Use WithClientTracing to also start client spans.
+func NewClientTracingMiddleware() flight.ClientMiddleware {
+	return otelflight.NewPropagatingClientMiddleware()
+}
+
+// WithClientTracing returns middleware for NewClient which starts an
+// OpenTelemetry span around each call, see otelflight.NewClientMiddleware.
+func WithClientTracing(opts ...otelflight.Option) flight.ClientMiddleware {
+	return otelflight.NewClientMiddleware(opts...)
+}
+
+// WithServerTracing starts an OpenTelemetry span around each call handled
+// by the server, as NewServerTracingMiddleware does for a flight.Server,
+// see otelflight.NewServerMiddleware. It should not be used with both.
+func WithServerTracing(opts ...otelflight.Option) FlightServerOption {
+	return withServerMiddleware(otelflight.NewServerMiddleware(opts...))
+}
@@ -0,0 +1,239 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v16/arrow/flight/otelflight"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tracedServer records the span in the context of the first call of each
+// of its handlers.
+type tracedServer struct {
+	flightsql.BaseServer
+
+	mx    sync.Mutex
+	spans map[string]trace.SpanContext
+}
+
+func (s *tracedServer) record(ctx context.Context, handler string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if _, ok := s.spans[handler]; !ok {
+		s.spans[handler] = trace.SpanContextFromContext(ctx)
+	}
+}
+
+func (s *tracedServer) GetFlightInfoStatement(ctx context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	s.record(ctx, "GetFlightInfoStatement")
+	if cmd.GetQuery() == "bad" {
+		return nil, status.Error(codes.InvalidArgument, "bad query")
+	}
+
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte(cmd.GetQuery()))
+	if err != nil {
+		return nil, err
+	}
+	return &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: tkt}}},
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+func (s *tracedServer) DoGetStatement(ctx context.Context, _ flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	s.record(ctx, "DoGetStatement")
+	schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(`[{"id": 1}]`))
+	if err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: rec}
+	close(ch)
+	return schema, ch, nil
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	srv := &tracedServer{spans: make(map[string]trace.SpanContext)}
+	s := flight.NewServerWithMiddleware([]flight.ServerMiddleware{flightsql.NewServerTracingMiddleware(tp)})
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil,
+		[]flight.ClientMiddleware{flightsql.NewClientTracingMiddleware()}, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx, parent := tp.Tracer("client").Start(context.Background(), "query")
+	info, err := cl.Execute(ctx, "SELECT 1")
+	require.NoError(t, err)
+	rdr, err := cl.DoGet(ctx, info.Endpoint[0].Ticket)
+	require.NoError(t, err)
+	for rdr.Next() {
+	}
+	require.NoError(t, rdr.Err())
+	rdr.Release()
+
+	_, err = cl.Execute(ctx, "bad")
+	require.Error(t, err)
+	parent.End()
+
+	// calls without a span in their context start a new trace
+	_, err = cl.Execute(context.Background(), "untraced")
+	require.NoError(t, err)
+
+	byName := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range spans.Ended() {
+		byName[span.Name()] = append(byName[span.Name()], span)
+	}
+	require.Len(t, byName["GetFlightInfo CommandStatementQuery"], 3)
+	require.Len(t, byName["DoGet TicketStatementQuery"], 1)
+
+	getInfo := byName["GetFlightInfo CommandStatementQuery"][0]
+	doGet := byName["DoGet TicketStatementQuery"][0]
+	for _, span := range []sdktrace.ReadOnlySpan{getInfo, doGet} {
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.True(t, span.Parent().IsRemote())
+	}
+
+	srv.mx.Lock()
+	assert.Equal(t, getInfo.SpanContext().SpanID(), srv.spans["GetFlightInfoStatement"].SpanID())
+	assert.Equal(t, doGet.SpanContext().SpanID(), srv.spans["DoGetStatement"].SpanID())
+	srv.mx.Unlock()
+
+	failed := byName["GetFlightInfo CommandStatementQuery"][1]
+	assert.Equal(t, parent.SpanContext().SpanID(), failed.Parent().SpanID())
+	assert.Equal(t, otelcodes.Error, failed.Status().Code)
+
+	untraced := byName["GetFlightInfo CommandStatementQuery"][2]
+	assert.False(t, untraced.Parent().IsValid())
+	assert.NotEqual(t, parent.SpanContext().TraceID(), untraced.SpanContext().TraceID())
+}
+
+func TestTracingExecuteDoGet(t *testing.T) {
+	spans := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+
+	srv := &tracedServer{spans: make(map[string]trace.SpanContext)}
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(srv,
+		flightsql.WithServerTracing(otelflight.WithTracerProvider(tp))))
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	cl, err := flightsql.NewClient(s.Addr().String(), nil,
+		[]flight.ClientMiddleware{flightsql.WithClientTracing(otelflight.WithTracerProvider(tp))}, dialOpts...)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ended := func(name string, kind trace.SpanKind) (tracetest.SpanStub, bool) {
+		for _, span := range spans.GetSpans() {
+			if span.Name == name && span.SpanKind == kind {
+				return span, true
+			}
+		}
+		return tracetest.SpanStub{}, false
+	}
+	attrs := func(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	ctx, parent := tp.Tracer("client").Start(context.Background(), "query")
+	info, err := cl.Execute(ctx, "SELECT 1")
+	require.NoError(t, err)
+	rdr, err := cl.DoGet(ctx, info.Endpoint[0].Ticket)
+	require.NoError(t, err)
+
+	// the span of the stream ends with it rather than with the call
+	_, ok := ended("DoGet TicketStatementQuery", trace.SpanKindClient)
+	assert.False(t, ok)
+	for rdr.Next() {
+	}
+	require.NoError(t, rdr.Err())
+	rdr.Release()
+	parent.End()
+
+	for _, name := range []string{"GetFlightInfo CommandStatementQuery", "DoGet TicketStatementQuery"} {
+		client, ok := ended(name, trace.SpanKindClient)
+		require.True(t, ok, name)
+		server, ok := ended(name, trace.SpanKindServer)
+		require.True(t, ok, name)
+
+		assert.Equal(t, parent.SpanContext().SpanID(), client.Parent.SpanID())
+		assert.Equal(t, client.SpanContext.TraceID(), server.SpanContext.TraceID())
+		assert.Equal(t, client.SpanContext.SpanID(), server.Parent.SpanID())
+		assert.True(t, server.Parent.IsRemote())
+		assert.False(t, server.EndTime.After(client.EndTime))
+
+		for _, span := range []tracetest.SpanStub{client, server} {
+			a := attrs(span)
+			assert.EqualValues(t, codes.OK, a["rpc.grpc.status_code"].AsInt64())
+			assert.Equal(t, otelcodes.Unset, span.Status.Code)
+			assert.NotContains(t, a, attribute.Key("db.statement"))
+		}
+	}
+
+	getInfo, _ := ended("GetFlightInfo CommandStatementQuery", trace.SpanKindClient)
+	assert.Equal(t, "CommandStatementQuery", attrs(getInfo)["flightsql.command"].AsString())
+	assert.EqualValues(t, len("SELECT 1"), attrs(getInfo)["flightsql.query.length"].AsInt64())
+
+	client, _ := ended("DoGet TicketStatementQuery", trace.SpanKindClient)
+	server, _ := ended("DoGet TicketStatementQuery", trace.SpanKindServer)
+	assert.NotEmpty(t, attrs(client)["flightsql.handle.hash"].AsString())
+	assert.Equal(t, attrs(client)["flightsql.handle.hash"], attrs(server)["flightsql.handle.hash"])
+	assert.EqualValues(t, 1, attrs(client)["flight.records.received"].AsInt64())
+	assert.EqualValues(t, 1, attrs(server)["flight.records.sent"].AsInt64())
+	assert.Equal(t, attrs(client)["flight.bytes.received"], attrs(server)["flight.bytes.sent"])
+	assert.Positive(t, attrs(server)["flight.bytes.sent"].AsInt64())
+
+	// the handlers see the span of the server
+	srv.mx.Lock()
+	assert.Equal(t, server.SpanContext.SpanID(), srv.spans["DoGetStatement"].SpanID())
+	srv.mx.Unlock()
+}
@@ -17,6 +17,7 @@
 package flightsql
 
 import (
+	"github.com/apache/arrow/go/v16/arrow/flight"
 	pb "github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
@@ -32,8 +33,26 @@ const (
 	BeginTransactionActionType            = "BeginTransaction"
 	EndTransactionActionType              = "EndTransaction"
 	EndSavepointActionType                = "EndSavepoint"
+	// HealthCheckActionType is not part of the Flight SQL protocol. It
+	// allows load balancers and readiness probes to check that the server
+	// is alive without running a query, see HealthCheckResult.
+	HealthCheckActionType = "HealthCheck"
+	// ExplainPreparedStatementActionType is not part of the Flight SQL
+	// protocol either. It returns the query plan of a prepared statement,
+	// see PreparedStatement.Explain.
+	ExplainPreparedStatementActionType = "ExplainPreparedStatement"
 )
 
+// UpdateResultUnknown is the row count reported for an update when the
+// number of affected rows is not known, for instance after executing DDL
+// or with a backend which doesn't report row counts. Use AffectedRows to
+// tell it apart from a real count on the client side.
+//
+// Row counts are never negative otherwise, so servers send any negative
+// count returned by a handler as UpdateResultUnknown, and clients read any
+// negative count as UpdateResultUnknown.
+const UpdateResultUnknown int64 = -1
+
 func toCrossTableRef(cmd *pb.CommandGetCrossReference) CrossTableRef {
 	return CrossTableRef{
 		PKRef: TableRef{
@@ -84,6 +103,18 @@ func CreateStatementQueryTicket(handle []byte) ([]byte, error) {
 	return proto.Marshal(&ticket)
 }
 
+// TicketStatementQuery returns the ticket of a TicketStatementQuery
+// containing handle, as CreateStatementQueryTicket, to be passed back to
+// DoGetStatement as StatementQueryTicket.GetStatementHandle. See
+// NewStatementFlightInfo for handles carrying the query itself.
+func TicketStatementQuery(handle []byte) (*flight.Ticket, error) {
+	tkt, err := CreateStatementQueryTicket(handle)
+	if err != nil {
+		return nil, err
+	}
+	return &flight.Ticket{Ticket: tkt}, nil
+}
+
 type (
 	// GetDBSchemasOpts contains the options to request Database Schemas:
 	// an optional Catalog and a Schema Name filter pattern.
@@ -854,3 +885,93 @@ const (
 )
 
 type CreatePreparedStatementResult = pb.ActionCreatePreparedStatementResult
+
+// XdbcDataType is the JDBC/ODBC-defined type of any object, as used
+// in the data_type and sql_data_type columns of GetXdbcTypeInfo.
+//
+// duplicated from protobuf to avoid relying directly on the protobuf
+// generated code, also making them shorter and easier to use
+type XdbcDataType = pb.XdbcDataType
+
+const (
+	XdbcUnknownType   = pb.XdbcDataType_XDBC_UNKNOWN_TYPE
+	XdbcChar          = pb.XdbcDataType_XDBC_CHAR
+	XdbcNumeric       = pb.XdbcDataType_XDBC_NUMERIC
+	XdbcDecimal       = pb.XdbcDataType_XDBC_DECIMAL
+	XdbcInteger       = pb.XdbcDataType_XDBC_INTEGER
+	XdbcSmallInt      = pb.XdbcDataType_XDBC_SMALLINT
+	XdbcFloat         = pb.XdbcDataType_XDBC_FLOAT
+	XdbcReal          = pb.XdbcDataType_XDBC_REAL
+	XdbcDouble        = pb.XdbcDataType_XDBC_DOUBLE
+	XdbcDatetime      = pb.XdbcDataType_XDBC_DATETIME
+	XdbcInterval      = pb.XdbcDataType_XDBC_INTERVAL
+	XdbcVarchar       = pb.XdbcDataType_XDBC_VARCHAR
+	XdbcDate          = pb.XdbcDataType_XDBC_DATE
+	XdbcTime          = pb.XdbcDataType_XDBC_TIME
+	XdbcTimestamp     = pb.XdbcDataType_XDBC_TIMESTAMP
+	XdbcLongVarchar   = pb.XdbcDataType_XDBC_LONGVARCHAR
+	XdbcBinary        = pb.XdbcDataType_XDBC_BINARY
+	XdbcVarbinary     = pb.XdbcDataType_XDBC_VARBINARY
+	XdbcLongVarbinary = pb.XdbcDataType_XDBC_LONGVARBINARY
+	XdbcBigInt        = pb.XdbcDataType_XDBC_BIGINT
+	XdbcTinyInt       = pb.XdbcDataType_XDBC_TINYINT
+	XdbcBit           = pb.XdbcDataType_XDBC_BIT
+	XdbcWChar         = pb.XdbcDataType_XDBC_WCHAR
+	XdbcWVarchar      = pb.XdbcDataType_XDBC_WVARCHAR
+)
+
+// XdbcDatetimeSubcode is the subtype of the datetime and interval data
+// types, as used in the datetime_subcode column of GetXdbcTypeInfo.
+type XdbcDatetimeSubcode = pb.XdbcDatetimeSubcode
+
+const (
+	XdbcSubcodeUnknown                = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_UNKNOWN
+	XdbcSubcodeDate                   = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_DATE
+	XdbcSubcodeTime                   = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_TIME
+	XdbcSubcodeTimestamp              = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_TIMESTAMP
+	XdbcSubcodeTimeWithTimezone       = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_TIME_WITH_TIMEZONE
+	XdbcSubcodeTimestampWithTimezone  = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_TIMESTAMP_WITH_TIMEZONE
+	XdbcSubcodeIntervalYear           = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_YEAR
+	XdbcSubcodeIntervalMonth          = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_MONTH
+	XdbcSubcodeIntervalDay            = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_DAY
+	XdbcSubcodeIntervalHour           = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_HOUR
+	XdbcSubcodeIntervalMinute         = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_MINUTE
+	XdbcSubcodeIntervalSecond         = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_SECOND
+	XdbcSubcodeIntervalYearToMonth    = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_YEAR_TO_MONTH
+	XdbcSubcodeIntervalDayToHour      = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_DAY_TO_HOUR
+	XdbcSubcodeIntervalDayToMinute    = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_DAY_TO_MINUTE
+	XdbcSubcodeIntervalDayToSecond    = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_DAY_TO_SECOND
+	XdbcSubcodeIntervalHourToMinute   = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_HOUR_TO_MINUTE
+	XdbcSubcodeIntervalHourToSecond   = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_HOUR_TO_SECOND
+	XdbcSubcodeIntervalMinuteToSecond = pb.XdbcDatetimeSubcode_XDBC_SUBCODE_INTERVAL_MINUTE_TO_SECOND
+)
+
+// XdbcNullable indicates whether a data type or column accepts null
+// values.
+type XdbcNullable = pb.Nullable
+
+const (
+	// Indicates that the fields do not allow the use of null values
+	NullabilityNoNulls = pb.Nullable_NULLABILITY_NO_NULLS
+	// Indicates that the fields allow the use of null values
+	NullabilityNullable = pb.Nullable_NULLABILITY_NULLABLE
+	// Indicates that the nullability of the fields cannot be determined
+	NullabilityUnknown = pb.Nullable_NULLABILITY_UNKNOWN
+)
+
+// XdbcSearchable indicates how a data type can be used in a WHERE clause.
+type XdbcSearchable = pb.Searchable
+
+const (
+	// Indicates that the column cannot be used in a WHERE clause
+	SearchableNone = pb.Searchable_SEARCHABLE_NONE
+	// Indicates that the column can be used in a WHERE clause only
+	// with the LIKE predicate
+	SearchableChar = pb.Searchable_SEARCHABLE_CHAR
+	// Indicates that the column can be used in a WHERE clause with
+	// all comparison operators except LIKE
+	SearchableBasic = pb.Searchable_SEARCHABLE_BASIC
+	// Indicates that the column can be used in a WHERE clause with
+	// any comparison operator
+	SearchableFull = pb.Searchable_SEARCHABLE_FULL
+)
@@ -0,0 +1,486 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/compute"
+	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/schema_ref"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"google.golang.org/grpc"
+)
+
+// XdbcTypeInfoRow describes a single data type supported by a server,
+// corresponding to one row of the GetXdbcTypeInfo result. Columns which
+// are nullable in the result schema are represented by pointers (or a
+// nil slice for CreateParams).
+type XdbcTypeInfoRow struct {
+	TypeName          string
+	DataType          XdbcDataType
+	ColumnSize        *int32
+	LiteralPrefix     *string
+	LiteralSuffix     *string
+	CreateParams      []string
+	Nullable          XdbcNullable
+	CaseSensitive     bool
+	Searchable        XdbcSearchable
+	UnsignedAttribute *bool
+	FixedPrecScale    bool
+	AutoIncrement     *bool
+	LocalTypeName     *string
+	MinimumScale      *int32
+	MaximumScale      *int32
+	SqlDataType       XdbcDataType
+	DatetimeSubcode   *int32
+	NumPrecRadix      *int32
+	IntervalPrecision *int32
+}
+
+// NewXdbcTypeInfoRow returns the description of the Arrow data type dt
+// as an XDBC data type named typeName, to be adjusted as needed and
+// registered with BaseServer.RegisterXdbcTypeInfo or appended to an
+// XdbcTypeInfoResultBuilder. Columns which don't follow from the Arrow
+// type, such as the column size of strings, are left unset.
+//
+// The interval types are described as XdbcInterval: MonthInterval with
+// the year to month subcode, DayTimeInterval and Duration with the day to
+// second subcode and MonthDayNanoInterval, which spans both, with no
+// subcode.
+//
+// Dictionary and run-end encoded types are described by their value
+// type. An error wrapping arrow.ErrNotImplemented is returned for the
+// types which have no XDBC equivalent, such as nested types.
+func NewXdbcTypeInfoRow(typeName string, dt arrow.DataType) (XdbcTypeInfoRow, error) {
+	var (
+		yes, no = true, false
+		quote   = "'"
+	)
+
+	row := XdbcTypeInfoRow{
+		TypeName:   typeName,
+		Nullable:   NullabilityNullable,
+		Searchable: SearchableBasic,
+	}
+	setType := func(typ XdbcDataType) {
+		row.DataType, row.SqlDataType = typ, typ
+	}
+	setDatetime := func(typ, sqlType XdbcDataType, subcode XdbcDatetimeSubcode) {
+		row.DataType, row.SqlDataType = typ, sqlType
+		if subcode != XdbcSubcodeUnknown {
+			row.DatetimeSubcode = int32Ptr(int32(subcode))
+		}
+		row.LiteralPrefix, row.LiteralSuffix = &quote, &quote
+	}
+	setNumeric := func(typ XdbcDataType, unsigned bool, radix int32) {
+		setType(typ)
+		row.UnsignedAttribute = &no
+		if unsigned {
+			row.UnsignedAttribute = &yes
+		}
+		row.NumPrecRadix = int32Ptr(radix)
+	}
+
+	switch dt := dt.(type) {
+	case *arrow.BooleanType:
+		setType(XdbcBit)
+	case *arrow.Int8Type, *arrow.Uint8Type:
+		setNumeric(XdbcTinyInt, dt.ID() == arrow.UINT8, 10)
+	case *arrow.Int16Type, *arrow.Uint16Type:
+		setNumeric(XdbcSmallInt, dt.ID() == arrow.UINT16, 10)
+	case *arrow.Int32Type, *arrow.Uint32Type:
+		setNumeric(XdbcInteger, dt.ID() == arrow.UINT32, 10)
+	case *arrow.Int64Type, *arrow.Uint64Type:
+		setNumeric(XdbcBigInt, dt.ID() == arrow.UINT64, 10)
+	case *arrow.Float16Type, *arrow.Float32Type:
+		setNumeric(XdbcReal, false, 2)
+	case *arrow.Float64Type:
+		setNumeric(XdbcDouble, false, 2)
+	case arrow.DecimalType:
+		setNumeric(XdbcDecimal, false, 10)
+		row.ColumnSize = int32Ptr(dt.GetPrecision())
+		row.MinimumScale, row.MaximumScale = int32Ptr(0), int32Ptr(dt.GetPrecision())
+	case *arrow.StringType, *arrow.LargeStringType, *arrow.StringViewType:
+		setType(XdbcVarchar)
+		row.CaseSensitive = true
+		row.Searchable = SearchableFull
+		row.LiteralPrefix, row.LiteralSuffix = &quote, &quote
+	case *arrow.BinaryType, *arrow.LargeBinaryType, *arrow.BinaryViewType:
+		setType(XdbcVarbinary)
+	case *arrow.FixedSizeBinaryType:
+		setType(XdbcBinary)
+		row.ColumnSize = int32Ptr(int32(dt.ByteWidth))
+	case *arrow.Date32Type, *arrow.Date64Type:
+		setDatetime(XdbcDate, XdbcDatetime, XdbcSubcodeDate)
+	case *arrow.Time32Type, *arrow.Time64Type:
+		setDatetime(XdbcTime, XdbcDatetime, XdbcSubcodeTime)
+	case *arrow.TimestampType:
+		subcode := XdbcSubcodeTimestamp
+		if dt.TimeZone != "" {
+			subcode = XdbcSubcodeTimestampWithTimezone
+		}
+		setDatetime(XdbcTimestamp, XdbcDatetime, subcode)
+	case *arrow.MonthIntervalType:
+		setDatetime(XdbcInterval, XdbcInterval, XdbcSubcodeIntervalYearToMonth)
+	case *arrow.DayTimeIntervalType:
+		setDatetime(XdbcInterval, XdbcInterval, XdbcSubcodeIntervalDayToSecond)
+	case *arrow.MonthDayNanoIntervalType:
+		setDatetime(XdbcInterval, XdbcInterval, XdbcSubcodeUnknown)
+	case *arrow.DurationType:
+		setDatetime(XdbcInterval, XdbcInterval, XdbcSubcodeIntervalDayToSecond)
+	case *arrow.DictionaryType:
+		return NewXdbcTypeInfoRow(typeName, dt.ValueType)
+	case *arrow.RunEndEncodedType:
+		return NewXdbcTypeInfoRow(typeName, dt.Encoded())
+	default:
+		return XdbcTypeInfoRow{}, fmt.Errorf("%w: arrow/flightsql: no XDBC data type for %s", arrow.ErrNotImplemented, dt)
+	}
+	return row, nil
+}
+
+func int32Ptr(v int32) *int32 { return &v }
+
+// xdbcTypeNames are the SQL names of the XDBC data types of the rows
+// returned by NewXdbcTypeInfoRow.
+var xdbcTypeNames = map[XdbcDataType]string{
+	XdbcBit:       "BOOLEAN",
+	XdbcTinyInt:   "TINYINT",
+	XdbcSmallInt:  "SMALLINT",
+	XdbcInteger:   "INTEGER",
+	XdbcBigInt:    "BIGINT",
+	XdbcReal:      "REAL",
+	XdbcDouble:    "DOUBLE",
+	XdbcDecimal:   "DECIMAL",
+	XdbcVarchar:   "VARCHAR",
+	XdbcVarbinary: "VARBINARY",
+	XdbcBinary:    "BINARY",
+	XdbcDate:      "DATE",
+	XdbcTime:      "TIME",
+	XdbcTimestamp: "TIMESTAMP",
+	XdbcInterval:  "INTERVAL",
+}
+
+// ArrowToXdbcDataType returns the XDBC data type code and the SQL name of
+// the type used to describe columns of the Arrow type dt, such as in the
+// data_type column of GetXdbcTypeInfo or the metadata of GetTables
+// schemas. The code is the DataType of NewXdbcTypeInfoRow.
+//
+// Dictionary and run-end encoded types are described by their value
+// type. ok is false for the types which have no XDBC equivalent, such as
+// null and nested types.
+func ArrowToXdbcDataType(dt arrow.DataType) (xdbcType int32, typeName string, ok bool) {
+	row, err := NewXdbcTypeInfoRow("", dt)
+	if err != nil {
+		return int32(XdbcUnknownType), "", false
+	}
+
+	typeName = xdbcTypeNames[row.DataType]
+	if row.DatetimeSubcode != nil && XdbcDatetimeSubcode(*row.DatetimeSubcode) == XdbcSubcodeTimestampWithTimezone {
+		typeName = "TIMESTAMP WITH TIME ZONE"
+	}
+	return int32(row.DataType), typeName, true
+}
+
+// XdbcTypeInfoResultBuilder is a helper for constructing a record
+// conforming to schema_ref.XdbcTypeInfo from a list of XdbcTypeInfoRow
+// values.
+type XdbcTypeInfoResultBuilder struct {
+	bldr *array.RecordBuilder
+}
+
+// NewXdbcTypeInfoResultBuilder constructs a builder using the provided
+// allocator, using memory.DefaultAllocator if mem is nil.
+func NewXdbcTypeInfoResultBuilder(mem memory.Allocator) *XdbcTypeInfoResultBuilder {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	return &XdbcTypeInfoResultBuilder{bldr: array.NewRecordBuilder(mem, schema_ref.XdbcTypeInfo)}
+}
+
+// Release releases the underlying record builder.
+func (b *XdbcTypeInfoResultBuilder) Release() { b.bldr.Release() }
+
+// NewRecord returns a record containing all of the rows appended so far
+// and resets the builder so it can be reused.
+func (b *XdbcTypeInfoResultBuilder) NewRecord() arrow.Record { return b.bldr.NewRecord() }
+
+// Append adds the rows to the result being built.
+func (b *XdbcTypeInfoResultBuilder) Append(rows ...XdbcTypeInfoRow) {
+	for _, r := range rows {
+		b.appendRow(r)
+	}
+}
+
+// AppendFiltered adds the rows answering a GetXdbcTypeInfo request for
+// dataType, see GetXdbcTypeInfo.GetDataType: all of them if dataType is
+// nil, otherwise only those of that type. If none is, as when the server
+// doesn't support the requested type, nothing is added, so that the
+// result is empty rather than an error.
+func (b *XdbcTypeInfoResultBuilder) AppendFiltered(dataType *int32, rows ...XdbcTypeInfoRow) {
+	for _, r := range rows {
+		if matchesXdbcDataType(dataType, int32(r.DataType)) {
+			b.appendRow(r)
+		}
+	}
+}
+
+// matchesXdbcDataType reports whether the rows of type typ answer a
+// GetXdbcTypeInfo request for dataType, all types if nil.
+func matchesXdbcDataType(dataType *int32, typ int32) bool {
+	return dataType == nil || *dataType == typ
+}
+
+func (b *XdbcTypeInfoResultBuilder) appendRow(r XdbcTypeInfoRow) {
+	b.bldr.Field(0).(*array.StringBuilder).Append(r.TypeName)
+	b.bldr.Field(1).(*array.Int32Builder).Append(int32(r.DataType))
+	appendInt32Ptr(b.bldr.Field(2).(*array.Int32Builder), r.ColumnSize)
+	appendStrPtr(b.bldr.Field(3).(*array.StringBuilder), r.LiteralPrefix)
+	appendStrPtr(b.bldr.Field(4).(*array.StringBuilder), r.LiteralSuffix)
+
+	createParams := b.bldr.Field(5).(*array.ListBuilder)
+	if r.CreateParams == nil {
+		createParams.AppendNull()
+	} else {
+		createParams.Append(true)
+		createParams.ValueBuilder().(*array.StringBuilder).AppendValues(r.CreateParams, nil)
+	}
+
+	b.bldr.Field(6).(*array.Int32Builder).Append(int32(r.Nullable))
+	b.bldr.Field(7).(*array.BooleanBuilder).Append(r.CaseSensitive)
+	b.bldr.Field(8).(*array.Int32Builder).Append(int32(r.Searchable))
+	appendBoolPtr(b.bldr.Field(9).(*array.BooleanBuilder), r.UnsignedAttribute)
+	b.bldr.Field(10).(*array.BooleanBuilder).Append(r.FixedPrecScale)
+	appendBoolPtr(b.bldr.Field(11).(*array.BooleanBuilder), r.AutoIncrement)
+	appendStrPtr(b.bldr.Field(12).(*array.StringBuilder), r.LocalTypeName)
+	appendInt32Ptr(b.bldr.Field(13).(*array.Int32Builder), r.MinimumScale)
+	appendInt32Ptr(b.bldr.Field(14).(*array.Int32Builder), r.MaximumScale)
+	b.bldr.Field(15).(*array.Int32Builder).Append(int32(r.SqlDataType))
+	appendInt32Ptr(b.bldr.Field(16).(*array.Int32Builder), r.DatetimeSubcode)
+	appendInt32Ptr(b.bldr.Field(17).(*array.Int32Builder), r.NumPrecRadix)
+	appendInt32Ptr(b.bldr.Field(18).(*array.Int32Builder), r.IntervalPrecision)
+}
+
+func appendInt32Ptr(b *array.Int32Builder, v *int32) {
+	if v == nil {
+		b.AppendNull()
+		return
+	}
+	b.Append(*v)
+}
+
+func appendStrPtr(b *array.StringBuilder, v *string) {
+	if v == nil {
+		b.AppendNull()
+		return
+	}
+	b.Append(*v)
+}
+
+func appendBoolPtr(b *array.BooleanBuilder, v *bool) {
+	if v == nil {
+		b.AppendNull()
+		return
+	}
+	b.Append(*v)
+}
+
+// filterXdbcTypeInfo returns the rows of rec whose data_type matches the
+// requested type, like XdbcTypeInfoResultBuilder.AppendFiltered, the
+// record having no rows if the type is unknown. If dataType is nil, rec
+// is returned as is. The caller is responsible for releasing the returned
+// record.
+func filterXdbcTypeInfo(mem memory.Allocator, rec arrow.Record, dataType *int32) (arrow.Record, error) {
+	if dataType == nil {
+		rec.Retain()
+		return rec, nil
+	}
+
+	idx := rec.Schema().FieldIndices("data_type")
+	if len(idx) != 1 {
+		return nil, fmt.Errorf("%w: type info record must have exactly one data_type column", arrow.ErrInvalid)
+	}
+	types, ok := rec.Column(idx[0]).(*array.Int32)
+	if !ok {
+		return nil, fmt.Errorf("%w: type info data_type column must be int32, got %s",
+			arrow.ErrInvalid, rec.Column(idx[0]).DataType())
+	}
+
+	mask := array.NewBooleanBuilder(mem)
+	defer mask.Release()
+	mask.Reserve(types.Len())
+	for _, v := range types.Int32Values() {
+		mask.UnsafeAppend(matchesXdbcDataType(dataType, v))
+	}
+
+	filter := mask.NewArray()
+	defer filter.Release()
+
+	ctx := compute.WithAllocator(context.Background(), mem)
+	return compute.FilterRecordBatch(ctx, rec, filter, compute.DefaultFilterOptions())
+}
+
+// GetXdbcTypeInfoTyped requests the data types supported by the server
+// like GetXdbcTypeInfo and decodes the result. If dataType is not nil,
+// only the rows for that XDBC data type are requested.
+//
+// The columns are looked up by name, so columns added by newer servers
+// and schema metadata are ignored. An error wrapping arrow.ErrInvalid is
+// returned if a non-nullable column of schema_ref.XdbcTypeInfo is missing
+// or a column has an unexpected type, while missing nullable columns are
+// left unset.
+func (c *Client) GetXdbcTypeInfoTyped(ctx context.Context, dataType *int32, opts ...grpc.CallOption) ([]XdbcTypeInfoRow, error) {
+	info, err := c.GetXdbcTypeInfo(ctx, dataType, opts...)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := c.ReadFlightInfo(ctx, info, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	it := newResultIterator(rdr, bindXdbcTypeInfo)
+	defer it.Release()
+
+	var rows []XdbcTypeInfoRow
+	for it.Next() {
+		rows = append(rows, it.Value())
+	}
+	return rows, it.Err()
+}
+
+// xdbcTypeInfoColumn returns the column of rec named after field, or the
+// zero value if rec has no such column and the field is nullable.
+func xdbcTypeInfoColumn[A arrow.Array](rec arrow.Record, field arrow.Field) (A, error) {
+	var col A
+	switch idx := rec.Schema().FieldIndices(field.Name); len(idx) {
+	case 0:
+		if field.Nullable {
+			return col, nil
+		}
+		return col, fmt.Errorf("%w: arrow/flightsql: type info result is missing column %q",
+			arrow.ErrInvalid, field.Name)
+	case 1:
+		return resultColumn[A](rec, idx[0])
+	default:
+		return col, fmt.Errorf("%w: arrow/flightsql: type info result has %d columns named %q",
+			arrow.ErrInvalid, len(idx), field.Name)
+	}
+}
+
+func bindXdbcTypeInfo(rec arrow.Record) (func(int) (XdbcTypeInfoRow, error), error) {
+	var (
+		err     error
+		fields  = schema_ref.XdbcTypeInfo.Fields()
+		strs    = map[int]*array.String{}
+		int32s  = map[int]*array.Int32{}
+		bools   = map[int]*array.Boolean{}
+		params  *array.List
+		paramsV *array.String
+	)
+	for i, f := range fields {
+		switch f.Type.ID() {
+		case arrow.STRING:
+			strs[i], err = xdbcTypeInfoColumn[*array.String](rec, f)
+		case arrow.INT32:
+			int32s[i], err = xdbcTypeInfoColumn[*array.Int32](rec, f)
+		case arrow.BOOL:
+			bools[i], err = xdbcTypeInfoColumn[*array.Boolean](rec, f)
+		case arrow.LIST:
+			if params, err = xdbcTypeInfoColumn[*array.List](rec, f); err == nil && params != nil {
+				var ok bool
+				if paramsV, ok = params.ListValues().(*array.String); !ok {
+					err = fmt.Errorf("%w: arrow/flightsql: unexpected type %s for result column %q",
+						arrow.ErrInvalid, params.DataType(), f.Name)
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the columns which must not have nulls, present if binding succeeded
+	required := make(map[string]arrow.Array)
+	for _, f := range fields {
+		if !f.Nullable {
+			required[f.Name] = rec.Column(rec.Schema().FieldIndices(f.Name)[0])
+		}
+	}
+
+	str := func(col, i int) *string {
+		if strs[col] == nil {
+			return nil
+		}
+		return optionalString(strs[col], i)
+	}
+	i32 := func(col, i int) *int32 {
+		if c := int32s[col]; c != nil && c.IsValid(i) {
+			v := c.Value(i)
+			return &v
+		}
+		return nil
+	}
+	boolean := func(col, i int) *bool {
+		if c := bools[col]; c != nil && c.IsValid(i) {
+			v := c.Value(i)
+			return &v
+		}
+		return nil
+	}
+
+	return func(i int) (XdbcTypeInfoRow, error) {
+		for name, col := range required {
+			if col.IsNull(i) {
+				return XdbcTypeInfoRow{}, fmt.Errorf("%w: arrow/flightsql: type info result has a null %s",
+					arrow.ErrInvalid, name)
+			}
+		}
+
+		row := XdbcTypeInfoRow{
+			TypeName:          *str(0, i),
+			DataType:          XdbcDataType(*i32(1, i)),
+			ColumnSize:        i32(2, i),
+			LiteralPrefix:     str(3, i),
+			LiteralSuffix:     str(4, i),
+			Nullable:          XdbcNullable(*i32(6, i)),
+			CaseSensitive:     *boolean(7, i),
+			Searchable:        XdbcSearchable(*i32(8, i)),
+			UnsignedAttribute: boolean(9, i),
+			FixedPrecScale:    *boolean(10, i),
+			AutoIncrement:     boolean(11, i),
+			LocalTypeName:     str(12, i),
+			MinimumScale:      i32(13, i),
+			MaximumScale:      i32(14, i),
+			SqlDataType:       XdbcDataType(*i32(15, i)),
+			DatetimeSubcode:   i32(16, i),
+			NumPrecRadix:      i32(17, i),
+			IntervalPrecision: i32(18, i),
+		}
+		if params != nil && params.IsValid(i) {
+			start, end := params.ValueOffsets(i)
+			row.CreateParams = make([]string, 0, end-start)
+			for j := start; j < end; j++ {
+				row.CreateParams = append(row.CreateParams, strings.Clone(paramsV.Value(int(j))))
+			}
+		}
+		return row, nil
+	}, nil
+}
@@ -0,0 +1,185 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Constants for the schemes of the Location URIs supported by
+// DialLocation.
+const (
+	LocationSchemeGRPC = "grpc"
+	LocationSchemeTCP  = "grpc+tcp"
+	LocationSchemeTLS  = "grpc+tls"
+	LocationSchemeUnix = "grpc+unix"
+)
+
+// LocationSchemeReuseConnection is the scheme of LocationReuseConnection.
+const LocationSchemeReuseConnection = "arrow-flight-reuse-connection"
+
+// NewLocationTCP returns the location of a service listening on host and
+// port, grpc+tls if it uses TLS and grpc+tcp otherwise. IPv6 addresses
+// are bracketed, as in "grpc+tcp://[::1]:1234".
+func NewLocationTCP(host string, port int, tls bool) *Location {
+	scheme := LocationSchemeTCP
+	if tls {
+		scheme = LocationSchemeTLS
+	}
+	return &Location{Uri: scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))}
+}
+
+// NewLocationUnix returns the grpc+unix location of a service listening
+// on the unix socket at path, such as "grpc+unix:///run/flight.sock". A
+// relative path is made absolute, as the URI has no relative form, and
+// the characters which can't appear in a URI path are percent-encoded.
+func NewLocationUnix(path string) (*Location, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: arrow/flight: invalid unix socket path %q: %s", arrow.ErrInvalid, path, err.Error())
+	}
+	u := url.URL{Scheme: LocationSchemeUnix, Path: filepath.ToSlash(abs)}
+	return &Location{Uri: u.String()}, nil
+}
+
+// NewLocationReuseConnection returns a location whose endpoint is to be
+// retrieved with the connection the FlightInfo was obtained with, see
+// LocationReuseConnection.
+func NewLocationReuseConnection() *Location {
+	return &Location{Uri: LocationReuseConnection}
+}
+
+// NewFlightEndpoint returns an endpoint whose ticket can be retrieved
+// from any of the locations, such as those of NewLocationTCP or
+// LocationReuseConnection, failing if one of them is malformed, see
+// ParseLocation. Without locations, the ticket is to be retrieved from
+// the service the FlightInfo was obtained from.
+func NewFlightEndpoint(tkt *Ticket, locations ...string) (*FlightEndpoint, error) {
+	ep := &FlightEndpoint{Ticket: tkt}
+	for _, uri := range locations {
+		if _, _, _, err := ParseLocation(uri); err != nil {
+			return nil, err
+		}
+		ep.Location = append(ep.Location, &Location{Uri: uri})
+	}
+	return ep, nil
+}
+
+// LocationForAddr returns the location of a service listening on addr,
+// such as the Addr of a Server: a grpc+tcp location for tcp addresses
+// and a grpc+unix location for unix sockets.
+func LocationForAddr(addr net.Addr) (*Location, error) {
+	switch addr.Network() {
+	case "tcp", "tcp4", "tcp6":
+		return &Location{Uri: LocationSchemeTCP + "://" + addr.String()}, nil
+	case "unix":
+		return NewLocationUnix(addr.String())
+	default:
+		return nil, fmt.Errorf("%w: arrow/flight: no location for %s address %q", arrow.ErrNotImplemented, addr.Network(), addr.String())
+	}
+}
+
+// ParseLocation splits the location URI uri into its scheme, authority
+// and decoded path, failing with an error wrapping arrow.ErrInvalid if it
+// is malformed: the grpc, grpc+tcp and grpc+tls locations must have a
+// host and a port, such as "[::1]:1234", and grpc+unix locations an
+// absolute socket path without authority. The locations of other schemes
+// only need to be valid URIs, whether DialLocation supports them or not.
+func ParseLocation(uri string) (scheme, authority, path string, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", "", fmt.Errorf("%w: arrow/flight: invalid location %q: %s", arrow.ErrInvalid, uri, err.Error())
+	}
+	if u.Scheme == "" || u.Opaque != "" {
+		return "", "", "", fmt.Errorf("%w: arrow/flight: location %q is not of the form scheme://authority/path", arrow.ErrInvalid, uri)
+	}
+
+	switch u.Scheme {
+	case LocationSchemeGRPC, LocationSchemeTCP, LocationSchemeTLS:
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil || host == "" {
+			return "", "", "", fmt.Errorf("%w: arrow/flight: location %q has no host and port", arrow.ErrInvalid, uri)
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return "", "", "", fmt.Errorf("%w: arrow/flight: invalid port of location %q", arrow.ErrInvalid, uri)
+		}
+	case LocationSchemeUnix:
+		if u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+			return "", "", "", fmt.Errorf("%w: arrow/flight: location %q has no absolute socket path, as in grpc+unix:///path", arrow.ErrInvalid, uri)
+		}
+	}
+	return u.Scheme, u.Host, u.Path, nil
+}
+
+// dialTarget returns the gRPC target of addr, which is passed as is
+// unless it is a grpc+unix location URI.
+func dialTarget(addr string) string {
+	if !strings.HasPrefix(addr, LocationSchemeUnix+"://") {
+		return addr
+	}
+	if _, _, path, err := ParseLocation(addr); err == nil {
+		return unixTarget(path)
+	}
+	return addr
+}
+
+// unixTarget returns the gRPC target of the unix socket at path, whose
+// special characters are percent-encoded as gRPC decodes them.
+func unixTarget(path string) string {
+	u := url.URL{Scheme: "unix", Path: path}
+	return u.String()
+}
+
+// DialLocation connects to location without caching the connection. It
+// supports grpc, grpc+tcp and grpc+unix locations, which are dialed
+// without transport security, and grpc+tls locations, which use
+// tlsConfig, or the system's root certificates if nil. The dial options
+// are added to those.
+func DialLocation(ctx context.Context, location *Location, tlsConfig *tls.Config, opts ...grpc.DialOption) (Client, error) {
+	scheme, target, path, err := ParseLocation(location.GetUri())
+	switch {
+	case err != nil:
+		return nil, err
+	case scheme == LocationSchemeUnix:
+		target = unixTarget(path)
+	case scheme != LocationSchemeGRPC && scheme != LocationSchemeTCP && scheme != LocationSchemeTLS:
+		return nil, fmt.Errorf("%w: arrow/flight: unsupported scheme %q of location %q", arrow.ErrNotImplemented, scheme, location.GetUri())
+	}
+
+	creds := insecure.NewCredentials()
+	if scheme == LocationSchemeTLS {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	dialOpts := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts...)
+	return NewClientWithMiddlewareCtx(ctx, target, nil, nil, dialOpts...)
+}
@@ -0,0 +1,219 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// latencyWeight is the weight of the latest latency in the moving average
+// of the latencies of a location kept by the latency policy.
+const latencyWeight = 0.3
+
+// LocationPolicy chooses the order in which the readers of
+// NewFlightInfoReader try the locations of an endpoint, which replicate
+// the same data, so that the load is spread between them rather than
+// always falling on the first. A policy is shared by the readers it is
+// given to, see WithLocationPolicy, and must be safe for concurrent use.
+//
+// Users can implement their own, such as to prefer the locations in the
+// same zone as the client.
+type LocationPolicy interface {
+	// Order returns the locations of an endpoint in the order to try
+	// them, without modifying locations.
+	Order(locations []*Location) []*Location
+	// Report is told how retrieving an endpoint from location went: the
+	// time until its first message was received, which includes dialing
+	// it, or the error it failed with.
+	Report(location *Location, latency time.Duration, err error)
+}
+
+// WithLocationPolicy makes NewFlightInfoReader try the locations of each
+// endpoint in the order chosen by policy, reporting the outcome of each
+// attempt to it. By default they are tried in the order they are listed.
+func WithLocationPolicy(policy LocationPolicy) grpc.CallOption {
+	return flightInfoReaderOption{apply: func(cfg *flightInfoReaderConfig) { cfg.policy = policy }}
+}
+
+// failureMemory remembers the locations which failed recently, so that
+// they are tried after the others until cooldown has elapsed.
+type failureMemory struct {
+	cooldown time.Duration
+
+	mu     sync.Mutex
+	failed map[string]time.Time
+}
+
+func newFailureMemory(cooldown time.Duration) failureMemory {
+	return failureMemory{cooldown: cooldown, failed: make(map[string]time.Time)}
+}
+
+func (m *failureMemory) report(location *Location, err error) {
+	if m.cooldown <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.failed[location.GetUri()] = time.Now()
+	} else {
+		delete(m.failed, location.GetUri())
+	}
+}
+
+// deprioritize moves the locations of locs which failed within the
+// cooldown after the others, the least recently failed first, keeping
+// the order of the others.
+func (m *failureMemory) deprioritize(locs []*Location) []*Location {
+	if m.cooldown <= 0 {
+		return locs
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var (
+		healthy  = make([]*Location, 0, len(locs))
+		failed   []*Location
+		failedAt = make(map[*Location]time.Time)
+	)
+	for _, loc := range locs {
+		at, ok := m.failed[loc.GetUri()]
+		switch {
+		case !ok:
+			healthy = append(healthy, loc)
+		case time.Since(at) >= m.cooldown:
+			delete(m.failed, loc.GetUri())
+			healthy = append(healthy, loc)
+		default:
+			failed = append(failed, loc)
+			failedAt[loc] = at
+		}
+	}
+	sort.SliceStable(failed, func(i, j int) bool { return failedAt[failed[i]].Before(failedAt[failed[j]]) })
+	return append(healthy, failed...)
+}
+
+type roundRobinPolicy struct {
+	next     atomic.Uint64
+	failures failureMemory
+}
+
+// NewRoundRobinLocationPolicy returns a LocationPolicy which rotates the
+// locations of the endpoints, starting from the next one at each call.
+// The locations which failed less than cooldown ago are tried after the
+// others; a zero cooldown doesn't remember failures.
+func NewRoundRobinLocationPolicy(cooldown time.Duration) LocationPolicy {
+	return &roundRobinPolicy{failures: newFailureMemory(cooldown)}
+}
+
+func (p *roundRobinPolicy) Order(locations []*Location) []*Location {
+	if len(locations) == 0 {
+		return locations
+	}
+	start := int((p.next.Add(1) - 1) % uint64(len(locations)))
+	locs := append(append(make([]*Location, 0, len(locations)), locations[start:]...), locations[:start]...)
+	return p.failures.deprioritize(locs)
+}
+
+func (p *roundRobinPolicy) Report(location *Location, _ time.Duration, err error) {
+	p.failures.report(location, err)
+}
+
+type randomPolicy struct {
+	failures failureMemory
+}
+
+// NewRandomLocationPolicy returns a LocationPolicy which shuffles the
+// locations of the endpoints. The locations which failed less than
+// cooldown ago are tried after the others; a zero cooldown doesn't
+// remember failures.
+func NewRandomLocationPolicy(cooldown time.Duration) LocationPolicy {
+	return &randomPolicy{failures: newFailureMemory(cooldown)}
+}
+
+func (p *randomPolicy) Order(locations []*Location) []*Location {
+	locs := make([]*Location, len(locations))
+	for i, j := range rand.Perm(len(locations)) {
+		locs[i] = locations[j]
+	}
+	return p.failures.deprioritize(locs)
+}
+
+func (p *randomPolicy) Report(location *Location, _ time.Duration, err error) {
+	p.failures.report(location, err)
+}
+
+type latencyPolicy struct {
+	failures failureMemory
+
+	mu        sync.Mutex
+	latencies map[string]time.Duration
+}
+
+// NewLatencyLocationPolicy returns a LocationPolicy which tries the
+// locations of the endpoints with the lowest recent latency first, that
+// is the time until the first message of an endpoint was received from
+// them, tracked per location as a moving average. The locations without
+// latency yet are tried first, so that they are measured. The locations
+// which failed less than cooldown ago are tried after the others; a zero
+// cooldown doesn't remember failures.
+func NewLatencyLocationPolicy(cooldown time.Duration) LocationPolicy {
+	return &latencyPolicy{failures: newFailureMemory(cooldown), latencies: make(map[string]time.Duration)}
+}
+
+func (p *latencyPolicy) Order(locations []*Location) []*Location {
+	locs := append(make([]*Location, 0, len(locations)), locations...)
+	p.mu.Lock()
+	latencies := make([]time.Duration, len(locs))
+	for i, loc := range locs {
+		latencies[i] = p.latencies[loc.GetUri()]
+	}
+	p.mu.Unlock()
+
+	idx := make([]int, len(locs))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return latencies[idx[i]] < latencies[idx[j]] })
+	for i, j := range idx {
+		locs[i] = locations[j]
+	}
+	return p.failures.deprioritize(locs)
+}
+
+func (p *latencyPolicy) Report(location *Location, latency time.Duration, err error) {
+	p.failures.report(location, err)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	uri := location.GetUri()
+	if prev, ok := p.latencies[uri]; ok {
+		latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(prev))
+	}
+	// a latency of 0 means unmeasured
+	p.latencies[uri] = max(latency, 1)
+}
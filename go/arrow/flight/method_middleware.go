@@ -0,0 +1,192 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// FlightMethod identifies a method of the Flight service.
+type FlightMethod int8
+
+// Constants for FlightMethod
+const (
+	FlightMethodInvalid FlightMethod = iota
+	FlightMethodHandshake
+	FlightMethodListFlights
+	FlightMethodGetFlightInfo
+	FlightMethodPollFlightInfo
+	FlightMethodGetSchema
+	FlightMethodDoGet
+	FlightMethodDoPut
+	FlightMethodDoAction
+	FlightMethodListActions
+	FlightMethodDoExchange
+)
+
+var flightMethodNames = [...]string{
+	FlightMethodInvalid:        "Invalid",
+	FlightMethodHandshake:      "Handshake",
+	FlightMethodListFlights:    "ListFlights",
+	FlightMethodGetFlightInfo:  "GetFlightInfo",
+	FlightMethodPollFlightInfo: "PollFlightInfo",
+	FlightMethodGetSchema:      "GetSchema",
+	FlightMethodDoGet:          "DoGet",
+	FlightMethodDoPut:          "DoPut",
+	FlightMethodDoAction:       "DoAction",
+	FlightMethodListActions:    "ListActions",
+	FlightMethodDoExchange:     "DoExchange",
+}
+
+func (m FlightMethod) String() string {
+	if m < 0 || int(m) >= len(flightMethodNames) {
+		return flightMethodNames[FlightMethodInvalid]
+	}
+	return flightMethodNames[m]
+}
+
+// flightMethodFromFullMethod returns the method of the Flight service
+// named by the gRPC full method name, FlightMethodInvalid for the methods
+// of other services.
+func flightMethodFromFullMethod(fullMethod string) FlightMethod {
+	name, ok := strings.CutPrefix(fullMethod, "/"+flight.FlightService_ServiceDesc.ServiceName+"/")
+	if !ok {
+		return FlightMethodInvalid
+	}
+	for m, n := range flightMethodNames {
+		if n == name && m != int(FlightMethodInvalid) {
+			return FlightMethod(m)
+		}
+	}
+	return FlightMethodInvalid
+}
+
+// MethodServerMiddleware is like CustomServerMiddleware, but is told which
+// method of the Flight service is called along with the descriptor or
+// ticket of the call, so that authorization can be decided without parsing
+// the requests. See CreateMethodServerMiddleware.
+type MethodServerMiddleware interface {
+	// StartCall is called once the request of the call is known: desc is
+	// that of GetFlightInfo, PollFlightInfo and GetSchema, or the descriptor
+	// of the first message of DoPut and DoExchange, and ticket that of
+	// DoGet, both being nil for the other methods. If an error is returned,
+	// the call fails with it without being handled, otherwise the context
+	// returned, if not nil, is used for the rest of the call.
+	StartCall(ctx context.Context, method FlightMethod, desc *FlightDescriptor, ticket *Ticket) (context.Context, error)
+	// CallCompleted is called with the error returned by the call, nil
+	// if successful, which gRPC sends as the status of the call. It isn't
+	// called for calls rejected by StartCall.
+	CallCompleted(ctx context.Context, method FlightMethod, err error)
+}
+
+// CreateMethodServerMiddleware constructs a ServerMiddleware calling the
+// passed in middleware around the calls of the methods of the Flight
+// service, the calls of other services registered on the same server
+// being passed through. It composes with other middleware given to
+// NewServerWithMiddleware like those of CreateServerMiddleware.
+//
+// DoGet, DoPut and DoExchange receive the first message of the stream,
+// holding the ticket or descriptor, before calling StartCall. The handler
+// then receives it as usual.
+func CreateMethodServerMiddleware(middleware MethodServerMiddleware) ServerMiddleware {
+	return ServerMiddleware{
+		Unary: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			method := flightMethodFromFullMethod(info.FullMethod)
+			if method == FlightMethodInvalid {
+				return handler(ctx, req)
+			}
+
+			desc, _ := req.(*FlightDescriptor)
+			nctx, err := middleware.StartCall(ctx, method, desc, nil)
+			if err != nil {
+				return nil, err
+			}
+			if nctx != nil {
+				ctx = nctx
+			}
+
+			ret, err := handler(ctx, req)
+			middleware.CallCompleted(ctx, method, err)
+			return ret, err
+		},
+		Stream: func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			method := flightMethodFromFullMethod(info.FullMethod)
+			if method == FlightMethodInvalid {
+				return handler(srv, stream)
+			}
+
+			var (
+				desc   *FlightDescriptor
+				ticket *Ticket
+			)
+			switch method {
+			case FlightMethodDoGet:
+				req := &Ticket{}
+				rs := &replayStream{ServerStream: stream, msg: req}
+				if rs.err = stream.RecvMsg(req); rs.err == nil {
+					ticket = req
+				}
+				stream = rs
+			case FlightMethodDoPut, FlightMethodDoExchange:
+				data := &FlightData{}
+				rs := &replayStream{ServerStream: stream, msg: data}
+				if rs.err = stream.RecvMsg(data); rs.err == nil {
+					desc = data.FlightDescriptor
+				}
+				stream = rs
+			}
+
+			ctx, err := middleware.StartCall(stream.Context(), method, desc, ticket)
+			if err != nil {
+				return err
+			}
+			if ctx != nil {
+				stream = &wrappedStream{ServerStream: stream, ctx: ctx}
+			}
+
+			err = handler(srv, stream)
+			middleware.CallCompleted(stream.Context(), method, err)
+			return err
+		},
+	}
+}
+
+// replayStream is a stream whose first message was already received,
+// which it receives again for the handler.
+type replayStream struct {
+	grpc.ServerStream
+	msg    proto.Message
+	err    error
+	played bool
+}
+
+func (s *replayStream) RecvMsg(m interface{}) error {
+	if s.played {
+		return s.ServerStream.RecvMsg(m)
+	}
+	s.played = true
+	if s.err != nil {
+		return s.err
+	}
+	proto.Merge(m.(proto.Message), s.msg)
+	return nil
+}
@@ -0,0 +1,444 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otelflight provides client and server middleware tracing Flight
+// and Flight SQL calls with OpenTelemetry.
+//
+// [NewClientMiddleware] and [NewServerMiddleware] start a span per call,
+// propagating the span of the client to the server through the gRPC
+// metadata of the call so that the spans of the server are its children.
+// Spans are named after the RPC and the Flight SQL command it carries,
+// such as "GetFlightInfo CommandStatementQuery", and have the following
+// attributes when they apply:
+//
+//   - rpc.system, rpc.service, rpc.method and rpc.grpc.status_code
+//   - flightsql.command, the name of the Flight SQL command
+//   - flightsql.query.length, the length of the SQL query of the command,
+//     and db.statement, the query itself, if [WithQueryText] is given
+//   - flightsql.handle.hash, a hash of the prepared statement or ticket
+//     handle of the command, which identifies the statement across calls
+//     without recording the handle, which may hold sensitive data
+//   - flight.records.sent, flight.records.received, flight.bytes.sent and
+//     flight.bytes.received, the number of record batches and of bytes of
+//     FlightData streamed
+//
+// The spans of streaming calls end when the stream ends rather than when
+// the call returns. On the client, this is once the stream has been read
+// to the end, has failed, or its context is done, so streams which are
+// neither read to the end nor canceled don't end their span.
+package otelflight
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	_ "github.com/apache/arrow/go/v16/arrow/flight/gen/flight" // registers the Flight SQL commands
+	"github.com/apache/arrow/go/v16/arrow/internal/flatbuf"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const instrumentationName = "github.com/apache/arrow/go/v16/arrow/flight/otelflight"
+
+type config struct {
+	tp         trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	queryText  bool
+}
+
+// Option configures the middleware of this package.
+type Option func(*config)
+
+// WithTracerProvider sets the TracerProvider spans are started with,
+// which is the global one by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		if tp != nil {
+			c.tp = tp
+		}
+	}
+}
+
+// WithPropagator sets how spans are propagated in the metadata of calls,
+// which is with the W3C traceparent and tracestate headers by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		if p != nil {
+			c.propagator = p
+		}
+	}
+}
+
+// WithQueryText records the SQL query of Flight SQL commands in the
+// db.statement attribute. Only its length is recorded by default, as
+// queries may hold sensitive data.
+func WithQueryText() Option {
+	return func(c *config) { c.queryText = true }
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{tp: otel.GetTracerProvider(), propagator: propagation.TraceContext{}}
+	for _, o := range opts {
+		o(cfg)
+	}
+	return cfg
+}
+
+type tracer struct {
+	trace.Tracer
+	*config
+}
+
+func newTracer(opts []Option) *tracer {
+	cfg := newConfig(opts)
+	return &tracer{Tracer: cfg.tp.Tracer(instrumentationName), config: cfg}
+}
+
+// NewServerMiddleware returns the middleware starting a span around each
+// call handled by a server, as the child of the span of the client if it
+// was propagated with the call. The span is in the context of the call,
+// so handlers can add attributes or start child spans of their own with
+// trace.SpanFromContext.
+func NewServerMiddleware(opts ...Option) flight.ServerMiddleware {
+	t := newTracer(opts)
+	return flight.ServerMiddleware{Unary: t.serverUnary, Stream: t.serverStream}
+}
+
+// NewClientMiddleware returns the middleware starting a span around each
+// call made by a client, as the child of the span in the context of the
+// call, and propagating it to the server.
+func NewClientMiddleware(opts ...Option) flight.ClientMiddleware {
+	t := newTracer(opts)
+	return flight.ClientMiddleware{Unary: t.clientUnary, Stream: t.clientStream}
+}
+
+// NewPropagatingClientMiddleware returns the middleware propagating the
+// span in the context of each call to the server, without starting spans
+// of its own. Only WithPropagator applies to it.
+func NewPropagatingClientMiddleware(opts ...Option) flight.ClientMiddleware {
+	cfg := newConfig(opts)
+	return flight.ClientMiddleware{
+		Unary: func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(cfg.inject(ctx), method, req, reply, cc, opts...)
+		},
+		Stream: func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(cfg.inject(ctx), desc, cc, method, opts...)
+		},
+	}
+}
+
+func (c *config) inject(ctx context.Context) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	c.propagator.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func (c *config) extract(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = c.propagator.Extract(ctx, metadataCarrier(md))
+	}
+	return ctx
+}
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	if v := metadata.MD(m).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (m metadataCarrier) Set(key, value string) { metadata.MD(m).Set(key, value) }
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// start starts the span of a call to the full gRPC method name, whose
+// request is req if known.
+func (t *tracer) start(ctx context.Context, kind trace.SpanKind, method string, req interface{}) (context.Context, trace.Span) {
+	service, rpc := splitMethod(method)
+	attrs := []attribute.KeyValue{
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", rpc),
+	}
+	command, cmdAttrs := t.commandAttrs(req)
+	attrs = append(attrs, cmdAttrs...)
+	return t.Start(ctx, spanName(rpc, command), trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// end ends span with the status of err, io.EOF being the successful end
+// of a stream.
+func end(span trace.Span, err error, stats *streamStats) {
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	span.SetAttributes(attribute.Int64("rpc.grpc.status_code", int64(status.Code(err))))
+	if stats != nil {
+		span.SetAttributes(
+			attribute.Int64("flight.records.sent", stats.sent.records.Load()),
+			attribute.Int64("flight.bytes.sent", stats.sent.bytes.Load()),
+			attribute.Int64("flight.records.received", stats.received.records.Load()),
+			attribute.Int64("flight.bytes.received", stats.received.bytes.Load()),
+		)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *tracer) serverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span := t.start(t.extract(ctx), trace.SpanKindServer, info.FullMethod, req)
+	resp, err := handler(ctx, req)
+	end(span, err, nil)
+	return resp, err
+}
+
+func (t *tracer) serverStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := t.start(t.extract(stream.Context()), trace.SpanKindServer, info.FullMethod, nil)
+	ts := &tracedServerStream{ServerStream: stream, ctx: ctx}
+	ts.init(t, span, info.FullMethod)
+	err := handler(srv, ts)
+	end(span, err, &ts.stats)
+	return err
+}
+
+func (t *tracer) clientUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, span := t.start(ctx, trace.SpanKindClient, method, req)
+	err := invoker(t.inject(ctx), method, req, reply, cc, opts...)
+	end(span, err, nil)
+	return err
+}
+
+func (t *tracer) clientStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, span := t.start(ctx, trace.SpanKindClient, method, nil)
+	stream, err := streamer(t.inject(ctx), desc, cc, method, opts...)
+	if err != nil {
+		end(span, err, nil)
+		return nil, err
+	}
+
+	ts := &tracedClientStream{ClientStream: stream}
+	ts.init(t, span, method)
+	ts.stop = context.AfterFunc(ctx, func() { ts.end(ctx.Err()) })
+	return ts, nil
+}
+
+// streamCounts counts the record batches and bytes of the FlightData
+// streamed in one direction.
+type streamCounts struct {
+	records, bytes atomic.Int64
+}
+
+func (c *streamCounts) observe(m interface{}) {
+	data, ok := m.(*flight.FlightData)
+	if !ok {
+		return
+	}
+	c.bytes.Add(int64(len(data.DataHeader) + len(data.DataBody)))
+	if len(data.DataHeader) > 0 && flatbuf.GetRootAsMessage(data.DataHeader, 0).HeaderType() == flatbuf.MessageHeaderRecordBatch {
+		c.records.Add(1)
+	}
+}
+
+type streamStats struct {
+	sent, received streamCounts
+}
+
+// streamSpan is the span of a streaming call, which is named after the
+// command of the first message sent by the client, as it is only known
+// once sent.
+type streamSpan struct {
+	t     *tracer
+	span  trace.Span
+	rpc   string
+	named atomic.Bool
+	stats streamStats
+}
+
+func (s *streamSpan) init(t *tracer, span trace.Span, method string) {
+	s.t, s.span = t, span
+	_, s.rpc = splitMethod(method)
+}
+
+func (s *streamSpan) name(m interface{}) {
+	if s.named.Swap(true) {
+		return
+	}
+	if command, attrs := s.t.commandAttrs(m); command != "" {
+		s.span.SetName(spanName(s.rpc, command))
+		s.span.SetAttributes(attrs...)
+	}
+}
+
+type tracedServerStream struct {
+	grpc.ServerStream
+	streamSpan
+	ctx context.Context
+}
+
+func (s *tracedServerStream) Context() context.Context { return s.ctx }
+
+func (s *tracedServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.stats.sent.observe(m)
+	}
+	return err
+}
+
+func (s *tracedServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.name(m)
+		s.stats.received.observe(m)
+	}
+	return err
+}
+
+type tracedClientStream struct {
+	grpc.ClientStream
+	streamSpan
+
+	once sync.Once
+	// stop stops ending the span once the context of the call is done
+	stop func() bool
+}
+
+func (s *tracedClientStream) end(err error) {
+	s.once.Do(func() { end(s.span, err, &s.stats) })
+}
+
+func (s *tracedClientStream) SendMsg(m interface{}) error {
+	s.name(m)
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.stats.sent.observe(m)
+	}
+	return err
+}
+
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.stop()
+		s.end(err)
+		return err
+	}
+	s.stats.received.observe(m)
+	return nil
+}
+
+// splitMethod splits a full gRPC method name such as
+// "/arrow.flight.protocol.FlightService/DoGet" into its service and RPC.
+func splitMethod(method string) (service, rpc string) {
+	service, rpc, _ = strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	return
+}
+
+func spanName(rpc, command string) string {
+	if command == "" {
+		return rpc
+	}
+	return rpc + " " + command
+}
+
+// commandAttrs returns the name of the Flight SQL command carried by a
+// request message and the attributes describing it, or "" if it doesn't
+// carry one.
+func (t *tracer) commandAttrs(req interface{}) (string, []attribute.KeyValue) {
+	var (
+		name string
+		cmd  []byte
+	)
+	switch req := req.(type) {
+	case *flight.Action:
+		name, cmd = req.GetType(), req.GetBody()
+	case *flight.FlightDescriptor:
+		cmd = req.GetCmd()
+	case *flight.Ticket:
+		cmd = req.GetTicket()
+	case *flight.FlightData:
+		cmd = req.GetFlightDescriptor().GetCmd()
+	default:
+		return "", nil
+	}
+
+	var container anypb.Any
+	if len(cmd) == 0 || proto.Unmarshal(cmd, &container) != nil || container.GetTypeUrl() == "" {
+		if name == "" {
+			return "", nil
+		}
+		return name, []attribute.KeyValue{attribute.String("flightsql.command", name)}
+	}
+	if name == "" {
+		name = string(container.MessageName().Name())
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("flightsql.command", name)}
+	msg, err := container.UnmarshalNew()
+	if err != nil {
+		// commands the generated code predates are only named
+		return name, attrs
+	}
+	if msg, ok := msg.(interface{ GetQuery() string }); ok {
+		attrs = append(attrs, attribute.Int("flightsql.query.length", len(msg.GetQuery())))
+		if t.queryText {
+			attrs = append(attrs, attribute.String("db.statement", msg.GetQuery()))
+		}
+	}
+	switch msg := msg.(type) {
+	case interface{ GetPreparedStatementHandle() []byte }:
+		attrs = append(attrs, attribute.String("flightsql.handle.hash", hashHandle(msg.GetPreparedStatementHandle())))
+	case interface{ GetStatementHandle() []byte }:
+		attrs = append(attrs, attribute.String("flightsql.handle.hash", hashHandle(msg.GetStatementHandle())))
+	}
+	return name, attrs
+}
+
+// hashHandle returns a short hash identifying a handle.
+func hashHandle(handle []byte) string {
+	sum := sha256.Sum256(handle)
+	return hex.EncodeToString(sum[:8])
+}
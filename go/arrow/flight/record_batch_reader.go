@@ -18,7 +18,9 @@ package flight
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"sync/atomic"
 
 	"github.com/apache/arrow/go/v16/arrow"
@@ -44,6 +46,37 @@ type dataMessageReader struct {
 
 	lastAppMetadata []byte
 	descr           *FlightDescriptor
+
+	// exchange is set for the readers of DoExchange streams, whose
+	// messages carrying only app metadata are returned by Reader.Next.
+	exchange bool
+	// pending holds the metadata-only messages of an exchange received
+	// while the ipc reader was waiting for a schema or dictionary.
+	pending []*FlightData
+	// skipped holds the metadata-only messages of other streams received
+	// since the previous record.
+	skipped []*FlightData
+	// err is the error which ended an exchange.
+	err error
+}
+
+// peek returns the next message of an exchange without consuming it.
+func (d *dataMessageReader) peek() (*FlightData, error) {
+	if d.peeked == nil && d.err == nil {
+		d.peeked, d.err = d.rdr.Recv()
+	}
+	return d.peeked, d.err
+}
+
+func (d *dataMessageReader) recv() (*FlightData, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	fd, err := d.rdr.Recv()
+	if d.exchange {
+		d.err = err
+	}
+	return fd, err
 }
 
 func (d *dataMessageReader) Message() (*ipc.Message, error) {
@@ -56,7 +89,16 @@ func (d *dataMessageReader) Message() (*ipc.Message, error) {
 		fd = d.peeked
 		d.peeked = nil
 	} else {
-		fd, err = d.rdr.Recv()
+		fd, err = d.recv()
+	}
+
+	for err == nil && len(fd.DataHeader) == 0 {
+		if d.exchange {
+			d.pending = append(d.pending, fd)
+		} else {
+			d.skipped = append(d.skipped, fd)
+		}
+		fd, err = d.recv()
 	}
 
 	if err != nil {
@@ -89,6 +131,8 @@ func (d *dataMessageReader) Release() {
 			d.msg = nil
 		}
 		d.lastAppMetadata = nil
+		d.pending = nil
+		d.skipped = nil
 	}
 }
 
@@ -98,6 +142,117 @@ func (d *dataMessageReader) Release() {
 type Reader struct {
 	*ipc.Reader
 	dmr *dataMessageReader
+
+	// noRecord is set when the current message of an exchange carries
+	// no record, or once the exchange ended.
+	noRecord bool
+	// metadata holds the metadata-only messages skipped by the last call
+	// to Next.
+	metadata []*FlightData
+	// queued holds the messages received by the last call to Next which
+	// NextMessage didn't return yet, the one of the record last.
+	queued []Message
+}
+
+// Message is a message of a Flight stream as returned by
+// Reader.NextMessage, with the app metadata and descriptor sent along
+// with its record.
+type Message struct {
+	// Record is the record of the message, nil if it carries only app
+	// metadata. It is owned by the reader and only valid until the next
+	// call to NextMessage, unless retained.
+	Record      arrow.Record
+	AppMetadata []byte
+	Desc        *FlightDescriptor
+}
+
+// MessageSource is implemented by the readers which return each message
+// of a Flight stream in turn, such as Reader.
+type MessageSource interface {
+	NextMessage() (Message, error)
+}
+
+// NextMessage returns the next message of the stream, in the order they
+// were sent: the messages carrying a record, with their app metadata and
+// descriptor, as well as those carrying only app metadata, which Next
+// skips unless reading a DoExchange stream. It returns io.EOF once the
+// stream ended, after any message sent after the last record. The records
+// of the stream are read by NextMessage or by Next, and the two shouldn't
+// be mixed.
+func (r *Reader) NextMessage() (Message, error) {
+	if len(r.queued) == 0 {
+		next := r.Next()
+		for _, fd := range r.metadata {
+			r.queued = append(r.queued, Message{AppMetadata: fd.AppMetadata, Desc: fd.FlightDescriptor})
+		}
+		if next {
+			r.queued = append(r.queued, Message{
+				Record:      r.Record(),
+				AppMetadata: r.dmr.lastAppMetadata,
+				Desc:        r.dmr.descr,
+			})
+		}
+		if len(r.queued) == 0 {
+			if err := r.Err(); err != nil {
+				return Message{}, err
+			}
+			return Message{}, io.EOF
+		}
+	}
+
+	msg := r.queued[0]
+	r.queued = r.queued[1:]
+	return msg, nil
+}
+
+// Next advances to the next record of the stream, returning false once
+// the stream ended or failed. The readers of DoExchange streams also stop
+// at each message carrying only app metadata, Record returning nil and
+// LatestAppMetadata the metadata. Other readers skip those messages,
+// whose metadata is returned by LatestMetadataMessages.
+func (r *Reader) Next() bool {
+	r.noRecord = false
+	if !r.dmr.exchange {
+		next := r.Reader.Next()
+		r.metadata, r.dmr.skipped = r.dmr.skipped, nil
+		return next
+	}
+
+	if r.Reader.Err() != nil {
+		return false
+	}
+
+	var fd *FlightData
+	if len(r.dmr.pending) > 0 {
+		fd = r.dmr.pending[0]
+		r.dmr.pending = r.dmr.pending[1:]
+	} else {
+		var err error
+		fd, err = r.dmr.peek()
+		switch {
+		case err == io.EOF:
+			// the exchange may end before any schema was sent
+			r.noRecord = true
+			return false
+		case err != nil || len(fd.DataHeader) > 0:
+			return r.Reader.Next()
+		}
+		r.dmr.peeked = nil
+	}
+
+	r.noRecord = true
+	r.dmr.lastAppMetadata = fd.AppMetadata
+	r.dmr.descr = fd.FlightDescriptor
+	return true
+}
+
+// Record returns the current record, which is nil if the current message
+// of an exchange carries only app metadata.
+func (r *Reader) Record() arrow.Record {
+	if r.noRecord {
+		return nil
+	}
+	return r.Reader.Record()
 }
 
 // Retain increases the reference count for the underlying message reader
@@ -123,6 +278,24 @@ func (r *Reader) LatestAppMetadata() []byte {
 	return r.dmr.lastAppMetadata
 }
 
+// LatestMetadataMessages returns the app metadata of the messages carrying
+// only app metadata, such as progress updates sent with
+// Writer.WriteMetadata, which the most recent call to Next skipped before
+// the current record, or before the end of the stream once Next returned
+// false. Those received before the schema are returned after the first
+// call to Next. The readers of DoExchange streams return these messages
+// from Next instead, this returning nil.
+func (r *Reader) LatestMetadataMessages() [][]byte {
+	if len(r.metadata) == 0 {
+		return nil
+	}
+	metadata := make([][]byte, len(r.metadata))
+	for i, fd := range r.metadata {
+		metadata[i] = fd.AppMetadata
+	}
+	return metadata
+}
+
 // LatestFlightDescriptor returns a pointer to the last FlightDescriptor object
 // that was received in the most recently read FlightData message that was
 // processed by calling the Next function. The descriptor returned would correspond
@@ -157,6 +330,8 @@ func NewRecordReader(r DataStreamReader, opts ...ipc.Option) (*Reader, error) {
 	rdr.dmr.descr = data.FlightDescriptor
 	if len(data.DataHeader) > 0 {
 		rdr.dmr.peeked = data
+	} else if len(data.AppMetadata) > 0 {
+		rdr.dmr.skipped = append(rdr.dmr.skipped, data)
 	}
 
 	rdr.dmr.Retain()
@@ -167,6 +342,49 @@ func NewRecordReader(r DataStreamReader, opts ...ipc.Option) (*Reader, error) {
 	return rdr, nil
 }
 
+// NewExchangeReader constructs a reader of the FlightData received on a
+// DoExchange stream, such as the client side of the stream returned by
+// Client.DoExchangeRecords. Unlike NewRecordReader, it doesn't wait for
+// the schema, which is read by the first call to Next, and the messages
+// carrying only app metadata are returned by Next along with the records.
+// Those received while the schema or a dictionary is expected, such as by
+// calling Schema before Next, are returned by Next afterwards.
+//
+// The stream may end before any schema was received, Next then returning
+// false without error.
+func NewExchangeReader(r DataStreamReader, opts ...ipc.Option) (*Reader, error) {
+	rdr := &Reader{dmr: &dataMessageReader{rdr: r, refCount: 1, exchange: true}}
+	rdr.dmr.Retain()
+	var err error
+	if rdr.Reader, err = ipc.NewReaderFromMessageReader(rdr.dmr, append(opts, ipc.WithDelayReadSchema(true))...); err != nil {
+		return nil, fmt.Errorf("arrow/flight: could not create flight reader: %w", err)
+	}
+	return rdr, nil
+}
+
+// NewExchangeReaderWriter wraps the server side of a DoExchange stream into
+// the reader of the records and metadata sent by the client and the writer
+// of those sent back, see NewExchangeReader and NewExchangeWriter, opts
+// being passed to both of them. It waits for the first message of the
+// client, whose FlightDescriptor is returned by LatestFlightDescriptor
+// until the first call to Next.
+func NewExchangeReaderWriter(stream FlightService_DoExchangeServer, opts ...ipc.Option) (*Reader, *Writer, error) {
+	data, err := stream.Recv()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rdr, err := NewExchangeReader(stream, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	rdr.dmr.descr = data.FlightDescriptor
+	if len(data.DataHeader) > 0 || len(data.AppMetadata) > 0 {
+		rdr.dmr.peeked = data
+	}
+	return rdr, NewExchangeWriter(stream, opts...), nil
+}
+
 // DeserializeSchema takes the schema bytes from FlightInfo or SchemaResult
 // and returns the deserialized arrow schema.
 func DeserializeSchema(info []byte, mem memory.Allocator) (*arrow.Schema, error) {
@@ -182,7 +400,10 @@ func DeserializeSchema(info []byte, mem memory.Allocator) (*arrow.Schema, error)
 	return rdr.Schema(), nil
 }
 
-// StreamChunk represents a single chunk of a FlightData stream
+// StreamChunk represents a single chunk of a FlightData stream. The
+// chunks sent by the DoGet handlers of a flightsql server may have no
+// Data, their AppMetadata being sent in a message carrying only app
+// metadata, see Writer.WriteMetadata.
 type StreamChunk struct {
 	Data        arrow.Record
 	Desc        *FlightDescriptor
@@ -213,11 +434,37 @@ type haserr interface {
 // If the record reader panics, an error chunk will get sent on the channel.
 //
 // This will close the channel and release the reader when it completes.
+// As it can't stop early, the channel must be drained, see
+// StreamChunksFromReaderCtx otherwise.
 func StreamChunksFromReader(rdr array.RecordReader, ch chan<- StreamChunk) {
+	StreamChunksFromReaderCtx(context.Background(), rdr, ch)
+}
+
+// StreamChunksFromReaderCtx is like StreamChunksFromReader, but stops once
+// ctx is done, so that the consumer can abandon the channel by cancelling
+// ctx. The record which couldn't be sent is then released, as is the
+// reader, and the channel is closed. An error of the reader is sent as
+// the last chunk.
+func StreamChunksFromReaderCtx(ctx context.Context, rdr array.RecordReader, ch chan<- StreamChunk) {
 	defer close(ch)
+
+	send := func(chunk StreamChunk) bool {
+		if ctx.Err() == nil {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+			}
+		}
+		if chunk.Data != nil {
+			chunk.Data.Release()
+		}
+		return false
+	}
+
 	defer func() {
 		if err := recover(); err != nil {
-			ch <- StreamChunk{Err: fmt.Errorf("panic while reading: %s", err)}
+			send(StreamChunk{Err: fmt.Errorf("panic while reading: %s", err)})
 		}
 	}()
 
@@ -225,13 +472,13 @@ func StreamChunksFromReader(rdr array.RecordReader, ch chan<- StreamChunk) {
 	for rdr.Next() {
 		rec := rdr.Record()
 		rec.Retain()
-		ch <- StreamChunk{Data: rec}
+		if !send(StreamChunk{Data: rec}) {
+			return
+		}
 	}
 
-	if e, ok := rdr.(haserr); ok {
-		if e.Err() != nil {
-			ch <- StreamChunk{Err: e.Err()}
-		}
+	if err := rdr.Err(); err != nil {
+		send(StreamChunk{Err: err})
 	}
 }
 
@@ -18,6 +18,7 @@ package flight
 
 import (
 	"bytes"
+	"sync/atomic"
 
 	"github.com/apache/arrow/go/v16/arrow"
 	"github.com/apache/arrow/go/v16/arrow/ipc"
@@ -34,6 +35,10 @@ type flightPayloadWriter struct {
 	w   DataStreamWriter
 	fd  FlightData
 	buf bytes.Buffer
+	// closeSend half-closes the stream of an exchange once done writing.
+	closeSend func() error
+	// messages is the number of FlightData messages sent.
+	messages atomic.Int64
 }
 
 func (f *flightPayloadWriter) Start() error { return nil }
@@ -47,10 +52,23 @@ func (f *flightPayloadWriter) WritePayload(payload ipc.Payload) error {
 	payload.SerializeBody(&f.buf)
 	f.fd.DataBody = f.buf.Bytes()
 
-	return f.w.Send(&f.fd)
+	return f.send(&f.fd)
 }
 
-func (f *flightPayloadWriter) Close() error { return nil }
+func (f *flightPayloadWriter) send(fd *FlightData) error {
+	if err := f.w.Send(fd); err != nil {
+		return err
+	}
+	f.messages.Add(1)
+	return nil
+}
+
+func (f *flightPayloadWriter) Close() error {
+	if f.closeSend != nil {
+		return f.closeSend()
+	}
+	return nil
+}
 
 // Writer is an ipc.Writer which also adds a WriteWithAppMetadata function
 // in order to allow adding AppMetadata to the FlightData messages which
@@ -58,12 +76,41 @@ func (f *flightPayloadWriter) Close() error { return nil }
 type Writer struct {
 	*ipc.Writer
 	pw *flightPayloadWriter
+	// ipcw is the ipc writer once created, for Stats to be called
+	// concurrently with the writes of an exchange.
+	ipcw atomic.Pointer[ipc.Writer]
+
+	// opts are those of the ipc writer of an exchange, which is created
+	// with the schema of the first record written.
+	opts []ipc.Option
 }
 
 // WriteMetadata writes a payload message to the stream containing only
-// the specified app metadata.
+// the specified app metadata, with neither data header nor body, such as
+// a progress update between records. It carries the flight descriptor set
+// with SetFlightDescriptor, if any. Such messages may be written before
+// the first record, the readers receiving them along with the records,
+// see Reader.LatestMetadataMessages.
 func (w *Writer) WriteMetadata(appMetadata []byte) error {
-	return w.pw.w.Send(&FlightData{AppMetadata: appMetadata})
+	fd := &FlightData{FlightDescriptor: w.pw.fd.FlightDescriptor, AppMetadata: appMetadata}
+	w.pw.fd.FlightDescriptor = nil
+	return w.pw.send(fd)
+}
+
+// WriterStats counts what a Writer has written. Its Messages are the
+// FlightData messages sent, including those written with WriteMetadata.
+type WriterStats = ipc.WriterStats
+
+// Stats returns the counts of what the writer has written so far, such
+// as to fill the TotalBytes of a FlightInfo once a result was written. It
+// may be called concurrently with the writes, such as to report metrics.
+func (w *Writer) Stats() WriterStats {
+	var stats WriterStats
+	if iw := w.ipcw.Load(); iw != nil {
+		stats = iw.Stats()
+	}
+	stats.Messages = w.pw.messages.Load()
+	return stats
 }
 
 // SetFlightDescriptor sets the flight descriptor into the next payload that will
@@ -80,9 +127,22 @@ func (w *Writer) Write(rec arrow.Record) error {
 			w.pw.fd.FlightDescriptor = nil
 		}()
 	}
+	if w.Writer == nil {
+		w.Writer = ipc.NewWriterWithPayloadWriter(w.pw, append(w.opts, ipc.WithSchema(rec.Schema()))...)
+		w.ipcw.Store(w.Writer)
+	}
 	return w.Writer.Write(rec)
 }
 
+// Close closes the writer, also half-closing the stream of an exchange
+// written by the client, which can then keep on reading the results.
+func (w *Writer) Close() error {
+	if w.Writer == nil {
+		return w.pw.Close()
+	}
+	return w.Writer.Close()
+}
+
 // WriteWithAppMetadata will write this record with the supplied application
 // metadata attached in the flightData message.
 func (w *Writer) WriteWithAppMetadata(rec arrow.Record, appMeta []byte) error {
@@ -96,10 +156,27 @@ func (w *Writer) WriteWithAppMetadata(rec arrow.Record, appMeta []byte) error {
 // NewRecordWriter can be used to construct a writer for arrow flight via
 // the grpc stream handler to write flight data objects and write
 // record batches to the stream. Options passed here will be passed to
-// ipc.NewWriter
+// ipc.NewWriter, such as ipc.WithDictionaryDeltas to send only the values
+// appended to dictionaries from one record to the next.
 func NewRecordWriter(w DataStreamWriter, opts ...ipc.Option) *Writer {
 	pw := &flightPayloadWriter{w: w}
-	return &Writer{Writer: ipc.NewWriterWithPayloadWriter(pw, opts...), pw: pw}
+	wr := &Writer{Writer: ipc.NewWriterWithPayloadWriter(pw, opts...), pw: pw}
+	wr.ipcw.Store(wr.Writer)
+	return wr
+}
+
+// NewExchangeWriter constructs a writer of the FlightData sent on a
+// DoExchange stream, by either the client or the server. Unlike
+// NewRecordWriter, the schema is that of the first record written, which
+// is sent along with it, so that messages carrying only app metadata can
+// be written with WriteMetadata beforehand. If w can be half-closed, such
+// as the client side of the stream, Close half-closes it.
+func NewExchangeWriter(w DataStreamWriter, opts ...ipc.Option) *Writer {
+	pw := &flightPayloadWriter{w: w}
+	if cs, ok := w.(interface{ CloseSend() error }); ok {
+		pw.closeSend = cs.CloseSend
+	}
+	return &Writer{pw: pw, opts: opts}
 }
 
 // SerializeSchema returns the serialized schema bytes for use in Arrow Flight
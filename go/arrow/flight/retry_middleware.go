@@ -0,0 +1,301 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	flightServicePrefix = "/arrow.flight.protocol.FlightService/"
+
+	// retryPushbackKey is the trailer through which a server tells the
+	// client how long to wait before retrying, in milliseconds. A
+	// negative or malformed value means the call must not be retried.
+	retryPushbackKey = "grpc-retry-pushback-ms"
+)
+
+// RetryPolicy configures the middleware created by NewRetryMiddleware.
+// The zero value retries UNAVAILABLE and RESOURCE_EXHAUSTED errors up to
+// 3 attempts in total, waiting 100ms before the first retry and twice as
+// long before each subsequent one, up to 5s, with 20% jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made for a call,
+	// including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the delay grows by after each retry.
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction of it in
+	// either direction, between 0 and 1. Set it to a negative value to
+	// disable jitter.
+	Jitter float64
+	// RetryableCodes are the status codes for which a call is retried.
+	RetryableCodes []codes.Code
+	// IdempotentActions are the types of the actions which may be
+	// retried by DoAction, such as "CreatePreparedStatement". Other
+	// actions are never retried, since they may have side effects.
+	IdempotentActions []string
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 5 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	} else if p.Jitter < 0 {
+		p.Jitter = 0
+	} else if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	if p.RetryableCodes == nil {
+		p.RetryableCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted}
+	}
+	return p
+}
+
+// NewRetryMiddleware returns client middleware which retries calls
+// failing with one of the policy's retryable codes:
+//
+//   - the unary GetFlightInfo, GetSchema and PollFlightInfo calls;
+//   - DoGet, ListFlights and ListActions, as long as no message has been
+//     received yet, so that no data is ever duplicated;
+//   - DoAction, under the same condition, for the action types listed in
+//     IdempotentActions.
+//
+// Opening any other stream is retried too, since nothing has been sent
+// yet at that point. If the server returns a grpc-retry-pushback-ms
+// trailer, its delay is used instead of the backoff, and a negative one
+// stops the retries.
+func NewRetryMiddleware(policy RetryPolicy) ClientMiddleware {
+	r := &retrier{policy: policy.withDefaults()}
+	return ClientMiddleware{Unary: r.unary, Stream: r.stream}
+}
+
+type retrier struct {
+	policy RetryPolicy
+}
+
+// wait sleeps before retrying after attempt (counting from 1) failed with
+// err, returning false if the call should not be retried.
+func (r *retrier) wait(ctx context.Context, attempt int, err error, trailer metadata.MD) bool {
+	if attempt >= r.policy.MaxAttempts || !r.retryable(err) {
+		return false
+	}
+
+	delay := r.backoff(attempt)
+	if v := trailer.Get(retryPushbackKey); len(v) > 0 {
+		ms, err := strconv.Atoi(v[0])
+		if err != nil || ms < 0 {
+			return false
+		}
+		delay = time.Duration(ms) * time.Millisecond
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (r *retrier) retryable(err error) bool {
+	code := status.Code(err)
+	for _, c := range r.policy.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *retrier) backoff(attempt int) time.Duration {
+	delay := float64(r.policy.InitialBackoff) * math.Pow(r.policy.Multiplier, float64(attempt-1))
+	if max := float64(r.policy.MaxBackoff); delay > max {
+		delay = max
+	}
+	delay *= 1 + r.policy.Jitter*(2*rand.Float64()-1)
+	return time.Duration(delay)
+}
+
+func (r *retrier) unary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	switch strings.TrimPrefix(method, flightServicePrefix) {
+	case "GetFlightInfo", "GetSchema", "PollFlightInfo":
+	default:
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	for attempt := 1; ; attempt++ {
+		var trailer metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+		if err == nil || !r.wait(ctx, attempt, err, trailer) {
+			return err
+		}
+	}
+}
+
+func (r *retrier) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	open := func() (grpc.ClientStream, error) {
+		for attempt := 1; ; attempt++ {
+			cs, err := streamer(ctx, desc, cc, method, opts...)
+			if err == nil || !r.wait(ctx, attempt, err, nil) {
+				return cs, err
+			}
+		}
+	}
+
+	cs, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isServerStreaming(method) {
+		return cs, nil
+	}
+	return &replayingStream{
+		ClientStream: cs,
+		open:         func() (grpc.ClientStream, error) { return streamer(ctx, desc, cc, method, opts...) },
+		replay: func(attempt int, req interface{}, err error, trailer metadata.MD) bool {
+			return r.idempotent(method, req) && r.wait(ctx, attempt, err, trailer)
+		},
+	}, nil
+}
+
+// idempotent reports whether the request of a server streaming call may
+// be sent again.
+func (r *retrier) idempotent(method string, req interface{}) bool {
+	if strings.TrimPrefix(method, flightServicePrefix) != "DoAction" {
+		return true
+	}
+
+	action, ok := req.(*Action)
+	if !ok {
+		return false
+	}
+	for _, typ := range r.policy.IdempotentActions {
+		if typ == action.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// isServerStreaming reports whether method is one of the Flight calls
+// which send a single request and stream back the response.
+func isServerStreaming(method string) bool {
+	switch strings.TrimPrefix(method, flightServicePrefix) {
+	case "DoGet", "ListFlights", "ListActions", "DoAction":
+		return true
+	}
+	return false
+}
+
+// replayingStream is a server streaming call which is opened again if it
+// fails before its first message has been received, resending its
+// request.
+type replayingStream struct {
+	grpc.ClientStream
+
+	open func() (grpc.ClientStream, error)
+	// replay is called after the attempt, counting from 1, failed with
+	// err and reports whether req should be sent again. trailer is nil
+	// if the stream could not be opened at all.
+	replay func(attempt int, req interface{}, err error, trailer metadata.MD) bool
+
+	req        interface{}
+	closedSend bool
+	received   bool
+}
+
+func (s *replayingStream) SendMsg(m interface{}) error {
+	s.req = m
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *replayingStream) CloseSend() error {
+	s.closedSend = true
+	return s.ClientStream.CloseSend()
+}
+
+func (s *replayingStream) RecvMsg(m interface{}) error {
+	err, opened := s.ClientStream.RecvMsg(m), true
+	for attempt := 1; err != nil && err != io.EOF && s.canReplay(); attempt++ {
+		var trailer metadata.MD
+		if opened {
+			trailer = s.ClientStream.Trailer()
+		}
+		if !s.replay(attempt, s.req, err, trailer) {
+			return err
+		}
+		opened, err = s.reopen(m)
+	}
+	if err == nil {
+		s.received = true
+	}
+	return err
+}
+
+func (s *replayingStream) canReplay() bool {
+	return !s.received && s.req != nil && s.closedSend
+}
+
+// reopen replaces the failed stream with a new one sending the same
+// request, and receives its first message into m. opened reports
+// whether the new stream could be opened at all.
+func (s *replayingStream) reopen(m interface{}) (opened bool, err error) {
+	cs, err := s.open()
+	if err != nil {
+		return false, err
+	}
+	s.ClientStream = cs
+	// a failure to send shows up as io.EOF, with the actual error
+	// returned by RecvMsg
+	if err := cs.SendMsg(s.req); err != nil && err != io.EOF {
+		return true, err
+	}
+	if err := cs.CloseSend(); err != nil {
+		return true, err
+	}
+	return true, cs.RecvMsg(m)
+}
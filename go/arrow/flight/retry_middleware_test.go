@@ -0,0 +1,182 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// flakyServer fails the first two attempts of each call with
+// UNAVAILABLE, keyed by the method and its request.
+type flakyServer struct {
+	flight.BaseFlightServer
+
+	mx       sync.Mutex
+	attempts map[string]int
+}
+
+func (f *flakyServer) attempt(key string) int {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	f.attempts[key]++
+	return f.attempts[key]
+}
+
+func (f *flakyServer) count(key string) int {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	return f.attempts[key]
+}
+
+var errFlaky = status.Error(codes.Unavailable, "try again")
+
+func (f *flakyServer) GetFlightInfo(ctx context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	key := "GetFlightInfo/" + string(desc.Cmd)
+	n := f.attempt(key)
+	switch string(desc.Cmd) {
+	case "pushback":
+		grpc.SetTrailer(ctx, metadata.Pairs("grpc-retry-pushback-ms", "-1"))
+		return nil, errFlaky
+	case "invalid":
+		return nil, status.Error(codes.InvalidArgument, "not retryable")
+	}
+	if n <= 2 {
+		return nil, errFlaky
+	}
+	return &flight.FlightInfo{FlightDescriptor: desc}, nil
+}
+
+func (f *flakyServer) DoGet(tkt *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	n := f.attempt("DoGet/" + string(tkt.Ticket))
+	if string(tkt.Ticket) == "midstream" {
+		if err := stream.Send(&flight.FlightData{DataBody: []byte("first")}); err != nil {
+			return err
+		}
+		return errFlaky
+	}
+	if n <= 2 {
+		return errFlaky
+	}
+	return stream.Send(&flight.FlightData{DataBody: tkt.Ticket})
+}
+
+func (f *flakyServer) DoAction(action *flight.Action, stream flight.FlightService_DoActionServer) error {
+	if f.attempt("DoAction/"+action.Type) <= 2 {
+		return errFlaky
+	}
+	return stream.Send(&flight.Result{Body: []byte(action.Type)})
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	srv := &flakyServer{attempts: make(map[string]int)}
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(srv)
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil,
+		[]flight.ClientMiddleware{flight.NewRetryMiddleware(flight.RetryPolicy{
+			InitialBackoff:    time.Millisecond,
+			IdempotentActions: []string{"idempotent"},
+		})}, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := context.Background()
+
+	t.Run("GetFlightInfo", func(t *testing.T) {
+		info, err := client.GetFlightInfo(ctx, &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("query")})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("query"), info.FlightDescriptor.Cmd)
+		assert.Equal(t, 3, srv.count("GetFlightInfo/query"))
+
+		_, err = client.GetFlightInfo(ctx, &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("invalid")})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, 1, srv.count("GetFlightInfo/invalid"))
+	})
+
+	t.Run("pushback", func(t *testing.T) {
+		_, err := client.GetFlightInfo(ctx, &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("pushback")})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 1, srv.count("GetFlightInfo/pushback"))
+	})
+
+	t.Run("DoGet", func(t *testing.T) {
+		stream, err := client.DoGet(ctx, &flight.Ticket{Ticket: []byte("data")})
+		require.NoError(t, err)
+		data, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, []byte("data"), data.DataBody)
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, 3, srv.count("DoGet/data"))
+	})
+
+	t.Run("DoGet after the first message", func(t *testing.T) {
+		stream, err := client.DoGet(ctx, &flight.Ticket{Ticket: []byte("midstream")})
+		require.NoError(t, err)
+		data, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, []byte("first"), data.DataBody)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 1, srv.count("DoGet/midstream"))
+	})
+
+	t.Run("DoAction", func(t *testing.T) {
+		stream, err := client.DoAction(ctx, &flight.Action{Type: "idempotent"})
+		require.NoError(t, err)
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, []byte("idempotent"), res.Body)
+		assert.Equal(t, 3, srv.count("DoAction/idempotent"))
+
+		stream, err = client.DoAction(ctx, &flight.Action{Type: "other"})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 1, srv.count("DoAction/other"))
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil,
+			[]flight.ClientMiddleware{flight.NewRetryMiddleware(flight.RetryPolicy{
+				MaxAttempts:    2,
+				InitialBackoff: time.Millisecond,
+			})}, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer client.Close()
+
+		_, err = client.GetFlightInfo(ctx, &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("exhausted")})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 2, srv.count("GetFlightInfo/exhausted"))
+	})
+}
@@ -18,52 +18,59 @@ package flight
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 
 	"github.com/apache/arrow/go/v16/arrow/flight/gen/flight"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/status"
 )
 
 type (
-	FlightServer                    = flight.FlightServiceServer
-	FlightService_HandshakeServer   = flight.FlightService_HandshakeServer
-	HandshakeResponse               = flight.HandshakeResponse
-	HandshakeRequest                = flight.HandshakeRequest
-	FlightService_ListFlightsServer = flight.FlightService_ListFlightsServer
-	FlightService_DoGetServer       = flight.FlightService_DoGetServer
-	FlightService_DoPutServer       = flight.FlightService_DoPutServer
-	FlightService_DoExchangeServer  = flight.FlightService_DoExchangeServer
-	FlightService_DoActionServer    = flight.FlightService_DoActionServer
-	FlightService_ListActionsServer = flight.FlightService_ListActionsServer
-	Criteria                        = flight.Criteria
-	FlightDescriptor                = flight.FlightDescriptor
-	FlightEndpoint                  = flight.FlightEndpoint
-	Location                        = flight.Location
-	FlightInfo                      = flight.FlightInfo
-	PollInfo                        = flight.PollInfo
-	FlightData                      = flight.FlightData
-	PutResult                       = flight.PutResult
-	Ticket                          = flight.Ticket
-	SchemaResult                    = flight.SchemaResult
-	Action                          = flight.Action
-	ActionType                      = flight.ActionType
-	CancelFlightInfoRequest         = flight.CancelFlightInfoRequest
-	RenewFlightEndpointRequest      = flight.RenewFlightEndpointRequest
-	Result                          = flight.Result
-	CancelFlightInfoResult          = flight.CancelFlightInfoResult
-	CancelStatus                    = flight.CancelStatus
-	SessionOptionValue              = flight.SessionOptionValue
-	SetSessionOptionsRequest        = flight.SetSessionOptionsRequest
-	SetSessionOptionsResult         = flight.SetSessionOptionsResult
-	SetSessionOptionsResultError    = flight.SetSessionOptionsResult_Error
-	GetSessionOptionsRequest        = flight.GetSessionOptionsRequest
-	GetSessionOptionsResult         = flight.GetSessionOptionsResult
-	CloseSessionRequest             = flight.CloseSessionRequest
-	CloseSessionResult              = flight.CloseSessionResult
-	Empty                           = flight.Empty
+	FlightServer                      = flight.FlightServiceServer
+	FlightService_HandshakeServer     = flight.FlightService_HandshakeServer
+	HandshakeResponse                 = flight.HandshakeResponse
+	HandshakeRequest                  = flight.HandshakeRequest
+	FlightService_ListFlightsServer   = flight.FlightService_ListFlightsServer
+	FlightService_DoGetServer         = flight.FlightService_DoGetServer
+	FlightService_DoPutServer         = flight.FlightService_DoPutServer
+	FlightService_DoExchangeServer    = flight.FlightService_DoExchangeServer
+	FlightService_DoActionServer      = flight.FlightService_DoActionServer
+	FlightService_ListActionsServer   = flight.FlightService_ListActionsServer
+	Criteria                          = flight.Criteria
+	FlightDescriptor                  = flight.FlightDescriptor
+	FlightEndpoint                    = flight.FlightEndpoint
+	Location                          = flight.Location
+	FlightInfo                        = flight.FlightInfo
+	PollInfo                          = flight.PollInfo
+	FlightData                        = flight.FlightData
+	PutResult                         = flight.PutResult
+	Ticket                            = flight.Ticket
+	SchemaResult                      = flight.SchemaResult
+	Action                            = flight.Action
+	ActionType                        = flight.ActionType
+	CancelFlightInfoRequest           = flight.CancelFlightInfoRequest
+	RenewFlightEndpointRequest        = flight.RenewFlightEndpointRequest
+	Result                            = flight.Result
+	CancelFlightInfoResult            = flight.CancelFlightInfoResult
+	CancelStatus                      = flight.CancelStatus
+	SessionOptionValue                = flight.SessionOptionValue
+	SetSessionOptionsRequest          = flight.SetSessionOptionsRequest
+	SetSessionOptionsResult           = flight.SetSessionOptionsResult
+	SetSessionOptionsResultError      = flight.SetSessionOptionsResult_Error
+	SetSessionOptionsResultErrorValue = flight.SetSessionOptionsResult_ErrorValue
+	GetSessionOptionsRequest          = flight.GetSessionOptionsRequest
+	GetSessionOptionsResult           = flight.GetSessionOptionsResult
+	CloseSessionRequest               = flight.CloseSessionRequest
+	CloseSessionResult                = flight.CloseSessionResult
+	Empty                             = flight.Empty
 )
 
 // Constants for Action types
@@ -139,6 +146,26 @@ func NewSessionOptionValue(value any) (flight.SessionOptionValue, error) {
 	}
 }
 
+// SessionOptionValueToAny is the inverse of NewSessionOptionValue: it returns
+// the string, bool, int64, float64 or []string held by value, or nil if it
+// holds none.
+func SessionOptionValueToAny(value *flight.SessionOptionValue) any {
+	switch val := value.GetOptionValue().(type) {
+	case *flight.SessionOptionValue_StringValue:
+		return val.StringValue
+	case *flight.SessionOptionValue_BoolValue:
+		return val.BoolValue
+	case *flight.SessionOptionValue_Int64Value:
+		return val.Int64Value
+	case *flight.SessionOptionValue_DoubleValue:
+		return val.DoubleValue
+	case *flight.SessionOptionValue_StringListValue_:
+		return val.StringListValue.GetValues()
+	default:
+		return nil
+	}
+}
+
 // Constants for CancelStatus
 const (
 	// The cancellation status is unknown. Servers should avoid
@@ -203,6 +230,11 @@ type Server interface {
 	// whichever was called last is what will be used as they both set a listener
 	// into the server.
 	InitListener(lis net.Listener)
+	// InitUnix creates the listener on the unix socket at path, replacing the
+	// socket file left there by a server which didn't shut down, and fails if
+	// a server still listens on it. The socket file is removed on Shutdown.
+	// Clients connect to it with the location of NewLocationUnix.
+	InitUnix(path string) error
 	// Addr will return the address that was bound to for the service to listen on
 	Addr() net.Addr
 	// SetShutdownOnSignals sets notifications on the given signals to call GracefulStop
@@ -215,8 +247,25 @@ type Server interface {
 	// Shutdown will call GracefulStop on the grpc server so that it stops accepting connections
 	// and will wait until current methods complete
 	Shutdown()
+	// GracefulShutdown stops accepting new RPCs and cancels the contexts
+	// of the active streaming RPCs, such as DoGet, DoPut and DoExchange,
+	// so that their handlers stop, the clients receiving an Unavailable
+	// error unless the handler returns one with details, see
+	// ErrorWithDetails. It then waits for the RPCs to finish until ctx is done, at
+	// which point the remaining streams are closed, and returns the
+	// number of streams which had to be closed that way.
+	//
+	// The health service of WithHealthServer reports NOT_SERVING from the
+	// start, and keeps answering the health checks until the streams
+	// finished, only the RPCs of the Flight service and new streams being
+	// rejected in the meantime.
+	//
+	// The shutdown is only done once: calling GracefulShutdown again, even
+	// concurrently, waits for it to be done and returns the same number.
+	GracefulShutdown(ctx context.Context) int
 	// RegisterFlightService sets up the handler for the Flight Endpoints as per
-	// normal Grpc setups
+	// normal Grpc setups. If the handler is a ServerOptionsProvider, it must be
+	// registered before the server is otherwise used.
 	RegisterFlightService(FlightServer)
 	// ServiceRegistrar wraps a single method that supports service registration.
 	// For example, it may be used to register health check provided by grpc-go.
@@ -292,16 +341,70 @@ type ServerMiddleware struct {
 	Unary  grpc.UnaryServerInterceptor
 }
 
+// DefaultMaxRecvMsgSize is the maximum size of the messages received by
+// the servers of NewServerWithMiddleware and NewFlightServer unless
+// grpc.MaxRecvMsgSize is passed, larger than the 4MB gRPC default so that
+// they accept record batches of usual sizes. The size of the messages
+// sent isn't limited by default.
+const DefaultMaxRecvMsgSize = 64 << 20
+
+// ServerOptionsProvider is implemented by the FlightServers which need
+// options of the gRPC server they are registered on, such as those of
+// flightsql.WithGrpcServerOptions. Server.RegisterFlightService creates
+// the gRPC server with them, after those of the constructor, so that they
+// override them.
+type ServerOptionsProvider interface {
+	GrpcServerOptions() []grpc.ServerOption
+}
+
 type server struct {
 	lis        net.Listener
 	sigChannel <-chan os.Signal
 	done       chan bool
 
+	// server is created on first use with opts, so that the options of
+	// the Flight service registered can be added
+	mu     sync.Mutex
+	opts   []grpc.ServerOption
 	server *grpc.Server
+
+	// streams holds the active streaming RPCs, cancelled on
+	// GracefulShutdown, after which draining rejects new ones and drained
+	// is closed once the last one finished
+	streamsMu sync.Mutex
+	streams   map[*activeStream]struct{}
+	draining  bool
+	drained   chan struct{}
+
+	// shutdown runs GracefulShutdown once, the calls sharing the number
+	// of streams it closed
+	shutdown       sync.Once
+	shutdownForced int
+
+	// health, reflection and hooks are set by the options of the
+	// constructor, see serverOption
+	health     *health.Server
+	reflection bool
+	hooks      []func(*grpc.Server)
+}
+
+type activeStream struct {
+	cancel context.CancelCauseFunc
+}
+
+// errShuttingDown is the cause of the cancellation of the contexts of
+// the streams by GracefulShutdown.
+var errShuttingDown = status.Error(codes.Unavailable, "arrow/flight: server is shutting down")
+
+// defaultServerOptions returns the options of the gRPC servers of flight
+// servers, which those passed to the constructors override.
+func defaultServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{grpc.MaxRecvMsgSize(DefaultMaxRecvMsgSize)}
 }
 
 // NewFlightServer takes any grpc Server options desired, such as TLS certs and so
-// on which will just be passed through to the underlying grpc server.
+// on which will just be passed through to the underlying grpc server. They
+// override the defaults of flight servers, see DefaultMaxRecvMsgSize.
 //
 // Alternatively, a grpc server can be created normally without this helper as the
 // grpc server generated code is still being exported. This only exists to allow
@@ -310,21 +413,21 @@ type server struct {
 // Deprecated: prefer to use NewServerWithMiddleware, due to auth handler middleware
 // this function will be problematic if any of the grpc options specify other middleware.
 func NewFlightServer(opt ...grpc.ServerOption) Server {
-	opt = append([]grpc.ServerOption{
+	opt = append(append(defaultServerOptions(),
 		grpc.ChainStreamInterceptor(serverAuthStreamInterceptor),
 		grpc.ChainUnaryInterceptor(serverAuthUnaryInterceptor),
-	}, opt...)
+	), opt...)
 
-	return &server{
-		server: grpc.NewServer(opt...),
-	}
+	return newServer(opt)
 }
 
 // NewServerWithMiddleware takes a slice of middleware which will be used
 // by grpc and chained, the first middleware will be the outer most with the last
 // middleware being the inner most wrapper around the actual call. It also takes
-// any grpc Server options desired, such as TLS certs and so on which will just
-// be passed through to the underlying grpc server.
+// any grpc Server options desired, such as TLS certs, message size limits
+// or keepalive enforcement, which will just be passed through to the
+// underlying grpc server, overriding the defaults of flight servers such as
+// DefaultMaxRecvMsgSize.
 //
 // Because of the usage of `ChainStreamInterceptor` and `ChainUnaryInterceptor` do
 // not specify any middleware using the grpc options, use the ServerMiddleware slice
@@ -350,9 +453,33 @@ func NewServerWithMiddleware(middleware []ServerMiddleware, opts ...grpc.ServerO
 			}
 		}
 	}
-	opts = append(opts, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	opts = append(append(defaultServerOptions(), opts...),
+		grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
 
-	return &server{server: grpc.NewServer(opts...)}
+	return newServer(opts)
+}
+
+// grpcServer returns the gRPC server, creating it with the options of the
+// constructor followed by extra if it wasn't used yet. It panics if extra
+// options are passed once the server was created, as they can't be
+// applied.
+func (s *server) grpcServer(extra ...grpc.ServerOption) *grpc.Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server == nil {
+		// the streams are tracked by the outermost interceptor, so that
+		// the middleware also sees their contexts cancelled on
+		// GracefulShutdown
+		opts := append([]grpc.ServerOption{
+			grpc.ChainStreamInterceptor(s.trackStream),
+			grpc.ChainUnaryInterceptor(s.rejectWhileDraining),
+		}, s.opts...)
+		s.server = grpc.NewServer(append(opts, extra...)...)
+		s.registerServices(s.server)
+	} else if len(extra) > 0 {
+		panic("arrow/flight: the Flight service with gRPC server options must be registered before the server is used")
+	}
+	return s.server
 }
 
 func (s *server) Init(addr string) (err error) {
@@ -364,6 +491,25 @@ func (s *server) InitListener(lis net.Listener) {
 	s.lis = lis
 }
 
+func (s *server) InitUnix(path string) error {
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return fmt.Errorf("arrow/flight: unix socket %s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	s.lis = lis
+	return nil
+}
+
 func (s *server) Addr() net.Addr {
 	return s.lis.Addr()
 }
@@ -375,31 +521,150 @@ func (s *server) SetShutdownOnSignals(sig ...os.Signal) {
 }
 
 func (s *server) Serve() error {
+	gs := s.grpcServer()
 	s.done = make(chan bool)
 	go func() {
 		select {
 		case <-s.sigChannel:
-			s.server.GracefulStop()
+			gs.GracefulStop()
 		case <-s.done:
 		}
 	}()
-	err := s.server.Serve(s.lis)
+	err := gs.Serve(s.lis)
 	close(s.done)
 	return err
 }
 
 func (s *server) RegisterFlightService(svc FlightServer) {
-	flight.RegisterFlightServiceServer(s.server, svc)
+	var opts []grpc.ServerOption
+	if p, ok := svc.(ServerOptionsProvider); ok {
+		opts = p.GrpcServerOptions()
+	}
+	flight.RegisterFlightServiceServer(s.grpcServer(opts...), svc)
 }
 
 func (s *server) Shutdown() {
-	s.server.GracefulStop()
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+	s.grpcServer().GracefulStop()
+	// the listener is only closed by GracefulStop once served, and closing
+	// a unix listener removes its socket file
+	if s.lis != nil {
+		s.lis.Close()
+	}
+}
+
+func (s *server) GracefulShutdown(ctx context.Context) int {
+	s.shutdown.Do(func() { s.shutdownForced = s.gracefulShutdown(ctx) })
+	return s.shutdownForced
+}
+
+func (s *server) gracefulShutdown(ctx context.Context) int {
+	gs := s.grpcServer()
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+
+	s.streamsMu.Lock()
+	s.draining = true
+	drained := make(chan struct{})
+	if len(s.streams) == 0 {
+		close(drained)
+	}
+	s.drained = drained
+	for st := range s.streams {
+		st.cancel(errShuttingDown)
+	}
+	s.streamsMu.Unlock()
+
+	// the gRPC server keeps serving the RPCs of other services, such as
+	// health checks, until the streams finished
+	forced := 0
+	select {
+	case <-drained:
+		stopped := make(chan struct{})
+		go func() {
+			gs.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			// only unary RPCs remain, and Stop may have to wait for
+			// GracefulStop to be done with them
+			go gs.Stop()
+		}
+	case <-ctx.Done():
+		s.streamsMu.Lock()
+		forced = len(s.streams)
+		s.streamsMu.Unlock()
+		// the handlers of the streams which are closed may still be
+		// running, which Stop doesn't wait for
+		gs.Stop()
+	}
+
+	if s.lis != nil {
+		s.lis.Close()
+	}
+	return forced
+}
+
+// trackStream records the stream as active while its handler runs, with
+// a context cancelled by GracefulShutdown, the handler's error then
+// being replaced by errShuttingDown unless it has details, see
+// ErrorWithDetails.
+func (s *server) trackStream(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, cancel := context.WithCancelCause(stream.Context())
+	defer cancel(nil)
+
+	st := &activeStream{cancel: cancel}
+	s.streamsMu.Lock()
+	if s.draining {
+		s.streamsMu.Unlock()
+		return errShuttingDown
+	}
+	if s.streams == nil {
+		s.streams = make(map[*activeStream]struct{})
+	}
+	s.streams[st] = struct{}{}
+	s.streamsMu.Unlock()
+
+	defer func() {
+		s.streamsMu.Lock()
+		delete(s.streams, st)
+		if s.draining && len(s.streams) == 0 {
+			close(s.drained)
+		}
+		s.streamsMu.Unlock()
+	}()
+
+	err := handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+	if err != nil && errors.Is(context.Cause(ctx), errShuttingDown) && !hasErrorDetails(err) {
+		return errShuttingDown
+	}
+	return err
+}
+
+// rejectWhileDraining rejects the unary RPCs of the Flight service once
+// GracefulShutdown started, those of other services being served until
+// the gRPC server stops.
+func (s *server) rejectWhileDraining(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if strings.HasPrefix(info.FullMethod, "/"+flight.FlightService_ServiceDesc.ServiceName+"/") {
+		s.streamsMu.Lock()
+		draining := s.draining
+		s.streamsMu.Unlock()
+		if draining {
+			return nil, errShuttingDown
+		}
+	}
+	return handler(ctx, req)
 }
 
 func (s *server) RegisterService(sd *grpc.ServiceDesc, ss interface{}) {
-	s.server.RegisterService(sd, ss)
+	s.grpcServer().RegisterService(sd, ss)
 }
 
 func (s *server) GetServiceInfo() map[string]grpc.ServiceInfo {
-	return s.server.GetServiceInfo()
+	return s.grpcServer().GetServiceInfo()
 }
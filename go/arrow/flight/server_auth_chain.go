@@ -0,0 +1,216 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNoCredentials is returned by an Authenticator for the calls which
+// don't carry credentials it handles, so that the next authenticator of
+// the chain is tried.
+var ErrNoCredentials = errors.New("arrow/flight: no credentials")
+
+// Authenticator authenticates the calls of a flight server as one of the
+// chain of CreateServerAuthMiddleware. Both methods return ErrNoCredentials
+// for the calls without credentials they handle, any other error
+// rejecting the call.
+type Authenticator interface {
+	// Handshake authenticates a Handshake call from its incoming metadata,
+	// returning the metadata to send back in its trailer, such as the
+	// bearer token to authenticate the following calls with.
+	Handshake(ctx context.Context) (metadata.MD, error)
+	// Authenticate authenticates any other call from its incoming metadata
+	// or peer, returning the identity of the caller which the handlers
+	// retrieve with AuthFromContext.
+	Authenticate(ctx context.Context) (interface{}, error)
+}
+
+// CreateServerAuthMiddleware returns a ServerMiddleware which can be passed
+// to NewServerWithMiddleware to authenticate each call with the first of
+// authenticators which handles its credentials, so that clients can use any
+// of several schemes, such as basic credentials exchanged for a token and
+// static API keys. The calls which none of them handles, or which the one
+// handling them rejects, fail with UNAUTHENTICATED unless the error already
+// has a gRPC status.
+func CreateServerAuthMiddleware(authenticators ...Authenticator) ServerMiddleware {
+	authenticate := func(ctx context.Context) (context.Context, error) {
+		for _, a := range authenticators {
+			identity, err := a.Authenticate(ctx)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err != nil {
+				return nil, authError(err)
+			}
+			return context.WithValue(ctx, authCtxKey{}, identity), nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "no valid credentials")
+	}
+
+	return ServerMiddleware{
+		Unary: func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := authenticate(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		},
+		Stream: func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if strings.TrimPrefix(info.FullMethod, flightServicePrefix) != "Handshake" {
+				ctx, err := authenticate(stream.Context())
+				if err != nil {
+					return err
+				}
+				return handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+			}
+
+			for _, a := range authenticators {
+				md, err := a.Handshake(stream.Context())
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					return authError(err)
+				}
+				stream.SetTrailer(md)
+				return handler(srv, stream)
+			}
+			return status.Error(codes.Unauthenticated, "no valid credentials")
+		},
+	}
+}
+
+// authError returns err as an UNAUTHENTICATED status unless it already
+// has a status.
+func authError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Errorf(codes.Unauthenticated, "auth-error: %s", err)
+}
+
+// incomingHeader returns the first value of the incoming metadata key of
+// ctx, if any.
+func incomingHeader(ctx context.Context, key string) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	vals := md.Get(key)
+	if len(vals) == 0 {
+		return "", false
+	}
+	return vals[0], true
+}
+
+type basicTokenAuthenticator struct {
+	validator BasicAuthValidator
+}
+
+// NewBasicTokenAuthenticator returns an Authenticator which exchanges the
+// basic credentials sent to Handshake in the authorization header for a
+// bearer token, as CreateServerBasicAuthMiddleware does, and authenticates
+// the other calls with that token. validator cannot be nil.
+func NewBasicTokenAuthenticator(validator BasicAuthValidator) Authenticator {
+	if validator == nil {
+		panic("validator cannot be nil")
+	}
+	return &basicTokenAuthenticator{validator: validator}
+}
+
+func (a *basicTokenAuthenticator) Handshake(ctx context.Context) (metadata.MD, error) {
+	auth, _ := incomingHeader(ctx, basicAuthHeader)
+	scheme, creds, _ := strings.Cut(auth, " ")
+	if scheme != basicAuthPrefix {
+		return nil, ErrNoCredentials
+	}
+
+	val, err := base64.RawStdEncoding.DecodeString(creds)
+	if err != nil {
+		if val, err = base64.StdEncoding.DecodeString(creds); err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid basic auth encoding: %s", err)
+		}
+	}
+
+	username, password, _ := strings.Cut(string(val), ":")
+	token, err := a.validator.Validate(username, password)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Pairs(basicAuthHeader, bearerTokenPrefix+" "+token), nil
+}
+
+func (a *basicTokenAuthenticator) Authenticate(ctx context.Context) (interface{}, error) {
+	auth, _ := incomingHeader(ctx, basicAuthHeader)
+	scheme, token, _ := strings.Cut(auth, " ")
+	if scheme != bearerTokenPrefix {
+		return nil, ErrNoCredentials
+	}
+	return a.validator.IsValid(token)
+}
+
+type apiKeyAuthenticator struct {
+	header string
+	keys   map[string]interface{}
+}
+
+// NewAPIKeyAuthenticator returns an Authenticator of the calls sending one
+// of the pre-issued API keys of keys in the incoming metadata header, such
+// as "x-api-key", the identity of the call being the value of the key.
+// Handshake isn't needed with API keys, and fails with them.
+func NewAPIKeyAuthenticator(header string, keys map[string]interface{}) Authenticator {
+	return &apiKeyAuthenticator{header: strings.ToLower(header), keys: keys}
+}
+
+func (a *apiKeyAuthenticator) Handshake(ctx context.Context) (metadata.MD, error) {
+	if _, ok := incomingHeader(ctx, a.header); ok {
+		return nil, status.Error(codes.Unauthenticated, "handshake is not supported with API keys")
+	}
+	return nil, ErrNoCredentials
+}
+
+func (a *apiKeyAuthenticator) Authenticate(ctx context.Context) (interface{}, error) {
+	key, ok := incomingHeader(ctx, a.header)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	identity, ok := a.keys[key]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	return identity, nil
+}
+
+// AuthenticatorFunc is an Authenticator calling the function to
+// authenticate the calls other than Handshake, such as by checking the
+// client certificates of mTLS connections from peer.FromContext. Handshake
+// calls are left to the other authenticators of the chain.
+type AuthenticatorFunc func(ctx context.Context) (interface{}, error)
+
+func (AuthenticatorFunc) Handshake(context.Context) (metadata.MD, error) {
+	return nil, ErrNoCredentials
+}
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context) (interface{}, error) {
+	return f(ctx)
+}
@@ -0,0 +1,86 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// serverOption is a grpc.ServerOption configuring the flight server
+// built by NewServerWithMiddleware or NewFlightServer rather than its
+// gRPC server, which ignores it.
+type serverOption struct {
+	grpc.EmptyServerOption
+	apply func(*server)
+}
+
+// WithHealthServer registers hs as the grpc.health.v1.Health service of
+// the flight server, such as for the probes of Kubernetes. Its serving
+// status is set with hs.SetServingStatus, and set to NOT_SERVING by
+// Shutdown and GracefulShutdown, see Server.GracefulShutdown.
+//
+//	hs := health.NewServer()
+//	s := flight.NewServerWithMiddleware(nil, flight.WithHealthServer(hs))
+//	...
+//	hs.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+func WithHealthServer(hs *health.Server) grpc.ServerOption {
+	return serverOption{apply: func(s *server) { s.health = hs }}
+}
+
+// WithReflection registers the gRPC server reflection service, so that
+// tools such as grpcurl can list and call the services of the flight
+// server.
+func WithReflection() grpc.ServerOption {
+	return serverOption{apply: func(s *server) { s.reflection = true }}
+}
+
+// WithGrpcServerHook calls hook with the gRPC server of the flight server
+// once it is created, such as to register other services or inspect it,
+// the flight server still managing its lifecycle. The hook must not
+// start nor stop it.
+func WithGrpcServerHook(hook func(*grpc.Server)) grpc.ServerOption {
+	return serverOption{apply: func(s *server) { s.hooks = append(s.hooks, hook) }}
+}
+
+// newServer returns a flight server whose gRPC server is created with
+// opts, once the serverOptions among them are applied.
+func newServer(opts []grpc.ServerOption) *server {
+	s := &server{opts: opts}
+	for _, o := range opts {
+		if o, ok := o.(serverOption); ok {
+			o.apply(s)
+		}
+	}
+	return s
+}
+
+// registerServices registers the services of the options on gs, the gRPC
+// server just created, and calls the hooks.
+func (s *server) registerServices(gs *grpc.Server) {
+	if s.health != nil {
+		grpc_health_v1.RegisterHealthServer(gs, s.health)
+	}
+	if s.reflection {
+		reflection.Register(gs)
+	}
+	for _, hook := range s.hooks {
+		hook(gs)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -78,3 +79,22 @@ func CreateCookieForSession(session ServerSession) (http.Cookie, error) {
 
 	return *cookie, nil
 }
+
+// SetOutgoingCookie sends a Set-Cookie for cookie to the client of the RPC
+// handled by ctx, such as an affinity cookie pinning the client's session
+// to this server. It is sent with the response headers if they haven't
+// been sent yet, otherwise with the trailer.
+//
+// Setting cookie.MaxAge to a negative value tells the client to delete a
+// cookie it already has.
+func SetOutgoingCookie(ctx context.Context, cookie http.Cookie) error {
+	if err := cookie.Valid(); err != nil {
+		return err
+	}
+
+	md := metadata.Pairs("Set-Cookie", cookie.String())
+	if err := grpc.SetHeader(ctx, md); err == nil {
+		return nil
+	}
+	return grpc.SetTrailer(ctx, md)
+}
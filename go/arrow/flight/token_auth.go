@@ -0,0 +1,302 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NewBearerTokenMiddleware returns client middleware which authenticates
+// every call with a bearer token, acquired through a Handshake using the
+// basic credentials username and password.
+//
+// The token is acquired by the first call and shared by all of them. When
+// a call fails with UNAUTHENTICATED, such as because the token expired, a
+// new token is acquired, by a single Handshake however many calls failed
+// at once, and the call is retried once with it. Unary calls are retried,
+// as are DoGet, DoAction, ListFlights and ListActions provided no message
+// had been received yet; DoPut and DoExchange are not.
+func NewBearerTokenMiddleware(username, password string) ClientMiddleware {
+	return newTokenAuth(&tokenAuth{username: username, password: password})
+}
+
+// NewStaticBearerTokenMiddleware returns client middleware which
+// authenticates every call with the bearer token token, such as one
+// obtained out of band. Calls are not retried.
+func NewStaticBearerTokenMiddleware(token string) ClientMiddleware {
+	return newTokenAuth(&tokenAuth{static: true, header: bearerTokenPrefix + " " + token})
+}
+
+func newTokenAuth(a *tokenAuth) ClientMiddleware {
+	return ClientMiddleware{Unary: a.unary, Stream: a.stream}
+}
+
+// tokenAuth caches the authorization header sent by the bearer token
+// middleware. gen counts the headers acquired, so that calls which failed
+// with a header which has since been replaced don't acquire another one.
+type tokenAuth struct {
+	username, password string
+	static             bool
+
+	mu         sync.Mutex
+	header     string
+	gen        int
+	err        error
+	refreshing chan struct{}
+}
+
+// current returns the authorization header to send and its generation,
+// acquiring one if there is none yet.
+func (a *tokenAuth) current(ctx context.Context, cc *grpc.ClientConn) (string, int, error) {
+	a.mu.Lock()
+	header, gen := a.header, a.gen
+	a.mu.Unlock()
+	if header != "" {
+		return header, gen, nil
+	}
+	return a.refresh(ctx, cc, gen)
+}
+
+// refresh acquires a new header to replace the one of generation stale,
+// unless it has already been replaced or another call is replacing it.
+func (a *tokenAuth) refresh(ctx context.Context, cc *grpc.ClientConn, stale int) (string, int, error) {
+	a.mu.Lock()
+	for {
+		if a.gen != stale && a.header != "" {
+			defer a.mu.Unlock()
+			return a.header, a.gen, nil
+		}
+		if a.refreshing == nil {
+			break
+		}
+
+		done := a.refreshing
+		a.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return "", 0, ctx.Err()
+		}
+		a.mu.Lock()
+		if a.err != nil && a.gen == stale {
+			defer a.mu.Unlock()
+			return "", 0, a.err
+		}
+	}
+
+	done := make(chan struct{})
+	a.refreshing = done
+	a.mu.Unlock()
+
+	header, err := basicAuthHandshake(withoutAuthorization(ctx), NewFlightServiceClient(cc), a.username, a.password)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.refreshing = nil
+	close(done)
+	if a.err = err; err != nil {
+		return "", 0, err
+	}
+	a.header = header
+	a.gen++
+	return a.header, a.gen, nil
+}
+
+// retry reports whether a call which failed with err using the header of
+// generation gen should be retried, acquiring a new header if so.
+func (a *tokenAuth) retry(ctx context.Context, cc *grpc.ClientConn, gen int, err error) bool {
+	if a.static || status.Code(err) != codes.Unauthenticated {
+		return false
+	}
+	_, _, err = a.refresh(ctx, cc, gen)
+	return err == nil
+}
+
+func (a *tokenAuth) unary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	header, gen, err := a.current(ctx, cc)
+	if err != nil {
+		return err
+	}
+
+	err = invoker(withAuthorization(ctx, header), method, req, reply, cc, opts...)
+	if err == nil || !a.retry(ctx, cc, gen, err) {
+		return err
+	}
+
+	if header, _, err = a.current(ctx, cc); err != nil {
+		return err
+	}
+	return invoker(withAuthorization(ctx, header), method, req, reply, cc, opts...)
+}
+
+func (a *tokenAuth) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if strings.TrimPrefix(method, flightServicePrefix) == "Handshake" {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+
+	header, gen, err := a.current(ctx, cc)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := streamer(withAuthorization(ctx, header), desc, cc, method, opts...)
+	if err != nil || a.static || !isServerStreaming(method) {
+		return cs, err
+	}
+
+	return &replayingStream{
+		ClientStream: cs,
+		open: func() (grpc.ClientStream, error) {
+			header, _, err := a.current(ctx, cc)
+			if err != nil {
+				return nil, err
+			}
+			return streamer(withAuthorization(ctx, header), desc, cc, method, opts...)
+		},
+		replay: func(attempt int, _ interface{}, err error, _ metadata.MD) bool {
+			return attempt == 1 && a.retry(ctx, cc, gen, err)
+		},
+	}, nil
+}
+
+// withAuthorization returns ctx with its outgoing authorization header
+// replaced by header.
+func withAuthorization(ctx context.Context, header string) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(basicAuthHeader, header)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// withoutAuthorization returns ctx without an outgoing authorization
+// header.
+func withoutAuthorization(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	md = md.Copy()
+	md.Delete(basicAuthHeader)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// TokenIssuer checks the basic credentials sent with a Handshake and
+// returns the bearer token to authenticate later calls with.
+type TokenIssuer func(ctx context.Context, username, password string) (token string, err error)
+
+// TokenValidator checks a bearer token and returns the identity of its
+// holder, which handlers can retrieve with AuthFromContext.
+type TokenValidator func(ctx context.Context, token string) (identity interface{}, err error)
+
+// CreateServerBearerTokenMiddleware returns a ServerMiddleware which
+// requires every call to be authenticated with a bearer token accepted
+// by validate, failing the others with UNAUTHENTICATED so that clients
+// using NewBearerTokenMiddleware acquire a new token.
+//
+// If issue is not nil, Handshakes sent with basic credentials are
+// answered with a token from it in an authorization trailer. Otherwise
+// Handshakes are passed to the server unauthenticated. validate cannot
+// be nil.
+func CreateServerBearerTokenMiddleware(issue TokenIssuer, validate TokenValidator) ServerMiddleware {
+	if validate == nil {
+		panic("validate cannot be nil")
+	}
+
+	authenticate := func(ctx context.Context) (context.Context, error) {
+		token, ok := bearerToken(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+		identity, err := validate(ctx, token)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid bearer token: %s", err)
+		}
+		return context.WithValue(ctx, authCtxKey{}, identity), nil
+	}
+
+	return ServerMiddleware{
+		Unary: func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := authenticate(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		},
+		Stream: func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if strings.HasSuffix(info.FullMethod, "/Handshake") {
+				if issue == nil {
+					return handler(srv, stream)
+				}
+				return issueToken(srv, stream, handler, issue)
+			}
+
+			ctx, err := authenticate(stream.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+		},
+	}
+}
+
+func issueToken(srv interface{}, stream grpc.ServerStream, handler grpc.StreamHandler, issue TokenIssuer) error {
+	var auth string
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if vals := md.Get(basicAuthHeader); len(vals) > 0 {
+			auth = vals[0]
+		}
+	}
+
+	encoded, ok := strings.CutPrefix(auth, basicAuthPrefix+" ")
+	if !ok {
+		return status.Error(codes.Unauthenticated, "handshake requires basic credentials")
+	}
+	val, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		if val, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return status.Errorf(codes.Unauthenticated, "invalid basic auth encoding: %s", err)
+		}
+	}
+	username, password, _ := strings.Cut(string(val), ":")
+
+	token, err := issue(stream.Context(), username, password)
+	if err != nil {
+		return status.Errorf(codes.Unauthenticated, "invalid credentials: %s", err)
+	}
+	stream.SetTrailer(metadata.Pairs(basicAuthHeader, bearerTokenPrefix+" "+token))
+	return handler(srv, stream)
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, v := range md.Get(basicAuthHeader) {
+		if token, ok := strings.CutPrefix(v, bearerTokenPrefix+" "); ok {
+			return token, true
+		}
+	}
+	return "", false
+}
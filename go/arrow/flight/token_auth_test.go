@@ -0,0 +1,199 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flight_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// tokenStore issues numbered tokens to a single user, all of which can be
+// expired at once.
+type tokenStore struct {
+	mx      sync.Mutex
+	issued  int
+	expired int
+}
+
+func (s *tokenStore) issue(_ context.Context, username, password string) (string, error) {
+	if username != "user" || password != "pass" {
+		return "", errors.New("unknown user")
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.issued++
+	return fmt.Sprintf("token-%d", s.issued), nil
+}
+
+func (s *tokenStore) validate(_ context.Context, token string) (interface{}, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	var n int
+	if _, err := fmt.Sscanf(token, "token-%d", &n); err != nil || n < 1 || n > s.issued {
+		return nil, errors.New("unknown token")
+	}
+	if n <= s.expired {
+		return nil, errors.New("token expired")
+	}
+	return "user", nil
+}
+
+func (s *tokenStore) expireAll() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.expired = s.issued
+}
+
+func (s *tokenStore) numIssued() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.issued
+}
+
+// identityServer echoes the identity of the caller.
+type identityServer struct {
+	flight.BaseFlightServer
+}
+
+func (identityServer) GetFlightInfo(ctx context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	identity, _ := flight.AuthFromContext(ctx).(string)
+	return &flight.FlightInfo{FlightDescriptor: &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte(identity)}}, nil
+}
+
+func (identityServer) DoGet(_ *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	identity, _ := flight.AuthFromContext(stream.Context()).(string)
+	return stream.Send(&flight.FlightData{DataBody: []byte(identity)})
+}
+
+func TestBearerTokenMiddleware(t *testing.T) {
+	store := &tokenStore{}
+	s := flight.NewServerWithMiddleware([]flight.ServerMiddleware{
+		flight.CreateServerBearerTokenMiddleware(store.issue, store.validate),
+	})
+	s.RegisterFlightService(&identityServer{})
+	require.NoError(t, s.Init("localhost:0"))
+	go s.Serve()
+	defer s.Shutdown()
+
+	newClient := func(mw flight.ClientMiddleware) flight.Client {
+		client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil,
+			[]flight.ClientMiddleware{mw}, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		return client
+	}
+
+	ctx := context.Background()
+	getInfo := func(client flight.Client) (string, error) {
+		info, err := client.GetFlightInfo(ctx, &flight.FlightDescriptor{Type: flight.DescriptorCMD})
+		if err != nil {
+			return "", err
+		}
+		return string(info.FlightDescriptor.Cmd), nil
+	}
+	doGet := func(client flight.Client) (string, error) {
+		stream, err := client.DoGet(ctx, &flight.Ticket{})
+		if err != nil {
+			return "", err
+		}
+		data, err := stream.Recv()
+		if err != nil {
+			return "", err
+		}
+		return string(data.DataBody), nil
+	}
+
+	client := newClient(flight.NewBearerTokenMiddleware("user", "pass"))
+	defer client.Close()
+
+	t.Run("acquire", func(t *testing.T) {
+		identity, err := getInfo(client)
+		require.NoError(t, err)
+		assert.Equal(t, "user", identity)
+		identity, err = doGet(client)
+		require.NoError(t, err)
+		assert.Equal(t, "user", identity)
+		assert.Equal(t, 1, store.numIssued())
+	})
+
+	t.Run("refresh on expiry", func(t *testing.T) {
+		store.expireAll()
+		identity, err := getInfo(client)
+		require.NoError(t, err)
+		assert.Equal(t, "user", identity)
+		assert.Equal(t, 2, store.numIssued())
+
+		store.expireAll()
+		identity, err = doGet(client)
+		require.NoError(t, err)
+		assert.Equal(t, "user", identity)
+		assert.Equal(t, 3, store.numIssued())
+	})
+
+	t.Run("concurrent refresh", func(t *testing.T) {
+		store.expireAll()
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := getInfo(client)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 4, store.numIssued())
+	})
+
+	t.Run("bad credentials", func(t *testing.T) {
+		client := newClient(flight.NewBearerTokenMiddleware("user", "wrong"))
+		defer client.Close()
+		_, err := getInfo(client)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("static token", func(t *testing.T) {
+		client := newClient(flight.NewStaticBearerTokenMiddleware("token-4"))
+		defer client.Close()
+		identity, err := getInfo(client)
+		require.NoError(t, err)
+		assert.Equal(t, "user", identity)
+
+		store.expireAll()
+		_, err = doGet(client)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		client, err := flight.NewClientWithMiddleware(s.Addr().String(), nil, nil,
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer client.Close()
+		_, err = getInfo(client)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
@@ -38,9 +38,11 @@ import (
 	"github.com/apache/arrow/go/v16/arrow/flight/flightsql/schema_ref"
 	"github.com/apache/arrow/go/v16/arrow/flight/session"
 	"github.com/apache/arrow/go/v16/arrow/internal/arrjson"
+	"github.com/apache/arrow/go/v16/arrow/internal/flatbuf"
 	"github.com/apache/arrow/go/v16/arrow/ipc"
 	"github.com/apache/arrow/go/v16/arrow/memory"
 	"github.com/apache/arrow/go/v16/internal/types"
+	flatbuffers "github.com/google/flatbuffers/go"
 	"golang.org/x/xerrors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -76,6 +78,8 @@ func GetScenario(name string, args ...string) Scenario {
 		return &pollFlightInfoScenarioTester{}
 	case "app_metadata_flight_info_endpoint":
 		return &appMetadataFlightInfoEndpointScenarioTester{}
+	case "dictionary_deltas":
+		return &dictionaryDeltasScenarioTester{}
 	case "flight_sql":
 		return &flightSqlScenarioTester{}
 	case "flight_sql:extension":
@@ -1353,6 +1357,221 @@ func (tester *appMetadataFlightInfoEndpointScenarioTester) RunClient(addr string
 	return nil
 }
 
+// dictionaryDeltasScenarioTester sends a dictionary-encoded column whose
+// dictionary grows from batch to batch, and is then replaced by a
+// smaller one, with dictionary deltas enabled: from the server with
+// DoGet and from the client with DoPut. The receiving side checks the
+// decoded batches and, when it can inspect the messages, that the
+// dictionary grew with deltas rather than replacements.
+type dictionaryDeltasScenarioTester struct {
+	flight.BaseFlightServer
+}
+
+var dictionaryDeltasSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "value", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}, Nullable: true},
+}, nil)
+
+const (
+	// the first dictionary is new, the two next are deltas and the last
+	// one replaces it
+	dictionaryDeltasExpectedDictionaries = 4
+	dictionaryDeltasExpectedDeltas       = 2
+)
+
+// dictionaryDeltasBatches returns the batches sent by the scenario, each
+// dictionary starting with the previous one except for the last.
+func dictionaryDeltasBatches(mem memory.Allocator) ([]arrow.Record, error) {
+	batches := []struct{ dict, indices string }{
+		{`["a", "b"]`, `[0, 1]`},
+		{`["a", "b", "c"]`, `[0, 2, null]`},
+		{`["a", "b", "c", "d"]`, `[3, 1, 2]`},
+		{`["x", "y"]`, `[1, 0]`},
+	}
+
+	dt := dictionaryDeltasSchema.Field(0).Type.(*arrow.DictionaryType)
+	recs := make([]arrow.Record, 0, len(batches))
+	for _, b := range batches {
+		dict, _, err := array.FromJSON(mem, dt.ValueType, strings.NewReader(b.dict))
+		if err != nil {
+			return nil, err
+		}
+		indices, _, err := array.FromJSON(mem, dt.IndexType, strings.NewReader(b.indices))
+		if err != nil {
+			dict.Release()
+			return nil, err
+		}
+		arr := array.NewDictionaryArray(dt, indices, dict)
+		dict.Release()
+		indices.Release()
+		recs = append(recs, array.NewRecord(dictionaryDeltasSchema, []arrow.Array{arr}, int64(arr.Len())))
+		arr.Release()
+	}
+	return recs, nil
+}
+
+// dictionaryBatchCounter counts the dictionary batches of the messages
+// read from the stream, and those of them which are deltas.
+type dictionaryBatchCounter struct {
+	flight.DataStreamReader
+	dictionaries, deltas int
+}
+
+func (c *dictionaryBatchCounter) Recv() (*flight.FlightData, error) {
+	data, err := c.DataStreamReader.Recv()
+	if err != nil || len(data.DataHeader) == 0 {
+		return data, err
+	}
+
+	msg := flatbuf.GetRootAsMessage(data.DataHeader, 0)
+	var tbl flatbuffers.Table
+	if msg.HeaderType() == flatbuf.MessageHeaderDictionaryBatch && msg.Header(&tbl) {
+		var batch flatbuf.DictionaryBatch
+		batch.Init(tbl.Bytes, tbl.Pos)
+		c.dictionaries++
+		if batch.IsDelta() {
+			c.deltas++
+		}
+	}
+	return data, nil
+}
+
+func (c *dictionaryBatchCounter) check() error {
+	if c.dictionaries != dictionaryDeltasExpectedDictionaries || c.deltas != dictionaryDeltasExpectedDeltas {
+		return fmt.Errorf("expected %d dictionary batches of which %d deltas, got %d of which %d deltas",
+			dictionaryDeltasExpectedDictionaries, dictionaryDeltasExpectedDeltas, c.dictionaries, c.deltas)
+	}
+	return nil
+}
+
+// checkDictionaryDeltas reads the batches of the stream, checking them
+// and the dictionary batches they were sent with.
+func checkDictionaryDeltas(stream flight.DataStreamReader) error {
+	expected, err := dictionaryDeltasBatches(memory.DefaultAllocator)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, rec := range expected {
+			rec.Release()
+		}
+	}()
+
+	counter := &dictionaryBatchCounter{DataStreamReader: stream}
+	rdr, err := flight.NewRecordReader(counter)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	if !rdr.Schema().Equal(dictionaryDeltasSchema) {
+		return fmt.Errorf("expected schema %s, got %s", dictionaryDeltasSchema, rdr.Schema())
+	}
+	for i, rec := range expected {
+		if !rdr.Next() {
+			if err := rdr.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("got %d batches, expected %d", i, len(expected))
+		}
+		if !array.RecordEqual(rec, rdr.Record()) {
+			return fmt.Errorf("batch %d doesn't match\nexpected: %v\ngot: %v", i, rec, rdr.Record())
+		}
+	}
+	if rdr.Next() {
+		return fmt.Errorf("got more than the %d expected batches", len(expected))
+	}
+	if err := rdr.Err(); err != nil {
+		return err
+	}
+	return counter.check()
+}
+
+// writeDictionaryDeltas writes the batches of the scenario to w, which
+// must emit dictionary deltas.
+func writeDictionaryDeltas(w *flight.Writer) error {
+	recs, err := dictionaryDeltasBatches(memory.DefaultAllocator)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, rec := range recs {
+			rec.Release()
+		}
+	}()
+
+	for _, rec := range recs {
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tester *dictionaryDeltasScenarioTester) MakeServer(port int) flight.Server {
+	srv := flight.NewServerWithMiddleware(nil)
+	srv.RegisterFlightService(tester)
+	initServer(port, srv)
+	return srv
+}
+
+func (tester *dictionaryDeltasScenarioTester) DoGet(tkt *flight.Ticket, fs flight.FlightService_DoGetServer) error {
+	if string(tkt.GetTicket()) != "dictionary_deltas" {
+		return status.Errorf(codes.NotFound, "unknown ticket: %s", tkt.GetTicket())
+	}
+
+	w := flight.NewRecordWriter(fs, ipc.WithSchema(dictionaryDeltasSchema), ipc.WithDictionaryDeltas(true))
+	defer w.Close()
+	return writeDictionaryDeltas(w)
+}
+
+func (tester *dictionaryDeltasScenarioTester) DoPut(stream flight.FlightService_DoPutServer) error {
+	if err := checkDictionaryDeltas(stream); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+func (tester *dictionaryDeltasScenarioTester) RunClient(addr string, opts ...grpc.DialOption) error {
+	client, err := flight.NewClientWithMiddleware(addr, nil, nil, opts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	stream, err := client.DoGet(ctx, &flight.Ticket{Ticket: []byte("dictionary_deltas")})
+	if err != nil {
+		return err
+	}
+	if err := checkDictionaryDeltas(stream); err != nil {
+		return fmt.Errorf("DoGet: %w", err)
+	}
+
+	put, err := client.DoPut(ctx)
+	if err != nil {
+		return err
+	}
+	w := flight.NewRecordWriter(put, ipc.WithSchema(dictionaryDeltasSchema), ipc.WithDictionaryDeltas(true))
+	w.SetFlightDescriptor(&flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("dictionary_deltas")})
+	if err := writeDictionaryDeltas(w); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := put.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		if _, err := put.Recv(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("DoPut: %w", err)
+		}
+	}
+}
+
 const (
 	updateStatementExpectedRows                        int64 = 10000
 	updateStatementWithTransactionExpectedRows         int64 = 15000
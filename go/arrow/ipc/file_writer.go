@@ -199,6 +199,8 @@ type Payload struct {
 	meta *memory.Buffer
 	body []*memory.Buffer
 	size int64 // length of body
+	// rawSize is the length of body before compression
+	rawSize int64
 }
 
 // Meta returns the buffer containing the metadata for this payload,
@@ -180,15 +180,16 @@ func (r *Reader) Next() bool {
 func (r *Reader) getInitialDicts() bool {
 	var msg *Message
 	// we have to get all dictionaries before reconstructing the first
-	// record. subsequent deltas and replacements modify the memo
+	// record. subsequent deltas and replacements modify the memo. deltas
+	// and replacements may also come before the first record, so the
+	// dictionaries are read until each of them was received
 	numDicts := r.memo.Mapper.NumDicts()
-	// there should be numDicts dictionary messages
-	for i := 0; i < numDicts; i++ {
+	for r.memo.Len() < numDicts {
 		msg, r.err = r.r.Message()
 		if r.err != nil {
 			r.done = true
 			if r.err == io.EOF {
-				if i == 0 {
+				if r.memo.Len() == 0 {
 					r.err = nil
 				} else {
 					r.err = fmt.Errorf("arrow/ipc: IPC stream ended without reading the expected (%d) dictionaries", numDicts)
@@ -198,7 +199,9 @@ func (r *Reader) getInitialDicts() bool {
 		}
 
 		if msg.Type() != MessageDictionaryBatch {
+			r.done = true
 			r.err = fmt.Errorf("arrow/ipc: IPC stream did not have the expected (%d) dictionaries at the start of the stream", numDicts)
+			return false
 		}
 		if _, err := readDictionary(&r.memo, msg.meta, bytes.NewReader(msg.body.Bytes()), r.swapEndianness, r.mem); err != nil {
 			r.done = true
@@ -183,3 +183,140 @@ func BenchmarkIPC(b *testing.B) {
 		})
 	}
 }
+
+// messageRecorder is a PayloadWriter keeping each message serialized.
+type messageRecorder struct {
+	msgs [][]byte
+}
+
+func (r *messageRecorder) Start() error { return nil }
+func (r *messageRecorder) Close() error { return nil }
+
+func (r *messageRecorder) WritePayload(p Payload) error {
+	var buf bytes.Buffer
+	if _, err := writeIPCPayload(&buf, p); err != nil {
+		return err
+	}
+	r.msgs = append(r.msgs, buf.Bytes())
+	return nil
+}
+
+func TestReaderDictionaryReplacement(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	dictType := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+	schema := arrow.NewSchema([]arrow.Field{{Name: "s", Type: dictType}}, nil)
+
+	newRecord := func(dict []string, indices []int32) arrow.Record {
+		dictBldr := array.NewStringBuilder(mem)
+		defer dictBldr.Release()
+		dictBldr.AppendValues(dict, nil)
+		dictArr := dictBldr.NewArray()
+		defer dictArr.Release()
+
+		idxBldr := array.NewInt32Builder(mem)
+		defer idxBldr.Release()
+		idxBldr.AppendValues(indices, nil)
+		idxArr := idxBldr.NewArray()
+		defer idxArr.Release()
+
+		col := array.NewDictionaryArray(dictType, idxArr, dictArr)
+		defer col.Release()
+		return array.NewRecord(schema, []arrow.Array{col}, int64(col.Len()))
+	}
+
+	// the writer sends a delta for a dictionary extending the previous
+	// one, and a replacement otherwise
+	rec := &messageRecorder{}
+	w := NewWriterWithPayloadWriter(rec, WithSchema(schema), WithAllocator(mem), WithDictionaryDeltas(true))
+	for _, r := range []struct {
+		dict    []string
+		indices []int32
+	}{
+		{[]string{"a", "b"}, []int32{0, 1}},
+		{[]string{"a", "b", "c"}, []int32{2, 0}},
+		{[]string{"x", "y"}, []int32{1, 0}},
+		{[]string{"x", "y", "z"}, []int32{2, 0}},
+	} {
+		batch := newRecord(r.dict, r.indices)
+		require.NoError(t, w.Write(batch))
+		batch.Release()
+	}
+	require.NoError(t, w.Close())
+
+	const (
+		schemaMsg = iota
+		dictAB
+		recAB
+		deltaC
+		recCA
+		replaceXY
+		recYX
+		deltaZ
+		recZX
+	)
+	require.Len(t, rec.msgs, recZX+1)
+
+	for _, tc := range []struct {
+		name     string
+		messages []int
+		want     [][]string
+	}{
+		{"delta then replacement", []int{schemaMsg, dictAB, recAB, deltaC, recCA, replaceXY, recYX, deltaZ, recZX},
+			[][]string{{"a", "b"}, {"c", "a"}, {"y", "x"}, {"z", "x"}}},
+		{"replacement before the first record", []int{schemaMsg, dictAB, replaceXY, recYX},
+			[][]string{{"y", "x"}}},
+		{"delta before the first record", []int{schemaMsg, dictAB, deltaC, recCA},
+			[][]string{{"c", "a"}}},
+		{"replacement after delta without record", []int{schemaMsg, dictAB, recAB, deltaC, replaceXY, deltaZ, recZX},
+			[][]string{{"a", "b"}, {"z", "x"}}},
+		{"replacement after delta", []int{schemaMsg, dictAB, deltaC, recCA, replaceXY, recYX, dictAB, recAB},
+			[][]string{{"c", "a"}, {"y", "x"}, {"a", "b"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			for _, i := range tc.messages {
+				buf.Write(rec.msgs[i])
+			}
+			buf.Write(kEOS[:])
+
+			rdr, err := NewReader(&buf, WithAllocator(mem))
+			require.NoError(t, err)
+			defer rdr.Release()
+
+			// records are kept past the dictionaries they use being
+			// replaced
+			var got []arrow.Record
+			for rdr.Next() {
+				r := rdr.Record()
+				r.Retain()
+				got = append(got, r)
+			}
+			require.NoError(t, rdr.Err())
+
+			require.Len(t, got, len(tc.want))
+			for i, r := range got {
+				col := r.Column(0).(*array.Dictionary)
+				dict := col.Dictionary().(*array.String)
+				values := make([]string, col.Len())
+				for j := range values {
+					values[j] = dict.Value(col.GetValueIndex(j))
+				}
+				assert.Equal(t, tc.want[i], values, "record %d", i)
+				r.Release()
+			}
+		})
+	}
+
+	// a record can't come before the dictionary it uses
+	var buf bytes.Buffer
+	buf.Write(rec.msgs[schemaMsg])
+	buf.Write(rec.msgs[recAB])
+	buf.Write(kEOS[:])
+	rdr, err := NewReader(&buf, WithAllocator(mem))
+	require.NoError(t, err)
+	defer rdr.Release()
+	assert.False(t, rdr.Next())
+	assert.ErrorContains(t, rdr.Err(), "did not have the expected (1) dictionaries")
+}
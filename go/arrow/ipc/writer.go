@@ -24,6 +24,7 @@ import (
 	"io"
 	"math"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/apache/arrow/go/v16/arrow"
@@ -92,6 +93,60 @@ type Writer struct {
 	// so we can avoid writing the same dictionary over and over
 	lastWrittenDicts map[int64]arrow.Array
 	emitDictDeltas   bool
+
+	stats writerStats
+}
+
+// WriterStats counts the messages written by a Writer.
+type WriterStats struct {
+	// Messages is the number of messages written: the schema, dictionary
+	// batches and record batches.
+	Messages          int64
+	RecordBatches     int64
+	DictionaryBatches int64
+	// RawBodyBytes is the length of the bodies of the messages before
+	// compression and BodyBytes that of the bodies written, which are
+	// equal unless the bodies are compressed, see WithLZ4 and WithZstd.
+	RawBodyBytes int64
+	BodyBytes    int64
+}
+
+// CompressionRatio returns the ratio of the length of the bodies written
+// before and after compression, 1 if there is no body or no compression.
+func (s WriterStats) CompressionRatio() float64 {
+	if s.BodyBytes == 0 {
+		return 1
+	}
+	return float64(s.RawBodyBytes) / float64(s.BodyBytes)
+}
+
+// writerStats holds the counters of WriterStats, which are read
+// concurrently with the writes.
+type writerStats struct {
+	messages, recordBatches, dictBatches atomic.Int64
+	rawBodyBytes, bodyBytes              atomic.Int64
+}
+
+// statsPayloadWriter counts the payloads written to its PayloadWriter.
+type statsPayloadWriter struct {
+	PayloadWriter
+	stats *writerStats
+}
+
+func (w *statsPayloadWriter) WritePayload(p Payload) error {
+	if err := w.PayloadWriter.WritePayload(p); err != nil {
+		return err
+	}
+	w.stats.messages.Add(1)
+	switch p.msg {
+	case MessageRecordBatch:
+		w.stats.recordBatches.Add(1)
+	case MessageDictionaryBatch:
+		w.stats.dictBatches.Add(1)
+	}
+	w.stats.rawBodyBytes.Add(p.rawSize)
+	w.stats.bodyBytes.Add(p.size)
+	return nil
 }
 
 // NewWriterWithPayloadWriter constructs a writer with the provided payload writer
@@ -99,28 +154,42 @@ type Writer struct {
 // reusable such as by the Arrow Flight writer.
 func NewWriterWithPayloadWriter(pw PayloadWriter, opts ...Option) *Writer {
 	cfg := newConfig(opts...)
-	return &Writer{
+	w := &Writer{
 		mem:             cfg.alloc,
-		pw:              pw,
 		schema:          cfg.schema,
 		codec:           cfg.codec,
 		compressNP:      cfg.compressNP,
 		minSpaceSavings: cfg.minSpaceSavings,
 		emitDictDeltas:  cfg.emitDictDeltas,
 	}
+	w.pw = &statsPayloadWriter{PayloadWriter: pw, stats: &w.stats}
+	return w
 }
 
 // NewWriter returns a writer that writes records to the provided output stream.
 func NewWriter(w io.Writer, opts ...Option) *Writer {
 	cfg := newConfig(opts...)
-	return &Writer{
+	wr := &Writer{
 		w:              w,
 		mem:            cfg.alloc,
-		pw:             &swriter{w: w},
 		schema:         cfg.schema,
 		codec:          cfg.codec,
 		emitDictDeltas: cfg.emitDictDeltas,
 	}
+	wr.pw = &statsPayloadWriter{PayloadWriter: &swriter{w: w}, stats: &wr.stats}
+	return wr
+}
+
+// Stats returns the counts of what the writer has written so far. It may
+// be called concurrently with the writes, such as to report metrics.
+func (w *Writer) Stats() WriterStats {
+	return WriterStats{
+		Messages:          w.stats.messages.Load(),
+		RecordBatches:     w.stats.recordBatches.Load(),
+		DictionaryBatches: w.stats.dictBatches.Load(),
+		RawBodyBytes:      w.stats.rawBodyBytes.Load(),
+		BodyBytes:         w.stats.bodyBytes.Load(),
+	}
 }
 
 func (w *Writer) Close() error {
@@ -441,6 +510,7 @@ func (w *recordEncoder) encode(p *Payload, rec arrow.Record) error {
 		}
 	}
 
+	p.rawSize = bodyLength(p.body)
 	if w.codec != -1 {
 		if w.minSpaceSavings != nil {
 			pct := *w.minSpaceSavings
@@ -486,6 +556,17 @@ func (w *recordEncoder) encode(p *Payload, rec arrow.Record) error {
 	return nil
 }
 
+// bodyLength returns the length of body once padded.
+func bodyLength(body []*memory.Buffer) int64 {
+	var n int64
+	for _, buf := range body {
+		if buf != nil {
+			n += bitutil.CeilByte64(int64(buf.Len()))
+		}
+	}
+	return n
+}
+
 func (w *recordEncoder) visit(p *Payload, arr arrow.Array) error {
 	if w.depth <= 0 {
 		return errMaxRecursion
@@ -169,6 +169,50 @@ func TestWriterMemCompression(t *testing.T) {
 	require.NoError(t, w.Write(rec))
 }
 
+func TestWriterStats(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	dictType := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "i", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "d", Type: dictType},
+	}, nil)
+
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues(make([]int64, 1000), nil)
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, b.Field(1).(*array.BinaryDictionaryBuilder).AppendString("repeated"))
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	write := func(opts ...Option) WriterStats {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, append(opts, WithAllocator(mem), WithSchema(schema))...)
+		require.NoError(t, w.Write(rec))
+		require.NoError(t, w.Write(rec))
+		require.NoError(t, w.Close())
+		return w.Stats()
+	}
+
+	// the dictionary is only sent with the first record
+	stats := write()
+	assert.EqualValues(t, 4, stats.Messages)
+	assert.EqualValues(t, 2, stats.RecordBatches)
+	assert.EqualValues(t, 1, stats.DictionaryBatches)
+	assert.Greater(t, stats.BodyBytes, int64(2*1000*8))
+	assert.Equal(t, stats.BodyBytes, stats.RawBodyBytes)
+	assert.EqualValues(t, 1, stats.CompressionRatio())
+
+	compressed := write(WithZstd())
+	assert.EqualValues(t, 4, compressed.Messages)
+	assert.Equal(t, stats.RawBodyBytes, compressed.RawBodyBytes)
+	assert.Less(t, compressed.BodyBytes, compressed.RawBodyBytes)
+	assert.Greater(t, compressed.CompressionRatio(), 10.0)
+}
+
 func TestWriteWithCompressionAndMinSavings(t *testing.T) {
 	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
 	defer mem.AssertSize(t, 0)
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,14 +32,23 @@ import (
 )
 
 type CheckedAllocator struct {
-	mem Allocator
-	sz  int64
+	mem    Allocator
+	sz     int64
+	frames int
 
 	allocs sync.Map
 }
 
 func NewCheckedAllocator(mem Allocator) *CheckedAllocator {
-	return &CheckedAllocator{mem: mem}
+	return &CheckedAllocator{mem: mem, frames: maxRetainedFrames}
+}
+
+// NewCheckedAllocatorWithStacks returns a CheckedAllocator which retains
+// up to frames frames of the stack of each allocation, whatever the value
+// of ARROW_CHECKED_MAX_RETAINED_FRAMES, so that the leaks reported by
+// AssertSize and LeakReport show how the leaked memory was allocated.
+func NewCheckedAllocatorWithStacks(mem Allocator, frames int) *CheckedAllocator {
+	return &CheckedAllocator{mem: mem, frames: frames}
 }
 
 func (a *CheckedAllocator) CurrentAlloc() int { return int(atomic.LoadInt64(&a.sz)) }
@@ -51,16 +61,15 @@ func (a *CheckedAllocator) Allocate(size int) []byte {
 	}
 
 	ptr := uintptr(unsafe.Pointer(&out[0]))
-	pcs := make([]uintptr, maxRetainedFrames)
+	pcs := make([]uintptr, a.frames)
 
 	// For historical reasons the meaning of the skip argument
 	// differs between Caller and Callers. For Callers, 0 identifies
 	// the frame for the caller itself. We skip 2 additional frames
 	// here to get to the caller right before the call to Allocate.
-	runtime.Callers(allocFrames+2, pcs)
-	callersFrames := runtime.CallersFrames(pcs)
+	pcs = pcs[:runtime.Callers(allocFrames+2, pcs)]
 	if pc, _, l, ok := runtime.Caller(allocFrames); ok {
-		a.allocs.Store(ptr, &dalloc{pc: pc, line: l, sz: size, callersFrames: callersFrames})
+		a.allocs.Store(ptr, &dalloc{pc: pc, line: l, sz: size, callers: pcs})
 	}
 	return out
 }
@@ -76,16 +85,15 @@ func (a *CheckedAllocator) Reallocate(size int, b []byte) []byte {
 
 	newptr := uintptr(unsafe.Pointer(&out[0]))
 	a.allocs.Delete(oldptr)
-	pcs := make([]uintptr, maxRetainedFrames)
+	pcs := make([]uintptr, a.frames)
 
 	// For historical reasons the meaning of the skip argument
 	// differs between Caller and Callers. For Callers, 0 identifies
 	// the frame for the caller itself. We skip 2 additional frames
 	// here to get to the caller right before the call to Reallocate.
-	runtime.Callers(reallocFrames+2, pcs)
-	callersFrames := runtime.CallersFrames(pcs)
+	pcs = pcs[:runtime.Callers(reallocFrames+2, pcs)]
 	if pc, _, l, ok := runtime.Caller(reallocFrames); ok {
-		a.allocs.Store(newptr, &dalloc{pc: pc, line: l, sz: size, callersFrames: callersFrames})
+		a.allocs.Store(newptr, &dalloc{pc: pc, line: l, sz: size, callers: pcs})
 	}
 
 	return out
@@ -140,10 +148,67 @@ func init() {
 }
 
 type dalloc struct {
-	pc            uintptr
-	line          int
-	sz            int
-	callersFrames *runtime.Frames
+	pc      uintptr
+	line    int
+	sz      int
+	callers []uintptr
+}
+
+// String describes the leak of the allocation.
+func (info *dalloc) String() string {
+	var callersMsg strings.Builder
+	frames := runtime.CallersFrames(info.callers)
+	for {
+		frame, more := frames.Next()
+		if frame.Line == 0 {
+			break
+		}
+		callersMsg.WriteString("\t")
+		// frame.Func is a useful source of information if it's present.
+		// It may be nil for non-Go code or fully inlined functions.
+		if fn := frame.Func; fn != nil {
+			// format as func name + the offset in bytes from func entrypoint
+			callersMsg.WriteString(fmt.Sprintf("%s+%x", fn.Name(), frame.PC-fn.Entry()))
+		} else {
+			// fallback to outer func name + file line
+			callersMsg.WriteString(fmt.Sprintf("%s, line %d", frame.Function, frame.Line))
+		}
+
+		// Write a proper file name + line, so it's really easy to find the leak
+		callersMsg.WriteString("\n\t\t")
+		callersMsg.WriteString(frame.File + ":" + strconv.Itoa(frame.Line))
+		callersMsg.WriteString("\n")
+		if !more {
+			break
+		}
+	}
+
+	f := runtime.FuncForPC(info.pc)
+	file, line := f.FileLine(info.pc)
+	return fmt.Sprintf("LEAK of %d bytes FROM\n\t%s+%x\n\t\t%s:%d\n%v",
+		info.sz,
+		f.Name(), info.pc-f.Entry(), // func name + offset in bytes between frame & entrypoint to func
+		file, line, // a proper file name + line, so it's really easy to find the leak
+		callersMsg.String(),
+	)
+}
+
+// leaks returns the description of each outstanding allocation, sorted.
+func (a *CheckedAllocator) leaks() []string {
+	var leaks []string
+	a.allocs.Range(func(_, value interface{}) bool {
+		leaks = append(leaks, value.(*dalloc).String())
+		return true
+	})
+	sort.Strings(leaks)
+	return leaks
+}
+
+// LeakReport describes every allocation which hasn't been freed, with
+// the function it was made from and as much of its stack as the
+// allocator retains, or returns an empty string if there is none.
+func (a *CheckedAllocator) LeakReport() string {
+	return strings.Join(a.leaks(), "\n")
 }
 
 type TestingT interface {
@@ -152,48 +217,12 @@ type TestingT interface {
 }
 
 func (a *CheckedAllocator) AssertSize(t TestingT, sz int) {
-	a.allocs.Range(func(_, value interface{}) bool {
-		info := value.(*dalloc)
-		f := runtime.FuncForPC(info.pc)
-		frames := info.callersFrames
-		var callersMsg strings.Builder
-		for {
-			frame, more := frames.Next()
-			if frame.Line == 0 {
-				break
-			}
-			callersMsg.WriteString("\t")
-			// frame.Func is a useful source of information if it's present.
-			// It may be nil for non-Go code or fully inlined functions.
-			if fn := frame.Func; fn != nil {
-				// format as func name + the offset in bytes from func entrypoint
-				callersMsg.WriteString(fmt.Sprintf("%s+%x", fn.Name(), frame.PC-fn.Entry()))
-			} else {
-				// fallback to outer func name + file line
-				callersMsg.WriteString(fmt.Sprintf("%s, line %d", frame.Function, frame.Line))
-			}
-
-			// Write a proper file name + line, so it's really easy to find the leak
-			callersMsg.WriteString("\n\t\t")
-			callersMsg.WriteString(frame.File + ":" + strconv.Itoa(frame.Line))
-			callersMsg.WriteString("\n")
-			if !more {
-				break
-			}
-		}
-
-		file, line := f.FileLine(info.pc)
-		t.Errorf("LEAK of %d bytes FROM\n\t%s+%x\n\t\t%s:%d\n%v",
-			info.sz,
-			f.Name(), info.pc-f.Entry(), // func name + offset in bytes between frame & entrypoint to func
-			file, line, // a proper file name + line, so it's really easy to find the leak
-			callersMsg.String(),
-		)
-		return true
-	})
+	t.Helper()
+	for _, leak := range a.leaks() {
+		t.Errorf("%s", leak)
+	}
 
 	if int(atomic.LoadInt64(&a.sz)) != sz {
-		t.Helper()
 		t.Errorf("invalid memory size exp=%d, got=%d", sz, a.sz)
 	}
 }
@@ -0,0 +1,72 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !tinygo
+// +build !tinygo
+
+package memory_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingT records the errors reported by an assertion.
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Helper() {}
+
+func leakBuffer(mem memory.Allocator, size int) *memory.Buffer {
+	buf := memory.NewResizableBuffer(mem)
+	buf.Resize(size)
+	return buf
+}
+
+func TestCheckedAllocatorLeakReport(t *testing.T) {
+	mem := memory.NewCheckedAllocatorWithStacks(memory.NewGoAllocator(), 16)
+	assert.Empty(t, mem.LeakReport())
+
+	freed := leakBuffer(mem, 32)
+	leaked := leakBuffer(mem, 64)
+	freed.Release()
+
+	report := mem.LeakReport()
+	assert.Contains(t, report, "LEAK of 64 bytes FROM")
+	assert.NotContains(t, report, "LEAK of 32 bytes")
+	// the retained stack leads back to the code which leaked the buffer
+	assert.Contains(t, report, "memory_test.leakBuffer")
+	assert.Contains(t, report, "checked_allocator_test.go:")
+
+	// the report can be produced again, such as by AssertSize
+	assert.Equal(t, report, mem.LeakReport())
+
+	var rec recordingT
+	mem.AssertSize(&rec, 0)
+	assert.Equal(t, []string{report, "invalid memory size exp=0, got=64"}, rec.errors)
+
+	leaked.Release()
+	assert.Empty(t, mem.LeakReport())
+	mem.AssertSize(t, 0)
+}